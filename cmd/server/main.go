@@ -2,12 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"time"
 
-	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog/log"
 
@@ -21,45 +21,111 @@ import (
 	"github.com/rishavmehra/indexer/pkg/logger"
 )
 
+// poolCloseTimeout bounds how long shutdown waits for database connections to be returned
+const poolCloseTimeout = 5 * time.Second
+
 func main() {
+	migrateOnly := flag.Bool("migrate-only", false, "run the database migrations and exit")
+	flag.Parse()
+
 	cfg, err := config.LoadConfig(".env")
 	if err != nil {
-		fmt.Printf("Error loading config: %v\n", err)
+		printConfigError(err)
 		os.Exit(1)
 	}
 
-	logger.SetupLogger(cfg.Logger.Level)
+	logger.SetupLogger(cfg.Logger.Level, cfg.Logger.Format)
+
+	migrator := service.NewMigrator(cfg.Database.MigrationURL, databaseURL(cfg.Database))
+
+	// "migrate up|down N|force V" runs a single migration command instead
+	// of starting the server
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrateCommand(migrator, flag.Args()[1:]); err != nil {
+			log.Fatal().Err(err).Msg("Migration command failed")
+		}
+		return
+	}
 
-	if err := runMigrations(cfg.Database); err != nil {
+	if err := runMigrations(migrator); err != nil {
 		log.Fatal().Err(err).Msg("Failed to run database migrations")
 	}
+	if *migrateOnly {
+		return
+	}
 
 	pool, err := connectToDatabase(cfg.Database)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to connect to database")
 	}
-	defer pool.Close()
+	defer closePool(pool, poolCloseTimeout)
 
-	queries := db.New(pool)
+	primary := db.New(pool)
+	if _, err := service.EncryptStoredPasswords(context.Background(), primary, cfg.Credentials.EncryptionKey); err != nil {
+		log.Fatal().Err(err).Msg("Failed to encrypt stored DB credential passwords")
+	}
 
-	heliusClient := indexer.NewHeliusClient(
-		cfg.Helius.APIKey,
-		cfg.Helius.WebhookSecret,
-		cfg.Helius.WebhookBaseURL,
-		cfg.Helius.WebhookID,
-	)
+	var queries db.Querier = primary
+
+	if cfg.Database.ReadURL != "" {
+		readPool, err := openPool(cfg.Database.ReadURL, cfg.Database)
+		if err != nil {
+			log.Warn().Err(logger.RedactError(err)).Msg("Failed to connect to read replica, serving reads from the primary")
+		} else {
+			defer closePool(readPool, poolCloseTimeout)
+			queries = service.NewReadRoutingStore(queries, db.New(readPool))
+			log.Info().Msg("Connected to read replica")
+		}
+	}
+
+	queries = service.NewEncryptingStore(queries, cfg.Credentials.EncryptionKey)
+
+	metadataCache := indexer.NewTokenMetadataCacheWithSize(cfg.MetadataCache.MaxSize, cfg.MetadataCache.TTL, cfg.MetadataCache.NegativeTTL)
+	metadataCache.StartSweeper(cfg.MetadataCache.SweepInterval)
+	defer metadataCache.Close()
+
+	heliusClient := indexer.NewHeliusClient(cfg.Helius)
 
 	authService := service.NewAuthService(cfg.JWT, queries)
 	userService := service.NewUserService(queries)
 	indexerService := service.NewIndexerService(queries, heliusClient)
+	indexerService.SetDedupWindow(cfg.Webhook.DedupWindow)
+	indexerService.SetMetadataCache(metadataCache)
+	indexerService.SetDBAcquireTimeout(cfg.Webhook.DBAcquireTimeout)
+	indexerService.SetTargetPoolSize(cfg.Database.TargetMaxConns, cfg.Database.TargetMinConns)
+	indexerService.SetLogLimits(cfg.Logs.MaxLimit, cfg.Logs.EnhanceLimit)
+	indexerService.SetDeleteRetention(cfg.Indexers.DeleteRetention)
+	indexerService.SetPayloadJobRetention(cfg.Webhook.QueueRetention)
+	indexerService.SetRawPayloadRetention(cfg.Webhook.RawPayloadRetention)
+	indexerService.SetReservedTablePrefixes(cfg.Indexers.ReservedTablePrefixes)
+	indexerService.SetMaxIndexersPerUser(cfg.Indexers.MaxPerUser)
+	indexerService.SetStaleAfter(cfg.Indexers.StaleAfter)
+	indexerService.SetAutoPause(cfg.Indexers.AutoPauseFailures, cfg.Indexers.AutoPauseWindow)
+	webhookDispatcher := service.NewWebhookDispatcher(indexerService, cfg.Webhook)
+
+	if cfg.Helius.ReconcileInterval > 0 {
+		stopReconciler := indexerService.StartWebhookReconciler(cfg.Helius.ReconcileInterval, cfg.Helius.ReconcileDryRun)
+		defer stopReconciler()
+	}
+
+	stopPurger := indexerService.StartIndexerPurger()
+	defer stopPurger()
+
+	if cfg.Indexers.PollCheckInterval > 0 {
+		stopPoller := indexerService.StartPoller(cfg.Indexers.PollCheckInterval)
+		defer stopPoller()
+	}
+
+	if cfg.Server.MaintenanceMode {
+		indexerService.Maintenance().Set(true, "MAINTENANCE_MODE is set")
+	}
 
 	authHandler := handlers.NewAuthHandler(authService)
 	userHandler := handlers.NewUserHandler(userService)
-	indexerHandler := handlers.NewIndexerHandler(indexerService)
+	indexerHandler := handlers.NewIndexerHandler(indexerService, webhookDispatcher)
+	adminHandler := handlers.NewAdminHandler(indexerService, migrator)
 
-	mw := middleware.MiddlewareConfig{
-		Auth: middleware.AuthMiddleware(cfg.JWT),
-	}
+	mw := middleware.NewMiddlewareConfig(cfg.JWT, cfg.Admin, cfg.RateLimit)
 
 	server := api.NewServer(cfg.Server)
 	api.SetupRoutes(
@@ -67,45 +133,83 @@ func main() {
 		authHandler,
 		userHandler,
 		indexerHandler,
+		adminHandler,
 		mw,
 	)
 
+	// Errors are logged rather than fatal so the deferred pool.Close runs
+	// only after webhook processing has drained
 	if err := server.Start(); err != nil {
-		log.Fatal().Err(err).Msg("Server failed")
+		log.Error().Err(err).Msg("Server failed")
+	}
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.DrainTimeout)
+	defer cancel()
+
+	if err := indexerHandler.Drain(drainCtx); err != nil {
+		log.Warn().Err(err).Dur("drainTimeout", cfg.Server.DrainTimeout).Msg("Timed out waiting for webhook processing to finish")
+	} else {
+		log.Info().Msg("Webhook processing drained")
 	}
 }
 
-// runMigrations runs database migrations
-func runMigrations(dbConfig config.DatabaseConfig) error {
-	dbURL := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
-		dbConfig.User, dbConfig.Password, dbConfig.Host, dbConfig.Port, dbConfig.DBName, dbConfig.SSLMode)
+// closePool closes the pool, giving up after timeout if connections are
+// still checked out so a stuck query cannot hang shutdown
+func closePool(pool *pgxpool.Pool, timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		pool.Close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		log.Warn().Dur("timeout", timeout).Msg("Database pool did not close in time, exiting anyway")
+	}
+}
 
-	m, err := migrate.New(dbConfig.MigrationURL, dbURL)
-	if err != nil {
-		return fmt.Errorf("failed to create migration instance: %w", err)
+// runMigrations runs database migrations
+func runMigrations(migrator *service.Migrator) error {
+	if err := migrator.Up(); err != nil {
+		return err
 	}
-	defer m.Close()
 
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return fmt.Errorf("failed to run migrations: %w", err)
+	status, err := migrator.Version()
+	if err != nil {
+		return err
 	}
 
-	log.Info().Msg("Database migrations completed successfully")
+	log.Info().Uint("version", status.Version).Msg("Database migrations completed successfully")
 	return nil
 }
 
+// databaseURL returns the connection URL of the control database
+func databaseURL(dbConfig config.DatabaseConfig) string {
+	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
+		dbConfig.User, dbConfig.Password, dbConfig.Host, dbConfig.Port, dbConfig.DBName, dbConfig.SSLMode)
+}
+
 // connectToDatabase connects to the PostgreSQL database
 func connectToDatabase(dbConfig config.DatabaseConfig) (*pgxpool.Pool, error) {
-	dbURL := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
-		dbConfig.User, dbConfig.Password, dbConfig.Host, dbConfig.Port, dbConfig.DBName, dbConfig.SSLMode)
+	pool, err := openPool(databaseURL(dbConfig), dbConfig)
+	if err != nil {
+		return nil, err
+	}
 
+	log.Info().Msg("Connected to database")
+	return pool, nil
+}
+
+// openPool opens and pings a connection pool for dbURL, sized by dbConfig
+func openPool(dbURL string, dbConfig config.DatabaseConfig) (*pgxpool.Pool, error) {
 	poolConfig, err := pgxpool.ParseConfig(dbURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse database config: %w", err)
 	}
 
-	poolConfig.MaxConns = 25
-	poolConfig.MinConns = 5
+	poolConfig.MaxConns = dbConfig.MaxConns
+	poolConfig.MinConns = dbConfig.MinConns
 
 	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
@@ -113,9 +217,33 @@ func connectToDatabase(dbConfig config.DatabaseConfig) (*pgxpool.Pool, error) {
 	}
 
 	if err := pool.Ping(context.Background()); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	log.Info().Msg("Connected to database")
 	return pool, nil
 }
+
+// printConfigError reports a failed config load, listing missing settings
+// apart from invalid ones when there are several
+func printConfigError(err error) {
+	var verr *config.ValidationError
+	if !errors.As(err, &verr) {
+		fmt.Printf("Error loading config: %v\n", err)
+		return
+	}
+
+	fmt.Println("Error loading config:")
+	if missing := verr.MissingProblems(); len(missing) > 0 {
+		fmt.Println("  Missing required settings:")
+		for _, p := range missing {
+			fmt.Printf("    - %s\n", p.Key)
+		}
+	}
+	if invalid := verr.InvalidProblems(); len(invalid) > 0 {
+		fmt.Println("  Invalid settings:")
+		for _, p := range invalid {
+			fmt.Printf("    - %s\n", p)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/rishavmehra/indexer/internal/service"
+)
+
+var errMigrateUsage = errors.New("usage: migrate up | migrate down N | migrate force V")
+
+// runMigrateCommand runs the migrate subcommand given by args, the arguments
+// after "migrate"
+func runMigrateCommand(migrator *service.Migrator, args []string) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+
+	switch args[0] {
+	case "up":
+		if len(args) != 1 {
+			return errMigrateUsage
+		}
+		if err := migrator.Up(); err != nil {
+			return err
+		}
+	case "down":
+		if len(args) != 2 {
+			return errMigrateUsage
+		}
+		steps, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid number of steps %q", args[1])
+		}
+		if err := migrator.Down(steps); err != nil {
+			return err
+		}
+	case "force":
+		if len(args) != 2 {
+			return errMigrateUsage
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		if err := migrator.Force(version); err != nil {
+			return err
+		}
+	default:
+		return errMigrateUsage
+	}
+
+	status, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("command", args[0]).
+		Uint("version", status.Version).
+		Bool("dirty", status.Dirty).
+		Msg("Migration command completed")
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rishavmehra/indexer/internal/service"
+)
+
+func TestRunMigrateCommandRejectsBadArguments(t *testing.T) {
+	// The arguments are checked before the database is opened, so an
+	// unreachable one is never dialled
+	migrator := service.NewMigrator("file://../../internal/db/migrations", "postgres://127.0.0.1:1/control")
+
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{args: nil, wantErr: errMigrateUsage.Error()},
+		{args: []string{"sideways"}, wantErr: errMigrateUsage.Error()},
+		{args: []string{"up", "1"}, wantErr: errMigrateUsage.Error()},
+		{args: []string{"down"}, wantErr: errMigrateUsage.Error()},
+		{args: []string{"down", "one"}, wantErr: `invalid number of steps "one"`},
+		{args: []string{"down", "0"}, wantErr: "steps must be positive"},
+		{args: []string{"force"}, wantErr: errMigrateUsage.Error()},
+		{args: []string{"force", "v3"}, wantErr: `invalid version "v3"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			err := runMigrateCommand(migrator, tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("runMigrateCommand(%q) error = %v, want %q", tt.args, err, tt.wantErr)
+			}
+			if tt.wantErr == errMigrateUsage.Error() && !errors.Is(err, errMigrateUsage) {
+				t.Errorf("runMigrateCommand(%q) error = %v, want the usage", tt.args, err)
+			}
+		})
+	}
+}
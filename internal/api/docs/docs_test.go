@@ -0,0 +1,164 @@
+package docs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// openAPISpec is the part of the spec the tests look at
+type openAPISpec struct {
+	OpenAPI    string                     `json:"openapi"`
+	Paths      map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func get(t *testing.T, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	RegisterRoutes(router)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	return recorder
+}
+
+func servedSpec(t *testing.T) openAPISpec {
+	t.Helper()
+
+	recorder := get(t, "/swagger/openapi.json")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", recorder.Code)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		t.Errorf("Content-Type = %q, want JSON", contentType)
+	}
+
+	var spec openAPISpec
+	if err := json.Unmarshal(recorder.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("spec does not parse: %v", err)
+	}
+	return spec
+}
+
+func TestSpecIsServedAndParses(t *testing.T) {
+	spec := servedSpec(t)
+
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("openapi = %q, want an OpenAPI 3 document", spec.OpenAPI)
+	}
+	for _, path := range []string{"/auth/login", "/users/db-credentials", "/indexers", "/indexers/{id}"} {
+		if _, ok := spec.Paths[path]; !ok {
+			t.Errorf("spec has no %s path", path)
+		}
+	}
+}
+
+func TestSwaggerUIIsServed(t *testing.T) {
+	recorder := get(t, "/swagger")
+
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "/swagger/openapi.json") {
+		t.Errorf("status = %d, want Swagger UI pointed at the spec", recorder.Code)
+	}
+}
+
+func TestSpecRefsResolve(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal(get(t, "/swagger/openapi.json").Body.Bytes(), &doc); err != nil {
+		t.Fatalf("unmarshal spec: %v", err)
+	}
+
+	// resolve follows a local reference such as #/components/schemas/Error
+	resolve := func(ref string) bool {
+		node := doc
+		for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			object, ok := node.(map[string]interface{})
+			if !ok {
+				return false
+			}
+			if node, ok = object[key]; !ok {
+				return false
+			}
+		}
+		return strings.HasPrefix(ref, "#/")
+	}
+
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for key, value := range v {
+				if ref, ok := value.(string); ok && key == "$ref" && !resolve(ref) {
+					t.Errorf("$ref %s does not resolve", ref)
+				}
+				walk(value)
+			}
+		case []interface{}:
+			for _, value := range v {
+				walk(value)
+			}
+		}
+	}
+	walk(doc)
+}
+
+// jsonFields returns the JSON names of a struct's fields
+func jsonFields(typ reflect.Type) []string {
+	var fields []string
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func TestSpecSchemasMatchModels(t *testing.T) {
+	spec := servedSpec(t)
+
+	for name, model := range map[string]interface{}{
+		"SignupRequest":        models.SignupRequest{},
+		"LoginRequest":         models.LoginRequest{},
+		"TokenResponse":        models.TokenResponse{},
+		"UserResponse":         models.UserResponse{},
+		"DBCredentialRequest":  models.DBCredentialRequest{},
+		"DBCredentialResponse": models.DBCredentialResponse{},
+		"CreateIndexerRequest": models.CreateIndexerRequest{},
+		"IndexerResponse":      models.IndexerResponse{},
+		"IndexingLogResponse":  models.IndexingLogResponse{},
+		"IndexerStatsResponse": models.IndexerStatsResponse{},
+		"CollectionStats":      models.CollectionStats{},
+	} {
+		schema, ok := spec.Components.Schemas[name]
+		if !ok {
+			t.Errorf("spec has no %s schema", name)
+			continue
+		}
+
+		var properties []string
+		for property := range schema.Properties {
+			properties = append(properties, property)
+		}
+		sort.Strings(properties)
+
+		if want := jsonFields(reflect.TypeOf(model)); !reflect.DeepEqual(properties, want) {
+			t.Errorf("%s properties = %v, want the model's JSON fields %v", name, properties, want)
+		}
+	}
+}
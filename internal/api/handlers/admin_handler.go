@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rishavmehra/indexer/internal/api/middleware"
+	"github.com/rishavmehra/indexer/internal/service"
+)
+
+// AdminHandler handles operator requests
+type AdminHandler struct {
+	indexerService *service.IndexerService
+	migrator       *service.Migrator
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(indexerService *service.IndexerService, migrator *service.Migrator) *AdminHandler {
+	return &AdminHandler{
+		indexerService: indexerService,
+		migrator:       migrator,
+	}
+}
+
+// RegisterRoutes registers the routes for the admin handler
+func (h *AdminHandler) RegisterRoutes(router *gin.RouterGroup, mw middleware.MiddlewareConfig) {
+	admin := router.Group("/admin")
+	admin.Use(mw.Admin)
+	{
+		admin.GET("/maintenance", h.GetMaintenance)
+		admin.PUT("/maintenance", h.SetMaintenance)
+		admin.GET("/webhooks", h.ListWebhooks)
+		admin.POST("/webhooks/reconcile", h.ReconcileWebhooks)
+		admin.GET("/migration-version", h.GetMigrationVersion)
+	}
+}
+
+// GetMaintenance returns whether indexing is paused for maintenance
+func (h *AdminHandler) GetMaintenance(c *gin.Context) {
+	c.JSON(http.StatusOK, h.indexerService.Maintenance().Status())
+}
+
+// SetMaintenance turns maintenance mode on or off
+func (h *AdminHandler) SetMaintenance(c *gin.Context) {
+	var req struct {
+		Enabled *bool  `json:"enabled" binding:"required"`
+		Reason  string `json:"reason"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	maintenance := h.indexerService.Maintenance()
+	maintenance.Set(*req.Enabled, req.Reason)
+
+	c.JSON(http.StatusOK, maintenance.Status())
+}
+
+// ListWebhooks lists the webhooks registered at Helius against the indexers
+// in the database, flagging orphaned webhooks and indexers whose webhook is gone
+func (h *AdminHandler) ListWebhooks(c *gin.Context) {
+	audit, err := h.indexerService.AuditWebhooks(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, service.ErrHeliusNotConfigured) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, audit)
+}
+
+// ReconcileWebhooks deletes orphaned Helius webhooks and fails indexers whose
+// webhook is gone. With ?dryRun=true it only reports what it would do.
+func (h *AdminHandler) ReconcileWebhooks(c *gin.Context) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dryRun", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dryRun must be true or false"})
+		return
+	}
+
+	result, err := h.indexerService.Reconcile(c.Request.Context(), dryRun)
+	if err != nil {
+		if errors.Is(err, service.ErrHeliusNotConfigured) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetMigrationVersion reports the migration the control database is at and
+// whether it is dirty
+func (h *AdminHandler) GetMigrationVersion(c *gin.Context) {
+	status, err := h.migrator.Version()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
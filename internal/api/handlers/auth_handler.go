@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/rishavmehra/indexer/internal/api/middleware"
 	"github.com/rishavmehra/indexer/internal/models"
 	"github.com/rishavmehra/indexer/internal/service"
 )
@@ -22,11 +24,14 @@ func NewAuthHandler(authService *service.AuthService) *AuthHandler {
 }
 
 // RegisterRoutes registers the routes for the auth handler
-func (h *AuthHandler) RegisterRoutes(router *gin.RouterGroup) {
+func (h *AuthHandler) RegisterRoutes(router *gin.RouterGroup, mw middleware.MiddlewareConfig) {
 	auth := router.Group("/auth")
 	{
-		auth.POST("/signup", h.Signup)
-		auth.POST("/login", h.Login)
+		auth.POST("/signup", mw.AuthRateLimit, h.Signup)
+		auth.POST("/login", mw.AuthRateLimit, h.Login)
+		auth.POST("/refresh", h.Refresh)
+		auth.POST("/logout", h.Logout)
+		auth.POST("/change-password", mw.Auth, h.ChangePassword)
 	}
 }
 
@@ -63,3 +68,63 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	c.JSON(http.StatusOK, token)
 }
+
+// Refresh exchanges a refresh token for a new access token
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	token, err := h.authService.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, token)
+}
+
+// Logout revokes a refresh token
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err := h.authService.Logout(c.Request.Context(), req.RefreshToken); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ChangePassword replaces the authenticated user's password
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	err = h.authService.ChangePassword(c.Request.Context(), userID, req)
+	if errors.Is(err, service.ErrInvalidCurrentPassword) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/rishavmehra/indexer/internal/api/middleware"
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/internal/service"
+	"github.com/rishavmehra/indexer/internal/testutil"
+)
+
+// createStore serves one user's DB credential and keeps the indexer
+// CreateIndexer writes
+type createStore struct {
+	db.Querier
+	cred    db.DbCredential
+	indexer db.Indexer
+}
+
+func (s *createStore) GetDBCredentialByID(ctx context.Context, id pgtype.UUID) (db.DbCredential, error) {
+	return s.cred, nil
+}
+
+func (s *createStore) GetUserByID(ctx context.Context, id pgtype.UUID) (db.User, error) {
+	return db.User{ID: id}, nil
+}
+
+func (s *createStore) CountIndexersByUserID(ctx context.Context, userID pgtype.UUID) (int64, error) {
+	return 0, nil
+}
+
+func (s *createStore) CreateIndexer(ctx context.Context, arg db.CreateIndexerParams) (db.Indexer, error) {
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	s.indexer = db.Indexer{
+		ID:             pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		UserID:         arg.UserID,
+		DbCredentialID: arg.DbCredentialID,
+		IndexerType:    arg.IndexerType,
+		Params:         arg.Params,
+		TargetTable:    arg.TargetTable,
+		Status:         arg.Status,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	return s.indexer, nil
+}
+
+func (s *createStore) UpdateIndexerStatus(ctx context.Context, arg db.UpdateIndexerStatusParams) (db.Indexer, error) {
+	s.indexer.Status = arg.Status
+	s.indexer.ErrorMessage = arg.ErrorMessage
+	return s.indexer, nil
+}
+
+func (s *createStore) UpsertIndexerWebhookSecret(ctx context.Context, arg db.UpsertIndexerWebhookSecretParams) (db.IndexerWebhookSecret, error) {
+	return db.IndexerWebhookSecret{IndexerID: arg.IndexerID, Secret: arg.Secret}, nil
+}
+
+func (s *createStore) CreateIndexingLog(ctx context.Context, arg db.CreateIndexingLogParams) (db.IndexingLog, error) {
+	return db.IndexingLog{}, nil
+}
+
+func (s *createStore) CreateAuditEvent(ctx context.Context, arg db.CreateAuditEventParams) error {
+	return nil
+}
+
+func newCreateStore(userID uuid.UUID) *createStore {
+	return &createStore{cred: db.DbCredential{
+		ID:     pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		UserID: pgtype.UUID{Bytes: userID, Valid: true},
+	}}
+}
+
+func createIndexerBody(t *testing.T, credID uuid.UUID, table string) *strings.Reader {
+	t.Helper()
+
+	body, err := json.Marshal(models.CreateIndexerRequest{
+		DBCredentialID: credID,
+		IndexerType:    models.NFTPrices,
+		TargetTable:    table,
+		Params:         json.RawMessage(`{"collection": "J1S9H3QjnRtBbbuD4HjPV6RpRhwuk4zKbxsnCHuTgh9w"}`),
+	})
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+	return strings.NewReader(string(body))
+}
+
+// testTarget points cred at the database in TEST_DATABASE_URL and returns a
+// table name that is dropped when the test ends
+func testTarget(t *testing.T, cred *db.DbCredential) string {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatalf("parse TEST_DATABASE_URL: %v", err)
+	}
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		t.Fatalf("connect to test database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	cred.DbHost = config.ConnConfig.Host
+	cred.DbPort = int32(config.ConnConfig.Port)
+	cred.DbName = config.ConnConfig.Database
+	cred.DbUser = config.ConnConfig.User
+	cred.DbPassword = config.ConnConfig.Password
+	cred.DbSslMode = "disable"
+	if config.ConnConfig.TLSConfig != nil {
+		cred.DbSslMode = "require"
+	}
+
+	table := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		pool.Exec(context.Background(), "DROP TABLE IF EXISTS "+indexer.QuoteTableName(table)+" CASCADE")
+	})
+	return table
+}
+
+func TestCreateIndexerRejectsMissingToken(t *testing.T) {
+	cfg := testutil.JWTConfig()
+	handler := NewIndexerHandler(service.NewIndexerService(newCreateStore(uuid.New()), nil), nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/indexers", middleware.AuthMiddleware(cfg), handler.CreateIndexer)
+
+	body := createIndexerBody(t, uuid.New(), "sales")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, testutil.NewRequest(http.MethodPost, "/indexers", body, ""))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("status without a token = %d, want 401", recorder.Code)
+	}
+
+	// A token signed with another secret is no better than none
+	otherSecret := cfg
+	otherSecret.Secret = "other-secret"
+	token, err := testutil.MintToken(otherSecret, uuid.New())
+	if err != nil {
+		t.Fatalf("MintToken: %v", err)
+	}
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, testutil.NewRequest(http.MethodPost, "/indexers", createIndexerBody(t, uuid.New(), "sales"), token))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("status with a foreign token = %d, want 401", recorder.Code)
+	}
+}
+
+func TestCreateIndexerRequiresUser(t *testing.T) {
+	handler := NewIndexerHandler(service.NewIndexerService(newCreateStore(uuid.New()), nil), nil)
+
+	c, recorder := testutil.NewContext(http.MethodPost, "/indexers", createIndexerBody(t, uuid.New(), "sales"))
+	handler.CreateIndexer(c)
+
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", recorder.Code)
+	}
+}
+
+func TestCreateIndexerRejectsInvalidTargetTable(t *testing.T) {
+	userID := uuid.New()
+	store := newCreateStore(userID)
+	handler := NewIndexerHandler(service.NewIndexerService(store, nil), nil)
+
+	c, recorder := testutil.NewAuthedContext(http.MethodPost, "/indexers", createIndexerBody(t, uuid.UUID(store.cred.ID.Bytes), "pg_sales"), userID)
+	handler.CreateIndexer(c)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400: %s", recorder.Code, recorder.Body)
+	}
+}
+
+func TestCreateIndexerCreatesIndexer(t *testing.T) {
+	userID := uuid.New()
+	store := newCreateStore(userID)
+	table := testTarget(t, &store.cred)
+
+	cfg := testutil.JWTConfig()
+	handler := NewIndexerHandler(service.NewIndexerService(store, nil), nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/indexers", middleware.AuthMiddleware(cfg), handler.CreateIndexer)
+
+	token, err := testutil.MintToken(cfg, userID)
+	if err != nil {
+		t.Fatalf("MintToken: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, testutil.NewRequest(http.MethodPost, "/indexers", createIndexerBody(t, uuid.UUID(store.cred.ID.Bytes), table), token))
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", recorder.Code, recorder.Body)
+	}
+
+	var resp models.IndexerResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.UserID != userID || resp.TargetTable != table || resp.Status != models.Active {
+		t.Errorf("response = %+v, want an active indexer on %s owned by the user", resp, table)
+	}
+}
+
+func TestCreateIndexerReportsFailedPreflight(t *testing.T) {
+	userID := uuid.New()
+	store := newCreateStore(userID)
+
+	// Nothing listens on a port that was just released
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	store.cred.DbHost = "127.0.0.1"
+	store.cred.DbPort = int32(listener.Addr().(*net.TCPAddr).Port)
+	store.cred.DbSslMode = "disable"
+	listener.Close()
+
+	handler := NewIndexerHandler(service.NewIndexerService(store, nil), nil)
+	c, recorder := testutil.NewAuthedContext(http.MethodPost, "/indexers", createIndexerBody(t, uuid.UUID(store.cred.ID.Bytes), "sales"), userID)
+	handler.CreateIndexer(c)
+
+	if recorder.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422: %s", recorder.Code, recorder.Body)
+	}
+	var resp struct {
+		Check string `json:"check"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil || resp.Check != "connect" {
+		t.Errorf("response %s, want the connect check named", recorder.Body)
+	}
+	if store.indexer.ID.Valid {
+		t.Error("indexer row created despite the failed preflight")
+	}
+}
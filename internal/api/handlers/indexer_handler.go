@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -15,56 +16,91 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/rishavmehra/indexer/internal/api/middleware"
+	"github.com/rishavmehra/indexer/internal/indexer"
 	"github.com/rishavmehra/indexer/internal/models"
 	"github.com/rishavmehra/indexer/internal/service"
+	"github.com/rishavmehra/indexer/pkg/validator"
 )
 
 // IndexerHandler handles indexer-related requests
 type IndexerHandler struct {
 	indexerService *service.IndexerService
+	dispatcher     *service.WebhookDispatcher
 }
 
-// heliusWebhookMapping maintains a mapping of Helius webhook IDs to indexer IDs
-var heliusWebhookMapping = make(map[string]string)
-
-// RegisterWebhookMapping adds a mapping between Helius webhook ID and indexer ID
+// RegisterWebhookMapping adds a mapping between Helius webhook ID and indexer
+// ID to the mappings the indexer package keeps
 func RegisterWebhookMapping(heliusWebhookID string, indexerID string) {
-	heliusWebhookMapping[heliusWebhookID] = indexerID
-	log.Info().
-		Str("heliusWebhookID", heliusWebhookID).
-		Str("indexerID", indexerID).
-		Msg("Registered webhook ID mapping")
+	indexer.RegisterWebhookMapping(heliusWebhookID, indexerID)
 }
 
 // GetIndexerIDFromHeliusWebhookID retrieves the indexer ID for a given Helius webhook ID
 func GetIndexerIDFromHeliusWebhookID(heliusWebhookID string) (string, bool) {
-	indexerID, found := heliusWebhookMapping[heliusWebhookID]
-	return indexerID, found
+	return indexer.GetIndexerIDFromHeliusWebhookID(heliusWebhookID)
 }
 
 // NewIndexerHandler creates a new indexer handler
-func NewIndexerHandler(indexerService *service.IndexerService) *IndexerHandler {
+func NewIndexerHandler(indexerService *service.IndexerService, dispatcher *service.WebhookDispatcher) *IndexerHandler {
 	return &IndexerHandler{
 		indexerService: indexerService,
+		dispatcher:     dispatcher,
 	}
 }
 
+// Drain waits for in-flight webhook processing to finish or for ctx to expire
+func (h *IndexerHandler) Drain(ctx context.Context) error {
+	return h.dispatcher.Drain(ctx)
+}
+
+// WebhookStats returns webhook processing counters
+func (h *IndexerHandler) WebhookStats() service.WebhookDispatcherStats {
+	return h.dispatcher.Stats()
+}
+
+// MetadataCacheStats returns token metadata cache usage
+func (h *IndexerHandler) MetadataCacheStats() indexer.MetadataCacheStats {
+	return h.indexerService.MetadataCacheStats()
+}
+
 // RegisterRoutes registers the routes for the indexer handler
 func (h *IndexerHandler) RegisterRoutes(router *gin.RouterGroup, mw middleware.MiddlewareConfig) {
 	indexers := router.Group("/indexers")
 	indexers.Use(mw.Auth)
 	{
 		indexers.GET("", h.GetIndexers)
-		indexers.POST("", h.CreateIndexer)
+		indexers.POST("", mw.CreateRateLimit, h.CreateIndexer)
+		indexers.POST("/validate", h.ValidateIndexer)
 		indexers.GET("/:id", h.GetIndexerByID)
+		indexers.PATCH("/:id", h.UpdateIndexer)
 		indexers.POST("/:id/pause", h.PauseIndexer)
 		indexers.POST("/:id/resume", h.ResumeIndexer)
 		indexers.DELETE("/:id", h.DeleteIndexer)
+		indexers.POST("/:id/restore", h.RestoreIndexer)
+		indexers.DELETE("/:id/last-error", h.ClearLastError)
 		indexers.GET("/:id/logs", h.GetIndexerLogs)
+		indexers.GET("/:id/logs/tail", h.TailIndexerLogs)
+		indexers.GET("/:id/stream", h.StreamIndexerEvents)
+		indexers.GET("/:id/stats", h.GetIndexerStats)
+		indexers.GET("/:id/health", h.GetIndexerHealth)
+		indexers.POST("/:id/reprocess", h.ReprocessIndexer)
+		indexers.POST("/:id/retarget", h.RetargetIndexer)
+		indexers.POST("/:id/reset", h.ResetIndexer)
+		indexers.POST("/:id/rotate-secret", h.RotateWebhookSecret)
+		indexers.POST("/:id/backfill", h.ResumeBackfill)
+		indexers.GET("/:id/failures", h.GetFailedPayloads)
+		indexers.POST("/:id/failures/:failureId/retry", h.RetryFailedPayload)
+		indexers.GET("/:id/price", h.GetTokenPrice)
+		indexers.GET("/:id/price/history", h.GetTokenPriceHistory)
+		indexers.GET("/:id/tokens", h.GetTokens)
 		indexers.GET("/debug/webhook/:webhookId", h.DebugWebhookIndexer)
 		indexers.POST("/test-process", h.TestProcessWebhook)
 	}
 
+	activity := router.Group("/activity")
+	activity.Use(mw.Auth)
+	{
+		activity.GET("", h.GetActivity)
+	}
 }
 
 // RegisterWebhookRoute registers the webhook route
@@ -163,6 +199,26 @@ func (h *IndexerHandler) CreateIndexer(c *gin.Context) {
 
 	indexer, err := h.indexerService.CreateIndexer(c.Request.Context(), userID, req)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidTargetTable) || errors.Is(err, service.ErrInvalidIndexerParams) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrAddressLimitExceeded) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrIndexerQuotaExceeded) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		var preflightErr *service.PreflightError
+		if errors.As(err, &preflightErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": err.Error(),
+				"check": preflightErr.Check,
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -170,6 +226,158 @@ func (h *IndexerHandler) CreateIndexer(c *gin.Context) {
 	c.JSON(http.StatusCreated, indexer)
 }
 
+// ValidateIndexer checks indexer params the way CreateIndexer would, without
+// touching the database or Helius, so clients can report problems inline
+func (h *IndexerHandler) ValidateIndexer(c *gin.Context) {
+	if _, err := middleware.GetUserID(c); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.ValidateIndexerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	resp := models.ValidateIndexerResponse{Valid: true}
+	if err := validator.ValidateIndexerParams(string(req.IndexerType), req.Params); err != nil {
+		resp.Valid = false
+		var paramsErr *validator.ParamsError
+		if errors.As(err, &paramsErr) {
+			resp.Problems = paramsErr.Problems
+		} else {
+			resp.Problems = []validator.ParamProblem{{Field: "params", Message: err.Error()}}
+		}
+	}
+	if err := h.indexerService.ValidatePollInterval(req.Params); err != nil {
+		resp.Valid = false
+		resp.Problems = append(resp.Problems, validator.ParamProblem{Field: "pollInterval", Message: err.Error()})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// UpdateIndexer replaces the params of an indexer
+func (h *IndexerHandler) UpdateIndexer(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	indexerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid indexer ID"})
+		return
+	}
+
+	var req models.UpdateIndexerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	indexer, err := h.indexerService.UpdateIndexerParams(c.Request.Context(), userID, indexerID, req.Params)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidIndexerParams) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrAddressLimitExceeded) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, indexer)
+}
+
+// RetargetIndexer moves an indexer to a new target table
+func (h *IndexerHandler) RetargetIndexer(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	indexerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid indexer ID"})
+		return
+	}
+
+	var req models.RetargetIndexerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	indexer, err := h.indexerService.RetargetIndexer(c.Request.Context(), userID, indexerID, req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidTargetTable) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrMaintenance) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		var preflightErr *service.PreflightError
+		if errors.As(err, &preflightErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": err.Error(),
+				"check": preflightErr.Check,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, indexer)
+}
+
+// ResetIndexer empties the target table of an indexer, keeping the table
+// and the indexer's webhook
+func (h *IndexerHandler) ResetIndexer(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	indexerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid indexer ID"})
+		return
+	}
+
+	var req models.ResetIndexerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	indexer, err := h.indexerService.ResetIndexer(c.Request.Context(), userID, indexerID, req.Confirm)
+	if err != nil {
+		if errors.Is(err, service.ErrResetNotConfirmed) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrMaintenance) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, indexer)
+}
+
 // GetIndexerByID returns an indexer by ID
 func (h *IndexerHandler) GetIndexerByID(c *gin.Context) {
 	userID, err := middleware.GetUserID(c)
@@ -232,6 +440,10 @@ func (h *IndexerHandler) ResumeIndexer(c *gin.Context) {
 
 	indexer, err := h.indexerService.ResumeIndexer(c.Request.Context(), userID, indexerID)
 	if err != nil {
+		if errors.Is(err, service.ErrIndexerQuotaExceeded) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -261,6 +473,95 @@ func (h *IndexerHandler) DeleteIndexer(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// RestoreIndexer brings back an indexer deleted within the delete retention
+func (h *IndexerHandler) RestoreIndexer(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	indexerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid indexer ID"})
+		return
+	}
+
+	indexer, err := h.indexerService.RestoreIndexer(c.Request.Context(), userID, indexerID)
+	if err != nil {
+		if errors.Is(err, service.ErrRestoreWindowPassed) {
+			c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, indexer)
+}
+
+// RotateWebhookSecret gives an indexer a new webhook secret
+func (h *IndexerHandler) RotateWebhookSecret(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	indexerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid indexer ID"})
+		return
+	}
+
+	indexer, err := h.indexerService.RotateWebhookSecret(c.Request.Context(), userID, indexerID)
+	if err != nil {
+		if errors.Is(err, service.ErrMaintenance) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, indexer)
+}
+
+// ResumeBackfill continues an indexer's backfill from where it stopped
+func (h *IndexerHandler) ResumeBackfill(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	indexerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid indexer ID"})
+		return
+	}
+
+	backfill, err := h.indexerService.ResumeBackfill(c.Request.Context(), userID, indexerID)
+	if err != nil {
+		if errors.Is(err, service.ErrMaintenance) || errors.Is(err, service.ErrHeliusNotConfigured) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrNoBackfill) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrIndexerNotActive) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, backfill)
+}
+
 // GetIndexerLogs returns logs for an indexer
 func (h *IndexerHandler) GetIndexerLogs(c *gin.Context) {
 	userID, err := middleware.GetUserID(c)
@@ -278,10 +579,14 @@ func (h *IndexerHandler) GetIndexerLogs(c *gin.Context) {
 	limit := int32(100)
 	offset := int32(0)
 
+	// Limits above the configured maximum are clamped by the service
 	if limitStr := c.Query("limit"); limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = int32(l)
+		l, err := strconv.ParseInt(limitStr, 10, 32)
+		if err != nil || l <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
 		}
+		limit = int32(l)
 	}
 
 	if offsetStr := c.Query("offset"); offsetStr != "" {
@@ -299,90 +604,570 @@ func (h *IndexerHandler) GetIndexerLogs(c *gin.Context) {
 	c.JSON(http.StatusOK, logs)
 }
 
-// HandleWebhook processes webhook requests from Helius
-func (h *IndexerHandler) HandleWebhook(c *gin.Context) {
-	webhookID := c.Query("id")
-
-	if webhookID == "" {
-		parts := strings.Split(c.Request.URL.Path, "/")
-		if len(parts) > 2 {
-			webhookID = parts[len(parts)-1]
-		}
-	}
-
-	if webhookID == "" {
-		log.Warn().Msg("No webhook ID provided in request")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing webhook ID"})
+// GetFailedPayloads lists the payloads an indexer gave up on after running
+// out of attempts
+func (h *IndexerHandler) GetFailedPayloads(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	log.Info().
-		Str("webhookID", webhookID).
-		Msg("Received webhook request")
-
-	body, err := io.ReadAll(c.Request.Body)
+	indexerID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to read request body")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid indexer ID"})
 		return
 	}
-	defer c.Request.Body.Close()
 
-	log.Debug().Str("rawPayload", string(body)).Msg("Received webhook payload")
+	limit := int32(100)
+	offset := int32(0)
 
-	if len(body) > 0 && body[0] == '[' {
-		var transactions []json.RawMessage
-		if err := json.Unmarshal(body, &transactions); err != nil {
-			log.Error().Err(err).Msg("Failed to parse transaction array")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid array format"})
+	if limitStr := c.Query("limit"); limitStr != "" {
+		l, err := strconv.ParseInt(limitStr, 10, 32)
+		if err != nil || l <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
 			return
 		}
+		limit = int32(l)
+	}
 
-		for _, txData := range transactions {
-			var tx map[string]interface{}
-			if err := json.Unmarshal(txData, &tx); err != nil {
-				log.Error().Err(err).Msg("Failed to parse transaction data")
-				continue
-			}
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = int32(o)
+		}
+	}
 
-			slot, _ := tx["slot"].(float64)
+	failures, err := h.indexerService.GetFailedPayloads(c.Request.Context(), userID, indexerID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-			enhancedDetails, _ := json.Marshal(tx)
+	c.JSON(http.StatusOK, failures)
+}
 
-			payload := models.HeliusWebhookPayload{
-				Slot: int64(slot),
-				Transaction: models.HeliusTransaction{
-					ID:              tx["signature"].(string),
-					Signatures:      []string{tx["signature"].(string)},
-					EnhancedDetails: enhancedDetails,
-				},
-			}
+// RetryFailedPayload processes a failed payload again, removing it from the
+// failures once it succeeds
+func (h *IndexerHandler) RetryFailedPayload(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
 
-			go func(p models.HeliusWebhookPayload) {
-				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-				defer cancel()
+	indexerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid indexer ID"})
+		return
+	}
 
-				if err := h.indexerService.ProcessWebhookPayload(ctx, webhookID, p); err != nil {
-					log.Error().Err(err).Str("webhookID", webhookID).Int64("slot", p.Slot).Msg("Failed to process")
-				}
-			}(payload)
-		}
-	} else {
-		var payload models.HeliusWebhookPayload
-		if err := json.Unmarshal(body, &payload); err != nil {
-			log.Error().Err(err).Msg("Failed to parse webhook payload")
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
-			return
+	failureID, err := strconv.ParseInt(c.Param("failureId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid failure ID"})
+		return
+	}
+
+	if err := h.indexerService.RetryFailedPayload(c.Request.Context(), userID, indexerID, failureID); err != nil {
+		if errors.Is(err, service.ErrFailedPayloadNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrFailedPayloadRetry) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrMaintenance) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// GetActivity returns the most recent events across all of the user's active indexers
+func (h *IndexerHandler) GetActivity(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	limit := service.DefaultActivityLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+		}
+	}
+
+	events, err := h.indexerService.GetActivity(c.Request.Context(), userID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+const (
+	defaultLogTailWait = 25 * time.Second
+	maxLogTailWait     = 60 * time.Second
+	logTailLimit       = 100
+)
+
+// TailIndexerLogs long-polls for logs written after the since and sinceId cursor
+func (h *IndexerHandler) TailIndexerLogs(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	indexerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid indexer ID"})
+		return
+	}
+
+	// Without a cursor only logs written from now on are returned
+	since := time.Now()
+	if sinceStr := c.Query("since"); sinceStr != "" {
+		since, err = time.Parse(time.RFC3339Nano, sinceStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since cursor, expected an RFC3339 timestamp"})
+			return
+		}
+	}
+
+	var sinceID uuid.UUID
+	if sinceIDStr := c.Query("sinceId"); sinceIDStr != "" {
+		sinceID, err = uuid.Parse(sinceIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sinceId cursor, expected a log ID"})
+			return
+		}
+	}
+
+	wait := defaultLogTailWait
+	if waitStr := c.Query("wait"); waitStr != "" {
+		if w, err := strconv.Atoi(waitStr); err == nil && w >= 0 {
+			wait = time.Duration(w) * time.Second
+		}
+	}
+	if wait > maxLogTailWait {
+		wait = maxLogTailWait
+	}
+
+	tail, err := h.indexerService.TailIndexingLogs(c.Request.Context(), userID, indexerID, since, sinceID, wait, logTailLimit)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tail)
+}
+
+// streamKeepAlive is how often an idle event stream sends a comment so
+// proxies do not close the connection
+const streamKeepAlive = 15 * time.Second
+
+// StreamIndexerEvents pushes processing events of an indexer as Server-Sent
+// Events until the client disconnects
+func (h *IndexerHandler) StreamIndexerEvents(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	indexerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid indexer ID"})
+		return
+	}
+
+	sub, err := h.indexerService.SubscribeEvents(c.Request.Context(), userID, indexerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.EventType, event)
+			return true
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// ClearLastError removes the recorded processing failure of an indexer
+func (h *IndexerHandler) ClearLastError(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	indexerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid indexer ID"})
+		return
+	}
+
+	indexer, err := h.indexerService.ClearLastError(c.Request.Context(), userID, indexerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, indexer)
+}
+
+// GetIndexerStats returns processing latency percentiles for an indexer
+func (h *IndexerHandler) GetIndexerStats(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	indexerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid indexer ID"})
+		return
+	}
+
+	stats, err := h.indexerService.GetIndexerStats(c.Request.Context(), userID, indexerID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetIndexerHealth reports whether an indexer is still receiving data
+func (h *IndexerHandler) GetIndexerHealth(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	indexerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid indexer ID"})
+		return
+	}
+
+	health, err := h.indexerService.GetIndexerHealth(c.Request.Context(), userID, indexerID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, health)
+}
+
+// ReprocessIndexer replays the stored webhook payloads of an indexer
+func (h *IndexerHandler) ReprocessIndexer(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	indexerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid indexer ID"})
+		return
+	}
+
+	var req models.ReprocessRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	result, err := h.indexerService.ReprocessPayloads(c.Request.Context(), userID, indexerID, req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidSlotRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrMaintenance) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetTokenPrice returns a token's volume-weighted price across platforms
+func (h *IndexerHandler) GetTokenPrice(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	indexerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid indexer ID"})
+		return
+	}
+
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token query parameter is required"})
+		return
+	}
+	if !validator.IsValidSolanaAddress(token) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token address format"})
+		return
+	}
+
+	price, err := h.indexerService.GetTokenPrice(c.Request.Context(), userID, indexerID, token)
+	if err != nil {
+		if errors.Is(err, service.ErrNotTokenPriceIndexer) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrTokenPriceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, price)
+}
+
+// GetTokenPriceHistory returns a token's recorded prices in OHLC buckets. The
+// interval defaults to 1h and the range to the last 24 hours.
+func (h *IndexerHandler) GetTokenPriceHistory(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	indexerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid indexer ID"})
+		return
+	}
+
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token query parameter is required"})
+		return
+	}
+	if !validator.IsValidSolanaAddress(token) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token address format"})
+		return
+	}
+
+	interval, err := time.ParseDuration(c.DefaultQuery("interval", "1h"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "interval must be a duration such as 15m or 1h"})
+		return
+	}
+
+	to := time.Now()
+	if raw := c.Query("to"); raw != "" {
+		if to, err = time.Parse(time.RFC3339, raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC 3339 timestamp"})
+			return
+		}
+	}
+	from := to.Add(-24 * time.Hour)
+	if raw := c.Query("from"); raw != "" {
+		if from, err = time.Parse(time.RFC3339, raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC 3339 timestamp"})
+			return
+		}
+	}
+
+	history, err := h.indexerService.GetTokenPriceHistory(c.Request.Context(), userID, indexerID, token, interval, from, to)
+	if err != nil {
+		if errors.Is(err, service.ErrNotTokenPriceIndexer) ||
+			errors.Is(err, service.ErrPriceHistoryDisabled) ||
+			errors.Is(err, service.ErrInvalidPriceHistoryRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// GetTokens returns the latest row of each token a token price indexer
+// tracks, optionally only from one platform
+func (h *IndexerHandler) GetTokens(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	indexerID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid indexer ID"})
+		return
+	}
+
+	tokens, err := h.indexerService.GetTokens(c.Request.Context(), userID, indexerID, c.Query("platform"))
+	if err != nil {
+		if errors.Is(err, service.ErrNotTokenPriceIndexer) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-		go func() {
-			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-			defer cancel()
+	c.JSON(http.StatusOK, tokens)
+}
 
-			if err := h.indexerService.ProcessWebhookPayload(ctx, webhookID, payload); err != nil {
-				log.Error().Err(err).Str("webhookID", webhookID).Int64("slot", payload.Slot).Msg("Failed to process")
+// HandleWebhook processes webhook requests from Helius
+func (h *IndexerHandler) HandleWebhook(c *gin.Context) {
+	// Helius retries non-2xx deliveries, so nothing is lost while paused
+	if h.indexerService.Maintenance().Enabled() {
+		c.Header("Retry-After", "60")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": service.ErrMaintenance.Error()})
+		return
+	}
+
+	webhookID := c.Query("id")
+
+	if webhookID == "" {
+		parts := strings.Split(c.Request.URL.Path, "/")
+		if len(parts) > 2 {
+			webhookID = parts[len(parts)-1]
+		}
+	}
+
+	if webhookID == "" {
+		log.Warn().Ctx(c.Request.Context()).Msg("No webhook ID provided in request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing webhook ID"})
+		return
+	}
+
+	key := c.Query("key")
+	if authHeader := c.GetHeader("Authorization"); key == "" && strings.HasPrefix(authHeader, "Bearer ") {
+		key = strings.TrimPrefix(authHeader, "Bearer ")
+	}
+
+	if err := h.indexerService.VerifyWebhookKey(c.Request.Context(), webhookID, key); err != nil {
+		if errors.Is(err, service.ErrInvalidWebhookKey) {
+			log.Warn().Ctx(c.Request.Context()).Str("webhookID", webhookID).Msg("Rejected webhook with an invalid key")
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid webhook key"})
+			return
+		}
+		log.Error().Ctx(c.Request.Context()).Err(err).Str("webhookID", webhookID).Msg("Failed to verify webhook key")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify webhook key"})
+		return
+	}
+
+	log.Info().Ctx(c.Request.Context()).
+		Str("webhookID", webhookID).
+		Msg("Received webhook request")
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to read request body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+	defer c.Request.Body.Close()
+
+	log.Debug().Ctx(c.Request.Context()).Str("rawPayload", string(body)).Msg("Received webhook payload")
+
+	var payloads []models.HeliusWebhookPayload
+	var received []service.ReceivedPayload
+
+	if len(body) > 0 && body[0] == '[' {
+		var transactions []json.RawMessage
+		if err := json.Unmarshal(body, &transactions); err != nil {
+			log.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to parse transaction array")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid array format"})
+			return
+		}
+
+		for _, txData := range transactions {
+			payload, ok := service.TransactionPayload(txData)
+			if !ok {
+				log.Warn().Ctx(c.Request.Context()).Msg("Skipping transaction without a signature")
+				continue
 			}
-		}()
+			payloads = append(payloads, payload)
+			received = append(received, service.ReceivedPayload{Payload: payload, Body: txData, Batched: true})
+		}
+	} else {
+		payload, err := service.WebhookPayload(body)
+		if err != nil {
+			log.Error().Ctx(c.Request.Context()).Err(err).Msg("Failed to parse webhook payload")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+			return
+		}
+
+		if len(payload.Transaction.Signatures) == 0 {
+			log.Warn().Ctx(c.Request.Context()).Msg("Skipping transaction without a signature")
+		} else {
+			payloads = append(payloads, payload)
+			received = append(received, service.ReceivedPayload{Payload: payload, Body: body})
+		}
+	}
+
+	if _, err := h.dispatcher.Dispatch(c.Request.Context(), webhookID, payloads); err != nil {
+		if errors.Is(err, service.ErrWebhookBackpressure) {
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Keep the payloads so they can be replayed after a parser fix. A batch
+	// rejected above is redelivered by Helius and kept then.
+	if err := h.indexerService.StoreRawPayloads(c.Request.Context(), webhookID, received); err != nil {
+		log.Warn().Ctx(c.Request.Context()).
+			Err(err).
+			Str("webhookID", webhookID).
+			Msg("Failed to store raw webhook payloads")
 	}
 
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
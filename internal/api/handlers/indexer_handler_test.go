@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/rishavmehra/indexer/internal/api/middleware"
+	"github.com/rishavmehra/indexer/internal/config"
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/service"
+	"github.com/rishavmehra/indexer/internal/testutil"
+	"github.com/rishavmehra/indexer/pkg/logger"
+)
+
+// webhookStore resolves every webhook to a paused indexer without a secret
+// of its own, and counts what the handler does with the store
+type webhookStore struct {
+	db.Querier
+	lookups atomic.Int32
+	stored  atomic.Int32
+
+	mu  sync.Mutex
+	raw []db.CreateRawPayloadParams
+}
+
+func (s *webhookStore) GetIndexerByWebhookID(ctx context.Context, webhookID pgtype.Text) (db.Indexer, error) {
+	s.lookups.Add(1)
+	return db.Indexer{Status: db.IndexerStatusPaused}, nil
+}
+
+func (s *webhookStore) GetIndexerWebhookSecret(ctx context.Context, indexerID pgtype.UUID) (db.IndexerWebhookSecret, error) {
+	return db.IndexerWebhookSecret{}, pgx.ErrNoRows
+}
+
+func (s *webhookStore) CreateRawPayload(ctx context.Context, arg db.CreateRawPayloadParams) error {
+	s.stored.Add(1)
+	s.mu.Lock()
+	s.raw = append(s.raw, arg)
+	s.mu.Unlock()
+	return nil
+}
+
+func newWebhookHandler(store db.Querier) (*IndexerHandler, *service.IndexerService) {
+	indexerService := service.NewIndexerService(store, nil)
+	dispatcher := service.NewWebhookDispatcher(indexerService, config.WebhookConfig{MaxConcurrency: 1})
+	return NewIndexerHandler(indexerService, dispatcher), indexerService
+}
+
+const webhookBody = `{"slot": 1, "transaction": {"signatures": ["sig"]}}`
+
+func TestHandleWebhookRejectsDuringMaintenance(t *testing.T) {
+	store := &webhookStore{}
+	handler, indexerService := newWebhookHandler(store)
+	indexerService.Maintenance().Set(true, "migration")
+
+	c, recorder := testutil.NewContext(http.MethodPost, "/webhooks?id=webhook", strings.NewReader(webhookBody))
+	handler.HandleWebhook(c)
+
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", recorder.Code)
+	}
+	if recorder.Header().Get("Retry-After") == "" {
+		t.Error("no Retry-After header, want Helius told when to redeliver")
+	}
+	if store.lookups.Load() != 0 || store.stored.Load() != 0 {
+		t.Errorf("store used %d lookups and %d writes during maintenance, want none", store.lookups.Load(), store.stored.Load())
+	}
+}
+
+func TestHandleWebhookProcessesOutsideMaintenance(t *testing.T) {
+	store := &webhookStore{}
+	handler, indexerService := newWebhookHandler(store)
+	indexerService.Maintenance().Set(true, "migration")
+	indexerService.Maintenance().Set(false, "")
+
+	c, recorder := testutil.NewContext(http.MethodPost, "/webhooks?id=webhook", strings.NewReader(webhookBody))
+	handler.HandleWebhook(c)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := handler.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+
+	if store.stored.Load() != 1 {
+		t.Errorf("stored %d raw payloads, want 1", store.stored.Load())
+	}
+	// The key check, the raw payload and the processing each look the indexer up
+	if store.lookups.Load() < 3 {
+		t.Errorf("indexer looked up %d times, want the payload processed", store.lookups.Load())
+	}
+}
+
+func TestHandleWebhookKeepsOnlyDispatchedPayloads(t *testing.T) {
+	store := &webhookStore{}
+	indexerService := service.NewIndexerService(store, nil)
+	dispatcher := service.NewWebhookDispatcher(indexerService, config.WebhookConfig{MaxConcurrency: 1, FairQueueCapacity: 1})
+	handler := NewIndexerHandler(indexerService, dispatcher)
+
+	// A drained dispatcher rejects every batch
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := handler.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+
+	c, recorder := testutil.NewContext(http.MethodPost, "/webhooks?id=webhook", strings.NewReader(webhookBody))
+	handler.HandleWebhook(c)
+
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429: %s", recorder.Code, recorder.Body)
+	}
+	if store.stored.Load() != 0 {
+		t.Errorf("stored %d raw payloads of a rejected batch, want none so a redelivery is not kept twice", store.stored.Load())
+	}
+}
+
+func TestHandleWebhookKeepsTransactionsAsSent(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantBody    string
+		wantBatched bool
+	}{
+		{
+			name:        "array",
+			body:        `[{"signature": "sig", "slot": 1, "unmapped": [1, 2]}]`,
+			wantBody:    `{"signature": "sig", "slot": 1, "unmapped": [1, 2]}`,
+			wantBatched: true,
+		},
+		{
+			name:     "single object",
+			body:     `{"slot": 1, "transaction": {"signatures": ["sig"]}, "unmapped": "kept"}`,
+			wantBody: `{"slot": 1, "transaction": {"signatures": ["sig"]}, "unmapped": "kept"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &webhookStore{}
+			handler, _ := newWebhookHandler(store)
+
+			c, recorder := testutil.NewContext(http.MethodPost, "/webhooks?id=webhook", strings.NewReader(tt.body))
+			handler.HandleWebhook(c)
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := handler.Drain(ctx); err != nil {
+				t.Fatalf("Drain: %v", err)
+			}
+
+			store.mu.Lock()
+			defer store.mu.Unlock()
+			if len(store.raw) != 1 {
+				t.Fatalf("stored %d raw payloads, want 1", len(store.raw))
+			}
+			if string(store.raw[0].Body) != tt.wantBody || store.raw[0].Batched != tt.wantBatched {
+				t.Errorf("stored %s (batched %v), want %s (batched %v)", store.raw[0].Body, store.raw[0].Batched, tt.wantBody, tt.wantBatched)
+			}
+		})
+	}
+}
+
+// logBuffer collects log lines written from several goroutines
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// lines returns the JSON log lines written so far
+func (b *logBuffer) lines(t *testing.T) []map[string]interface{} {
+	t.Helper()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(b.buf.Bytes()))
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("log line %q is not JSON: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// captureLogs sends the global logger to a buffer for the rest of the test
+func captureLogs(t *testing.T) *logBuffer {
+	t.Helper()
+
+	buf := &logBuffer{}
+	previous := log.Logger
+	log.Logger = zerolog.New(buf).Hook(logger.RequestIDHook{})
+	t.Cleanup(func() { log.Logger = previous })
+	return buf
+}
+
+func TestHandleWebhookLogsCarryRequestID(t *testing.T) {
+	logs := captureLogs(t)
+	handler, _ := newWebhookHandler(&webhookStore{})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.RequestID())
+	router.POST("/webhooks", handler.HandleWebhook)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks?id=webhook", strings.NewReader(webhookBody))
+	req.Header.Set(middleware.RequestIDHeader, "req-123")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := handler.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+
+	// The handler logs the request and the dispatcher, on its own goroutine,
+	// logs that the paused indexer's payload could not be processed
+	messages := make(map[string]interface{})
+	for _, line := range logs.lines(t) {
+		message, _ := line["message"].(string)
+		messages[message] = line["request_id"]
+	}
+	for _, message := range []string{"Received webhook request", "Failed to process"} {
+		requestID, ok := messages[message]
+		if !ok {
+			t.Errorf("no %q log line", message)
+		} else if requestID != "req-123" {
+			t.Errorf("%q logged with request_id %v, want req-123", message, requestID)
+		}
+	}
+}
+
+func TestHandleWebhookSkipsTransactionsWithoutSignature(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantKept  int32
+		wantSkips int
+	}{
+		{
+			name:      "array",
+			body:      `[{"slot": 1}, {"slot": 2, "signature": 42}, {"slot": 3, "signature": "sig"}, "not an object"]`,
+			wantKept:  1,
+			wantSkips: 3,
+		},
+		{
+			name:      "raw array",
+			body:      `[{"slot": 4, "transaction": {"signatures": ["raw-sig"]}}, {"slot": 5, "transaction": {"signatures": []}}]`,
+			wantKept:  1,
+			wantSkips: 1,
+		},
+		{
+			name:      "single object",
+			body:      `{"slot": 6, "transaction": {}}`,
+			wantKept:  0,
+			wantSkips: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLogs(t)
+			store := &webhookStore{}
+			handler, _ := newWebhookHandler(store)
+
+			c, recorder := testutil.NewContext(http.MethodPost, "/webhooks?id=webhook", strings.NewReader(tt.body))
+			handler.HandleWebhook(c)
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := handler.Drain(ctx); err != nil {
+				t.Fatalf("Drain: %v", err)
+			}
+
+			if store.stored.Load() != tt.wantKept {
+				t.Errorf("kept %d transactions, want %d", store.stored.Load(), tt.wantKept)
+			}
+			skips := 0
+			for _, line := range logs.lines(t) {
+				if line["message"] == "Skipping transaction without a signature" {
+					skips++
+				}
+			}
+			if skips != tt.wantSkips {
+				t.Errorf("logged %d skips, want %d", skips, tt.wantSkips)
+			}
+		})
+	}
+}
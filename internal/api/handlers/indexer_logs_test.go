@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/rishavmehra/indexer/internal/testutil"
+)
+
+func TestGetIndexerLogsRejectsBadLimit(t *testing.T) {
+	handler, _ := newWebhookHandler(&webhookStore{})
+
+	for _, limit := range []string{"0", "-5", "lots", "100000000000"} {
+		c, recorder := testutil.NewAuthedContext(http.MethodGet, "/indexers/id/logs?limit="+limit, nil, uuid.New())
+		testutil.WithParams(c, map[string]string{"id": uuid.NewString()})
+
+		handler.GetIndexerLogs(c)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("limit=%s: status = %d, want 400", limit, recorder.Code)
+		}
+	}
+}
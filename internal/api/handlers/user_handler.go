@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -33,6 +34,7 @@ func (h *UserHandler) RegisterRoutes(router *gin.RouterGroup, mw middleware.Midd
 	users.Use(mw.Auth)
 	{
 		users.GET("/me", h.GetCurrentUser)
+		users.GET("/me/audit", h.GetAuditEvents)
 		dbCreds := users.Group("/db-credentials")
 		{
 			dbCreds.GET("", h.GetDBCredentials)
@@ -62,6 +64,43 @@ func (h *UserHandler) GetCurrentUser(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
+// GetAuditEvents returns the changes the current user made to their indexers
+// and credentials, newest first
+func (h *UserHandler) GetAuditEvents(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	limit := int32(100)
+	offset := int32(0)
+
+	// Limits above the maximum are clamped by the service
+	if limitStr := c.Query("limit"); limitStr != "" {
+		l, err := strconv.ParseInt(limitStr, 10, 32)
+		if err != nil || l <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = int32(l)
+	}
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = int32(o)
+		}
+	}
+
+	events, err := h.userService.GetAuditEvents(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
 // GetDBCredentials returns all database credentials for the user
 func (h *UserHandler) GetDBCredentials(c *gin.Context) {
 	userID, err := middleware.GetUserID(c)
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/internal/testutil"
+)
+
+func TestValidateIndexer(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantValid    bool
+		wantProblems []string
+	}{
+		{
+			name:      "valid",
+			body:      `{"indexerType": "nft_prices", "params": {"collection": "J1S9H3QjnRtBbbuD4HjPV6RpRhwuk4zKbxsnCHuTgh9w", "marketplaces": ["MAGIC_EDEN"]}}`,
+			wantValid: true,
+		},
+		{
+			name:         "bad collection and unknown marketplace",
+			body:         `{"indexerType": "nft_prices", "params": {"collection": "not-an-address", "marketplaces": ["NOT_A_MARKET"]}}`,
+			wantProblems: []string{"collection", "marketplaces"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &webhookStore{}
+			handler, _ := newWebhookHandler(store)
+
+			c, recorder := testutil.NewAuthedContext(http.MethodPost, "/indexers/validate", strings.NewReader(tt.body), uuid.New())
+			handler.ValidateIndexer(c)
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body)
+			}
+			var resp models.ValidateIndexerResponse
+			if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.Valid != tt.wantValid {
+				t.Errorf("valid = %v, want %v", resp.Valid, tt.wantValid)
+			}
+			if len(resp.Problems) != len(tt.wantProblems) {
+				t.Fatalf("problems = %+v, want fields %v", resp.Problems, tt.wantProblems)
+			}
+			for i, field := range tt.wantProblems {
+				if resp.Problems[i].Field != field {
+					t.Errorf("problem %d is on %q, want %q", i, resp.Problems[i].Field, field)
+				}
+			}
+			if store.lookups.Load() != 0 || store.stored.Load() != 0 {
+				t.Error("validation touched the store")
+			}
+		})
+	}
+}
+
+func TestValidateIndexerRequiresUser(t *testing.T) {
+	handler, _ := newWebhookHandler(&webhookStore{})
+
+	c, recorder := testutil.NewContext(http.MethodPost, "/indexers/validate", strings.NewReader(`{"indexerType": "nft_prices", "params": {}}`))
+	handler.ValidateIndexer(c)
+
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", recorder.Code)
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/testutil"
+)
+
+// keyedWebhookStore resolves webhooks like webhookStore, but its indexer has
+// a secret of its own
+type keyedWebhookStore struct {
+	webhookStore
+	secret string
+}
+
+func (s *keyedWebhookStore) GetIndexerWebhookSecret(ctx context.Context, indexerID pgtype.UUID) (db.IndexerWebhookSecret, error) {
+	return db.IndexerWebhookSecret{IndexerID: indexerID, Secret: s.secret}, nil
+}
+
+// unknownWebhookStore resolves no webhook to an indexer
+type unknownWebhookStore struct {
+	webhookStore
+}
+
+func (s *unknownWebhookStore) GetIndexerByWebhookID(ctx context.Context, webhookID pgtype.Text) (db.Indexer, error) {
+	return db.Indexer{}, pgx.ErrNoRows
+}
+
+func TestHandleWebhookVerifiesKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		bearer     string
+		wantStatus int
+	}{
+		{name: "right key", path: "/webhooks?id=webhook&key=indexer-secret", wantStatus: http.StatusOK},
+		{name: "right key in header", path: "/webhooks?id=webhook", bearer: "indexer-secret", wantStatus: http.StatusOK},
+		{name: "wrong key", path: "/webhooks?id=webhook&key=wrong", wantStatus: http.StatusUnauthorized},
+		{name: "no key", path: "/webhooks?id=webhook", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &keyedWebhookStore{secret: "indexer-secret"}
+			handler, _ := newWebhookHandler(store)
+
+			c, recorder := testutil.NewContext(http.MethodPost, tt.path, strings.NewReader(webhookBody))
+			if tt.bearer != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			handler.HandleWebhook(c)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := handler.Drain(ctx); err != nil {
+				t.Fatalf("Drain: %v", err)
+			}
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			wantStored := int32(0)
+			if tt.wantStatus == http.StatusOK {
+				wantStored = 1
+			}
+			if store.stored.Load() != wantStored {
+				t.Errorf("stored %d raw payloads, want %d", store.stored.Load(), wantStored)
+			}
+		})
+	}
+}
+
+func TestHandleWebhookRejectsUnknownWebhook(t *testing.T) {
+	store := &unknownWebhookStore{}
+	handler, _ := newWebhookHandler(store)
+
+	c, recorder := testutil.NewContext(http.MethodPost, "/webhooks?id=unknown&key=anything", strings.NewReader(webhookBody))
+	handler.HandleWebhook(c)
+
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401: %s", recorder.Code, recorder.Body)
+	}
+	if store.stored.Load() != 0 {
+		t.Errorf("stored %d raw payloads for an unknown webhook, want none", store.stored.Load())
+	}
+}
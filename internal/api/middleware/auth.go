@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"errors"
 	"fmt"
 	"net/http"
@@ -15,12 +16,22 @@ import (
 )
 
 type MiddlewareConfig struct {
-	Auth gin.HandlerFunc
+	Auth  gin.HandlerFunc
+	Admin gin.HandlerFunc
+	// AuthRateLimit limits login and signup attempts per client IP
+	AuthRateLimit gin.HandlerFunc
+	// CreateRateLimit limits indexer creation per user
+	CreateRateLimit gin.HandlerFunc
 }
 
-func NewMiddlewareConfig(jwtConfig config.JWTConfig) MiddlewareConfig {
+func NewMiddlewareConfig(jwtConfig config.JWTConfig, adminConfig config.AdminConfig, rateLimitConfig config.RateLimitConfig) MiddlewareConfig {
 	return MiddlewareConfig{
-		Auth: AuthMiddleware(jwtConfig),
+		Auth:  AuthMiddleware(jwtConfig),
+		Admin: AdminMiddleware(adminConfig),
+		AuthRateLimit: RateLimit(
+			NewRateLimiter(rateLimitConfig.AuthPerMinute, rateLimitConfig.AuthBurst), ClientIPKey),
+		CreateRateLimit: RateLimit(
+			NewRateLimiter(rateLimitConfig.CreatePerMinute, rateLimitConfig.CreateBurst), UserKey),
 	}
 }
 
@@ -50,7 +61,7 @@ func AuthMiddleware(cfg config.JWTConfig) gin.HandlerFunc {
 		})
 
 		if err != nil {
-			log.Error().Err(err).Str("token", tokenString).Msg("Failed to parse token")
+			log.Error().Err(err).Msg("Failed to parse token")
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			return
 		}
@@ -72,6 +83,26 @@ func AuthMiddleware(cfg config.JWTConfig) gin.HandlerFunc {
 	}
 }
 
+// AdminMiddleware checks the X-Admin-Key header against the configured admin
+// API key. Every request is rejected when no key is configured.
+func AdminMiddleware(cfg config.AdminConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cfg.APIKey == "" {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Admin endpoints are disabled"})
+			return
+		}
+
+		key := c.GetHeader("X-Admin-Key")
+		if subtle.ConstantTimeCompare([]byte(key), []byte(cfg.APIKey)) != 1 {
+			log.Warn().Str("clientIP", c.ClientIP()).Msg("Rejected admin request with invalid key")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid admin key"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // GetUserID extracts the user ID from the context
 func GetUserID(c *gin.Context) (uuid.UUID, error) {
 	userID, exists := c.Get("userID")
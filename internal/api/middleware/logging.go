@@ -32,6 +32,7 @@ func Logger() gin.HandlerFunc {
 		switch {
 		case statusCode >= 500:
 			log.Error().
+				Ctx(c.Request.Context()).
 				Str("method", method).
 				Str("path", path).
 				Int("status", statusCode).
@@ -41,6 +42,7 @@ func Logger() gin.HandlerFunc {
 				Msg("HTTP Request")
 		case statusCode >= 400:
 			log.Warn().
+				Ctx(c.Request.Context()).
 				Str("method", method).
 				Str("path", path).
 				Int("status", statusCode).
@@ -50,6 +52,7 @@ func Logger() gin.HandlerFunc {
 				Msg("HTTP Request")
 		default:
 			log.Info().
+				Ctx(c.Request.Context()).
 				Str("method", method).
 				Str("path", path).
 				Int("status", statusCode).
@@ -67,6 +70,7 @@ func Recovery() gin.HandlerFunc {
 		defer func() {
 			if err := recover(); err != nil {
 				log.Error().
+					Ctx(c.Request.Context()).
 					Str("method", c.Request.Method).
 					Str("path", c.Request.URL.Path).
 					Str("ip", c.ClientIP()).
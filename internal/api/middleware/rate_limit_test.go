@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// fakeClock is a settable time source for a RateLimiter
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestLimiter(perMinute, burst int) (*RateLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	limiter := NewRateLimiter(perMinute, burst)
+	limiter.now = clock.Now
+	limiter.lastSweep = clock.now
+	return limiter, clock
+}
+
+func newRateLimitedRouter(limiter *RateLimiter) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/auth/login", RateLimit(limiter, ClientIPKey), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func login(router *gin.Engine, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+	req.RemoteAddr = ip + ":1234"
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestRateLimitRejectsOnceBucketIsEmpty(t *testing.T) {
+	limiter, clock := newTestLimiter(6, 3)
+	router := newRateLimitedRouter(limiter)
+
+	for i := 0; i < 3; i++ {
+		if recorder := login(router, "10.0.0.1"); recorder.Code != http.StatusOK {
+			t.Fatalf("request %d status = %d, want 200 within the burst", i+1, recorder.Code)
+		}
+	}
+
+	recorder := login(router, "10.0.0.1")
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429 once the burst is used up", recorder.Code)
+	}
+	// Six a minute refill a token every ten seconds
+	if retryAfter := recorder.Header().Get("Retry-After"); retryAfter != "10" {
+		t.Errorf("Retry-After = %q, want 10", retryAfter)
+	}
+
+	if recorder := login(router, "10.0.0.2"); recorder.Code != http.StatusOK {
+		t.Errorf("another client got status %d, want its own bucket", recorder.Code)
+	}
+
+	clock.now = clock.now.Add(10 * time.Second)
+	if recorder := login(router, "10.0.0.1"); recorder.Code != http.StatusOK {
+		t.Errorf("status after the refill = %d, want 200", recorder.Code)
+	}
+	if recorder := login(router, "10.0.0.1"); recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want only the one refilled token available", recorder.Code)
+	}
+}
+
+func TestRateLimitSweepsFullBuckets(t *testing.T) {
+	limiter, clock := newTestLimiter(60, 1)
+
+	limiter.Allow("ip:10.0.0.1")
+	clock.now = clock.now.Add(rateLimitSweepInterval)
+	limiter.Allow("ip:10.0.0.2")
+
+	if _, ok := limiter.buckets["ip:10.0.0.1"]; ok {
+		t.Error("refilled bucket kept after the sweep")
+	}
+}
+
+func TestNilRateLimiterAllowsEverything(t *testing.T) {
+	if limiter := NewRateLimiter(0, 10); limiter != nil {
+		t.Fatal("NewRateLimiter(0) returned a limiter, want nil to disable limiting")
+	}
+
+	router := newRateLimitedRouter(nil)
+	for i := 0; i < 100; i++ {
+		if recorder := login(router, "10.0.0.1"); recorder.Code != http.StatusOK {
+			t.Fatalf("request %d status = %d, want 200 without a limit", i+1, recorder.Code)
+		}
+	}
+}
+
+func TestUserKeyPrefersAuthenticatedUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/indexers", nil)
+	c.Request.RemoteAddr = "10.0.0.1:1234"
+
+	if key := UserKey(c); key != "ip:10.0.0.1" {
+		t.Errorf("UserKey without a user = %q, want the client IP", key)
+	}
+
+	userID := uuid.New()
+	c.Set("userID", userID)
+	if key := UserKey(c); key != "user:"+userID.String() {
+		t.Errorf("UserKey = %q, want the user ID", key)
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rishavmehra/indexer/pkg/logger"
+)
+
+// requestIDRouter answers with the request ID found in the request context
+func requestIDRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, logger.RequestID(c.Request.Context()))
+	})
+	return router
+}
+
+func TestRequestIDReusesCallerID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "req-123")
+	recorder := httptest.NewRecorder()
+	requestIDRouter().ServeHTTP(recorder, req)
+
+	if got := recorder.Header().Get(RequestIDHeader); got != "req-123" {
+		t.Errorf("response %s = %q, want req-123", RequestIDHeader, got)
+	}
+	if recorder.Body.String() != "req-123" {
+		t.Errorf("context request ID = %q, want req-123", recorder.Body.String())
+	}
+}
+
+func TestRequestIDReplacesMissingOrMalformedID(t *testing.T) {
+	for _, sent := range []string{"", "has spaces", "new\nline"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if sent != "" {
+			req.Header[RequestIDHeader] = []string{sent}
+		}
+		recorder := httptest.NewRecorder()
+		requestIDRouter().ServeHTTP(recorder, req)
+
+		got := recorder.Header().Get(RequestIDHeader)
+		if got == "" || got == sent {
+			t.Errorf("sent %q, got request ID %q, want a generated one", sent, got)
+		}
+		if recorder.Body.String() != got {
+			t.Errorf("context request ID = %q, want the echoed %q", recorder.Body.String(), got)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"github.com/gin-gonic/gin"
 
+	"github.com/rishavmehra/indexer/internal/api/docs"
 	"github.com/rishavmehra/indexer/internal/api/handlers"
 	"github.com/rishavmehra/indexer/internal/api/middleware"
 )
@@ -13,8 +14,10 @@ func SetupRoutes(
 	authHandler *handlers.AuthHandler,
 	userHandler *handlers.UserHandler,
 	indexerHandler *handlers.IndexerHandler,
+	adminHandler *handlers.AdminHandler,
 	mw middleware.MiddlewareConfig,
 ) {
+	router.Use(middleware.RequestID())
 	router.Use(middleware.Logger())
 	router.Use(middleware.Recovery())
 	router.Use(gin.Recovery())
@@ -22,8 +25,8 @@ func SetupRoutes(
 	router.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Request-ID, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -39,11 +42,21 @@ func SetupRoutes(
 		})
 	})
 
+	router.GET("/metrics", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"tokenMetadataCache": indexerHandler.MetadataCacheStats(),
+			"webhooks":           indexerHandler.WebhookStats(),
+		})
+	})
+
+	docs.RegisterRoutes(router)
+
 	v1 := router.Group("/api/v1")
 	{
-		authHandler.RegisterRoutes(v1)
+		authHandler.RegisterRoutes(v1, mw)
 		userHandler.RegisterRoutes(v1, mw)
 		indexerHandler.RegisterRoutes(v1, mw)
+		adminHandler.RegisterRoutes(v1, mw)
 	}
 
 	indexerHandler.RegisterWebhookRoute(router)
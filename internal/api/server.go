@@ -29,6 +29,13 @@ func NewServer(cfg config.ServerConfig) *Server {
 	}
 
 	router := gin.New()
+	// Only the configured proxies may set the client IP through
+	// X-Forwarded-For; otherwise it is the connection's remote address, so
+	// clients cannot pick their own rate limit bucket
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Error().Err(err).Msg("Invalid trusted proxies, trusting none")
+		router.SetTrustedProxies(nil)
+	}
 
 	return &Server{
 		router: router,
@@ -41,31 +48,38 @@ func (s *Server) Router() *gin.Engine {
 	return s.router
 }
 
-// Start starts the server
+// Start starts the server and blocks until it receives SIGINT or SIGTERM,
+// then stops accepting connections and waits up to the configured shutdown
+// timeout for in-flight requests to complete
 func (s *Server) Start() error {
 	s.server = &http.Server{
 		Addr:    fmt.Sprintf(":%s", s.cfg.Port),
 		Handler: s.router,
 	}
 
+	errCh := make(chan error, 1)
+
 	// Start the server in a goroutine
 	go func() {
 		log.Info().Str("port", s.cfg.Port).Msg("Starting server")
 		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatal().Err(err).Msg("Failed to start server")
+			errCh <- err
 		}
 	}()
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	log.Info().Msg("Shutting down server...")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := s.server.Shutdown(ctx); err != nil {
-		log.Fatal().Err(err).Msg("Server forced to shutdown")
-		return err
+	defer signal.Stop(quit)
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("failed to start server: %w", err)
+	case sig := <-quit:
+		log.Info().Str("signal", sig.String()).Msg("Shutting down server...")
+	}
+
+	if err := s.Stop(); err != nil {
+		return fmt.Errorf("server forced to shutdown: %w", err)
 	}
 
 	log.Info().Msg("Server shutdown gracefully")
@@ -75,9 +89,17 @@ func (s *Server) Start() error {
 // Stop stops the server
 func (s *Server) Stop() error {
 	if s.server != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout())
 		defer cancel()
 		return s.server.Shutdown(ctx)
 	}
 	return nil
 }
+
+// shutdownTimeout returns the configured drain timeout, falling back to 5 seconds
+func (s *Server) shutdownTimeout() time.Duration {
+	if s.cfg.ShutdownTimeout > 0 {
+		return s.cfg.ShutdownTimeout
+	}
+	return 5 * time.Second
+}
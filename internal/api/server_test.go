@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rishavmehra/indexer/internal/config"
+)
+
+func clientIP(t *testing.T, cfg config.ServerConfig, remoteAddr, forwardedFor string) string {
+	t.Helper()
+
+	server := NewServer(cfg)
+	var ip string
+	server.Router().GET("/ip", func(c *gin.Context) {
+		ip = c.ClientIP()
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("X-Forwarded-For", forwardedFor)
+	server.Router().ServeHTTP(httptest.NewRecorder(), req)
+	return ip
+}
+
+func TestNewServerIgnoresForwardedForByDefault(t *testing.T) {
+	if ip := clientIP(t, config.ServerConfig{}, "10.0.0.1:1234", "203.0.113.7"); ip != "10.0.0.1" {
+		t.Errorf("client IP = %q, want the remote address 10.0.0.1", ip)
+	}
+}
+
+func TestNewServerTrustsConfiguredProxies(t *testing.T) {
+	cfg := config.ServerConfig{TrustedProxies: []string{"10.0.0.0/8"}}
+	if ip := clientIP(t, cfg, "10.0.0.1:1234", "203.0.113.7"); ip != "203.0.113.7" {
+		t.Errorf("client IP = %q, want the forwarded 203.0.113.7", ip)
+	}
+	if ip := clientIP(t, cfg, "192.0.2.1:1234", "203.0.113.7"); ip != "192.0.2.1" {
+		t.Errorf("client IP = %q, want the untrusted remote address 192.0.2.1", ip)
+	}
+}
@@ -1,24 +1,92 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
+
+	"github.com/rishavmehra/indexer/pkg/crypto"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	Helius   HeliusConfig
-	Logger   LoggerConfig
+	Server        ServerConfig
+	Database      DatabaseConfig
+	JWT           JWTConfig
+	Helius        HeliusConfig
+	Logger        LoggerConfig
+	MetadataCache MetadataCacheConfig
+	Webhook       WebhookConfig
+	Admin         AdminConfig
+	Credentials   CredentialsConfig
+	RateLimit     RateLimitConfig
+	Logs          LogsConfig
+	Indexers      IndexersConfig
 }
 
 type ServerConfig struct {
-	Port string
-	Env  string
+	Port            string
+	Env             string
+	ShutdownTimeout time.Duration
+	DrainTimeout    time.Duration
+	// MaintenanceMode starts the server with indexing paused
+	MaintenanceMode bool
+	// TrustedProxies are the IPs and CIDRs allowed to set the client IP
+	// through X-Forwarded-For; none are trusted by default
+	TrustedProxies []string
+}
+
+type AdminConfig struct {
+	// APIKey guards the admin endpoints, which are disabled when it is empty
+	APIKey string
+}
+
+type RateLimitConfig struct {
+	// AuthPerMinute and AuthBurst limit login and signup per client IP
+	AuthPerMinute int
+	AuthBurst     int
+	// CreatePerMinute and CreateBurst limit indexer creation per user
+	CreatePerMinute int
+	CreateBurst     int
+}
+
+type LogsConfig struct {
+	// MaxLimit caps the page size of the indexing logs endpoint
+	MaxLimit int32
+	// EnhanceLimit is the largest page whose logs are enriched with rows
+	// from the target table; bigger pages are returned as stored
+	EnhanceLimit int32
+}
+
+type IndexersConfig struct {
+	// DeleteRetention is how long a deleted indexer can be restored before
+	// it is purged; zero deletes indexers right away
+	DeleteRetention time.Duration
+	// MaxPerUser caps the indexers a user can have, unless the user has an
+	// override of their own; zero means no cap
+	MaxPerUser int
+	// ReservedTablePrefixes are prefixes target table names may not start
+	// with, besides pg_
+	ReservedTablePrefixes []string
+	// PollCheckInterval is how often indexers with a pollInterval are
+	// checked for a poll being due; zero turns polling off
+	PollCheckInterval time.Duration
+	// StaleAfter is how long an indexer may go without indexing anything
+	// before its health check reports it unhealthy
+	StaleAfter time.Duration
+	// AutoPauseFailures consecutive processing failures within
+	// AutoPauseWindow pause an indexer; zero turns auto-pausing off
+	AutoPauseFailures int
+	AutoPauseWindow   time.Duration
+}
+
+type CredentialsConfig struct {
+	// EncryptionKey is the AES-256 key sealing stored database passwords
+	EncryptionKey []byte
 }
 
 type DatabaseConfig struct {
@@ -29,11 +97,22 @@ type DatabaseConfig struct {
 	DBName       string
 	SSLMode      string
 	MigrationURL string
+	// ReadURL is an optional DSN for a read replica serving read-heavy queries
+	ReadURL string
+	// MaxConns and MinConns size the pools to the application database
+	MaxConns int32
+	MinConns int32
+	// TargetMaxConns and TargetMinConns size the pools opened to the users'
+	// target databases
+	TargetMaxConns int32
+	TargetMinConns int32
 }
 
 type JWTConfig struct {
 	Secret    string
 	ExpiresIn time.Duration
+	// RefreshExpiresIn is how long a refresh token can mint new access tokens
+	RefreshExpiresIn time.Duration
 }
 
 type HeliusConfig struct {
@@ -41,10 +120,87 @@ type HeliusConfig struct {
 	WebhookSecret  string
 	WebhookBaseURL string
 	WebhookID      string
+	APIBaseURL     string
+	RPCURL         string
+	Cluster        string
+	MaxAttempts    int
+	RetryBaseDelay time.Duration
+	// MaxWebhooks caps how many webhooks the client spreads addresses over,
+	// matching the Helius plan limit; zero means no limit
+	MaxWebhooks int
+	// ReconcileInterval is how often orphaned webhooks are cleaned up; zero
+	// disables the background reconciler
+	ReconcileInterval time.Duration
+	// ReconcileDryRun makes the background reconciler only log what it would do
+	ReconcileDryRun bool
+	// HTTPTimeout bounds each request to the Helius webhook API
+	HTTPTimeout time.Duration
+	// RPCTimeout bounds each Helius RPC call of the token metadata and supply lookups
+	RPCTimeout time.Duration
+}
+
+// HeliusEndpoints are the Helius hosts serving one Solana cluster
+type HeliusEndpoints struct {
+	APIBaseURL string
+	RPCURL     string
+}
+
+// HeliusClusters maps SOLANA_CLUSTER values to their Helius endpoints
+var HeliusClusters = map[string]HeliusEndpoints{
+	"mainnet": {
+		APIBaseURL: "https://api.helius.xyz/v0",
+		RPCURL:     "https://mainnet.helius-rpc.com",
+	},
+	"devnet": {
+		APIBaseURL: "https://api-devnet.helius.xyz/v0",
+		RPCURL:     "https://devnet.helius-rpc.com",
+	},
+	"testnet": {
+		APIBaseURL: "https://api-testnet.helius.xyz/v0",
+		RPCURL:     "https://testnet.helius-rpc.com",
+	},
 }
 
 type LoggerConfig struct {
 	Level string
+	// Format is json or console; it defaults to json in production
+	Format string
+}
+
+type WebhookConfig struct {
+	MaxConcurrency   int
+	AcquireTimeout   time.Duration
+	DBAcquireTimeout time.Duration
+	// FairQueueCapacity switches to one queue per indexer, served round-robin,
+	// holding at most this many payloads each; zero keeps the shared pool
+	FairQueueCapacity int
+	// DedupWindow is how long a processed signature is remembered per
+	// indexer so redeliveries are skipped; zero disables deduplication
+	DedupWindow time.Duration
+	// Queue is where payloads wait for a worker: "memory", "postgres" for a
+	// queue that survives restarts, or empty to process them straight away
+	Queue string
+	// MaxAttempts is how often a payload is tried before it is given up on
+	// and kept in failed_payloads for its owner to replay
+	MaxAttempts int
+	// QueuePollInterval is how often idle workers look for due payloads
+	QueuePollInterval time.Duration
+	// QueueRetention is how long done and failed payload jobs are kept
+	// before the purger deletes them
+	QueueRetention time.Duration
+	// RawPayloadRetention is how long received payloads are kept for replay
+	// before the purger deletes them
+	RawPayloadRetention time.Duration
+}
+
+type MetadataCacheConfig struct {
+	MaxSize int
+	// TTL is how long fetched token metadata is used before it is fetched again
+	TTL time.Duration
+	// NegativeTTL is how long a token without metadata is remembered before
+	// it is looked up again
+	NegativeTTL   time.Duration
+	SweepInterval time.Duration
 }
 
 func LoadConfig(path string) (config Config, err error) {
@@ -52,61 +208,273 @@ func LoadConfig(path string) (config Config, err error) {
 
 	viper.SetDefault("SERVER_PORT", "8080")
 	viper.SetDefault("SERVER_ENV", "development")
+	viper.SetDefault("SERVER_SHUTDOWN_TIMEOUT", "15s")
+	viper.SetDefault("SERVER_DRAIN_TIMEOUT", "15s")
+	viper.SetDefault("SERVER_TRUSTED_PROXIES", "")
+	viper.SetDefault("MAINTENANCE_MODE", false)
+	viper.SetDefault("ADMIN_API_KEY", "")
 	viper.SetDefault("JWT_EXPIRES_IN", "24h")
+	viper.SetDefault("JWT_REFRESH_EXPIRES_IN", "720h")
 	viper.SetDefault("DB_SSL_MODE", "disable")
+	viper.SetDefault("DB_MAX_CONNS", 25)
+	viper.SetDefault("DB_MIN_CONNS", 5)
+	viper.SetDefault("TARGET_DB_MAX_CONNS", 10)
+	viper.SetDefault("TARGET_DB_MIN_CONNS", 1)
 	viper.SetDefault("LOG_LEVEL", "info")
 	viper.SetDefault("MIGRATION_PATH", "file://internal/db/migrations")
 	viper.SetDefault("HELIUS_WEBHOOK_ID", "")
+	viper.SetDefault("METADATA_CACHE_MAX_SIZE", 10000)
+	viper.SetDefault("METADATA_CACHE_TTL", "24h")
+	viper.SetDefault("METADATA_CACHE_NEGATIVE_TTL", "10m")
+	viper.SetDefault("METADATA_CACHE_SWEEP_INTERVAL", "10m")
+	viper.SetDefault("WEBHOOK_MAX_CONCURRENCY", 16)
+	viper.SetDefault("WEBHOOK_ACQUIRE_TIMEOUT", "2s")
+	viper.SetDefault("WEBHOOK_DB_ACQUIRE_TIMEOUT", "5s")
+	viper.SetDefault("WEBHOOK_FAIR_QUEUE_CAPACITY", 0)
+	viper.SetDefault("WEBHOOK_DEDUP_WINDOW", "10m")
+	viper.SetDefault("WEBHOOK_QUEUE", "")
+	viper.SetDefault("WEBHOOK_MAX_ATTEMPTS", 3)
+	viper.SetDefault("WEBHOOK_QUEUE_POLL_INTERVAL", "1s")
+	viper.SetDefault("WEBHOOK_QUEUE_RETENTION", "24h")
+	viper.SetDefault("WEBHOOK_RAW_PAYLOAD_RETENTION", "168h")
+	viper.SetDefault("HELIUS_MAX_ATTEMPTS", 4)
+	viper.SetDefault("HELIUS_RETRY_BASE_DELAY", "500ms")
+	viper.SetDefault("HELIUS_MAX_WEBHOOKS", 0)
+	viper.SetDefault("HELIUS_RECONCILE_INTERVAL", "0")
+	viper.SetDefault("HELIUS_RECONCILE_DRY_RUN", false)
+	viper.SetDefault("HELIUS_HTTP_TIMEOUT", "30s")
+	viper.SetDefault("HELIUS_RPC_TIMEOUT", "10s")
+	viper.SetDefault("RATE_LIMIT_AUTH_PER_MINUTE", 10)
+	viper.SetDefault("RATE_LIMIT_AUTH_BURST", 5)
+	viper.SetDefault("RATE_LIMIT_CREATE_PER_MINUTE", 10)
+	viper.SetDefault("RATE_LIMIT_CREATE_BURST", 5)
+	viper.SetDefault("LOGS_MAX_LIMIT", 500)
+	viper.SetDefault("LOGS_ENHANCE_LIMIT", 100)
+	viper.SetDefault("INDEXER_DELETE_RETENTION", "168h")
+	viper.SetDefault("MAX_INDEXERS_PER_USER", 0)
+	viper.SetDefault("INDEXER_POLL_CHECK_INTERVAL", "30s")
+	viper.SetDefault("INDEXER_STALE_AFTER", "1h")
+	viper.SetDefault("INDEXER_AUTO_PAUSE_FAILURES", 0)
+	viper.SetDefault("INDEXER_AUTO_PAUSE_WINDOW", "10m")
+	viper.SetDefault("RESERVED_TABLE_PREFIXES", "")
 
 	viper.AutomaticEnv()
 
-	jwtExpiresIn, err := time.ParseDuration(viper.GetString("JWT_EXPIRES_IN"))
+	// Settings that fail to parse are collected with the problems Validate
+	// finds, so every mistake is reported in one go
+	parseErrs := &ValidationError{}
+
+	jwtExpiresIn := parseDuration(parseErrs, "JWT_EXPIRES_IN")
+	jwtRefreshExpiresIn := parseDuration(parseErrs, "JWT_REFRESH_EXPIRES_IN")
+	shutdownTimeout := parseDuration(parseErrs, "SERVER_SHUTDOWN_TIMEOUT")
+	drainTimeout := parseDuration(parseErrs, "SERVER_DRAIN_TIMEOUT")
+
+	heliusCluster, heliusEndpoints, err := ResolveHeliusCluster(
+		viper.GetString("SOLANA_CLUSTER"),
+		viper.GetString("HELIUS_API_BASE_URL"),
+		viper.GetString("HELIUS_RPC_URL"),
+	)
 	if err != nil {
-		return config, fmt.Errorf("invalid JWT_EXPIRES_IN: %w", err)
+		parseErrs.invalid("SOLANA_CLUSTER", err.Error())
+		// Carry on with the mainnet endpoints so the URL checks below don't
+		// repeat the same problem
+		heliusEndpoints = HeliusClusters["mainnet"]
+	}
+
+	heliusRetryBaseDelay := parseDuration(parseErrs, "HELIUS_RETRY_BASE_DELAY")
+	heliusReconcileInterval := parseDuration(parseErrs, "HELIUS_RECONCILE_INTERVAL")
+	heliusHTTPTimeout := parseDuration(parseErrs, "HELIUS_HTTP_TIMEOUT")
+	heliusRPCTimeout := parseDuration(parseErrs, "HELIUS_RPC_TIMEOUT")
+	cacheTTL := parseDuration(parseErrs, "METADATA_CACHE_TTL")
+	cacheNegativeTTL := parseDuration(parseErrs, "METADATA_CACHE_NEGATIVE_TTL")
+	cacheSweepInterval := parseDuration(parseErrs, "METADATA_CACHE_SWEEP_INTERVAL")
+	webhookAcquireTimeout := parseDuration(parseErrs, "WEBHOOK_ACQUIRE_TIMEOUT")
+	webhookDBAcquireTimeout := parseDuration(parseErrs, "WEBHOOK_DB_ACQUIRE_TIMEOUT")
+	webhookDedupWindow := parseDuration(parseErrs, "WEBHOOK_DEDUP_WINDOW")
+	webhookQueuePollInterval := parseDuration(parseErrs, "WEBHOOK_QUEUE_POLL_INTERVAL")
+	webhookQueueRetention := parseDuration(parseErrs, "WEBHOOK_QUEUE_RETENTION")
+	webhookRawPayloadRetention := parseDuration(parseErrs, "WEBHOOK_RAW_PAYLOAD_RETENTION")
+	indexerDeleteRetention := parseDuration(parseErrs, "INDEXER_DELETE_RETENTION")
+	indexerPollCheckInterval := parseDuration(parseErrs, "INDEXER_POLL_CHECK_INTERVAL")
+	indexerStaleAfter := parseDuration(parseErrs, "INDEXER_STALE_AFTER")
+	indexerAutoPauseWindow := parseDuration(parseErrs, "INDEXER_AUTO_PAUSE_WINDOW")
+
+	var credentialKey []byte
+	if encoded := viper.GetString("CREDENTIAL_ENCRYPTION_KEY"); encoded != "" {
+		credentialKey, err = crypto.ParseSecretKey(encoded)
+		if err != nil {
+			parseErrs.invalid("CREDENTIAL_ENCRYPTION_KEY", err.Error())
+		}
 	}
 
 	config = Config{
 		Server: ServerConfig{
-			Port: viper.GetString("SERVER_PORT"),
-			Env:  viper.GetString("SERVER_ENV"),
+			Port:            viper.GetString("SERVER_PORT"),
+			Env:             viper.GetString("SERVER_ENV"),
+			ShutdownTimeout: shutdownTimeout,
+			DrainTimeout:    drainTimeout,
+			MaintenanceMode: viper.GetBool("MAINTENANCE_MODE"),
+			TrustedProxies:  splitList(viper.GetString("SERVER_TRUSTED_PROXIES")),
 		},
 		Database: DatabaseConfig{
-			Host:         viper.GetString("DB_HOST"),
-			Port:         viper.GetString("DB_PORT"),
-			User:         viper.GetString("DB_USER"),
-			Password:     viper.GetString("DB_PASSWORD"),
-			DBName:       viper.GetString("DB_NAME"),
-			SSLMode:      viper.GetString("DB_SSL_MODE"),
-			MigrationURL: viper.GetString("MIGRATION_PATH"),
+			Host:           viper.GetString("DB_HOST"),
+			Port:           viper.GetString("DB_PORT"),
+			User:           viper.GetString("DB_USER"),
+			Password:       viper.GetString("DB_PASSWORD"),
+			DBName:         viper.GetString("DB_NAME"),
+			SSLMode:        viper.GetString("DB_SSL_MODE"),
+			ReadURL:        viper.GetString("DB_READ_URL"),
+			MigrationURL:   viper.GetString("MIGRATION_PATH"),
+			MaxConns:       viper.GetInt32("DB_MAX_CONNS"),
+			MinConns:       viper.GetInt32("DB_MIN_CONNS"),
+			TargetMaxConns: viper.GetInt32("TARGET_DB_MAX_CONNS"),
+			TargetMinConns: viper.GetInt32("TARGET_DB_MIN_CONNS"),
 		},
 		JWT: JWTConfig{
-			Secret:    viper.GetString("JWT_SECRET"),
-			ExpiresIn: jwtExpiresIn,
+			Secret:           viper.GetString("JWT_SECRET"),
+			ExpiresIn:        jwtExpiresIn,
+			RefreshExpiresIn: jwtRefreshExpiresIn,
 		},
 		Helius: HeliusConfig{
-			APIKey:         viper.GetString("HELIUS_API_KEY"),
-			WebhookSecret:  viper.GetString("HELIUS_WEBHOOK_SECRET"),
-			WebhookBaseURL: viper.GetString("HELIUS_WEBHOOK_BASE_URL"),
-			WebhookID:      viper.GetString("HELIUS_WEBHOOK_ID"),
+			APIKey:            viper.GetString("HELIUS_API_KEY"),
+			WebhookSecret:     viper.GetString("HELIUS_WEBHOOK_SECRET"),
+			WebhookBaseURL:    viper.GetString("HELIUS_WEBHOOK_BASE_URL"),
+			WebhookID:         viper.GetString("HELIUS_WEBHOOK_ID"),
+			APIBaseURL:        heliusEndpoints.APIBaseURL,
+			RPCURL:            heliusEndpoints.RPCURL,
+			Cluster:           heliusCluster,
+			MaxAttempts:       viper.GetInt("HELIUS_MAX_ATTEMPTS"),
+			RetryBaseDelay:    heliusRetryBaseDelay,
+			MaxWebhooks:       viper.GetInt("HELIUS_MAX_WEBHOOKS"),
+			ReconcileInterval: heliusReconcileInterval,
+			ReconcileDryRun:   viper.GetBool("HELIUS_RECONCILE_DRY_RUN"),
+			HTTPTimeout:       heliusHTTPTimeout,
+			RPCTimeout:        heliusRPCTimeout,
 		},
 		Logger: LoggerConfig{
-			Level: viper.GetString("LOG_LEVEL"),
+			Level:  viper.GetString("LOG_LEVEL"),
+			Format: strings.ToLower(viper.GetString("LOG_FORMAT")),
+		},
+		MetadataCache: MetadataCacheConfig{
+			MaxSize:       viper.GetInt("METADATA_CACHE_MAX_SIZE"),
+			TTL:           cacheTTL,
+			NegativeTTL:   cacheNegativeTTL,
+			SweepInterval: cacheSweepInterval,
+		},
+		Webhook: WebhookConfig{
+			MaxConcurrency:      viper.GetInt("WEBHOOK_MAX_CONCURRENCY"),
+			AcquireTimeout:      webhookAcquireTimeout,
+			DBAcquireTimeout:    webhookDBAcquireTimeout,
+			FairQueueCapacity:   viper.GetInt("WEBHOOK_FAIR_QUEUE_CAPACITY"),
+			DedupWindow:         webhookDedupWindow,
+			Queue:               strings.ToLower(viper.GetString("WEBHOOK_QUEUE")),
+			MaxAttempts:         viper.GetInt("WEBHOOK_MAX_ATTEMPTS"),
+			QueuePollInterval:   webhookQueuePollInterval,
+			QueueRetention:      webhookQueueRetention,
+			RawPayloadRetention: webhookRawPayloadRetention,
+		},
+		Admin: AdminConfig{
+			APIKey: viper.GetString("ADMIN_API_KEY"),
+		},
+		Credentials: CredentialsConfig{
+			EncryptionKey: credentialKey,
+		},
+		RateLimit: RateLimitConfig{
+			AuthPerMinute:   viper.GetInt("RATE_LIMIT_AUTH_PER_MINUTE"),
+			AuthBurst:       viper.GetInt("RATE_LIMIT_AUTH_BURST"),
+			CreatePerMinute: viper.GetInt("RATE_LIMIT_CREATE_PER_MINUTE"),
+			CreateBurst:     viper.GetInt("RATE_LIMIT_CREATE_BURST"),
+		},
+		Logs: LogsConfig{
+			MaxLimit:     viper.GetInt32("LOGS_MAX_LIMIT"),
+			EnhanceLimit: viper.GetInt32("LOGS_ENHANCE_LIMIT"),
+		},
+		Indexers: IndexersConfig{
+			DeleteRetention:       indexerDeleteRetention,
+			MaxPerUser:            viper.GetInt("MAX_INDEXERS_PER_USER"),
+			ReservedTablePrefixes: splitList(viper.GetString("RESERVED_TABLE_PREFIXES")),
+			PollCheckInterval:     indexerPollCheckInterval,
+			StaleAfter:            indexerStaleAfter,
+			AutoPauseFailures:     viper.GetInt("INDEXER_AUTO_PAUSE_FAILURES"),
+			AutoPauseWindow:       indexerAutoPauseWindow,
 		},
 	}
 
-	if config.JWT.Secret == "" {
-		return config, fmt.Errorf("JWT_SECRET is required")
+	if config.Logger.Format == "" {
+		config.Logger.Format = "console"
+		if config.Server.Env == "production" {
+			config.Logger.Format = "json"
+		}
 	}
-	if config.Database.Host == "" || config.Database.User == "" || config.Database.DBName == "" {
-		return config, fmt.Errorf("database configuration is incomplete")
+	if err := config.Validate(); err != nil {
+		var verr *ValidationError
+		if errors.As(err, &verr) {
+			parseErrs.merge(verr)
+		}
 	}
-	if config.Helius.APIKey == "" {
-		return config, fmt.Errorf("HELIUS_API_KEY is required")
+	if len(parseErrs.Problems) > 0 {
+		return config, parseErrs
 	}
 
 	return config, nil
 }
 
+// ResolveHeliusCluster picks the Helius endpoints for a cluster. Explicit URLs
+// are allowed alongside a cluster only if they match it. Without a cluster the
+// URLs default to mainnet, and the cluster is inferred from them or reported
+// as "custom" for proxies and self-hosted endpoints.
+func ResolveHeliusCluster(cluster, apiBaseURL, rpcURL string) (string, HeliusEndpoints, error) {
+	cluster = strings.ToLower(strings.TrimSpace(cluster))
+	apiBaseURL = strings.TrimRight(apiBaseURL, "/")
+	rpcURL = strings.TrimRight(rpcURL, "/")
+
+	if cluster != "" {
+		endpoints, ok := HeliusClusters[cluster]
+		if !ok {
+			return "", HeliusEndpoints{}, fmt.Errorf("invalid SOLANA_CLUSTER %q, must be one of mainnet, devnet, testnet", cluster)
+		}
+		if apiBaseURL != "" && apiBaseURL != endpoints.APIBaseURL {
+			return "", HeliusEndpoints{}, fmt.Errorf("HELIUS_API_BASE_URL %s conflicts with SOLANA_CLUSTER %s", apiBaseURL, cluster)
+		}
+		if rpcURL != "" && rpcURL != endpoints.RPCURL {
+			return "", HeliusEndpoints{}, fmt.Errorf("HELIUS_RPC_URL %s conflicts with SOLANA_CLUSTER %s", rpcURL, cluster)
+		}
+		return cluster, endpoints, nil
+	}
+
+	mainnet := HeliusClusters["mainnet"]
+	if apiBaseURL == "" {
+		apiBaseURL = mainnet.APIBaseURL
+	}
+	if rpcURL == "" {
+		rpcURL = mainnet.RPCURL
+	}
+
+	endpoints := HeliusEndpoints{APIBaseURL: apiBaseURL, RPCURL: rpcURL}
+	for name, known := range HeliusClusters {
+		if known == endpoints {
+			return name, endpoints, nil
+		}
+	}
+
+	return "custom", endpoints, nil
+}
+
+// validateHTTPURL checks that raw is an absolute http or https URL
+func validateHTTPURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("scheme must be http or https, got %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+	return nil
+}
+
 func (c *DatabaseConfig) GetDSN() string {
 	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		c.Host, c.Port, c.User, c.Password, c.DBName, c.SSLMode)
@@ -0,0 +1,356 @@
+package config
+
+import (
+	"encoding/base64"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rishavmehra/indexer/pkg/crypto"
+)
+
+func TestResolveHeliusCluster(t *testing.T) {
+	mainnet, devnet, testnet := HeliusClusters["mainnet"], HeliusClusters["devnet"], HeliusClusters["testnet"]
+
+	tests := []struct {
+		name        string
+		cluster     string
+		apiBaseURL  string
+		rpcURL      string
+		wantCluster string
+		want        HeliusEndpoints
+		wantErr     string
+	}{
+		{name: "nothing set defaults to mainnet", wantCluster: "mainnet", want: mainnet},
+		{name: "mainnet", cluster: "mainnet", wantCluster: "mainnet", want: mainnet},
+		{name: "devnet", cluster: "devnet", wantCluster: "devnet", want: devnet},
+		{name: "testnet", cluster: "testnet", wantCluster: "testnet", want: testnet},
+		{name: "cluster is case-insensitive", cluster: " DevNet ", wantCluster: "devnet", want: devnet},
+		{name: "matching explicit URLs", cluster: "devnet", apiBaseURL: devnet.APIBaseURL + "/", rpcURL: devnet.RPCURL, wantCluster: "devnet", want: devnet},
+		{name: "URLs alone infer the cluster", apiBaseURL: devnet.APIBaseURL, rpcURL: devnet.RPCURL, wantCluster: "devnet", want: devnet},
+		{
+			name:        "custom proxy",
+			apiBaseURL:  "http://proxy.local/v0",
+			wantCluster: "custom",
+			want:        HeliusEndpoints{APIBaseURL: "http://proxy.local/v0", RPCURL: mainnet.RPCURL},
+		},
+		{name: "unknown cluster", cluster: "localnet", wantErr: "invalid SOLANA_CLUSTER"},
+		{name: "conflicting API URL", cluster: "devnet", apiBaseURL: mainnet.APIBaseURL, wantErr: "HELIUS_API_BASE_URL"},
+		{name: "conflicting RPC URL", cluster: "mainnet", rpcURL: "http://rpc.local", wantErr: "HELIUS_RPC_URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster, endpoints, err := ResolveHeliusCluster(tt.cluster, tt.apiBaseURL, tt.rpcURL)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ResolveHeliusCluster error = %v, want one mentioning %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveHeliusCluster: %v", err)
+			}
+			if cluster != tt.wantCluster || endpoints != tt.want {
+				t.Errorf("got %s %+v, want %s %+v", cluster, endpoints, tt.wantCluster, tt.want)
+			}
+		})
+	}
+}
+
+// setRequiredEnv sets the settings LoadConfig has no default for
+func setRequiredEnv(t *testing.T) {
+	t.Helper()
+
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("DB_HOST", "localhost")
+	t.Setenv("DB_USER", "indexer")
+	t.Setenv("DB_NAME", "indexer")
+	t.Setenv("HELIUS_API_KEY", "key")
+	t.Setenv("CREDENTIAL_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(make([]byte, crypto.SecretKeySize)))
+}
+
+// loadConfig runs LoadConfig without a .env file
+func loadConfig(t *testing.T) (Config, error) {
+	t.Helper()
+	return LoadConfig(filepath.Join(t.TempDir(), ".env"))
+}
+
+func TestLoadConfigPoolSizes(t *testing.T) {
+	setRequiredEnv(t)
+
+	cfg, err := loadConfig(t)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Database.MaxConns != 25 || cfg.Database.MinConns != 5 || cfg.Database.TargetMaxConns != 10 || cfg.Database.TargetMinConns != 1 {
+		t.Errorf("default pool sizes = %+v, want 5..25 and 1..10", cfg.Database)
+	}
+
+	t.Setenv("DB_MAX_CONNS", "40")
+	t.Setenv("DB_MIN_CONNS", "8")
+	t.Setenv("TARGET_DB_MAX_CONNS", "4")
+	t.Setenv("TARGET_DB_MIN_CONNS", "2")
+
+	cfg, err = loadConfig(t)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Database.MaxConns != 40 || cfg.Database.MinConns != 8 || cfg.Database.TargetMaxConns != 4 || cfg.Database.TargetMinConns != 2 {
+		t.Errorf("pool sizes = %+v, want 8..40 and 2..4", cfg.Database)
+	}
+}
+
+func TestLoadConfigRejectsMinConnsAboveMax(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("DB_MAX_CONNS", "5")
+	t.Setenv("DB_MIN_CONNS", "6")
+	t.Setenv("TARGET_DB_MAX_CONNS", "2")
+	t.Setenv("TARGET_DB_MIN_CONNS", "3")
+
+	_, err := loadConfig(t)
+	if err == nil {
+		t.Fatal("LoadConfig accepted pools with more minimum than maximum connections")
+	}
+	for _, want := range []string{"DB_MIN_CONNS must not exceed DB_MAX_CONNS", "TARGET_DB_MIN_CONNS must not exceed TARGET_DB_MAX_CONNS"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadConfig error = %v, want it to say %q", err, want)
+		}
+	}
+}
+
+func TestLoadConfigLogFormat(t *testing.T) {
+	tests := []struct {
+		env    string
+		format string
+		want   string
+	}{
+		{env: "development", want: "console"},
+		{env: "production", want: "json"},
+		{env: "production", format: "console", want: "console"},
+		{env: "development", format: "json", want: "json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.env+"/"+tt.format, func(t *testing.T) {
+			setRequiredEnv(t)
+			t.Setenv("SERVER_ENV", tt.env)
+			t.Setenv("LOG_FORMAT", tt.format)
+
+			cfg, err := loadConfig(t)
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.Logger.Format != tt.want {
+				t.Errorf("log format = %q, want %q", cfg.Logger.Format, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigRejectsUnknownLogFormat(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("LOG_FORMAT", "xml")
+
+	if _, err := loadConfig(t); err == nil || !strings.Contains(err.Error(), "LOG_FORMAT must be json or console") {
+		t.Errorf("LoadConfig error = %v, want LOG_FORMAT rejected", err)
+	}
+}
+
+func TestLoadConfigLogLimits(t *testing.T) {
+	setRequiredEnv(t)
+
+	cfg, err := loadConfig(t)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Logs.MaxLimit != 500 || cfg.Logs.EnhanceLimit != 100 {
+		t.Errorf("default log limits = %+v, want 500 and 100", cfg.Logs)
+	}
+
+	t.Setenv("LOGS_MAX_LIMIT", "0")
+	if _, err := loadConfig(t); err == nil || !strings.Contains(err.Error(), "LOGS_MAX_LIMIT must be positive") {
+		t.Errorf("LoadConfig error = %v, want LOGS_MAX_LIMIT rejected", err)
+	}
+}
+
+func TestLoadConfigHeliusTimeouts(t *testing.T) {
+	setRequiredEnv(t)
+
+	cfg, err := loadConfig(t)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Helius.HTTPTimeout != 30*time.Second || cfg.Helius.RPCTimeout != 10*time.Second {
+		t.Errorf("default timeouts = %v and %v, want 30s and 10s", cfg.Helius.HTTPTimeout, cfg.Helius.RPCTimeout)
+	}
+
+	t.Setenv("HELIUS_HTTP_TIMEOUT", "5s")
+	t.Setenv("HELIUS_RPC_TIMEOUT", "2s")
+	if cfg, err = loadConfig(t); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Helius.HTTPTimeout != 5*time.Second || cfg.Helius.RPCTimeout != 2*time.Second {
+		t.Errorf("timeouts = %v and %v, want 5s and 2s", cfg.Helius.HTTPTimeout, cfg.Helius.RPCTimeout)
+	}
+
+	t.Setenv("HELIUS_RPC_TIMEOUT", "0s")
+	if _, err := loadConfig(t); err == nil || !strings.Contains(err.Error(), "HELIUS_RPC_TIMEOUT must be positive") {
+		t.Errorf("LoadConfig error = %v, want HELIUS_RPC_TIMEOUT rejected", err)
+	}
+}
+
+func TestLoadConfigMetadataCacheTTL(t *testing.T) {
+	setRequiredEnv(t)
+
+	cfg, err := loadConfig(t)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.MetadataCache.TTL != 24*time.Hour {
+		t.Errorf("default TTL = %v, want 24h", cfg.MetadataCache.TTL)
+	}
+
+	t.Setenv("METADATA_CACHE_TTL", "15m")
+	if cfg, err = loadConfig(t); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.MetadataCache.TTL != 15*time.Minute {
+		t.Errorf("TTL = %v, want 15m", cfg.MetadataCache.TTL)
+	}
+
+	t.Setenv("METADATA_CACHE_TTL", "0s")
+	if _, err := loadConfig(t); err == nil || !strings.Contains(err.Error(), "METADATA_CACHE_TTL must be positive") {
+		t.Errorf("LoadConfig error = %v, want METADATA_CACHE_TTL rejected", err)
+	}
+}
+
+func TestLoadConfigMetadataCacheNegativeTTL(t *testing.T) {
+	setRequiredEnv(t)
+
+	cfg, err := loadConfig(t)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.MetadataCache.NegativeTTL != 10*time.Minute {
+		t.Errorf("default negative TTL = %v, want 10m", cfg.MetadataCache.NegativeTTL)
+	}
+
+	t.Setenv("METADATA_CACHE_NEGATIVE_TTL", "-1m")
+	if _, err := loadConfig(t); err == nil || !strings.Contains(err.Error(), "METADATA_CACHE_NEGATIVE_TTL must be positive") {
+		t.Errorf("LoadConfig error = %v, want METADATA_CACHE_NEGATIVE_TTL rejected", err)
+	}
+}
+
+func TestLoadConfigIndexerStaleAfter(t *testing.T) {
+	setRequiredEnv(t)
+
+	cfg, err := loadConfig(t)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Indexers.StaleAfter != time.Hour {
+		t.Errorf("default stale threshold = %v, want 1h", cfg.Indexers.StaleAfter)
+	}
+
+	t.Setenv("INDEXER_STALE_AFTER", "30m")
+	if cfg, err = loadConfig(t); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Indexers.StaleAfter != 30*time.Minute {
+		t.Errorf("stale threshold = %v, want 30m", cfg.Indexers.StaleAfter)
+	}
+
+	t.Setenv("INDEXER_STALE_AFTER", "0s")
+	if _, err := loadConfig(t); err == nil || !strings.Contains(err.Error(), "INDEXER_STALE_AFTER must be positive") {
+		t.Errorf("LoadConfig error = %v, want INDEXER_STALE_AFTER rejected", err)
+	}
+}
+
+func TestLoadConfigAutoPause(t *testing.T) {
+	setRequiredEnv(t)
+
+	cfg, err := loadConfig(t)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Indexers.AutoPauseFailures != 0 || cfg.Indexers.AutoPauseWindow != 10*time.Minute {
+		t.Errorf("default auto-pause = %d within %v, want off within 10m", cfg.Indexers.AutoPauseFailures, cfg.Indexers.AutoPauseWindow)
+	}
+
+	t.Setenv("INDEXER_AUTO_PAUSE_FAILURES", "5")
+	t.Setenv("INDEXER_AUTO_PAUSE_WINDOW", "2m")
+	if cfg, err = loadConfig(t); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Indexers.AutoPauseFailures != 5 || cfg.Indexers.AutoPauseWindow != 2*time.Minute {
+		t.Errorf("auto-pause = %d within %v, want 5 within 2m", cfg.Indexers.AutoPauseFailures, cfg.Indexers.AutoPauseWindow)
+	}
+
+	t.Setenv("INDEXER_AUTO_PAUSE_WINDOW", "0s")
+	if _, err := loadConfig(t); err == nil || !strings.Contains(err.Error(), "INDEXER_AUTO_PAUSE_WINDOW must be positive") {
+		t.Errorf("LoadConfig error = %v, want INDEXER_AUTO_PAUSE_WINDOW rejected", err)
+	}
+
+	t.Setenv("INDEXER_AUTO_PAUSE_FAILURES", "-1")
+	if _, err := loadConfig(t); err == nil || !strings.Contains(err.Error(), "INDEXER_AUTO_PAUSE_FAILURES must not be negative") {
+		t.Errorf("LoadConfig error = %v, want INDEXER_AUTO_PAUSE_FAILURES rejected", err)
+	}
+}
+
+func TestLoadConfigRawPayloadRetention(t *testing.T) {
+	setRequiredEnv(t)
+
+	cfg, err := loadConfig(t)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Webhook.RawPayloadRetention != 168*time.Hour {
+		t.Errorf("default raw payload retention = %v, want 168h", cfg.Webhook.RawPayloadRetention)
+	}
+
+	t.Setenv("WEBHOOK_RAW_PAYLOAD_RETENTION", "24h")
+	if cfg, err = loadConfig(t); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Webhook.RawPayloadRetention != 24*time.Hour {
+		t.Errorf("raw payload retention = %v, want 24h", cfg.Webhook.RawPayloadRetention)
+	}
+
+	t.Setenv("WEBHOOK_RAW_PAYLOAD_RETENTION", "-1h")
+	if _, err := loadConfig(t); err == nil || !strings.Contains(err.Error(), "WEBHOOK_RAW_PAYLOAD_RETENTION must not be negative") {
+		t.Errorf("LoadConfig error = %v, want WEBHOOK_RAW_PAYLOAD_RETENTION rejected", err)
+	}
+}
+
+func TestLoadConfigDrainTimeout(t *testing.T) {
+	setRequiredEnv(t)
+
+	cfg, err := loadConfig(t)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Server.DrainTimeout > cfg.Server.ShutdownTimeout {
+		t.Errorf("default drain timeout %v exceeds shutdown timeout %v", cfg.Server.DrainTimeout, cfg.Server.ShutdownTimeout)
+	}
+
+	t.Setenv("SERVER_SHUTDOWN_TIMEOUT", "30s")
+	t.Setenv("SERVER_DRAIN_TIMEOUT", "30s")
+	if cfg, err = loadConfig(t); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Server.DrainTimeout != 30*time.Second {
+		t.Errorf("drain timeout = %v, want 30s", cfg.Server.DrainTimeout)
+	}
+
+	t.Setenv("SERVER_DRAIN_TIMEOUT", "45s")
+	if _, err := loadConfig(t); err == nil || !strings.Contains(err.Error(), "SERVER_DRAIN_TIMEOUT must not exceed SERVER_SHUTDOWN_TIMEOUT") {
+		t.Errorf("LoadConfig error = %v, want SERVER_DRAIN_TIMEOUT rejected", err)
+	}
+
+	t.Setenv("SERVER_SHUTDOWN_TIMEOUT", "0s")
+	if _, err := loadConfig(t); err == nil || !strings.Contains(err.Error(), "SERVER_SHUTDOWN_TIMEOUT must be positive") {
+		t.Errorf("LoadConfig error = %v, want SERVER_SHUTDOWN_TIMEOUT rejected", err)
+	}
+}
@@ -0,0 +1,281 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Problem is one thing wrong with the configuration. Missing is set for
+// required settings that were not given at all, as opposed to settings that
+// were given but hold a bad value.
+type Problem struct {
+	Key     string
+	Message string
+	Missing bool
+}
+
+func (p Problem) String() string {
+	return p.Message
+}
+
+// ValidationError collects every problem found in a configuration so they can
+// be fixed together rather than one per restart
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		messages[i] = p.Message
+	}
+	return "invalid configuration: " + strings.Join(messages, "; ")
+}
+
+// MissingProblems returns the required settings that were not given
+func (e *ValidationError) MissingProblems() []Problem {
+	var problems []Problem
+	for _, p := range e.Problems {
+		if p.Missing {
+			problems = append(problems, p)
+		}
+	}
+	return problems
+}
+
+// InvalidProblems returns the settings that were given with a bad value
+func (e *ValidationError) InvalidProblems() []Problem {
+	var problems []Problem
+	for _, p := range e.Problems {
+		if !p.Missing {
+			problems = append(problems, p)
+		}
+	}
+	return problems
+}
+
+func (e *ValidationError) missing(key string) {
+	e.Problems = append(e.Problems, Problem{Key: key, Message: key + " is required", Missing: true})
+}
+
+func (e *ValidationError) invalid(key, message string) {
+	e.Problems = append(e.Problems, Problem{Key: key, Message: message})
+}
+
+func (e *ValidationError) has(key string) bool {
+	for _, p := range e.Problems {
+		if p.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// merge adds the problems of other, skipping settings already reported so a
+// value that failed to parse isn't reported again for being zero
+func (e *ValidationError) merge(other *ValidationError) {
+	for _, p := range other.Problems {
+		if !e.has(p.Key) {
+			e.Problems = append(e.Problems, p)
+		}
+	}
+}
+
+// parseDuration reads a duration setting, recording a problem if it doesn't parse
+func parseDuration(problems *ValidationError, key string) time.Duration {
+	d, err := time.ParseDuration(viper.GetString(key))
+	if err != nil {
+		problems.invalid(key, fmt.Sprintf("invalid %s: %v", key, err))
+	}
+	return d
+}
+
+// splitList turns a comma-separated setting into its trimmed, lower-cased
+// entries, leaving out empty ones
+func splitList(value string) []string {
+	var entries []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.ToLower(strings.TrimSpace(entry)); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// tablePrefixPattern matches a reserved table prefix
+var tablePrefixPattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// Validate checks the whole configuration and returns a *ValidationError
+// listing every problem, or nil. Settings without a default (the JWT secret,
+// database connection, Helius API key and credential key) are reported as
+// missing; everything else as invalid.
+func (c *Config) Validate() error {
+	problems := &ValidationError{}
+
+	if c.JWT.Secret == "" {
+		problems.missing("JWT_SECRET")
+	}
+	if c.Database.Host == "" {
+		problems.missing("DB_HOST")
+	}
+	if c.Database.User == "" {
+		problems.missing("DB_USER")
+	}
+	if c.Database.DBName == "" {
+		problems.missing("DB_NAME")
+	}
+	if c.Helius.APIKey == "" {
+		problems.missing("HELIUS_API_KEY")
+	}
+	if len(c.Credentials.EncryptionKey) == 0 {
+		problems.missing("CREDENTIAL_ENCRYPTION_KEY")
+	}
+
+	if c.Logger.Format != "json" && c.Logger.Format != "console" {
+		problems.invalid("LOG_FORMAT", "LOG_FORMAT must be json or console")
+	}
+	if c.JWT.RefreshExpiresIn <= 0 {
+		problems.invalid("JWT_REFRESH_EXPIRES_IN", "JWT_REFRESH_EXPIRES_IN must be positive")
+	}
+	if c.Database.MaxConns <= 0 {
+		problems.invalid("DB_MAX_CONNS", "DB_MAX_CONNS must be positive")
+	}
+	if c.Database.MinConns < 0 {
+		problems.invalid("DB_MIN_CONNS", "DB_MIN_CONNS must not be negative")
+	} else if c.Database.MaxConns > 0 && c.Database.MinConns > c.Database.MaxConns {
+		problems.invalid("DB_MIN_CONNS", "DB_MIN_CONNS must not exceed DB_MAX_CONNS")
+	}
+	if c.Database.TargetMaxConns <= 0 {
+		problems.invalid("TARGET_DB_MAX_CONNS", "TARGET_DB_MAX_CONNS must be positive")
+	}
+	if c.Database.TargetMinConns < 0 {
+		problems.invalid("TARGET_DB_MIN_CONNS", "TARGET_DB_MIN_CONNS must not be negative")
+	} else if c.Database.TargetMaxConns > 0 && c.Database.TargetMinConns > c.Database.TargetMaxConns {
+		problems.invalid("TARGET_DB_MIN_CONNS", "TARGET_DB_MIN_CONNS must not exceed TARGET_DB_MAX_CONNS")
+	}
+	if err := validateHTTPURL(c.Helius.APIBaseURL); err != nil {
+		problems.invalid("HELIUS_API_BASE_URL", fmt.Sprintf("invalid HELIUS_API_BASE_URL: %v", err))
+	}
+	if err := validateHTTPURL(c.Helius.RPCURL); err != nil {
+		problems.invalid("HELIUS_RPC_URL", fmt.Sprintf("invalid HELIUS_RPC_URL: %v", err))
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		problems.invalid("SERVER_SHUTDOWN_TIMEOUT", "SERVER_SHUTDOWN_TIMEOUT must be positive")
+	}
+	if c.Server.DrainTimeout <= 0 {
+		problems.invalid("SERVER_DRAIN_TIMEOUT", "SERVER_DRAIN_TIMEOUT must be positive")
+	} else if c.Server.ShutdownTimeout > 0 && c.Server.DrainTimeout > c.Server.ShutdownTimeout {
+		problems.invalid("SERVER_DRAIN_TIMEOUT", "SERVER_DRAIN_TIMEOUT must not exceed SERVER_SHUTDOWN_TIMEOUT")
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				problems.invalid("SERVER_TRUSTED_PROXIES", fmt.Sprintf("SERVER_TRUSTED_PROXIES entry %q must be an IP address or CIDR", proxy))
+			}
+		}
+	}
+	if c.Helius.MaxAttempts <= 0 {
+		problems.invalid("HELIUS_MAX_ATTEMPTS", "HELIUS_MAX_ATTEMPTS must be positive")
+	}
+	if c.Helius.MaxWebhooks < 0 {
+		problems.invalid("HELIUS_MAX_WEBHOOKS", "HELIUS_MAX_WEBHOOKS must not be negative")
+	}
+	if c.Helius.ReconcileInterval < 0 {
+		problems.invalid("HELIUS_RECONCILE_INTERVAL", "HELIUS_RECONCILE_INTERVAL must not be negative")
+	}
+	if c.Helius.HTTPTimeout <= 0 {
+		problems.invalid("HELIUS_HTTP_TIMEOUT", "HELIUS_HTTP_TIMEOUT must be positive")
+	}
+	if c.Helius.RPCTimeout <= 0 {
+		problems.invalid("HELIUS_RPC_TIMEOUT", "HELIUS_RPC_TIMEOUT must be positive")
+	}
+	if c.MetadataCache.MaxSize <= 0 {
+		problems.invalid("METADATA_CACHE_MAX_SIZE", "METADATA_CACHE_MAX_SIZE must be positive")
+	}
+	if c.MetadataCache.TTL <= 0 {
+		problems.invalid("METADATA_CACHE_TTL", "METADATA_CACHE_TTL must be positive")
+	}
+	if c.MetadataCache.NegativeTTL <= 0 {
+		problems.invalid("METADATA_CACHE_NEGATIVE_TTL", "METADATA_CACHE_NEGATIVE_TTL must be positive")
+	}
+	if c.Webhook.MaxConcurrency <= 0 {
+		problems.invalid("WEBHOOK_MAX_CONCURRENCY", "WEBHOOK_MAX_CONCURRENCY must be positive")
+	}
+	if c.Webhook.DBAcquireTimeout <= 0 {
+		problems.invalid("WEBHOOK_DB_ACQUIRE_TIMEOUT", "WEBHOOK_DB_ACQUIRE_TIMEOUT must be positive")
+	}
+	if c.Webhook.FairQueueCapacity < 0 {
+		problems.invalid("WEBHOOK_FAIR_QUEUE_CAPACITY", "WEBHOOK_FAIR_QUEUE_CAPACITY must not be negative")
+	}
+	if c.Webhook.DedupWindow < 0 {
+		problems.invalid("WEBHOOK_DEDUP_WINDOW", "WEBHOOK_DEDUP_WINDOW must not be negative")
+	}
+	switch c.Webhook.Queue {
+	case "", "memory", "postgres":
+	default:
+		problems.invalid("WEBHOOK_QUEUE", "WEBHOOK_QUEUE must be memory, postgres or empty")
+	}
+	if c.Webhook.MaxAttempts <= 0 {
+		problems.invalid("WEBHOOK_MAX_ATTEMPTS", "WEBHOOK_MAX_ATTEMPTS must be positive")
+	}
+	if c.Webhook.QueuePollInterval <= 0 {
+		problems.invalid("WEBHOOK_QUEUE_POLL_INTERVAL", "WEBHOOK_QUEUE_POLL_INTERVAL must be positive")
+	}
+	if c.Webhook.QueueRetention < 0 {
+		problems.invalid("WEBHOOK_QUEUE_RETENTION", "WEBHOOK_QUEUE_RETENTION must not be negative")
+	}
+	if c.Webhook.RawPayloadRetention < 0 {
+		problems.invalid("WEBHOOK_RAW_PAYLOAD_RETENTION", "WEBHOOK_RAW_PAYLOAD_RETENTION must not be negative")
+	}
+	if c.RateLimit.AuthPerMinute < 0 {
+		problems.invalid("RATE_LIMIT_AUTH_PER_MINUTE", "RATE_LIMIT_AUTH_PER_MINUTE must not be negative")
+	}
+	if c.RateLimit.AuthPerMinute > 0 && c.RateLimit.AuthBurst <= 0 {
+		problems.invalid("RATE_LIMIT_AUTH_BURST", "RATE_LIMIT_AUTH_BURST must be positive")
+	}
+	if c.RateLimit.CreatePerMinute < 0 {
+		problems.invalid("RATE_LIMIT_CREATE_PER_MINUTE", "RATE_LIMIT_CREATE_PER_MINUTE must not be negative")
+	}
+	if c.RateLimit.CreatePerMinute > 0 && c.RateLimit.CreateBurst <= 0 {
+		problems.invalid("RATE_LIMIT_CREATE_BURST", "RATE_LIMIT_CREATE_BURST must be positive")
+	}
+	if c.Logs.MaxLimit <= 0 {
+		problems.invalid("LOGS_MAX_LIMIT", "LOGS_MAX_LIMIT must be positive")
+	}
+	if c.Logs.EnhanceLimit < 0 {
+		problems.invalid("LOGS_ENHANCE_LIMIT", "LOGS_ENHANCE_LIMIT must not be negative")
+	}
+	if c.Indexers.DeleteRetention < 0 {
+		problems.invalid("INDEXER_DELETE_RETENTION", "INDEXER_DELETE_RETENTION must not be negative")
+	}
+	if c.Indexers.PollCheckInterval < 0 {
+		problems.invalid("INDEXER_POLL_CHECK_INTERVAL", "INDEXER_POLL_CHECK_INTERVAL must not be negative")
+	}
+	if c.Indexers.StaleAfter <= 0 {
+		problems.invalid("INDEXER_STALE_AFTER", "INDEXER_STALE_AFTER must be positive")
+	}
+	if c.Indexers.AutoPauseFailures < 0 {
+		problems.invalid("INDEXER_AUTO_PAUSE_FAILURES", "INDEXER_AUTO_PAUSE_FAILURES must not be negative")
+	}
+	if c.Indexers.AutoPauseFailures > 0 && c.Indexers.AutoPauseWindow <= 0 {
+		problems.invalid("INDEXER_AUTO_PAUSE_WINDOW", "INDEXER_AUTO_PAUSE_WINDOW must be positive when INDEXER_AUTO_PAUSE_FAILURES is set")
+	}
+	if c.Indexers.MaxPerUser < 0 {
+		problems.invalid("MAX_INDEXERS_PER_USER", "MAX_INDEXERS_PER_USER must not be negative")
+	}
+	for _, prefix := range c.Indexers.ReservedTablePrefixes {
+		if !tablePrefixPattern.MatchString(prefix) {
+			problems.invalid("RESERVED_TABLE_PREFIXES", fmt.Sprintf("RESERVED_TABLE_PREFIXES entry %q must contain only lowercase letters, digits and underscores", prefix))
+		}
+	}
+
+	if len(problems.Problems) > 0 {
+		return problems
+	}
+	return nil
+}
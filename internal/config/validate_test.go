@@ -0,0 +1,113 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// problemKeys returns the settings err reports, split into missing and invalid
+func problemKeys(t *testing.T, err error) (missing, invalid []string) {
+	t.Helper()
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("error = %v, want a *ValidationError", err)
+	}
+	for _, p := range verr.MissingProblems() {
+		missing = append(missing, p.Key)
+	}
+	for _, p := range verr.InvalidProblems() {
+		invalid = append(invalid, p.Key)
+	}
+	return missing, invalid
+}
+
+func TestLoadConfigReportsEveryMissingSetting(t *testing.T) {
+	tests := []struct {
+		name    string
+		set     map[string]string
+		missing []string
+	}{
+		{
+			name:    "nothing set",
+			missing: []string{"JWT_SECRET", "DB_HOST", "DB_USER", "DB_NAME", "HELIUS_API_KEY", "CREDENTIAL_ENCRYPTION_KEY"},
+		},
+		{
+			name:    "database only",
+			set:     map[string]string{"DB_HOST": "localhost", "DB_USER": "indexer", "DB_NAME": "indexer"},
+			missing: []string{"JWT_SECRET", "HELIUS_API_KEY", "CREDENTIAL_ENCRYPTION_KEY"},
+		},
+		{
+			name:    "secrets only",
+			set:     map[string]string{"JWT_SECRET": "secret", "HELIUS_API_KEY": "key"},
+			missing: []string{"DB_HOST", "DB_USER", "DB_NAME", "CREDENTIAL_ENCRYPTION_KEY"},
+		},
+	}
+
+	required := []string{"JWT_SECRET", "DB_HOST", "DB_USER", "DB_NAME", "HELIUS_API_KEY", "CREDENTIAL_ENCRYPTION_KEY"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range required {
+				t.Setenv(key, tt.set[key])
+			}
+
+			_, err := loadConfig(t)
+			missing, invalid := problemKeys(t, err)
+			if !reflect.DeepEqual(missing, tt.missing) {
+				t.Errorf("missing = %v, want %v", missing, tt.missing)
+			}
+			if len(invalid) != 0 {
+				t.Errorf("invalid = %v, want none: optional settings have defaults", invalid)
+			}
+		})
+	}
+}
+
+func TestLoadConfigReportsMissingAndInvalidTogether(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("JWT_EXPIRES_IN", "soon")
+	t.Setenv("WEBHOOK_MAX_CONCURRENCY", "0")
+	t.Setenv("CREDENTIAL_ENCRYPTION_KEY", "not-a-key")
+
+	_, err := loadConfig(t)
+	missing, invalid := problemKeys(t, err)
+
+	if !reflect.DeepEqual(missing, []string{"JWT_SECRET"}) {
+		t.Errorf("missing = %v, want [JWT_SECRET]", missing)
+	}
+	// The bad key is reported as invalid, not again as missing
+	want := map[string]bool{"JWT_EXPIRES_IN": true, "WEBHOOK_MAX_CONCURRENCY": true, "CREDENTIAL_ENCRYPTION_KEY": true}
+	if len(invalid) != len(want) {
+		t.Errorf("invalid = %v, want %d settings", invalid, len(want))
+	}
+	for _, key := range invalid {
+		if !want[key] {
+			t.Errorf("unexpected invalid setting %s", key)
+		}
+	}
+	if !strings.HasPrefix(err.Error(), "invalid configuration: ") || !strings.Contains(err.Error(), "JWT_SECRET is required") {
+		t.Errorf("error = %q, want every problem in one message", err)
+	}
+}
+
+func TestValidateAcceptsDefaults(t *testing.T) {
+	setRequiredEnv(t)
+
+	cfg, err := loadConfig(t)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate of a loaded configuration: %v", err)
+	}
+
+	cfg.Webhook.Queue = "redis"
+	cfg.Server.TrustedProxies = []string{"10.0.0.0/8", "proxy.local"}
+	_, invalid := problemKeys(t, cfg.Validate())
+	if !reflect.DeepEqual(invalid, []string{"SERVER_TRUSTED_PROXIES", "WEBHOOK_QUEUE"}) {
+		t.Errorf("invalid = %v, want the trusted proxy and queue reported", invalid)
+	}
+}
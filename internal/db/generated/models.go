@@ -60,10 +60,12 @@ func (ns NullIndexerStatus) Value() (driver.Value, error) {
 type IndexerType string
 
 const (
-	IndexerTypeNftBids     IndexerType = "nft_bids"
-	IndexerTypeNftPrices   IndexerType = "nft_prices"
-	IndexerTypeTokenBorrow IndexerType = "token_borrow"
-	IndexerTypeTokenPrices IndexerType = "token_prices"
+	IndexerTypeNftBids      IndexerType = "nft_bids"
+	IndexerTypeNftPrices    IndexerType = "nft_prices"
+	IndexerTypeTokenBorrow  IndexerType = "token_borrow"
+	IndexerTypeTokenPrices  IndexerType = "token_prices"
+	IndexerTypeTokenHolders IndexerType = "token_holders"
+	IndexerTypeProgramLogs  IndexerType = "program_logs"
 )
 
 func (e *IndexerType) Scan(src interface{}) error {
@@ -101,6 +103,16 @@ func (ns NullIndexerType) Value() (driver.Value, error) {
 	return string(ns.IndexerType), nil
 }
 
+type AuditEvent struct {
+	ID          int64              `json:"id"`
+	ActorUserID pgtype.UUID        `json:"actorUserId"`
+	Action      string             `json:"action"`
+	EntityType  string             `json:"entityType"`
+	EntityID    pgtype.UUID        `json:"entityId"`
+	Metadata    json.RawMessage    `json:"metadata"`
+	CreatedAt   pgtype.Timestamptz `json:"createdAt"`
+}
+
 type DbCredential struct {
 	ID         pgtype.UUID        `json:"id"`
 	UserID     pgtype.UUID        `json:"userId"`
@@ -112,6 +124,21 @@ type DbCredential struct {
 	DbSslMode  string             `json:"dbSslMode"`
 	CreatedAt  pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt  pgtype.Timestamptz `json:"updatedAt"`
+	TableOwner pgtype.Text        `json:"tableOwner"`
+}
+
+type FailedPayload struct {
+	ID        int64              `json:"id"`
+	IndexerID pgtype.UUID        `json:"indexerId"`
+	WebhookID string             `json:"webhookId"`
+	RequestID string             `json:"requestId"`
+	Slot      int64              `json:"slot"`
+	Signature string             `json:"signature"`
+	Body      json.RawMessage    `json:"body"`
+	Error     string             `json:"error"`
+	Attempts  int32              `json:"attempts"`
+	CreatedAt pgtype.Timestamptz `json:"createdAt"`
+	UpdatedAt pgtype.Timestamptz `json:"updatedAt"`
 }
 
 type Indexer struct {
@@ -127,6 +154,26 @@ type Indexer struct {
 	ErrorMessage   pgtype.Text        `json:"errorMessage"`
 	CreatedAt      pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt      pgtype.Timestamptz `json:"updatedAt"`
+	LastError      pgtype.Text        `json:"lastError"`
+	LastErrorAt    pgtype.Timestamptz `json:"lastErrorAt"`
+	DeletedAt      pgtype.Timestamptz `json:"deletedAt"`
+}
+
+type IndexerBackfill struct {
+	IndexerID       pgtype.UUID        `json:"indexerId"`
+	Address         string             `json:"address"`
+	BeforeSignature pgtype.Text        `json:"beforeSignature"`
+	Fetched         int32              `json:"fetched"`
+	Completed       bool               `json:"completed"`
+	CreatedAt       pgtype.Timestamptz `json:"createdAt"`
+	UpdatedAt       pgtype.Timestamptz `json:"updatedAt"`
+}
+
+type IndexerWebhookSecret struct {
+	IndexerID pgtype.UUID        `json:"indexerId"`
+	Secret    string             `json:"secret"`
+	CreatedAt pgtype.Timestamptz `json:"createdAt"`
+	UpdatedAt pgtype.Timestamptz `json:"updatedAt"`
 }
 
 type IndexingLog struct {
@@ -138,10 +185,44 @@ type IndexingLog struct {
 	CreatedAt pgtype.Timestamptz `json:"createdAt"`
 }
 
+type PayloadJob struct {
+	ID          int64              `json:"id"`
+	WebhookID   string             `json:"webhookId"`
+	RequestID   string             `json:"requestId"`
+	Body        json.RawMessage    `json:"body"`
+	Status      string             `json:"status"`
+	Attempts    int32              `json:"attempts"`
+	LastError   pgtype.Text        `json:"lastError"`
+	NextRetryAt pgtype.Timestamptz `json:"nextRetryAt"`
+	CreatedAt   pgtype.Timestamptz `json:"createdAt"`
+	UpdatedAt   pgtype.Timestamptz `json:"updatedAt"`
+}
+
+type RawPayload struct {
+	ID         int64              `json:"id"`
+	IndexerID  pgtype.UUID        `json:"indexerId"`
+	WebhookID  string             `json:"webhookId"`
+	Slot       int64              `json:"slot"`
+	Signature  string             `json:"signature"`
+	Body       json.RawMessage    `json:"body"`
+	ReceivedAt pgtype.Timestamptz `json:"receivedAt"`
+	Batched    bool               `json:"batched"`
+}
+
+type RefreshToken struct {
+	ID        pgtype.UUID        `json:"id"`
+	UserID    pgtype.UUID        `json:"userId"`
+	TokenHash string             `json:"tokenHash"`
+	ExpiresAt pgtype.Timestamptz `json:"expiresAt"`
+	RevokedAt pgtype.Timestamptz `json:"revokedAt"`
+	CreatedAt pgtype.Timestamptz `json:"createdAt"`
+}
+
 type User struct {
 	ID           pgtype.UUID        `json:"id"`
 	Email        string             `json:"email"`
 	PasswordHash string             `json:"passwordHash"`
 	CreatedAt    pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt    pgtype.Timestamptz `json:"updatedAt"`
+	MaxIndexers  pgtype.Int4        `json:"maxIndexers"`
 }
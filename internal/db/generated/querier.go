@@ -11,25 +11,66 @@ import (
 )
 
 type Querier interface {
+	ClaimPayloadJob(ctx context.Context) (PayloadJob, error)
+	ClearIndexerLastError(ctx context.Context, id pgtype.UUID) (Indexer, error)
+	ClearLastIndexedTime(ctx context.Context, id pgtype.UUID) (Indexer, error)
+	CompletePayloadJob(ctx context.Context, id int64) error
+	CountIndexersByUserID(ctx context.Context, userID pgtype.UUID) (int64, error)
+	CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) error
 	CreateDBCredential(ctx context.Context, arg CreateDBCredentialParams) (DbCredential, error)
+	CreateFailedPayload(ctx context.Context, arg CreateFailedPayloadParams) (FailedPayload, error)
 	CreateIndexer(ctx context.Context, arg CreateIndexerParams) (Indexer, error)
 	CreateIndexingLog(ctx context.Context, arg CreateIndexingLogParams) (IndexingLog, error)
+	CreatePayloadJob(ctx context.Context, arg CreatePayloadJobParams) error
+	CreateRawPayload(ctx context.Context, arg CreateRawPayloadParams) error
+	CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
 	DeleteDBCredential(ctx context.Context, arg DeleteDBCredentialParams) error
+	DeleteFailedPayload(ctx context.Context, id int64) error
+	DeleteFinishedPayloadJobs(ctx context.Context, finishedBefore pgtype.Timestamptz) (int64, error)
 	DeleteIndexer(ctx context.Context, arg DeleteIndexerParams) error
+	DeleteIndexerBackfills(ctx context.Context, indexerID pgtype.UUID) error
+	DeleteRawPayloadsBefore(ctx context.Context, receivedBefore pgtype.Timestamptz) (int64, error)
+	FailPayloadJob(ctx context.Context, arg FailPayloadJobParams) error
 	GetActiveIndexers(ctx context.Context) ([]Indexer, error)
+	GetAuditEventsByUserID(ctx context.Context, arg GetAuditEventsByUserIDParams) ([]AuditEvent, error)
 	GetDBCredentialByID(ctx context.Context, id pgtype.UUID) (DbCredential, error)
 	GetDBCredentialsByUserID(ctx context.Context, userID pgtype.UUID) ([]DbCredential, error)
+	GetDeletedIndexerByID(ctx context.Context, id pgtype.UUID) (Indexer, error)
+	GetExpiredDeletedIndexers(ctx context.Context, deletedAt pgtype.Timestamptz) ([]Indexer, error)
+	GetFailedPayloadByID(ctx context.Context, arg GetFailedPayloadByIDParams) (FailedPayload, error)
+	GetFailedPayloadsByIndexerID(ctx context.Context, arg GetFailedPayloadsByIndexerIDParams) ([]FailedPayload, error)
+	GetIndexerBackfills(ctx context.Context, indexerID pgtype.UUID) ([]IndexerBackfill, error)
 	GetIndexerByID(ctx context.Context, id pgtype.UUID) (Indexer, error)
 	GetIndexerByWebhookID(ctx context.Context, webhookID pgtype.Text) (Indexer, error)
+	GetIndexerWebhookSecret(ctx context.Context, indexerID pgtype.UUID) (IndexerWebhookSecret, error)
 	GetIndexersByUserID(ctx context.Context, userID pgtype.UUID) ([]Indexer, error)
 	GetIndexingLogsByIndexerID(ctx context.Context, arg GetIndexingLogsByIndexerIDParams) ([]IndexingLog, error)
+	GetIndexingLogsSince(ctx context.Context, arg GetIndexingLogsSinceParams) ([]IndexingLog, error)
+	GetRawPayloadsBySlotRange(ctx context.Context, arg GetRawPayloadsBySlotRangeParams) ([]RawPayload, error)
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (RefreshToken, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByID(ctx context.Context, id pgtype.UUID) (User, error)
+	ListDBCredentials(ctx context.Context) ([]DbCredential, error)
+	ListIndexers(ctx context.Context) ([]Indexer, error)
+	RequeueStalePayloadJobs(ctx context.Context, staleBefore pgtype.Timestamptz) (int64, error)
+	RestoreIndexer(ctx context.Context, id pgtype.UUID) (Indexer, error)
+	RetryPayloadJob(ctx context.Context, arg RetryPayloadJobParams) error
+	RevokeRefreshToken(ctx context.Context, tokenHash string) error
+	RevokeUserRefreshTokens(ctx context.Context, userID pgtype.UUID) error
+	SoftDeleteIndexer(ctx context.Context, arg SoftDeleteIndexerParams) (Indexer, error)
 	UpdateDBCredential(ctx context.Context, arg UpdateDBCredentialParams) (DbCredential, error)
+	UpdateDBCredentialPassword(ctx context.Context, arg UpdateDBCredentialPasswordParams) error
+	UpdateFailedPayloadError(ctx context.Context, arg UpdateFailedPayloadErrorParams) error
+	UpdateIndexerLastError(ctx context.Context, arg UpdateIndexerLastErrorParams) (Indexer, error)
+	UpdateIndexerParams(ctx context.Context, arg UpdateIndexerParamsParams) (Indexer, error)
 	UpdateIndexerStatus(ctx context.Context, arg UpdateIndexerStatusParams) (Indexer, error)
+	UpdateIndexerTargetTable(ctx context.Context, arg UpdateIndexerTargetTableParams) (Indexer, error)
 	UpdateIndexerWebhookID(ctx context.Context, arg UpdateIndexerWebhookIDParams) (Indexer, error)
 	UpdateLastIndexedTime(ctx context.Context, id pgtype.UUID) (Indexer, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error
+	UpsertIndexerBackfill(ctx context.Context, arg UpsertIndexerBackfillParams) (IndexerBackfill, error)
+	UpsertIndexerWebhookSecret(ctx context.Context, arg UpsertIndexerWebhookSecretParams) (IndexerWebhookSecret, error)
 }
 
 var _ Querier = (*Queries)(nil)
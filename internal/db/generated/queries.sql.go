@@ -12,6 +12,156 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const claimPayloadJob = `-- name: ClaimPayloadJob :one
+UPDATE payload_jobs
+SET status = 'processing', attempts = attempts + 1, updated_at = NOW()
+WHERE id = (
+    SELECT id FROM payload_jobs
+    WHERE status = 'pending' AND next_retry_at <= NOW()
+    ORDER BY next_retry_at ASC, id ASC
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, webhook_id, request_id, body, status, attempts, last_error, next_retry_at, created_at, updated_at
+`
+
+func (q *Queries) ClaimPayloadJob(ctx context.Context) (PayloadJob, error) {
+	row := q.db.QueryRow(ctx, claimPayloadJob)
+	var i PayloadJob
+	err := row.Scan(
+		&i.ID,
+		&i.WebhookID,
+		&i.RequestID,
+		&i.Body,
+		&i.Status,
+		&i.Attempts,
+		&i.LastError,
+		&i.NextRetryAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const clearIndexerLastError = `-- name: ClearIndexerLastError :one
+UPDATE indexers
+SET
+    last_error = NULL,
+    last_error_at = NULL,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at, last_error, last_error_at, deleted_at
+`
+
+func (q *Queries) ClearIndexerLastError(ctx context.Context, id pgtype.UUID) (Indexer, error) {
+	row := q.db.QueryRow(ctx, clearIndexerLastError, id)
+	var i Indexer
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.DbCredentialID,
+		&i.IndexerType,
+		&i.Params,
+		&i.TargetTable,
+		&i.WebhookID,
+		&i.Status,
+		&i.LastIndexedAt,
+		&i.ErrorMessage,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const clearLastIndexedTime = `-- name: ClearLastIndexedTime :one
+UPDATE indexers
+SET
+    last_indexed_at = NULL,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at, last_error, last_error_at, deleted_at
+`
+
+func (q *Queries) ClearLastIndexedTime(ctx context.Context, id pgtype.UUID) (Indexer, error) {
+	row := q.db.QueryRow(ctx, clearLastIndexedTime, id)
+	var i Indexer
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.DbCredentialID,
+		&i.IndexerType,
+		&i.Params,
+		&i.TargetTable,
+		&i.WebhookID,
+		&i.Status,
+		&i.LastIndexedAt,
+		&i.ErrorMessage,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const completePayloadJob = `-- name: CompletePayloadJob :exec
+UPDATE payload_jobs
+SET status = 'done', last_error = NULL, updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) CompletePayloadJob(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, completePayloadJob, id)
+	return err
+}
+
+const countIndexersByUserID = `-- name: CountIndexersByUserID :one
+SELECT COUNT(*) FROM indexers
+WHERE user_id = $1 AND status = 'active' AND deleted_at IS NULL
+`
+
+func (q *Queries) CountIndexersByUserID(ctx context.Context, userID pgtype.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countIndexersByUserID, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createAuditEvent = `-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (
+    actor_user_id,
+    action,
+    entity_type,
+    entity_id,
+    metadata
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+`
+
+type CreateAuditEventParams struct {
+	ActorUserID pgtype.UUID     `json:"actorUserId"`
+	Action      string          `json:"action"`
+	EntityType  string          `json:"entityType"`
+	EntityID    pgtype.UUID     `json:"entityId"`
+	Metadata    json.RawMessage `json:"metadata"`
+}
+
+func (q *Queries) CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) error {
+	_, err := q.db.Exec(ctx, createAuditEvent,
+		arg.ActorUserID,
+		arg.Action,
+		arg.EntityType,
+		arg.EntityID,
+		arg.Metadata,
+	)
+	return err
+}
+
 const createDBCredential = `-- name: CreateDBCredential :one
 INSERT INTO db_credentials (
     user_id,
@@ -20,10 +170,11 @@ INSERT INTO db_credentials (
     db_name,
     db_user,
     db_password,
-    db_ssl_mode
+    db_ssl_mode,
+    table_owner
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7
-) RETURNING id, user_id, db_host, db_port, db_name, db_user, db_password, db_ssl_mode, created_at, updated_at
+    $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING id, user_id, db_host, db_port, db_name, db_user, db_password, db_ssl_mode, created_at, updated_at, table_owner
 `
 
 type CreateDBCredentialParams struct {
@@ -34,6 +185,7 @@ type CreateDBCredentialParams struct {
 	DbUser     string      `json:"dbUser"`
 	DbPassword string      `json:"dbPassword"`
 	DbSslMode  string      `json:"dbSslMode"`
+	TableOwner pgtype.Text `json:"tableOwner"`
 }
 
 func (q *Queries) CreateDBCredential(ctx context.Context, arg CreateDBCredentialParams) (DbCredential, error) {
@@ -45,6 +197,7 @@ func (q *Queries) CreateDBCredential(ctx context.Context, arg CreateDBCredential
 		arg.DbUser,
 		arg.DbPassword,
 		arg.DbSslMode,
+		arg.TableOwner,
 	)
 	var i DbCredential
 	err := row.Scan(
@@ -58,6 +211,61 @@ func (q *Queries) CreateDBCredential(ctx context.Context, arg CreateDBCredential
 		&i.DbSslMode,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TableOwner,
+	)
+	return i, err
+}
+
+const createFailedPayload = `-- name: CreateFailedPayload :one
+INSERT INTO failed_payloads (
+    indexer_id,
+    webhook_id,
+    request_id,
+    slot,
+    signature,
+    body,
+    error,
+    attempts
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING id, indexer_id, webhook_id, request_id, slot, signature, body, error, attempts, created_at, updated_at
+`
+
+type CreateFailedPayloadParams struct {
+	IndexerID pgtype.UUID     `json:"indexerId"`
+	WebhookID string          `json:"webhookId"`
+	RequestID string          `json:"requestId"`
+	Slot      int64           `json:"slot"`
+	Signature string          `json:"signature"`
+	Body      json.RawMessage `json:"body"`
+	Error     string          `json:"error"`
+	Attempts  int32           `json:"attempts"`
+}
+
+func (q *Queries) CreateFailedPayload(ctx context.Context, arg CreateFailedPayloadParams) (FailedPayload, error) {
+	row := q.db.QueryRow(ctx, createFailedPayload,
+		arg.IndexerID,
+		arg.WebhookID,
+		arg.RequestID,
+		arg.Slot,
+		arg.Signature,
+		arg.Body,
+		arg.Error,
+		arg.Attempts,
+	)
+	var i FailedPayload
+	err := row.Scan(
+		&i.ID,
+		&i.IndexerID,
+		&i.WebhookID,
+		&i.RequestID,
+		&i.Slot,
+		&i.Signature,
+		&i.Body,
+		&i.Error,
+		&i.Attempts,
+		&i.CreatedAt,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
@@ -72,7 +280,7 @@ INSERT INTO indexers (
     status
 ) VALUES (
     $1, $2, $3, $4, $5, $6
-) RETURNING id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at
+) RETURNING id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at, last_error, last_error_at, deleted_at
 `
 
 type CreateIndexerParams struct {
@@ -107,6 +315,9 @@ func (q *Queries) CreateIndexer(ctx context.Context, arg CreateIndexerParams) (I
 		&i.ErrorMessage,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -148,13 +359,98 @@ func (q *Queries) CreateIndexingLog(ctx context.Context, arg CreateIndexingLogPa
 	return i, err
 }
 
+const createPayloadJob = `-- name: CreatePayloadJob :exec
+INSERT INTO payload_jobs (
+    webhook_id,
+    request_id,
+    body
+) VALUES (
+    $1, $2, $3
+)
+`
+
+type CreatePayloadJobParams struct {
+	WebhookID string          `json:"webhookId"`
+	RequestID string          `json:"requestId"`
+	Body      json.RawMessage `json:"body"`
+}
+
+func (q *Queries) CreatePayloadJob(ctx context.Context, arg CreatePayloadJobParams) error {
+	_, err := q.db.Exec(ctx, createPayloadJob, arg.WebhookID, arg.RequestID, arg.Body)
+	return err
+}
+
+const createRawPayload = `-- name: CreateRawPayload :exec
+INSERT INTO raw_payloads (
+    indexer_id,
+    webhook_id,
+    slot,
+    signature,
+    body,
+    batched
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+`
+
+type CreateRawPayloadParams struct {
+	IndexerID pgtype.UUID     `json:"indexerId"`
+	WebhookID string          `json:"webhookId"`
+	Slot      int64           `json:"slot"`
+	Signature string          `json:"signature"`
+	Body      json.RawMessage `json:"body"`
+	Batched   bool            `json:"batched"`
+}
+
+func (q *Queries) CreateRawPayload(ctx context.Context, arg CreateRawPayloadParams) error {
+	_, err := q.db.Exec(ctx, createRawPayload,
+		arg.IndexerID,
+		arg.WebhookID,
+		arg.Slot,
+		arg.Signature,
+		arg.Body,
+		arg.Batched,
+	)
+	return err
+}
+
+const createRefreshToken = `-- name: CreateRefreshToken :one
+INSERT INTO refresh_tokens (
+    user_id,
+    token_hash,
+    expires_at
+) VALUES (
+    $1, $2, $3
+) RETURNING id, user_id, token_hash, expires_at, revoked_at, created_at
+`
+
+type CreateRefreshTokenParams struct {
+	UserID    pgtype.UUID        `json:"userId"`
+	TokenHash string             `json:"tokenHash"`
+	ExpiresAt pgtype.Timestamptz `json:"expiresAt"`
+}
+
+func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error) {
+	row := q.db.QueryRow(ctx, createRefreshToken, arg.UserID, arg.TokenHash, arg.ExpiresAt)
+	var i RefreshToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (
     email,
     password_hash
 ) VALUES (
     $1, $2
-) RETURNING id, email, password_hash, created_at, updated_at
+) RETURNING id, email, password_hash, created_at, updated_at, max_indexers
 `
 
 type CreateUserParams struct {
@@ -171,6 +467,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.PasswordHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.MaxIndexers,
 	)
 	return i, err
 }
@@ -190,6 +487,29 @@ func (q *Queries) DeleteDBCredential(ctx context.Context, arg DeleteDBCredential
 	return err
 }
 
+const deleteFailedPayload = `-- name: DeleteFailedPayload :exec
+DELETE FROM failed_payloads
+WHERE id = $1
+`
+
+func (q *Queries) DeleteFailedPayload(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, deleteFailedPayload, id)
+	return err
+}
+
+const deleteFinishedPayloadJobs = `-- name: DeleteFinishedPayloadJobs :execrows
+DELETE FROM payload_jobs
+WHERE status IN ('done', 'failed') AND updated_at < $1
+`
+
+func (q *Queries) DeleteFinishedPayloadJobs(ctx context.Context, finishedBefore pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteFinishedPayloadJobs, finishedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteIndexer = `-- name: DeleteIndexer :exec
 DELETE FROM indexers
 WHERE id = $1 AND user_id = $2
@@ -205,9 +525,48 @@ func (q *Queries) DeleteIndexer(ctx context.Context, arg DeleteIndexerParams) er
 	return err
 }
 
+const deleteIndexerBackfills = `-- name: DeleteIndexerBackfills :exec
+DELETE FROM indexer_backfills
+WHERE indexer_id = $1
+`
+
+func (q *Queries) DeleteIndexerBackfills(ctx context.Context, indexerID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteIndexerBackfills, indexerID)
+	return err
+}
+
+const deleteRawPayloadsBefore = `-- name: DeleteRawPayloadsBefore :execrows
+DELETE FROM raw_payloads
+WHERE received_at < $1
+`
+
+func (q *Queries) DeleteRawPayloadsBefore(ctx context.Context, receivedBefore pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteRawPayloadsBefore, receivedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const failPayloadJob = `-- name: FailPayloadJob :exec
+UPDATE payload_jobs
+SET status = 'failed', last_error = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type FailPayloadJobParams struct {
+	ID        int64       `json:"id"`
+	LastError pgtype.Text `json:"lastError"`
+}
+
+func (q *Queries) FailPayloadJob(ctx context.Context, arg FailPayloadJobParams) error {
+	_, err := q.db.Exec(ctx, failPayloadJob, arg.ID, arg.LastError)
+	return err
+}
+
 const getActiveIndexers = `-- name: GetActiveIndexers :many
-SELECT id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at FROM indexers
-WHERE status = 'active'
+SELECT id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at, last_error, last_error_at, deleted_at FROM indexers
+WHERE status = 'active' AND deleted_at IS NULL
 `
 
 func (q *Queries) GetActiveIndexers(ctx context.Context) ([]Indexer, error) {
@@ -232,6 +591,50 @@ func (q *Queries) GetActiveIndexers(ctx context.Context) ([]Indexer, error) {
 			&i.ErrorMessage,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAuditEventsByUserID = `-- name: GetAuditEventsByUserID :many
+SELECT id, actor_user_id, action, entity_type, entity_id, metadata, created_at FROM audit_events
+WHERE actor_user_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2 OFFSET $3
+`
+
+type GetAuditEventsByUserIDParams struct {
+	ActorUserID pgtype.UUID `json:"actorUserId"`
+	Limit       int32       `json:"limit"`
+	Offset      int32       `json:"offset"`
+}
+
+func (q *Queries) GetAuditEventsByUserID(ctx context.Context, arg GetAuditEventsByUserIDParams) ([]AuditEvent, error) {
+	rows, err := q.db.Query(ctx, getAuditEventsByUserID, arg.ActorUserID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AuditEvent{}
+	for rows.Next() {
+		var i AuditEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.ActorUserID,
+			&i.Action,
+			&i.EntityType,
+			&i.EntityID,
+			&i.Metadata,
+			&i.CreatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -244,7 +647,7 @@ func (q *Queries) GetActiveIndexers(ctx context.Context) ([]Indexer, error) {
 }
 
 const getDBCredentialByID = `-- name: GetDBCredentialByID :one
-SELECT id, user_id, db_host, db_port, db_name, db_user, db_password, db_ssl_mode, created_at, updated_at FROM db_credentials
+SELECT id, user_id, db_host, db_port, db_name, db_user, db_password, db_ssl_mode, created_at, updated_at, table_owner FROM db_credentials
 WHERE id = $1 LIMIT 1
 `
 
@@ -262,12 +665,13 @@ func (q *Queries) GetDBCredentialByID(ctx context.Context, id pgtype.UUID) (DbCr
 		&i.DbSslMode,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TableOwner,
 	)
 	return i, err
 }
 
 const getDBCredentialsByUserID = `-- name: GetDBCredentialsByUserID :many
-SELECT id, user_id, db_host, db_port, db_name, db_user, db_password, db_ssl_mode, created_at, updated_at FROM db_credentials
+SELECT id, user_id, db_host, db_port, db_name, db_user, db_password, db_ssl_mode, created_at, updated_at, table_owner FROM db_credentials
 WHERE user_id = $1
 `
 
@@ -291,6 +695,7 @@ func (q *Queries) GetDBCredentialsByUserID(ctx context.Context, userID pgtype.UU
 			&i.DbSslMode,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.TableOwner,
 		); err != nil {
 			return nil, err
 		}
@@ -302,38 +707,13 @@ func (q *Queries) GetDBCredentialsByUserID(ctx context.Context, userID pgtype.UU
 	return items, nil
 }
 
-const getIndexerByID = `-- name: GetIndexerByID :one
-SELECT id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at FROM indexers
-WHERE id = $1 LIMIT 1
-`
-
-func (q *Queries) GetIndexerByID(ctx context.Context, id pgtype.UUID) (Indexer, error) {
-	row := q.db.QueryRow(ctx, getIndexerByID, id)
-	var i Indexer
-	err := row.Scan(
-		&i.ID,
-		&i.UserID,
-		&i.DbCredentialID,
-		&i.IndexerType,
-		&i.Params,
-		&i.TargetTable,
-		&i.WebhookID,
-		&i.Status,
-		&i.LastIndexedAt,
-		&i.ErrorMessage,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-	)
-	return i, err
-}
-
-const getIndexerByWebhookID = `-- name: GetIndexerByWebhookID :one
-SELECT id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at FROM indexers
-WHERE webhook_id = $1 LIMIT 1
+const getDeletedIndexerByID = `-- name: GetDeletedIndexerByID :one
+SELECT id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at, last_error, last_error_at, deleted_at FROM indexers
+WHERE id = $1 AND deleted_at IS NOT NULL LIMIT 1
 `
 
-func (q *Queries) GetIndexerByWebhookID(ctx context.Context, webhookID pgtype.Text) (Indexer, error) {
-	row := q.db.QueryRow(ctx, getIndexerByWebhookID, webhookID)
+func (q *Queries) GetDeletedIndexerByID(ctx context.Context, id pgtype.UUID) (Indexer, error) {
+	row := q.db.QueryRow(ctx, getDeletedIndexerByID, id)
 	var i Indexer
 	err := row.Scan(
 		&i.ID,
@@ -348,17 +728,21 @@ func (q *Queries) GetIndexerByWebhookID(ctx context.Context, webhookID pgtype.Te
 		&i.ErrorMessage,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
-const getIndexersByUserID = `-- name: GetIndexersByUserID :many
-SELECT id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at FROM indexers
-WHERE user_id = $1
+const getExpiredDeletedIndexers = `-- name: GetExpiredDeletedIndexers :many
+SELECT id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at, last_error, last_error_at, deleted_at FROM indexers
+WHERE deleted_at IS NOT NULL AND deleted_at < $1
+ORDER BY deleted_at ASC
 `
 
-func (q *Queries) GetIndexersByUserID(ctx context.Context, userID pgtype.UUID) ([]Indexer, error) {
-	rows, err := q.db.Query(ctx, getIndexersByUserID, userID)
+func (q *Queries) GetExpiredDeletedIndexers(ctx context.Context, deletedAt pgtype.Timestamptz) ([]Indexer, error) {
+	rows, err := q.db.Query(ctx, getExpiredDeletedIndexers, deletedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -379,6 +763,9 @@ func (q *Queries) GetIndexersByUserID(ctx context.Context, userID pgtype.UUID) (
 			&i.ErrorMessage,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -390,35 +777,69 @@ func (q *Queries) GetIndexersByUserID(ctx context.Context, userID pgtype.UUID) (
 	return items, nil
 }
 
-const getIndexingLogsByIndexerID = `-- name: GetIndexingLogsByIndexerID :many
-SELECT id, indexer_id, event_type, message, details, created_at FROM indexing_logs
+const getFailedPayloadByID = `-- name: GetFailedPayloadByID :one
+SELECT id, indexer_id, webhook_id, request_id, slot, signature, body, error, attempts, created_at, updated_at FROM failed_payloads
+WHERE id = $1 AND indexer_id = $2
+`
+
+type GetFailedPayloadByIDParams struct {
+	ID        int64       `json:"id"`
+	IndexerID pgtype.UUID `json:"indexerId"`
+}
+
+func (q *Queries) GetFailedPayloadByID(ctx context.Context, arg GetFailedPayloadByIDParams) (FailedPayload, error) {
+	row := q.db.QueryRow(ctx, getFailedPayloadByID, arg.ID, arg.IndexerID)
+	var i FailedPayload
+	err := row.Scan(
+		&i.ID,
+		&i.IndexerID,
+		&i.WebhookID,
+		&i.RequestID,
+		&i.Slot,
+		&i.Signature,
+		&i.Body,
+		&i.Error,
+		&i.Attempts,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getFailedPayloadsByIndexerID = `-- name: GetFailedPayloadsByIndexerID :many
+SELECT id, indexer_id, webhook_id, request_id, slot, signature, body, error, attempts, created_at, updated_at FROM failed_payloads
 WHERE indexer_id = $1
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3
 `
 
-type GetIndexingLogsByIndexerIDParams struct {
+type GetFailedPayloadsByIndexerIDParams struct {
 	IndexerID pgtype.UUID `json:"indexerId"`
 	Limit     int32       `json:"limit"`
 	Offset    int32       `json:"offset"`
 }
 
-func (q *Queries) GetIndexingLogsByIndexerID(ctx context.Context, arg GetIndexingLogsByIndexerIDParams) ([]IndexingLog, error) {
-	rows, err := q.db.Query(ctx, getIndexingLogsByIndexerID, arg.IndexerID, arg.Limit, arg.Offset)
+func (q *Queries) GetFailedPayloadsByIndexerID(ctx context.Context, arg GetFailedPayloadsByIndexerIDParams) ([]FailedPayload, error) {
+	rows, err := q.db.Query(ctx, getFailedPayloadsByIndexerID, arg.IndexerID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []IndexingLog{}
+	items := []FailedPayload{}
 	for rows.Next() {
-		var i IndexingLog
+		var i FailedPayload
 		if err := rows.Scan(
 			&i.ID,
 			&i.IndexerID,
-			&i.EventType,
-			&i.Message,
-			&i.Details,
+			&i.WebhookID,
+			&i.RequestID,
+			&i.Slot,
+			&i.Signature,
+			&i.Body,
+			&i.Error,
+			&i.Attempts,
 			&i.CreatedAt,
+			&i.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -430,12 +851,316 @@ func (q *Queries) GetIndexingLogsByIndexerID(ctx context.Context, arg GetIndexin
 	return items, nil
 }
 
-const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, password_hash, created_at, updated_at FROM users
-WHERE email = $1 LIMIT 1
+const getIndexerBackfills = `-- name: GetIndexerBackfills :many
+SELECT indexer_id, address, before_signature, fetched, completed, created_at, updated_at FROM indexer_backfills
+WHERE indexer_id = $1
+ORDER BY address
 `
 
-func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+func (q *Queries) GetIndexerBackfills(ctx context.Context, indexerID pgtype.UUID) ([]IndexerBackfill, error) {
+	rows, err := q.db.Query(ctx, getIndexerBackfills, indexerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []IndexerBackfill{}
+	for rows.Next() {
+		var i IndexerBackfill
+		if err := rows.Scan(
+			&i.IndexerID,
+			&i.Address,
+			&i.BeforeSignature,
+			&i.Fetched,
+			&i.Completed,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getIndexerByID = `-- name: GetIndexerByID :one
+SELECT id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at, last_error, last_error_at, deleted_at FROM indexers
+WHERE id = $1 AND deleted_at IS NULL LIMIT 1
+`
+
+func (q *Queries) GetIndexerByID(ctx context.Context, id pgtype.UUID) (Indexer, error) {
+	row := q.db.QueryRow(ctx, getIndexerByID, id)
+	var i Indexer
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.DbCredentialID,
+		&i.IndexerType,
+		&i.Params,
+		&i.TargetTable,
+		&i.WebhookID,
+		&i.Status,
+		&i.LastIndexedAt,
+		&i.ErrorMessage,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getIndexerByWebhookID = `-- name: GetIndexerByWebhookID :one
+SELECT id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at, last_error, last_error_at, deleted_at FROM indexers
+WHERE webhook_id = $1 LIMIT 1
+`
+
+func (q *Queries) GetIndexerByWebhookID(ctx context.Context, webhookID pgtype.Text) (Indexer, error) {
+	row := q.db.QueryRow(ctx, getIndexerByWebhookID, webhookID)
+	var i Indexer
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.DbCredentialID,
+		&i.IndexerType,
+		&i.Params,
+		&i.TargetTable,
+		&i.WebhookID,
+		&i.Status,
+		&i.LastIndexedAt,
+		&i.ErrorMessage,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getIndexerWebhookSecret = `-- name: GetIndexerWebhookSecret :one
+SELECT indexer_id, secret, created_at, updated_at FROM indexer_webhook_secrets
+WHERE indexer_id = $1
+`
+
+func (q *Queries) GetIndexerWebhookSecret(ctx context.Context, indexerID pgtype.UUID) (IndexerWebhookSecret, error) {
+	row := q.db.QueryRow(ctx, getIndexerWebhookSecret, indexerID)
+	var i IndexerWebhookSecret
+	err := row.Scan(
+		&i.IndexerID,
+		&i.Secret,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getIndexersByUserID = `-- name: GetIndexersByUserID :many
+SELECT id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at, last_error, last_error_at, deleted_at FROM indexers
+WHERE user_id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) GetIndexersByUserID(ctx context.Context, userID pgtype.UUID) ([]Indexer, error) {
+	rows, err := q.db.Query(ctx, getIndexersByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Indexer{}
+	for rows.Next() {
+		var i Indexer
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.DbCredentialID,
+			&i.IndexerType,
+			&i.Params,
+			&i.TargetTable,
+			&i.WebhookID,
+			&i.Status,
+			&i.LastIndexedAt,
+			&i.ErrorMessage,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getIndexingLogsByIndexerID = `-- name: GetIndexingLogsByIndexerID :many
+SELECT id, indexer_id, event_type, message, details, created_at FROM indexing_logs
+WHERE indexer_id = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3
+`
+
+type GetIndexingLogsByIndexerIDParams struct {
+	IndexerID pgtype.UUID `json:"indexerId"`
+	Limit     int32       `json:"limit"`
+	Offset    int32       `json:"offset"`
+}
+
+func (q *Queries) GetIndexingLogsByIndexerID(ctx context.Context, arg GetIndexingLogsByIndexerIDParams) ([]IndexingLog, error) {
+	rows, err := q.db.Query(ctx, getIndexingLogsByIndexerID, arg.IndexerID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []IndexingLog{}
+	for rows.Next() {
+		var i IndexingLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.IndexerID,
+			&i.EventType,
+			&i.Message,
+			&i.Details,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getIndexingLogsSince = `-- name: GetIndexingLogsSince :many
+SELECT id, indexer_id, event_type, message, details, created_at FROM indexing_logs
+WHERE indexer_id = $1
+  AND (created_at, id) > ($2::timestamptz, $3::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT $4
+`
+
+type GetIndexingLogsSinceParams struct {
+	IndexerID      pgtype.UUID        `json:"indexerId"`
+	AfterCreatedAt pgtype.Timestamptz `json:"afterCreatedAt"`
+	AfterID        pgtype.UUID        `json:"afterId"`
+	Limit          int32              `json:"limit"`
+}
+
+func (q *Queries) GetIndexingLogsSince(ctx context.Context, arg GetIndexingLogsSinceParams) ([]IndexingLog, error) {
+	rows, err := q.db.Query(ctx, getIndexingLogsSince,
+		arg.IndexerID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []IndexingLog{}
+	for rows.Next() {
+		var i IndexingLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.IndexerID,
+			&i.EventType,
+			&i.Message,
+			&i.Details,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRawPayloadsBySlotRange = `-- name: GetRawPayloadsBySlotRange :many
+SELECT id, indexer_id, webhook_id, slot, signature, body, received_at, batched FROM raw_payloads
+WHERE indexer_id = $1
+  AND slot >= $2
+  AND slot <= $3
+ORDER BY slot ASC, id ASC
+LIMIT $4
+`
+
+type GetRawPayloadsBySlotRangeParams struct {
+	IndexerID pgtype.UUID `json:"indexerId"`
+	FromSlot  int64       `json:"fromSlot"`
+	ToSlot    int64       `json:"toSlot"`
+	RowLimit  int32       `json:"rowLimit"`
+}
+
+func (q *Queries) GetRawPayloadsBySlotRange(ctx context.Context, arg GetRawPayloadsBySlotRangeParams) ([]RawPayload, error) {
+	rows, err := q.db.Query(ctx, getRawPayloadsBySlotRange,
+		arg.IndexerID,
+		arg.FromSlot,
+		arg.ToSlot,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []RawPayload{}
+	for rows.Next() {
+		var i RawPayload
+		if err := rows.Scan(
+			&i.ID,
+			&i.IndexerID,
+			&i.WebhookID,
+			&i.Slot,
+			&i.Signature,
+			&i.Body,
+			&i.ReceivedAt,
+			&i.Batched,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRefreshTokenByHash = `-- name: GetRefreshTokenByHash :one
+SELECT id, user_id, token_hash, expires_at, revoked_at, created_at FROM refresh_tokens
+WHERE token_hash = $1 LIMIT 1
+`
+
+func (q *Queries) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	row := q.db.QueryRow(ctx, getRefreshTokenByHash, tokenHash)
+	var i RefreshToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT id, email, password_hash, created_at, updated_at, max_indexers FROM users
+WHERE email = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
 	row := q.db.QueryRow(ctx, getUserByEmail, email)
 	var i User
 	err := row.Scan(
@@ -444,12 +1169,13 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.PasswordHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.MaxIndexers,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, email, password_hash, created_at, updated_at FROM users
+SELECT id, email, password_hash, created_at, updated_at, max_indexers FROM users
 WHERE id = $1 LIMIT 1
 `
 
@@ -462,6 +1188,207 @@ func (q *Queries) GetUserByID(ctx context.Context, id pgtype.UUID) (User, error)
 		&i.PasswordHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.MaxIndexers,
+	)
+	return i, err
+}
+
+const listDBCredentials = `-- name: ListDBCredentials :many
+SELECT id, user_id, db_host, db_port, db_name, db_user, db_password, db_ssl_mode, created_at, updated_at, table_owner FROM db_credentials
+ORDER BY created_at
+`
+
+func (q *Queries) ListDBCredentials(ctx context.Context) ([]DbCredential, error) {
+	rows, err := q.db.Query(ctx, listDBCredentials)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []DbCredential{}
+	for rows.Next() {
+		var i DbCredential
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.DbHost,
+			&i.DbPort,
+			&i.DbName,
+			&i.DbUser,
+			&i.DbPassword,
+			&i.DbSslMode,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.TableOwner,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listIndexers = `-- name: ListIndexers :many
+SELECT id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at, last_error, last_error_at, deleted_at FROM indexers
+ORDER BY created_at ASC
+`
+
+func (q *Queries) ListIndexers(ctx context.Context) ([]Indexer, error) {
+	rows, err := q.db.Query(ctx, listIndexers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Indexer{}
+	for rows.Next() {
+		var i Indexer
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.DbCredentialID,
+			&i.IndexerType,
+			&i.Params,
+			&i.TargetTable,
+			&i.WebhookID,
+			&i.Status,
+			&i.LastIndexedAt,
+			&i.ErrorMessage,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const requeueStalePayloadJobs = `-- name: RequeueStalePayloadJobs :execrows
+UPDATE payload_jobs
+SET status = 'pending', updated_at = NOW()
+WHERE status = 'processing' AND updated_at < $1
+`
+
+func (q *Queries) RequeueStalePayloadJobs(ctx context.Context, staleBefore pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, requeueStalePayloadJobs, staleBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const restoreIndexer = `-- name: RestoreIndexer :one
+UPDATE indexers
+SET
+    deleted_at = NULL,
+    updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at, last_error, last_error_at, deleted_at
+`
+
+func (q *Queries) RestoreIndexer(ctx context.Context, id pgtype.UUID) (Indexer, error) {
+	row := q.db.QueryRow(ctx, restoreIndexer, id)
+	var i Indexer
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.DbCredentialID,
+		&i.IndexerType,
+		&i.Params,
+		&i.TargetTable,
+		&i.WebhookID,
+		&i.Status,
+		&i.LastIndexedAt,
+		&i.ErrorMessage,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const retryPayloadJob = `-- name: RetryPayloadJob :exec
+UPDATE payload_jobs
+SET status = 'pending', last_error = $2, next_retry_at = $3, updated_at = NOW()
+WHERE id = $1
+`
+
+type RetryPayloadJobParams struct {
+	ID          int64              `json:"id"`
+	LastError   pgtype.Text        `json:"lastError"`
+	NextRetryAt pgtype.Timestamptz `json:"nextRetryAt"`
+}
+
+func (q *Queries) RetryPayloadJob(ctx context.Context, arg RetryPayloadJobParams) error {
+	_, err := q.db.Exec(ctx, retryPayloadJob, arg.ID, arg.LastError, arg.NextRetryAt)
+	return err
+}
+
+const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE token_hash = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	_, err := q.db.Exec(ctx, revokeRefreshToken, tokenHash)
+	return err
+}
+
+const revokeUserRefreshTokens = `-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeUserRefreshTokens(ctx context.Context, userID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, revokeUserRefreshTokens, userID)
+	return err
+}
+
+const softDeleteIndexer = `-- name: SoftDeleteIndexer :one
+UPDATE indexers
+SET
+    deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+RETURNING id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at, last_error, last_error_at, deleted_at
+`
+
+type SoftDeleteIndexerParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"userId"`
+}
+
+func (q *Queries) SoftDeleteIndexer(ctx context.Context, arg SoftDeleteIndexerParams) (Indexer, error) {
+	row := q.db.QueryRow(ctx, softDeleteIndexer, arg.ID, arg.UserID)
+	var i Indexer
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.DbCredentialID,
+		&i.IndexerType,
+		&i.Params,
+		&i.TargetTable,
+		&i.WebhookID,
+		&i.Status,
+		&i.LastIndexedAt,
+		&i.ErrorMessage,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -475,9 +1402,10 @@ SET
     db_user = $5,
     db_password = $6,
     db_ssl_mode = $7,
+    table_owner = $8,
     updated_at = NOW()
 WHERE id = $1
-RETURNING id, user_id, db_host, db_port, db_name, db_user, db_password, db_ssl_mode, created_at, updated_at
+RETURNING id, user_id, db_host, db_port, db_name, db_user, db_password, db_ssl_mode, created_at, updated_at, table_owner
 `
 
 type UpdateDBCredentialParams struct {
@@ -488,6 +1416,7 @@ type UpdateDBCredentialParams struct {
 	DbUser     string      `json:"dbUser"`
 	DbPassword string      `json:"dbPassword"`
 	DbSslMode  string      `json:"dbSslMode"`
+	TableOwner pgtype.Text `json:"tableOwner"`
 }
 
 func (q *Queries) UpdateDBCredential(ctx context.Context, arg UpdateDBCredentialParams) (DbCredential, error) {
@@ -499,6 +1428,7 @@ func (q *Queries) UpdateDBCredential(ctx context.Context, arg UpdateDBCredential
 		arg.DbUser,
 		arg.DbPassword,
 		arg.DbSslMode,
+		arg.TableOwner,
 	)
 	var i DbCredential
 	err := row.Scan(
@@ -512,6 +1442,114 @@ func (q *Queries) UpdateDBCredential(ctx context.Context, arg UpdateDBCredential
 		&i.DbSslMode,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TableOwner,
+	)
+	return i, err
+}
+
+const updateDBCredentialPassword = `-- name: UpdateDBCredentialPassword :exec
+UPDATE db_credentials
+SET db_password = $2
+WHERE id = $1
+`
+
+type UpdateDBCredentialPasswordParams struct {
+	ID         pgtype.UUID `json:"id"`
+	DbPassword string      `json:"dbPassword"`
+}
+
+func (q *Queries) UpdateDBCredentialPassword(ctx context.Context, arg UpdateDBCredentialPasswordParams) error {
+	_, err := q.db.Exec(ctx, updateDBCredentialPassword, arg.ID, arg.DbPassword)
+	return err
+}
+
+const updateFailedPayloadError = `-- name: UpdateFailedPayloadError :exec
+UPDATE failed_payloads
+SET error = $2, attempts = attempts + 1, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFailedPayloadErrorParams struct {
+	ID    int64  `json:"id"`
+	Error string `json:"error"`
+}
+
+func (q *Queries) UpdateFailedPayloadError(ctx context.Context, arg UpdateFailedPayloadErrorParams) error {
+	_, err := q.db.Exec(ctx, updateFailedPayloadError, arg.ID, arg.Error)
+	return err
+}
+
+const updateIndexerLastError = `-- name: UpdateIndexerLastError :one
+UPDATE indexers
+SET
+    last_error = $2,
+    last_error_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at, last_error, last_error_at, deleted_at
+`
+
+type UpdateIndexerLastErrorParams struct {
+	ID        pgtype.UUID `json:"id"`
+	LastError pgtype.Text `json:"lastError"`
+}
+
+func (q *Queries) UpdateIndexerLastError(ctx context.Context, arg UpdateIndexerLastErrorParams) (Indexer, error) {
+	row := q.db.QueryRow(ctx, updateIndexerLastError, arg.ID, arg.LastError)
+	var i Indexer
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.DbCredentialID,
+		&i.IndexerType,
+		&i.Params,
+		&i.TargetTable,
+		&i.WebhookID,
+		&i.Status,
+		&i.LastIndexedAt,
+		&i.ErrorMessage,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const updateIndexerParams = `-- name: UpdateIndexerParams :one
+UPDATE indexers
+SET
+    params = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at, last_error, last_error_at, deleted_at
+`
+
+type UpdateIndexerParamsParams struct {
+	ID     pgtype.UUID     `json:"id"`
+	Params json.RawMessage `json:"params"`
+}
+
+func (q *Queries) UpdateIndexerParams(ctx context.Context, arg UpdateIndexerParamsParams) (Indexer, error) {
+	row := q.db.QueryRow(ctx, updateIndexerParams, arg.ID, arg.Params)
+	var i Indexer
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.DbCredentialID,
+		&i.IndexerType,
+		&i.Params,
+		&i.TargetTable,
+		&i.WebhookID,
+		&i.Status,
+		&i.LastIndexedAt,
+		&i.ErrorMessage,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -523,7 +1561,7 @@ SET
     error_message = $3,
     updated_at = NOW()
 WHERE id = $1
-RETURNING id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at
+RETURNING id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at, last_error, last_error_at, deleted_at
 `
 
 type UpdateIndexerStatusParams struct {
@@ -548,6 +1586,46 @@ func (q *Queries) UpdateIndexerStatus(ctx context.Context, arg UpdateIndexerStat
 		&i.ErrorMessage,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const updateIndexerTargetTable = `-- name: UpdateIndexerTargetTable :one
+UPDATE indexers
+SET
+    target_table = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at, last_error, last_error_at, deleted_at
+`
+
+type UpdateIndexerTargetTableParams struct {
+	ID          pgtype.UUID `json:"id"`
+	TargetTable string      `json:"targetTable"`
+}
+
+func (q *Queries) UpdateIndexerTargetTable(ctx context.Context, arg UpdateIndexerTargetTableParams) (Indexer, error) {
+	row := q.db.QueryRow(ctx, updateIndexerTargetTable, arg.ID, arg.TargetTable)
+	var i Indexer
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.DbCredentialID,
+		&i.IndexerType,
+		&i.Params,
+		&i.TargetTable,
+		&i.WebhookID,
+		&i.Status,
+		&i.LastIndexedAt,
+		&i.ErrorMessage,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -558,7 +1636,7 @@ SET
     webhook_id = $2,
     updated_at = NOW()
 WHERE id = $1
-RETURNING id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at
+RETURNING id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at, last_error, last_error_at, deleted_at
 `
 
 type UpdateIndexerWebhookIDParams struct {
@@ -582,6 +1660,9 @@ func (q *Queries) UpdateIndexerWebhookID(ctx context.Context, arg UpdateIndexerW
 		&i.ErrorMessage,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -592,7 +1673,7 @@ SET
     last_indexed_at = NOW(),
     updated_at = NOW()
 WHERE id = $1
-RETURNING id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at
+RETURNING id, user_id, db_credential_id, indexer_type, params, target_table, webhook_id, status, last_indexed_at, error_message, created_at, updated_at, last_error, last_error_at, deleted_at
 `
 
 func (q *Queries) UpdateLastIndexedTime(ctx context.Context, id pgtype.UUID) (Indexer, error) {
@@ -611,6 +1692,101 @@ func (q *Queries) UpdateLastIndexedTime(ctx context.Context, id pgtype.UUID) (In
 		&i.ErrorMessage,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET password_hash = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID           pgtype.UUID `json:"id"`
+	PasswordHash string      `json:"passwordHash"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.Exec(ctx, updateUserPassword, arg.ID, arg.PasswordHash)
+	return err
+}
+
+const upsertIndexerBackfill = `-- name: UpsertIndexerBackfill :one
+INSERT INTO indexer_backfills (
+    indexer_id,
+    address,
+    before_signature,
+    fetched,
+    completed
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+ON CONFLICT (indexer_id, address) DO UPDATE
+SET before_signature = EXCLUDED.before_signature,
+    fetched = EXCLUDED.fetched,
+    completed = EXCLUDED.completed,
+    updated_at = NOW()
+RETURNING indexer_id, address, before_signature, fetched, completed, created_at, updated_at
+`
+
+type UpsertIndexerBackfillParams struct {
+	IndexerID       pgtype.UUID `json:"indexerId"`
+	Address         string      `json:"address"`
+	BeforeSignature pgtype.Text `json:"beforeSignature"`
+	Fetched         int32       `json:"fetched"`
+	Completed       bool        `json:"completed"`
+}
+
+func (q *Queries) UpsertIndexerBackfill(ctx context.Context, arg UpsertIndexerBackfillParams) (IndexerBackfill, error) {
+	row := q.db.QueryRow(ctx, upsertIndexerBackfill,
+		arg.IndexerID,
+		arg.Address,
+		arg.BeforeSignature,
+		arg.Fetched,
+		arg.Completed,
+	)
+	var i IndexerBackfill
+	err := row.Scan(
+		&i.IndexerID,
+		&i.Address,
+		&i.BeforeSignature,
+		&i.Fetched,
+		&i.Completed,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertIndexerWebhookSecret = `-- name: UpsertIndexerWebhookSecret :one
+INSERT INTO indexer_webhook_secrets (
+    indexer_id,
+    secret
+) VALUES (
+    $1, $2
+)
+ON CONFLICT (indexer_id) DO UPDATE
+SET secret = EXCLUDED.secret, updated_at = NOW()
+RETURNING indexer_id, secret, created_at, updated_at
+`
+
+type UpsertIndexerWebhookSecretParams struct {
+	IndexerID pgtype.UUID `json:"indexerId"`
+	Secret    string      `json:"secret"`
+}
+
+func (q *Queries) UpsertIndexerWebhookSecret(ctx context.Context, arg UpsertIndexerWebhookSecretParams) (IndexerWebhookSecret, error) {
+	row := q.db.QueryRow(ctx, upsertIndexerWebhookSecret, arg.IndexerID, arg.Secret)
+	var i IndexerWebhookSecret
+	err := row.Scan(
+		&i.IndexerID,
+		&i.Secret,
+		&i.CreatedAt,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
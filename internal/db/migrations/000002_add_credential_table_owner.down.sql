@@ -0,0 +1 @@
+ALTER TABLE db_credentials DROP COLUMN IF EXISTS table_owner;
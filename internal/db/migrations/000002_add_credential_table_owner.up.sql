@@ -0,0 +1,2 @@
+-- Role that should own target tables created with this credential
+ALTER TABLE db_credentials ADD COLUMN table_owner VARCHAR(63);
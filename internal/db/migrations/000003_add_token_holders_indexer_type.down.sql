@@ -0,0 +1,15 @@
+-- Enum values cannot be dropped, so rebuild the type without token_holders
+DELETE FROM indexers WHERE indexer_type = 'token_holders';
+
+ALTER TYPE indexer_type RENAME TO indexer_type_old;
+
+CREATE TYPE indexer_type AS ENUM (
+    'nft_bids',
+    'nft_prices',
+    'token_borrow',
+    'token_prices'
+);
+
+ALTER TABLE indexers ALTER COLUMN indexer_type TYPE indexer_type USING indexer_type::text::indexer_type;
+
+DROP TYPE indexer_type_old;
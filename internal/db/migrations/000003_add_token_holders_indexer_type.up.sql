@@ -0,0 +1,2 @@
+-- Holder count and supply snapshots for tracked mints
+ALTER TYPE indexer_type ADD VALUE IF NOT EXISTS 'token_holders';
@@ -0,0 +1,12 @@
+-- Webhook payloads as received, kept so they can be replayed after a parser fix
+CREATE TABLE raw_payloads (
+    id BIGSERIAL PRIMARY KEY,
+    indexer_id UUID NOT NULL REFERENCES indexers(id) ON DELETE CASCADE,
+    webhook_id TEXT NOT NULL,
+    slot BIGINT NOT NULL,
+    signature TEXT NOT NULL,
+    body JSONB NOT NULL,
+    received_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_raw_payloads_indexer_slot ON raw_payloads(indexer_id, slot);
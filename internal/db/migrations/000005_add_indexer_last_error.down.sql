@@ -0,0 +1,2 @@
+ALTER TABLE indexers DROP COLUMN IF EXISTS last_error_at;
+ALTER TABLE indexers DROP COLUMN IF EXISTS last_error;
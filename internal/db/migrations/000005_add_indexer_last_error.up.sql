@@ -0,0 +1,3 @@
+-- Last processing failure, kept across status changes until the user clears it
+ALTER TABLE indexers ADD COLUMN last_error TEXT;
+ALTER TABLE indexers ADD COLUMN last_error_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,16 @@
+-- Enum values cannot be dropped, so rebuild the type without program_logs
+DELETE FROM indexers WHERE indexer_type = 'program_logs';
+
+ALTER TYPE indexer_type RENAME TO indexer_type_old;
+
+CREATE TYPE indexer_type AS ENUM (
+    'nft_bids',
+    'nft_prices',
+    'token_borrow',
+    'token_prices',
+    'token_holders'
+);
+
+ALTER TABLE indexers ALTER COLUMN indexer_type TYPE indexer_type USING indexer_type::text::indexer_type;
+
+DROP TYPE indexer_type_old;
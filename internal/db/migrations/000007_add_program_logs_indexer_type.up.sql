@@ -0,0 +1,2 @@
+-- Raw instructions of an arbitrary program
+ALTER TYPE indexer_type ADD VALUE IF NOT EXISTS 'program_logs';
@@ -0,0 +1,16 @@
+-- Payloads that kept failing, kept with their error so owners can replay them
+CREATE TABLE failed_payloads (
+    id BIGSERIAL PRIMARY KEY,
+    indexer_id UUID NOT NULL REFERENCES indexers(id) ON DELETE CASCADE,
+    webhook_id TEXT NOT NULL,
+    request_id TEXT NOT NULL DEFAULT '',
+    slot BIGINT NOT NULL,
+    signature TEXT NOT NULL DEFAULT '',
+    body JSONB NOT NULL,
+    error TEXT NOT NULL,
+    attempts INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_failed_payloads_indexer_id ON failed_payloads(indexer_id, created_at DESC);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_indexers_deleted_at;
+ALTER TABLE indexers DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,4 @@
+-- Deleted indexers are kept for a retention window so they can be restored
+ALTER TABLE indexers ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_indexers_deleted_at ON indexers(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,2 @@
+-- Per-user override of MAX_INDEXERS_PER_USER; NULL uses the configured quota
+ALTER TABLE users ADD COLUMN max_indexers INTEGER;
@@ -0,0 +1,8 @@
+-- The secret each indexer's webhook callback URL carries as its key; indexers
+-- without a row still use HELIUS_WEBHOOK_SECRET
+CREATE TABLE indexer_webhook_secrets (
+    indexer_id UUID PRIMARY KEY REFERENCES indexers(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
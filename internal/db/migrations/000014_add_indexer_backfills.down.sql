@@ -0,0 +1 @@
+DROP TABLE IF EXISTS indexer_backfills;
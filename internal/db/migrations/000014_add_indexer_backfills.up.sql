@@ -0,0 +1,12 @@
+-- How far the initial backfill of each address of an indexer got, so a
+-- backfill that was cut short picks up where it stopped
+CREATE TABLE indexer_backfills (
+    indexer_id UUID NOT NULL REFERENCES indexers(id) ON DELETE CASCADE,
+    address TEXT NOT NULL,
+    before_signature TEXT,
+    fetched INTEGER NOT NULL DEFAULT 0,
+    completed BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (indexer_id, address)
+);
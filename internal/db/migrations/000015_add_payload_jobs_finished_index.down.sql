@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_payload_jobs_finished;
@@ -0,0 +1,2 @@
+-- Finished payload jobs are deleted once they are older than the retention
+CREATE INDEX idx_payload_jobs_finished ON payload_jobs(updated_at) WHERE status IN ('done', 'failed');
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_raw_payloads_received_at;
+ALTER TABLE raw_payloads DROP COLUMN IF EXISTS batched;
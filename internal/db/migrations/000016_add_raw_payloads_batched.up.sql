@@ -0,0 +1,7 @@
+-- raw_payloads now keeps each transaction as Helius sent it. batched marks a
+-- transaction that was one element of an array delivery, which is parsed
+-- differently from a delivery of a single payload on replay.
+ALTER TABLE raw_payloads ADD COLUMN batched BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Raw payloads are deleted once they are older than the retention
+CREATE INDEX idx_raw_payloads_received_at ON raw_payloads(received_at);
@@ -14,6 +14,11 @@ WHERE email = $1 LIMIT 1;
 SELECT * FROM users
 WHERE id = $1 LIMIT 1;
 
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET password_hash = $2, updated_at = NOW()
+WHERE id = $1;
+
 -- name: CreateDBCredential :one
 INSERT INTO db_credentials (
     user_id,
@@ -22,9 +27,10 @@ INSERT INTO db_credentials (
     db_name,
     db_user,
     db_password,
-    db_ssl_mode
+    db_ssl_mode,
+    table_owner
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7
+    $1, $2, $3, $4, $5, $6, $7, $8
 ) RETURNING *;
 
 -- name: GetDBCredentialsByUserID :many
@@ -44,10 +50,20 @@ SET
     db_user = $5,
     db_password = $6,
     db_ssl_mode = $7,
+    table_owner = $8,
     updated_at = NOW()
 WHERE id = $1
 RETURNING *;
 
+-- name: ListDBCredentials :many
+SELECT * FROM db_credentials
+ORDER BY created_at;
+
+-- name: UpdateDBCredentialPassword :exec
+UPDATE db_credentials
+SET db_password = $2
+WHERE id = $1;
+
 -- name: DeleteDBCredential :exec
 DELETE FROM db_credentials
 WHERE id = $1 AND user_id = $2;
@@ -66,11 +82,15 @@ INSERT INTO indexers (
 
 -- name: GetIndexersByUserID :many
 SELECT * FROM indexers
-WHERE user_id = $1;
+WHERE user_id = $1 AND deleted_at IS NULL;
+
+-- name: CountIndexersByUserID :one
+SELECT COUNT(*) FROM indexers
+WHERE user_id = $1 AND status = 'active' AND deleted_at IS NULL;
 
 -- name: GetIndexerByID :one
 SELECT * FROM indexers
-WHERE id = $1 LIMIT 1;
+WHERE id = $1 AND deleted_at IS NULL LIMIT 1;
 
 -- name: GetIndexerByWebhookID :one
 SELECT * FROM indexers
@@ -85,6 +105,22 @@ SET
 WHERE id = $1
 RETURNING *;
 
+-- name: UpdateIndexerParams :one
+UPDATE indexers
+SET
+    params = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateIndexerTargetTable :one
+UPDATE indexers
+SET
+    target_table = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
 -- name: UpdateIndexerWebhookID :one
 UPDATE indexers
 SET
@@ -93,6 +129,24 @@ SET
 WHERE id = $1
 RETURNING *;
 
+-- name: UpdateIndexerLastError :one
+UPDATE indexers
+SET
+    last_error = $2,
+    last_error_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ClearIndexerLastError :one
+UPDATE indexers
+SET
+    last_error = NULL,
+    last_error_at = NULL,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
 -- name: UpdateLastIndexedTime :one
 UPDATE indexers
 SET
@@ -101,10 +155,43 @@ SET
 WHERE id = $1
 RETURNING *;
 
+-- name: ClearLastIndexedTime :one
+UPDATE indexers
+SET
+    last_indexed_at = NULL,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
 -- name: DeleteIndexer :exec
 DELETE FROM indexers
 WHERE id = $1 AND user_id = $2;
 
+-- name: SoftDeleteIndexer :one
+UPDATE indexers
+SET
+    deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: GetDeletedIndexerByID :one
+SELECT * FROM indexers
+WHERE id = $1 AND deleted_at IS NOT NULL LIMIT 1;
+
+-- name: RestoreIndexer :one
+UPDATE indexers
+SET
+    deleted_at = NULL,
+    updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: GetExpiredDeletedIndexers :many
+SELECT * FROM indexers
+WHERE deleted_at IS NOT NULL AND deleted_at < $1
+ORDER BY deleted_at ASC;
+
 -- name: CreateIndexingLog :one
 INSERT INTO indexing_logs (
     indexer_id,
@@ -121,6 +208,200 @@ WHERE indexer_id = $1
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3;
 
+-- name: GetIndexingLogsSince :many
+SELECT * FROM indexing_logs
+WHERE indexer_id = $1
+  AND (created_at, id) > (sqlc.arg(after_created_at)::timestamptz, sqlc.arg(after_id)::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT $4;
+
 -- name: GetActiveIndexers :many
 SELECT * FROM indexers
-WHERE status = 'active';
+WHERE status = 'active' AND deleted_at IS NULL;
+
+-- name: ListIndexers :many
+SELECT * FROM indexers
+ORDER BY created_at ASC;
+
+-- name: CreateRawPayload :exec
+INSERT INTO raw_payloads (
+    indexer_id,
+    webhook_id,
+    slot,
+    signature,
+    body,
+    batched
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+);
+
+-- name: GetRawPayloadsBySlotRange :many
+SELECT * FROM raw_payloads
+WHERE indexer_id = sqlc.arg(indexer_id)
+  AND slot >= sqlc.arg(from_slot)
+  AND slot <= sqlc.arg(to_slot)
+ORDER BY slot ASC, id ASC
+LIMIT sqlc.arg(row_limit);
+
+-- name: DeleteRawPayloadsBefore :execrows
+DELETE FROM raw_payloads
+WHERE received_at < sqlc.arg(received_before);
+
+-- name: CreateRefreshToken :one
+INSERT INTO refresh_tokens (
+    user_id,
+    token_hash,
+    expires_at
+) VALUES (
+    $1, $2, $3
+) RETURNING *;
+
+-- name: GetRefreshTokenByHash :one
+SELECT * FROM refresh_tokens
+WHERE token_hash = $1 LIMIT 1;
+
+-- name: RevokeRefreshToken :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE token_hash = $1 AND revoked_at IS NULL;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: CreatePayloadJob :exec
+INSERT INTO payload_jobs (
+    webhook_id,
+    request_id,
+    body
+) VALUES (
+    $1, $2, $3
+);
+
+-- name: ClaimPayloadJob :one
+UPDATE payload_jobs
+SET status = 'processing', attempts = attempts + 1, updated_at = NOW()
+WHERE id = (
+    SELECT id FROM payload_jobs
+    WHERE status = 'pending' AND next_retry_at <= NOW()
+    ORDER BY next_retry_at ASC, id ASC
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompletePayloadJob :exec
+UPDATE payload_jobs
+SET status = 'done', last_error = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: RetryPayloadJob :exec
+UPDATE payload_jobs
+SET status = 'pending', last_error = $2, next_retry_at = $3, updated_at = NOW()
+WHERE id = $1;
+
+-- name: FailPayloadJob :exec
+UPDATE payload_jobs
+SET status = 'failed', last_error = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: RequeueStalePayloadJobs :execrows
+UPDATE payload_jobs
+SET status = 'pending', updated_at = NOW()
+WHERE status = 'processing' AND updated_at < sqlc.arg(stale_before);
+
+-- name: DeleteFinishedPayloadJobs :execrows
+DELETE FROM payload_jobs
+WHERE status IN ('done', 'failed') AND updated_at < sqlc.arg(finished_before);
+
+-- name: CreateFailedPayload :one
+INSERT INTO failed_payloads (
+    indexer_id,
+    webhook_id,
+    request_id,
+    slot,
+    signature,
+    body,
+    error,
+    attempts
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING *;
+
+-- name: GetFailedPayloadsByIndexerID :many
+SELECT * FROM failed_payloads
+WHERE indexer_id = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: GetFailedPayloadByID :one
+SELECT * FROM failed_payloads
+WHERE id = $1 AND indexer_id = $2;
+
+-- name: UpdateFailedPayloadError :exec
+UPDATE failed_payloads
+SET error = $2, attempts = attempts + 1, updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteFailedPayload :exec
+DELETE FROM failed_payloads
+WHERE id = $1;
+
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (
+    actor_user_id,
+    action,
+    entity_type,
+    entity_id,
+    metadata
+) VALUES (
+    $1, $2, $3, $4, $5
+);
+
+-- name: GetAuditEventsByUserID :many
+SELECT * FROM audit_events
+WHERE actor_user_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2 OFFSET $3;
+
+-- name: GetIndexerBackfills :many
+SELECT * FROM indexer_backfills
+WHERE indexer_id = $1
+ORDER BY address;
+
+-- name: DeleteIndexerBackfills :exec
+DELETE FROM indexer_backfills
+WHERE indexer_id = $1;
+
+-- name: UpsertIndexerBackfill :one
+INSERT INTO indexer_backfills (
+    indexer_id,
+    address,
+    before_signature,
+    fetched,
+    completed
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+ON CONFLICT (indexer_id, address) DO UPDATE
+SET before_signature = EXCLUDED.before_signature,
+    fetched = EXCLUDED.fetched,
+    completed = EXCLUDED.completed,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: GetIndexerWebhookSecret :one
+SELECT * FROM indexer_webhook_secrets
+WHERE indexer_id = $1;
+
+-- name: UpsertIndexerWebhookSecret :one
+INSERT INTO indexer_webhook_secrets (
+    indexer_id,
+    secret
+) VALUES (
+    $1, $2
+)
+ON CONFLICT (indexer_id) DO UPDATE
+SET secret = EXCLUDED.secret, updated_at = NOW()
+RETURNING *;
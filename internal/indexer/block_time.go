@@ -0,0 +1,47 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+type blockTimeKey struct{}
+
+// payloadBlockTime returns the block time Helius sent with a payload: the
+// transaction timestamp, else the timestamp or blockTime of its enhanced
+// details. It reports false when the payload carries neither.
+func payloadBlockTime(payload models.HeliusWebhookPayload) (time.Time, bool) {
+	timestamp := payload.Transaction.Timestamp
+	if timestamp <= 0 && len(payload.Transaction.EnhancedDetails) > 0 {
+		var details struct {
+			Timestamp int64 `json:"timestamp"`
+			BlockTime int64 `json:"blockTime"`
+		}
+		if err := json.Unmarshal(payload.Transaction.EnhancedDetails, &details); err == nil {
+			timestamp = details.Timestamp
+			if timestamp <= 0 {
+				timestamp = details.BlockTime
+			}
+		}
+	}
+	if timestamp <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(timestamp, 0).UTC(), true
+}
+
+// eventBlockTime returns the block time of an event: its own timestamp, else
+// that of the payload being processed, and the current time only when
+// neither is known
+func eventBlockTime(ctx context.Context, eventData map[string]interface{}) time.Time {
+	if ts := numberField(eventData, "timestamp"); ts > 0 {
+		return time.Unix(int64(ts), 0).UTC()
+	}
+	if blockTime, ok := ctx.Value(blockTimeKey{}).(time.Time); ok {
+		return blockTime
+	}
+	return time.Now().UTC()
+}
@@ -0,0 +1,95 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+func TestPayloadBlockTime(t *testing.T) {
+	tests := []struct {
+		name      string
+		timestamp int64
+		details   string
+		want      int64
+	}{
+		{name: "transaction timestamp", timestamp: 1700000100, details: `{"timestamp": 1700000000}`, want: 1700000100},
+		{name: "enhanced timestamp", details: `{"timestamp": 1700000000}`, want: 1700000000},
+		{name: "raw block time", details: `{"blockTime": 1700000200}`, want: 1700000200},
+		{name: "none", details: `{"type": "NFT_SALE"}`},
+		{name: "no details"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := models.HeliusWebhookPayload{Transaction: models.HeliusTransaction{
+				Timestamp:       tt.timestamp,
+				EnhancedDetails: json.RawMessage(tt.details),
+			}}
+
+			got, ok := payloadBlockTime(payload)
+			if tt.want == 0 {
+				if ok {
+					t.Errorf("payloadBlockTime = %s, want none", got)
+				}
+				return
+			}
+			if !ok || !got.Equal(time.Unix(tt.want, 0)) || got.Location() != time.UTC {
+				t.Errorf("payloadBlockTime = %s (%v), want %s in UTC", got, ok, time.Unix(tt.want, 0).UTC())
+			}
+		})
+	}
+}
+
+func TestEventBlockTime(t *testing.T) {
+	payloadTime := time.Unix(1700000000, 0).UTC()
+	ctx := context.WithValue(context.Background(), blockTimeKey{}, payloadTime)
+
+	if got := eventBlockTime(ctx, map[string]interface{}{"timestamp": float64(1700000500)}); !got.Equal(time.Unix(1700000500, 0)) {
+		t.Errorf("event with a timestamp: block time = %s, want its own timestamp", got)
+	}
+	if got := eventBlockTime(ctx, map[string]interface{}{}); !got.Equal(payloadTime) {
+		t.Errorf("event without a timestamp: block time = %s, want the payload's %s", got, payloadTime)
+	}
+
+	before := time.Now()
+	if got := eventBlockTime(context.Background(), map[string]interface{}{}); got.Before(before.Add(-time.Second)) {
+		t.Errorf("nothing known: block time = %s, want now", got)
+	}
+}
+
+func TestProcessPayloadStoresBlockTime(t *testing.T) {
+	pool := testPool(t)
+	table := testTable(t, pool)
+	idx := newTestPriceIndexer(t, `{"collection": "collection"}`)
+	initializeTable(t, pool, idx, table)
+	ctx := context.Background()
+
+	// The event's own timestamp, then one only the transaction carries
+	withTimestamp := listingEventData("mint-1")
+	withoutTimestamp := listingEventData("mint-2")
+	delete(withoutTimestamp, "timestamp")
+
+	payload := enhancedPayload(t, "event-time", 1, withTimestamp)
+	if _, err := idx.ProcessPayload(ctx, pool, table, payload); err != nil {
+		t.Fatalf("ProcessPayload: %v", err)
+	}
+	payload = enhancedPayload(t, "transaction-time", 2, withoutTimestamp)
+	payload.Transaction.Timestamp = 1690000000
+	if _, err := idx.ProcessPayload(ctx, pool, table, payload); err != nil {
+		t.Fatalf("ProcessPayload: %v", err)
+	}
+
+	for signature, want := range map[string]int64{"event-time": 1700000000, "transaction-time": 1690000000} {
+		var blockTime time.Time
+		if err := pool.QueryRow(ctx, "SELECT block_time FROM "+QuoteTableName(table)+" WHERE signature = $1", signature).Scan(&blockTime); err != nil {
+			t.Fatalf("read %s: %v", signature, err)
+		}
+		if !blockTime.Equal(time.Unix(want, 0)) {
+			t.Errorf("%s block_time = %s, want %s", signature, blockTime, time.Unix(want, 0).UTC())
+		}
+	}
+}
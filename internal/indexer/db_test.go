@@ -0,0 +1,71 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testPool connects to the database in TEST_DATABASE_URL, skipping the test
+// when it isn't set
+func testPool(tb testing.TB) *pgxpool.Pool {
+	tb.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		tb.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		tb.Fatalf("connect to test database: %v", err)
+	}
+	tb.Cleanup(pool.Close)
+	return pool
+}
+
+// testTable returns a target table name unique to the test. The table and
+// any companion tables starting with its name are dropped when the test ends.
+func testTable(tb testing.TB, pool *pgxpool.Pool) string {
+	tb.Helper()
+
+	name := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	tb.Cleanup(func() {
+		ctx := context.Background()
+		rows, err := pool.Query(ctx, "SELECT tablename FROM pg_tables WHERE schemaname = 'public' AND tablename LIKE $1", name+"%")
+		if err != nil {
+			return
+		}
+		var tables []string
+		for rows.Next() {
+			var table string
+			if rows.Scan(&table) == nil {
+				tables = append(tables, table)
+			}
+		}
+		rows.Close()
+		for _, table := range tables {
+			pool.Exec(ctx, "DROP TABLE IF EXISTS "+QuoteTableName(table)+" CASCADE")
+		}
+	})
+	return name
+}
+
+// initializeTable creates the target table of idx
+func initializeTable(tb testing.TB, pool *pgxpool.Pool, idx Indexer, table string) {
+	tb.Helper()
+
+	conn, err := pool.Acquire(context.Background())
+	if err != nil {
+		tb.Fatalf("acquire connection: %v", err)
+	}
+	defer conn.Release()
+
+	if err := idx.Initialize(context.Background(), conn.Conn(), table); err != nil {
+		tb.Fatalf("initialize %s: %v", table, err)
+	}
+}
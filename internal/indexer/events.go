@@ -0,0 +1,62 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// EventHandler processes one event: the transaction itself when its type is
+// the event type, or one entry of its events array. It gets the untyped event
+// since the processors read fields the typed model doesn't cover.
+type EventHandler func(ctx context.Context, event map[string]interface{}) error
+
+// errEventSkipped is returned by a handler that looked at an event and found
+// it isn't one it processes after all, such as a sale that didn't fill a
+// collection offer
+var errEventSkipped = errors.New("event skipped")
+
+// DispatchEvents hands the events of a transaction to the handlers registered
+// for their types. A transaction whose own type has a handler is processed as
+// a single event; otherwise every entry of its events array with a handler is
+// processed in order, stopping at the first error. It reports whether any
+// handler took an event, so callers can fall back to parsing the description.
+func DispatchEvents(ctx context.Context, enhanced *models.EnhancedTransaction, handlers map[string]EventHandler) (bool, error) {
+	if handler, ok := handlers[enhanced.Type]; ok {
+		err := handler(ctx, enhanced.Raw)
+		if !errors.Is(err, errEventSkipped) {
+			log.Debug().
+				Str("eventType", enhanced.Type).
+				Msg("Dispatched direct event")
+			return true, err
+		}
+	}
+
+	matched := false
+	for idx, event := range enhanced.Events.List {
+		handler, ok := handlers[event.Type]
+		if !ok {
+			continue
+		}
+
+		err := handler(ctx, event.Raw)
+		if errors.Is(err, errEventSkipped) {
+			continue
+		}
+		matched = true
+
+		log.Debug().
+			Int("eventIndex", idx).
+			Str("eventType", event.Type).
+			Msg("Dispatched event from array")
+
+		if err != nil {
+			return true, err
+		}
+	}
+
+	return matched, nil
+}
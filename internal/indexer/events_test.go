@@ -0,0 +1,122 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// recordHandlers returns handlers for types that note the mint of every event
+// they get, and fail with the given errors
+func recordHandlers(got *[]string, failures map[string]error, types ...string) map[string]EventHandler {
+	handlers := make(map[string]EventHandler)
+	for _, eventType := range types {
+		handlers[eventType] = func(ctx context.Context, event map[string]interface{}) error {
+			mint, _ := event["mint"].(string)
+			*got = append(*got, eventType+":"+mint)
+			return failures[mint]
+		}
+	}
+	return handlers
+}
+
+func parseEnhanced(t *testing.T, body string) *models.EnhancedTransaction {
+	t.Helper()
+
+	enhanced, err := models.ParseEnhancedTransaction([]byte(body))
+	if err != nil {
+		t.Fatalf("ParseEnhancedTransaction: %v", err)
+	}
+	return enhanced
+}
+
+func TestDispatchEventsDirectType(t *testing.T) {
+	var got []string
+	enhanced := parseEnhanced(t, `{"type": "NFT_BID", "mint": "direct", "events": [{"type": "NFT_BID", "mint": "array"}]}`)
+
+	matched, err := DispatchEvents(context.Background(), enhanced, recordHandlers(&got, nil, "NFT_BID"))
+	if err != nil {
+		t.Fatalf("DispatchEvents: %v", err)
+	}
+	if !matched {
+		t.Error("direct event not reported as matched")
+	}
+	// A direct event is the whole transaction, so the array isn't looked at
+	if len(got) != 1 || got[0] != "NFT_BID:direct" {
+		t.Errorf("handled %v, want only the transaction itself", got)
+	}
+}
+
+func TestDispatchEventsArray(t *testing.T) {
+	var got []string
+	enhanced := parseEnhanced(t, `{"type": "UNKNOWN", "events": [
+		{"type": "NFT_BID", "mint": "mint-1"},
+		{"type": "NFT_SALE", "mint": "mint-2"},
+		{"type": "NFT_BID_CANCELLED", "mint": "mint-3"},
+		{"type": "NFT_BID", "mint": "mint-4"}
+	]}`)
+
+	matched, err := DispatchEvents(context.Background(), enhanced, recordHandlers(&got, nil, "NFT_BID", "NFT_BID_CANCELLED"))
+	if err != nil {
+		t.Fatalf("DispatchEvents: %v", err)
+	}
+	if !matched {
+		t.Error("array events not reported as matched")
+	}
+	want := []string{"NFT_BID:mint-1", "NFT_BID_CANCELLED:mint-3", "NFT_BID:mint-4"}
+	if len(got) != len(want) {
+		t.Fatalf("handled %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("handled %v, want %v in order", got, want)
+			break
+		}
+	}
+}
+
+func TestDispatchEventsStopsAtFirstError(t *testing.T) {
+	var got []string
+	enhanced := parseEnhanced(t, `{"events": [
+		{"type": "NFT_BID", "mint": "mint-1"},
+		{"type": "NFT_BID", "mint": "mint-2"},
+		{"type": "NFT_BID", "mint": "mint-3"}
+	]}`)
+	failure := errors.New("insert failed")
+
+	matched, err := DispatchEvents(context.Background(), enhanced, recordHandlers(&got, map[string]error{"mint-2": failure}, "NFT_BID"))
+	if !errors.Is(err, failure) {
+		t.Errorf("DispatchEvents error = %v, want the handler's", err)
+	}
+	if !matched {
+		t.Error("failed event not reported as matched")
+	}
+	if len(got) != 2 {
+		t.Errorf("handled %v, want processing stopped at mint-2", got)
+	}
+}
+
+func TestDispatchEventsSkippedEvents(t *testing.T) {
+	var got []string
+	failures := map[string]error{"direct": errEventSkipped, "mint-1": errEventSkipped}
+
+	// A skipped direct event falls through to the events array
+	enhanced := parseEnhanced(t, `{"type": "NFT_SALE", "mint": "direct", "events": [{"type": "NFT_SALE", "mint": "mint-2"}]}`)
+	matched, err := DispatchEvents(context.Background(), enhanced, recordHandlers(&got, failures, "NFT_SALE"))
+	if err != nil || !matched {
+		t.Errorf("DispatchEvents = %v, %v; want the array event matched", matched, err)
+	}
+	if len(got) != 2 || got[1] != "NFT_SALE:mint-2" {
+		t.Errorf("handled %v, want the array tried after the skipped direct event", got)
+	}
+
+	// Events that were all skipped, or had no handler, match nothing
+	got = nil
+	enhanced = parseEnhanced(t, `{"events": [{"type": "NFT_SALE", "mint": "mint-1"}, {"type": "TRANSFER", "mint": "mint-2"}]}`)
+	matched, err = DispatchEvents(context.Background(), enhanced, recordHandlers(&got, failures, "NFT_SALE"))
+	if err != nil || matched {
+		t.Errorf("DispatchEvents = %v, %v; want nothing matched so the description is parsed", matched, err)
+	}
+}
@@ -1,22 +1,33 @@
 package indexer
 
 import (
-	"bytes"
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/rishavmehra/indexer/internal/config"
 	"github.com/rishavmehra/indexer/internal/models"
 )
 
+// ErrWebhookNotFound is returned when Helius no longer knows the configured webhook
+var ErrWebhookNotFound = errors.New("helius webhook not found")
+
 const (
-	HeliusAPIBase     = "https://api.helius.xyz/v0"
+	DefaultHeliusAPIBaseURL = "https://api.helius.xyz/v0"
+	DefaultHeliusRPCURL     = "https://mainnet.helius-rpc.com"
+	// DefaultHeliusHTTPTimeout and DefaultHeliusRPCTimeout apply when the
+	// configured timeouts are unset
+	DefaultHeliusHTTPTimeout = 30 * time.Second
+	DefaultHeliusRPCTimeout  = 10 * time.Second
+	// MaxAddressesLimit is the number of addresses a single Helius webhook holds
 	MaxAddressesLimit = 25
 )
 
@@ -32,6 +43,7 @@ type WebhookConfig struct {
 type AddressEntry struct {
 	Address   string    `json:"address"`
 	IndexerID string    `json:"indexerId"`
+	WebhookID string    `json:"webhookId"`
 	AddedAt   time.Time `json:"addedAt"`
 }
 
@@ -40,21 +52,59 @@ type HeliusClient struct {
 	webhookSecret  string
 	webhookBaseURL string
 	webhookID      string
+	apiBaseURL     string
+	rpcURL         string
+	cluster        string
+	maxAttempts    int
+	retryBaseDelay time.Duration
+	maxWebhooks    int
+	rpcTimeout     time.Duration
 	httpClient     *http.Client
 	addresses      []AddressEntry
-	addressesLock  sync.RWMutex
+	// shards are the webhooks holding addresses, grouped by callback URL
+	shards        map[string][]*webhookShard
+	addressesLock sync.RWMutex
 }
 
-func NewHeliusClient(apiKey, webhookSecret, webhookBaseURL, webhookID string) *HeliusClient {
+func NewHeliusClient(cfg config.HeliusConfig) *HeliusClient {
+	apiBaseURL := cfg.APIBaseURL
+	if apiBaseURL == "" {
+		apiBaseURL = DefaultHeliusAPIBaseURL
+	}
+	rpcURL := cfg.RPCURL
+	if rpcURL == "" {
+		rpcURL = DefaultHeliusRPCURL
+	}
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultHeliusMaxAttempts
+	}
+	retryBaseDelay := cfg.RetryBaseDelay
+	if retryBaseDelay <= 0 {
+		retryBaseDelay = DefaultHeliusRetryBaseDelay
+	}
+	httpTimeout := cfg.HTTPTimeout
+	if httpTimeout <= 0 {
+		httpTimeout = DefaultHeliusHTTPTimeout
+	}
+
 	return &HeliusClient{
-		apiKey:         apiKey,
-		webhookSecret:  webhookSecret,
-		webhookBaseURL: webhookBaseURL,
-		webhookID:      webhookID,
+		apiKey:         cfg.APIKey,
+		webhookSecret:  cfg.WebhookSecret,
+		webhookBaseURL: cfg.WebhookBaseURL,
+		webhookID:      cfg.WebhookID,
+		apiBaseURL:     strings.TrimRight(apiBaseURL, "/"),
+		rpcURL:         strings.TrimRight(rpcURL, "/"),
+		cluster:        cfg.Cluster,
+		maxAttempts:    maxAttempts,
+		retryBaseDelay: retryBaseDelay,
+		maxWebhooks:    cfg.MaxWebhooks,
+		rpcTimeout:     cfg.RPCTimeout,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: httpTimeout,
 		},
 		addresses: []AddressEntry{},
+		shards:    make(map[string][]*webhookShard),
 	}
 }
 
@@ -66,141 +116,19 @@ func (c *HeliusClient) GetAPIKey() string {
 	return c.apiKey
 }
 
-func (c *HeliusClient) AddAddresses(ctx context.Context, addresses []string, indexerID string) error {
-	if len(addresses) == 0 {
-		log.Info().Msg("No addresses to add")
-		return nil
-	}
-
-	log.Info().
-		Strs("addresses", addresses).
-		Str("indexerID", indexerID).
-		Str("currentWebhookID", c.webhookID).
-		Msg("Adding addresses to webhook")
-
-	c.addressesLock.Lock()
-	defer c.addressesLock.Unlock()
-
-	if c.webhookID == "" {
-		return c.createWebhookWithAddresses(ctx, addresses, indexerID)
-	}
-
-	currentConfig, err := c.GetWebhookConfig(ctx)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to get current webhook configuration")
-
-		if c.webhookID != "" {
-			deleteErr := c.DeleteWebhook(ctx, c.webhookID)
-			if deleteErr != nil {
-				log.Warn().Err(deleteErr).Msg("Failed to delete existing webhook before creating new one")
-			}
-			c.webhookID = ""
-		}
-
-		return c.createWebhookWithAddresses(ctx, addresses, indexerID)
-	}
-
-	newAddresses := []string{}
-	for _, addr := range addresses {
-		isNew := true
-		for _, existing := range currentConfig.AccountAddresses {
-			if existing == addr {
-				isNew = false
-				break
-			}
-		}
-		if isNew {
-			newAddresses = append(newAddresses, addr)
-		}
-	}
-
-	if len(newAddresses) == 0 {
-		log.Info().Msg("No new addresses to add to webhook")
-		return nil
-	}
-
-	log.Info().
-		Strs("newAddresses", newAddresses).
-		Int("currentAddressCount", len(currentConfig.AccountAddresses)).
-		Msg("Found new addresses to add")
-
-	now := time.Now()
-	for _, addr := range newAddresses {
-		c.addresses = append(c.addresses, AddressEntry{
-			Address:   addr,
-			IndexerID: indexerID,
-			AddedAt:   now,
-		})
-	}
-
-	if len(currentConfig.AccountAddresses)+len(newAddresses) > MaxAddressesLimit {
-		log.Info().
-			Int("currentCount", len(currentConfig.AccountAddresses)).
-			Int("newCount", len(newAddresses)).
-			Int("limit", MaxAddressesLimit).
-			Msg("Address limit will be exceeded, removing oldest addresses")
-
-		return c.updateWebhookAddresses(ctx)
-	} else {
-		allAddresses := append(currentConfig.AccountAddresses, newAddresses...)
-		log.Info().
-			Int("totalAddressCount", len(allAddresses)).
-			Msg("Updating webhook with all addresses")
-
-		return c.updateWebhookWithAddresses(ctx, allAddresses)
-	}
+// GetCluster returns the Solana cluster the client talks to
+func (c *HeliusClient) GetCluster() string {
+	return c.cluster
 }
 
-func (c *HeliusClient) GetAddresses() []AddressEntry {
-	c.addressesLock.RLock()
-	defer c.addressesLock.RUnlock()
-
-	result := make([]AddressEntry, len(c.addresses))
-	copy(result, c.addresses)
-	return result
+// GetRPCURL returns the Helius RPC endpoint used for DAS lookups
+func (c *HeliusClient) GetRPCURL() string {
+	return c.rpcURL
 }
 
-func (c *HeliusClient) RemoveIndexerAddresses(ctx context.Context, indexerID string) error {
-	c.addressesLock.Lock()
-	defer c.addressesLock.Unlock()
-
-	var remainingAddresses []AddressEntry
-	addressesToRemove := []string{}
-
-	for _, entry := range c.addresses {
-		if entry.IndexerID == indexerID {
-			addressesToRemove = append(addressesToRemove, entry.Address)
-		} else {
-			remainingAddresses = append(remainingAddresses, entry)
-		}
-	}
-
-	if len(addressesToRemove) == 0 {
-		return nil
-	}
-
-	c.addresses = remainingAddresses
-
-	currentConfig, err := c.GetWebhookConfig(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get current webhook configuration: %w", err)
-	}
-
-	var updatedAddresses []string
-	for _, addr := range currentConfig.AccountAddresses {
-		shouldKeep := true
-		for _, removeAddr := range addressesToRemove {
-			if addr == removeAddr {
-				shouldKeep = false
-				break
-			}
-		}
-		if shouldKeep {
-			updatedAddresses = append(updatedAddresses, addr)
-		}
-	}
-
-	return c.updateWebhookWithAddresses(ctx, updatedAddresses)
+// GetRPCTimeout returns the timeout of RPC calls made by the token indexers
+func (c *HeliusClient) GetRPCTimeout() time.Duration {
+	return c.rpcTimeout
 }
 
 func (c *HeliusClient) GetWebhookBaseURL() string {
@@ -217,11 +145,7 @@ func (c *HeliusClient) CreateWebhook(ctx context.Context, config WebhookConfig)
 			return nil, fmt.Errorf("webhook URL is required")
 		}
 
-		if c.webhookBaseURL[len(c.webhookBaseURL)-1] == '/' {
-			config.WebhookURL = fmt.Sprintf("%swebhooks?key=%s", c.webhookBaseURL, c.webhookSecret)
-		} else {
-			config.WebhookURL = fmt.Sprintf("%s/webhooks?key=%s", c.webhookBaseURL, c.webhookSecret)
-		}
+		config.WebhookURL = c.defaultWebhookURL()
 	}
 
 	requestBody, err := json.Marshal(config)
@@ -230,89 +154,96 @@ func (c *HeliusClient) CreateWebhook(ctx context.Context, config WebhookConfig)
 	}
 
 	log.Debug().
-		Str("url", fmt.Sprintf("%s/webhooks?api-key=%s", HeliusAPIBase, c.apiKey)).
+		Str("url", fmt.Sprintf("%s/webhooks", c.apiBaseURL)).
 		Str("webhookURL", config.WebhookURL).
 		Interface("config", config).
 		Msg("Creating Helius webhook")
 
-	req, err := http.NewRequestWithContext(
+	resp, err := c.doWithRetry(
 		ctx,
 		http.MethodPost,
-		fmt.Sprintf("%s/webhooks?api-key=%s", HeliusAPIBase, c.apiKey),
-		bytes.NewBuffer(requestBody),
+		fmt.Sprintf("%s/webhooks?api-key=%s", c.apiBaseURL, c.apiKey),
+		requestBody,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to create webhook: %s (status code: %d)", string(resp.Body), resp.StatusCode)
+	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+	var response models.HeliusWebhookResponse
+	if err := json.Unmarshal(resp.Body, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	return &response, nil
+}
+
+// ListWebhooks returns every webhook registered under the API key
+func (c *HeliusClient) ListWebhooks(ctx context.Context) ([]models.HeliusWebhook, error) {
+	resp, err := c.doWithRetry(
+		ctx,
+		http.MethodGet,
+		fmt.Sprintf("%s/webhooks?api-key=%s", c.apiBaseURL, c.apiKey),
+		nil,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to create webhook: %s (status code: %d)", string(body), resp.StatusCode)
+		return nil, fmt.Errorf("failed to list webhooks: %s (status code: %d)", string(resp.Body), resp.StatusCode)
 	}
 
-	var response models.HeliusWebhookResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	var webhooks []models.HeliusWebhook
+	if err := json.Unmarshal(resp.Body, &webhooks); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal webhook list: %w", err)
 	}
 
-	return &response, nil
+	return webhooks, nil
 }
 
+// GetWebhookConfig returns the configuration of the configured shared webhook
 func (c *HeliusClient) GetWebhookConfig(ctx context.Context) (*WebhookConfig, error) {
 	if c.webhookID == "" {
 		return nil, fmt.Errorf("no webhook ID configured")
 	}
 
-	req, err := http.NewRequestWithContext(
+	return c.getWebhookConfig(ctx, c.webhookID)
+}
+
+// GetWebhookConfigByID returns the configuration of a webhook, or
+// ErrWebhookNotFound once Helius no longer has it
+func (c *HeliusClient) GetWebhookConfigByID(ctx context.Context, webhookID string) (*WebhookConfig, error) {
+	return c.getWebhookConfig(ctx, webhookID)
+}
+
+func (c *HeliusClient) getWebhookConfig(ctx context.Context, webhookID string) (*WebhookConfig, error) {
+	resp, err := c.doWithRetry(
 		ctx,
 		http.MethodGet,
-		fmt.Sprintf("%s/webhooks/%s?api-key=%s", HeliusAPIBase, c.webhookID, c.apiKey),
+		fmt.Sprintf("%s/webhooks/%s?api-key=%s", c.apiBaseURL, webhookID, c.apiKey),
 		nil,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
+	body := resp.Body
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrWebhookNotFound, webhookID)
 	}
 
+	// Any other failure is reported rather than treated as an empty webhook,
+	// which would wipe its addresses on the next update
 	if resp.StatusCode != http.StatusOK {
-		log.Warn().
-			Str("webhookID", c.webhookID).
-			Int("statusCode", resp.StatusCode).
-			Str("responseBody", string(body)).
-			Msg("Failed to get webhook configuration")
-
-		// Return an empty config that we can use as a fallback
-		return &WebhookConfig{
-			WebhookType:      "enhanced",
-			AccountAddresses: []string{},
-			TransactionTypes: []string{"ANY"},
-		}, nil
+		return nil, fmt.Errorf("failed to get webhook configuration: %s (status code: %d)", string(body), resp.StatusCode)
 	}
 
 	log.Debug().
-		Str("webhookID", c.webhookID).
+		Str("webhookID", webhookID).
 		Str("responseBody", string(body)).
 		Msg("Got webhook configuration from Helius")
 
@@ -350,238 +281,54 @@ func (c *HeliusClient) GetWebhookConfig(ctx context.Context) (*WebhookConfig, er
 	return &config, nil
 }
 
-func (c *HeliusClient) updateWebhookWithAddresses(ctx context.Context, addresses []string) error {
-	if c.webhookID == "" {
-		return fmt.Errorf("no webhook ID configured")
+// defaultWebhookURL is the callback URL for webhooks created by this client
+func (c *HeliusClient) defaultWebhookURL() string {
+	if strings.HasSuffix(c.webhookBaseURL, "/") {
+		return fmt.Sprintf("%swebhooks?key=%s", c.webhookBaseURL, c.webhookSecret)
 	}
-
-	currentConfig, err := c.GetWebhookConfig(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get current webhook configuration: %w", err)
-	}
-
-	webhookURL := currentConfig.WebhookURL
-	if webhookURL == "" {
-		if c.webhookBaseURL == "" {
-			return fmt.Errorf("webhook base URL is required for update")
-		}
-
-		if c.webhookBaseURL[len(c.webhookBaseURL)-1] == '/' {
-			webhookURL = fmt.Sprintf("%swebhooks?key=%s", c.webhookBaseURL, c.webhookSecret)
-		} else {
-			webhookURL = fmt.Sprintf("%s/webhooks?key=%s", c.webhookBaseURL, c.webhookSecret)
-		}
-	}
-
-	config := WebhookConfig{
-		WebhookURL:                     webhookURL,
-		WebhookType:                    "enhanced",
-		AccountAddresses:               addresses,
-		TransactionTypes:               []string{"ANY"},
-		AccountAddressTransactionTypes: currentConfig.AccountAddressTransactionTypes,
-		Blocks:                         currentConfig.Blocks,
-	}
-
-	configJSON, _ := json.Marshal(config)
-	log.Debug().
-		Str("webhookID", c.webhookID).
-		RawJSON("config", configJSON).
-		Msg("Updating webhook with configuration")
-
-	requestBody, err := json.Marshal(config)
-	if err != nil {
-		return fmt.Errorf("failed to marshal webhook config: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(
-		ctx,
-		http.MethodPut,
-		fmt.Sprintf("%s/webhooks/%s?api-key=%s", HeliusAPIBase, c.webhookID, c.apiKey),
-		bytes.NewBuffer(requestBody),
-	)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		log.Error().
-			Str("webhookID", c.webhookID).
-			Int("statusCode", resp.StatusCode).
-			Str("responseBody", string(body)).
-			Str("requestBody", string(requestBody)).
-			Msg("Failed to update webhook")
-
-		// If we failed to update, try to recreate the webhook
-		return c.recreateWebhookWithAddresses(ctx, addresses)
-	}
-
-	log.Info().
-		Str("webhookID", c.webhookID).
-		Int("addressCount", len(addresses)).
-		Msg("Successfully updated webhook addresses")
-
-	return nil
-}
-func (c *HeliusClient) recreateWebhookWithAddresses(ctx context.Context, addresses []string) error {
-	log.Info().Msg("Attempting to recreate webhook after update failure")
-
-	// Try to delete the existing webhook
-	oldWebhookID := c.webhookID
-	err := c.DeleteWebhook(ctx, oldWebhookID)
-	if err != nil {
-		log.Warn().Err(err).Msg("Failed to delete old webhook, continuing anyway")
-	}
-
-	// Create a new webhook config
-	config := WebhookConfig{
-		WebhookType:      "enhanced",
-		AccountAddresses: addresses,
-		TransactionTypes: []string{"ANY"},
-	}
-
-	// Create the new webhook
-	resp, err := c.CreateWebhook(ctx, config)
-	if err != nil {
-		return fmt.Errorf("failed to recreate webhook: %w", err)
-	}
-
-	log.Info().
-		Str("oldWebhookID", oldWebhookID).
-		Str("newWebhookID", resp.WebhookID).
-		Int("addressCount", len(addresses)).
-		Msg("Successfully recreated webhook")
-
-	return nil
+	return fmt.Sprintf("%s/webhooks?key=%s", c.webhookBaseURL, c.webhookSecret)
 }
 
-func (c *HeliusClient) createWebhookWithAddresses(ctx context.Context, addresses []string, indexerID string) error {
-	if c.webhookBaseURL == "" {
-		return fmt.Errorf("webhook base URL is required to create a webhook")
-	}
-
-	var webhookURL string
-	if c.webhookBaseURL[len(c.webhookBaseURL)-1] == '/' {
-		webhookURL = fmt.Sprintf("%swebhooks?key=%s", c.webhookBaseURL, c.webhookSecret)
-	} else {
-		webhookURL = fmt.Sprintf("%s/webhooks?key=%s", c.webhookBaseURL, c.webhookSecret)
-	}
-
-	config := WebhookConfig{
-		WebhookURL:       webhookURL,
-		WebhookType:      "enhanced",
-		AccountAddresses: addresses,
-		TransactionTypes: []string{"ANY"},
-	}
-
-	configJSON, _ := json.Marshal(config)
-	log.Info().
-		RawJSON("config", configJSON).
-		Msg("Creating new webhook with configuration")
-
-	response, err := c.CreateWebhook(ctx, config)
-	if err != nil {
-		log.Error().
-			Err(err).
-			Strs("addresses", addresses).
-			Msg("Failed to create webhook")
+// DeleteWebhook deletes a webhook on Helius and stops tracking it and the
+// addresses it held
+func (c *HeliusClient) DeleteWebhook(ctx context.Context, webhookID string) error {
+	if err := c.deleteWebhook(ctx, webhookID); err != nil {
 		return err
 	}
 
-	now := time.Now()
 	c.addressesLock.Lock()
-	for _, addr := range addresses {
-		c.addresses = append(c.addresses, AddressEntry{
-			Address:   addr,
-			IndexerID: indexerID,
-			AddedAt:   now,
-		})
-	}
+	c.forgetShard(webhookID)
 	c.addressesLock.Unlock()
 
-	log.Info().
-		Str("webhookID", response.WebhookID).
-		Str("webhookURL", webhookURL).
-		Int("addressCount", len(addresses)).
-		Strs("addresses", addresses).
-		Msg("Successfully created new webhook with addresses")
-
 	return nil
 }
 
-func (c *HeliusClient) updateWebhookAddresses(ctx context.Context) error {
-	addresses := c.GetAddresses()
-
-	if len(addresses) > MaxAddressesLimit {
-		for i := 0; i < len(addresses); i++ {
-			for j := i + 1; j < len(addresses); j++ {
-				if addresses[i].AddedAt.After(addresses[j].AddedAt) {
-					addresses[i], addresses[j] = addresses[j], addresses[i]
-				}
-			}
-		}
-
-		addresses = addresses[len(addresses)-MaxAddressesLimit:]
-
-		c.addressesLock.Lock()
-		c.addresses = addresses
-		c.addressesLock.Unlock()
-	}
-
-	addressList := make([]string, len(addresses))
-	for i, entry := range addresses {
-		addressList[i] = entry.Address
-	}
-
-	return c.updateWebhookWithAddresses(ctx, addressList)
-}
-
-func (c *HeliusClient) DeleteWebhook(ctx context.Context, webhookID string) error {
-	req, err := http.NewRequestWithContext(
+func (c *HeliusClient) deleteWebhook(ctx context.Context, webhookID string) error {
+	resp, err := c.doWithRetry(
 		ctx,
 		http.MethodDelete,
-		fmt.Sprintf("%s/webhooks/%s?api-key=%s", HeliusAPIBase, webhookID, c.apiKey),
+		fmt.Sprintf("%s/webhooks/%s?api-key=%s", c.apiBaseURL, webhookID, c.apiKey),
 		nil,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
+		return err
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to delete webhook: %s (status code: %d)", string(body), resp.StatusCode)
+		return fmt.Errorf("failed to delete webhook: %s (status code: %d)", string(resp.Body), resp.StatusCode)
 	}
 
 	return nil
 }
 
-func (c *HeliusClient) VerifyWebhookSignature(header, payload string) bool {
-	if c.webhookSecret == "" {
+// VerifyWebhookSignature checks the key a webhook delivery came with against
+// secret, the one the webhook's callback URL was created with. Deliveries
+// are accepted unchecked when there is no secret at all.
+func (c *HeliusClient) VerifyWebhookSignature(secret, header, payload string) bool {
+	if secret == "" {
 		log.Warn().Msg("No webhook secret configured, skipping signature verification")
 		return true
 	}
 
-	return header == c.webhookSecret
+	return subtle.ConstantTimeCompare([]byte(header), []byte(secret)) == 1
 }
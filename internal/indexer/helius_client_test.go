@@ -0,0 +1,84 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rishavmehra/indexer/internal/config"
+)
+
+func TestNewHeliusClientDefaultsToMainnet(t *testing.T) {
+	client := NewHeliusClient(config.HeliusConfig{})
+
+	if client.apiBaseURL != DefaultHeliusAPIBaseURL {
+		t.Errorf("API base URL = %q, want %q", client.apiBaseURL, DefaultHeliusAPIBaseURL)
+	}
+	if got := client.GetRPCURL(); got != DefaultHeliusRPCURL {
+		t.Errorf("RPC URL = %q, want %q", got, DefaultHeliusRPCURL)
+	}
+}
+
+func TestNewHeliusClientTrimsTrailingSlash(t *testing.T) {
+	client := NewHeliusClient(config.HeliusConfig{
+		APIBaseURL: "http://proxy.local/v0/",
+		RPCURL:     "http://rpc.local/",
+	})
+
+	if client.apiBaseURL != "http://proxy.local/v0" || client.GetRPCURL() != "http://rpc.local" {
+		t.Errorf("URLs = %q and %q, want them without the trailing slash", client.apiBaseURL, client.GetRPCURL())
+	}
+}
+
+func TestHeliusClientCallsConfiguredAPIBaseURL(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+		switch r.Method {
+		case http.MethodPost:
+			json.NewEncoder(w).Encode(map[string]string{"webhookID": "created"})
+		case http.MethodGet:
+			json.NewEncoder(w).Encode([]map[string]string{{"webhookID": "listed"}})
+		}
+	}))
+	defer server.Close()
+
+	client := newTestHeliusClient(server.URL + "/v0")
+	ctx := context.Background()
+
+	created, err := client.CreateWebhook(ctx, WebhookConfig{WebhookURL: "http://app.local/webhooks", WebhookType: "enhanced"})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	if created.WebhookID != "created" {
+		t.Errorf("created webhook ID = %q, want created", created.WebhookID)
+	}
+
+	webhooks, err := client.ListWebhooks(ctx)
+	if err != nil {
+		t.Fatalf("ListWebhooks: %v", err)
+	}
+	if len(webhooks) != 1 || webhooks[0].WebhookID != "listed" {
+		t.Errorf("webhooks = %+v, want the mock server's one", webhooks)
+	}
+
+	if err := client.deleteWebhook(ctx, "created"); err != nil {
+		t.Fatalf("deleteWebhook: %v", err)
+	}
+
+	want := []string{
+		"POST /v0/webhooks?api-key=test-key",
+		"GET /v0/webhooks?api-key=test-key",
+		"DELETE /v0/webhooks/created?api-key=test-key",
+	}
+	if len(paths) != len(want) {
+		t.Fatalf("requests = %v, want %v", paths, want)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Errorf("request %d = %q, want %q", i, paths[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// MaxAddressTransactionsLimit is the most transactions Helius returns per
+// page of an address's history
+const MaxAddressTransactionsLimit = 100
+
+// GetAddressTransactions returns up to limit enhanced transactions of an
+// address, newest first. When before is set, only transactions older than
+// that signature are returned, which pages further back in the history.
+func (c *HeliusClient) GetAddressTransactions(ctx context.Context, address, before string, limit int) ([]json.RawMessage, error) {
+	if limit <= 0 || limit > MaxAddressTransactionsLimit {
+		limit = MaxAddressTransactionsLimit
+	}
+
+	query := url.Values{}
+	query.Set("api-key", c.apiKey)
+	query.Set("limit", fmt.Sprint(limit))
+	if before != "" {
+		query.Set("before", before)
+	}
+
+	resp, err := c.doWithRetry(
+		ctx,
+		http.MethodGet,
+		fmt.Sprintf("%s/addresses/%s/transactions?%s", c.apiBaseURL, url.PathEscape(address), query.Encode()),
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get address transactions: %s (status code: %d)", string(resp.Body), resp.StatusCode)
+	}
+
+	var transactions []json.RawMessage
+	if err := json.Unmarshal(resp.Body, &transactions); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal address transactions: %w", err)
+	}
+
+	return transactions, nil
+}
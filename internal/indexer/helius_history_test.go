@@ -0,0 +1,75 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGetAddressTransactions(t *testing.T) {
+	var requests []*url.URL
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL)
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"signature": "sig-2", "slot": 2},
+			{"signature": "sig-1", "slot": 1},
+		})
+	}))
+	t.Cleanup(server.Close)
+	client := newTestHeliusClient(server.URL)
+
+	transactions, err := client.GetAddressTransactions(context.Background(), "collection", "sig-3", 2)
+	if err != nil {
+		t.Fatalf("GetAddressTransactions: %v", err)
+	}
+	if len(transactions) != 2 || !strings.Contains(string(transactions[0]), "sig-2") {
+		t.Errorf("transactions = %s, want both history entries in order", transactions)
+	}
+
+	if len(requests) != 1 {
+		t.Fatalf("server saw %d requests, want 1", len(requests))
+	}
+	got := requests[0]
+	if got.Path != "/addresses/collection/transactions" {
+		t.Errorf("path = %s, want the collection's transaction history", got.Path)
+	}
+	if got.Query().Get("before") != "sig-3" || got.Query().Get("limit") != "2" || got.Query().Get("api-key") != "test-key" {
+		t.Errorf("query = %s, want before=sig-3, limit=2 and the API key", got.RawQuery)
+	}
+}
+
+func TestGetAddressTransactionsClampsLimit(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(server.Close)
+	client := newTestHeliusClient(server.URL)
+
+	for _, limit := range []int{0, -5, MaxAddressTransactionsLimit + 1} {
+		if _, err := client.GetAddressTransactions(context.Background(), "collection", "", limit); err != nil {
+			t.Fatalf("GetAddressTransactions(limit %d): %v", limit, err)
+		}
+		if query.Get("limit") != "100" || query.Has("before") {
+			t.Errorf("limit %d sent query %s, want limit=100 and no before", limit, query.Encode())
+		}
+	}
+}
+
+func TestGetAddressTransactionsReportsErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad address", http.StatusBadRequest)
+	}))
+	t.Cleanup(server.Close)
+	client := newTestHeliusClient(server.URL)
+
+	_, err := client.GetAddressTransactions(context.Background(), "not-an-address", "", 10)
+	if err == nil || !strings.Contains(err.Error(), "status code: 400") {
+		t.Errorf("GetAddressTransactions error = %v, want the 400 reported", err)
+	}
+}
@@ -0,0 +1,169 @@
+package indexer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/rishavmehra/indexer/pkg/logger"
+)
+
+const (
+	DefaultHeliusMaxAttempts    = 4
+	DefaultHeliusRetryBaseDelay = 500 * time.Millisecond
+	// maxHeliusRetryDelay caps both the exponential backoff and Retry-After
+	maxHeliusRetryDelay = 30 * time.Second
+)
+
+// heliusResponse is a Helius API response whose body has been read
+type heliusResponse struct {
+	StatusCode int
+	Body       []byte
+}
+
+// doWithRetry sends a request to the Helius API, retrying network errors, 429
+// and 5xx responses with exponential backoff and jitter. A Retry-After header
+// on the response takes precedence over the computed backoff. Other statuses
+// are returned to the caller straight away, as is the last response once the
+// attempts run out.
+//
+// A POST is not idempotent: Helius may have acted on it before failing, and
+// sending it again would create a second webhook. It is only retried on 429
+// or when the request never reached the server.
+func (c *HeliusClient) doWithRetry(ctx context.Context, method, url string, body []byte) (*heliusResponse, error) {
+	maxAttempts := c.maxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	idempotent := isIdempotentMethod(method)
+
+	for attempt := 1; ; attempt++ {
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(body)
+		}
+
+		// The transport reports the write from its own goroutine
+		var sent atomic.Bool
+		trace := &httptrace.ClientTrace{
+			WroteRequest: func(httptrace.WroteRequestInfo) { sent.Store(true) },
+		}
+
+		req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), method, url, reqBody)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		var delay time.Duration
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil || attempt >= maxAttempts || (!idempotent && sent.Load()) {
+				return nil, fmt.Errorf("failed to send request: %w", logger.RedactError(err))
+			}
+			delay = c.backoff(attempt)
+			log.Warn().
+				Err(logger.RedactError(err)).
+				Str("method", method).
+				Int("attempt", attempt).
+				Dur("retryIn", delay).
+				Msg("Helius request failed, retrying")
+		} else {
+			respBody, readErr := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if readErr != nil {
+				return nil, fmt.Errorf("failed to read response body: %w", readErr)
+			}
+
+			retryable := isRetryableStatus(resp.StatusCode)
+			if !idempotent {
+				retryable = resp.StatusCode == http.StatusTooManyRequests
+			}
+			if !retryable || attempt >= maxAttempts {
+				return &heliusResponse{StatusCode: resp.StatusCode, Body: respBody}, nil
+			}
+
+			delay = c.backoff(attempt)
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = retryAfter
+			}
+			log.Warn().
+				Str("method", method).
+				Int("statusCode", resp.StatusCode).
+				Int("attempt", attempt).
+				Dur("retryIn", delay).
+				Msg("Helius returned a retryable status, retrying")
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the delay before the retry that follows the given attempt:
+// the base delay doubled per attempt, with the upper half randomised
+func (c *HeliusClient) backoff(attempt int) time.Duration {
+	base := c.retryBaseDelay
+	if base <= 0 {
+		base = DefaultHeliusRetryBaseDelay
+	}
+
+	delay := base << (attempt - 1)
+	if delay <= 0 || delay > maxHeliusRetryDelay {
+		delay = maxHeliusRetryDelay
+	}
+
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// isIdempotentMethod reports whether sending the request twice has the same
+// effect as sending it once
+func isIdempotentMethod(method string) bool {
+	return method != http.MethodPost && method != http.MethodPatch
+}
+
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an
+// HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		delay = time.Until(at)
+	} else {
+		return 0, false
+	}
+
+	if delay < 0 {
+		delay = 0
+	}
+	if delay > maxHeliusRetryDelay {
+		delay = maxHeliusRetryDelay
+	}
+	return delay, true
+}
@@ -0,0 +1,148 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rishavmehra/indexer/internal/config"
+)
+
+func newTestHeliusClient(url string) *HeliusClient {
+	return NewHeliusClient(config.HeliusConfig{
+		APIKey:         "test-key",
+		APIBaseURL:     url,
+		MaxAttempts:    3,
+		RetryBaseDelay: time.Millisecond,
+	})
+}
+
+// statusServer answers with the given statuses in turn, repeating the last
+func statusServer(t *testing.T, requests *atomic.Int32, statuses ...int) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1)) - 1
+		if n >= len(statuses) {
+			n = len(statuses) - 1
+		}
+		w.WriteHeader(statuses[n])
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDoWithRetryRetriesTooManyRequests(t *testing.T) {
+	var requests atomic.Int32
+	server := statusServer(t, &requests, http.StatusTooManyRequests, http.StatusOK)
+	client := newTestHeliusClient(server.URL)
+
+	resp, err := client.doWithRetry(context.Background(), http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("doWithRetry: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || requests.Load() != 2 {
+		t.Errorf("got status %d after %d requests, want 200 after 2", resp.StatusCode, requests.Load())
+	}
+}
+
+func TestDoWithRetryReturnsLastResponseWhenAttemptsRunOut(t *testing.T) {
+	var requests atomic.Int32
+	server := statusServer(t, &requests, http.StatusServiceUnavailable)
+	client := newTestHeliusClient(server.URL)
+
+	resp, err := client.doWithRetry(context.Background(), http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("doWithRetry: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable || requests.Load() != 3 {
+		t.Errorf("got status %d after %d requests, want 503 after 3", resp.StatusCode, requests.Load())
+	}
+}
+
+func TestDoWithRetryDoesNotRetryPostOnServerError(t *testing.T) {
+	var requests atomic.Int32
+	server := statusServer(t, &requests, http.StatusInternalServerError, http.StatusOK)
+	client := newTestHeliusClient(server.URL)
+
+	resp, err := client.doWithRetry(context.Background(), http.MethodPost, server.URL, []byte(`{}`))
+	if err != nil {
+		t.Fatalf("doWithRetry: %v", err)
+	}
+	if resp.StatusCode != http.StatusInternalServerError || requests.Load() != 1 {
+		t.Errorf("got status %d after %d requests, want 500 after 1", resp.StatusCode, requests.Load())
+	}
+}
+
+func TestDoWithRetryRetriesPostOnTooManyRequests(t *testing.T) {
+	var requests atomic.Int32
+	server := statusServer(t, &requests, http.StatusTooManyRequests, http.StatusOK)
+	client := newTestHeliusClient(server.URL)
+
+	resp, err := client.doWithRetry(context.Background(), http.MethodPost, server.URL, []byte(`{}`))
+	if err != nil {
+		t.Fatalf("doWithRetry: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || requests.Load() != 2 {
+		t.Errorf("got status %d after %d requests, want 200 after 2", resp.StatusCode, requests.Load())
+	}
+}
+
+func TestDoWithRetryDoesNotRetryPostAfterConnectionDrop(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	t.Cleanup(server.Close)
+	client := newTestHeliusClient(server.URL)
+
+	if _, err := client.doWithRetry(context.Background(), http.MethodPost, server.URL, []byte(`{}`)); err == nil {
+		t.Fatal("doWithRetry succeeded on a dropped connection")
+	}
+	if requests.Load() != 1 {
+		t.Errorf("server saw %d requests, want 1", requests.Load())
+	}
+}
+
+// failingTransport fails every request before anything is written
+type failingTransport struct {
+	attempts atomic.Int32
+}
+
+func (t *failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	t.attempts.Add(1)
+	return nil, errors.New("connection refused")
+}
+
+func TestDoWithRetryRetriesPostThatNeverReachedServer(t *testing.T) {
+	client := newTestHeliusClient("http://helius.invalid")
+	transport := &failingTransport{}
+	client.httpClient.Transport = transport
+
+	if _, err := client.doWithRetry(context.Background(), http.MethodPost, "http://helius.invalid", []byte(`{}`)); err == nil {
+		t.Fatal("doWithRetry succeeded without a server")
+	}
+	if transport.attempts.Load() != 3 {
+		t.Errorf("got %d attempts, want 3", transport.attempts.Load())
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if delay, ok := parseRetryAfter("2"); !ok || delay != 2*time.Second {
+		t.Errorf("parseRetryAfter(2) = %v, %v", delay, ok)
+	}
+	if delay, ok := parseRetryAfter("3600"); !ok || delay != maxHeliusRetryDelay {
+		t.Errorf("parseRetryAfter(3600) = %v, %v; want the cap", delay, ok)
+	}
+	if _, ok := parseRetryAfter("soon"); ok {
+		t.Error("parseRetryAfter accepted an invalid value")
+	}
+}
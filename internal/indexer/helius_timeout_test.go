@@ -0,0 +1,107 @@
+package indexer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rishavmehra/indexer/internal/config"
+)
+
+// slowServer never answers, holding each request until the client gives up
+func slowServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	t.Cleanup(server.Close)
+	// Cleanups run last first, so waiting requests are released before Close
+	t.Cleanup(func() { close(release) })
+	return server
+}
+
+// within fails the test unless call returns an error in well under limit
+func within(t *testing.T, limit time.Duration, call func() error) {
+	t.Helper()
+
+	start := time.Now()
+	err := call()
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("call against a server that never answers succeeded")
+	}
+	if elapsed > limit {
+		t.Errorf("gave up after %v, want within %v", elapsed, limit)
+	}
+}
+
+func TestHeliusClientGivesUpAtHTTPTimeout(t *testing.T) {
+	server := slowServer(t)
+	client := NewHeliusClient(config.HeliusConfig{
+		APIKey:      "test-key",
+		APIBaseURL:  server.URL,
+		MaxAttempts: 1,
+		HTTPTimeout: 50 * time.Millisecond,
+	})
+
+	within(t, 2*time.Second, func() error {
+		_, err := client.ListWebhooks(context.Background())
+		return err
+	})
+}
+
+func TestHeliusClientHonorsContextDeadline(t *testing.T) {
+	server := slowServer(t)
+	client := NewHeliusClient(config.HeliusConfig{
+		APIKey:      "test-key",
+		APIBaseURL:  server.URL,
+		MaxAttempts: 3,
+		HTTPTimeout: time.Minute,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	within(t, 2*time.Second, func() error {
+		_, err := client.ListWebhooks(ctx)
+		return err
+	})
+}
+
+func TestHeliusTimeoutDefaults(t *testing.T) {
+	client := NewHeliusClient(config.HeliusConfig{})
+	if client.httpClient.Timeout != DefaultHeliusHTTPTimeout {
+		t.Errorf("HTTP timeout = %v, want %v", client.httpClient.Timeout, DefaultHeliusHTTPTimeout)
+	}
+	if fetcher := NewTokenMetadataFetcher("", "", 0, nil); fetcher.httpClient.Timeout != DefaultHeliusRPCTimeout {
+		t.Errorf("metadata RPC timeout = %v, want %v", fetcher.httpClient.Timeout, DefaultHeliusRPCTimeout)
+	}
+}
+
+func TestRPCFetchersGiveUpAtRPCTimeout(t *testing.T) {
+	server := slowServer(t)
+	timeout := 50 * time.Millisecond
+
+	t.Run("metadata", func(t *testing.T) {
+		fetcher := NewTokenMetadataFetcher(server.URL, "test-key", timeout, nil)
+		within(t, 2*time.Second, func() error {
+			_, err := fetcher.FetchTokenMetadata(context.Background(), usdcMint)
+			return err
+		})
+	})
+
+	t.Run("supply", func(t *testing.T) {
+		fetcher := NewTokenSupplyFetcher(server.URL, "test-key", timeout)
+		within(t, 2*time.Second, func() error {
+			_, err := fetcher.FetchTokenSupply(context.Background(), usdcMint)
+			return err
+		})
+	})
+}
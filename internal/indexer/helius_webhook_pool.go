@@ -0,0 +1,737 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrAddressLimitExceeded is returned when addresses do not fit on the
+// webhooks the client is allowed to hold
+var ErrAddressLimitExceeded = errors.New("webhook address limit exceeded")
+
+// AddressLimitError reports how many addresses could not be placed. Nothing is
+// changed on Helius when it is returned.
+type AddressLimitError struct {
+	Requested int
+	Available int
+}
+
+func (e *AddressLimitError) Error() string {
+	return fmt.Sprintf("%s: %d new addresses requested but only %d webhook address slots are free",
+		ErrAddressLimitExceeded, e.Requested, e.Available)
+}
+
+func (e *AddressLimitError) Unwrap() error {
+	return ErrAddressLimitExceeded
+}
+
+// webhookShard is one Helius webhook in a pool of webhooks that share a
+// callback URL. Helius caps the addresses per webhook, so addresses beyond
+// MaxAddressesLimit are spread over further shards instead of being dropped.
+type webhookShard struct {
+	id string
+	// config mirrors the webhook on Helius, including its address list
+	config WebhookConfig
+	// loaded is false until the configuration has been read from Helius,
+	// which is only needed for the pre-configured shared webhook
+	loaded bool
+}
+
+func (s *webhookShard) freeSlots() int {
+	return MaxAddressesLimit - len(s.config.AccountAddresses)
+}
+
+func (s *webhookShard) hasAddress(address string) bool {
+	for _, existing := range s.config.AccountAddresses {
+		if existing == address {
+			return true
+		}
+	}
+	return false
+}
+
+// AddAddresses adds addresses to the shared webhook pool, creating further
+// webhooks once the existing ones are full.
+func (c *HeliusClient) AddAddresses(ctx context.Context, addresses []string, indexerID string) error {
+	if c.webhookBaseURL == "" && c.webhookID == "" {
+		return fmt.Errorf("webhook base URL is required to create a webhook")
+	}
+
+	_, err := c.AllocateAddresses(ctx, c.defaultWebhookURL(), addresses, WebhookSettings{}, indexerID)
+	return err
+}
+
+// WebhookSettings are the parts of a webhook's configuration chosen by the
+// indexer it serves. Empty fields leave existing webhooks as they are and give
+// new ones an enhanced webhook for ANY transaction.
+type WebhookSettings struct {
+	WebhookType      string
+	TransactionTypes []string
+}
+
+// AllocateAddresses places addresses for an indexer on the webhooks that call
+// webhookURL. Addresses fill webhooks that still have free slots before new
+// webhooks are created. Webhooks in the pool are brought in line with
+// settings first. It returns the IDs of every webhook holding the indexer's
+// addresses so the caller can map each of them back to the indexer.
+func (c *HeliusClient) AllocateAddresses(ctx context.Context, webhookURL string, addresses []string, settings WebhookSettings, indexerID string) ([]string, error) {
+	if webhookURL == "" {
+		return nil, fmt.Errorf("webhook URL is required")
+	}
+
+	c.addressesLock.Lock()
+	defer c.addressesLock.Unlock()
+
+	if err := c.loadShards(ctx, webhookURL); err != nil {
+		return nil, err
+	}
+	if err := c.applySettings(ctx, webhookURL, settings); err != nil {
+		return nil, err
+	}
+
+	var webhookIDs []string
+	touched := make(map[string]bool)
+	touch := func(webhookID string) {
+		if !touched[webhookID] {
+			touched[webhookID] = true
+			webhookIDs = append(webhookIDs, webhookID)
+		}
+	}
+
+	var pending []string
+	seen := make(map[string]bool)
+	for _, addr := range addresses {
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+
+		if shard := c.shardFor(webhookURL, addr); shard != nil {
+			c.trackAddresses([]string{addr}, indexerID, shard.id)
+			touch(shard.id)
+			continue
+		}
+		pending = append(pending, addr)
+	}
+
+	if len(pending) == 0 {
+		log.Info().
+			Str("indexerID", indexerID).
+			Msg("No new addresses to add to webhooks")
+		return webhookIDs, nil
+	}
+
+	if err := c.checkCapacity(webhookURL, len(pending)); err != nil {
+		return webhookIDs, err
+	}
+
+	for _, shard := range c.shards[webhookURL] {
+		if len(pending) == 0 {
+			break
+		}
+		free := shard.freeSlots()
+		if free <= 0 {
+			continue
+		}
+		if free > len(pending) {
+			free = len(pending)
+		}
+		chunk := pending[:free]
+
+		merged := make([]string, 0, len(shard.config.AccountAddresses)+len(chunk))
+		merged = append(merged, shard.config.AccountAddresses...)
+		merged = append(merged, chunk...)
+
+		if err := c.putShardAddresses(ctx, shard, merged); err != nil {
+			return webhookIDs, err
+		}
+
+		c.trackAddresses(chunk, indexerID, shard.id)
+		touch(shard.id)
+		pending = pending[free:]
+	}
+
+	for len(pending) > 0 {
+		size := MaxAddressesLimit
+		if size > len(pending) {
+			size = len(pending)
+		}
+		chunk := pending[:size]
+
+		shard, err := c.createShard(ctx, webhookURL, chunk, settings)
+		if err != nil {
+			return webhookIDs, err
+		}
+
+		c.trackAddresses(chunk, indexerID, shard.id)
+		touch(shard.id)
+		pending = pending[size:]
+	}
+
+	log.Info().
+		Str("indexerID", indexerID).
+		Strs("webhookIDs", webhookIDs).
+		Int("addressCount", len(seen)).
+		Msg("Allocated addresses to webhooks")
+
+	return webhookIDs, nil
+}
+
+// SetWebhookSettings changes the webhook type and transaction types of every
+// webhook that calls webhookURL, leaving their addresses as they are
+func (c *HeliusClient) SetWebhookSettings(ctx context.Context, webhookURL string, settings WebhookSettings) error {
+	if webhookURL == "" || (settings.WebhookType == "" && len(settings.TransactionTypes) == 0) {
+		return nil
+	}
+
+	c.addressesLock.Lock()
+	defer c.addressesLock.Unlock()
+
+	if err := c.loadShards(ctx, webhookURL); err != nil {
+		return err
+	}
+	return c.applySettings(ctx, webhookURL, settings)
+}
+
+// ChangeWebhookURL points every webhook calling oldURL at newURL, keeping
+// their addresses and settings, and returns the IDs of the webhooks changed.
+// Webhooks are looked up on Helius, so those created before a restart are
+// found too. On error the webhooks changed so far keep newURL.
+func (c *HeliusClient) ChangeWebhookURL(ctx context.Context, oldURL, newURL string) ([]string, error) {
+	if oldURL == "" || newURL == "" {
+		return nil, fmt.Errorf("webhook URL is required")
+	}
+
+	c.addressesLock.Lock()
+	defer c.addressesLock.Unlock()
+
+	webhooks, err := c.ListWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for _, webhook := range webhooks {
+		if webhook.WebhookURL != oldURL {
+			continue
+		}
+
+		_, shard := c.findShard(webhook.WebhookID)
+		if shard == nil {
+			shard = &webhookShard{
+				id: webhook.WebhookID,
+				config: WebhookConfig{
+					WebhookType:      webhook.WebhookType,
+					TransactionTypes: webhook.TransactionTypes,
+					AccountAddresses: webhook.AccountAddresses,
+				},
+				loaded: true,
+			}
+			c.shards[oldURL] = append(c.shards[oldURL], shard)
+		}
+
+		previous := shard.config
+		shard.config.WebhookURL = newURL
+		if err := c.putShardAddresses(ctx, shard, shard.config.AccountAddresses); err != nil {
+			shard.config = previous
+			return changed, fmt.Errorf("failed to change webhook URL: %w", err)
+		}
+		changed = append(changed, shard.id)
+
+		c.dropShard(oldURL, shard.id)
+		c.shards[newURL] = append(c.shards[newURL], shard)
+	}
+
+	log.Info().
+		Strs("webhookIDs", changed).
+		Msg("Changed webhook callback URL")
+
+	return changed, nil
+}
+
+// applySettings updates the webhooks in the pool for webhookURL whose webhook
+// type or transaction types differ from settings. Callers must hold
+// addressesLock.
+func (c *HeliusClient) applySettings(ctx context.Context, webhookURL string, settings WebhookSettings) error {
+	if settings.WebhookType == "" && len(settings.TransactionTypes) == 0 {
+		return nil
+	}
+
+	for _, shard := range c.shards[webhookURL] {
+		next := shard.config
+		if settings.WebhookType != "" {
+			next.WebhookType = settings.WebhookType
+		}
+		if len(settings.TransactionTypes) > 0 {
+			next.TransactionTypes = settings.TransactionTypes
+		}
+		if next.WebhookType == shard.config.WebhookType && sameTransactionTypes(shard.config.TransactionTypes, next.TransactionTypes) {
+			continue
+		}
+
+		previous := shard.config
+		shard.config = next
+		if err := c.putShardAddresses(ctx, shard, shard.config.AccountAddresses); err != nil {
+			shard.config = previous
+			return fmt.Errorf("failed to update webhook settings: %w", err)
+		}
+
+		log.Info().
+			Str("webhookID", shard.id).
+			Str("webhookType", next.WebhookType).
+			Strs("transactionTypes", next.TransactionTypes).
+			Msg("Updated webhook settings")
+	}
+
+	return nil
+}
+
+// sameTransactionTypes compares two transaction type lists ignoring order
+func sameTransactionTypes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := make(map[string]int, len(a))
+	for _, t := range a {
+		counts[t]++
+	}
+	for _, t := range b {
+		if counts[t] == 0 {
+			return false
+		}
+		counts[t]--
+	}
+	return true
+}
+
+// RemoveIndexerAddresses releases the addresses owned by an indexer. Addresses
+// still owned by another indexer stay on their webhook. Webhooks left without
+// addresses are deleted, except for the configured shared webhook.
+func (c *HeliusClient) RemoveIndexerAddresses(ctx context.Context, indexerID string) error {
+	return c.releaseAddresses(ctx, indexerID, nil)
+}
+
+// RemoveAddresses releases only the given addresses of an indexer, as
+// RemoveIndexerAddresses does for all of them
+func (c *HeliusClient) RemoveAddresses(ctx context.Context, addresses []string, indexerID string) error {
+	if len(addresses) == 0 {
+		return nil
+	}
+
+	only := make(map[string]bool, len(addresses))
+	for _, addr := range addresses {
+		only[addr] = true
+	}
+	return c.releaseAddresses(ctx, indexerID, only)
+}
+
+// releaseAddresses drops the indexer's ownership of the addresses in only, or
+// of every address when only is nil, and updates the webhooks accordingly
+func (c *HeliusClient) releaseAddresses(ctx context.Context, indexerID string, only map[string]bool) error {
+	c.addressesLock.Lock()
+	defer c.addressesLock.Unlock()
+
+	var remaining []AddressEntry
+	var removed []AddressEntry
+	for _, entry := range c.addresses {
+		if entry.IndexerID == indexerID && (only == nil || only[entry.Address]) {
+			removed = append(removed, entry)
+		} else {
+			remaining = append(remaining, entry)
+		}
+	}
+
+	if len(removed) == 0 {
+		return nil
+	}
+
+	c.addresses = remaining
+
+	released := make(map[string]map[string]bool)
+	for _, entry := range removed {
+		if c.isTracked(entry.Address, entry.WebhookID) {
+			continue
+		}
+		if released[entry.WebhookID] == nil {
+			released[entry.WebhookID] = make(map[string]bool)
+		}
+		released[entry.WebhookID][entry.Address] = true
+	}
+
+	for webhookID, addrs := range released {
+		webhookURL, shard := c.findShard(webhookID)
+		if shard == nil {
+			continue
+		}
+
+		kept := make([]string, 0, len(shard.config.AccountAddresses))
+		for _, addr := range shard.config.AccountAddresses {
+			if !addrs[addr] {
+				kept = append(kept, addr)
+			}
+		}
+
+		if len(kept) == 0 && shard.id != c.webhookID {
+			if err := c.deleteWebhook(ctx, shard.id); err != nil {
+				return fmt.Errorf("failed to delete empty webhook %s: %w", shard.id, err)
+			}
+			c.dropShard(webhookURL, shard.id)
+			continue
+		}
+
+		if err := c.putShardAddresses(ctx, shard, kept); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *HeliusClient) GetAddresses() []AddressEntry {
+	c.addressesLock.RLock()
+	defer c.addressesLock.RUnlock()
+
+	result := make([]AddressEntry, len(c.addresses))
+	copy(result, c.addresses)
+	return result
+}
+
+// checkCapacity fails when placing count new addresses on the pool for
+// webhookURL would need more webhooks than maxWebhooks allows. Callers must
+// hold addressesLock.
+func (c *HeliusClient) checkCapacity(webhookURL string, count int) error {
+	if c.maxWebhooks <= 0 {
+		return nil
+	}
+
+	webhooks := 0
+	for _, pool := range c.shards {
+		webhooks += len(pool)
+	}
+
+	available := 0
+	for _, shard := range c.shards[webhookURL] {
+		if free := shard.freeSlots(); free > 0 {
+			available += free
+		}
+	}
+	if spare := c.maxWebhooks - webhooks; spare > 0 {
+		available += spare * MaxAddressesLimit
+	}
+
+	if count > available {
+		log.Warn().
+			Int("requested", count).
+			Int("available", available).
+			Int("maxWebhooks", c.maxWebhooks).
+			Msg("Not enough webhook capacity for addresses")
+
+		return &AddressLimitError{Requested: count, Available: available}
+	}
+
+	return nil
+}
+
+// loadShards seeds the shared pool with the configured webhook and reads the
+// configuration of any shard not yet loaded. A shard that no longer exists on
+// Helius is recreated with the addresses tracked for it. Callers must hold
+// addressesLock.
+func (c *HeliusClient) loadShards(ctx context.Context, webhookURL string) error {
+	if c.webhookID != "" && webhookURL == c.defaultWebhookURL() && len(c.shards[webhookURL]) == 0 {
+		c.shards[webhookURL] = []*webhookShard{{id: c.webhookID}}
+	}
+
+	for _, shard := range c.shards[webhookURL] {
+		if shard.loaded {
+			continue
+		}
+
+		config, err := c.getWebhookConfig(ctx, shard.id)
+		if errors.Is(err, ErrWebhookNotFound) {
+			log.Warn().
+				Str("webhookID", shard.id).
+				Msg("Webhook no longer exists, recreating it")
+
+			shard.config = WebhookConfig{WebhookURL: webhookURL}
+			shard.loaded = true
+			if err := c.recreateShard(ctx, shard, c.trackedAddressList(shard.id)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get current webhook configuration: %w", err)
+		}
+
+		shard.config = *config
+		if shard.config.WebhookURL == "" {
+			shard.config.WebhookURL = webhookURL
+		}
+		shard.loaded = true
+	}
+
+	return nil
+}
+
+// putShardAddresses replaces the address list of a webhook, keeping the rest of
+// its configuration. The webhook is recreated only if Helius reports that it
+// no longer exists. Callers must hold addressesLock.
+func (c *HeliusClient) putShardAddresses(ctx context.Context, shard *webhookShard, addresses []string) error {
+	config := shard.config
+	config.AccountAddresses = addresses
+	if config.WebhookType == "" {
+		config.WebhookType = "enhanced"
+	}
+	if len(config.TransactionTypes) == 0 {
+		config.TransactionTypes = []string{"ANY"}
+	}
+
+	requestBody, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook config: %w", err)
+	}
+
+	log.Debug().
+		Str("webhookID", shard.id).
+		RawJSON("config", requestBody).
+		Msg("Updating webhook with configuration")
+
+	resp, err := c.doWithRetry(
+		ctx,
+		http.MethodPut,
+		fmt.Sprintf("%s/webhooks/%s?api-key=%s", c.apiBaseURL, shard.id, c.apiKey),
+		requestBody,
+	)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		log.Warn().
+			Str("webhookID", shard.id).
+			Msg("Webhook disappeared before update, recreating it")
+
+		return c.recreateShard(ctx, shard, addresses)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		log.Error().
+			Str("webhookID", shard.id).
+			Int("statusCode", resp.StatusCode).
+			Str("responseBody", string(resp.Body)).
+			Msg("Failed to update webhook")
+
+		return fmt.Errorf("failed to update webhook: %s (status code: %d)", string(resp.Body), resp.StatusCode)
+	}
+
+	shard.config = config
+
+	log.Info().
+		Str("webhookID", shard.id).
+		Int("addressCount", len(addresses)).
+		Msg("Successfully updated webhook addresses")
+
+	return nil
+}
+
+// createShard creates a new webhook in the pool for webhookURL with settings,
+// falling back to an enhanced webhook for ANY transaction. Callers must hold
+// addressesLock.
+func (c *HeliusClient) createShard(ctx context.Context, webhookURL string, addresses []string, settings WebhookSettings) (*webhookShard, error) {
+	transactionTypes := settings.TransactionTypes
+	if len(transactionTypes) == 0 {
+		transactionTypes = []string{anyTransactionType}
+	}
+	webhookType := settings.WebhookType
+	if webhookType == "" {
+		webhookType = "enhanced"
+	}
+
+	config := WebhookConfig{
+		WebhookURL:       webhookURL,
+		WebhookType:      webhookType,
+		AccountAddresses: addresses,
+		TransactionTypes: transactionTypes,
+	}
+
+	response, err := c.CreateWebhook(ctx, config)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Strs("addresses", addresses).
+			Msg("Failed to create webhook")
+		return nil, err
+	}
+
+	shard := &webhookShard{
+		id:     response.WebhookID,
+		config: config,
+		loaded: true,
+	}
+	c.shards[webhookURL] = append(c.shards[webhookURL], shard)
+
+	if c.webhookID == "" && webhookURL == c.defaultWebhookURL() {
+		c.webhookID = shard.id
+	}
+
+	log.Info().
+		Str("webhookID", shard.id).
+		Int("addressCount", len(addresses)).
+		Int("poolSize", len(c.shards[webhookURL])).
+		Msg("Successfully created new webhook with addresses")
+
+	return shard, nil
+}
+
+// recreateShard replaces a webhook that no longer exists on Helius. Tracked
+// addresses and any mapping held for the old ID move to the new webhook.
+// Callers must hold addressesLock.
+func (c *HeliusClient) recreateShard(ctx context.Context, shard *webhookShard, addresses []string) error {
+	oldWebhookID := shard.id
+
+	log.Info().
+		Str("oldWebhookID", oldWebhookID).
+		Msg("Recreating webhook")
+
+	if err := c.deleteWebhook(ctx, oldWebhookID); err != nil {
+		log.Warn().Err(err).Msg("Failed to delete old webhook, continuing anyway")
+	}
+
+	config := shard.config
+	config.AccountAddresses = addresses
+	if config.WebhookType == "" {
+		config.WebhookType = "enhanced"
+	}
+	if len(config.TransactionTypes) == 0 {
+		config.TransactionTypes = []string{"ANY"}
+	}
+
+	resp, err := c.CreateWebhook(ctx, config)
+	if err != nil {
+		return fmt.Errorf("failed to recreate webhook: %w", err)
+	}
+
+	shard.id = resp.WebhookID
+	shard.config = config
+
+	if c.webhookID == oldWebhookID {
+		c.webhookID = resp.WebhookID
+	}
+	for i := range c.addresses {
+		if c.addresses[i].WebhookID == oldWebhookID {
+			c.addresses[i].WebhookID = resp.WebhookID
+		}
+	}
+	ReplaceWebhookMapping(oldWebhookID, resp.WebhookID)
+
+	log.Info().
+		Str("oldWebhookID", oldWebhookID).
+		Str("newWebhookID", resp.WebhookID).
+		Int("addressCount", len(addresses)).
+		Msg("Successfully recreated webhook")
+
+	return nil
+}
+
+// shardFor returns the shard in the pool that already holds address. Callers
+// must hold addressesLock.
+func (c *HeliusClient) shardFor(webhookURL, address string) *webhookShard {
+	for _, shard := range c.shards[webhookURL] {
+		if shard.hasAddress(address) {
+			return shard
+		}
+	}
+	return nil
+}
+
+// findShard looks a shard up by webhook ID across all pools. Callers must hold
+// addressesLock.
+func (c *HeliusClient) findShard(webhookID string) (string, *webhookShard) {
+	for webhookURL, pool := range c.shards {
+		for _, shard := range pool {
+			if shard.id == webhookID {
+				return webhookURL, shard
+			}
+		}
+	}
+	return "", nil
+}
+
+// dropShard removes a shard from its pool. Callers must hold addressesLock.
+func (c *HeliusClient) dropShard(webhookURL, webhookID string) {
+	pool := c.shards[webhookURL]
+	for i, shard := range pool {
+		if shard.id == webhookID {
+			c.shards[webhookURL] = append(pool[:i], pool[i+1:]...)
+			break
+		}
+	}
+	if len(c.shards[webhookURL]) == 0 {
+		delete(c.shards, webhookURL)
+	}
+}
+
+// forgetShard stops tracking a deleted webhook and the addresses it held.
+// Callers must hold addressesLock.
+func (c *HeliusClient) forgetShard(webhookID string) {
+	if webhookURL, shard := c.findShard(webhookID); shard != nil {
+		c.dropShard(webhookURL, webhookID)
+	}
+
+	remaining := c.addresses[:0]
+	for _, entry := range c.addresses {
+		if entry.WebhookID != webhookID {
+			remaining = append(remaining, entry)
+		}
+	}
+	c.addresses = remaining
+
+	if c.webhookID == webhookID {
+		c.webhookID = ""
+	}
+}
+
+// trackAddresses records addresses as owned by the indexer on a webhook.
+// Callers must hold addressesLock.
+func (c *HeliusClient) trackAddresses(addresses []string, indexerID, webhookID string) {
+	now := time.Now()
+	for _, addr := range addresses {
+		c.addresses = append(c.addresses, AddressEntry{
+			Address:   addr,
+			IndexerID: indexerID,
+			WebhookID: webhookID,
+			AddedAt:   now,
+		})
+	}
+}
+
+// trackedAddressList returns the distinct addresses tracked on a webhook.
+// Callers must hold addressesLock.
+func (c *HeliusClient) trackedAddressList(webhookID string) []string {
+	seen := make(map[string]bool)
+	addressList := []string{}
+	for _, entry := range c.addresses {
+		if entry.WebhookID == webhookID && !seen[entry.Address] {
+			seen[entry.Address] = true
+			addressList = append(addressList, entry.Address)
+		}
+	}
+	return addressList
+}
+
+// isTracked reports whether any indexer still owns address on a webhook.
+// Callers must hold addressesLock.
+func (c *HeliusClient) isTracked(address, webhookID string) bool {
+	for _, entry := range c.addresses {
+		if entry.Address == address && entry.WebhookID == webhookID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,345 @@
+package indexer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rishavmehra/indexer/internal/config"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// heliusFake keeps webhooks in memory and serves the Helius webhook API
+type heliusFake struct {
+	mu       sync.Mutex
+	prefix   string
+	nextID   int
+	webhooks map[string]WebhookConfig
+	requests []string
+}
+
+func newHeliusFake(t *testing.T) (*heliusFake, *httptest.Server) {
+	t.Helper()
+
+	fake := &heliusFake{
+		prefix:   strings.ReplaceAll(t.Name(), "/", "-"),
+		webhooks: make(map[string]WebhookConfig),
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (f *heliusFake) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/webhooks"), "/")
+	f.requests = append(f.requests, strings.TrimSpace(r.Method+" "+id))
+
+	switch {
+	case r.Method == http.MethodPost && id == "":
+		var config WebhookConfig
+		json.NewDecoder(r.Body).Decode(&config)
+		f.nextID++
+		id = fmt.Sprintf("%s-%d", f.prefix, f.nextID)
+		f.webhooks[id] = config
+		json.NewEncoder(w).Encode(map[string]string{"webhookID": id})
+
+	case r.Method == http.MethodGet && id == "":
+		webhooks := []models.HeliusWebhook{}
+		for webhookID, config := range f.webhooks {
+			webhooks = append(webhooks, models.HeliusWebhook{
+				WebhookID:        webhookID,
+				WebhookURL:       config.WebhookURL,
+				WebhookType:      config.WebhookType,
+				TransactionTypes: config.TransactionTypes,
+				AccountAddresses: config.AccountAddresses,
+			})
+		}
+		json.NewEncoder(w).Encode(webhooks)
+
+	case r.Method == http.MethodGet:
+		config, ok := f.webhooks[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(config)
+
+	case r.Method == http.MethodPut:
+		if _, ok := f.webhooks[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var config WebhookConfig
+		json.NewDecoder(r.Body).Decode(&config)
+		f.webhooks[id] = config
+		json.NewEncoder(w).Encode(config)
+
+	case r.Method == http.MethodDelete:
+		if _, ok := f.webhooks[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.webhooks, id)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// add registers a webhook on the fake as if it had been created earlier
+func (f *heliusFake) add(id string, addresses ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.webhooks[id] = WebhookConfig{
+		WebhookURL:       "http://app.local/webhooks?key=secret",
+		WebhookType:      "enhanced",
+		TransactionTypes: []string{"ANY"},
+		AccountAddresses: addresses,
+	}
+}
+
+func (f *heliusFake) remove(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.webhooks, id)
+}
+
+func (f *heliusFake) addresses(id string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.webhooks[id].AccountAddresses
+}
+
+func (f *heliusFake) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.webhooks)
+}
+
+// resetRequests forgets the requests seen so far and returns them
+func (f *heliusFake) resetRequests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	requests := f.requests
+	f.requests = nil
+	return requests
+}
+
+func newPoolClient(url, webhookID string) *HeliusClient {
+	return NewHeliusClient(config.HeliusConfig{
+		APIKey:         "test-key",
+		APIBaseURL:     url,
+		WebhookBaseURL: "http://app.local",
+		WebhookSecret:  "secret",
+		WebhookID:      webhookID,
+	})
+}
+
+func testAddresses(prefix string, n int) []string {
+	addresses := make([]string, n)
+	for i := range addresses {
+		addresses[i] = fmt.Sprintf("%s-%d", prefix, i)
+	}
+	return addresses
+}
+
+func TestAddAddressesUpdatesWebhookInPlace(t *testing.T) {
+	fake, server := newHeliusFake(t)
+	fake.add("shared", "existing")
+	client := newPoolClient(server.URL, "shared")
+	ctx := t.Context()
+
+	if err := client.AddAddresses(ctx, []string{"first"}, "indexer-1"); err != nil {
+		t.Fatalf("AddAddresses: %v", err)
+	}
+	if err := client.AddAddresses(ctx, []string{"second"}, "indexer-2"); err != nil {
+		t.Fatalf("AddAddresses: %v", err)
+	}
+
+	if got := client.GetDefaultWebhookID(); got != "shared" {
+		t.Errorf("webhook ID = %q, want it unchanged", got)
+	}
+	if got := fake.addresses("shared"); strings.Join(got, ",") != "existing,first,second" {
+		t.Errorf("addresses = %v, want the new ones merged into the existing list", got)
+	}
+	for _, request := range fake.resetRequests() {
+		if strings.HasPrefix(request, http.MethodPost) || strings.HasPrefix(request, http.MethodDelete) {
+			t.Errorf("unexpected %s, want the webhook updated with PUT only", request)
+		}
+	}
+	for _, entry := range client.GetAddresses() {
+		if entry.WebhookID != "shared" {
+			t.Errorf("%s tracked on %q, want shared", entry.Address, entry.WebhookID)
+		}
+	}
+}
+
+func TestAddAddressesRecreatesMissingWebhookAndMovesMapping(t *testing.T) {
+	fake, server := newHeliusFake(t)
+	oldID := t.Name() + "-old"
+	fake.add(oldID)
+	client := newPoolClient(server.URL, oldID)
+	ctx := t.Context()
+
+	if err := client.AddAddresses(ctx, []string{"first"}, "indexer-1"); err != nil {
+		t.Fatalf("AddAddresses: %v", err)
+	}
+	RegisterWebhookMapping(oldID, "indexer-1")
+
+	fake.remove(oldID)
+	if err := client.AddAddresses(ctx, []string{"second"}, "indexer-1"); err != nil {
+		t.Fatalf("AddAddresses: %v", err)
+	}
+
+	newID := client.GetDefaultWebhookID()
+	if newID == oldID {
+		t.Fatal("webhook ID unchanged, want the missing webhook recreated")
+	}
+	if got := fake.addresses(newID); strings.Join(got, ",") != "first,second" {
+		t.Errorf("recreated webhook addresses = %v, want [first second]", got)
+	}
+	if indexerID, ok := GetIndexerIDFromHeliusWebhookID(newID); !ok || indexerID != "indexer-1" {
+		t.Errorf("mapping for %s = %q, %v, want indexer-1", newID, indexerID, ok)
+	}
+	if _, ok := GetIndexerIDFromHeliusWebhookID(oldID); ok {
+		t.Errorf("mapping for %s still present, want it moved", oldID)
+	}
+	for _, entry := range client.GetAddresses() {
+		if entry.WebhookID != newID {
+			t.Errorf("%s tracked on %q, want %s", entry.Address, entry.WebhookID, newID)
+		}
+	}
+}
+
+func TestAllocateAddressesShardsPastAddressLimit(t *testing.T) {
+	fake, server := newHeliusFake(t)
+	client := newPoolClient(server.URL, "")
+	ctx := t.Context()
+	webhookURL := client.defaultWebhookURL()
+
+	webhookIDs, err := client.AllocateAddresses(ctx, webhookURL, testAddresses("first", MaxAddressesLimit+5), WebhookSettings{}, "indexer-1")
+	if err != nil {
+		t.Fatalf("AllocateAddresses: %v", err)
+	}
+	if len(webhookIDs) != 2 || fake.count() != 2 {
+		t.Fatalf("got webhooks %v with %d on Helius, want 2", webhookIDs, fake.count())
+	}
+	if full, rest := len(fake.addresses(webhookIDs[0])), len(fake.addresses(webhookIDs[1])); full != MaxAddressesLimit || rest != 5 {
+		t.Errorf("webhooks hold %d and %d addresses, want %d and 5", full, rest, MaxAddressesLimit)
+	}
+
+	// The second indexer fills the free slots before any webhook is created
+	moreIDs, err := client.AllocateAddresses(ctx, webhookURL, testAddresses("second", MaxAddressesLimit-5), WebhookSettings{}, "indexer-2")
+	if err != nil {
+		t.Fatalf("AllocateAddresses: %v", err)
+	}
+	if len(moreIDs) != 1 || moreIDs[0] != webhookIDs[1] || fake.count() != 2 {
+		t.Errorf("got webhooks %v with %d on Helius, want only %s filled up", moreIDs, fake.count(), webhookIDs[1])
+	}
+	if got := len(fake.addresses(webhookIDs[1])); got != MaxAddressesLimit {
+		t.Errorf("second webhook holds %d addresses, want %d", got, MaxAddressesLimit)
+	}
+
+	for _, webhookID := range webhookIDs {
+		RegisterWebhookMapping(webhookID, "indexer-1")
+	}
+	for _, webhookID := range webhookIDs {
+		if indexerID, ok := GetIndexerIDFromHeliusWebhookID(webhookID); !ok || indexerID != "indexer-1" {
+			t.Errorf("mapping for %s = %q, %v, want indexer-1", webhookID, indexerID, ok)
+		}
+	}
+}
+
+func TestAllocateAddressesRejectsAddressesBeyondMaxWebhooks(t *testing.T) {
+	fake, server := newHeliusFake(t)
+	client := newPoolClient(server.URL, "")
+	client.maxWebhooks = 1
+	ctx := t.Context()
+
+	_, err := client.AllocateAddresses(ctx, client.defaultWebhookURL(), testAddresses("addr", MaxAddressesLimit+1), WebhookSettings{}, "indexer-1")
+
+	var limitErr *AddressLimitError
+	if !errors.As(err, &limitErr) || !errors.Is(err, ErrAddressLimitExceeded) {
+		t.Fatalf("err = %v, want an AddressLimitError", err)
+	}
+	if limitErr.Requested != MaxAddressesLimit+1 || limitErr.Available != MaxAddressesLimit {
+		t.Errorf("limit error = %+v, want %d requested and %d available", limitErr, MaxAddressesLimit+1, MaxAddressesLimit)
+	}
+	if fake.count() != 0 || len(client.GetAddresses()) != 0 {
+		t.Errorf("%d webhooks created and %d addresses tracked, want nothing changed", fake.count(), len(client.GetAddresses()))
+	}
+}
+
+func TestRemoveIndexerAddressesDeletesEmptiedShards(t *testing.T) {
+	fake, server := newHeliusFake(t)
+	client := newPoolClient(server.URL, "")
+	ctx := t.Context()
+	webhookURL := client.defaultWebhookURL()
+
+	if _, err := client.AllocateAddresses(ctx, webhookURL, testAddresses("first", MaxAddressesLimit), WebhookSettings{}, "indexer-1"); err != nil {
+		t.Fatalf("AllocateAddresses: %v", err)
+	}
+	secondIDs, err := client.AllocateAddresses(ctx, webhookURL, testAddresses("second", 3), WebhookSettings{}, "indexer-2")
+	if err != nil {
+		t.Fatalf("AllocateAddresses: %v", err)
+	}
+
+	if err := client.RemoveIndexerAddresses(ctx, "indexer-2"); err != nil {
+		t.Fatalf("RemoveIndexerAddresses: %v", err)
+	}
+
+	if fake.count() != 1 || fake.addresses(secondIDs[0]) != nil {
+		t.Errorf("%d webhooks left, want the one emptied by indexer-2 deleted", fake.count())
+	}
+	if got := len(client.GetAddresses()); got != MaxAddressesLimit {
+		t.Errorf("%d addresses tracked, want only indexer-1's %d", got, MaxAddressesLimit)
+	}
+}
+
+func TestRemoveAddressesReleasesOnlyGivenAddresses(t *testing.T) {
+	fake, server := newHeliusFake(t)
+	client := newPoolClient(server.URL, "")
+	ctx := t.Context()
+	webhookURL := client.defaultWebhookURL()
+
+	webhookIDs, err := client.AllocateAddresses(ctx, webhookURL, []string{"keep", "drop"}, WebhookSettings{}, "indexer-1")
+	if err != nil {
+		t.Fatalf("AllocateAddresses: %v", err)
+	}
+	if _, err := client.AllocateAddresses(ctx, webhookURL, []string{"drop"}, WebhookSettings{}, "indexer-2"); err != nil {
+		t.Fatalf("AllocateAddresses: %v", err)
+	}
+
+	if err := client.RemoveAddresses(ctx, []string{"drop"}, "indexer-1"); err != nil {
+		t.Fatalf("RemoveAddresses: %v", err)
+	}
+
+	// indexer-2 still watches drop, so it stays on the webhook
+	if got := fake.addresses(webhookIDs[0]); strings.Join(got, ",") != "keep,drop" {
+		t.Errorf("webhook addresses = %v, want [keep drop]", got)
+	}
+	var owned []string
+	for _, entry := range client.GetAddresses() {
+		if entry.IndexerID == "indexer-1" {
+			owned = append(owned, entry.Address)
+		}
+	}
+	if strings.Join(owned, ",") != "keep" {
+		t.Errorf("indexer-1 owns %v, want only keep", owned)
+	}
+
+	if err := client.RemoveAddresses(ctx, []string{"drop"}, "indexer-2"); err != nil {
+		t.Fatalf("RemoveAddresses: %v", err)
+	}
+	if got := fake.addresses(webhookIDs[0]); strings.Join(got, ",") != "keep" {
+		t.Errorf("webhook addresses = %v, want drop removed once nobody watches it", got)
+	}
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/pkg/validator"
 )
 
 type Indexer interface {
@@ -16,31 +20,55 @@ type Indexer interface {
 
 	GetWebhookConfig(indexerID string) (WebhookConfig, error)
 
-	ProcessPayload(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload) error
+	// ProcessPayload writes the events of a payload to the target table and
+	// reports what it did
+	ProcessPayload(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload) (ProcessResult, error)
 }
 
 type TokenIndexer interface {
 	Indexer
-	ProcessPayloadWithMetadata(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload, heliusAPIKey string) error
+	ProcessPayloadWithMetadata(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload, heliusAPIKey string) (ProcessResult, error)
 
 	EnrichTokenMetadata(ctx context.Context, pool *pgxpool.Pool, targetTable string, heliusAPIKey string) error
 
 	InitializeWithAPIKey(ctx context.Context, conn *pgx.Conn, targetTable string, heliusAPIKey string) error
 }
 
+// BatchIndexer is implemented by indexers that can write a page of payloads,
+// such as a backfill page, in fewer round trips than one payload at a time
+type BatchIndexer interface {
+	Indexer
+	ProcessPayloads(ctx context.Context, pool *pgxpool.Pool, targetTable string, payloads []models.HeliusWebhookPayload) (ProcessResult, error)
+}
+
 type BaseIndexer struct {
-	ID          string
-	Params      json.RawMessage
-	initialized bool
+	ID     string
+	Params json.RawMessage
+	// TransactionTypes overrides the Helius transaction types the indexer's
+	// webhook asks for
+	TransactionTypes []string
+	// WebhookType is the Helius webhook type from params, empty for enhanced
+	WebhookType string
+	// acquireTimeout bounds waits for a target database connection, see
+	// SetAcquireTimeout
+	acquireTimeout time.Duration
+	initialized    bool
 }
 
 func NewBaseIndexer(id string, params json.RawMessage) BaseIndexer {
 	return BaseIndexer{
-		ID:     id,
-		Params: params,
+		ID:               id,
+		Params:           params,
+		TransactionTypes: parseTransactionTypes(params),
+		WebhookType:      parseWebhookType(params),
 	}
 }
 
+// RawParams returns the parameters the indexer was built from
+func (b *BaseIndexer) RawParams() json.RawMessage {
+	return b.Params
+}
+
 func (b *BaseIndexer) Initialize(ctx context.Context, conn *pgx.Conn, targetTable string) error {
 	if b.initialized {
 		return nil
@@ -49,21 +77,141 @@ func (b *BaseIndexer) Initialize(ctx context.Context, conn *pgx.Conn, targetTabl
 	return nil
 }
 
-func formatTableName(name string) string {
-	formattedName := ""
-	for _, c := range name {
-		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' {
-			formattedName += string(c)
-		} else {
-			formattedName += "_"
+// maxTableNameLength is the identifier length Postgres keeps, and the limit
+// validator.IsPlainTableName enforces
+const maxTableNameLength = 63
+
+// FormatTableName returns the name a target table has in the catalog. The
+// result always passes validator.IsPlainTableName: characters outside
+// [a-zA-Z0-9_] become underscores, names that do not start with a letter get
+// an idx_ prefix and long names are cut to the length Postgres keeps. It is
+// lower-cased because tables created before names were quoted had theirs
+// folded by Postgres.
+func FormatTableName(name string) string {
+	if !validator.IsPlainTableName(name) {
+		var formatted strings.Builder
+		for _, c := range name {
+			if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' {
+				formatted.WriteRune(c)
+			} else {
+				formatted.WriteByte('_')
+			}
+		}
+
+		name = formatted.String()
+		if name == "" || !((name[0] >= 'a' && name[0] <= 'z') || (name[0] >= 'A' && name[0] <= 'Z')) {
+			name = "idx_" + name
+		}
+		if len(name) > maxTableNameLength {
+			name = name[:maxTableNameLength]
 		}
 	}
 
-	if len(formattedName) == 0 || !((formattedName[0] >= 'a' && formattedName[0] <= 'z') || (formattedName[0] >= 'A' && formattedName[0] <= 'Z')) {
-		formattedName = "idx_" + formattedName
+	return strings.ToLower(name)
+}
+
+// tableName is FormatTableName for names that may already have been quoted
+// by QuoteTableName
+func tableName(name string) string {
+	if unquoted, ok := strings.CutPrefix(name, `"`); ok {
+		name = strings.TrimSuffix(unquoted, `"`)
 	}
+	return FormatTableName(name)
+}
 
-	return formattedName
+// QuoteTableName returns a target table as a quoted identifier, safe to
+// interpolate into SQL even when the name is a reserved word such as order
+func QuoteTableName(name string) string {
+	return pgx.Identifier{tableName(name)}.Sanitize()
+}
+
+// indexName returns the quoted name of the index on column of a table
+func indexName(table, column string) string {
+	return pgx.Identifier{tableName(table) + "_" + column + "_idx"}.Sanitize()
+}
+
+// SetTableOwner transfers ownership of an indexer's target table, and of the
+// companion tables created next to it, to role. Tables that do not exist are
+// left untouched.
+func SetTableOwner(ctx context.Context, conn *pgx.Conn, targetTable string, role string) error {
+	targetTable = tableName(targetTable)
+
+	tables := []string{targetTable}
+	for _, suffix := range validator.MetadataTableSuffixes {
+		tables = append(tables, tableName(targetTable+suffix))
+	}
+
+	for _, table := range tables {
+		exists, err := checkTableExists(ctx, conn, table)
+		if err != nil {
+			return fmt.Errorf("failed to check if table exists: %w", err)
+		}
+		if !exists {
+			continue
+		}
+
+		_, err = conn.Exec(ctx, fmt.Sprintf("ALTER TABLE %s OWNER TO %s",
+			QuoteTableName(table), pgx.Identifier{role}.Sanitize()))
+		if err != nil {
+			return fmt.Errorf("failed to set owner of %s to %s: %w", table, role, err)
+		}
+	}
+
+	return nil
+}
+
+// CopyTableRows copies the rows of one target table into another on the same
+// database, matching columns by name and leaving out the serial id. Rows that
+// collide with an existing row on a unique key are skipped, so copying again
+// after a partial or complete run adds only what is missing. It returns how
+// many rows were inserted; a missing source table copies nothing.
+func CopyTableRows(ctx context.Context, conn *pgx.Conn, from, to string) (int64, error) {
+	fromName, toName := tableName(from), tableName(to)
+
+	exists, err := checkTableExists(ctx, conn, fromName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check if table exists: %w", err)
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	rows, err := conn.Query(ctx, `
+		SELECT src.column_name
+		FROM information_schema.columns src
+		JOIN information_schema.columns dst
+			ON dst.table_schema = src.table_schema AND dst.column_name = src.column_name
+		WHERE src.table_schema = 'public'
+		AND src.table_name = $1
+		AND dst.table_name = $2
+		AND src.column_name <> 'id'
+		ORDER BY src.ordinal_position
+	`, fromName, toName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list columns of %s: %w", fromName, err)
+	}
+	columns, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return 0, fmt.Errorf("failed to list columns of %s: %w", fromName, err)
+	}
+	if len(columns) == 0 {
+		return 0, fmt.Errorf("tables %s and %s have no columns in common", fromName, toName)
+	}
+
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = pgx.Identifier{column}.Sanitize()
+	}
+	columnList := strings.Join(quoted, ", ")
+
+	tag, err := conn.Exec(ctx, fmt.Sprintf(
+		"INSERT INTO %s (%s) SELECT %s FROM %s ON CONFLICT DO NOTHING",
+		QuoteTableName(toName), columnList, columnList, QuoteTableName(fromName)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy rows from %s to %s: %w", fromName, toName, err)
+	}
+
+	return tag.RowsAffected(), nil
 }
 
 func checkTableExists(ctx context.Context, conn *pgx.Conn, tableName string) (bool, error) {
@@ -79,6 +227,25 @@ func checkTableExists(ctx context.Context, conn *pgx.Conn, tableName string) (bo
 	return exists, err
 }
 
+// nullableText maps an absent optional value to NULL. Optional columns never
+// hold "", so queries only have to deal with NULL.
+func nullableText(s string) pgtype.Text {
+	return pgtype.Text{String: s, Valid: s != ""}
+}
+
+// clearEmptyText rewrites "" to NULL in optional columns of tables written
+// before empty strings were stored as NULL
+func clearEmptyText(ctx context.Context, conn *pgx.Conn, tableName string, columns ...string) error {
+	for _, column := range columns {
+		_, err := conn.Exec(ctx, fmt.Sprintf("UPDATE %s SET %s = NULL WHERE %s = ''",
+			tableName, column, column))
+		if err != nil {
+			return fmt.Errorf("failed to clear empty %s values in %s: %w", column, tableName, err)
+		}
+	}
+	return nil
+}
+
 func executeWithRetry(attempts int, fn func() error) error {
 	var err error
 	for i := 0; i < attempts; i++ {
@@ -0,0 +1,96 @@
+package indexer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rishavmehra/indexer/pkg/validator"
+)
+
+func TestQuoteTableName(t *testing.T) {
+	tests := []struct {
+		name  string
+		table string
+		want  string
+	}{
+		{name: "plain", table: "sales", want: `"sales"`},
+		{name: "reserved word", table: "order", want: `"order"`},
+		{name: "another reserved word", table: "user", want: `"user"`},
+		{name: "already quoted", table: `"order"`, want: `"order"`},
+		{name: "upper case", table: "Sales", want: `"sales"`},
+		{name: "quote in name", table: `sales"; DROP TABLE users; --`, want: `"sales___drop_table_users____"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := QuoteTableName(tt.table); got != tt.want {
+				t.Errorf("QuoteTableName(%q) = %s, want %s", tt.table, got, tt.want)
+			}
+		})
+	}
+
+	if got := indexName("order", "slot"); got != `"order_slot_idx"` {
+		t.Errorf("indexName(order, slot) = %s, want a quoted index name", got)
+	}
+}
+
+func TestReservedWordTargetTables(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+
+	for _, table := range []string{"order", "user"} {
+		t.Run(table, func(t *testing.T) {
+			if _, err := pool.Exec(ctx, "DROP TABLE IF EXISTS "+QuoteTableName(table)); err != nil {
+				t.Fatalf("drop %s: %v", table, err)
+			}
+			t.Cleanup(func() {
+				pool.Exec(context.Background(), "DROP TABLE IF EXISTS "+QuoteTableName(table))
+			})
+
+			idx := newTestPriceIndexer(t, `{"collection": "collection"}`)
+			initializeTable(t, pool, idx, table)
+			if err := idx.InsertPriceEvents(ctx, pool, table, listingEvents(table, 3)); err != nil {
+				t.Fatalf("InsertPriceEvents: %v", err)
+			}
+
+			var rows int
+			if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+QuoteTableName(table)).Scan(&rows); err != nil {
+				t.Fatalf("count rows: %v", err)
+			}
+			if rows != 3 {
+				t.Errorf("got %d rows in %s, want 3", rows, table)
+			}
+		})
+	}
+}
+
+func TestFormatTableName(t *testing.T) {
+	tests := []struct {
+		name  string
+		table string
+		want  string
+	}{
+		{name: "valid name", table: "nft_sales", want: "nft_sales"},
+		{name: "upper case is folded", table: "NFT_Sales", want: "nft_sales"},
+		{name: "leading digit", table: "1sales", want: "idx_1sales"},
+		{name: "leading underscore", table: "_sales", want: "idx__sales"},
+		{name: "empty", table: "", want: "idx_"},
+		{name: "dash and space", table: "nft sales-2024", want: "nft_sales_2024"},
+		{name: "only special characters", table: "$%!", want: "idx____"},
+		{name: "non-ASCII", table: "ventes_été", want: "ventes__t_"},
+		{name: "too long", table: strings.Repeat("a", 70), want: strings.Repeat("a", 63)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FormatTableName(tt.table)
+			if got != tt.want {
+				t.Errorf("FormatTableName(%q) = %q, want %q", tt.table, got, tt.want)
+			}
+			if !validator.IsPlainTableName(got) {
+				t.Errorf("FormatTableName(%q) = %q, which IsPlainTableName rejects", tt.table, got)
+			}
+		})
+	}
+}
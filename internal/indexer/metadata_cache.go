@@ -0,0 +1,259 @@
+package indexer
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultMetadataCacheSize caps the number of tokens kept in the metadata cache
+	DefaultMetadataCacheSize = 10000
+	// DefaultMetadataCacheTTL is how long fetched metadata is considered fresh
+	DefaultMetadataCacheTTL = 24 * time.Hour
+	// DefaultMetadataCacheNegativeTTL is how long a token without metadata is
+	// remembered before it is looked up again
+	DefaultMetadataCacheNegativeTTL = 10 * time.Minute
+	// DefaultMetadataCacheSweepInterval is how often expired entries are evicted
+	DefaultMetadataCacheSweepInterval = 10 * time.Minute
+)
+
+type TokenMetadata struct {
+	Symbol    string
+	Name      string
+	Decimals  int
+	FetchedAt time.Time
+}
+
+// TokenMetadataCache is a size-bounded LRU of token metadata. It also
+// remembers, for a shorter negativeTTL, tokens that have no metadata at all.
+// Expired entries are treated as misses on read and removed by a background
+// sweeper.
+type TokenMetadataCache struct {
+	mu          sync.Mutex
+	entries     map[string]*list.Element
+	order       *list.List // front is most recently used
+	maxSize     int
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	hits      uint64
+	misses    uint64
+	evictions uint64
+	expired   uint64
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+type metadataCacheEntry struct {
+	key      string
+	metadata TokenMetadata
+	// notFound marks a token the DAS API has no metadata for
+	notFound bool
+}
+
+// MetadataCacheStats is a point-in-time view of cache usage
+type MetadataCacheStats struct {
+	Size      int     `json:"size"`
+	MaxSize   int     `json:"maxSize"`
+	Hits      uint64  `json:"hits"`
+	Misses    uint64  `json:"misses"`
+	HitRate   float64 `json:"hitRate"`
+	Evictions uint64  `json:"evictions"`
+	Expired   uint64  `json:"expired"`
+}
+
+func NewTokenMetadataCache() *TokenMetadataCache {
+	return NewTokenMetadataCacheWithSize(DefaultMetadataCacheSize, DefaultMetadataCacheTTL, DefaultMetadataCacheNegativeTTL)
+}
+
+// NewTokenMetadataCacheWithSize creates a cache holding at most maxSize entries
+// that stay fresh for ttl, or for negativeTTL when the token has no metadata
+func NewTokenMetadataCacheWithSize(maxSize int, ttl, negativeTTL time.Duration) *TokenMetadataCache {
+	if maxSize <= 0 {
+		maxSize = DefaultMetadataCacheSize
+	}
+	if ttl <= 0 {
+		ttl = DefaultMetadataCacheTTL
+	}
+	if negativeTTL <= 0 {
+		negativeTTL = DefaultMetadataCacheNegativeTTL
+	}
+
+	return &TokenMetadataCache{
+		entries:     make(map[string]*list.Element),
+		order:       list.New(),
+		maxSize:     maxSize,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		stop:        make(chan struct{}),
+	}
+}
+
+func (c *TokenMetadataCache) Get(tokenAddress string) (TokenMetadata, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, found := c.entries[strings.ToLower(tokenAddress)]
+	if !found {
+		c.misses++
+		return TokenMetadata{}, false
+	}
+
+	entry := elem.Value.(*metadataCacheEntry)
+	if entry.notFound || c.expiredAt(entry, time.Now()) {
+		c.misses++
+		return entry.metadata, false
+	}
+
+	c.order.MoveToFront(elem)
+	c.hits++
+	return entry.metadata, true
+}
+
+// NotFound reports whether the token was recently found to have no metadata
+func (c *TokenMetadataCache) NotFound(tokenAddress string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, found := c.entries[strings.ToLower(tokenAddress)]
+	if !found {
+		return false
+	}
+
+	entry := elem.Value.(*metadataCacheEntry)
+	if !entry.notFound || c.expiredAt(entry, time.Now()) {
+		return false
+	}
+
+	c.order.MoveToFront(elem)
+	c.hits++
+	return true
+}
+
+func (c *TokenMetadataCache) Set(tokenAddress string, metadata TokenMetadata) {
+	c.set(tokenAddress, metadata, false)
+}
+
+// SetNotFound remembers that the token has no metadata, so it isn't looked up
+// again until the negative TTL passes
+func (c *TokenMetadataCache) SetNotFound(tokenAddress string) {
+	c.set(tokenAddress, TokenMetadata{}, true)
+}
+
+func (c *TokenMetadataCache) set(tokenAddress string, metadata TokenMetadata, notFound bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := strings.ToLower(tokenAddress)
+	metadata.FetchedAt = time.Now()
+
+	if elem, found := c.entries[key]; found {
+		entry := elem.Value.(*metadataCacheEntry)
+		entry.metadata = metadata
+		entry.notFound = notFound
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&metadataCacheEntry{key: key, metadata: metadata, notFound: notFound})
+
+	for c.order.Len() > c.maxSize {
+		c.removeElement(c.order.Back())
+		c.evictions++
+	}
+}
+
+// Len returns the number of cached entries, including expired ones not yet swept
+func (c *TokenMetadataCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Stats returns the current size and hit/miss counters
+func (c *TokenMetadataCache) Stats() MetadataCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := MetadataCacheStats{
+		Size:      c.order.Len(),
+		MaxSize:   c.maxSize,
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+		Expired:   c.expired,
+	}
+	if total := c.hits + c.misses; total > 0 {
+		stats.HitRate = float64(c.hits) / float64(total)
+	}
+	return stats
+}
+
+// Sweep removes every expired entry and returns how many were removed
+func (c *TokenMetadataCache) Sweep() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	now := time.Now()
+	for elem := c.order.Back(); elem != nil; {
+		prev := elem.Prev()
+		if c.expiredAt(elem.Value.(*metadataCacheEntry), now) {
+			c.removeElement(elem)
+			removed++
+		}
+		elem = prev
+	}
+	c.expired += uint64(removed)
+	return removed
+}
+
+// StartSweeper evicts expired entries every interval until Close is called
+func (c *TokenMetadataCache) StartSweeper(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultMetadataCacheSweepInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if removed := c.Sweep(); removed > 0 {
+					log.Debug().Int("removed", removed).Msg("Swept expired token metadata")
+				}
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the background sweeper
+func (c *TokenMetadataCache) Close() {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
+}
+
+// expiredAt reports whether entry is past its TTL at now
+func (c *TokenMetadataCache) expiredAt(entry *metadataCacheEntry, now time.Time) bool {
+	ttl := c.ttl
+	if entry.notFound {
+		ttl = c.negativeTTL
+	}
+	return now.Sub(entry.metadata.FetchedAt) > ttl
+}
+
+func (c *TokenMetadataCache) removeElement(elem *list.Element) {
+	entry := elem.Value.(*metadataCacheEntry)
+	delete(c.entries, entry.key)
+	c.order.Remove(elem)
+}
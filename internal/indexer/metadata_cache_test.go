@@ -0,0 +1,80 @@
+package indexer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenMetadataCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewTokenMetadataCacheWithSize(2, time.Hour, time.Hour)
+
+	cache.Set("a", TokenMetadata{Symbol: "A"})
+	cache.Set("b", TokenMetadata{Symbol: "B"})
+	// Reading a makes b the least recently used
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("a missing before the cache was full")
+	}
+	cache.Set("c", TokenMetadata{Symbol: "C"})
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("b survived although it was the least recently used")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.Get(key); !ok {
+			t.Errorf("%s was evicted, want it kept", key)
+		}
+	}
+	if stats := cache.Stats(); stats.Size != 2 || stats.Evictions != 1 {
+		t.Errorf("stats = %+v, want size 2 and one eviction", stats)
+	}
+}
+
+func TestTokenMetadataCacheKeysAreCaseInsensitive(t *testing.T) {
+	cache := NewTokenMetadataCacheWithSize(10, time.Hour, time.Hour)
+
+	cache.Set("MintAddress", TokenMetadata{Symbol: "M"})
+	if got, ok := cache.Get("mintaddress"); !ok || got.Symbol != "M" {
+		t.Errorf("Get(mintaddress) = %+v, %v; want the entry set as MintAddress", got, ok)
+	}
+}
+
+func TestTokenMetadataCacheTreatsExpiredEntriesAsMisses(t *testing.T) {
+	cache := NewTokenMetadataCacheWithSize(10, 20*time.Millisecond, time.Hour)
+
+	cache.Set("a", TokenMetadata{Symbol: "A"})
+	time.Sleep(40 * time.Millisecond)
+
+	if _, ok := cache.Get("a"); ok {
+		t.Error("Get returned an entry past its TTL")
+	}
+}
+
+func TestTokenMetadataCacheSweeperEvictsExpiredEntries(t *testing.T) {
+	cache := NewTokenMetadataCacheWithSize(10, 20*time.Millisecond, time.Hour)
+	defer cache.Close()
+
+	cache.Set("a", TokenMetadata{Symbol: "A"})
+	cache.Set("b", TokenMetadata{Symbol: "B"})
+	cache.StartSweeper(10 * time.Millisecond)
+
+	deadline := time.Now().Add(time.Second)
+	for cache.Len() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("sweeper left %d expired entries", cache.Len())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if stats := cache.Stats(); stats.Expired != 2 {
+		t.Errorf("expired = %d, want 2", stats.Expired)
+	}
+}
+
+func TestTokenMetadataCacheSweepKeepsFreshEntries(t *testing.T) {
+	cache := NewTokenMetadataCacheWithSize(10, time.Hour, time.Hour)
+
+	cache.Set("a", TokenMetadata{Symbol: "A"})
+	if removed := cache.Sweep(); removed != 0 {
+		t.Errorf("Sweep removed %d fresh entries", removed)
+	}
+}
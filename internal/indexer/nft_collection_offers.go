@@ -0,0 +1,453 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+
+	"github.com/rishavmehra/indexer/pkg/validator"
+)
+
+// Collection offer statuses
+const (
+	CollectionOfferOpen      = "open"
+	CollectionOfferFilled    = "filled"
+	CollectionOfferCancelled = "cancelled"
+)
+
+// collectionOffersTable is the companion table holding collection-wide offers
+func collectionOffersTable(targetTable string) string {
+	return QuoteTableName(tableName(targetTable) + "_collection_offers")
+}
+
+// collectionOfferFillsTable is the companion table holding fills of collection offers
+func collectionOfferFillsTable(targetTable string) string {
+	return QuoteTableName(tableName(targetTable) + "_collection_offer_fills")
+}
+
+// Event types that place, cancel and fill collection-wide offers. A plain
+// NFT_SALE can fill one too, see isCollectionOfferFillEvent.
+var (
+	collectionOfferEventTypes       = []string{"NFT_GLOBAL_BID", "NFT_COLLECTION_OFFER"}
+	collectionOfferCancelEventTypes = []string{"NFT_GLOBAL_BID_CANCELLED", "NFT_COLLECTION_OFFER_CANCELLED"}
+	collectionOfferFillEventTypes   = []string{"NFT_GLOBAL_BID_FILLED", "NFT_COLLECTION_OFFER_FILLED", "NFT_SALE"}
+)
+
+// isCollectionOfferFillEvent reports whether an event fills a collection-wide
+// offer, either through a dedicated type or a sale made against a global bid
+func isCollectionOfferFillEvent(eventType string, eventData map[string]interface{}) bool {
+	switch eventType {
+	case "NFT_GLOBAL_BID_FILLED", "NFT_COLLECTION_OFFER_FILLED":
+		return true
+	case "NFT_SALE":
+		saleType, _ := offerData(eventData)["saleType"].(string)
+		switch strings.ToUpper(saleType) {
+		case "GLOBAL_BID", "GLOBAL_OFFER", "COLLECTION_OFFER":
+			return true
+		}
+	}
+	return false
+}
+
+// initializeCollectionOfferTables creates the offer and fill tables when missing
+func (i *NFTBidIndexer) initializeCollectionOfferTables(ctx context.Context, conn *pgx.Conn, targetTable string) error {
+	offersTable := collectionOffersTable(targetTable)
+	fillsTable := collectionOfferFillsTable(targetTable)
+
+	_, err := conn.Exec(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id SERIAL PRIMARY KEY,
+			signature TEXT UNIQUE NOT NULL,
+			slot BIGINT NOT NULL,
+			block_time TIMESTAMP WITH TIME ZONE NOT NULL,
+			collection TEXT NOT NULL,
+			marketplace TEXT NOT NULL,
+			bidder TEXT NOT NULL,
+			offer_amount NUMERIC NOT NULL,
+			offer_currency TEXT NOT NULL DEFAULT 'SOL',
+			quantity INTEGER NOT NULL DEFAULT 1,
+			filled_count INTEGER NOT NULL DEFAULT 0,
+			status TEXT NOT NULL DEFAULT 'open',
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)
+	`, offersTable))
+	if err != nil {
+		return fmt.Errorf("failed to create collection offers table: %w", err)
+	}
+
+	_, err = conn.Exec(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id SERIAL PRIMARY KEY,
+			offer_id INTEGER REFERENCES %s(id) ON DELETE SET NULL,
+			signature TEXT UNIQUE NOT NULL,
+			slot BIGINT NOT NULL,
+			block_time TIMESTAMP WITH TIME ZONE NOT NULL,
+			collection TEXT NOT NULL,
+			marketplace TEXT NOT NULL,
+			nft_mint TEXT NOT NULL,
+			bidder TEXT NOT NULL,
+			seller TEXT,
+			fill_amount NUMERIC,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)
+	`, fillsTable, offersTable))
+	if err != nil {
+		return fmt.Errorf("failed to create collection offer fills table: %w", err)
+	}
+
+	_, err = conn.Exec(ctx, fmt.Sprintf(`
+		CREATE INDEX IF NOT EXISTS %s ON %s(collection, bidder, status);
+		CREATE INDEX IF NOT EXISTS %s ON %s(offer_id);
+		CREATE INDEX IF NOT EXISTS %s ON %s(nft_mint);
+	`,
+		indexName(offersTable, "bidder"), offersTable,
+		indexName(fillsTable, "offer"), fillsTable,
+		indexName(fillsTable, "mint"), fillsTable,
+	))
+	if err != nil {
+		return fmt.Errorf("failed to create collection offer indices: %w", err)
+	}
+
+	log.Info().
+		Str("offersTable", offersTable).
+		Str("fillsTable", fillsTable).
+		Msg("Collection offer tables ready")
+
+	return nil
+}
+
+// collectionOffer holds the fields shared by offer, cancel and fill events
+type collectionOffer struct {
+	Collection  string
+	Marketplace string
+	Bidder      string
+	Seller      string
+	Mint        string
+	Amount      float64
+	Currency    string
+	Quantity    int
+	BlockTime   time.Time
+}
+
+// parseCollectionOffer pulls offer fields out of a Helius event, falling back
+// to the configured collection since the webhook only watches that address
+func (i *NFTBidIndexer) parseCollectionOffer(ctx context.Context, eventData map[string]interface{}) collectionOffer {
+	data := offerData(eventData)
+
+	offer := collectionOffer{
+		Collection: i.Collection,
+		Currency:   "SOL",
+		Quantity:   1,
+		BlockTime:  eventBlockTime(ctx, eventData),
+	}
+
+	if collection, ok := data["collection"].(string); ok && collection != "" {
+		offer.Collection = collection
+	} else if collectionData, ok := data["collection"].(map[string]interface{}); ok {
+		if addr, ok := collectionData["address"].(string); ok && addr != "" {
+			offer.Collection = addr
+		}
+	}
+
+	if mp, ok := data["marketplace"].(string); ok && mp != "" {
+		offer.Marketplace = mp
+	} else if source, ok := eventData["source"].(string); ok && source != "" {
+		offer.Marketplace = source
+	} else if source, ok := data["source"].(string); ok && source != "" {
+		offer.Marketplace = source
+	}
+
+	if bidder, ok := data["bidder"].(string); ok && bidder != "" {
+		offer.Bidder = bidder
+	} else if buyer, ok := data["buyer"].(string); ok {
+		offer.Bidder = buyer
+	}
+
+	if seller, ok := data["seller"].(string); ok {
+		offer.Seller = seller
+	}
+
+	if mint, ok := data["mint"].(string); ok && mint != "" {
+		offer.Mint = mint
+	} else if nfts, ok := data["nfts"].([]interface{}); ok && len(nfts) > 0 {
+		if nft, ok := nfts[0].(map[string]interface{}); ok {
+			offer.Mint, _ = nft["mint"].(string)
+		}
+	}
+
+	offer.Amount = numberField(data, "amount")
+	if offer.Amount <= 0 {
+		offer.Amount = numberField(data, "price")
+	}
+
+	if curr, ok := data["currency"].(string); ok && curr != "" {
+		offer.Currency = curr
+	}
+
+	if quantity := numberField(data, "quantity"); quantity >= 1 {
+		offer.Quantity = int(quantity)
+	}
+
+	if ts := numberField(data, "timestamp"); ts > 0 {
+		offer.BlockTime = time.Unix(int64(ts), 0).UTC()
+	}
+
+	if offer.Marketplace == "" {
+		offer.Marketplace = "UNKNOWN"
+	}
+
+	return offer
+}
+
+// marketplaceAllowed applies the configured marketplace filter
+func (i *NFTBidIndexer) marketplaceAllowed(marketplace string) bool {
+	if len(i.Marketplaces) == 0 {
+		return true
+	}
+	for _, m := range i.Marketplaces {
+		if validator.CanonicalMarketplace(marketplace) == m {
+			return true
+		}
+	}
+	return false
+}
+
+// processCollectionOffer records a newly placed collection-wide offer
+func (i *NFTBidIndexer) processCollectionOffer(ctx context.Context, pool *pgxpool.Pool, targetTable string, eventData map[string]interface{}, slot int64, signature string) error {
+	offer := i.parseCollectionOffer(ctx, eventData)
+
+	if !i.marketplaceAllowed(offer.Marketplace) {
+		log.Debug().
+			Str("foundMarketplace", offer.Marketplace).
+			Strs("configuredMarketplaces", i.Marketplaces).
+			Msg("Skipping collection offer - marketplace not in configured list")
+		return nil
+	}
+
+	if offer.Bidder == "" || offer.Amount <= 0 {
+		log.Warn().
+			Str("signature", signature).
+			Str("bidder", offer.Bidder).
+			Float64("amount", offer.Amount).
+			Msg("Skipping collection offer - missing essential data")
+		return nil
+	}
+
+	tx, err := beginTx(ctx, pool, i.acquireTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	written, err := tx.Exec(ctx, fmt.Sprintf(`
+		INSERT INTO %s (
+			signature, slot, block_time, collection, marketplace,
+			bidder, offer_amount, offer_currency, quantity, status
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+		) ON CONFLICT (signature)
+		DO UPDATE SET
+			collection = EXCLUDED.collection,
+			marketplace = EXCLUDED.marketplace,
+			bidder = EXCLUDED.bidder,
+			offer_amount = EXCLUDED.offer_amount,
+			offer_currency = EXCLUDED.offer_currency,
+			quantity = EXCLUDED.quantity,
+			slot = EXCLUDED.slot,
+			block_time = EXCLUDED.block_time,
+			updated_at = NOW()
+	`, collectionOffersTable(targetTable)),
+		signature, slot, offer.BlockTime, offer.Collection, offer.Marketplace,
+		offer.Bidder, offer.Amount, offer.Currency, offer.Quantity, CollectionOfferOpen,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert collection offer: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	recordRows(ctx, written.RowsAffected())
+
+	log.Info().
+		Str("signature", signature).
+		Str("collection", offer.Collection).
+		Str("marketplace", offer.Marketplace).
+		Str("bidder", offer.Bidder).
+		Float64("amount", offer.Amount).
+		Int("quantity", offer.Quantity).
+		Msg("Stored collection offer")
+
+	return nil
+}
+
+// processCollectionOfferCancel marks the bidder's open offers on the collection as cancelled
+func (i *NFTBidIndexer) processCollectionOfferCancel(ctx context.Context, pool *pgxpool.Pool, targetTable string, eventData map[string]interface{}, slot int64, signature string) error {
+	offer := i.parseCollectionOffer(ctx, eventData)
+
+	if offer.Bidder == "" {
+		log.Warn().
+			Str("signature", signature).
+			Msg("Skipping collection offer cancellation - missing bidder")
+		return nil
+	}
+
+	tx, err := beginTx(ctx, pool, i.acquireTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, fmt.Sprintf(`
+		UPDATE %s
+		SET status = $1, updated_at = NOW()
+		WHERE collection = $2 AND bidder = $3 AND status = $4
+	`, collectionOffersTable(targetTable)),
+		CollectionOfferCancelled, offer.Collection, offer.Bidder, CollectionOfferOpen,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to cancel collection offer: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	recordRows(ctx, result.RowsAffected())
+
+	log.Info().
+		Str("signature", signature).
+		Str("collection", offer.Collection).
+		Str("bidder", offer.Bidder).
+		Int64("rowsAffected", result.RowsAffected()).
+		Msg("Processed collection offer cancellation")
+
+	return nil
+}
+
+// processCollectionOfferFill records which mint filled a collection offer and
+// links it to the bidder's oldest open offer on the collection. A fill with no
+// matching offer, e.g. one placed before indexing started, is kept unlinked.
+func (i *NFTBidIndexer) processCollectionOfferFill(ctx context.Context, pool *pgxpool.Pool, targetTable string, eventData map[string]interface{}, slot int64, signature string) error {
+	fill := i.parseCollectionOffer(ctx, eventData)
+
+	if !i.marketplaceAllowed(fill.Marketplace) {
+		log.Debug().
+			Str("foundMarketplace", fill.Marketplace).
+			Strs("configuredMarketplaces", i.Marketplaces).
+			Msg("Skipping collection offer fill - marketplace not in configured list")
+		return nil
+	}
+
+	if fill.Bidder == "" || fill.Mint == "" {
+		log.Warn().
+			Str("signature", signature).
+			Str("bidder", fill.Bidder).
+			Str("mint", fill.Mint).
+			Msg("Skipping collection offer fill - missing essential data")
+		return nil
+	}
+
+	offersTable := collectionOffersTable(targetTable)
+
+	tx, err := beginTx(ctx, pool, i.acquireTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var offerID *int64
+	var matchedID int64
+	err = tx.QueryRow(ctx, fmt.Sprintf(`
+		SELECT id FROM %s
+		WHERE collection = $1 AND bidder = $2 AND status = $3
+		ORDER BY (offer_amount = $4) DESC, block_time ASC
+		LIMIT 1
+		FOR UPDATE
+	`, offersTable),
+		fill.Collection, fill.Bidder, CollectionOfferOpen, fill.Amount,
+	).Scan(&matchedID)
+	switch {
+	case err == nil:
+		offerID = &matchedID
+	case errors.Is(err, pgx.ErrNoRows):
+		log.Info().
+			Str("signature", signature).
+			Str("bidder", fill.Bidder).
+			Msg("No open collection offer found for fill, storing it unlinked")
+	default:
+		return fmt.Errorf("failed to find collection offer: %w", err)
+	}
+
+	result, err := tx.Exec(ctx, fmt.Sprintf(`
+		INSERT INTO %s (
+			offer_id, signature, slot, block_time, collection, marketplace,
+			nft_mint, bidder, seller, fill_amount
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+		) ON CONFLICT (signature) DO NOTHING
+	`, collectionOfferFillsTable(targetTable)),
+		offerID, signature, slot, fill.BlockTime, fill.Collection, fill.Marketplace,
+		fill.Mint, fill.Bidder, nullableText(fill.Seller), fill.Amount,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert collection offer fill: %w", err)
+	}
+
+	// Only count the fill against the offer the first time it is seen
+	if offerID != nil && result.RowsAffected() > 0 {
+		_, err = tx.Exec(ctx, fmt.Sprintf(`
+			UPDATE %s
+			SET filled_count = filled_count + 1,
+				status = CASE WHEN filled_count + 1 >= quantity THEN $2 ELSE status END,
+				updated_at = NOW()
+			WHERE id = $1
+		`, offersTable), *offerID, CollectionOfferFilled)
+		if err != nil {
+			return fmt.Errorf("failed to update collection offer: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	recordRows(ctx, result.RowsAffected())
+
+	log.Info().
+		Str("signature", signature).
+		Str("collection", fill.Collection).
+		Str("mint", fill.Mint).
+		Str("bidder", fill.Bidder).
+		Bool("linked", offerID != nil).
+		Msg("Stored collection offer fill")
+
+	return nil
+}
+
+// offerData returns the nested data object of an event, or the event itself
+func offerData(eventData map[string]interface{}) map[string]interface{} {
+	if data, ok := eventData["data"].(map[string]interface{}); ok {
+		return data
+	}
+	return eventData
+}
+
+// numberField reads a numeric field that may be encoded as a JSON number or string
+func numberField(data map[string]interface{}, key string) float64 {
+	switch v := data[key].(type) {
+	case float64:
+		return v
+	case string:
+		if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+			return parsed
+		}
+	}
+	return 0
+}
@@ -0,0 +1,161 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+func newTestBidIndexer(tb testing.TB, params string) *NFTBidIndexer {
+	tb.Helper()
+
+	idx, err := NewNFTBidIndexer("test", json.RawMessage(params))
+	if err != nil {
+		tb.Fatalf("NewNFTBidIndexer: %v", err)
+	}
+	return idx.(*NFTBidIndexer)
+}
+
+// enhancedPayload wraps enhanced transaction details in a webhook payload
+func enhancedPayload(tb testing.TB, signature string, slot int64, details map[string]interface{}) models.HeliusWebhookPayload {
+	tb.Helper()
+
+	raw, err := json.Marshal(details)
+	if err != nil {
+		tb.Fatalf("marshal details: %v", err)
+	}
+	return models.HeliusWebhookPayload{
+		Slot: slot,
+		Transaction: models.HeliusTransaction{
+			Signatures:      []string{signature},
+			EnhancedDetails: raw,
+		},
+	}
+}
+
+func globalBidPayload(tb testing.TB) models.HeliusWebhookPayload {
+	return enhancedPayload(tb, "global-bid", 1, map[string]interface{}{
+		"type":      "NFT_GLOBAL_BID",
+		"source":    "TENSOR",
+		"timestamp": float64(1700000000),
+		"data": map[string]interface{}{
+			"bidder":   "bidder",
+			"amount":   float64(2.5),
+			"quantity": float64(1),
+		},
+	})
+}
+
+func globalBidFilledPayload(tb testing.TB) models.HeliusWebhookPayload {
+	return enhancedPayload(tb, "global-bid-filled", 2, map[string]interface{}{
+		"type":      "NFT_GLOBAL_BID_FILLED",
+		"source":    "TENSOR",
+		"timestamp": float64(1700000100),
+		"data": map[string]interface{}{
+			"bidder": "bidder",
+			"seller": "seller",
+			"mint":   "filled-mint",
+			"amount": float64(2.5),
+		},
+	})
+}
+
+func TestCollectionOfferHandlersAreGatedByParam(t *testing.T) {
+	off := newTestBidIndexer(t, `{"collection": "collection"}`)
+	if _, ok := off.eventHandlers(nil, "t", 1, "sig")["NFT_GLOBAL_BID"]; ok {
+		t.Error("collection offer handler registered without collectionOffers")
+	}
+
+	on := newTestBidIndexer(t, `{"collection": "collection", "collectionOffers": true}`)
+	handlers := on.eventHandlers(nil, "t", 1, "sig")
+	for _, eventType := range []string{"NFT_GLOBAL_BID", "NFT_GLOBAL_BID_CANCELLED", "NFT_GLOBAL_BID_FILLED"} {
+		if _, ok := handlers[eventType]; !ok {
+			t.Errorf("no handler for %s with collectionOffers", eventType)
+		}
+	}
+}
+
+func TestIsCollectionOfferFillEvent(t *testing.T) {
+	tests := []struct {
+		eventType string
+		event     map[string]interface{}
+		want      bool
+	}{
+		{eventType: "NFT_GLOBAL_BID_FILLED", want: true},
+		{eventType: "NFT_COLLECTION_OFFER_FILLED", want: true},
+		{eventType: "NFT_SALE", event: map[string]interface{}{"saleType": "GLOBAL_BID"}, want: true},
+		{eventType: "NFT_SALE", event: map[string]interface{}{"data": map[string]interface{}{"saleType": "collection_offer"}}, want: true},
+		{eventType: "NFT_SALE", event: map[string]interface{}{"saleType": "INSTANT_SALE"}, want: false},
+		{eventType: "NFT_BID", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := isCollectionOfferFillEvent(tt.eventType, tt.event); got != tt.want {
+			t.Errorf("isCollectionOfferFillEvent(%s, %v) = %v, want %v", tt.eventType, tt.event, got, tt.want)
+		}
+	}
+}
+
+func TestParseCollectionOfferFallsBackToConfiguredCollection(t *testing.T) {
+	idx := newTestBidIndexer(t, `{"collection": "collection"}`)
+
+	offer := idx.parseCollectionOffer(context.Background(), map[string]interface{}{
+		"source": "TENSOR",
+		"data":   map[string]interface{}{"buyer": "bidder", "price": "1.25"},
+	})
+
+	if offer.Collection != "collection" || offer.Bidder != "bidder" || offer.Amount != 1.25 || offer.Marketplace != "TENSOR" {
+		t.Errorf("offer = %+v, want the configured collection, the buyer as bidder and the string price", offer)
+	}
+}
+
+func TestCollectionOfferCreatedThenFilled(t *testing.T) {
+	pool := testPool(t)
+	table := testTable(t, pool)
+	idx := newTestBidIndexer(t, `{"collection": "collection", "collectionOffers": true}`)
+	initializeTable(t, pool, idx, table)
+	ctx := context.Background()
+
+	if _, err := idx.ProcessPayload(ctx, pool, table, globalBidPayload(t)); err != nil {
+		t.Fatalf("process global bid: %v", err)
+	}
+
+	var offerID int
+	var status string
+	var amount float64
+	err := pool.QueryRow(ctx, "SELECT id, status, offer_amount FROM "+collectionOffersTable(table)+" WHERE signature = 'global-bid'").
+		Scan(&offerID, &status, &amount)
+	if err != nil {
+		t.Fatalf("read offer: %v", err)
+	}
+	if status != CollectionOfferOpen || amount != 2.5 {
+		t.Errorf("offer status %q amount %v, want an open offer of 2.5", status, amount)
+	}
+
+	if _, err := idx.ProcessPayload(ctx, pool, table, globalBidFilledPayload(t)); err != nil {
+		t.Fatalf("process global bid fill: %v", err)
+	}
+
+	var linkedID int
+	var mint string
+	err = pool.QueryRow(ctx, "SELECT offer_id, nft_mint FROM "+collectionOfferFillsTable(table)+" WHERE signature = 'global-bid-filled'").
+		Scan(&linkedID, &mint)
+	if err != nil {
+		t.Fatalf("read fill: %v", err)
+	}
+	if linkedID != offerID || mint != "filled-mint" {
+		t.Errorf("fill linked to offer %d with mint %q, want offer %d and filled-mint", linkedID, mint, offerID)
+	}
+
+	var filled int
+	err = pool.QueryRow(ctx, "SELECT status, filled_count FROM "+collectionOffersTable(table)+" WHERE id = $1", offerID).
+		Scan(&status, &filled)
+	if err != nil {
+		t.Fatalf("read offer: %v", err)
+	}
+	if status != CollectionOfferFilled || filled != 1 {
+		t.Errorf("offer status %q filled %d after the fill, want filled once", status, filled)
+	}
+}
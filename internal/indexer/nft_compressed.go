@@ -0,0 +1,63 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// compressedEvent returns the first compressed NFT event of a transaction
+func compressedEvent(eventData map[string]interface{}) map[string]interface{} {
+	events, ok := eventData["events"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	compressed, ok := events["compressed"].([]interface{})
+	if !ok || len(compressed) == 0 {
+		return nil
+	}
+	event, _ := compressed[0].(map[string]interface{})
+	return event
+}
+
+// compressedAssetID returns the asset ID of a compressed NFT, which takes the
+// place of a mint address for cNFTs. It returns "" for regular NFTs.
+func compressedAssetID(eventData map[string]interface{}) string {
+	if compressed := compressedEvent(eventData); compressed != nil {
+		if assetID, ok := compressed["assetId"].(string); ok && assetID != "" {
+			return assetID
+		}
+	}
+
+	if assetID, ok := eventData["assetId"].(string); ok && assetID != "" {
+		return assetID
+	}
+	if data, ok := eventData["data"].(map[string]interface{}); ok {
+		if assetID, ok := data["assetId"].(string); ok && assetID != "" {
+			return assetID
+		}
+	}
+	return ""
+}
+
+// addAssetIDColumn adds the asset_id column, holding the asset ID of
+// compressed NFTs, to NFT tables created before cNFTs were recognized
+func addAssetIDColumn(ctx context.Context, conn *pgx.Conn, targetTable string) error {
+	_, err := conn.Exec(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS asset_id TEXT", QuoteTableName(targetTable)))
+	if err != nil {
+		return fmt.Errorf("failed to add asset_id column to %s: %w", targetTable, err)
+	}
+	return nil
+}
+
+// addMintResolvedColumn adds the mint_resolved column, which is false on
+// listings stored without a known mint, to NFT price tables created before it
+// existed
+func addMintResolvedColumn(ctx context.Context, conn *pgx.Conn, targetTable string) error {
+	_, err := conn.Exec(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS mint_resolved BOOLEAN NOT NULL DEFAULT TRUE", QuoteTableName(targetTable)))
+	if err != nil {
+		return fmt.Errorf("failed to add mint_resolved column to %s: %w", targetTable, err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package indexer
+
+import (
+	"context"
+	"testing"
+)
+
+const cnftAssetID = "6pKfzXcGNqDHyV3bdD7X6RuNHTuMBSUQ6rUbt3hsXXdD"
+
+// cnftListingEventData is a Magic Eden listing of a compressed NFT, which
+// Helius reports without a mint and with the asset ID under events.compressed
+func cnftListingEventData() map[string]interface{} {
+	return map[string]interface{}{
+		"type":      "NFT_LISTING",
+		"source":    "MAGIC_EDEN",
+		"timestamp": float64(1700000000),
+		"data": map[string]interface{}{
+			"marketplace": "MAGIC_EDEN",
+			"seller":      "seller",
+			"amount":      float64(3.2),
+		},
+		"events": map[string]interface{}{
+			"compressed": []interface{}{
+				map[string]interface{}{
+					"type":         "COMPRESSED_NFT_TRANSFER",
+					"treeId":       "GXTXbFwcbNdWbiCWzZc3J2XGofopnhN9b6jwTdFnoGaN",
+					"assetId":      cnftAssetID,
+					"leafIndex":    float64(88213),
+					"newLeafOwner": "escrow",
+					"oldLeafOwner": "seller",
+				},
+			},
+		},
+	}
+}
+
+func TestCompressedAssetID(t *testing.T) {
+	tests := []struct {
+		name      string
+		eventData map[string]interface{}
+		want      string
+	}{
+		{name: "compressed event", eventData: cnftListingEventData(), want: cnftAssetID},
+		{name: "top level", eventData: map[string]interface{}{"assetId": cnftAssetID}, want: cnftAssetID},
+		{name: "event data", eventData: map[string]interface{}{"data": map[string]interface{}{"assetId": cnftAssetID}}, want: cnftAssetID},
+		{name: "regular NFT", eventData: listingEventData("mint-1"), want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compressedAssetID(tt.eventData); got != tt.want {
+				t.Errorf("compressedAssetID = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseListingEventUsesCompressedAssetID(t *testing.T) {
+	idx := newTestPriceIndexer(t, `{"collection": "collection"}`)
+
+	event, outcome := idx.parseListingEvent(context.Background(), cnftListingEventData(), 1, "cnft-sig")
+	if outcome != priceEventParsed {
+		t.Fatalf("outcome = %v, want the cNFT listing parsed", outcome)
+	}
+	if event.Mint != cnftAssetID || event.AssetID != cnftAssetID {
+		t.Errorf("mint = %q, asset ID = %q; want both the asset ID", event.Mint, event.AssetID)
+	}
+}
+
+func TestProcessCompressedListingStoresAssetID(t *testing.T) {
+	pool := testPool(t)
+	table := testTable(t, pool)
+	idx := newTestPriceIndexer(t, `{"collection": "collection"}`)
+	initializeTable(t, pool, idx, table)
+
+	ctx := context.Background()
+	if err := idx.processPayload(ctx, pool, table, enhancedPayload(t, "cnft-sig", 1, cnftListingEventData())); err != nil {
+		t.Fatalf("processPayload: %v", err)
+	}
+
+	var mint, assetID string
+	if err := pool.QueryRow(ctx, "SELECT nft_mint, asset_id FROM "+QuoteTableName(table)+" WHERE signature = 'cnft-sig'").Scan(&mint, &assetID); err != nil {
+		t.Fatalf("read listing: %v", err)
+	}
+	if assetID != cnftAssetID || mint != cnftAssetID {
+		t.Errorf("nft_mint = %q, asset_id = %q; want both the asset ID", mint, assetID)
+	}
+}
+
+func TestInitializeAddsAssetIDColumn(t *testing.T) {
+	pool := testPool(t)
+	table := testTable(t, pool)
+	idx := newTestPriceIndexer(t, `{"collection": "collection"}`)
+	initializeTable(t, pool, idx, table)
+
+	ctx := context.Background()
+	if _, err := pool.Exec(ctx, "ALTER TABLE "+QuoteTableName(table)+" DROP COLUMN asset_id"); err != nil {
+		t.Fatalf("drop asset_id: %v", err)
+	}
+	initializeTable(t, pool, idx, table)
+
+	var exists bool
+	if err := pool.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_schema = 'public' AND table_name = $1 AND column_name = 'asset_id'
+		)`, table).Scan(&exists); err != nil {
+		t.Fatalf("check column: %v", err)
+	}
+	if !exists {
+		t.Error("asset_id column not added to a table created before it existed")
+	}
+}
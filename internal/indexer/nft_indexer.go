@@ -3,6 +3,7 @@ package indexer
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -14,12 +15,15 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/pkg/validator"
 )
 
 type NFTBidIndexer struct {
 	BaseIndexer
-	Collection   string
-	Marketplaces []string
+	Collection       string
+	Marketplaces     []string
+	CollectionOffers bool
+	timeColumn       timeColumn
 }
 
 func NewNFTBidIndexer(id string, params json.RawMessage) (Indexer, error) {
@@ -34,21 +38,51 @@ func NewNFTBidIndexer(id string, params json.RawMessage) (Indexer, error) {
 		return nil, fmt.Errorf("collection address is required")
 	}
 
+	column, err := newTimeColumn(nftParams.TimeColumn)
+	if err != nil {
+		return nil, err
+	}
+
 	return &NFTBidIndexer{
-		BaseIndexer:  base,
-		Collection:   nftParams.Collection,
-		Marketplaces: nftParams.Marketplaces,
+		BaseIndexer:      base,
+		Collection:       nftParams.Collection,
+		Marketplaces:     canonicalMarketplaces(nftParams.Marketplaces),
+		CollectionOffers: nftParams.CollectionOffers,
+		timeColumn:       column,
 	}, nil
 }
 
+// canonicalMarketplaces maps a configured marketplace filter to canonical
+// names, so indexers created before filters were normalized match the same
+// Helius sources as new ones
+func canonicalMarketplaces(marketplaces []string) []string {
+	canonical := make([]string, 0, len(marketplaces))
+	for _, marketplace := range marketplaces {
+		canonical = append(canonical, validator.CanonicalMarketplace(marketplace))
+	}
+	return canonical
+}
+
+// shortMintName builds a placeholder NFT name from the first and last three
+// characters of a mint address, for example "NFT 7xK...9Qz". Mints too short
+// to abbreviate get no name.
+func shortMintName(mint string) string {
+	runes := []rune(mint)
+	if len(runes) < 6 {
+		return ""
+	}
+	return fmt.Sprintf("NFT %s...%s", string(runes[:3]), string(runes[len(runes)-3:]))
+}
+
 func (i *NFTBidIndexer) Initialize(ctx context.Context, conn *pgx.Conn, targetTable string) error {
 	if err := i.BaseIndexer.Initialize(ctx, conn, targetTable); err != nil {
 		return err
 	}
 
-	targetTable = formatTableName(targetTable)
+	name := tableName(targetTable)
+	targetTable = QuoteTableName(targetTable)
 
-	exists, err := checkTableExists(ctx, conn, targetTable)
+	exists, err := checkTableExists(ctx, conn, name)
 	if err != nil {
 		return fmt.Errorf("failed to check if table exists: %w", err)
 	}
@@ -59,8 +93,9 @@ func (i *NFTBidIndexer) Initialize(ctx context.Context, conn *pgx.Conn, targetTa
 				id SERIAL PRIMARY KEY,
 				signature TEXT UNIQUE NOT NULL,
 				slot BIGINT NOT NULL,
-				block_time TIMESTAMP WITH TIME ZONE NOT NULL,
+				%s,
 				nft_mint TEXT NOT NULL,
+				asset_id TEXT,
 				auction_house TEXT,
 				marketplace TEXT NOT NULL,
 				bidder TEXT NOT NULL,
@@ -70,45 +105,73 @@ func (i *NFTBidIndexer) Initialize(ctx context.Context, conn *pgx.Conn, targetTa
 				expiry TIMESTAMP WITH TIME ZONE,
 				created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
 			)
-		`, targetTable))
+		`, targetTable, i.timeColumn.definition()))
 		if err != nil {
 			return fmt.Errorf("failed to create table: %w", err)
 		}
 
-		_, err = conn.Exec(ctx, fmt.Sprintf(`
-			CREATE INDEX %s_nft_mint_idx ON %s(nft_mint);
-			CREATE INDEX %s_marketplace_idx ON %s(marketplace);
-			CREATE INDEX %s_bidder_idx ON %s(bidder);
-			CREATE INDEX %s_block_time_idx ON %s(block_time);
-			CREATE INDEX %s_slot_idx ON %s(slot);
+		_, err = conn.Exec(ctx, i.timeColumn.rewrite(fmt.Sprintf(`
+			CREATE INDEX %s ON %s(nft_mint);
+			CREATE INDEX %s ON %s(marketplace);
+			CREATE INDEX %s ON %s(bidder);
+			CREATE INDEX %s ON %s(block_time);
+			CREATE INDEX %s ON %s(slot);
 		`,
-			targetTable, targetTable,
-			targetTable, targetTable,
-			targetTable, targetTable,
-			targetTable, targetTable,
-			targetTable, targetTable,
-		))
+			indexName(name, "nft_mint"), targetTable,
+			indexName(name, "marketplace"), targetTable,
+			indexName(name, "bidder"), targetTable,
+			indexName(name, "block_time"), targetTable,
+			indexName(name, "slot"), targetTable,
+		)))
 		if err != nil {
 			return fmt.Errorf("failed to create indices: %w", err)
 		}
 
 		log.Info().Str("table", targetTable).Msg("Successfully created NFT bids table")
+	} else {
+		if err := i.timeColumn.check(ctx, conn, name); err != nil {
+			return err
+		}
+		if err := clearEmptyText(ctx, conn, targetTable, "auction_house"); err != nil {
+			return err
+		}
+		if err := addAssetIDColumn(ctx, conn, name); err != nil {
+			return err
+		}
+	}
+
+	if i.CollectionOffers {
+		if err := i.initializeCollectionOfferTables(ctx, conn, name); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
 func (i *NFTBidIndexer) GetWebhookConfig(indexerID string) (WebhookConfig, error) {
+	defaults := nftBidTransactionTypes
+	if i.CollectionOffers {
+		defaults = append(append([]string{}, nftBidTransactionTypes...), collectionOfferTransactionTypes...)
+	}
+
 	config := WebhookConfig{
-		WebhookType:      "enhanced",
+		WebhookType:      i.webhookType(),
 		AccountAddresses: []string{i.Collection},
-		TransactionTypes: []string{"ANY"},
+		TransactionTypes: i.webhookTransactionTypes(defaults),
 	}
 
 	return config, nil
 }
 
-func (i *NFTBidIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload) error {
+// ProcessPayload reports the rows the payload wrote and the events it carried
+func (i *NFTBidIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload) (ProcessResult, error) {
+	return processWithResult(ctx, payload, func(ctx context.Context) error {
+		return i.processPayload(ctx, pool, targetTable, payload)
+	})
+}
+
+func (i *NFTBidIndexer) processPayload(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload) error {
 	if len(payload.Transaction.Signatures) == 0 {
 		return nil
 	}
@@ -118,7 +181,7 @@ func (i *NFTBidIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.Pool,
 		signature = payload.Transaction.Signatures[0]
 	}
 
-	targetTable = formatTableName(targetTable)
+	targetTable = QuoteTableName(targetTable)
 
 	log.Info().
 		Str("collection", i.Collection).
@@ -127,18 +190,17 @@ func (i *NFTBidIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.Pool,
 		Int64("slot", payload.Slot).
 		Msg("Processing NFT bid payload")
 
-	var enhancedDetails map[string]interface{}
-	if err := json.Unmarshal(payload.Transaction.EnhancedDetails, &enhancedDetails); err != nil {
+	details, err := models.ParseEnhancedTransaction(payload.Transaction.EnhancedDetails)
+	if err != nil {
 		log.Error().Err(err).Str("signature", signature).Msg("Failed to unmarshal enhanced details")
 		return fmt.Errorf("failed to unmarshal enhanced details: %w", err)
 	}
 
 	// Dump full transaction data for debugging (remove in production)
-	fullJson, _ := json.Marshal(enhancedDetails)
-	log.Debug().RawJSON("transaction", fullJson).Msg("Full transaction data")
+	log.Debug().RawJSON("transaction", payload.Transaction.EnhancedDetails).Msg("Full transaction data")
 
 	// Check if we have a description that can help us identify the transaction
-	if description, ok := enhancedDetails["description"].(string); ok && description != "" {
+	if description := details.Description; description != "" {
 		descLower := strings.ToLower(description)
 		if strings.Contains(descLower, "bid") {
 			log.Info().
@@ -148,91 +210,17 @@ func (i *NFTBidIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.Pool,
 		}
 	}
 
-	// Check for direct event type first (no events array)
-	if eventType, ok := enhancedDetails["type"].(string); ok {
-		log.Info().
-			Str("signature", signature).
-			Str("eventType", eventType).
-			Msg("Found direct event type")
-
-		if eventType == "NFT_BID" {
-			log.Info().
-				Str("signature", signature).
-				Str("type", eventType).
-				Msg("🔷 Found direct NFT bid event")
-
-			return i.processBidEvent(ctx, pool, targetTable, enhancedDetails, payload.Slot, signature)
-		} else if eventType == "NFT_BID_CANCELLED" {
-			log.Info().
-				Str("signature", signature).
-				Str("type", eventType).
-				Msg("❌ Found direct NFT bid cancellation event")
-
-			return i.processBidCancellation(ctx, pool, targetTable, enhancedDetails, payload.Slot, signature)
-		}
+	matched, err := DispatchEvents(ctx, details, i.eventHandlers(pool, targetTable, payload.Slot, signature))
+	if err != nil {
+		log.Error().Err(err).Str("signature", signature).Msg("Failed to process NFT bid event")
+		return err
 	}
-
-	// Check for events array
-	events, ok := enhancedDetails["events"].([]interface{})
-	if ok && len(events) > 0 {
-		log.Info().
-			Str("signature", signature).
-			Int("eventCount", len(events)).
-			Msg("Found events array in transaction")
-
-		foundBidEvent := false
-
-		for idx, eventRaw := range events {
-			event, ok := eventRaw.(map[string]interface{})
-			if !ok {
-				continue
-			}
-
-			eventType, ok := event["type"].(string)
-			if !ok {
-				continue
-			}
-
-			log.Info().
-				Str("signature", signature).
-				Int("eventIndex", idx).
-				Str("eventType", eventType).
-				Msg("Processing event from array")
-
-			if eventType == "NFT_BID" {
-				log.Info().
-					Str("signature", signature).
-					Int("eventIndex", idx).
-					Str("eventType", eventType).
-					Msg("🔷 Found NFT bid event in array")
-
-				foundBidEvent = true
-				if err := i.processBidEvent(ctx, pool, targetTable, event, payload.Slot, signature); err != nil {
-					log.Error().Err(err).Str("signature", signature).Msg("Failed to process NFT bid event")
-					return err
-				}
-			} else if eventType == "NFT_BID_CANCELLED" {
-				log.Info().
-					Str("signature", signature).
-					Int("eventIndex", idx).
-					Str("eventType", eventType).
-					Msg("❌ Found NFT bid cancellation event in array")
-
-				foundBidEvent = true
-				if err := i.processBidCancellation(ctx, pool, targetTable, event, payload.Slot, signature); err != nil {
-					log.Error().Err(err).Str("signature", signature).Msg("Failed to process NFT bid cancellation")
-					return err
-				}
-			}
-		}
-
-		if foundBidEvent {
-			return nil
-		}
+	if matched {
+		return nil
 	}
 
 	// Try to detect bid from description as a last resort
-	if description, ok := enhancedDetails["description"].(string); ok && description != "" {
+	if description := details.Description; description != "" {
 		descLower := strings.ToLower(description)
 		if strings.Contains(descLower, "bid") && strings.Contains(descLower, "for") {
 			log.Info().
@@ -265,6 +253,41 @@ func (i *NFTBidIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.Pool,
 	return nil
 }
 
+// eventHandlers registers the bid processors, and the collection offer ones
+// when enabled, for the event types they handle
+func (i *NFTBidIndexer) eventHandlers(pool *pgxpool.Pool, targetTable string, slot int64, signature string) map[string]EventHandler {
+	handle := func(process func(context.Context, *pgxpool.Pool, string, map[string]interface{}, int64, string) error) EventHandler {
+		return func(ctx context.Context, event map[string]interface{}) error {
+			return process(ctx, pool, targetTable, event, slot, signature)
+		}
+	}
+
+	handlers := map[string]EventHandler{
+		"NFT_BID":           handle(i.processBidEvent),
+		"NFT_BID_CANCELLED": handle(i.processBidCancellation),
+	}
+
+	if i.CollectionOffers {
+		for _, eventType := range collectionOfferEventTypes {
+			handlers[eventType] = handle(i.processCollectionOffer)
+		}
+		for _, eventType := range collectionOfferCancelEventTypes {
+			handlers[eventType] = handle(i.processCollectionOfferCancel)
+		}
+		for _, eventType := range collectionOfferFillEventTypes {
+			fill := handle(i.processCollectionOfferFill)
+			handlers[eventType] = func(ctx context.Context, event map[string]interface{}) error {
+				if !isCollectionOfferFillEvent(eventType, event) {
+					return errEventSkipped
+				}
+				return fill(ctx, event)
+			}
+		}
+	}
+
+	return handlers
+}
+
 func (i *NFTBidIndexer) processBidEvent(ctx context.Context, pool *pgxpool.Pool, targetTable string, eventData map[string]interface{}, slot int64, signature string) error {
 	var bidData map[string]interface{}
 
@@ -296,6 +319,12 @@ func (i *NFTBidIndexer) processBidEvent(ctx context.Context, pool *pgxpool.Pool,
 		}
 	}
 
+	// Compressed NFTs are identified by their asset ID rather than a mint
+	assetID := compressedAssetID(eventData)
+	if mintAddress == "" {
+		mintAddress = assetID
+	}
+
 	// Try to extract NFT name from metadata
 	if nftName == "" {
 		if metadata, ok := bidData["metadata"].(map[string]interface{}); ok {
@@ -307,10 +336,7 @@ func (i *NFTBidIndexer) processBidEvent(ctx context.Context, pool *pgxpool.Pool,
 
 	// If we still don't have an NFT name, create one from mint address
 	if nftName == "" && mintAddress != "" {
-		parts := strings.Split(mintAddress, "")
-		if len(parts) > 5 {
-			nftName = fmt.Sprintf("NFT %s...%s", strings.Join(parts[0:3], ""), strings.Join(parts[len(parts)-3:], ""))
-		}
+		nftName = shortMintName(mintAddress)
 	}
 
 	// Only process if it matches our configured collection
@@ -355,7 +381,7 @@ func (i *NFTBidIndexer) processBidEvent(ctx context.Context, pool *pgxpool.Pool,
 	if len(i.Marketplaces) > 0 && marketplace != "" {
 		marketplaceMatched := false
 		for _, m := range i.Marketplaces {
-			if strings.EqualFold(marketplace, m) {
+			if validator.CanonicalMarketplace(marketplace) == m {
 				marketplaceMatched = true
 				break
 			}
@@ -456,8 +482,7 @@ func (i *NFTBidIndexer) processBidEvent(ctx context.Context, pool *pgxpool.Pool,
 		marketplace = "UNKNOWN"
 	}
 
-	// Use current time if no blockTime available
-	blockTime := time.Now().UTC()
+	blockTime := eventBlockTime(ctx, eventData)
 
 	// Format a nice price string with USD value if available
 	priceStr := fmt.Sprintf("%.4f %s", bidAmount, currency)
@@ -479,22 +504,23 @@ func (i *NFTBidIndexer) processBidEvent(ctx context.Context, pool *pgxpool.Pool,
 		Float64("usd_value", bidUSDValue).
 		Msg("💸 NFT BID PLACED")
 
-	tx, err := pool.Begin(ctx)
+	tx, err := beginTx(ctx, pool, i.acquireTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
 	// Insert or update the bid
-	_, err = tx.Exec(ctx, fmt.Sprintf(`
+	written, err := tx.Exec(ctx, i.timeColumn.rewrite(fmt.Sprintf(`
 		INSERT INTO %s (
 			signature, slot, block_time, nft_mint, auction_house, marketplace, 
-			bidder, bid_amount, bid_currency, bid_usd_value, expiry
+			bidder, bid_amount, bid_currency, bid_usd_value, expiry, asset_id
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
 		) ON CONFLICT (signature) 
 		DO UPDATE SET 
 			nft_mint = EXCLUDED.nft_mint,
+			asset_id = EXCLUDED.asset_id,
 			auction_house = EXCLUDED.auction_house,
 			marketplace = EXCLUDED.marketplace,
 			bidder = EXCLUDED.bidder,
@@ -504,9 +530,9 @@ func (i *NFTBidIndexer) processBidEvent(ctx context.Context, pool *pgxpool.Pool,
 			expiry = EXCLUDED.expiry,
 			slot = EXCLUDED.slot,
 			block_time = EXCLUDED.block_time
-	`, targetTable),
-		signature, slot, blockTime, mintAddress, auctionHouse, marketplace,
-		bidder, bidAmount, currency, bidUSDValue, expiryTime)
+	`, targetTable)),
+		signature, slot, i.timeColumn.value(blockTime), mintAddress, nullableText(auctionHouse), marketplace,
+		bidder, bidAmount, currency, bidUSDValue, expiryTime, nullableText(assetID))
 
 	if err != nil {
 		log.Error().
@@ -522,6 +548,7 @@ func (i *NFTBidIndexer) processBidEvent(ctx context.Context, pool *pgxpool.Pool,
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	recordRows(ctx, written.RowsAffected())
 
 	// Confirm database operation success
 	log.Info().
@@ -570,7 +597,7 @@ func (i *NFTBidIndexer) processBidCancellation(ctx context.Context, pool *pgxpoo
 	}
 
 	// Remove the bid from our database
-	tx, err := pool.Begin(ctx)
+	tx, err := beginTx(ctx, pool, i.acquireTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
@@ -606,6 +633,7 @@ func (i *NFTBidIndexer) processBidCancellation(ctx context.Context, pool *pgxpoo
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	recordRows(ctx, result.RowsAffected())
 
 	rowsAffected := result.RowsAffected()
 	log.Info().
@@ -622,6 +650,10 @@ type NFTPriceIndexer struct {
 	BaseIndexer
 	Collection   string
 	Marketplaces []string
+	timeColumn   timeColumn
+	// storeUnresolvedMint keeps listings whose mint cannot be resolved, with a
+	// NULL nft_mint, instead of skipping them
+	storeUnresolvedMint bool
 }
 
 func NewNFTPriceIndexer(id string, params json.RawMessage) (Indexer, error) {
@@ -636,10 +668,17 @@ func NewNFTPriceIndexer(id string, params json.RawMessage) (Indexer, error) {
 		return nil, fmt.Errorf("collection address is required")
 	}
 
+	column, err := newTimeColumn(nftParams.TimeColumn)
+	if err != nil {
+		return nil, err
+	}
+
 	return &NFTPriceIndexer{
-		BaseIndexer:  base,
-		Collection:   nftParams.Collection,
-		Marketplaces: nftParams.Marketplaces,
+		BaseIndexer:         base,
+		Collection:          nftParams.Collection,
+		Marketplaces:        canonicalMarketplaces(nftParams.Marketplaces),
+		timeColumn:          column,
+		storeUnresolvedMint: nftParams.UnresolvedMint == models.UnresolvedMintStore,
 	}, nil
 }
 
@@ -648,9 +687,10 @@ func (i *NFTPriceIndexer) Initialize(ctx context.Context, conn *pgx.Conn, target
 		return err
 	}
 
-	targetTable = formatTableName(targetTable)
+	name := tableName(targetTable)
+	targetTable = QuoteTableName(targetTable)
 
-	exists, err := checkTableExists(ctx, conn, targetTable)
+	exists, err := checkTableExists(ctx, conn, name)
 	if err != nil {
 		return fmt.Errorf("failed to check if table exists: %w", err)
 	}
@@ -662,8 +702,10 @@ func (i *NFTPriceIndexer) Initialize(ctx context.Context, conn *pgx.Conn, target
                 id SERIAL PRIMARY KEY,
                 signature TEXT UNIQUE NOT NULL,
                 slot BIGINT NOT NULL,
-                block_time TIMESTAMP WITH TIME ZONE NOT NULL,
+                %s,
                 nft_mint TEXT NOT NULL,
+                asset_id TEXT,
+                mint_resolved BOOLEAN NOT NULL DEFAULT TRUE,
                 nft_name TEXT,
                 marketplace TEXT NOT NULL,
                 price NUMERIC NOT NULL,
@@ -675,7 +717,7 @@ func (i *NFTPriceIndexer) Initialize(ctx context.Context, conn *pgx.Conn, target
                 created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
                 updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
             )
-        `, targetTable)
+        `, targetTable, i.timeColumn.definition())
 
 		log.Debug().Str("sql", createTableSQL).Msg("Creating table with SQL")
 
@@ -685,23 +727,23 @@ func (i *NFTPriceIndexer) Initialize(ctx context.Context, conn *pgx.Conn, target
 		}
 
 		// Create indices with explicit names to avoid conflicts
-		indexesSQL := fmt.Sprintf(`
-            CREATE INDEX IF NOT EXISTS %s_nft_mint_idx ON %s(nft_mint);
-            CREATE INDEX IF NOT EXISTS %s_marketplace_idx ON %s(marketplace);
-            CREATE INDEX IF NOT EXISTS %s_seller_idx ON %s(seller);
-            CREATE INDEX IF NOT EXISTS %s_buyer_idx ON %s(buyer);
-            CREATE INDEX IF NOT EXISTS %s_status_idx ON %s(status);
-            CREATE INDEX IF NOT EXISTS %s_block_time_idx ON %s(block_time);
-            CREATE INDEX IF NOT EXISTS %s_slot_idx ON %s(slot);
+		indexesSQL := i.timeColumn.rewrite(fmt.Sprintf(`
+            CREATE INDEX IF NOT EXISTS %s ON %s(nft_mint);
+            CREATE INDEX IF NOT EXISTS %s ON %s(marketplace);
+            CREATE INDEX IF NOT EXISTS %s ON %s(seller);
+            CREATE INDEX IF NOT EXISTS %s ON %s(buyer);
+            CREATE INDEX IF NOT EXISTS %s ON %s(status);
+            CREATE INDEX IF NOT EXISTS %s ON %s(block_time);
+            CREATE INDEX IF NOT EXISTS %s ON %s(slot);
         `,
-			targetTable, targetTable,
-			targetTable, targetTable,
-			targetTable, targetTable,
-			targetTable, targetTable,
-			targetTable, targetTable,
-			targetTable, targetTable,
-			targetTable, targetTable,
-		)
+			indexName(name, "nft_mint"), targetTable,
+			indexName(name, "marketplace"), targetTable,
+			indexName(name, "seller"), targetTable,
+			indexName(name, "buyer"), targetTable,
+			indexName(name, "status"), targetTable,
+			indexName(name, "block_time"), targetTable,
+			indexName(name, "slot"), targetTable,
+		))
 
 		log.Debug().Str("sql", indexesSQL).Msg("Creating indices with SQL")
 
@@ -712,7 +754,7 @@ func (i *NFTPriceIndexer) Initialize(ctx context.Context, conn *pgx.Conn, target
 
 		// Verify table creation
 		var tableCount int
-		err = conn.QueryRow(ctx, "SELECT COUNT(*) FROM pg_tables WHERE schemaname = 'public' AND tablename = $1", targetTable).Scan(&tableCount)
+		err = conn.QueryRow(ctx, "SELECT COUNT(*) FROM pg_tables WHERE schemaname = 'public' AND tablename = $1", name).Scan(&tableCount)
 		if err != nil {
 			return fmt.Errorf("failed to verify table creation: %w", err)
 		}
@@ -728,6 +770,25 @@ func (i *NFTPriceIndexer) Initialize(ctx context.Context, conn *pgx.Conn, target
 		log.Info().
 			Str("table", targetTable).
 			Msg("NFT prices table already exists, skipping creation")
+
+		if err := i.timeColumn.check(ctx, conn, name); err != nil {
+			return err
+		}
+		if err := clearEmptyText(ctx, conn, targetTable, "nft_name", "buyer"); err != nil {
+			return err
+		}
+		if err := addAssetIDColumn(ctx, conn, name); err != nil {
+			return err
+		}
+		if err := addMintResolvedColumn(ctx, conn, name); err != nil {
+			return err
+		}
+	}
+
+	if i.storeUnresolvedMint {
+		if _, err := conn.Exec(ctx, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN nft_mint DROP NOT NULL", targetTable)); err != nil {
+			return fmt.Errorf("failed to make nft_mint nullable: %w", err)
+		}
 	}
 
 	return nil
@@ -735,15 +796,22 @@ func (i *NFTPriceIndexer) Initialize(ctx context.Context, conn *pgx.Conn, target
 
 func (i *NFTPriceIndexer) GetWebhookConfig(indexerID string) (WebhookConfig, error) {
 	config := WebhookConfig{
-		WebhookType:      "enhanced",
+		WebhookType:      i.webhookType(),
 		AccountAddresses: []string{i.Collection},
-		TransactionTypes: []string{"ANY"},
+		TransactionTypes: i.webhookTransactionTypes(nftPriceTransactionTypes),
 	}
 
 	return config, nil
 }
 
-func (i *NFTPriceIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload) error {
+// ProcessPayload reports the rows the payload wrote and the events it carried
+func (i *NFTPriceIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload) (ProcessResult, error) {
+	return processWithResult(ctx, payload, func(ctx context.Context) error {
+		return i.processPayload(ctx, pool, targetTable, payload)
+	})
+}
+
+func (i *NFTPriceIndexer) processPayload(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload) error {
 
 	i.validateDatabaseSetup(ctx, pool, targetTable)
 
@@ -756,7 +824,7 @@ func (i *NFTPriceIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.Pool
 		signature = payload.Transaction.Signatures[0]
 	}
 
-	targetTable = formatTableName(targetTable)
+	targetTable = QuoteTableName(targetTable)
 
 	log.Info().
 		Str("collection", i.Collection).
@@ -770,22 +838,22 @@ func (i *NFTPriceIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.Pool
 		return fmt.Errorf("database validation failed: %w", err)
 	}
 
-	var enhancedDetails map[string]interface{}
-	if err := json.Unmarshal(payload.Transaction.EnhancedDetails, &enhancedDetails); err != nil {
+	details, err := models.ParseEnhancedTransaction(payload.Transaction.EnhancedDetails)
+	if err != nil {
 		log.Error().Err(err).Str("signature", signature).Msg("Failed to unmarshal enhanced details")
 		return fmt.Errorf("failed to unmarshal enhanced details: %w", err)
 	}
 
 	// Log the raw transaction type for debugging
-	if txType, ok := enhancedDetails["type"].(string); ok {
+	if details.Type != "" {
 		log.Debug().
 			Str("signature", signature).
-			Str("transactionType", txType).
+			Str("transactionType", details.Type).
 			Msg("Transaction type from Helius")
 	}
 
 	// Check if we have a description that can help us classify the event
-	if description, ok := enhancedDetails["description"].(string); ok && description != "" {
+	if description := details.Description; description != "" {
 		log.Debug().
 			Str("signature", signature).
 			Str("description", description).
@@ -811,8 +879,11 @@ func (i *NFTPriceIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.Pool
 		}
 	}
 
-	// First check if we have a direct event without the events array
-	if eventType, ok := enhancedDetails["type"].(string); ok {
+	// First check if we have a direct event without the events array. The
+	// event processors read the untyped transaction, which keeps fields the
+	// typed model doesn't cover.
+	enhancedDetails := details.Raw
+	if eventType := details.Type; eventType != "" {
 		if eventType == "NFT_LISTING" {
 			log.Info().Str("type", eventType).Msg("Found direct NFT listing event")
 			return i.processListingEvent(ctx, pool, targetTable, enhancedDetails, payload.Slot, signature)
@@ -826,71 +897,19 @@ func (i *NFTPriceIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.Pool
 	}
 
 	// Check for events array
-	events, ok := enhancedDetails["events"].([]interface{})
-	if ok && len(events) > 0 {
+	events := details.Events.List
+	if len(events) > 0 {
 		log.Info().
 			Str("signature", signature).
 			Int("eventCount", len(events)).
 			Msg("Found events array in transaction")
 
-		// Process all events in the array
-		for idx, eventRaw := range events {
-			event, ok := eventRaw.(map[string]interface{})
-			if !ok {
-				continue
-			}
-
-			eventType, ok := event["type"].(string)
-			if !ok {
-				continue
-			}
-
-			log.Info().
-				Str("signature", signature).
-				Int("eventIndex", idx).
-				Str("eventType", eventType).
-				Msg("Processing event from array")
-
-			if eventType == "NFT_LISTING" {
-				log.Info().
-					Str("signature", signature).
-					Int("eventIndex", idx).
-					Str("eventType", eventType).
-					Msg("📋 Found NFT listing event in array")
-
-				if err := i.processListingEvent(ctx, pool, targetTable, event, payload.Slot, signature); err != nil {
-					log.Error().Err(err).Str("signature", signature).Msg("Failed to process NFT listing")
-					return err
-				}
-			} else if eventType == "NFT_SALE" {
-				log.Info().
-					Str("signature", signature).
-					Int("eventIndex", idx).
-					Str("eventType", eventType).
-					Msg("💲 Found NFT sale event in array")
-
-				if err := i.processSaleEvent(ctx, pool, targetTable, event, payload.Slot, signature); err != nil {
-					log.Error().Err(err).Str("signature", signature).Msg("Failed to process NFT sale")
-					return err
-				}
-			} else if eventType == "NFT_CANCEL_LISTING" {
-				log.Info().
-					Str("signature", signature).
-					Int("eventIndex", idx).
-					Str("eventType", eventType).
-					Msg("🚫 Found NFT cancel listing event in array")
-
-				if err := i.processCancelListingEvent(ctx, pool, targetTable, event, payload.Slot, signature); err != nil {
-					log.Error().Err(err).Str("signature", signature).Msg("Failed to process NFT listing cancellation")
-					return err
-				}
-			}
-		}
-		return nil
+		// Listings and sales of the array are written in one batch
+		return i.processPriceEvents(ctx, pool, targetTable, events, payload.Slot, signature)
 	}
 
 	// If we couldn't find events array, try to parse from description
-	if description, ok := enhancedDetails["description"].(string); ok && description != "" {
+	if description := details.Description; description != "" {
 		descLower := strings.ToLower(description)
 
 		if strings.Contains(descLower, "listed") && strings.Contains(descLower, "for") && strings.Contains(descLower, "sol") {
@@ -925,7 +944,9 @@ func (i *NFTPriceIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.Pool
 	return nil
 }
 
-func (i *NFTPriceIndexer) processListingEvent(ctx context.Context, pool *pgxpool.Pool, targetTable string, eventData map[string]interface{}, slot int64, signature string) error {
+// parseListingEvent reads a listing out of an NFT_LISTING event without
+// writing it
+func (i *NFTPriceIndexer) parseListingEvent(ctx context.Context, eventData map[string]interface{}, slot int64, signature string) (NFTPriceEvent, priceEventOutcome) {
 	var listingData map[string]interface{}
 
 	if data, ok := eventData["data"].(map[string]interface{}); ok {
@@ -954,6 +975,12 @@ func (i *NFTPriceIndexer) processListingEvent(ctx context.Context, pool *pgxpool
 		}
 	}
 
+	// Compressed NFTs are identified by their asset ID rather than a mint
+	assetID := compressedAssetID(eventData)
+	if mintAddress == "" {
+		mintAddress = assetID
+	}
+
 	// Try to extract NFT name from metadata if available
 	if nftName == "" {
 		if metadata, ok := listingData["metadata"].(map[string]interface{}); ok {
@@ -965,10 +992,7 @@ func (i *NFTPriceIndexer) processListingEvent(ctx context.Context, pool *pgxpool
 
 	// If we still don't have an NFT name, try the NFT's metadata
 	if nftName == "" && mintAddress != "" {
-		parts := strings.Split(mintAddress, "")
-		if len(parts) > 5 {
-			nftName = fmt.Sprintf("NFT %s...%s", strings.Join(parts[0:3], ""), strings.Join(parts[len(parts)-3:], ""))
-		}
+		nftName = shortMintName(mintAddress)
 	}
 
 	// Only process if it matches our configured collection
@@ -1001,7 +1025,7 @@ func (i *NFTPriceIndexer) processListingEvent(ctx context.Context, pool *pgxpool
 	if len(i.Marketplaces) > 0 && marketplace != "" {
 		marketplaceMatched := false
 		for _, m := range i.Marketplaces {
-			if strings.EqualFold(marketplace, m) {
+			if validator.CanonicalMarketplace(marketplace) == m {
 				marketplaceMatched = true
 				break
 			}
@@ -1012,7 +1036,7 @@ func (i *NFTPriceIndexer) processListingEvent(ctx context.Context, pool *pgxpool
 				Str("foundMarketplace", marketplace).
 				Strs("configuredMarketplaces", i.Marketplaces).
 				Msg("Skipping NFT listing - marketplace not in configured list")
-			return nil
+			return NFTPriceEvent{}, priceEventSkipped
 		}
 	}
 
@@ -1033,6 +1057,36 @@ func (i *NFTPriceIndexer) processListingEvent(ctx context.Context, pool *pgxpool
 		price = p
 	}
 
+	// Tensor reports the price in lamports and cNFTs by asset ID, so its own
+	// event structure takes precedence over the generic fields
+	if tensor, ok := extractTensorEvent(eventData); ok {
+		if !i.marketplaceAllowed(tensorMarketplace) {
+			log.Debug().
+				Strs("configuredMarketplaces", i.Marketplaces).
+				Msg("Skipping NFT listing - marketplace not in configured list")
+			return NFTPriceEvent{}, priceEventSkipped
+		}
+
+		marketplace = tensorMarketplace
+		if tensor.Mint != "" {
+			mintAddress = tensor.Mint
+			if tensor.Compressed {
+				assetID = tensor.Mint
+			}
+		}
+		if tensor.Seller != "" {
+			seller = tensor.Seller
+		}
+		if tensor.Price > 0 {
+			price = tensor.Price
+		}
+
+		log.Debug().
+			Str("mint", mintAddress).
+			Bool("compressed", tensor.Compressed).
+			Msg("Parsed Tensor NFT listing")
+	}
+
 	// Extract USD value
 	if usd, ok := listingData["usdValue"].(float64); ok {
 		usdValue = usd
@@ -1043,13 +1097,10 @@ func (i *NFTPriceIndexer) processListingEvent(ctx context.Context, pool *pgxpool
 		currency = curr
 	}
 
-	// If we're missing essential data, try to parse from description
+	// If we're missing essential data, the caller can try the description
 	if mintAddress == "" || seller == "" || price <= 0 {
 		if description, ok := eventData["description"].(string); ok && description != "" {
-			log.Info().
-				Str("description", description).
-				Msg("Attempting to extract listing data from description")
-			return i.processListingFromDescription(ctx, pool, targetTable, description, eventData, slot, signature)
+			return NFTPriceEvent{}, priceEventIncomplete
 		}
 
 		log.Warn().
@@ -1057,7 +1108,7 @@ func (i *NFTPriceIndexer) processListingEvent(ctx context.Context, pool *pgxpool
 			Str("seller", seller).
 			Float64("price", price).
 			Msg("Skipping NFT listing - missing essential data")
-		return nil
+		return NFTPriceEvent{}, priceEventSkipped
 	}
 
 	// If marketplace is empty, use a default value
@@ -1065,8 +1116,38 @@ func (i *NFTPriceIndexer) processListingEvent(ctx context.Context, pool *pgxpool
 		marketplace = "UNKNOWN"
 	}
 
-	// Use current time if no blockTime available
-	blockTime := time.Now().UTC()
+	return NFTPriceEvent{
+		Signature:   signature,
+		Slot:        slot,
+		BlockTime:   eventBlockTime(ctx, eventData),
+		Mint:        mintAddress,
+		AssetID:     assetID,
+		Name:        nftName,
+		Marketplace: marketplace,
+		Price:       price,
+		Currency:    currency,
+		USDValue:    usdValue,
+		Seller:      seller,
+		Status:      "listed",
+	}, priceEventParsed
+}
+
+func (i *NFTPriceIndexer) processListingEvent(ctx context.Context, pool *pgxpool.Pool, targetTable string, eventData map[string]interface{}, slot int64, signature string) error {
+	listing, outcome := i.parseListingEvent(ctx, eventData, slot, signature)
+	if outcome == priceEventSkipped {
+		return nil
+	}
+	if outcome == priceEventIncomplete {
+		description, _ := eventData["description"].(string)
+		log.Info().
+			Str("description", description).
+			Msg("Attempting to extract listing data from description")
+		return i.processListingFromDescription(ctx, pool, targetTable, description, eventData, slot, signature)
+	}
+
+	mintAddress, nftName, marketplace := listing.Mint, listing.Name, listing.Marketplace
+	seller, price, currency, usdValue := listing.Seller, listing.Price, listing.Currency, listing.USDValue
+	assetID, blockTime := listing.AssetID, listing.BlockTime
 
 	// Log the NFT listing with all key details
 	log.Info().
@@ -1082,52 +1163,27 @@ func (i *NFTPriceIndexer) processListingEvent(ctx context.Context, pool *pgxpool
 		Float64("usd_value", usdValue).
 		Msg("✨ NFT LISTED")
 
-	// DIAGNOSTIC: Check table existence before attempting insertion
-	conn, err := pool.Acquire(ctx)
-	if err != nil {
-		log.Error().Err(err).Msg("⚠️ Failed to acquire connection for table verification")
-		return fmt.Errorf("failed to acquire connection: %w", err)
-	}
-
-	// Check if table exists before proceeding
-	var tableExists bool
-	err = conn.QueryRow(ctx, `
-		SELECT EXISTS (
-			SELECT FROM pg_tables 
-			WHERE schemaname = 'public' 
-			AND tablename = $1
-		)
-	`, targetTable).Scan(&tableExists)
-
-	conn.Release()
+	dbCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
 
+	tx, err := beginTx(dbCtx, pool, i.acquireTimeout)
 	if err != nil {
-		log.Error().Err(err).Str("table", targetTable).Msg("⚠️ Failed to check if table exists")
-		return fmt.Errorf("failed to check table existence: %w", err)
-	}
-
-	if !tableExists {
-		log.Error().Str("table", targetTable).Msg("⚠️ Target table does not exist!")
-		return fmt.Errorf("target table %s does not exist", targetTable)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback(dbCtx)
 
-	log.Info().Str("table", targetTable).Msg("✅ Target table exists, proceeding with insertion")
-
-	// Create a transaction with extended timeout
-	dbCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
-	defer cancel()
-
-	// MODIFIED: Use simpler direct connection approach first to diagnose issues
-	insertSQL := fmt.Sprintf(`
+	written, err := tx.Exec(dbCtx, i.timeColumn.rewrite(fmt.Sprintf(`
 		INSERT INTO %s (
 			signature, slot, block_time, nft_mint, nft_name, marketplace, 
-			price, currency, usd_value, seller, status
+			price, currency, usd_value, seller, status, asset_id
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
 		) ON CONFLICT (signature) 
 		DO UPDATE SET 
 			nft_mint = EXCLUDED.nft_mint,
-			nft_name = CASE WHEN EXCLUDED.nft_name IS NOT NULL AND EXCLUDED.nft_name != '' THEN EXCLUDED.nft_name ELSE nft_name END,
+			asset_id = EXCLUDED.asset_id,
+			mint_resolved = EXCLUDED.mint_resolved,
+			nft_name = COALESCE(EXCLUDED.nft_name, %s.nft_name),
 			marketplace = EXCLUDED.marketplace,
 			price = EXCLUDED.price,
 			currency = EXCLUDED.currency,
@@ -1137,82 +1193,33 @@ func (i *NFTPriceIndexer) processListingEvent(ctx context.Context, pool *pgxpool
 			slot = EXCLUDED.slot,
 			block_time = EXCLUDED.block_time,
 			updated_at = NOW()
-	`, targetTable)
-
-	log.Info().
-		Str("sql", insertSQL).
-		Str("signature", signature).
-		Str("mint", mintAddress).
-		Str("table", targetTable).
-		Msg("🔍 Executing SQL insert")
-
-	// Try with direct connection first to get more detailed error info
-	directConn, err := pool.Acquire(dbCtx)
-	if err != nil {
-		log.Error().Err(err).Msg("⚠️ Failed to acquire direct connection")
-		return fmt.Errorf("failed to acquire direct connection: %w", err)
-	}
-	defer directConn.Release()
-
-	// Execute the query directly first
-	_, err = directConn.Exec(dbCtx, insertSQL,
-		signature, slot, blockTime, mintAddress, nftName, marketplace,
-		price, currency, usdValue, seller, "listed")
+	`, targetTable, targetTable)),
+		signature, slot, i.timeColumn.value(blockTime), mintAddress, nullableText(nftName), marketplace,
+		price, currency, usdValue, seller, "listed", nullableText(assetID))
 
 	if err != nil {
-		log.Error().
+		event := log.Error().
 			Err(err).
 			Str("signature", signature).
 			Str("table", targetTable).
 			Str("mint", mintAddress).
-			Float64("price", price).
-			Msg("❌ Direct insert failed")
+			Float64("price", price)
 
-		// Check for specific error types
-		if pgErr, ok := err.(*pgconn.PgError); ok {
-			log.Error().
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			event = event.
 				Str("pgErrorCode", pgErr.Code).
-				Str("pgErrorMessage", pgErr.Message).
-				Str("pgErrorDetail", pgErr.Detail).
-				Msg("⚠️ PostgreSQL error details")
+				Str("pgErrorDetail", pgErr.Detail)
 		}
+		event.Msg("Error inserting NFT listing")
 
 		return fmt.Errorf("failed to insert NFT listing: %w", err)
 	}
 
-	// If direct execution worked, try with transaction
-	tx, err := pool.Begin(dbCtx)
-	if err != nil {
-		log.Error().Err(err).Msg("⚠️ Failed to begin transaction (but direct insert succeeded)")
-		// Don't return error here since direct insert worked
-		return nil
-	}
-	defer tx.Rollback(dbCtx)
-
-	_, err = tx.Exec(dbCtx, insertSQL,
-		signature, slot, blockTime, mintAddress, nftName, marketplace,
-		price, currency, usdValue, seller, "listed")
-
-	if err != nil {
-		log.Error().
-			Err(err).
-			Str("signature", signature).
-			Str("mint", mintAddress).
-			Str("seller", seller).
-			Float64("price", price).
-			Msg("❌ Error inserting NFT listing in transaction")
-		// Don't return error since direct insert worked
-		return nil
-	}
-
 	if err := tx.Commit(dbCtx); err != nil {
-		log.Error().
-			Err(err).
-			Str("signature", signature).
-			Msg("❌ Failed to commit transaction (but direct insert succeeded)")
-		// Don't return error since direct insert worked
-		return nil
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	recordRows(ctx, written.RowsAffected())
 
 	// Confirm database operation success
 	priceStr := fmt.Sprintf("%.4f %s ($%.2f)", price, currency, usdValue)
@@ -1295,7 +1302,7 @@ func (i *NFTPriceIndexer) processListingFromDescription(ctx context.Context, poo
 	if len(i.Marketplaces) > 0 && marketplace != "" {
 		marketplaceMatched := false
 		for _, m := range i.Marketplaces {
-			if strings.EqualFold(marketplace, m) {
+			if validator.CanonicalMarketplace(marketplace) == m {
 				marketplaceMatched = true
 				break
 			}
@@ -1315,12 +1322,13 @@ func (i *NFTPriceIndexer) processListingFromDescription(ctx context.Context, poo
 		marketplace = "UNKNOWN"
 	}
 
-	// Use current time if no blockTime available
-	blockTime := time.Now().UTC()
+	blockTime := eventBlockTime(ctx, eventData)
 
-	// Try to extract mint address from the event data
-	var mintAddress string
-	if instructions, ok := eventData["instructions"].([]interface{}); ok {
+	// A compressed NFT carries its asset ID; otherwise try to extract the mint
+	// address from the event data
+	assetID := compressedAssetID(eventData)
+	mintAddress := assetID
+	if instructions, ok := eventData["instructions"].([]interface{}); ok && mintAddress == "" {
 		for _, instruction := range instructions {
 			if instructionData, ok := instruction.(map[string]interface{}); ok {
 				if accounts, ok := instructionData["accounts"].([]interface{}); ok {
@@ -1337,37 +1345,46 @@ func (i *NFTPriceIndexer) processListingFromDescription(ctx context.Context, poo
 		}
 	}
 
-	// If we couldn't extract a mint address, treat this as informational only
-	if mintAddress == "" {
-		// 1. First try to use collection address
-		mintAddress = i.Collection
-		log.Info().
+	// Without a mint the row does not identify an NFT. It is never stored
+	// under the collection address: either it is skipped or, when configured,
+	// kept with a NULL mint and mint_resolved set to false.
+	if mintAddress == "" && !i.storeUnresolvedMint {
+		log.Warn().
 			Str("signature", signature).
 			Str("description", description).
 			Str("seller", seller).
 			Float64("price", price).
 			Str("marketplace", marketplace).
-			Str("collectionAsAddress", mintAddress).
-			Msg("Using collection as mint address for NFT listing")
+			Msg("Skipping NFT listing - no mint address or asset ID found")
+		return nil
+	}
+	if mintAddress == "" {
+		log.Info().
+			Str("signature", signature).
+			Str("seller", seller).
+			Float64("price", price).
+			Msg("Storing NFT listing with unresolved mint")
 	}
 
-	tx, err := pool.Begin(ctx)
+	tx, err := beginTx(ctx, pool, i.acquireTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
 	// Insert the listing
-	_, err = tx.Exec(ctx, fmt.Sprintf(`
+	written, err := tx.Exec(ctx, i.timeColumn.rewrite(fmt.Sprintf(`
 		INSERT INTO %s (
 			signature, slot, block_time, nft_mint, nft_name, marketplace, 
-			price, currency, seller, status
+			price, currency, seller, status, asset_id, mint_resolved
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
 		) ON CONFLICT (signature) 
 		DO UPDATE SET 
 			nft_mint = EXCLUDED.nft_mint,
-			nft_name = CASE WHEN EXCLUDED.nft_name IS NOT NULL AND EXCLUDED.nft_name != '' THEN EXCLUDED.nft_name ELSE %s.nft_name END,
+			asset_id = EXCLUDED.asset_id,
+			mint_resolved = EXCLUDED.mint_resolved,
+			nft_name = COALESCE(EXCLUDED.nft_name, %s.nft_name),
 			marketplace = EXCLUDED.marketplace,
 			price = EXCLUDED.price,
 			currency = EXCLUDED.currency,
@@ -1376,9 +1393,9 @@ func (i *NFTPriceIndexer) processListingFromDescription(ctx context.Context, poo
 			slot = EXCLUDED.slot,
 			block_time = EXCLUDED.block_time,
 			updated_at = NOW()
-	`, targetTable, targetTable),
-		signature, slot, blockTime, mintAddress, nftName, marketplace,
-		price, currency, seller, "listed")
+	`, targetTable, targetTable)),
+		signature, slot, i.timeColumn.value(blockTime), nullableText(mintAddress), nullableText(nftName), marketplace,
+		price, currency, seller, "listed", nullableText(assetID), mintAddress != "")
 
 	if err != nil {
 		log.Error().
@@ -1392,6 +1409,7 @@ func (i *NFTPriceIndexer) processListingFromDescription(ctx context.Context, poo
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	recordRows(ctx, written.RowsAffected())
 
 	log.Info().
 		Str("signature", signature).
@@ -1405,7 +1423,8 @@ func (i *NFTPriceIndexer) processListingFromDescription(ctx context.Context, poo
 	return nil
 }
 
-func (i *NFTPriceIndexer) processSaleEvent(ctx context.Context, pool *pgxpool.Pool, targetTable string, eventData map[string]interface{}, slot int64, signature string) error {
+// parseSaleEvent reads a sale out of an NFT_SALE event without writing it
+func (i *NFTPriceIndexer) parseSaleEvent(ctx context.Context, eventData map[string]interface{}, slot int64, signature string) (NFTPriceEvent, priceEventOutcome) {
 	var saleData map[string]interface{}
 
 	if data, ok := eventData["data"].(map[string]interface{}); ok {
@@ -1434,6 +1453,12 @@ func (i *NFTPriceIndexer) processSaleEvent(ctx context.Context, pool *pgxpool.Po
 		}
 	}
 
+	// Compressed NFTs are identified by their asset ID rather than a mint
+	assetID := compressedAssetID(eventData)
+	if mintAddress == "" {
+		mintAddress = assetID
+	}
+
 	// Try to extract NFT name from metadata if available
 	if nftName == "" {
 		if metadata, ok := saleData["metadata"].(map[string]interface{}); ok {
@@ -1445,11 +1470,7 @@ func (i *NFTPriceIndexer) processSaleEvent(ctx context.Context, pool *pgxpool.Po
 
 	// If we still don't have an NFT name, try the NFT's metadata
 	if nftName == "" && mintAddress != "" {
-		// This is intentionally kept simple - you could expand with actual metadata fetching
-		parts := strings.Split(mintAddress, "")
-		if len(parts) > 5 {
-			nftName = fmt.Sprintf("NFT %s...%s", parts[0:3], parts[len(parts)-3:])
-		}
+		nftName = shortMintName(mintAddress)
 	}
 
 	// Only process if it matches our configured collection
@@ -1489,7 +1510,7 @@ func (i *NFTPriceIndexer) processSaleEvent(ctx context.Context, pool *pgxpool.Po
 	if len(i.Marketplaces) > 0 && marketplace != "" {
 		marketplaceMatched := false
 		for _, m := range i.Marketplaces {
-			if strings.EqualFold(marketplace, m) {
+			if validator.CanonicalMarketplace(marketplace) == m {
 				marketplaceMatched = true
 				break
 			}
@@ -1500,7 +1521,7 @@ func (i *NFTPriceIndexer) processSaleEvent(ctx context.Context, pool *pgxpool.Po
 				Str("foundMarketplace", marketplace).
 				Strs("configuredMarketplaces", i.Marketplaces).
 				Msg("Skipping NFT sale - marketplace not in configured list")
-			return nil
+			return NFTPriceEvent{}, priceEventSkipped
 		}
 	}
 
@@ -1526,6 +1547,39 @@ func (i *NFTPriceIndexer) processSaleEvent(ctx context.Context, pool *pgxpool.Po
 		price = p
 	}
 
+	// Tensor reports the price in lamports and cNFTs by asset ID, so its own
+	// event structure takes precedence over the generic fields
+	if tensor, ok := extractTensorEvent(eventData); ok {
+		if !i.marketplaceAllowed(tensorMarketplace) {
+			log.Debug().
+				Strs("configuredMarketplaces", i.Marketplaces).
+				Msg("Skipping NFT sale - marketplace not in configured list")
+			return NFTPriceEvent{}, priceEventSkipped
+		}
+
+		marketplace = tensorMarketplace
+		if tensor.Mint != "" {
+			mintAddress = tensor.Mint
+			if tensor.Compressed {
+				assetID = tensor.Mint
+			}
+		}
+		if tensor.Seller != "" {
+			seller = tensor.Seller
+		}
+		if tensor.Buyer != "" {
+			buyer = tensor.Buyer
+		}
+		if tensor.Price > 0 {
+			price = tensor.Price
+		}
+
+		log.Debug().
+			Str("mint", mintAddress).
+			Bool("compressed", tensor.Compressed).
+			Msg("Parsed Tensor NFT sale")
+	}
+
 	// Try to extract from description if we don't have price
 	if price <= 0 {
 		if description, ok := eventData["description"].(string); ok && description != "" {
@@ -1601,7 +1655,7 @@ func (i *NFTPriceIndexer) processSaleEvent(ctx context.Context, pool *pgxpool.Po
 				Str("buyer", buyer).
 				Float64("price", price).
 				Msg("Skipping NFT sale - missing essential data")
-			return nil
+			return NFTPriceEvent{}, priceEventSkipped
 		}
 	}
 
@@ -1610,8 +1664,32 @@ func (i *NFTPriceIndexer) processSaleEvent(ctx context.Context, pool *pgxpool.Po
 		marketplace = "UNKNOWN"
 	}
 
-	// Use current time if no blockTime available
-	blockTime := time.Now().UTC()
+	return NFTPriceEvent{
+		Signature:   signature,
+		Slot:        slot,
+		BlockTime:   eventBlockTime(ctx, eventData),
+		Mint:        mintAddress,
+		AssetID:     assetID,
+		Name:        nftName,
+		Marketplace: marketplace,
+		Price:       price,
+		Currency:    currency,
+		USDValue:    usdValue,
+		Seller:      seller,
+		Buyer:       buyer,
+		Status:      "sold",
+	}, priceEventParsed
+}
+
+func (i *NFTPriceIndexer) processSaleEvent(ctx context.Context, pool *pgxpool.Pool, targetTable string, eventData map[string]interface{}, slot int64, signature string) error {
+	sale, outcome := i.parseSaleEvent(ctx, eventData, slot, signature)
+	if outcome != priceEventParsed {
+		return nil
+	}
+
+	mintAddress, nftName, marketplace := sale.Mint, sale.Name, sale.Marketplace
+	seller, buyer, price, currency, usdValue := sale.Seller, sale.Buyer, sale.Price, sale.Currency, sale.USDValue
+	assetID, blockTime := sale.AssetID, sale.BlockTime
 
 	// Log the NFT sale with all key details
 	log.Info().
@@ -1628,14 +1706,14 @@ func (i *NFTPriceIndexer) processSaleEvent(ctx context.Context, pool *pgxpool.Po
 		Float64("usd_value", usdValue).
 		Msg("🎉 NFT SOLD")
 
-	tx, err := pool.Begin(ctx)
+	tx, err := beginTx(ctx, pool, i.acquireTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
 	// First try to update an existing listing for this mint/seller
-	result, err := tx.Exec(ctx, fmt.Sprintf(`
+	result, err := tx.Exec(ctx, i.timeColumn.rewrite(fmt.Sprintf(`
 		UPDATE %s SET
 			status = 'sold',
 			buyer = $1,
@@ -1645,8 +1723,8 @@ func (i *NFTPriceIndexer) processSaleEvent(ctx context.Context, pool *pgxpool.Po
 			signature = $4
 		WHERE nft_mint = $5 AND seller = $6 AND status = 'listed'
 		AND marketplace = $7
-	`, targetTable),
-		buyer, slot, blockTime, signature,
+	`, targetTable)),
+		nullableText(buyer), slot, i.timeColumn.value(blockTime), signature,
 		mintAddress, seller, marketplace)
 
 	if err != nil {
@@ -1666,30 +1744,33 @@ func (i *NFTPriceIndexer) processSaleEvent(ctx context.Context, pool *pgxpool.Po
 	}
 
 	// If we didn't update an existing listing, insert as a direct sale
+	var inserted pgconn.CommandTag
 	if rowsAffected == 0 {
-		_, err = tx.Exec(ctx, fmt.Sprintf(`
+		inserted, err = tx.Exec(ctx, i.timeColumn.rewrite(fmt.Sprintf(`
 			INSERT INTO %s (
 				signature, slot, block_time, nft_mint, nft_name, marketplace, 
-				price, currency, usd_value, seller, buyer, status
+				price, currency, usd_value, seller, buyer, status, asset_id
 			) VALUES (
-				$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+				$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
 			) ON CONFLICT (signature) 
 			DO UPDATE SET 
 				nft_mint = EXCLUDED.nft_mint,
-				nft_name = CASE WHEN EXCLUDED.nft_name IS NOT NULL AND EXCLUDED.nft_name != '' THEN EXCLUDED.nft_name ELSE %s.nft_name END,
+				asset_id = EXCLUDED.asset_id,
+				mint_resolved = EXCLUDED.mint_resolved,
+				nft_name = COALESCE(EXCLUDED.nft_name, %s.nft_name),
 				marketplace = EXCLUDED.marketplace,
 				price = EXCLUDED.price,
 				currency = EXCLUDED.currency,
 				usd_value = EXCLUDED.usd_value,
 				seller = EXCLUDED.seller,
-				buyer = EXCLUDED.buyer,
+				buyer = COALESCE(EXCLUDED.buyer, %s.buyer),
 				status = EXCLUDED.status,
 				slot = EXCLUDED.slot,
 				block_time = EXCLUDED.block_time,
 				updated_at = NOW()
-		`, targetTable, targetTable),
-			signature, slot, blockTime, mintAddress, nftName, marketplace,
-			price, currency, usdValue, seller, buyer, "sold")
+		`, targetTable, targetTable, targetTable)),
+			signature, slot, i.timeColumn.value(blockTime), mintAddress, nullableText(nftName), marketplace,
+			price, currency, usdValue, seller, nullableText(buyer), "sold", nullableText(assetID))
 
 		if err != nil {
 			log.Error().
@@ -1707,6 +1788,7 @@ func (i *NFTPriceIndexer) processSaleEvent(ctx context.Context, pool *pgxpool.Po
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	recordRows(ctx, rowsAffected+inserted.RowsAffected())
 
 	// Format a clean price string with USD value if available
 	priceStr := fmt.Sprintf("%.4f %s", price, currency)
@@ -1789,7 +1871,7 @@ func (i *NFTPriceIndexer) processCancelListingEvent(ctx context.Context, pool *p
 	if len(i.Marketplaces) > 0 && marketplace != "" {
 		marketplaceMatched := false
 		for _, m := range i.Marketplaces {
-			if strings.EqualFold(marketplace, m) {
+			if validator.CanonicalMarketplace(marketplace) == m {
 				marketplaceMatched = true
 				break
 			}
@@ -1823,17 +1905,16 @@ func (i *NFTPriceIndexer) processCancelListingEvent(ctx context.Context, pool *p
 		marketplace = "UNKNOWN"
 	}
 
-	// Use current time if no blockTime available
-	blockTime := time.Now().UTC()
+	blockTime := eventBlockTime(ctx, eventData)
 
-	tx, err := pool.Begin(ctx)
+	tx, err := beginTx(ctx, pool, i.acquireTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
 	// Update existing listing to cancelled status
-	result, err := tx.Exec(ctx, fmt.Sprintf(`
+	result, err := tx.Exec(ctx, i.timeColumn.rewrite(fmt.Sprintf(`
 		UPDATE %s SET
 			status = 'cancelled',
 			updated_at = NOW(),
@@ -1842,8 +1923,8 @@ func (i *NFTPriceIndexer) processCancelListingEvent(ctx context.Context, pool *p
 			signature = $3
 		WHERE nft_mint = $4 AND seller = $5 AND status = 'listed'
 		AND (marketplace = $6 OR $6 = 'UNKNOWN')
-	`, targetTable),
-		slot, blockTime, signature,
+	`, targetTable)),
+		slot, i.timeColumn.value(blockTime), signature,
 		mintAddress, seller, marketplace)
 
 	if err != nil {
@@ -1859,16 +1940,17 @@ func (i *NFTPriceIndexer) processCancelListingEvent(ctx context.Context, pool *p
 	rowsAffected := result.RowsAffected()
 
 	// If we didn't find an existing listing, add an informational record
+	var inserted pgconn.CommandTag
 	if rowsAffected == 0 {
-		_, err = tx.Exec(ctx, fmt.Sprintf(`
+		inserted, err = tx.Exec(ctx, i.timeColumn.rewrite(fmt.Sprintf(`
 			INSERT INTO %s (
 				signature, slot, block_time, nft_mint, marketplace, 
 				price, currency, seller, status
 			) VALUES (
 				$1, $2, $3, $4, $5, 0, $6, $7, $8
 			) ON CONFLICT (signature) DO NOTHING
-		`, targetTable),
-			signature, slot, blockTime, mintAddress, marketplace,
+		`, targetTable)),
+			signature, slot, i.timeColumn.value(blockTime), mintAddress, marketplace,
 			"SOL", seller, "cancelled")
 
 		if err != nil {
@@ -1885,6 +1967,7 @@ func (i *NFTPriceIndexer) processCancelListingEvent(ctx context.Context, pool *p
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	recordRows(ctx, rowsAffected+inserted.RowsAffected())
 
 	log.Info().
 		Str("signature", signature).
@@ -1904,10 +1987,11 @@ func (i *NFTPriceIndexer) validateDatabaseConnection(ctx context.Context, pool *
 	}
 
 	// Format the table name
-	targetTable = formatTableName(targetTable)
+	name := tableName(targetTable)
+	targetTable = QuoteTableName(targetTable)
 
 	// Check if the table exists and has the right schema
-	conn, err := pool.Acquire(ctx)
+	conn, err := acquireConn(ctx, pool, i.acquireTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to acquire connection: %w", err)
 	}
@@ -1920,7 +2004,7 @@ func (i *NFTPriceIndexer) validateDatabaseConnection(ctx context.Context, pool *
             WHERE schemaname = 'public'
             AND tablename = $1
         )
-    `, targetTable).Scan(&tableExists)
+    `, name).Scan(&tableExists)
 
 	if err != nil {
 		return fmt.Errorf("failed to check if table exists: %w", err)
@@ -1936,8 +2020,8 @@ func (i *NFTPriceIndexer) validateDatabaseConnection(ctx context.Context, pool *
         SELECT COUNT(*) FROM information_schema.columns 
         WHERE table_schema = 'public' 
         AND table_name = $1
-        AND column_name IN ('signature', 'slot', 'block_time', 'nft_mint', 'marketplace', 'price', 'seller', 'status')
-    `, targetTable).Scan(&columnCount)
+        AND column_name IN ('signature', 'slot', $2, 'nft_mint', 'marketplace', 'price', 'seller', 'status')
+    `, name, i.timeColumn.name).Scan(&columnCount)
 
 	if err != nil {
 		return fmt.Errorf("failed to check table columns: %w", err)
@@ -1948,22 +2032,22 @@ func (i *NFTPriceIndexer) validateDatabaseConnection(ctx context.Context, pool *
 	}
 
 	// Test insert and rollback to verify permissions
-	tx, err := pool.Begin(ctx)
+	tx, err := beginTx(ctx, pool, i.acquireTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
 	// Test query with one row that will be rolled back
-	_, err = tx.Exec(ctx, fmt.Sprintf(`
+	_, err = tx.Exec(ctx, i.timeColumn.rewrite(fmt.Sprintf(`
         INSERT INTO %s (
             signature, slot, block_time, nft_mint, nft_name, marketplace, 
             price, currency, seller, status
         ) VALUES (
-            'test-signature-to-rollback', 0, NOW(), 'test-mint', 'Test NFT', 'TEST', 
+            'test-signature-to-rollback', 0, %s, 'test-mint', 'Test NFT', 'TEST', 
             0, 'SOL', 'test-seller', 'test'
         )
-    `, targetTable))
+    `, targetTable, i.timeColumn.now())))
 
 	if err != nil {
 		return fmt.Errorf("failed to execute test insert: %w", err)
@@ -1978,6 +2062,8 @@ func (i *NFTPriceIndexer) validateDatabaseConnection(ctx context.Context, pool *
 }
 
 func (i *NFTPriceIndexer) validateDatabaseSetup(ctx context.Context, pool *pgxpool.Pool, targetTable string) {
+	targetTable = tableName(targetTable)
+
 	// Check the database connection
 	if err := pool.Ping(ctx); err != nil {
 		log.Error().Err(err).Msg("⚠️ Failed to ping database")
@@ -1987,7 +2073,7 @@ func (i *NFTPriceIndexer) validateDatabaseSetup(ctx context.Context, pool *pgxpo
 	log.Info().Msg("✅ Database connection is healthy")
 
 	// Check table existence
-	conn, err := pool.Acquire(ctx)
+	conn, err := acquireConn(ctx, pool, i.acquireTimeout)
 	if err != nil {
 		log.Error().Err(err).Msg("⚠️ Failed to acquire connection")
 		return
@@ -2063,20 +2149,20 @@ func (i *NFTPriceIndexer) validateDatabaseSetup(ctx context.Context, pool *pgxpo
 	log.Info().Interface("columns", columns).Msg("✅ Table schema validated")
 
 	// Attempt a test insert and rollback
-	tx, err := pool.Begin(ctx)
+	tx, err := beginTx(ctx, pool, i.acquireTimeout)
 	if err != nil {
 		log.Error().Err(err).Msg("⚠️ Failed to begin transaction for test insert")
 		return
 	}
 	defer tx.Rollback(ctx)
 
-	testInsertSQL := fmt.Sprintf(`
+	testInsertSQL := i.timeColumn.rewrite(fmt.Sprintf(`
         INSERT INTO %s (
             signature, slot, block_time, nft_mint, marketplace, price, seller, status
         ) VALUES (
-            'test-signature-to-be-rolled-back', 0, NOW(), 'test-mint', 'TEST', 0, 'test-seller', 'test'
+            'test-signature-to-be-rolled-back', 0, %s, 'test-mint', 'TEST', 0, 'test-seller', 'test'
         )
-    `, targetTable)
+    `, targetTable, i.timeColumn.now()))
 
 	_, err = tx.Exec(ctx, testInsertSQL)
 	if err != nil {
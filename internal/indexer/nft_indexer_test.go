@@ -0,0 +1,271 @@
+package indexer
+
+import (
+	"context"
+	"testing"
+)
+
+func listingEventData(mint string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":      "NFT_LISTING",
+		"timestamp": float64(1700000000),
+		"data": map[string]interface{}{
+			"mint":        mint,
+			"marketplace": "MAGIC_EDEN",
+			"seller":      "seller",
+			"amount":      float64(1.5),
+		},
+	}
+}
+
+func TestProcessListingEventWritesOneRowPerSignature(t *testing.T) {
+	pool := testPool(t)
+	table := testTable(t, pool)
+	idx := newTestPriceIndexer(t, `{"collection": "collection"}`)
+	initializeTable(t, pool, idx, table)
+
+	ctx := context.Background()
+	for range 2 {
+		if err := idx.processListingEvent(ctx, pool, table, listingEventData("mint-1"), 1, "listing-sig"); err != nil {
+			t.Fatalf("processListingEvent: %v", err)
+		}
+	}
+
+	var count int
+	if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+QuoteTableName(table)+" WHERE signature = 'listing-sig'").Scan(&count); err != nil {
+		t.Fatalf("count rows: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("got %d rows for the listing signature, want 1", count)
+	}
+}
+
+func TestProcessListingEventReturnsInsertError(t *testing.T) {
+	pool := testPool(t)
+	table := testTable(t, pool)
+	idx := newTestPriceIndexer(t, `{"collection": "collection"}`)
+	initializeTable(t, pool, idx, table)
+
+	ctx := context.Background()
+	if _, err := pool.Exec(ctx, "DROP TABLE "+QuoteTableName(table)); err != nil {
+		t.Fatalf("drop table: %v", err)
+	}
+
+	if err := idx.processListingEvent(ctx, pool, table, listingEventData("mint-1"), 1, "listing-sig"); err == nil {
+		t.Fatal("processListingEvent into a missing table returned no error")
+	}
+}
+
+func TestInsertPriceEventsStoresAbsentOptionalFieldsAsNull(t *testing.T) {
+	pool := testPool(t)
+	table := testTable(t, pool)
+	idx := newTestPriceIndexer(t, `{"collection": "collection"}`)
+	initializeTable(t, pool, idx, table)
+
+	ctx := context.Background()
+	if err := idx.InsertPriceEvents(ctx, pool, table, listingEvents("listing", 1)); err != nil {
+		t.Fatalf("InsertPriceEvents: %v", err)
+	}
+
+	var nameIsNull, buyerIsNull bool
+	err := pool.QueryRow(ctx, "SELECT nft_name IS NULL, buyer IS NULL FROM "+QuoteTableName(table)+" WHERE signature = 'listing-0'").
+		Scan(&nameIsNull, &buyerIsNull)
+	if err != nil {
+		t.Fatalf("read row: %v", err)
+	}
+	if !nameIsNull || !buyerIsNull {
+		t.Errorf("nft_name NULL = %v, buyer NULL = %v, want both NULL rather than empty strings", nameIsNull, buyerIsNull)
+	}
+}
+
+func TestProcessBidEventStoresMissingAuctionHouseAsNull(t *testing.T) {
+	pool := testPool(t)
+	table := testTable(t, pool)
+	idx := newTestBidIndexer(t, `{"collection": "collection"}`)
+	initializeTable(t, pool, idx, table)
+
+	ctx := context.Background()
+	bid := map[string]interface{}{
+		"type":      "NFT_BID",
+		"timestamp": float64(1700000000),
+		"data": map[string]interface{}{
+			"mint":        "mint-1",
+			"marketplace": "MAGIC_EDEN",
+			"bidder":      "bidder",
+			"amount":      float64(1.5),
+		},
+	}
+	if err := idx.processBidEvent(ctx, pool, table, bid, 1, "bid-sig"); err != nil {
+		t.Fatalf("processBidEvent: %v", err)
+	}
+
+	var auctionHouseIsNull bool
+	if err := pool.QueryRow(ctx, "SELECT auction_house IS NULL FROM "+QuoteTableName(table)+" WHERE signature = 'bid-sig'").Scan(&auctionHouseIsNull); err != nil {
+		t.Fatalf("read row: %v", err)
+	}
+	if !auctionHouseIsNull {
+		t.Error("auction_house stored as an empty string, want NULL")
+	}
+}
+
+func TestInitializeClearsEmptyOptionalColumns(t *testing.T) {
+	pool := testPool(t)
+	table := testTable(t, pool)
+	idx := newTestPriceIndexer(t, `{"collection": "collection"}`)
+	initializeTable(t, pool, idx, table)
+
+	ctx := context.Background()
+	if err := idx.InsertPriceEvents(ctx, pool, table, listingEvents("listing", 1)); err != nil {
+		t.Fatalf("InsertPriceEvents: %v", err)
+	}
+	// Rows written before absent values were stored as NULL
+	if _, err := pool.Exec(ctx, "UPDATE "+QuoteTableName(table)+" SET nft_name = '', buyer = ''"); err != nil {
+		t.Fatalf("write empty strings: %v", err)
+	}
+
+	initializeTable(t, pool, idx, table)
+
+	var empty int
+	if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+QuoteTableName(table)+" WHERE nft_name = '' OR buyer = ''").Scan(&empty); err != nil {
+		t.Fatalf("count rows: %v", err)
+	}
+	if empty != 0 {
+		t.Errorf("%d rows still hold empty strings after initializing again, want them rewritten to NULL", empty)
+	}
+}
+
+func TestNullableTextTreatsEmptyAsNull(t *testing.T) {
+	if got := nullableText(""); got.Valid {
+		t.Errorf("nullableText(\"\") = %+v, want NULL", got)
+	}
+	if got := nullableText("name"); !got.Valid || got.String != "name" {
+		t.Errorf("nullableText(\"name\") = %+v, want a valid name", got)
+	}
+}
+
+func TestShortMintName(t *testing.T) {
+	tests := map[string]string{
+		"FeqSmGZFHFQLmQhCVVnGMYbAvtMLkvv8bSmo4S5ewpfK": "NFT Feq...pfK",
+		"ÅßçDéf": "NFT Åßç...Déf",
+		"abcdef": "NFT abc...def",
+		"abcde":  "",
+		"":       "",
+	}
+
+	for mint, want := range tests {
+		if got := shortMintName(mint); got != want {
+			t.Errorf("shortMintName(%q) = %q, want %q", mint, got, want)
+		}
+	}
+}
+
+func TestParseSaleEventNamesUnnamedNFTByMint(t *testing.T) {
+	idx := newTestPriceIndexer(t, `{"collection": "collection"}`)
+	event := map[string]interface{}{
+		"type":      "NFT_SALE",
+		"timestamp": float64(1700000000),
+		"data": map[string]interface{}{
+			"mint":        "FeqSmGZFHFQLmQhCVVnGMYbAvtMLkvv8bSmo4S5ewpfK",
+			"marketplace": "MAGIC_EDEN",
+			"seller":      "seller",
+			"buyer":       "buyer",
+			"amount":      float64(2.5),
+		},
+	}
+
+	sale, outcome := idx.parseSaleEvent(context.Background(), event, 1, "sale-sig")
+	if outcome != priceEventParsed {
+		t.Fatalf("outcome = %v, want the sale parsed", outcome)
+	}
+	if sale.Name != "NFT Feq...pfK" {
+		t.Errorf("name = %q, want the short mint name", sale.Name)
+	}
+}
+
+func TestParseListingEventMatchesHeliusMarketplaceSources(t *testing.T) {
+	idx := newTestPriceIndexer(t, `{"collection": "collection", "marketplaces": ["magic-eden", "TensorSwap"]}`)
+	if len(idx.Marketplaces) != 2 || idx.Marketplaces[0] != "MAGIC_EDEN" || idx.Marketplaces[1] != "TENSOR" {
+		t.Fatalf("Marketplaces = %v, want [MAGIC_EDEN TENSOR]", idx.Marketplaces)
+	}
+
+	tests := []struct {
+		source  string
+		skipped bool
+	}{
+		{source: "MAGIC_EDEN"},
+		{source: "MAGIC_EDEN_V2"},
+		{source: "TENSOR"},
+		{source: "TENSOR_CNFT"},
+		{source: "SOLANART", skipped: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			event := listingEventData("mint-1")
+			event["data"].(map[string]interface{})["marketplace"] = tt.source
+
+			_, outcome := idx.parseListingEvent(context.Background(), event, 1, "listing-sig")
+			if skipped := outcome == priceEventSkipped; skipped != tt.skipped {
+				t.Errorf("listing from %s skipped = %v, want %v", tt.source, skipped, tt.skipped)
+			}
+		})
+	}
+}
+
+func TestListingWithoutMintNeverStoresCollection(t *testing.T) {
+	const collection = "J1S9H3QjnRtBbbuD4HjPV6RpRhwuk4zKbxsnCHuTgh9w"
+	const description = "4wv6eShMW5ReztgeYd3kQHqbm4joY8QUpnS3SkdDhwyX listed Ivy #268 for 11.24999 SOL on MAGIC_EDEN."
+
+	tests := []struct {
+		unresolvedMint string
+		wantRows       int
+	}{
+		{unresolvedMint: "", wantRows: 0},
+		{unresolvedMint: "skip", wantRows: 0},
+		{unresolvedMint: "store", wantRows: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run("unresolvedMint="+tt.unresolvedMint, func(t *testing.T) {
+			pool := testPool(t)
+			table := testTable(t, pool)
+			idx := newTestPriceIndexer(t, `{"collection": "`+collection+`", "unresolvedMint": "`+tt.unresolvedMint+`"}`)
+			initializeTable(t, pool, idx, table)
+
+			ctx := context.Background()
+			eventData := map[string]interface{}{"description": description, "timestamp": float64(1700000000)}
+			if err := idx.processListingFromDescription(ctx, pool, table, description, eventData, 1, "unresolved-sig"); err != nil {
+				t.Fatalf("processListingFromDescription: %v", err)
+			}
+
+			var collectionRows int
+			if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+QuoteTableName(table)+" WHERE nft_mint = $1", collection).Scan(&collectionRows); err != nil {
+				t.Fatalf("count collection rows: %v", err)
+			}
+			if collectionRows != 0 {
+				t.Errorf("got %d rows with the collection address as nft_mint, want none", collectionRows)
+			}
+
+			rows, err := pool.Query(ctx, "SELECT nft_mint IS NULL, mint_resolved FROM "+QuoteTableName(table)+" WHERE signature = 'unresolved-sig'")
+			if err != nil {
+				t.Fatalf("read listing: %v", err)
+			}
+			defer rows.Close()
+
+			var count int
+			for rows.Next() {
+				count++
+				var mintNull, resolved bool
+				if err := rows.Scan(&mintNull, &resolved); err != nil {
+					t.Fatalf("scan listing: %v", err)
+				}
+				if !mintNull || resolved {
+					t.Errorf("nft_mint NULL = %v, mint_resolved = %v; want a NULL mint marked unresolved", mintNull, resolved)
+				}
+			}
+			if count != tt.wantRows {
+				t.Errorf("got %d rows for the listing, want %d", count, tt.wantRows)
+			}
+		})
+	}
+}
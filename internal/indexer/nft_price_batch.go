@@ -0,0 +1,390 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// NFTPriceEvent is a parsed NFT listing or sale ready to be written to an
+// NFT price target table
+type NFTPriceEvent struct {
+	Signature   string
+	Slot        int64
+	BlockTime   time.Time
+	Mint        string
+	AssetID     string
+	Name        string
+	Marketplace string
+	Price       float64
+	Currency    string
+	USDValue    float64
+	Seller      string
+	Buyer       string
+	Status      string
+}
+
+// priceEventOutcome is what parsing a listing or sale event came to
+type priceEventOutcome int
+
+const (
+	priceEventParsed priceEventOutcome = iota
+	// priceEventSkipped events are filtered out or can't be stored
+	priceEventSkipped
+	// priceEventIncomplete listings lack essential fields but carry a
+	// description they may be recovered from
+	priceEventIncomplete
+)
+
+// nftPriceBatchColumns are the staging table columns, in CopyFrom order
+var nftPriceBatchColumns = []string{
+	"signature", "slot", "block_time", "nft_mint", "nft_name", "marketplace",
+	"price", "currency", "usd_value", "seller", "buyer", "status", "asset_id",
+	"mint_resolved",
+}
+
+// ProcessPayloads writes a page of payloads, such as a backfill page, in as
+// few round trips as possible. The listings and sales of consecutive payloads
+// go through InsertPriceEvents together; a payload that needs the per-event
+// path, such as a cancellation or a listing only described in text, flushes
+// the batch and is processed on its own so the order of events is kept.
+func (i *NFTPriceIndexer) ProcessPayloads(ctx context.Context, pool *pgxpool.Pool, targetTable string, payloads []models.HeliusWebhookPayload) (ProcessResult, error) {
+	tally := &processTally{}
+	ctx = context.WithValue(ctx, processTallyKey{}, tally)
+
+	var eventTypes []string
+	seen := make(map[string]bool)
+	result := func() ProcessResult {
+		return ProcessResult{
+			RowsAffected: tally.rows.Load(),
+			EventTypes:   eventTypes,
+			Matched:      tally.matched.Load(),
+		}
+	}
+
+	var batch []NFTPriceEvent
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := i.InsertPriceEvents(ctx, pool, targetTable, batch)
+		batch = nil
+		return err
+	}
+
+	for _, payload := range payloads {
+		for _, eventType := range payloadEventTypes(payload) {
+			if !seen[eventType] {
+				seen[eventType] = true
+				eventTypes = append(eventTypes, eventType)
+			}
+		}
+
+		payloadCtx := ctx
+		if blockTime, ok := payloadBlockTime(payload); ok {
+			payloadCtx = context.WithValue(ctx, blockTimeKey{}, blockTime)
+		}
+
+		events, ok := i.priceEvents(payloadCtx, payload)
+		if ok {
+			batch = append(batch, events...)
+			continue
+		}
+
+		if err := flush(); err != nil {
+			return result(), err
+		}
+		if err := i.processPayload(payloadCtx, pool, targetTable, payload); err != nil {
+			return result(), err
+		}
+	}
+
+	return result(), flush()
+}
+
+// priceEvents parses the listings and sales of a payload for a batch. It
+// reports false when the payload needs the per-event path.
+func (i *NFTPriceIndexer) priceEvents(ctx context.Context, payload models.HeliusWebhookPayload) ([]NFTPriceEvent, bool) {
+	if len(payload.Transaction.Signatures) == 0 {
+		return nil, true
+	}
+	signature := payload.Transaction.ID
+	if signature == "" {
+		signature = payload.Transaction.Signatures[0]
+	}
+
+	details, err := models.ParseEnhancedTransaction(payload.Transaction.EnhancedDetails)
+	if err != nil {
+		return nil, false
+	}
+
+	if details.Type == "NFT_CANCEL_LISTING" {
+		return nil, false
+	}
+	events := []models.EnhancedEvent{{Type: details.Type, Raw: details.Raw}}
+	if details.Type != "NFT_LISTING" && details.Type != "NFT_SALE" {
+		events = details.Events.List
+		if len(events) == 0 {
+			return nil, false
+		}
+	}
+
+	var parsed []NFTPriceEvent
+	for _, event := range events {
+		var priceEvent NFTPriceEvent
+		var outcome priceEventOutcome
+		if event.Type == "NFT_LISTING" {
+			priceEvent, outcome = i.parseListingEvent(ctx, event.Raw, payload.Slot, signature)
+		} else if event.Type == "NFT_SALE" {
+			priceEvent, outcome = i.parseSaleEvent(ctx, event.Raw, payload.Slot, signature)
+		} else if event.Type == "NFT_CANCEL_LISTING" {
+			return nil, false
+		} else {
+			continue
+		}
+
+		if outcome == priceEventIncomplete {
+			return nil, false
+		}
+		if outcome == priceEventParsed {
+			parsed = append(parsed, priceEvent)
+		}
+	}
+	return parsed, true
+}
+
+// processPriceEvents writes the events array of a transaction. Listings and
+// sales are collected and written with InsertPriceEvents; a cancellation or a
+// listing that has to be recovered from its description flushes what was
+// collected first, so events still apply in order.
+func (i *NFTPriceIndexer) processPriceEvents(ctx context.Context, pool *pgxpool.Pool, targetTable string, events []models.EnhancedEvent, slot int64, signature string) error {
+	var batch []NFTPriceEvent
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := i.InsertPriceEvents(ctx, pool, targetTable, batch)
+		batch = nil
+		return err
+	}
+
+	for idx, typed := range events {
+		event, eventType := typed.Raw, typed.Type
+		if eventType == "" {
+			continue
+		}
+
+		log.Debug().
+			Str("signature", signature).
+			Int("eventIndex", idx).
+			Str("eventType", eventType).
+			Msg("Processing event from array")
+
+		if eventType == "NFT_LISTING" {
+			listing, outcome := i.parseListingEvent(ctx, event, slot, signature)
+			if outcome == priceEventParsed {
+				batch = append(batch, listing)
+			} else if outcome == priceEventIncomplete {
+				if err := flush(); err != nil {
+					return err
+				}
+				if err := i.processListingEvent(ctx, pool, targetTable, event, slot, signature); err != nil {
+					log.Error().Err(err).Str("signature", signature).Msg("Failed to process NFT listing")
+					return err
+				}
+			}
+		} else if eventType == "NFT_SALE" {
+			sale, outcome := i.parseSaleEvent(ctx, event, slot, signature)
+			if outcome == priceEventParsed {
+				batch = append(batch, sale)
+			}
+		} else if eventType == "NFT_CANCEL_LISTING" {
+			if err := flush(); err != nil {
+				return err
+			}
+			if err := i.processCancelListingEvent(ctx, pool, targetTable, event, slot, signature); err != nil {
+				log.Error().Err(err).Str("signature", signature).Msg("Failed to process NFT listing cancellation")
+				return err
+			}
+		}
+	}
+
+	return flush()
+}
+
+// InsertPriceEvents writes a batch of listings and sales in a single
+// transaction. Rows are streamed with COPY into a temporary staging table and
+// then upserted into the target table keyed on signature, so it is safe to
+// replay the same batch. When a batch holds the same signature more than once
+// the row with the highest slot wins.
+//
+// Like processSaleEvent, a sale turns the open listing of its mint by the same
+// seller on the same marketplace into the sale; listings of the batch are
+// written first so a sale later in the batch finds them. A compressed NFT
+// without a mint is stored under its asset ID, and an event with neither is
+// only kept, with mint_resolved false, when the indexer stores unresolved
+// mints.
+func (i *NFTPriceIndexer) InsertPriceEvents(ctx context.Context, pool *pgxpool.Pool, targetTable string, events []NFTPriceEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	targetTable = QuoteTableName(targetTable)
+
+	tx, err := beginTx(ctx, pool, i.acquireTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		CREATE TEMP TABLE nft_price_batch (
+			signature TEXT,
+			slot BIGINT,
+			block_time TIMESTAMPTZ,
+			nft_mint TEXT,
+			nft_name TEXT,
+			marketplace TEXT,
+			price DOUBLE PRECISION,
+			currency TEXT,
+			usd_value DOUBLE PRECISION,
+			seller TEXT,
+			buyer TEXT,
+			status TEXT,
+			asset_id TEXT,
+			mint_resolved BOOLEAN
+		) ON COMMIT DROP
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create staging table: %w", err)
+	}
+
+	copied, err := tx.CopyFrom(ctx,
+		pgx.Identifier{"nft_price_batch"},
+		nftPriceBatchColumns,
+		pgx.CopyFromSlice(len(events), func(idx int) ([]any, error) {
+			e := events[idx]
+			if e.Signature == "" {
+				return nil, fmt.Errorf("event %d has no signature", idx)
+			}
+
+			mint := e.Mint
+			if mint == "" {
+				mint = e.AssetID
+			}
+			if mint == "" && !i.storeUnresolvedMint {
+				return nil, fmt.Errorf("event %s has no mint address or asset ID", e.Signature)
+			}
+
+			blockTime := e.BlockTime
+			if blockTime.IsZero() {
+				blockTime = time.Now()
+			}
+			currency := e.Currency
+			if currency == "" {
+				currency = "SOL"
+			}
+
+			return []any{
+				e.Signature, e.Slot, blockTime, nullableText(mint), nullableText(e.Name), e.Marketplace,
+				e.Price, currency, e.USDValue, e.Seller, nullableText(e.Buyer), e.Status,
+				nullableText(e.AssetID), mint != "",
+			}, nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to copy events into staging table: %w", err)
+	}
+
+	// The staging table always holds TIMESTAMPTZ; convert on the way into the
+	// configured time column
+	upsert := func(status string) (int64, error) {
+		result, err := tx.Exec(ctx, fmt.Sprintf(`
+			INSERT INTO %[1]s (
+				signature, slot, %[2]s, nft_mint, nft_name, marketplace,
+				price, currency, usd_value, seller, buyer, status, asset_id, mint_resolved
+			)
+			SELECT DISTINCT ON (signature)
+				signature, slot, %[3]s, nft_mint, nft_name, marketplace,
+				price, currency, usd_value, seller, buyer, status, asset_id, mint_resolved
+			FROM nft_price_batch
+			WHERE status = $1
+			ORDER BY signature, slot DESC
+			ON CONFLICT (signature)
+			DO UPDATE SET
+				nft_mint = EXCLUDED.nft_mint,
+				asset_id = EXCLUDED.asset_id,
+				mint_resolved = EXCLUDED.mint_resolved,
+				nft_name = COALESCE(EXCLUDED.nft_name, %[1]s.nft_name),
+				marketplace = EXCLUDED.marketplace,
+				price = EXCLUDED.price,
+				currency = EXCLUDED.currency,
+				usd_value = EXCLUDED.usd_value,
+				seller = EXCLUDED.seller,
+				buyer = COALESCE(EXCLUDED.buyer, %[1]s.buyer),
+				status = EXCLUDED.status,
+				slot = EXCLUDED.slot,
+				%[2]s = EXCLUDED.%[2]s,
+				updated_at = NOW()
+		`, targetTable, i.timeColumn.name, i.timeColumn.fromTimestamptz("block_time")), status)
+		if err != nil {
+			return 0, fmt.Errorf("failed to upsert %s events into %s: %w", status, targetTable, err)
+		}
+		return result.RowsAffected(), nil
+	}
+
+	listed, err := upsert("listed")
+	if err != nil {
+		return err
+	}
+
+	// Sold listings take the sale's signature, so the sale upsert below
+	// updates them instead of adding a second row
+	promoted, err := tx.Exec(ctx, fmt.Sprintf(`
+		UPDATE %[1]s AS t SET
+			status = 'sold',
+			buyer = b.buyer,
+			slot = b.slot,
+			%[2]s = %[3]s,
+			signature = b.signature,
+			updated_at = NOW()
+		FROM (
+			SELECT DISTINCT ON (nft_mint, seller, marketplace) *
+			FROM nft_price_batch
+			WHERE status = 'sold' AND nft_mint IS NOT NULL
+			ORDER BY nft_mint, seller, marketplace, slot DESC
+		) AS b
+		WHERE t.nft_mint = b.nft_mint AND t.seller = b.seller AND t.status = 'listed'
+		AND t.marketplace = b.marketplace
+		AND NOT EXISTS (SELECT 1 FROM %[1]s AS s WHERE s.signature = b.signature)
+	`, targetTable, i.timeColumn.name, i.timeColumn.fromTimestamptz("b.block_time")))
+	if err != nil {
+		return fmt.Errorf("failed to mark sold listings in %s: %w", targetTable, err)
+	}
+
+	sold, err := upsert("sold")
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	recordRows(ctx, listed+sold)
+
+	log.Info().
+		Str("table", targetTable).
+		Int64("copied", copied).
+		Int64("listed", listed).
+		Int64("promoted", promoted.RowsAffected()).
+		Int64("sold", sold).
+		Msg("Flushed NFT price event batch")
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func newTestPriceIndexer(tb testing.TB, params string) *NFTPriceIndexer {
+	tb.Helper()
+
+	idx, err := NewNFTPriceIndexer("test", json.RawMessage(params))
+	if err != nil {
+		tb.Fatalf("NewNFTPriceIndexer: %v", err)
+	}
+	return idx.(*NFTPriceIndexer)
+}
+
+func listingEvents(prefix string, n int) []NFTPriceEvent {
+	events := make([]NFTPriceEvent, n)
+	for j := range events {
+		events[j] = NFTPriceEvent{
+			Signature:   fmt.Sprintf("%s-%d", prefix, j),
+			Slot:        int64(j + 1),
+			BlockTime:   time.Unix(1700000000, 0),
+			Mint:        fmt.Sprintf("mint-%d", j),
+			Marketplace: "MAGIC_EDEN",
+			Price:       1.5,
+			Currency:    "SOL",
+			Seller:      "seller",
+			Status:      "listed",
+		}
+	}
+	return events
+}
+
+func TestInsertPriceEventsStoresAssetIDWithoutMint(t *testing.T) {
+	pool := testPool(t)
+	table := testTable(t, pool)
+	idx := newTestPriceIndexer(t, `{"collection": "collection"}`)
+	initializeTable(t, pool, idx, table)
+
+	ctx := context.Background()
+	events := []NFTPriceEvent{{
+		Signature:   "cnft-listing",
+		Slot:        1,
+		AssetID:     "asset-1",
+		Marketplace: "TENSOR",
+		Price:       2,
+		Seller:      "seller",
+		Status:      "listed",
+	}}
+	if err := idx.InsertPriceEvents(ctx, pool, table, events); err != nil {
+		t.Fatalf("InsertPriceEvents: %v", err)
+	}
+
+	var mint, assetID string
+	var resolved bool
+	err := pool.QueryRow(ctx, "SELECT nft_mint, asset_id, mint_resolved FROM "+QuoteTableName(table)+" WHERE signature = 'cnft-listing'").
+		Scan(&mint, &assetID, &resolved)
+	if err != nil {
+		t.Fatalf("read row: %v", err)
+	}
+	if mint != "asset-1" || assetID != "asset-1" || !resolved {
+		t.Errorf("got mint %q, asset %q, resolved %v; want the asset ID as a resolved mint", mint, assetID, resolved)
+	}
+}
+
+func TestInsertPriceEventsRejectsUnresolvedMint(t *testing.T) {
+	pool := testPool(t)
+	table := testTable(t, pool)
+	idx := newTestPriceIndexer(t, `{"collection": "collection"}`)
+	initializeTable(t, pool, idx, table)
+
+	events := []NFTPriceEvent{{Signature: "no-mint", Slot: 1, Marketplace: "TENSOR", Price: 2, Seller: "seller", Status: "listed"}}
+	if err := idx.InsertPriceEvents(context.Background(), pool, table, events); err == nil {
+		t.Fatal("InsertPriceEvents stored an event without mint or asset ID")
+	}
+}
+
+func TestInsertPriceEventsPromotesListingToSale(t *testing.T) {
+	pool := testPool(t)
+	table := testTable(t, pool)
+	idx := newTestPriceIndexer(t, `{"collection": "collection"}`)
+	initializeTable(t, pool, idx, table)
+
+	ctx := context.Background()
+	listing := listingEvents("listing", 1)[0]
+	sale := listing
+	sale.Signature = "sale"
+	sale.Slot = 10
+	sale.Buyer = "buyer"
+	sale.Status = "sold"
+
+	if err := idx.InsertPriceEvents(ctx, pool, table, []NFTPriceEvent{listing, sale}); err != nil {
+		t.Fatalf("InsertPriceEvents: %v", err)
+	}
+
+	var count int
+	var status, buyer string
+	err := pool.QueryRow(ctx, "SELECT COUNT(*), MAX(status), MAX(buyer) FROM "+QuoteTableName(table)).Scan(&count, &status, &buyer)
+	if err != nil {
+		t.Fatalf("read rows: %v", err)
+	}
+	if count != 1 || status != "sold" || buyer != "buyer" {
+		t.Errorf("got %d rows, status %q, buyer %q; want the listing turned into one sold row", count, status, buyer)
+	}
+}
+
+// BenchmarkInsertPriceEvents compares writing 1,000 listings one transaction
+// at a time with writing them in one batch
+func BenchmarkInsertPriceEvents(b *testing.B) {
+	pool := testPool(b)
+	table := testTable(b, pool)
+	idx := newTestPriceIndexer(b, `{"collection": "collection"}`)
+	initializeTable(b, pool, idx, table)
+
+	level := zerolog.GlobalLevel()
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	b.Cleanup(func() { zerolog.SetGlobalLevel(level) })
+
+	ctx := context.Background()
+	const listings = 1000
+
+	b.Run("per-row", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			for _, event := range listingEvents(fmt.Sprintf("row-%d", n), listings) {
+				eventData := map[string]interface{}{
+					"mint":        event.Mint,
+					"seller":      event.Seller,
+					"amount":      event.Price,
+					"marketplace": event.Marketplace,
+				}
+				if err := idx.processListingEvent(ctx, pool, table, eventData, event.Slot, event.Signature); err != nil {
+					b.Fatalf("processListingEvent: %v", err)
+				}
+			}
+		}
+	})
+
+	b.Run("batched", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if err := idx.InsertPriceEvents(ctx, pool, table, listingEvents(fmt.Sprintf("batch-%d", n), listings)); err != nil {
+				b.Fatalf("InsertPriceEvents: %v", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package indexer
+
+import (
+	"strings"
+
+	"github.com/rishavmehra/indexer/pkg/validator"
+)
+
+// tensorMarketplace is the Helius source name of Tensor
+const tensorMarketplace = "TENSOR"
+
+// lamportsPerSOL converts the lamport amounts of Helius events to SOL
+const lamportsPerSOL = 1_000_000_000
+
+// tensorEvent holds what extractTensorEvent pulls out of a Tensor listing or
+// sale. For compressed NFTs Mint is the asset ID.
+type tensorEvent struct {
+	Mint       string
+	Seller     string
+	Buyer      string
+	Price      float64
+	Compressed bool
+}
+
+// extractTensorEvent reads a Tensor transaction from its Helius event shape:
+// the NFT event under events.nft (or the event itself when it came from an
+// events array) with the price in lamports, and for compressed NFTs the asset
+// ID and leaf owners under events.compressed. It reports false for anything
+// not sourced from Tensor.
+func extractTensorEvent(eventData map[string]interface{}) (tensorEvent, bool) {
+	nftEvent := eventData
+	if events, ok := eventData["events"].(map[string]interface{}); ok {
+		if nft, ok := events["nft"].(map[string]interface{}); ok {
+			nftEvent = nft
+		}
+	}
+
+	if !isTensorSource(eventData) && !isTensorSource(nftEvent) {
+		return tensorEvent{}, false
+	}
+
+	var event tensorEvent
+
+	if nfts, ok := nftEvent["nfts"].([]interface{}); ok && len(nfts) > 0 {
+		if nft, ok := nfts[0].(map[string]interface{}); ok {
+			event.Mint, _ = nft["mint"].(string)
+			if standard, _ := nft["tokenStandard"].(string); strings.EqualFold(standard, "Compressed") {
+				event.Compressed = true
+			}
+		}
+	}
+
+	event.Seller, _ = nftEvent["seller"].(string)
+	event.Buyer, _ = nftEvent["buyer"].(string)
+
+	if amount, ok := nftEvent["amount"].(float64); ok && amount > 0 {
+		event.Price = amount / lamportsPerSOL
+	}
+
+	if compressed := compressedEvent(eventData); compressed != nil {
+		event.Compressed = true
+		if event.Mint == "" {
+			event.Mint, _ = compressed["assetId"].(string)
+		}
+		if event.Seller == "" {
+			event.Seller, _ = compressed["oldLeafOwner"].(string)
+		}
+		if event.Buyer == "" {
+			event.Buyer, _ = compressed["newLeafOwner"].(string)
+		}
+	}
+
+	// A listing signed by the owner names no seller in the event
+	if event.Seller == "" {
+		event.Seller, _ = eventData["feePayer"].(string)
+	}
+
+	return event, true
+}
+
+// isTensorSource reports whether an event names Tensor as its source or marketplace
+func isTensorSource(data map[string]interface{}) bool {
+	for _, key := range []string{"source", "marketplace"} {
+		if source, ok := data[key].(string); ok && validator.CanonicalMarketplace(source) == tensorMarketplace {
+			return true
+		}
+	}
+	return false
+}
+
+// marketplaceAllowed applies the configured marketplace filter
+func (i *NFTPriceIndexer) marketplaceAllowed(marketplace string) bool {
+	if len(i.Marketplaces) == 0 {
+		return true
+	}
+	for _, m := range i.Marketplaces {
+		if validator.CanonicalMarketplace(marketplace) == m {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tensorFixture loads a Tensor transaction captured from a Helius enhanced webhook
+func tensorFixture(t *testing.T, name string) map[string]interface{} {
+	t.Helper()
+
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	var eventData map[string]interface{}
+	if err := json.Unmarshal(body, &eventData); err != nil {
+		t.Fatalf("unmarshal fixture %s: %v", name, err)
+	}
+	return eventData
+}
+
+func TestExtractTensorEvent(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    tensorEvent
+	}{
+		{
+			fixture: "tensor_listing.json",
+			want: tensorEvent{
+				Mint:   "FeqSmGZFHFQLmQhCVVnGMYbAvtMLkvv8bSmo4S5ewpfK",
+				Seller: "8Hyq5kKrTVbWqzwNNn8CiYkxAqvVjhaKXNiYRUb4mVp4",
+				Price:  142.5,
+			},
+		},
+		{
+			fixture: "tensor_sale.json",
+			want: tensorEvent{
+				Mint:   "7MsmqQ4Ns5BqQ2Yb6p8ZkYhZ5mQdUXkJ1nMDkUGJhHdR",
+				Seller: "3jfKqzFm1yGFEDu7sQwUaWNt7cF6ZCnkHWmh4dpRZbVA",
+				Buyer:  "6Rg8Bm5yjGMRpYNLvwS8qkHX5BvCnZz4kbDDsNPDGr3u",
+				Price:  139,
+			},
+		},
+		{
+			fixture: "tensor_cnft_sale.json",
+			want: tensorEvent{
+				Mint:       "6pKfzXcGNqDHyV3bdD7X6RuNHTuMBSUQ6rUbt3hsXXdD",
+				Seller:     "SeLLeR8pHwN4rZbT6yJkQ2vMf9cLd3aXs7uEg5oWi1Kn",
+				Buyer:      "BuYeR5qLJvTc2WfJkR8xNhD3pMa9sEoZgU7yVbKtQwXn",
+				Price:      2.75,
+				Compressed: true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			got, ok := extractTensorEvent(tensorFixture(t, tt.fixture))
+			if !ok {
+				t.Fatal("Tensor transaction not recognized")
+			}
+			if got != tt.want {
+				t.Errorf("extractTensorEvent = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractTensorEventIgnoresOtherSources(t *testing.T) {
+	eventData := tensorFixture(t, "tensor_sale.json")
+	eventData["source"] = "MAGIC_EDEN"
+	eventData["events"].(map[string]interface{})["nft"].(map[string]interface{})["source"] = "MAGIC_EDEN"
+
+	if _, ok := extractTensorEvent(eventData); ok {
+		t.Error("Magic Eden sale taken for a Tensor one")
+	}
+}
+
+func TestParseTensorListing(t *testing.T) {
+	idx := newTestPriceIndexer(t, `{"collection": "collection"}`)
+
+	event, outcome := idx.parseListingEvent(context.Background(), tensorFixture(t, "tensor_listing.json"), 251374821, "listing-sig")
+	if outcome != priceEventParsed {
+		t.Fatalf("outcome = %v, want the listing parsed", outcome)
+	}
+	if event.Mint != "FeqSmGZFHFQLmQhCVVnGMYbAvtMLkvv8bSmo4S5ewpfK" || event.AssetID != "" {
+		t.Errorf("mint = %q, asset ID = %q; want the NFT's mint and no asset ID", event.Mint, event.AssetID)
+	}
+	if event.Marketplace != tensorMarketplace || event.Price != 142.5 || event.Seller != "8Hyq5kKrTVbWqzwNNn8CiYkxAqvVjhaKXNiYRUb4mVp4" {
+		t.Errorf("got %s listing at %v by %s, want TENSOR at 142.5 SOL by the lister", event.Marketplace, event.Price, event.Seller)
+	}
+	if event.Status != "listed" || event.BlockTime.Unix() != 1708003200 {
+		t.Errorf("status = %q at %v, want listed at the transaction time", event.Status, event.BlockTime)
+	}
+}
+
+func TestParseTensorSales(t *testing.T) {
+	idx := newTestPriceIndexer(t, `{"collection": "collection"}`)
+
+	tests := []struct {
+		fixture string
+		mint    string
+		assetID string
+		seller  string
+		buyer   string
+		price   float64
+	}{
+		{
+			fixture: "tensor_sale.json",
+			mint:    "7MsmqQ4Ns5BqQ2Yb6p8ZkYhZ5mQdUXkJ1nMDkUGJhHdR",
+			seller:  "3jfKqzFm1yGFEDu7sQwUaWNt7cF6ZCnkHWmh4dpRZbVA",
+			buyer:   "6Rg8Bm5yjGMRpYNLvwS8qkHX5BvCnZz4kbDDsNPDGr3u",
+			price:   139,
+		},
+		{
+			fixture: "tensor_cnft_sale.json",
+			mint:    "6pKfzXcGNqDHyV3bdD7X6RuNHTuMBSUQ6rUbt3hsXXdD",
+			assetID: "6pKfzXcGNqDHyV3bdD7X6RuNHTuMBSUQ6rUbt3hsXXdD",
+			seller:  "SeLLeR8pHwN4rZbT6yJkQ2vMf9cLd3aXs7uEg5oWi1Kn",
+			buyer:   "BuYeR5qLJvTc2WfJkR8xNhD3pMa9sEoZgU7yVbKtQwXn",
+			price:   2.75,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			event, outcome := idx.parseSaleEvent(context.Background(), tensorFixture(t, tt.fixture), 1, "sale-sig")
+			if outcome != priceEventParsed {
+				t.Fatalf("outcome = %v, want the sale parsed", outcome)
+			}
+			if event.Mint != tt.mint || event.AssetID != tt.assetID {
+				t.Errorf("mint = %q, asset ID = %q; want %q, %q", event.Mint, event.AssetID, tt.mint, tt.assetID)
+			}
+			if event.Seller != tt.seller || event.Buyer != tt.buyer {
+				t.Errorf("seller = %q, buyer = %q; want %q, %q", event.Seller, event.Buyer, tt.seller, tt.buyer)
+			}
+			if event.Marketplace != tensorMarketplace || event.Price != tt.price || event.Status != "sold" {
+				t.Errorf("got %s %s at %v, want a TENSOR sale at %v", event.Marketplace, event.Status, event.Price, tt.price)
+			}
+		})
+	}
+}
+
+func TestParseTensorListingRespectsMarketplaceFilter(t *testing.T) {
+	idx := newTestPriceIndexer(t, `{"collection": "collection", "marketplaces": ["MAGIC_EDEN"]}`)
+
+	if _, outcome := idx.parseListingEvent(context.Background(), tensorFixture(t, "tensor_listing.json"), 1, "listing-sig"); outcome != priceEventSkipped {
+		t.Errorf("outcome = %v, want the Tensor listing skipped", outcome)
+	}
+}
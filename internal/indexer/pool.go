@@ -0,0 +1,69 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultAcquireTimeout bounds how long a processor waits for a target
+// database connection
+const DefaultAcquireTimeout = 5 * time.Second
+
+// ErrPoolExhausted is returned when no target database connection frees up
+// within the acquire timeout
+var ErrPoolExhausted = errors.New("database pool exhausted")
+
+// SetAcquireTimeout sets how long the indexer's processors wait for a target
+// database connection before failing with ErrPoolExhausted. Zero or a
+// negative value means DefaultAcquireTimeout.
+func (b *BaseIndexer) SetAcquireTimeout(d time.Duration) {
+	b.acquireTimeout = d
+}
+
+// beginTx starts a transaction, failing fast when the pool stays saturated.
+// The timeout only covers acquiring the connection and BEGIN; statements run
+// in the transaction use ctx as usual. A zero timeout means
+// DefaultAcquireTimeout.
+func beginTx(ctx context.Context, pool *pgxpool.Pool, timeout time.Duration) (pgx.Tx, error) {
+	if timeout <= 0 {
+		timeout = DefaultAcquireTimeout
+	}
+	acquireCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	tx, err := pool.Begin(acquireCtx)
+	if err != nil {
+		return nil, acquireError(ctx, acquireCtx, timeout, err)
+	}
+	return tx, nil
+}
+
+// acquireConn takes a connection from the pool, failing fast when the pool
+// stays saturated
+func acquireConn(ctx context.Context, pool *pgxpool.Pool, timeout time.Duration) (*pgxpool.Conn, error) {
+	if timeout <= 0 {
+		timeout = DefaultAcquireTimeout
+	}
+	acquireCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := pool.Acquire(acquireCtx)
+	if err != nil {
+		return nil, acquireError(ctx, acquireCtx, timeout, err)
+	}
+	return conn, nil
+}
+
+// acquireError reports ErrPoolExhausted when the acquire timeout, rather than
+// the caller's own context, cut the wait short
+func acquireError(ctx, acquireCtx context.Context, timeout time.Duration, err error) error {
+	if ctx.Err() == nil && errors.Is(acquireCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: no connection available within %s", ErrPoolExhausted, timeout)
+	}
+	return err
+}
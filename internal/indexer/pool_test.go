@@ -0,0 +1,95 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// exhaustedPool returns a single connection pool whose only connection is
+// held until the test ends
+func exhaustedPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatalf("parse test database URL: %v", err)
+	}
+	config.MaxConns = 1
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		t.Fatalf("connect to test database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	held, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire connection: %v", err)
+	}
+	t.Cleanup(held.Release)
+
+	return pool
+}
+
+func TestBeginTxFailsWhenPoolIsExhausted(t *testing.T) {
+	pool := exhaustedPool(t)
+
+	start := time.Now()
+	_, err := beginTx(context.Background(), pool, 50*time.Millisecond)
+	if !errors.Is(err, ErrPoolExhausted) {
+		t.Fatalf("beginTx error = %v, want ErrPoolExhausted", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("beginTx waited %s, want about the 50ms timeout", elapsed)
+	}
+}
+
+func TestAcquireConnFailsWhenPoolIsExhausted(t *testing.T) {
+	pool := exhaustedPool(t)
+
+	_, err := acquireConn(context.Background(), pool, 50*time.Millisecond)
+	if !errors.Is(err, ErrPoolExhausted) {
+		t.Fatalf("acquireConn error = %v, want ErrPoolExhausted", err)
+	}
+}
+
+func TestAcquireConnReportsCallerCancellation(t *testing.T) {
+	pool := exhaustedPool(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := acquireConn(ctx, pool, time.Minute)
+	if err == nil {
+		t.Fatal("acquireConn succeeded on an exhausted pool")
+	}
+	if errors.Is(err, ErrPoolExhausted) {
+		t.Errorf("acquireConn error = %v, want the caller's deadline rather than ErrPoolExhausted", err)
+	}
+}
+
+func TestAcquireErrorOnlyBlamesTheAcquireTimeout(t *testing.T) {
+	acquireCtx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	<-acquireCtx.Done()
+
+	err := acquireError(context.Background(), acquireCtx, time.Second, context.DeadlineExceeded)
+	if !errors.Is(err, ErrPoolExhausted) {
+		t.Errorf("acquireError = %v, want ErrPoolExhausted", err)
+	}
+
+	cause := errors.New("connection refused")
+	if err := acquireError(context.Background(), context.Background(), time.Second, cause); err != cause {
+		t.Errorf("acquireError = %v, want the original error", err)
+	}
+}
@@ -0,0 +1,92 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// ProcessResult describes what ProcessPayload did with a payload
+type ProcessResult struct {
+	// RowsAffected is the number of target table rows the payload wrote
+	RowsAffected int64
+	// EventTypes are the Helius event types the payload carried, in order of
+	// first appearance
+	EventTypes []string
+	// Matched reports whether an event passed the indexer's filters and
+	// reached its write path, even if the write changed no rows
+	Matched bool
+}
+
+// processTally collects the result of one ProcessPayload call. An indexer's
+// process helpers are shared by concurrent payloads, so the tally travels in
+// the context rather than on the indexer.
+type processTally struct {
+	rows    atomic.Int64
+	matched atomic.Bool
+}
+
+type processTallyKey struct{}
+
+// processWithResult runs process with a fresh tally and returns the result it
+// recorded for payload. The payload's block time travels in the context too,
+// for the insert paths that only see an event.
+func processWithResult(ctx context.Context, payload models.HeliusWebhookPayload, process func(ctx context.Context) error) (ProcessResult, error) {
+	tally := &processTally{}
+	ctx = context.WithValue(ctx, processTallyKey{}, tally)
+	if blockTime, ok := payloadBlockTime(payload); ok {
+		ctx = context.WithValue(ctx, blockTimeKey{}, blockTime)
+	}
+	err := process(ctx)
+
+	return ProcessResult{
+		RowsAffected: tally.rows.Load(),
+		EventTypes:   payloadEventTypes(payload),
+		Matched:      tally.matched.Load(),
+	}, err
+}
+
+// recordRows adds rows written by a committed statement to the tally of the
+// payload being processed and marks the payload as matched
+func recordRows(ctx context.Context, rows int64) {
+	if tally, ok := ctx.Value(processTallyKey{}).(*processTally); ok {
+		tally.rows.Add(rows)
+		tally.matched.Store(true)
+	}
+}
+
+// payloadEventTypes lists the transaction type and the types of the events
+// array of a payload's enhanced details
+func payloadEventTypes(payload models.HeliusWebhookPayload) []string {
+	var details struct {
+		Type   string          `json:"type"`
+		Events json.RawMessage `json:"events"`
+	}
+	if err := json.Unmarshal(payload.Transaction.EnhancedDetails, &details); err != nil {
+		return nil
+	}
+
+	var types []string
+	seen := make(map[string]bool)
+	add := func(eventType string) {
+		if eventType != "" && !seen[eventType] {
+			seen[eventType] = true
+			types = append(types, eventType)
+		}
+	}
+
+	add(details.Type)
+
+	var events []struct {
+		Type string `json:"type"`
+	}
+	if json.Unmarshal(details.Events, &events) == nil {
+		for _, event := range events {
+			add(event.Type)
+		}
+	}
+
+	return types
+}
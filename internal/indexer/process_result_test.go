@@ -0,0 +1,97 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+func TestPayloadEventTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		details string
+		want    []string
+	}{
+		{name: "direct event", details: `{"type": "NFT_SALE"}`, want: []string{"NFT_SALE"}},
+		{
+			name:    "events array",
+			details: `{"type": "UNKNOWN", "events": [{"type": "NFT_LISTING"}, {"type": "NFT_SALE"}, {"type": "NFT_LISTING"}]}`,
+			want:    []string{"UNKNOWN", "NFT_LISTING", "NFT_SALE"},
+		},
+		{name: "events object", details: `{"type": "NFT_SALE", "events": {"nft": {"type": "NFT_SALE"}}}`, want: []string{"NFT_SALE"}},
+		{name: "no type", details: `{}`, want: nil},
+		{name: "not JSON", details: `not json`, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := models.HeliusWebhookPayload{Transaction: models.HeliusTransaction{EnhancedDetails: json.RawMessage(tt.details)}}
+			if got := payloadEventTypes(payload); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("payloadEventTypes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessWithResultTalliesRows(t *testing.T) {
+	payload := enhancedPayload(t, "sig", 1, map[string]interface{}{"type": "NFT_SALE"})
+
+	result, err := processWithResult(context.Background(), payload, func(ctx context.Context) error {
+		recordRows(ctx, 2)
+		recordRows(ctx, 1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("processWithResult: %v", err)
+	}
+	want := ProcessResult{RowsAffected: 3, EventTypes: []string{"NFT_SALE"}, Matched: true}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("result = %+v, want %+v", result, want)
+	}
+
+	// A write that changed nothing still counts as a match
+	result, _ = processWithResult(context.Background(), payload, func(ctx context.Context) error {
+		recordRows(ctx, 0)
+		return nil
+	})
+	if result.RowsAffected != 0 || !result.Matched {
+		t.Errorf("result = %+v, want a match with no rows", result)
+	}
+
+	result, _ = processWithResult(context.Background(), payload, func(ctx context.Context) error { return nil })
+	if result.RowsAffected != 0 || result.Matched {
+		t.Errorf("result = %+v, want no rows and no match", result)
+	}
+}
+
+func TestProcessPayloadResult(t *testing.T) {
+	pool := testPool(t)
+	table := testTable(t, pool)
+	idx := newTestPriceIndexer(t, `{"collection": "collection", "marketplaces": ["MAGIC_EDEN"]}`)
+	initializeTable(t, pool, idx, table)
+
+	ctx := context.Background()
+
+	result, err := idx.ProcessPayload(ctx, pool, table, enhancedPayload(t, "listing-sig", 1, listingEventData("mint-1")))
+	if err != nil {
+		t.Fatalf("ProcessPayload: %v", err)
+	}
+	want := ProcessResult{RowsAffected: 1, EventTypes: []string{"NFT_LISTING"}, Matched: true}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("matching payload result = %+v, want %+v", result, want)
+	}
+
+	filtered := listingEventData("mint-2")
+	filtered["data"].(map[string]interface{})["marketplace"] = "SOLANART"
+	result, err = idx.ProcessPayload(ctx, pool, table, enhancedPayload(t, "filtered-sig", 2, filtered))
+	if err != nil {
+		t.Fatalf("ProcessPayload: %v", err)
+	}
+	want = ProcessResult{EventTypes: []string{"NFT_LISTING"}}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("non-matching payload result = %+v, want %+v", result, want)
+	}
+}
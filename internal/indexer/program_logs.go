@@ -0,0 +1,222 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// programInstruction is one instruction of a Helius enhanced transaction
+type programInstruction struct {
+	ProgramID         string          `json:"programId"`
+	Accounts          []string        `json:"accounts"`
+	Data              string          `json:"data"`
+	InnerInstructions json.RawMessage `json:"innerInstructions,omitempty"`
+}
+
+// ProgramLogIndexer stores the raw top-level instructions of one program, so
+// users can decode them later with their own program's layout
+type ProgramLogIndexer struct {
+	BaseIndexer
+	ProgramID string
+	Accounts  []string
+}
+
+func NewProgramLogIndexer(id string, params json.RawMessage) (Indexer, error) {
+	base := NewBaseIndexer(id, params)
+
+	var programParams models.ProgramLogParams
+	if err := json.Unmarshal(params, &programParams); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal program log parameters: %w", err)
+	}
+
+	if programParams.ProgramID == "" {
+		return nil, fmt.Errorf("program ID is required")
+	}
+
+	return &ProgramLogIndexer{
+		BaseIndexer: base,
+		ProgramID:   programParams.ProgramID,
+		Accounts:    programParams.Accounts,
+	}, nil
+}
+
+func (i *ProgramLogIndexer) Initialize(ctx context.Context, conn *pgx.Conn, targetTable string) error {
+	if err := i.BaseIndexer.Initialize(ctx, conn, targetTable); err != nil {
+		return err
+	}
+
+	name := tableName(targetTable)
+	targetTable = QuoteTableName(targetTable)
+
+	exists, err := checkTableExists(ctx, conn, name)
+	if err != nil {
+		return fmt.Errorf("failed to check if table exists: %w", err)
+	}
+
+	if !exists {
+		_, err = conn.Exec(ctx, fmt.Sprintf(`
+			CREATE TABLE %s (
+				id SERIAL PRIMARY KEY,
+				signature TEXT NOT NULL,
+				slot BIGINT NOT NULL,
+				block_time TIMESTAMP WITH TIME ZONE NOT NULL,
+				program_id TEXT NOT NULL,
+				instruction_index INTEGER NOT NULL,
+				account_keys JSONB NOT NULL,
+				data JSONB NOT NULL,
+				created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+				UNIQUE(signature, instruction_index)
+			)
+		`, targetTable))
+		if err != nil {
+			return fmt.Errorf("failed to create table: %w", err)
+		}
+
+		_, err = conn.Exec(ctx, fmt.Sprintf(`
+			CREATE INDEX %s ON %s(slot);
+			CREATE INDEX %s ON %s(block_time);
+		`,
+			indexName(name, "slot"), targetTable,
+			indexName(name, "block_time"), targetTable,
+		))
+		if err != nil {
+			return fmt.Errorf("failed to create indices: %w", err)
+		}
+
+		log.Info().
+			Str("targetTable", targetTable).
+			Msg("Successfully created program log table")
+	}
+
+	return nil
+}
+
+func (i *ProgramLogIndexer) GetWebhookConfig(indexerID string) (WebhookConfig, error) {
+
+	addresses := append([]string{i.ProgramID}, i.Accounts...)
+
+	config := WebhookConfig{
+		WebhookType:      i.webhookType(),
+		AccountAddresses: addresses,
+		TransactionTypes: i.webhookTransactionTypes(nil),
+	}
+
+	return config, nil
+}
+
+// ProcessPayload reports the rows the payload wrote and the events it carried
+func (i *ProgramLogIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload) (ProcessResult, error) {
+	return processWithResult(ctx, payload, func(ctx context.Context) error {
+		return i.processPayload(ctx, pool, targetTable, payload)
+	})
+}
+
+func (i *ProgramLogIndexer) processPayload(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload) error {
+
+	if len(payload.Transaction.Signatures) == 0 {
+		log.Debug().Msg("Skipping payload with no signatures")
+		return nil
+	}
+	signature := payload.Transaction.Signatures[0]
+
+	var details struct {
+		Instructions []programInstruction `json:"instructions"`
+	}
+	if len(payload.Transaction.EnhancedDetails) > 0 {
+		if err := json.Unmarshal(payload.Transaction.EnhancedDetails, &details); err != nil {
+			return fmt.Errorf("failed to unmarshal enhanced details: %w", err)
+		}
+	}
+	if len(details.Instructions) == 0 && len(payload.Transaction.Instructions) > 0 {
+		if err := json.Unmarshal(payload.Transaction.Instructions, &details.Instructions); err != nil {
+			return fmt.Errorf("failed to unmarshal instructions: %w", err)
+		}
+	}
+
+	blockTime, ok := payloadBlockTime(payload)
+	if !ok {
+		blockTime = time.Now().UTC()
+	}
+
+	targetTable = QuoteTableName(targetTable)
+
+	tx, err := beginTx(ctx, pool, i.acquireTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	var written int64
+	for index, instruction := range details.Instructions {
+		if !i.matches(instruction) {
+			continue
+		}
+
+		accountKeys, err := json.Marshal(instruction.Accounts)
+		if err != nil {
+			tx.Rollback(ctx)
+			return fmt.Errorf("failed to marshal instruction accounts: %w", err)
+		}
+		data, err := json.Marshal(instruction)
+		if err != nil {
+			tx.Rollback(ctx)
+			return fmt.Errorf("failed to marshal instruction: %w", err)
+		}
+
+		result, err := tx.Exec(ctx, fmt.Sprintf(`
+			INSERT INTO %s (
+				signature, slot, block_time, program_id, instruction_index, account_keys, data
+			) VALUES (
+				$1, $2, $3, $4, $5, $6, $7
+			) ON CONFLICT (signature, instruction_index) DO NOTHING
+		`, targetTable),
+			signature, payload.Slot, blockTime, instruction.ProgramID, index, accountKeys, data,
+		)
+		if err != nil {
+			tx.Rollback(ctx)
+			return fmt.Errorf("failed to insert program instruction: %w", err)
+		}
+		written += result.RowsAffected()
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	if written > 0 {
+		recordRows(ctx, written)
+	}
+
+	log.Debug().
+		Str("signature", signature).
+		Str("programID", i.ProgramID).
+		Int64("rowsWritten", written).
+		Msg("Processed program instructions")
+
+	return nil
+}
+
+// matches reports whether an instruction calls the program and, when accounts
+// are configured, touches one of them
+func (i *ProgramLogIndexer) matches(instruction programInstruction) bool {
+	if instruction.ProgramID != i.ProgramID {
+		return false
+	}
+	if len(i.Accounts) == 0 {
+		return true
+	}
+	for _, account := range instruction.Accounts {
+		for _, tracked := range i.Accounts {
+			if account == tracked {
+				return true
+			}
+		}
+	}
+	return false
+}
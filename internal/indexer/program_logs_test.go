@@ -0,0 +1,163 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+const jupiterProgram = "JUP6LkbZbjS1jKKwapdHNy74zcZ3tLUZoi5QNyVTaV4"
+
+// programLogPayload is the program_logs.json fixture delivered by an
+// enhanced webhook
+func programLogPayload(t *testing.T) models.HeliusWebhookPayload {
+	t.Helper()
+
+	body, err := os.ReadFile(filepath.Join("testdata", "program_logs.json"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	var tx struct {
+		Signature string `json:"signature"`
+		Slot      int64  `json:"slot"`
+	}
+	if err := json.Unmarshal(body, &tx); err != nil {
+		t.Fatalf("unmarshal fixture: %v", err)
+	}
+	return models.HeliusWebhookPayload{
+		Slot: tx.Slot,
+		Transaction: models.HeliusTransaction{
+			Signatures:      []string{tx.Signature},
+			EnhancedDetails: body,
+		},
+	}
+}
+
+func newTestProgramLogIndexer(t *testing.T, params string) *ProgramLogIndexer {
+	t.Helper()
+
+	idx, err := NewProgramLogIndexer("test", json.RawMessage(params))
+	if err != nil {
+		t.Fatalf("NewProgramLogIndexer: %v", err)
+	}
+	return idx.(*ProgramLogIndexer)
+}
+
+func TestNewProgramLogIndexerRequiresProgramID(t *testing.T) {
+	if _, err := NewProgramLogIndexer("test", json.RawMessage(`{"accounts": ["account"]}`)); err == nil {
+		t.Error("NewProgramLogIndexer accepted params without a program ID")
+	}
+}
+
+func TestProgramLogWebhookConfig(t *testing.T) {
+	idx := newTestProgramLogIndexer(t, `{"programId": "`+jupiterProgram+`", "accounts": ["`+usdcMint+`"]}`)
+
+	config, err := idx.GetWebhookConfig("test")
+	if err != nil {
+		t.Fatalf("GetWebhookConfig: %v", err)
+	}
+	if !reflect.DeepEqual(config.AccountAddresses, []string{jupiterProgram, usdcMint}) {
+		t.Errorf("addresses = %v, want the program then its accounts", config.AccountAddresses)
+	}
+	if !reflect.DeepEqual(config.TransactionTypes, []string{"ANY"}) {
+		t.Errorf("transaction types = %v, want ANY", config.TransactionTypes)
+	}
+}
+
+func TestProgramLogMatches(t *testing.T) {
+	payload := programLogPayload(t)
+	var details struct {
+		Instructions []programInstruction `json:"instructions"`
+	}
+	if err := json.Unmarshal(payload.Transaction.EnhancedDetails, &details); err != nil {
+		t.Fatalf("unmarshal instructions: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		params string
+		want   []int
+	}{
+		{name: "program", params: `{"programId": "` + jupiterProgram + `"}`, want: []int{1, 2}},
+		{name: "program and account", params: `{"programId": "` + jupiterProgram + `", "accounts": ["` + usdcMint + `"]}`, want: []int{1}},
+		{name: "other program", params: `{"programId": "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"}`, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := newTestProgramLogIndexer(t, tt.params)
+
+			// Inner instructions are kept with their parent, never matched alone
+			var got []int
+			for index, instruction := range details.Instructions {
+				if idx.matches(instruction) {
+					got = append(got, index)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matched instructions %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessProgramLogFixture(t *testing.T) {
+	pool := testPool(t)
+	table := testTable(t, pool)
+	ctx := context.Background()
+
+	idx := newTestProgramLogIndexer(t, `{"programId": "`+jupiterProgram+`"}`)
+	initializeTable(t, pool, idx, table)
+
+	payload := programLogPayload(t)
+	for run := 1; run <= 2; run++ {
+		if _, err := idx.ProcessPayload(ctx, pool, table, payload); err != nil {
+			t.Fatalf("ProcessPayload run %d: %v", run, err)
+		}
+	}
+
+	rows, err := pool.Query(ctx, "SELECT instruction_index, program_id, block_time, account_keys, data FROM "+QuoteTableName(table)+" ORDER BY instruction_index")
+	if err != nil {
+		t.Fatalf("query rows: %v", err)
+	}
+	defer rows.Close()
+
+	var indexes []int32
+	for rows.Next() {
+		var (
+			index       int32
+			programID   string
+			blockTime   time.Time
+			accountKeys []string
+			data        programInstruction
+		)
+		if err := rows.Scan(&index, &programID, &blockTime, &accountKeys, &data); err != nil {
+			t.Fatalf("scan row: %v", err)
+		}
+		indexes = append(indexes, index)
+
+		if programID != jupiterProgram || data.ProgramID != jupiterProgram {
+			t.Errorf("instruction %d stored for program %s, want %s", index, programID, jupiterProgram)
+		}
+		if !blockTime.Equal(time.Unix(1708006600, 0)) {
+			t.Errorf("instruction %d block_time = %s, want the fixture timestamp", index, blockTime)
+		}
+		if len(accountKeys) != 2 || accountKeys[0] != "6Rg8Bm5yjGMRpYNLvwS8qkHX5BvCnZz4kbDDsNPDGr3u" {
+			t.Errorf("instruction %d account_keys = %v, want its two accounts", index, accountKeys)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("read rows: %v", err)
+	}
+
+	// Processing the payload twice doesn't duplicate its instructions
+	if !reflect.DeepEqual(indexes, []int32{1, 2}) {
+		t.Errorf("stored instructions %v, want [1 2]", indexes)
+	}
+}
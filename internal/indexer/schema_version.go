@@ -0,0 +1,76 @@
+package indexer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// schemaVersionPrefix is bumped if the way fingerprints are derived changes
+const schemaVersionPrefix = "k1-"
+
+// PayloadSchemaVersion fingerprints the shape of a webhook payload from its
+// top-level keys. Payloads with the same set of keys share a version, so a
+// field Helius adds or drops shows up as a new version. It returns "" when
+// raw is not a JSON object.
+func PayloadSchemaVersion(raw []byte) string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil || len(fields) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sum := sha256.Sum256([]byte(strings.Join(keys, ",")))
+	return schemaVersionPrefix + hex.EncodeToString(sum[:6])
+}
+
+// AddSchemaVersionColumn adds the optional schema_version column, recording
+// which payload version wrote a row, to a target table
+func AddSchemaVersionColumn(ctx context.Context, conn *pgx.Conn, targetTable string) error {
+	targetTable = QuoteTableName(targetTable)
+
+	_, err := conn.Exec(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS schema_version TEXT", targetTable))
+	if err != nil {
+		return fmt.Errorf("failed to add schema_version column to %s: %w", targetTable, err)
+	}
+	return nil
+}
+
+// StampSchemaVersion records version on the rows of targetTable whose
+// keyColumn holds key. Tables without the schema_version column are left
+// alone.
+func StampSchemaVersion(ctx context.Context, pool *pgxpool.Pool, targetTable, keyColumn, key, version string) error {
+	if version == "" || key == "" {
+		return nil
+	}
+
+	targetTable = QuoteTableName(targetTable)
+
+	_, err := pool.Exec(ctx, fmt.Sprintf(`
+		UPDATE %s SET schema_version = $1
+		WHERE %s = $2 AND schema_version IS DISTINCT FROM $1
+	`, targetTable, pgx.Identifier{keyColumn}.Sanitize()), version, key)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "42703" {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record schema version in %s: %w", targetTable, err)
+	}
+	return nil
+}
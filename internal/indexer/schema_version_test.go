@@ -0,0 +1,72 @@
+package indexer
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPayloadSchemaVersion(t *testing.T) {
+	base := PayloadSchemaVersion([]byte(`{"slot": 1, "signature": "a", "type": "NFT_SALE"}`))
+	if base == "" {
+		t.Fatal("PayloadSchemaVersion returned no version for a JSON object")
+	}
+
+	// Values and key order don't matter, only the set of keys
+	if got := PayloadSchemaVersion([]byte(`{"type": "TRANSFER", "signature": "b", "slot": 2}`)); got != base {
+		t.Errorf("same keys gave version %s, want %s", got, base)
+	}
+	if got := PayloadSchemaVersion([]byte(`{"slot": 1, "signature": "a", "type": "NFT_SALE", "events": {}}`)); got == base {
+		t.Error("an added top-level key kept the same version")
+	}
+
+	for _, raw := range []string{`[]`, `{}`, `"payload"`, `not json`} {
+		if got := PayloadSchemaVersion([]byte(raw)); got != "" {
+			t.Errorf("PayloadSchemaVersion(%s) = %q, want none", raw, got)
+		}
+	}
+}
+
+func TestStampSchemaVersion(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+
+	table := testTable(t, pool)
+	idx := newTestPriceIndexer(t, `{"collection": "collection"}`)
+	initializeTable(t, pool, idx, table)
+	if err := idx.InsertPriceEvents(ctx, pool, table, listingEvents("stamp", 2)); err != nil {
+		t.Fatalf("InsertPriceEvents: %v", err)
+	}
+
+	// Without the column the stamp is skipped rather than failing the payload
+	if err := StampSchemaVersion(ctx, pool, table, "signature", "stamp-0", "k1-test"); err != nil {
+		t.Fatalf("StampSchemaVersion without the column: %v", err)
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("acquire connection: %v", err)
+	}
+	err = AddSchemaVersionColumn(ctx, conn.Conn(), table)
+	conn.Release()
+	if err != nil {
+		t.Fatalf("AddSchemaVersionColumn: %v", err)
+	}
+
+	if err := StampSchemaVersion(ctx, pool, table, "signature", "stamp-0", "k1-test"); err != nil {
+		t.Fatalf("StampSchemaVersion: %v", err)
+	}
+
+	var stamped, unstamped *string
+	if err := pool.QueryRow(ctx, "SELECT schema_version FROM "+QuoteTableName(table)+" WHERE signature = 'stamp-0'").Scan(&stamped); err != nil {
+		t.Fatalf("read stamped row: %v", err)
+	}
+	if err := pool.QueryRow(ctx, "SELECT schema_version FROM "+QuoteTableName(table)+" WHERE signature = 'stamp-1'").Scan(&unstamped); err != nil {
+		t.Fatalf("read unstamped row: %v", err)
+	}
+	if stamped == nil || *stamped != "k1-test" {
+		t.Errorf("stamped row version = %v, want k1-test", stamped)
+	}
+	if unstamped != nil {
+		t.Errorf("other row version = %s, want it left NULL", *unstamped)
+	}
+}
@@ -0,0 +1,81 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestSetTableOwnerCoversCompanionTables(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+
+	role := fmt.Sprintf("test_owner_%d", time.Now().UnixNano())
+	if _, err := pool.Exec(ctx, "CREATE ROLE "+pgx.Identifier{role}.Sanitize()); err != nil {
+		t.Skipf("test database user can't create roles: %v", err)
+	}
+	t.Cleanup(func() {
+		pool.Exec(context.Background(), "DROP ROLE IF EXISTS "+pgx.Identifier{role}.Sanitize())
+	})
+	// The test user needs to be a member of the role to hand tables to it
+	if _, err := pool.Exec(ctx, "GRANT "+pgx.Identifier{role}.Sanitize()+" TO CURRENT_USER"); err != nil {
+		t.Skipf("test database user can't join role: %v", err)
+	}
+
+	table := testTable(t, pool)
+	tables := []string{
+		table,
+		table + "_price_history",
+		table + "_collection_offers",
+		table + "_collection_offer_fills",
+	}
+	for _, name := range tables {
+		if _, err := pool.Exec(ctx, "CREATE TABLE "+QuoteTableName(name)+" (id SERIAL PRIMARY KEY)"); err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("acquire connection: %v", err)
+	}
+	defer conn.Release()
+
+	if err := SetTableOwner(ctx, conn.Conn(), table, role); err != nil {
+		t.Fatalf("SetTableOwner: %v", err)
+	}
+
+	for _, name := range tables {
+		var owner string
+		err := pool.QueryRow(ctx, "SELECT tableowner FROM pg_tables WHERE schemaname = 'public' AND tablename = $1", name).Scan(&owner)
+		if err != nil {
+			t.Fatalf("read owner of %s: %v", name, err)
+		}
+		if owner != role {
+			t.Errorf("owner of %s = %s, want %s", name, owner, role)
+		}
+	}
+}
+
+func TestSetTableOwnerSkipsMissingTables(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	table := testTable(t, pool)
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("acquire connection: %v", err)
+	}
+	defer conn.Release()
+
+	var user string
+	if err := conn.QueryRow(ctx, "SELECT current_user").Scan(&user); err != nil {
+		t.Fatalf("read current user: %v", err)
+	}
+	if err := SetTableOwner(ctx, conn.Conn(), table, user); err != nil {
+		t.Fatalf("SetTableOwner on a missing table: %v", err)
+	}
+}
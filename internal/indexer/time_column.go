@@ -0,0 +1,138 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/pkg/validator"
+)
+
+const defaultTimeColumn = "block_time"
+
+// timeColumn is the column an NFT indexer stores the event time in. By default
+// it is block_time TIMESTAMPTZ; existing schemas may use another name or keep
+// the time as epoch seconds in an integer column.
+type timeColumn struct {
+	name  string
+	epoch bool
+}
+
+func newTimeColumn(cfg *models.TimeColumn) (timeColumn, error) {
+	column := timeColumn{name: defaultTimeColumn}
+	if cfg == nil {
+		return column, nil
+	}
+
+	if err := validator.ValidateTimeColumn(cfg.Name, cfg.Type); err != nil {
+		return column, err
+	}
+
+	if cfg.Name != "" {
+		column.name = cfg.Name
+	}
+	column.epoch = cfg.Type == models.TimeColumnEpoch
+
+	return column, nil
+}
+
+// TimeColumnExpr returns a TIMESTAMPTZ expression reading the time column of
+// an NFT indexer built from params
+func TimeColumnExpr(params json.RawMessage) string {
+	var cfg struct {
+		TimeColumn *models.TimeColumn `json:"timeColumn"`
+	}
+	if err := json.Unmarshal(params, &cfg); err != nil {
+		return defaultTimeColumn
+	}
+
+	column, err := newTimeColumn(cfg.TimeColumn)
+	if err != nil {
+		return defaultTimeColumn
+	}
+	return column.selectExpr()
+}
+
+// definition is the column definition used when the indexer creates its table
+func (t timeColumn) definition() string {
+	if t.epoch {
+		return t.name + " BIGINT NOT NULL"
+	}
+	return t.name + " TIMESTAMP WITH TIME ZONE NOT NULL"
+}
+
+// value converts an event time to what the column stores
+func (t timeColumn) value(ts time.Time) any {
+	if t.epoch {
+		return ts.Unix()
+	}
+	return ts
+}
+
+// now is an SQL expression for the current time in the column's storage type
+func (t timeColumn) now() string {
+	if t.epoch {
+		return "EXTRACT(EPOCH FROM NOW())::BIGINT"
+	}
+	return "NOW()"
+}
+
+// fromTimestamptz converts a TIMESTAMPTZ expression to the column's storage type
+func (t timeColumn) fromTimestamptz(expr string) string {
+	if t.epoch {
+		return fmt.Sprintf("EXTRACT(EPOCH FROM %s)::BIGINT", expr)
+	}
+	return expr
+}
+
+func (t timeColumn) selectExpr() string {
+	if t.epoch {
+		return fmt.Sprintf("to_timestamp(%s)", t.name)
+	}
+	return t.name
+}
+
+// rewrite points the block_time references in query at the configured column
+func (t timeColumn) rewrite(query string) string {
+	if t.name == defaultTimeColumn {
+		return query
+	}
+	return strings.ReplaceAll(query, defaultTimeColumn, t.name)
+}
+
+// check verifies that an existing table has the time column with a matching
+// storage type, so a misconfigured indexer fails at Initialize rather than on
+// its first insert
+func (t timeColumn) check(ctx context.Context, conn *pgx.Conn, tableName string) error {
+	var dataType string
+	err := conn.QueryRow(ctx, `
+		SELECT data_type FROM information_schema.columns
+		WHERE table_schema = 'public'
+		AND table_name = $1
+		AND column_name = $2
+	`, tableName, t.name).Scan(&dataType)
+	if err == pgx.ErrNoRows {
+		return fmt.Errorf("table %s has no time column %s", tableName, t.name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check time column of %s: %w", tableName, err)
+	}
+
+	switch {
+	case t.epoch && (dataType == "bigint" || dataType == "integer"):
+	case !t.epoch && dataType == "timestamp with time zone":
+	default:
+		storage := models.TimeColumnTimestamptz
+		if t.epoch {
+			storage = models.TimeColumnEpoch
+		}
+		return fmt.Errorf("time column %s of %s is %s, which cannot store %s values", t.name, tableName, dataType, storage)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+func TestNewTimeColumn(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+
+	column, err := newTimeColumn(nil)
+	if err != nil {
+		t.Fatalf("newTimeColumn(nil): %v", err)
+	}
+	if column.name != "block_time" || column.epoch {
+		t.Errorf("default column = %+v, want block_time stored as timestamptz", column)
+	}
+	if got := column.value(ts); got != ts {
+		t.Errorf("timestamptz value = %v, want %v", got, ts)
+	}
+	if got := column.rewrite("ORDER BY block_time DESC"); got != "ORDER BY block_time DESC" {
+		t.Errorf("default rewrite = %q, want the query unchanged", got)
+	}
+
+	column, err = newTimeColumn(&models.TimeColumn{Name: "ts", Type: models.TimeColumnEpoch})
+	if err != nil {
+		t.Fatalf("newTimeColumn(epoch): %v", err)
+	}
+	if got := column.definition(); got != "ts BIGINT NOT NULL" {
+		t.Errorf("epoch definition = %q, want a BIGINT column", got)
+	}
+	if got := column.value(ts); got != int64(1700000000) {
+		t.Errorf("epoch value = %v, want 1700000000", got)
+	}
+	if got := column.rewrite("ORDER BY block_time DESC"); got != "ORDER BY ts DESC" {
+		t.Errorf("epoch rewrite = %q, want the query pointed at ts", got)
+	}
+
+	if _, err := newTimeColumn(&models.TimeColumn{Type: "unix"}); err == nil {
+		t.Error("newTimeColumn accepted an unknown column type")
+	}
+}
+
+func TestTimeColumnExpr(t *testing.T) {
+	tests := []struct {
+		params string
+		want   string
+	}{
+		{params: `{"collection": "collection"}`, want: "block_time"},
+		{params: `{"timeColumn": {"name": "event_time", "type": "timestamptz"}}`, want: "event_time"},
+		{params: `{"timeColumn": {"name": "ts", "type": "epoch"}}`, want: "to_timestamp(ts)"},
+		{params: `{"timeColumn": {"name": "Bad Name"}}`, want: "block_time"},
+	}
+
+	for _, tt := range tests {
+		if got := TimeColumnExpr(json.RawMessage(tt.params)); got != tt.want {
+			t.Errorf("TimeColumnExpr(%s) = %q, want %q", tt.params, got, tt.want)
+		}
+	}
+}
+
+func TestInsertPriceEventsWithTimeColumn(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		params string
+		query  string
+	}{
+		{
+			name:   "timestamptz",
+			params: `{"collection": "collection", "timeColumn": {"name": "event_time", "type": "timestamptz"}}`,
+			query:  "SELECT EXTRACT(EPOCH FROM event_time)::BIGINT FROM %s",
+		},
+		{
+			name:   "epoch",
+			params: `{"collection": "collection", "timeColumn": {"name": "ts", "type": "epoch"}}`,
+			query:  "SELECT ts FROM %s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := testTable(t, pool)
+			idx := newTestPriceIndexer(t, tt.params)
+			initializeTable(t, pool, idx, table)
+
+			if err := idx.InsertPriceEvents(ctx, pool, table, listingEvents(tt.name, 1)); err != nil {
+				t.Fatalf("InsertPriceEvents: %v", err)
+			}
+
+			var seconds int64
+			if err := pool.QueryRow(ctx, fmt.Sprintf(tt.query, QuoteTableName(table))).Scan(&seconds); err != nil {
+				t.Fatalf("read time column: %v", err)
+			}
+			if seconds != 1700000000 {
+				t.Errorf("stored time = %d, want 1700000000", seconds)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+)
+
+// transferAmount returns the amount of a token transfer in whole tokens and,
+// when the transfer carried one, its raw unscaled amount. Helius sends
+// tokenAmount already scaled; a raw amount is divided by 10^decimals, with
+// the decimals taken from the transfer when it has them and from the token's
+// metadata otherwise. scaled is false when the decimals can't be resolved, in
+// which case only the raw amount is known.
+func (i *TokenPriceIndexer) transferAmount(ctx context.Context, transfer map[string]interface{}, mint string, heliusAPIKey string) (amount float64, scaled bool, rawAmount pgtype.Numeric) {
+	if amount, ok := transfer["tokenAmount"].(float64); ok {
+		return amount, true, rawAmount
+	}
+
+	var raw float64
+	if rawToken, ok := transfer["rawTokenAmount"].(map[string]interface{}); ok {
+		value := fmt.Sprint(rawToken["tokenAmount"])
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, false, rawAmount
+		}
+		raw = parsed
+		rawAmount.Scan(value)
+
+		if decimals, ok := rawToken["decimals"].(float64); ok {
+			return raw / math.Pow10(int(decimals)), true, rawAmount
+		}
+	} else if value, ok := transfer["amount"].(float64); ok {
+		raw = value
+		rawAmount.Scan(strconv.FormatFloat(value, 'f', -1, 64))
+	} else {
+		return 0, false, rawAmount
+	}
+
+	if decimals, ok := transfer["decimals"].(float64); ok {
+		return raw / math.Pow10(int(decimals)), true, rawAmount
+	}
+	if decimals, ok := i.tokenDecimals(ctx, mint, heliusAPIKey); ok {
+		return raw / math.Pow10(decimals), true, rawAmount
+	}
+
+	log.Debug().Str("token", mint).Msg("Token decimals unknown, storing only the raw transfer amount")
+	return 0, false, rawAmount
+}
+
+// tokenDecimals looks up the decimals of a mint in the metadata cache, asking
+// the DAS API when they aren't cached and an API key is available
+func (i *TokenPriceIndexer) tokenDecimals(ctx context.Context, mint string, heliusAPIKey string) (int, bool) {
+	if i.MetadataCache != nil {
+		if metadata, found := i.MetadataCache.Get(mint); found {
+			return metadata.Decimals, true
+		}
+	}
+	if heliusAPIKey == "" {
+		return 0, false
+	}
+
+	metadata, err := NewTokenMetadataFetcher(i.RPCURL, heliusAPIKey, i.RPCTimeout, i.MetadataCache).FetchTokenMetadata(ctx, mint)
+	if err != nil {
+		return 0, false
+	}
+	return metadata.Decimals, true
+}
+
+// scaledAmount is the value written for a transfer amount: NULL when it
+// could not be scaled to whole tokens
+func scaledAmount(amount float64, scaled bool) interface{} {
+	if !scaled {
+		return nil
+	}
+	return amount
+}
+
+// addRawAmountColumn adds the raw_amount column, holding the unscaled amount
+// of the last transfer, to token price tables created before it existed
+func addRawAmountColumn(ctx context.Context, conn *pgx.Conn, targetTable string) error {
+	_, err := conn.Exec(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS raw_amount NUMERIC", QuoteTableName(targetTable)))
+	if err != nil {
+		return fmt.Errorf("failed to add raw_amount column to %s: %w", targetTable, err)
+	}
+	return nil
+}
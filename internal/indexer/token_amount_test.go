@@ -0,0 +1,100 @@
+package indexer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const usdcMint = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+
+func numericString(t *testing.T, n pgtype.Numeric) string {
+	t.Helper()
+
+	if !n.Valid {
+		return ""
+	}
+	value, err := n.Value()
+	if err != nil {
+		t.Fatalf("numeric value: %v", err)
+	}
+	return value.(string)
+}
+
+func TestTransferAmountScalesSixDecimalToken(t *testing.T) {
+	cache := NewTokenMetadataCache()
+	cache.Set(usdcMint, TokenMetadata{Symbol: "USDC", Decimals: 6})
+	idx := &TokenPriceIndexer{MetadataCache: cache}
+
+	tests := []struct {
+		name     string
+		transfer map[string]interface{}
+		raw      string
+	}{
+		{
+			name:     "scaled token amount",
+			transfer: map[string]interface{}{"tokenAmount": 1.5},
+		},
+		{
+			name: "raw token amount with decimals",
+			transfer: map[string]interface{}{"rawTokenAmount": map[string]interface{}{
+				"tokenAmount": "1500000",
+				"decimals":    float64(6),
+			}},
+			raw: "1500000",
+		},
+		{
+			name:     "raw amount with decimals",
+			transfer: map[string]interface{}{"amount": float64(1500000), "decimals": float64(6)},
+			raw:      "1500000",
+		},
+		{
+			name:     "raw amount with cached decimals",
+			transfer: map[string]interface{}{"amount": float64(1500000)},
+			raw:      "1500000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, scaled, raw := idx.transferAmount(context.Background(), tt.transfer, usdcMint, "")
+			if !scaled || amount != 1.5 {
+				t.Errorf("amount = %v (scaled %v), want 1.5", amount, scaled)
+			}
+			if got := numericString(t, raw); got != tt.raw {
+				t.Errorf("raw amount = %q, want %q", got, tt.raw)
+			}
+		})
+	}
+}
+
+func TestTransferAmountFetchesDecimals(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc": "2.0", "result": {"id": "` + usdcMint + `", "token_info": {"decimals": 6}}}`))
+	}))
+	defer server.Close()
+
+	idx := &TokenPriceIndexer{RPCURL: server.URL, MetadataCache: NewTokenMetadataCache()}
+	amount, scaled, _ := idx.transferAmount(context.Background(), map[string]interface{}{"amount": float64(2500000)}, usdcMint, "key")
+	if !scaled || amount != 2.5 {
+		t.Errorf("amount = %v (scaled %v), want 2.5", amount, scaled)
+	}
+}
+
+func TestTransferAmountWithUnknownDecimals(t *testing.T) {
+	idx := &TokenPriceIndexer{MetadataCache: NewTokenMetadataCache()}
+
+	amount, scaled, raw := idx.transferAmount(context.Background(), map[string]interface{}{"amount": float64(1500000)}, usdcMint, "")
+	if scaled || amount != 0 {
+		t.Errorf("amount = %v (scaled %v), want no scaled amount", amount, scaled)
+	}
+	if got := numericString(t, raw); got != "1500000" {
+		t.Errorf("raw amount = %q, want 1500000", got)
+	}
+	if scaledAmount(amount, scaled) != nil {
+		t.Error("an unscaled amount is not written as NULL")
+	}
+}
@@ -0,0 +1,472 @@
+package indexer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+
+	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/pkg/logger"
+)
+
+const (
+	// tokenAccountsPageSize is the largest page getTokenAccounts accepts
+	tokenAccountsPageSize = 1000
+	// maxTokenAccountPages caps how many accounts are scanned per mint; counts
+	// for mints with more holders are reported as inexact
+	maxTokenAccountPages = 50
+	// DefaultHolderSnapshotInterval is the least time between two snapshots
+	// of a mint when the params don't set snapshotInterval
+	DefaultHolderSnapshotInterval = time.Minute
+)
+
+// TokenSupply is the supply and holder count of a mint at a point in time
+type TokenSupply struct {
+	TotalSupply     float64
+	Decimals        int
+	HolderCount     int64
+	HolderCountFull bool
+}
+
+// TokenSupplyFetcher reads supply and holder counts from the Helius DAS API
+type TokenSupplyFetcher struct {
+	rpcURL       string
+	heliusAPIKey string
+	httpClient   *http.Client
+}
+
+func NewTokenSupplyFetcher(rpcURL, heliusAPIKey string, timeout time.Duration) *TokenSupplyFetcher {
+	if rpcURL == "" {
+		rpcURL = DefaultHeliusRPCURL
+	}
+	if timeout <= 0 {
+		timeout = DefaultHeliusRPCTimeout
+	}
+
+	return &TokenSupplyFetcher{
+		rpcURL:       strings.TrimRight(rpcURL, "/"),
+		heliusAPIKey: heliusAPIKey,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// FetchTokenSupply returns the current supply of a mint and the number of
+// token accounts holding a non-zero balance
+func (f *TokenSupplyFetcher) FetchTokenSupply(ctx context.Context, mint string) (TokenSupply, error) {
+	var asset struct {
+		TokenInfo struct {
+			Supply   json.Number `json:"supply"`
+			Decimals int         `json:"decimals"`
+		} `json:"token_info"`
+	}
+	if err := f.call(ctx, "getAsset", map[string]interface{}{"id": mint}, &asset); err != nil {
+		return TokenSupply{}, fmt.Errorf("failed to get asset: %w", err)
+	}
+
+	rawSupply, err := asset.TokenInfo.Supply.Float64()
+	if err != nil && asset.TokenInfo.Supply != "" {
+		return TokenSupply{}, fmt.Errorf("invalid supply %q: %w", asset.TokenInfo.Supply, err)
+	}
+
+	supply := TokenSupply{
+		TotalSupply:     rawSupply / math.Pow10(asset.TokenInfo.Decimals),
+		Decimals:        asset.TokenInfo.Decimals,
+		HolderCountFull: true,
+	}
+
+	for page := 1; ; page++ {
+		if page > maxTokenAccountPages {
+			supply.HolderCountFull = false
+			log.Warn().
+				Str("mint", mint).
+				Int64("holderCount", supply.HolderCount).
+				Msg("Token account scan limit reached, holder count is a lower bound")
+			break
+		}
+
+		var accounts struct {
+			TokenAccounts []struct {
+				Amount json.Number `json:"amount"`
+			} `json:"token_accounts"`
+		}
+		err := f.call(ctx, "getTokenAccounts", map[string]interface{}{
+			"mint":  mint,
+			"page":  page,
+			"limit": tokenAccountsPageSize,
+		}, &accounts)
+		if err != nil {
+			return TokenSupply{}, fmt.Errorf("failed to get token accounts: %w", err)
+		}
+
+		for _, account := range accounts.TokenAccounts {
+			if account.Amount != "" && account.Amount != "0" {
+				supply.HolderCount++
+			}
+		}
+
+		if len(accounts.TokenAccounts) < tokenAccountsPageSize {
+			break
+		}
+	}
+
+	return supply, nil
+}
+
+// call sends a DAS JSON-RPC request and decodes its result into out
+func (f *TokenSupplyFetcher) call(ctx context.Context, method string, params interface{}, out interface{}) error {
+	payloadBytes, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      method + "-request",
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", method, err)
+	}
+
+	requestURL := fmt.Sprintf("%s/?api-key=%s", f.rpcURL, f.heliusAPIKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, bytes.NewReader(payloadBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", logger.RedactError(err))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error,omitempty"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if response.Error != nil {
+		return fmt.Errorf("RPC error: %s (code %d)", response.Error.Message, response.Error.Code)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(response.Result))
+	decoder.UseNumber()
+	if err := decoder.Decode(out); err != nil {
+		return fmt.Errorf("failed to unmarshal %s result: %w", method, err)
+	}
+
+	return nil
+}
+
+// TokenHolderIndexer records supply and holder count snapshots for the
+// configured mints when a transaction moves them. A snapshot scans every
+// token account of the mint, so a mint is snapshotted at most once per
+// snapshot interval however many transactions touch it.
+type TokenHolderIndexer struct {
+	BaseIndexer
+	Tokens []string
+	// RPCURL is the Helius RPC endpoint used for DAS lookups, mainnet when empty
+	RPCURL string
+	// RPCTimeout bounds each RPC call, DefaultHeliusRPCTimeout when zero
+	RPCTimeout       time.Duration
+	snapshotInterval time.Duration
+
+	// snapshots holds when each mint was last snapshotted
+	snapshots     map[string]time.Time
+	snapshotsLock sync.Mutex
+}
+
+func NewTokenHolderIndexer(id string, params json.RawMessage) (Indexer, error) {
+	base := NewBaseIndexer(id, params)
+
+	var holderParams models.TokenHolderParams
+	if err := json.Unmarshal(params, &holderParams); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal token holder parameters: %w", err)
+	}
+
+	if len(holderParams.Tokens) == 0 {
+		return nil, fmt.Errorf("at least one token address is required")
+	}
+
+	snapshotInterval := DefaultHolderSnapshotInterval
+	if holderParams.SnapshotInterval != "" {
+		interval, err := time.ParseDuration(holderParams.SnapshotInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid snapshot interval: %w", err)
+		}
+		snapshotInterval = interval
+	}
+
+	return &TokenHolderIndexer{
+		BaseIndexer:      base,
+		Tokens:           holderParams.Tokens,
+		snapshotInterval: snapshotInterval,
+		snapshots:        make(map[string]time.Time),
+	}, nil
+}
+
+func (i *TokenHolderIndexer) Initialize(ctx context.Context, conn *pgx.Conn, targetTable string) error {
+	return i.InitializeWithAPIKey(ctx, conn, targetTable, "")
+}
+
+func (i *TokenHolderIndexer) InitializeWithAPIKey(ctx context.Context, conn *pgx.Conn, targetTable string, heliusAPIKey string) error {
+	if err := i.BaseIndexer.Initialize(ctx, conn, targetTable); err != nil {
+		return err
+	}
+
+	name := tableName(targetTable)
+	targetTable = QuoteTableName(targetTable)
+
+	exists, err := checkTableExists(ctx, conn, name)
+	if err != nil {
+		return fmt.Errorf("failed to check if table exists: %w", err)
+	}
+
+	if !exists {
+		_, err = conn.Exec(ctx, fmt.Sprintf(`
+			CREATE TABLE %s (
+				id SERIAL PRIMARY KEY,
+				mint TEXT NOT NULL,
+				total_supply NUMERIC,
+				decimals INTEGER,
+				holder_count BIGINT,
+				holder_count_exact BOOLEAN NOT NULL DEFAULT TRUE,
+				slot BIGINT NOT NULL,
+				updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+				UNIQUE(mint, slot)
+			)
+		`, targetTable))
+		if err != nil {
+			return fmt.Errorf("failed to create table: %w", err)
+		}
+
+		_, err = conn.Exec(ctx, fmt.Sprintf(`
+			CREATE INDEX %s ON %s(mint);
+			CREATE INDEX %s ON %s(updated_at);
+		`,
+			indexName(name, "mint"), targetTable,
+			indexName(name, "updated_at"), targetTable,
+		))
+		if err != nil {
+			return fmt.Errorf("failed to create indices: %w", err)
+		}
+
+		log.Info().
+			Str("targetTable", targetTable).
+			Msg("Successfully created token holder table")
+	}
+
+	return nil
+}
+
+func (i *TokenHolderIndexer) GetWebhookConfig(indexerID string) (WebhookConfig, error) {
+
+	config := WebhookConfig{
+		WebhookType:      i.webhookType(),
+		AccountAddresses: i.Tokens,
+		TransactionTypes: i.webhookTransactionTypes(nil),
+	}
+
+	return config, nil
+}
+
+// ProcessPayload needs the Helius API key to look up supply, so the service
+// calls ProcessPayloadWithMetadata instead
+func (i *TokenHolderIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload) (ProcessResult, error) {
+	return ProcessResult{}, fmt.Errorf("token holder indexing requires a Helius API key")
+}
+
+// ProcessPayloadWithMetadata reports the rows the payload wrote and the events it carried
+func (i *TokenHolderIndexer) ProcessPayloadWithMetadata(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload, heliusAPIKey string) (ProcessResult, error) {
+	return processWithResult(ctx, payload, func(ctx context.Context) error {
+		return i.processPayloadWithMetadata(ctx, pool, targetTable, payload, heliusAPIKey)
+	})
+}
+
+func (i *TokenHolderIndexer) processPayloadWithMetadata(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload, heliusAPIKey string) error {
+
+	if len(payload.Transaction.Signatures) == 0 {
+		log.Debug().Msg("Skipping payload with no signatures")
+		return nil
+	}
+
+	mints := i.touchedMints(payload)
+	if len(mints) == 0 {
+		log.Debug().Int64("slot", payload.Slot).Msg("Payload does not touch any tracked mint")
+		return nil
+	}
+
+	targetTable = QuoteTableName(targetTable)
+	fetcher := NewTokenSupplyFetcher(i.RPCURL, heliusAPIKey, i.RPCTimeout)
+
+	for _, mint := range mints {
+		if !i.claimSnapshot(mint, time.Now()) {
+			log.Debug().
+				Str("mint", mint).
+				Int64("slot", payload.Slot).
+				Dur("snapshotInterval", i.snapshotInterval).
+				Msg("Mint was snapshotted recently, skipping")
+			continue
+		}
+
+		supply, err := fetcher.FetchTokenSupply(ctx, mint)
+		if err != nil {
+			i.releaseSnapshot(mint)
+			return fmt.Errorf("failed to fetch supply for %s: %w", mint, err)
+		}
+
+		tx, err := beginTx(ctx, pool, i.acquireTimeout)
+		if err != nil {
+			i.releaseSnapshot(mint)
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+
+		written, err := tx.Exec(ctx, fmt.Sprintf(`
+			INSERT INTO %s (
+				mint, total_supply, decimals, holder_count, holder_count_exact, slot, updated_at
+			) VALUES (
+				$1, $2, $3, $4, $5, $6, NOW()
+			) ON CONFLICT (mint, slot)
+			DO UPDATE SET
+				total_supply = EXCLUDED.total_supply,
+				decimals = EXCLUDED.decimals,
+				holder_count = EXCLUDED.holder_count,
+				holder_count_exact = EXCLUDED.holder_count_exact,
+				updated_at = NOW()
+		`, targetTable),
+			mint, supply.TotalSupply, supply.Decimals, supply.HolderCount, supply.HolderCountFull, payload.Slot,
+		)
+		if err != nil {
+			tx.Rollback(ctx)
+			i.releaseSnapshot(mint)
+			return fmt.Errorf("failed to upsert token holders for %s: %w", mint, err)
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			i.releaseSnapshot(mint)
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		recordRows(ctx, written.RowsAffected())
+
+		log.Info().
+			Str("mint", mint).
+			Float64("totalSupply", supply.TotalSupply).
+			Int64("holderCount", supply.HolderCount).
+			Int64("slot", payload.Slot).
+			Msg("Successfully recorded token holder snapshot")
+	}
+
+	return nil
+}
+
+// EnrichTokenMetadata is a no-op, holder snapshots carry no token metadata
+func (i *TokenHolderIndexer) EnrichTokenMetadata(ctx context.Context, pool *pgxpool.Pool, targetTable string, heliusAPIKey string) error {
+	return nil
+}
+
+// claimSnapshot reports whether mint is due a snapshot at now and, if so,
+// marks it as taken so concurrent payloads for the same mint skip it
+func (i *TokenHolderIndexer) claimSnapshot(mint string, now time.Time) bool {
+	i.snapshotsLock.Lock()
+	defer i.snapshotsLock.Unlock()
+
+	if last, ok := i.snapshots[mint]; ok && now.Sub(last) < i.snapshotInterval {
+		return false
+	}
+	i.snapshots[mint] = now
+	return true
+}
+
+// releaseSnapshot forgets a claimed snapshot that failed, so the next
+// transaction touching the mint tries again
+func (i *TokenHolderIndexer) releaseSnapshot(mint string) {
+	i.snapshotsLock.Lock()
+	defer i.snapshotsLock.Unlock()
+
+	delete(i.snapshots, mint)
+}
+
+// touchedMints returns the tracked mints whose balances the payload moved:
+// the mints of its token transfers and token balance changes, or of the
+// pre and post token balances of a raw transaction
+func (i *TokenHolderIndexer) touchedMints(payload models.HeliusWebhookPayload) []string {
+	moved := movedMints(payload)
+
+	var mints []string
+	for _, mint := range i.Tokens {
+		if moved[mint] {
+			mints = append(mints, mint)
+		}
+	}
+	return mints
+}
+
+// movedMints collects the mints whose balances a payload changed
+func movedMints(payload models.HeliusWebhookPayload) map[string]bool {
+	mints := make(map[string]bool)
+	for _, account := range payload.AccountData {
+		mints[account.Account] = true
+	}
+
+	if len(payload.Transaction.EnhancedDetails) == 0 {
+		return mints
+	}
+
+	type tokenBalance struct {
+		Mint string `json:"mint"`
+	}
+	var details struct {
+		TokenTransfers []models.TokenTransfer `json:"tokenTransfers"`
+		AccountData    []models.AccountData   `json:"accountData"`
+		Meta           struct {
+			PreTokenBalances  []tokenBalance `json:"preTokenBalances"`
+			PostTokenBalances []tokenBalance `json:"postTokenBalances"`
+		} `json:"meta"`
+	}
+	// A field of an unexpected type is skipped; whatever decoded is still used
+	if err := json.Unmarshal(payload.Transaction.EnhancedDetails, &details); err != nil {
+		log.Debug().Err(err).Int64("slot", payload.Slot).Msg("Transaction details only partly decoded")
+	}
+
+	for _, transfer := range details.TokenTransfers {
+		mints[transfer.Mint] = true
+	}
+	for _, account := range details.AccountData {
+		for _, change := range account.TokenBalanceChanges {
+			mints[change.Mint] = true
+		}
+	}
+	for _, balance := range details.Meta.PreTokenBalances {
+		mints[balance.Mint] = true
+	}
+	for _, balance := range details.Meta.PostTokenBalances {
+		mints[balance.Mint] = true
+	}
+	delete(mints, "")
+	return mints
+}
@@ -0,0 +1,106 @@
+package indexer
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+const (
+	trackedMint = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+	otherMint   = "Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYB"
+)
+
+func newTestHolderIndexer(t *testing.T, params string) *TokenHolderIndexer {
+	t.Helper()
+
+	idx, err := NewTokenHolderIndexer("test", json.RawMessage(params))
+	if err != nil {
+		t.Fatalf("NewTokenHolderIndexer: %v", err)
+	}
+	return idx.(*TokenHolderIndexer)
+}
+
+func holderPayload(details string) models.HeliusWebhookPayload {
+	return models.HeliusWebhookPayload{
+		Slot:        1,
+		Transaction: models.HeliusTransaction{EnhancedDetails: json.RawMessage(details)},
+	}
+}
+
+func TestTouchedMints(t *testing.T) {
+	idx := newTestHolderIndexer(t, `{"tokens": ["`+trackedMint+`", "`+otherMint+`"]}`)
+
+	tests := []struct {
+		name    string
+		details string
+		want    []string
+	}{
+		{
+			name:    "token transfer",
+			details: `{"tokenTransfers": [{"mint": "` + trackedMint + `", "tokenAmount": 1}]}`,
+			want:    []string{trackedMint},
+		},
+		{
+			name:    "token balance change",
+			details: `{"accountData": [{"account": "a", "tokenBalanceChanges": [{"mint": "` + otherMint + `"}]}]}`,
+			want:    []string{otherMint},
+		},
+		{
+			name:    "raw token balances",
+			details: `{"meta": {"preTokenBalances": [{"mint": "` + trackedMint + `"}], "postTokenBalances": [{"mint": "` + otherMint + `"}]}}`,
+			want:    []string{trackedMint, otherMint},
+		},
+		{
+			name:    "mint only named in the description",
+			details: `{"description": "swapped ` + trackedMint + `", "tokenTransfers": []}`,
+		},
+		{
+			name:    "untracked mint",
+			details: `{"tokenTransfers": [{"mint": "So11111111111111111111111111111111111111112"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := idx.touchedMints(holderPayload(tt.details)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("touchedMints = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClaimSnapshotDebouncesPerMint(t *testing.T) {
+	idx := newTestHolderIndexer(t, `{"tokens": ["`+trackedMint+`"], "snapshotInterval": "1m"}`)
+	now := time.Now()
+
+	if !idx.claimSnapshot(trackedMint, now) {
+		t.Fatal("first snapshot of a mint was not claimed")
+	}
+	if idx.claimSnapshot(trackedMint, now.Add(30*time.Second)) {
+		t.Error("second snapshot within the interval was claimed")
+	}
+	if !idx.claimSnapshot(otherMint, now.Add(30*time.Second)) {
+		t.Error("snapshot of another mint was held back")
+	}
+	if !idx.claimSnapshot(trackedMint, now.Add(time.Minute)) {
+		t.Error("snapshot after the interval was not claimed")
+	}
+
+	idx.releaseSnapshot(trackedMint)
+	if !idx.claimSnapshot(trackedMint, now.Add(time.Minute+time.Second)) {
+		t.Error("snapshot after a released failure was not claimed")
+	}
+}
+
+func TestClaimSnapshotWithoutInterval(t *testing.T) {
+	idx := newTestHolderIndexer(t, `{"tokens": ["`+trackedMint+`"], "snapshotInterval": "0s"}`)
+	now := time.Now()
+
+	if !idx.claimSnapshot(trackedMint, now) || !idx.claimSnapshot(trackedMint, now) {
+		t.Error("a zero snapshot interval held back a snapshot")
+	}
+}
@@ -3,8 +3,10 @@ package indexer
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -17,6 +19,16 @@ type TokenPriceIndexer struct {
 	BaseIndexer
 	Tokens    []string
 	Platforms []string
+	Sources   []string
+	// KeepHistory appends every observed price to the price history table
+	KeepHistory bool
+	// RPCURL is the Helius RPC endpoint used for metadata lookups, mainnet when empty
+	RPCURL string
+	// RPCTimeout bounds each RPC call, DefaultHeliusRPCTimeout when zero
+	RPCTimeout time.Duration
+	// MetadataCache holds the token metadata fetched for this indexer; the
+	// service shares one cache between all its token price indexers
+	MetadataCache *TokenMetadataCache
 }
 
 func NewTokenPriceIndexer(id string, params json.RawMessage) (Indexer, error) {
@@ -32,12 +44,28 @@ func NewTokenPriceIndexer(id string, params json.RawMessage) (Indexer, error) {
 	}
 
 	return &TokenPriceIndexer{
-		BaseIndexer: base,
-		Tokens:      tokenParams.Tokens,
-		Platforms:   tokenParams.Platforms,
+		BaseIndexer:   base,
+		Tokens:        tokenParams.Tokens,
+		Platforms:     tokenParams.Platforms,
+		Sources:       tokenParams.Sources,
+		KeepHistory:   tokenParams.KeepHistory,
+		MetadataCache: NewTokenMetadataCache(),
 	}, nil
 }
 
+// sourceEnabled reports whether the given extractor is configured to run
+func (i *TokenPriceIndexer) sourceEnabled(source string) bool {
+	if len(i.Sources) == 0 {
+		return true
+	}
+	for _, s := range i.Sources {
+		if strings.EqualFold(s, source) {
+			return true
+		}
+	}
+	return false
+}
+
 func (i *TokenPriceIndexer) Initialize(ctx context.Context, conn *pgx.Conn, targetTable string) error {
 
 	return i.InitializeWithAPIKey(ctx, conn, targetTable, "")
@@ -48,9 +76,10 @@ func (i *TokenPriceIndexer) InitializeWithAPIKey(ctx context.Context, conn *pgx.
 		return err
 	}
 
-	targetTable = formatTableName(targetTable)
+	name := tableName(targetTable)
+	targetTable = QuoteTableName(targetTable)
 
-	exists, err := checkTableExists(ctx, conn, targetTable)
+	exists, err := checkTableExists(ctx, conn, name)
 	if err != nil {
 		return fmt.Errorf("failed to check if table exists: %w", err)
 	}
@@ -74,6 +103,7 @@ func (i *TokenPriceIndexer) InitializeWithAPIKey(ctx context.Context, conn *pgx.
 				transaction_id TEXT,
 				updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
 				slot BIGINT NOT NULL,
+				raw_amount NUMERIC,
 				UNIQUE(token_address, platform)
 			)
 		`, targetTable))
@@ -82,15 +112,15 @@ func (i *TokenPriceIndexer) InitializeWithAPIKey(ctx context.Context, conn *pgx.
 		}
 
 		_, err = conn.Exec(ctx, fmt.Sprintf(`
-			CREATE INDEX %s_token_address_idx ON %s(token_address);
-			CREATE INDEX %s_platform_idx ON %s(platform);
-			CREATE INDEX %s_updated_at_idx ON %s(updated_at);
-			CREATE INDEX %s_slot_idx ON %s(slot);
+			CREATE INDEX %s ON %s(token_address);
+			CREATE INDEX %s ON %s(platform);
+			CREATE INDEX %s ON %s(updated_at);
+			CREATE INDEX %s ON %s(slot);
 		`,
-			targetTable, targetTable,
-			targetTable, targetTable,
-			targetTable, targetTable,
-			targetTable, targetTable,
+			indexName(name, "token_address"), targetTable,
+			indexName(name, "platform"), targetTable,
+			indexName(name, "updated_at"), targetTable,
+			indexName(name, "slot"), targetTable,
 		))
 		if err != nil {
 			return fmt.Errorf("failed to create indices: %w", err)
@@ -99,12 +129,23 @@ func (i *TokenPriceIndexer) InitializeWithAPIKey(ctx context.Context, conn *pgx.
 		log.Info().
 			Str("targetTable", targetTable).
 			Msg("Successfully created token price table with enhanced schema")
+	} else if err := addRawAmountColumn(ctx, conn, name); err != nil {
+		return err
 	}
 
-	if heliusAPIKey != "" {
-		log.Info().Strs("tokens", i.Tokens).Msg("Pre-fetching token metadata at initialization")
-		metadataFetcher := NewTokenMetadataFetcher(heliusAPIKey)
-		tokenMetadata := metadataFetcher.FetchMultipleTokenMetadata(ctx, i.Tokens)
+	if i.KeepHistory {
+		if err := i.initializePriceHistoryTable(ctx, conn, name); err != nil {
+			return err
+		}
+	}
+
+	seedPlatforms := i.seedPlatforms()
+	if len(seedPlatforms) == 0 {
+		log.Debug().Str("targetTable", targetTable).Msg("No platforms configured, skipping token metadata pre-seed")
+	} else if heliusAPIKey != "" {
+		log.Info().Strs("tokens", i.Tokens).Strs("platforms", seedPlatforms).Msg("Pre-fetching token metadata at initialization")
+		metadataFetcher := NewTokenMetadataFetcher(i.RPCURL, heliusAPIKey, i.RPCTimeout, i.MetadataCache)
+		tokenMetadata := metadataFetcher.FetchTokenMetadataBatch(ctx, i.Tokens)
 
 		if len(tokenMetadata) > 0 {
 			for tokenAddr, metadata := range tokenMetadata {
@@ -114,7 +155,7 @@ func (i *TokenPriceIndexer) InitializeWithAPIKey(ctx context.Context, conn *pgx.
 					Str("symbol", metadata.Symbol).
 					Msg("Initializing token metadata in database")
 
-				for _, platform := range []string{"UNKNOWN", "JUPITER", "RAYDIUM", "ORCA", "OPENBOOK"} {
+				for _, platform := range seedPlatforms {
 					_, err := conn.Exec(ctx, fmt.Sprintf(`
 						INSERT INTO %s (
 							token_address, token_name, token_symbol, platform, 
@@ -139,25 +180,64 @@ func (i *TokenPriceIndexer) InitializeWithAPIKey(ctx context.Context, conn *pgx.
 	return nil
 }
 
+// seedPlatforms lists the platforms that get a metadata row per token at
+// initialization. Only explicitly configured platforms are seeded, so an
+// indexer tracking every platform starts with an empty table instead of zero
+// price rows for platforms it may never see.
+func (i *TokenPriceIndexer) seedPlatforms() []string {
+	platforms := make([]string, 0, len(i.Platforms))
+	seen := make(map[string]bool, len(i.Platforms))
+	for _, p := range i.Platforms {
+		p = strings.ToUpper(strings.TrimSpace(p))
+		if p == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		platforms = append(platforms, p)
+	}
+	return platforms
+}
+
+// sourceTransactionTypes maps the enabled price sources to the Helius
+// transaction types that carry them. Balance changes are read off whatever
+// transactions arrive, so a balance-only indexer still needs every type.
+func (i *TokenPriceIndexer) sourceTransactionTypes() []string {
+	var types []string
+	if i.sourceEnabled(models.TokenSourceSwap) {
+		types = append(types, "SWAP")
+	}
+	if i.sourceEnabled(models.TokenSourceTransfer) {
+		types = append(types, "TRANSFER")
+	}
+	return types
+}
+
 func (i *TokenPriceIndexer) GetWebhookConfig(indexerID string) (WebhookConfig, error) {
 
 	config := WebhookConfig{
-		WebhookType:      "enhanced",
+		WebhookType:      i.webhookType(),
 		AccountAddresses: i.Tokens,
-		TransactionTypes: []string{"ANY"},
+		TransactionTypes: i.webhookTransactionTypes(i.sourceTransactionTypes()),
 	}
 
 	return config, nil
 }
 
-func (i *TokenPriceIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload) error {
+// ProcessPayload reports the rows the payload wrote and the events it carried
+func (i *TokenPriceIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload) (ProcessResult, error) {
+	return processWithResult(ctx, payload, func(ctx context.Context) error {
+		return i.processPayload(ctx, pool, targetTable, payload)
+	})
+}
+
+func (i *TokenPriceIndexer) processPayload(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload) error {
 
 	if len(payload.Transaction.Signatures) == 0 {
 		log.Debug().Msg("Skipping payload with no signatures")
 		return nil
 	}
 
-	targetTable = formatTableName(targetTable)
+	targetTable = QuoteTableName(targetTable)
 
 	log.Info().
 		Strs("tracking_tokens", i.Tokens).
@@ -195,17 +275,19 @@ func (i *TokenPriceIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.Po
 		transactionID = payload.Transaction.Signatures[0]
 	}
 
+	swapsEnabled := i.sourceEnabled(models.TokenSourceSwap)
+
 	switch {
-	case enhancedDetails["type"] == "SWAP":
+	case swapsEnabled && enhancedDetails["type"] == "SWAP":
 
 		return i.processSwapTransaction(ctx, pool, targetTable, enhancedDetails, source, payload.Slot, transactionID)
 
-	case enhancedDetails["type"] == "JUPITER_SWAP":
+	case swapsEnabled && enhancedDetails["type"] == "JUPITER_SWAP":
 
 		return i.processJupiterSwap(ctx, pool, targetTable, enhancedDetails, source, payload.Slot, transactionID)
 	}
 
-	if events, hasEvents := enhancedDetails["events"].([]interface{}); hasEvents && len(events) > 0 {
+	if events, hasEvents := enhancedDetails["events"].([]interface{}); swapsEnabled && hasEvents && len(events) > 0 {
 		for _, eventRaw := range events {
 			event, ok := eventRaw.(map[string]interface{})
 			if !ok {
@@ -225,7 +307,7 @@ func (i *TokenPriceIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.Po
 		}
 	}
 
-	if tokenTransfers, hasTransfers := enhancedDetails["tokenTransfers"].([]interface{}); hasTransfers && len(tokenTransfers) > 0 {
+	if tokenTransfers, hasTransfers := enhancedDetails["tokenTransfers"].([]interface{}); i.sourceEnabled(models.TokenSourceTransfer) && hasTransfers && len(tokenTransfers) > 0 {
 		log.Info().Int("transferCount", len(tokenTransfers)).Msg("Found token transfers")
 
 		for _, transferRaw := range tokenTransfers {
@@ -236,12 +318,16 @@ func (i *TokenPriceIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.Po
 		}
 	}
 
-	if nativeBalances, hasNative := enhancedDetails["nativeBalanceChanges"].([]interface{}); hasNative && len(nativeBalances) > 0 {
+	if nativeBalances, hasNative := enhancedDetails["nativeBalanceChanges"].([]interface{}); i.sourceEnabled(models.TokenSourceTransfer) && hasNative && len(nativeBalances) > 0 {
 		log.Info().Int("nativeBalanceCount", len(nativeBalances)).Msg("Found native balance changes")
 
+		if err := i.processNativeBalanceChanges(ctx, pool, targetTable, nativeBalances, source, payload.Slot, transactionID); err != nil {
+			log.Error().Err(err).Msg("Error processing native balance changes")
+
+		}
 	}
 
-	if tokenBalances, hasBalances := enhancedDetails["tokenBalances"].([]interface{}); hasBalances && len(tokenBalances) > 0 {
+	if tokenBalances, hasBalances := enhancedDetails["tokenBalances"].([]interface{}); i.sourceEnabled(models.TokenSourceBalance) && hasBalances && len(tokenBalances) > 0 {
 		log.Info().Int("balanceCount", len(tokenBalances)).Msg("Found token balances")
 
 		for _, balanceRaw := range tokenBalances {
@@ -302,12 +388,8 @@ func (i *TokenPriceIndexer) processTokenTransfer(ctx context.Context, pool *pgxp
 		tokenName = name
 	}
 
-	var amount float64
-	if amountVal, ok := transfer["tokenAmount"].(float64); ok {
-		amount = amountVal
-	} else if amountVal, ok := transfer["amount"].(float64); ok {
-		amount = amountVal
-	}
+	// Without an API key the decimals come from the transfer or the metadata cache
+	amount, scaled, rawAmount := i.transferAmount(ctx, transfer, mint, "")
 
 	var priceUSD float64 = 0
 	if usdValue, ok := transfer["usdValue"].(float64); ok {
@@ -319,24 +401,26 @@ func (i *TokenPriceIndexer) processTokenTransfer(ctx context.Context, pool *pgxp
 		Str("symbol", tokenSymbol).
 		Str("name", tokenName).
 		Float64("amount", amount).
+		Bool("amountScaled", scaled).
 		Float64("priceUSD", priceUSD).
 		Msg("Extracted token data from transfer")
 
-	tx, err := pool.Begin(ctx)
+	tx, err := beginTx(ctx, pool, i.acquireTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx, fmt.Sprintf(`
+	written, err := tx.Exec(ctx, fmt.Sprintf(`
         INSERT INTO %s (
             token_address, token_name, token_symbol, platform, 
             price_usd, price_sol, volume_24h, market_cap, liquidity,
-            price_change_24h, total_supply, transaction_id, updated_at, slot
+            price_change_24h, total_supply, transaction_id, updated_at, slot, raw_amount
         ) VALUES (
-            $1, $2, $3, $4, $5, $6, NULL, NULL, NULL, NULL, NULL, $7, NOW(), $8
+            $1, $2, $3, $4, $5, $6, NULL, NULL, NULL, NULL, NULL, $7, NOW(), $8, $9
         ) ON CONFLICT (token_address, platform) 
         DO UPDATE SET 
+            raw_amount = COALESCE(EXCLUDED.raw_amount, %s.raw_amount),
             token_name = CASE WHEN EXCLUDED.token_name != '' AND %s.token_name IS NULL THEN EXCLUDED.token_name ELSE %s.token_name END,
             token_symbol = CASE WHEN EXCLUDED.token_symbol != '' AND %s.token_symbol IS NULL THEN EXCLUDED.token_symbol ELSE %s.token_symbol END,
             price_usd = CASE WHEN EXCLUDED.price_usd > 0 THEN EXCLUDED.price_usd ELSE %s.price_usd END,
@@ -344,18 +428,23 @@ func (i *TokenPriceIndexer) processTokenTransfer(ctx context.Context, pool *pgxp
             updated_at = CASE WHEN EXCLUDED.slot > %s.slot THEN NOW() ELSE %s.updated_at END,
             slot = GREATEST(EXCLUDED.slot, %s.slot),
             transaction_id = CASE WHEN EXCLUDED.slot > %s.slot THEN EXCLUDED.transaction_id ELSE %s.transaction_id END
-    `, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable),
+    `, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable),
 		mint, tokenName, tokenSymbol, platform,
-		priceUSD, amount, transactionID, slot,
+		priceUSD, scaledAmount(amount, scaled), transactionID, slot, rawAmount,
 	)
 
 	if err != nil {
 		return fmt.Errorf("failed to insert/update token price: %w", err)
 	}
 
+	if err := i.appendPriceHistory(ctx, tx, targetTable, mint, platform, priceUSD, amount, slot, transactionID); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	recordRows(ctx, written.RowsAffected())
 
 	log.Info().
 		Str("token", mint).
@@ -406,13 +495,13 @@ func (i *TokenPriceIndexer) processTokenBalance(ctx context.Context, pool *pgxpo
 		return nil
 	}
 
-	tx, err := pool.Begin(ctx)
+	tx, err := beginTx(ctx, pool, i.acquireTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx, fmt.Sprintf(`
+	written, err := tx.Exec(ctx, fmt.Sprintf(`
         INSERT INTO %s (
             token_address, token_name, token_symbol, platform, 
             price_usd, price_sol, transaction_id, updated_at, slot
@@ -441,6 +530,7 @@ func (i *TokenPriceIndexer) processTokenBalance(ctx context.Context, pool *pgxpo
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	recordRows(ctx, written.RowsAffected())
 
 	log.Info().
 		Str("token", mint).
@@ -535,6 +625,34 @@ func (i *TokenPriceIndexer) processJupiterSwap(ctx context.Context, pool *pgxpoo
 	return nil
 }
 
+// tokenPriceUpsert returns the upsert processJupiterToken and
+// processSwapTokenData write a token's market data with. Every reference to
+// the target table uses the one argument, so none can be left without it.
+func tokenPriceUpsert(targetTable string) string {
+	return fmt.Sprintf(`
+        INSERT INTO %[1]s (
+            token_address, token_name, token_symbol, platform, 
+            price_usd, price_sol, volume_24h, market_cap, liquidity,
+            price_change_24h, total_supply, transaction_id, updated_at, slot
+        ) VALUES (
+            $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW(), $13
+        ) ON CONFLICT (token_address, platform) 
+        DO UPDATE SET 
+            token_name = CASE WHEN EXCLUDED.token_name != '' THEN EXCLUDED.token_name ELSE %[1]s.token_name END,
+            token_symbol = CASE WHEN EXCLUDED.token_symbol != '' THEN EXCLUDED.token_symbol ELSE %[1]s.token_symbol END,
+            price_usd = CASE WHEN EXCLUDED.price_usd > 0 THEN EXCLUDED.price_usd ELSE %[1]s.price_usd END,
+            price_sol = CASE WHEN EXCLUDED.price_sol > 0 THEN EXCLUDED.price_sol ELSE %[1]s.price_sol END,
+            volume_24h = CASE WHEN EXCLUDED.volume_24h > 0 THEN EXCLUDED.volume_24h ELSE %[1]s.volume_24h END,
+            market_cap = CASE WHEN EXCLUDED.market_cap > 0 THEN EXCLUDED.market_cap ELSE %[1]s.market_cap END,
+            liquidity = CASE WHEN EXCLUDED.liquidity > 0 THEN EXCLUDED.liquidity ELSE %[1]s.liquidity END,
+            price_change_24h = CASE WHEN EXCLUDED.price_change_24h != 0 THEN EXCLUDED.price_change_24h ELSE %[1]s.price_change_24h END,
+            total_supply = CASE WHEN EXCLUDED.total_supply > 0 THEN EXCLUDED.total_supply ELSE %[1]s.total_supply END,
+            transaction_id = CASE WHEN EXCLUDED.slot > %[1]s.slot THEN EXCLUDED.transaction_id ELSE %[1]s.transaction_id END,
+            updated_at = CASE WHEN EXCLUDED.slot > %[1]s.slot THEN NOW() ELSE %[1]s.updated_at END,
+            slot = GREATEST(EXCLUDED.slot, %[1]s.slot)
+    `, targetTable)
+}
+
 func (i *TokenPriceIndexer) processJupiterToken(ctx context.Context, pool *pgxpool.Pool, targetTable string, tokenData map[string]interface{}, platform string, slot int64, transactionID string) error {
 
 	mintAddress, ok := tokenData["mint"].(string)
@@ -576,34 +694,13 @@ func (i *TokenPriceIndexer) processJupiterToken(ctx context.Context, pool *pgxpo
 
 	var volume24h, marketCap, liquidity, priceChange24h, totalSupply float64
 
-	tx, err := pool.Begin(ctx)
+	tx, err := beginTx(ctx, pool, i.acquireTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx, fmt.Sprintf(`
-        INSERT INTO %s (
-            token_address, token_name, token_symbol, platform, 
-            price_usd, price_sol, volume_24h, market_cap, liquidity,
-            price_change_24h, total_supply, transaction_id, updated_at, slot
-        ) VALUES (
-            $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW(), $13
-        ) ON CONFLICT (token_address, platform) 
-        DO UPDATE SET 
-            token_name = CASE WHEN EXCLUDED.token_name != '' THEN EXCLUDED.token_name ELSE %s.token_name END,
-            token_symbol = CASE WHEN EXCLUDED.token_symbol != '' THEN EXCLUDED.token_symbol ELSE %s.token_symbol END,
-            price_usd = CASE WHEN EXCLUDED.price_usd > 0 THEN EXCLUDED.price_usd ELSE %s.price_usd END,
-            price_sol = CASE WHEN EXCLUDED.price_sol > 0 THEN EXCLUDED.price_sol ELSE %s.price_sol END,
-            volume_24h = CASE WHEN EXCLUDED.volume_24h > 0 THEN EXCLUDED.volume_24h ELSE %s.volume_24h END,
-            market_cap = CASE WHEN EXCLUDED.market_cap > 0 THEN EXCLUDED.market_cap ELSE %s.market_cap END,
-            liquidity = CASE WHEN EXCLUDED.liquidity > 0 THEN EXCLUDED.liquidity ELSE %s.liquidity END,
-            price_change_24h = CASE WHEN EXCLUDED.price_change_24h != 0 THEN EXCLUDED.price_change_24h ELSE %s.price_change_24h END,
-            total_supply = CASE WHEN EXCLUDED.total_supply > 0 THEN EXCLUDED.total_supply ELSE %s.total_supply END,
-            transaction_id = CASE WHEN EXCLUDED.slot > %s.slot THEN EXCLUDED.transaction_id ELSE %s.transaction_id END,
-            updated_at = CASE WHEN EXCLUDED.slot > %s.slot THEN NOW() ELSE %s.updated_at END,
-            slot = GREATEST(EXCLUDED.slot, %s.slot)
-    `, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable),
+	written, err := tx.Exec(ctx, tokenPriceUpsert(targetTable),
 		mintAddress, tokenName, tokenSymbol, platform,
 		priceUSD, priceSol, volume24h, marketCap, liquidity,
 		priceChange24h, totalSupply, transactionID, slot,
@@ -613,9 +710,14 @@ func (i *TokenPriceIndexer) processJupiterToken(ctx context.Context, pool *pgxpo
 		return fmt.Errorf("failed to update token data: %w", err)
 	}
 
+	if err := i.appendPriceHistory(ctx, tx, targetTable, mintAddress, platform, priceUSD, priceSol, slot, transactionID); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	recordRows(ctx, written.RowsAffected())
 
 	log.Info().
 		Str("token", mintAddress).
@@ -728,13 +830,13 @@ func (i *TokenPriceIndexer) processSwapTokenField(ctx context.Context, pool *pgx
 		}
 	}
 
-	tx, err := pool.Begin(ctx)
+	tx, err := beginTx(ctx, pool, i.acquireTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx, fmt.Sprintf(`
+	written, err := tx.Exec(ctx, fmt.Sprintf(`
         INSERT INTO %s (
             token_address, token_name, token_symbol, platform, 
             price_usd, price_sol, volume_24h, market_cap, liquidity,
@@ -798,9 +900,14 @@ func (i *TokenPriceIndexer) processSwapTokenField(ctx context.Context, pool *pgx
 		return fmt.Errorf("failed to insert/update token from swap: %w", err)
 	}
 
+	if err := i.appendPriceHistory(ctx, tx, targetTable, mint, platform, priceUSD, priceSOL, slot, transactionID); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	recordRows(ctx, written.RowsAffected())
 
 	log.Info().
 		Str("token", mint).
@@ -879,34 +986,13 @@ func (i *TokenPriceIndexer) processSwapTokenData(ctx context.Context, pool *pgxp
 		}
 	}
 
-	tx, err := pool.Begin(ctx)
+	tx, err := beginTx(ctx, pool, i.acquireTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx, fmt.Sprintf(`
-        INSERT INTO %s (
-            token_address, token_name, token_symbol, platform, 
-            price_usd, price_sol, volume_24h, market_cap, liquidity,
-            price_change_24h, total_supply, transaction_id, updated_at, slot
-        ) VALUES (
-            $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW(), $13
-        ) ON CONFLICT (token_address, platform) 
-        DO UPDATE SET 
-            token_name = CASE WHEN EXCLUDED.token_name != '' THEN EXCLUDED.token_name ELSE %s.token_name END,
-            token_symbol = CASE WHEN EXCLUDED.token_symbol != '' THEN EXCLUDED.token_symbol ELSE %s.token_symbol END,
-            price_usd = CASE WHEN EXCLUDED.price_usd > 0 THEN EXCLUDED.price_usd ELSE %s.price_usd END,
-            price_sol = CASE WHEN EXCLUDED.price_sol > 0 THEN EXCLUDED.price_sol ELSE %s.price_sol END,
-            volume_24h = CASE WHEN EXCLUDED.volume_24h > 0 THEN EXCLUDED.volume_24h ELSE %s.volume_24h END,
-            market_cap = CASE WHEN EXCLUDED.market_cap > 0 THEN EXCLUDED.market_cap ELSE %s.market_cap END,
-            liquidity = CASE WHEN EXCLUDED.liquidity > 0 THEN EXCLUDED.liquidity ELSE %s.liquidity END,
-            price_change_24h = CASE WHEN EXCLUDED.price_change_24h != 0 THEN EXCLUDED.price_change_24h ELSE %s.price_change_24h END,
-            total_supply = CASE WHEN EXCLUDED.total_supply > 0 THEN EXCLUDED.total_supply ELSE %s.total_supply END,
-            transaction_id = CASE WHEN EXCLUDED.slot > %s.slot THEN EXCLUDED.transaction_id ELSE %s.transaction_id END,
-            updated_at = CASE WHEN EXCLUDED.slot > %s.slot THEN NOW() ELSE %s.updated_at END,
-            slot = GREATEST(EXCLUDED.slot, %s.slot)
-    `, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable),
+	written, err := tx.Exec(ctx, tokenPriceUpsert(targetTable),
 		mintAddress, tokenName, tokenSymbol, platform,
 		priceUSD, priceSol, volume24h, marketCap, liquidity,
 		priceChange24h, totalSupply, transactionID, slot,
@@ -916,9 +1002,14 @@ func (i *TokenPriceIndexer) processSwapTokenData(ctx context.Context, pool *pgxp
 		return fmt.Errorf("failed to update token from swap: %w", err)
 	}
 
+	if err := i.appendPriceHistory(ctx, tx, targetTable, mintAddress, platform, priceUSD, priceSol, slot, transactionID); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	recordRows(ctx, written.RowsAffected())
 
 	log.Info().
 		Str("token", mintAddress).
@@ -961,21 +1052,28 @@ func NewTokenBorrowIndexer(id string, params json.RawMessage) (Indexer, error) {
 func (i *TokenBorrowIndexer) GetWebhookConfig(indexerID string) (WebhookConfig, error) {
 
 	config := WebhookConfig{
-		WebhookType:      "enhanced",
+		WebhookType:      i.webhookType(),
 		AccountAddresses: i.Tokens,
-		TransactionTypes: []string{"ANY"},
+		TransactionTypes: i.webhookTransactionTypes(tokenBorrowTransactionTypes),
 	}
 
 	return config, nil
 }
 
-func (i *TokenBorrowIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload) error {
+// ProcessPayload reports the rows the payload wrote and the events it carried
+func (i *TokenBorrowIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload) (ProcessResult, error) {
+	return processWithResult(ctx, payload, func(ctx context.Context) error {
+		return i.processPayload(ctx, pool, targetTable, payload)
+	})
+}
+
+func (i *TokenBorrowIndexer) processPayload(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload) error {
 
 	if len(payload.Transaction.Signatures) == 0 {
 		return nil
 	}
 
-	targetTable = formatTableName(targetTable)
+	targetTable = QuoteTableName(targetTable)
 
 	var enhancedDetails map[string]interface{}
 	if err := json.Unmarshal(payload.Transaction.EnhancedDetails, &enhancedDetails); err != nil {
@@ -1072,13 +1170,13 @@ func (i *TokenBorrowIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.P
 			}
 		}
 
-		tx, err := pool.Begin(ctx)
+		tx, err := beginTx(ctx, pool, i.acquireTimeout)
 		if err != nil {
 			return fmt.Errorf("failed to begin transaction: %w", err)
 		}
 		defer tx.Rollback(ctx)
 
-		_, err = tx.Exec(ctx, fmt.Sprintf(`
+		written, err := tx.Exec(ctx, fmt.Sprintf(`
 			INSERT INTO %s (
 				token_address, platform, available_amount, borrow_rate, supply_rate,
 				utilization_rate, total_borrowed, total_supplied, updated_at, slot
@@ -1105,6 +1203,7 @@ func (i *TokenBorrowIndexer) ProcessPayload(ctx context.Context, pool *pgxpool.P
 		if err := tx.Commit(ctx); err != nil {
 			return fmt.Errorf("failed to commit transaction: %w", err)
 		}
+		recordRows(ctx, written.RowsAffected())
 	}
 
 	return nil
@@ -1122,14 +1221,21 @@ func isLendingEvent(eventType string) bool {
 	return lendingEvents[eventType]
 }
 
-func (i *TokenPriceIndexer) ProcessPayloadWithMetadata(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload, heliusAPIKey string) error {
+// ProcessPayloadWithMetadata reports the rows the payload wrote and the events it carried
+func (i *TokenPriceIndexer) ProcessPayloadWithMetadata(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload, heliusAPIKey string) (ProcessResult, error) {
+	return processWithResult(ctx, payload, func(ctx context.Context) error {
+		return i.processPayloadWithMetadata(ctx, pool, targetTable, payload, heliusAPIKey)
+	})
+}
+
+func (i *TokenPriceIndexer) processPayloadWithMetadata(ctx context.Context, pool *pgxpool.Pool, targetTable string, payload models.HeliusWebhookPayload, heliusAPIKey string) error {
 
 	if len(payload.Transaction.Signatures) == 0 {
 		log.Debug().Msg("Skipping payload with no signatures")
 		return nil
 	}
 
-	targetTable = formatTableName(targetTable)
+	targetTable = QuoteTableName(targetTable)
 
 	log.Info().
 		Strs("tracking_tokens", i.Tokens).
@@ -1167,20 +1273,22 @@ func (i *TokenPriceIndexer) ProcessPayloadWithMetadata(ctx context.Context, pool
 		transactionID = payload.Transaction.Signatures[0]
 	}
 
+	swapsEnabled := i.sourceEnabled(models.TokenSourceSwap)
+
 	switch {
-	case enhancedDetails["type"] == "SWAP":
+	case swapsEnabled && enhancedDetails["type"] == "SWAP":
 
 		if err := i.processSwapTransaction(ctx, pool, targetTable, enhancedDetails, source, payload.Slot, transactionID); err != nil {
 			return err
 		}
-	case enhancedDetails["type"] == "JUPITER_SWAP":
+	case swapsEnabled && enhancedDetails["type"] == "JUPITER_SWAP":
 
 		if err := i.processJupiterSwap(ctx, pool, targetTable, enhancedDetails, source, payload.Slot, transactionID); err != nil {
 			return err
 		}
 	}
 
-	if events, hasEvents := enhancedDetails["events"].([]interface{}); hasEvents && len(events) > 0 {
+	if events, hasEvents := enhancedDetails["events"].([]interface{}); swapsEnabled && hasEvents && len(events) > 0 {
 		for _, eventRaw := range events {
 			event, ok := eventRaw.(map[string]interface{})
 			if !ok {
@@ -1200,7 +1308,7 @@ func (i *TokenPriceIndexer) ProcessPayloadWithMetadata(ctx context.Context, pool
 		}
 	}
 
-	if tokenTransfers, hasTransfers := enhancedDetails["tokenTransfers"].([]interface{}); hasTransfers && len(tokenTransfers) > 0 {
+	if tokenTransfers, hasTransfers := enhancedDetails["tokenTransfers"].([]interface{}); i.sourceEnabled(models.TokenSourceTransfer) && hasTransfers && len(tokenTransfers) > 0 {
 		log.Info().Int("transferCount", len(tokenTransfers)).Msg("Found token transfers")
 
 		for _, transferRaw := range tokenTransfers {
@@ -1211,12 +1319,16 @@ func (i *TokenPriceIndexer) ProcessPayloadWithMetadata(ctx context.Context, pool
 		}
 	}
 
-	if nativeBalances, hasNative := enhancedDetails["nativeBalanceChanges"].([]interface{}); hasNative && len(nativeBalances) > 0 {
+	if nativeBalances, hasNative := enhancedDetails["nativeBalanceChanges"].([]interface{}); i.sourceEnabled(models.TokenSourceTransfer) && hasNative && len(nativeBalances) > 0 {
 		log.Info().Int("nativeBalanceCount", len(nativeBalances)).Msg("Found native balance changes")
 
+		if err := i.processNativeBalanceChanges(ctx, pool, targetTable, nativeBalances, source, payload.Slot, transactionID); err != nil {
+			log.Error().Err(err).Msg("Error processing native balance changes")
+
+		}
 	}
 
-	if tokenBalances, hasBalances := enhancedDetails["tokenBalances"].([]interface{}); hasBalances && len(tokenBalances) > 0 {
+	if tokenBalances, hasBalances := enhancedDetails["tokenBalances"].([]interface{}); i.sourceEnabled(models.TokenSourceBalance) && hasBalances && len(tokenBalances) > 0 {
 		log.Info().Int("balanceCount", len(tokenBalances)).Msg("Found token balances")
 
 		for _, balanceRaw := range tokenBalances {
@@ -1254,7 +1366,7 @@ func (i *TokenPriceIndexer) ProcessPayloadWithMetadata(ctx context.Context, pool
 
 		tokensNeedingMetadata := make([]string, 0)
 
-		conn, err := pool.Acquire(ctx)
+		conn, err := acquireConn(ctx, pool, i.acquireTimeout)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to acquire connection from pool")
 			return nil
@@ -1291,11 +1403,11 @@ func (i *TokenPriceIndexer) ProcessPayloadWithMetadata(ctx context.Context, pool
 		if len(tokensNeedingMetadata) > 0 {
 			log.Info().Strs("tokens", tokensNeedingMetadata).Msg("Fetching metadata for tokens with missing info")
 
-			metadataFetcher := NewTokenMetadataFetcher(heliusAPIKey)
+			metadataFetcher := NewTokenMetadataFetcher(i.RPCURL, heliusAPIKey, i.RPCTimeout, i.MetadataCache)
 			tokenMetadata := metadataFetcher.FetchMultipleTokenMetadata(ctx, tokensNeedingMetadata)
 
 			if len(tokenMetadata) > 0 {
-				tx, err := pool.Begin(ctx)
+				tx, err := beginTx(ctx, pool, i.acquireTimeout)
 				if err != nil {
 					log.Error().Err(err).Msg("Failed to begin transaction")
 					return nil
@@ -1388,12 +1500,7 @@ func (i *TokenPriceIndexer) processTokenTransferWithMetadata(ctx context.Context
 		tokenName = name
 	}
 
-	var amount float64
-	if amountVal, ok := transfer["tokenAmount"].(float64); ok {
-		amount = amountVal
-	} else if amountVal, ok := transfer["amount"].(float64); ok {
-		amount = amountVal
-	}
+	amount, scaled, rawAmount := i.transferAmount(ctx, transfer, mint, heliusAPIKey)
 
 	var priceUSD float64 = 0
 	if usdValue, ok := transfer["usdValue"].(float64); ok {
@@ -1401,10 +1508,12 @@ func (i *TokenPriceIndexer) processTokenTransferWithMetadata(ctx context.Context
 	}
 
 	if (tokenName == "" || tokenSymbol == "") && heliusAPIKey != "" {
-		metadataFetcher := NewTokenMetadataFetcher(heliusAPIKey)
+		metadataFetcher := NewTokenMetadataFetcher(i.RPCURL, heliusAPIKey, i.RPCTimeout, i.MetadataCache)
 		metadata, err := metadataFetcher.FetchTokenMetadata(ctx, mint)
 		if err != nil {
-			log.Warn().Err(err).Str("token", mint).Msg("Failed to fetch token metadata from Helius DAS API")
+			if !errors.Is(err, ErrTokenMetadataNotFound) {
+				log.Warn().Err(err).Str("token", mint).Msg("Failed to fetch token metadata from Helius DAS API")
+			}
 		} else {
 
 			if tokenName == "" {
@@ -1422,21 +1531,22 @@ func (i *TokenPriceIndexer) processTokenTransferWithMetadata(ctx context.Context
 		}
 	}
 
-	tx, err := pool.Begin(ctx)
+	tx, err := beginTx(ctx, pool, i.acquireTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx, fmt.Sprintf(`
+	written, err := tx.Exec(ctx, fmt.Sprintf(`
         INSERT INTO %s (
             token_address, token_name, token_symbol, platform, 
             price_usd, price_sol, volume_24h, market_cap, liquidity,
-            price_change_24h, total_supply, transaction_id, updated_at, slot
+            price_change_24h, total_supply, transaction_id, updated_at, slot, raw_amount
         ) VALUES (
-            $1, $2, $3, $4, $5, $6, NULL, NULL, NULL, NULL, NULL, $7, NOW(), $8
+            $1, $2, $3, $4, $5, $6, NULL, NULL, NULL, NULL, NULL, $7, NOW(), $8, $9
         ) ON CONFLICT (token_address, platform) 
         DO UPDATE SET 
+            raw_amount = COALESCE(EXCLUDED.raw_amount, %s.raw_amount),
             token_name = CASE WHEN (EXCLUDED.token_name != '' AND EXCLUDED.token_name != 'UNKNOWN') 
                 THEN (CASE WHEN (%s.token_name IS NULL OR %s.token_name = '' OR %s.token_name = 'UNKNOWN') 
                     THEN EXCLUDED.token_name ELSE %s.token_name END)
@@ -1450,21 +1560,26 @@ func (i *TokenPriceIndexer) processTokenTransferWithMetadata(ctx context.Context
             updated_at = CASE WHEN EXCLUDED.slot > %s.slot THEN NOW() ELSE %s.updated_at END,
             slot = GREATEST(EXCLUDED.slot, %s.slot),
             transaction_id = CASE WHEN EXCLUDED.slot > %s.slot THEN EXCLUDED.transaction_id ELSE %s.transaction_id END
-    `, targetTable,
+    `, targetTable, targetTable,
 		targetTable, targetTable, targetTable, targetTable, targetTable,
 		targetTable, targetTable, targetTable, targetTable, targetTable,
 		targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable),
 		mint, tokenName, tokenSymbol, platform,
-		priceUSD, amount, transactionID, slot,
+		priceUSD, scaledAmount(amount, scaled), transactionID, slot, rawAmount,
 	)
 
 	if err != nil {
 		return fmt.Errorf("failed to insert/update token price: %w", err)
 	}
 
+	if err := i.appendPriceHistory(ctx, tx, targetTable, mint, platform, priceUSD, amount, slot, transactionID); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	recordRows(ctx, written.RowsAffected())
 
 	log.Info().
 		Str("token", mint).
@@ -1484,7 +1599,7 @@ func (i *TokenPriceIndexer) enhanceTokenMetadataInDB(ctx context.Context, pool *
 		return
 	}
 
-	conn, err := pool.Acquire(ctx)
+	conn, err := acquireConn(ctx, pool, i.acquireTimeout)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to acquire connection from pool")
 		return
@@ -1502,15 +1617,17 @@ func (i *TokenPriceIndexer) enhanceTokenMetadataInDB(ctx context.Context, pool *
 		return
 	}
 
-	metadataFetcher := NewTokenMetadataFetcher(heliusAPIKey)
+	metadataFetcher := NewTokenMetadataFetcher(i.RPCURL, heliusAPIKey, i.RPCTimeout, i.MetadataCache)
 	metadata, err := metadataFetcher.FetchTokenMetadata(ctx, tokenAddress)
 	if err != nil {
-		log.Warn().Err(err).Str("token", tokenAddress).Msg("Failed to fetch token metadata from Helius DAS API")
+		if !errors.Is(err, ErrTokenMetadataNotFound) {
+			log.Warn().Err(err).Str("token", tokenAddress).Msg("Failed to fetch token metadata from Helius DAS API")
+		}
 		return
 	}
 
 	if metadata.Name != "" || metadata.Symbol != "" {
-		tx, err := pool.Begin(ctx)
+		tx, err := beginTx(ctx, pool, i.acquireTimeout)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to begin transaction")
 			return
@@ -1557,15 +1674,15 @@ func (i *TokenPriceIndexer) EnrichTokenMetadata(ctx context.Context, pool *pgxpo
 		return nil
 	}
 
-	metadataFetcher := NewTokenMetadataFetcher(heliusAPIKey)
+	metadataFetcher := NewTokenMetadataFetcher(i.RPCURL, heliusAPIKey, i.RPCTimeout, i.MetadataCache)
 
-	tokenMetadata := metadataFetcher.FetchMultipleTokenMetadata(ctx, i.Tokens)
+	tokenMetadata := metadataFetcher.FetchTokenMetadataBatch(ctx, i.Tokens)
 
 	if len(tokenMetadata) == 0 {
 		return nil
 	}
 
-	tx, err := pool.Begin(ctx)
+	tx, err := beginTx(ctx, pool, i.acquireTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
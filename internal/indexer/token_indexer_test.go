@@ -0,0 +1,207 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+func newTestTokenIndexer(tb testing.TB, params string) *TokenPriceIndexer {
+	tb.Helper()
+
+	idx, err := NewTokenPriceIndexer("test", json.RawMessage(params))
+	if err != nil {
+		tb.Fatalf("NewTokenPriceIndexer: %v", err)
+	}
+	return idx.(*TokenPriceIndexer)
+}
+
+// transferPayload is a plain transfer of 2.5 USDC
+func transferPayload(signature string, slot int64) models.HeliusWebhookPayload {
+	details, _ := json.Marshal(map[string]interface{}{
+		"type":   "TRANSFER",
+		"source": "SYSTEM_PROGRAM",
+		"tokenTransfers": []interface{}{map[string]interface{}{
+			"mint":        usdcMint,
+			"tokenAmount": float64(2.5),
+			"decimals":    float64(6),
+			"usdValue":    float64(2.5),
+		}},
+	})
+	return models.HeliusWebhookPayload{
+		Slot: slot,
+		Transaction: models.HeliusTransaction{
+			Signatures:      []string{signature},
+			EnhancedDetails: details,
+		},
+	}
+}
+
+func TestTokenPriceSourcesPickTransactionTypes(t *testing.T) {
+	tests := []struct {
+		sources string
+		want    []string
+	}{
+		{sources: `[]`, want: []string{"SWAP", "TRANSFER"}},
+		{sources: `["swap"]`, want: []string{"SWAP"}},
+		{sources: `["Transfer"]`, want: []string{"TRANSFER"}},
+		{sources: `["balance"]`, want: nil},
+	}
+
+	for _, tt := range tests {
+		idx := newTestTokenIndexer(t, `{"tokens": ["`+usdcMint+`"], "sources": `+tt.sources+`}`)
+		if got := idx.sourceTransactionTypes(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sources %s: transaction types = %v, want %v", tt.sources, got, tt.want)
+		}
+	}
+}
+
+func TestTokenPriceSkipsDisabledTransfers(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		sources  string
+		wantRows int
+	}{
+		{name: "transfer disabled", sources: `["swap", "balance"]`, wantRows: 0},
+		{name: "all sources", sources: `[]`, wantRows: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := testTable(t, pool)
+			idx := newTestTokenIndexer(t, `{"tokens": ["`+usdcMint+`"], "sources": `+tt.sources+`}`)
+			initializeTable(t, pool, idx, table)
+
+			if _, err := idx.ProcessPayload(ctx, pool, table, transferPayload("transfer-sig", 1)); err != nil {
+				t.Fatalf("ProcessPayload: %v", err)
+			}
+
+			var rows int
+			if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+QuoteTableName(table)).Scan(&rows); err != nil {
+				t.Fatalf("count rows: %v", err)
+			}
+			if rows != tt.wantRows {
+				t.Errorf("got %d rows, want %d", rows, tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestTokenPriceSeedPlatforms(t *testing.T) {
+	idx := newTestTokenIndexer(t, `{"tokens": ["`+usdcMint+`"], "platforms": ["raydium", " ORCA ", "Raydium", ""]}`)
+	got := idx.seedPlatforms()
+	if !reflect.DeepEqual(got, []string{"RAYDIUM", "ORCA"}) {
+		t.Errorf("seedPlatforms() = %v, want [RAYDIUM ORCA]", got)
+	}
+
+	idx = newTestTokenIndexer(t, `{"tokens": ["`+usdcMint+`"]}`)
+	if got := idx.seedPlatforms(); len(got) != 0 {
+		t.Errorf("seedPlatforms() without platforms = %v, want none", got)
+	}
+}
+
+func TestTokenPriceInitializePreSeedsConfiguredPlatforms(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	server := newDASServer(t, map[string]dasTokenAsset{usdcMint: dasAsset(usdcMint, "USDC", 6)})
+
+	tests := []struct {
+		name      string
+		platforms string
+		want      []string
+	}{
+		{name: "configured platforms", platforms: `["raydium", "orca"]`, want: []string{"ORCA", "RAYDIUM"}},
+		{name: "no platforms", platforms: `[]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := testTable(t, pool)
+			idx := newTestTokenIndexer(t, `{"tokens": ["`+usdcMint+`"], "platforms": `+tt.platforms+`}`)
+			idx.RPCURL = server.URL
+
+			conn, err := pool.Acquire(ctx)
+			if err != nil {
+				t.Fatalf("acquire connection: %v", err)
+			}
+			defer conn.Release()
+
+			if err := idx.InitializeWithAPIKey(ctx, conn.Conn(), table, "key"); err != nil {
+				t.Fatalf("InitializeWithAPIKey: %v", err)
+			}
+
+			rows, err := pool.Query(ctx, "SELECT platform FROM "+QuoteTableName(table)+" WHERE token_address = $1 ORDER BY platform", usdcMint)
+			if err != nil {
+				t.Fatalf("query seeded rows: %v", err)
+			}
+			var got []string
+			for rows.Next() {
+				var platform string
+				if err := rows.Scan(&platform); err != nil {
+					t.Fatalf("scan platform: %v", err)
+				}
+				got = append(got, platform)
+			}
+			rows.Close()
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("seeded platforms = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenPriceUpsertReferencesTheTableEverywhere(t *testing.T) {
+	query := tokenPriceUpsert(`"prices"`)
+
+	if strings.Contains(query, "%!") {
+		t.Fatalf("upsert has an unfilled placeholder:\n%s", query)
+	}
+	if n := strings.Count(query, `"prices"`); n != 15 {
+		t.Errorf("upsert names the table %d times, want 15", n)
+	}
+	if !strings.Contains(query, "$13") || strings.Contains(query, "$14") {
+		t.Errorf("upsert does not take the 13 values processJupiterToken and processSwapTokenData pass:\n%s", query)
+	}
+}
+
+func TestTokenPriceSwapTokenUpsertsRow(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	table := testTable(t, pool)
+	idx := newTestTokenIndexer(t, `{"tokens": ["`+usdcMint+`"]}`)
+	initializeTable(t, pool, idx, table)
+
+	// The second swap takes the conflict branch of the upsert
+	for _, swap := range []struct {
+		slot  int64
+		price float64
+	}{{slot: 10, price: 1.01}, {slot: 11, price: 0.99}} {
+		tokenData := map[string]interface{}{
+			"mint":   usdcMint,
+			"symbol": "USDC",
+			"price":  map[string]interface{}{"usd": swap.price},
+		}
+		if err := idx.processSwapTokenData(ctx, pool, QuoteTableName(table), tokenData, "RAYDIUM", swap.slot, fmt.Sprintf("sig-%d", swap.slot)); err != nil {
+			t.Fatalf("processSwapTokenData at slot %d: %v", swap.slot, err)
+		}
+	}
+
+	var price float64
+	var slot int64
+	err := pool.QueryRow(ctx, "SELECT price_usd, slot FROM "+QuoteTableName(table)+" WHERE token_address = $1 AND platform = 'RAYDIUM'", usdcMint).Scan(&price, &slot)
+	if err != nil {
+		t.Fatalf("read token row: %v", err)
+	}
+	if slot != 11 || price != 0.99 {
+		t.Errorf("row at slot %d with price %v, want the newer swap at 11 with 0.99", slot, price)
+	}
+}
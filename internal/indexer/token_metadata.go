@@ -3,6 +3,7 @@ package indexer
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,66 +12,82 @@ import (
 	"time"
 
 	"github.com/rs/zerolog/log"
+
+	"github.com/rishavmehra/indexer/pkg/logger"
 )
 
-type TokenMetadataCache struct {
-	cache map[string]TokenMetadata
-	mu    sync.RWMutex
-}
+const (
+	// maxConcurrentMetadataFetches bounds the DAS requests in flight per batch
+	maxConcurrentMetadataFetches = 5
+	// maxAssetBatchSize is the most ids getAssetBatch accepts in one call
+	maxAssetBatchSize = 1000
+)
 
-type TokenMetadata struct {
-	Symbol    string
-	Name      string
-	Decimals  int
-	FetchedAt time.Time
+// ErrTokenMetadataNotFound is returned for a token the DAS API has no asset
+// for. Unlike a failed request it is cached, so the token isn't looked up
+// again on every transfer.
+var ErrTokenMetadataNotFound = errors.New("token metadata not found")
+
+// dasTokenAsset is the part of a DAS asset the metadata is read from
+type dasTokenAsset struct {
+	ID      string `json:"id"`
+	Content struct {
+		Metadata struct {
+			Name   string `json:"name"`
+			Symbol string `json:"symbol"`
+		} `json:"metadata"`
+	} `json:"content"`
+	TokenInfo struct {
+		Decimals int `json:"decimals"`
+	} `json:"token_info"`
 }
 
-func NewTokenMetadataCache() *TokenMetadataCache {
-	return &TokenMetadataCache{
-		cache: make(map[string]TokenMetadata),
+func (a dasTokenAsset) metadata() TokenMetadata {
+	return TokenMetadata{
+		Symbol:   a.Content.Metadata.Symbol,
+		Name:     a.Content.Metadata.Name,
+		Decimals: a.TokenInfo.Decimals,
 	}
 }
 
-func (c *TokenMetadataCache) Get(tokenAddress string) (TokenMetadata, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	metadata, found := c.cache[strings.ToLower(tokenAddress)]
-
-	if found && time.Since(metadata.FetchedAt) > 24*time.Hour {
-		return metadata, false
-	}
-
-	return metadata, found
-}
-
-func (c *TokenMetadataCache) Set(tokenAddress string, metadata TokenMetadata) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	metadata.FetchedAt = time.Now()
-	c.cache[strings.ToLower(tokenAddress)] = metadata
-}
-
-var metadataCache = NewTokenMetadataCache()
-
 type TokenMetadataFetcher struct {
+	rpcURL       string
 	heliusAPIKey string
 	httpClient   *http.Client
+	cache        *TokenMetadataCache
 }
 
-func NewTokenMetadataFetcher(heliusAPIKey string) *TokenMetadataFetcher {
+// NewTokenMetadataFetcher creates a fetcher that reads and fills cache, or a
+// fresh cache of its own when cache is nil
+func NewTokenMetadataFetcher(rpcURL, heliusAPIKey string, timeout time.Duration, cache *TokenMetadataCache) *TokenMetadataFetcher {
+	if rpcURL == "" {
+		rpcURL = DefaultHeliusRPCURL
+	}
+	if timeout <= 0 {
+		timeout = DefaultHeliusRPCTimeout
+	}
+	if cache == nil {
+		cache = NewTokenMetadataCache()
+	}
+
 	return &TokenMetadataFetcher{
+		rpcURL:       strings.TrimRight(rpcURL, "/"),
 		heliusAPIKey: heliusAPIKey,
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout: timeout,
 		},
+		cache: cache,
 	}
 }
 
 func (f *TokenMetadataFetcher) FetchTokenMetadata(ctx context.Context, tokenAddress string) (TokenMetadata, error) {
 
-	if metadata, found := metadataCache.Get(tokenAddress); found {
+	if metadata, found := f.cache.Get(tokenAddress); found {
 		return metadata, nil
 	}
+	if f.cache.NotFound(tokenAddress) {
+		return TokenMetadata{}, ErrTokenMetadataNotFound
+	}
 
 	log.Info().Str("token", tokenAddress).Msg("Fetching token metadata from Helius DAS API")
 
@@ -88,7 +105,7 @@ func (f *TokenMetadataFetcher) FetchTokenMetadata(ctx context.Context, tokenAddr
 		return TokenMetadata{}, fmt.Errorf("failed to marshal getAsset request: %w", err)
 	}
 
-	requestURL := fmt.Sprintf("https://mainnet.helius-rpc.com/?api-key=%s", f.heliusAPIKey)
+	requestURL := fmt.Sprintf("%s/?api-key=%s", f.rpcURL, f.heliusAPIKey)
 	req, err := http.NewRequestWithContext(ctx, "POST", requestURL, strings.NewReader(string(payloadBytes)))
 	if err != nil {
 		return TokenMetadata{}, fmt.Errorf("failed to create request: %w", err)
@@ -98,7 +115,7 @@ func (f *TokenMetadataFetcher) FetchTokenMetadata(ctx context.Context, tokenAddr
 
 	resp, err := f.httpClient.Do(req)
 	if err != nil {
-		return TokenMetadata{}, fmt.Errorf("failed to send request: %w", err)
+		return TokenMetadata{}, fmt.Errorf("failed to send request: %w", logger.RedactError(err))
 	}
 	defer resp.Body.Close()
 
@@ -109,19 +126,13 @@ func (f *TokenMetadataFetcher) FetchTokenMetadata(ctx context.Context, tokenAddr
 
 	log.Debug().Str("response", string(body)).Msg("Raw DAS API response")
 
+	if resp.StatusCode != http.StatusOK {
+		return TokenMetadata{}, fmt.Errorf("DAS API returned status %d", resp.StatusCode)
+	}
+
 	var response struct {
-		Result struct {
-			Content struct {
-				Metadata struct {
-					Name   string `json:"name"`
-					Symbol string `json:"symbol"`
-				} `json:"metadata"`
-			} `json:"content"`
-			TokenInfo struct {
-				Decimals int `json:"decimals"`
-			} `json:"token_info"`
-		} `json:"result"`
-		Error *struct {
+		Result *dasTokenAsset `json:"result"`
+		Error  *struct {
 			Code    int    `json:"code"`
 			Message string `json:"message"`
 		} `json:"error,omitempty"`
@@ -132,16 +143,18 @@ func (f *TokenMetadataFetcher) FetchTokenMetadata(ctx context.Context, tokenAddr
 	}
 
 	if response.Error != nil {
+		if strings.Contains(strings.ToLower(response.Error.Message), "not found") {
+			return TokenMetadata{}, f.notFound(tokenAddress)
+		}
 		return TokenMetadata{}, fmt.Errorf("RPC error: %s (code %d)", response.Error.Message, response.Error.Code)
 	}
-
-	metadata := TokenMetadata{
-		Symbol:   response.Result.Content.Metadata.Symbol,
-		Name:     response.Result.Content.Metadata.Name,
-		Decimals: response.Result.TokenInfo.Decimals,
+	if response.Result == nil {
+		return TokenMetadata{}, f.notFound(tokenAddress)
 	}
 
-	metadataCache.Set(tokenAddress, metadata)
+	metadata := response.Result.metadata()
+
+	f.cache.Set(tokenAddress, metadata)
 
 	log.Info().
 		Str("token", tokenAddress).
@@ -153,27 +166,177 @@ func (f *TokenMetadataFetcher) FetchTokenMetadata(ctx context.Context, tokenAddr
 	return metadata, nil
 }
 
+// notFound caches that the token has no metadata and returns
+// ErrTokenMetadataNotFound
+func (f *TokenMetadataFetcher) notFound(tokenAddress string) error {
+	f.cache.SetNotFound(tokenAddress)
+	log.Info().Str("token", tokenAddress).Msg("Token has no DAS metadata, skipping lookups for a while")
+	return ErrTokenMetadataNotFound
+}
+
+// FetchTokenMetadataBatch fetches metadata for many tokens with getAssetBatch,
+// maxAssetBatchSize tokens per request. Tokens a batch fails for or leaves
+// out are fetched one by one with FetchMultipleTokenMetadata.
+func (f *TokenMetadataFetcher) FetchTokenMetadataBatch(ctx context.Context, tokenAddresses []string) map[string]TokenMetadata {
+	results := make(map[string]TokenMetadata)
+
+	var missing []string
+	for _, addr := range tokenAddresses {
+		if metadata, found := f.cache.Get(addr); found {
+			results[addr] = metadata
+			continue
+		}
+		if f.cache.NotFound(addr) {
+			continue
+		}
+		missing = append(missing, addr)
+	}
+
+	var fallback []string
+	for start := 0; start < len(missing); start += maxAssetBatchSize {
+		if ctx.Err() != nil {
+			break
+		}
+
+		end := start + maxAssetBatchSize
+		if end > len(missing) {
+			end = len(missing)
+		}
+		batch := missing[start:end]
+
+		assets, err := f.fetchAssetBatch(ctx, batch)
+		if err != nil {
+			log.Warn().Err(err).Int("tokens", len(batch)).Msg("getAssetBatch failed, fetching token metadata one by one")
+			fallback = append(fallback, batch...)
+			continue
+		}
+
+		for _, addr := range batch {
+			asset, found := assets[strings.ToLower(addr)]
+			if !found {
+				fallback = append(fallback, addr)
+				continue
+			}
+			metadata := asset.metadata()
+			f.cache.Set(addr, metadata)
+			results[addr] = metadata
+		}
+	}
+
+	if len(fallback) > 0 && ctx.Err() == nil {
+		for addr, metadata := range f.FetchMultipleTokenMetadata(ctx, fallback) {
+			results[addr] = metadata
+		}
+	}
+
+	log.Info().
+		Int("requested", len(tokenAddresses)).
+		Int("fetched", len(results)).
+		Msg("Fetched token metadata in batches")
+
+	return results
+}
+
+// fetchAssetBatch requests the assets of ids in one getAssetBatch call and
+// returns them keyed by lower-cased id. Ids without an asset are left out.
+func (f *TokenMetadataFetcher) fetchAssetBatch(ctx context.Context, ids []string) (map[string]dasTokenAsset, error) {
+	payloadBytes, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      "metadata-batch-request",
+		"method":  "getAssetBatch",
+		"params": map[string]interface{}{
+			"ids": ids,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal getAssetBatch request: %w", err)
+	}
+
+	requestURL := fmt.Sprintf("%s/?api-key=%s", f.rpcURL, f.heliusAPIKey)
+	req, err := http.NewRequestWithContext(ctx, "POST", requestURL, strings.NewReader(string(payloadBytes)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", logger.RedactError(err))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DAS API returned status %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Result []*dasTokenAsset `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error,omitempty"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if response.Error != nil {
+		return nil, fmt.Errorf("RPC error: %s (code %d)", response.Error.Message, response.Error.Code)
+	}
+
+	assets := make(map[string]dasTokenAsset, len(response.Result))
+	for _, asset := range response.Result {
+		if asset != nil && asset.ID != "" {
+			assets[strings.ToLower(asset.ID)] = *asset
+		}
+	}
+	return assets, nil
+}
+
+// FetchMultipleTokenMetadata fetches metadata for several tokens, at most
+// maxConcurrentMetadataFetches at a time. If ctx is done before every fetch
+// finishes, the remaining fetches are abandoned and whatever completed so far
+// is returned.
 func (f *TokenMetadataFetcher) FetchMultipleTokenMetadata(ctx context.Context, tokenAddresses []string) map[string]TokenMetadata {
 	results := make(map[string]TokenMetadata)
 	var wg sync.WaitGroup
 	var mu sync.Mutex
+	sem := make(chan struct{}, maxConcurrentMetadataFetches)
 
+dispatch:
 	for _, addr := range tokenAddresses {
 
-		if metadata, found := metadataCache.Get(addr); found {
+		if metadata, found := f.cache.Get(addr); found {
 			mu.Lock()
 			results[addr] = metadata
 			mu.Unlock()
 			continue
 		}
 
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break dispatch
+		}
+
 		wg.Add(1)
 		go func(tokenAddr string) {
-			defer wg.Done()
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
 
 			metadata, err := f.FetchTokenMetadata(ctx, tokenAddr)
 			if err != nil {
-				log.Warn().Err(err).Str("token", tokenAddr).Msg("Failed to fetch token metadata from Helius DAS API")
+				if errors.Is(err, ErrTokenMetadataNotFound) {
+					log.Debug().Str("token", tokenAddr).Msg("Token has no DAS metadata")
+				} else if ctx.Err() == nil {
+					log.Warn().Err(err).Str("token", tokenAddr).Msg("Failed to fetch token metadata from Helius DAS API")
+				}
 				return
 			}
 
@@ -183,6 +346,31 @@ func (f *TokenMetadataFetcher) FetchMultipleTokenMetadata(ctx context.Context, t
 		}(addr)
 	}
 
-	wg.Wait()
-	return results
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return results
+	case <-ctx.Done():
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	partial := make(map[string]TokenMetadata, len(results))
+	for addr, metadata := range results {
+		partial[addr] = metadata
+	}
+
+	log.Warn().
+		Err(ctx.Err()).
+		Int("requested", len(tokenAddresses)).
+		Int("fetched", len(partial)).
+		Msg("Token metadata fetch cancelled, returning partial results")
+
+	return partial
 }
@@ -0,0 +1,367 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// dasServer is a mock Helius RPC answering getAsset and getAssetBatch from
+// assets. Tokens listed in slow block until the request is cancelled, tokens
+// listed in unbatched are left out of batch responses, and failBatch makes
+// every getAssetBatch call fail.
+type dasServer struct {
+	*httptest.Server
+	assets     map[string]dasTokenAsset
+	slow       map[string]bool
+	unbatched  map[string]bool
+	failBatch  bool
+	calls      atomic.Int32
+	batchCalls atomic.Int32
+}
+
+func newDASServer(t *testing.T, assets map[string]dasTokenAsset) *dasServer {
+	t.Helper()
+
+	s := &dasServer{assets: assets, slow: map[string]bool{}, unbatched: map[string]bool{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *dasServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.calls.Add(1)
+
+	var req struct {
+		Method string `json:"method"`
+		Params struct {
+			ID  string   `json:"id"`
+			IDs []string `json:"ids"`
+		} `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch req.Method {
+	case "getAsset":
+		if s.slow[req.Params.ID] {
+			<-r.Context().Done()
+			return
+		}
+		asset, ok := s.assets[req.Params.ID]
+		if !ok {
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]interface{}{"code": -32000, "message": "Asset not found"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": asset})
+	case "getAssetBatch":
+		s.batchCalls.Add(1)
+		if s.failBatch {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		result := make([]*dasTokenAsset, 0, len(req.Params.IDs))
+		for _, id := range req.Params.IDs {
+			if asset, ok := s.assets[id]; ok && !s.unbatched[id] {
+				result = append(result, &asset)
+			} else {
+				result = append(result, nil)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"result": result})
+	default:
+		http.Error(w, "unknown method", http.StatusBadRequest)
+	}
+}
+
+func dasAsset(id, symbol string, decimals int) dasTokenAsset {
+	var asset dasTokenAsset
+	asset.ID = id
+	asset.Content.Metadata.Symbol = symbol
+	asset.Content.Metadata.Name = symbol + " token"
+	asset.TokenInfo.Decimals = decimals
+	return asset
+}
+
+func TestFetchMultipleTokenMetadataReturnsPartialResultsOnCancel(t *testing.T) {
+	server := newDASServer(t, map[string]dasTokenAsset{"fast": dasAsset("fast", "FAST", 6)})
+	server.slow["slow"] = true
+	fetcher := NewTokenMetadataFetcher(server.URL, "key", time.Minute, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	results := fetcher.FetchMultipleTokenMetadata(ctx, []string{"fast", "slow"})
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("fetch returned after %s, want it to stop soon after the context was cancelled", elapsed)
+	}
+
+	if _, ok := results["fast"]; !ok {
+		t.Error("completed fetch for fast is missing from the partial results")
+	}
+	if _, ok := results["slow"]; ok {
+		t.Error("results hold the fetch that never completed")
+	}
+}
+
+func TestFetchMultipleTokenMetadataStopsDispatchingWhenCancelled(t *testing.T) {
+	server := newDASServer(t, nil)
+	fetcher := NewTokenMetadataFetcher(server.URL, "key", time.Minute, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := fetcher.FetchMultipleTokenMetadata(ctx, []string{"a", "b", "c", "d", "e", "f", "g"})
+	if len(results) != 0 {
+		t.Errorf("got %d results from a cancelled context, want none", len(results))
+	}
+	if calls := server.calls.Load(); calls > maxConcurrentMetadataFetches {
+		t.Errorf("server saw %d requests after cancellation, want at most %d", calls, maxConcurrentMetadataFetches)
+	}
+}
+
+func TestTokenMetadataFetcherRefetchesAfterTTL(t *testing.T) {
+	server := newDASServer(t, map[string]dasTokenAsset{"mint": dasAsset("mint", "MINT", 6)})
+	fetcher := NewTokenMetadataFetcher(server.URL, "key", time.Minute, NewTokenMetadataCacheWithSize(10, 20*time.Millisecond, time.Hour))
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := fetcher.FetchTokenMetadata(ctx, "mint"); err != nil {
+			t.Fatalf("FetchTokenMetadata: %v", err)
+		}
+	}
+	if calls := server.calls.Load(); calls != 1 {
+		t.Fatalf("server saw %d requests within the TTL, want 1", calls)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if _, err := fetcher.FetchTokenMetadata(ctx, "mint"); err != nil {
+		t.Fatalf("FetchTokenMetadata: %v", err)
+	}
+	if calls := server.calls.Load(); calls != 2 {
+		t.Errorf("server saw %d requests, want the expired entry fetched again", calls)
+	}
+}
+
+func TestTokenMetadataFetchersUseTheirOwnCache(t *testing.T) {
+	server := newDASServer(t, map[string]dasTokenAsset{"mint": dasAsset("mint", "DAS", 6)})
+	first, second := NewTokenMetadataCacheWithSize(10, time.Hour, time.Hour), NewTokenMetadataCacheWithSize(10, time.Hour, time.Hour)
+	first.Set("mint", TokenMetadata{Symbol: "CACHED"})
+
+	cached := NewTokenMetadataFetcher(server.URL, "key", time.Minute, first)
+	fresh := NewTokenMetadataFetcher(server.URL, "key", time.Minute, second)
+	ctx := context.Background()
+
+	if metadata, err := cached.FetchTokenMetadata(ctx, "mint"); err != nil || metadata.Symbol != "CACHED" {
+		t.Errorf("fetcher with a filled cache got %+v, %v; want the cached entry", metadata, err)
+	}
+	if metadata, err := fresh.FetchTokenMetadata(ctx, "mint"); err != nil || metadata.Symbol != "DAS" {
+		t.Errorf("fetcher with an empty cache got %+v, %v; want the entry fetched from DAS", metadata, err)
+	}
+
+	if calls := server.calls.Load(); calls != 1 {
+		t.Errorf("server saw %d requests, want only the fetcher with the empty cache to call it", calls)
+	}
+	if got, _ := first.Get("mint"); got.Symbol != "CACHED" {
+		t.Errorf("first cache holds %+v, want it untouched by the other fetcher", got)
+	}
+	if first.Len() != 1 || second.Len() != 1 {
+		t.Errorf("caches hold %d and %d entries, want one each", first.Len(), second.Len())
+	}
+}
+
+func TestTokenMetadataFetchersWithoutCacheShareNothing(t *testing.T) {
+	t.Parallel()
+
+	server := newDASServer(t, map[string]dasTokenAsset{"mint": dasAsset("mint", "MINT", 6)})
+	first := NewTokenMetadataFetcher(server.URL, "key", time.Minute, nil)
+	second := NewTokenMetadataFetcher(server.URL, "key", time.Minute, nil)
+	ctx := context.Background()
+
+	if _, err := first.FetchTokenMetadata(ctx, "mint"); err != nil {
+		t.Fatalf("first FetchTokenMetadata: %v", err)
+	}
+	if _, err := second.FetchTokenMetadata(ctx, "mint"); err != nil {
+		t.Fatalf("second FetchTokenMetadata: %v", err)
+	}
+	if calls := server.calls.Load(); calls != 2 {
+		t.Errorf("server saw %d requests, want each fetcher to fetch with its own fresh cache", calls)
+	}
+}
+
+func TestNewTokenPriceIndexerHasItsOwnCache(t *testing.T) {
+	t.Parallel()
+
+	first := newTestTokenIndexer(t, `{"tokens": ["`+usdcMint+`"]}`)
+	second := newTestTokenIndexer(t, `{"tokens": ["`+usdcMint+`"]}`)
+
+	if first.MetadataCache == nil || first.MetadataCache == second.MetadataCache {
+		t.Fatal("token price indexers built separately share a metadata cache")
+	}
+	first.MetadataCache.Set(usdcMint, TokenMetadata{Symbol: "USDC"})
+	if _, ok := second.MetadataCache.Get(usdcMint); ok {
+		t.Error("metadata cached by one indexer is visible to the other")
+	}
+}
+
+func TestFetchTokenMetadataCachesNotFound(t *testing.T) {
+	server := newDASServer(t, nil)
+	fetcher := NewTokenMetadataFetcher(server.URL, "key", time.Minute, NewTokenMetadataCacheWithSize(10, time.Hour, 30*time.Millisecond))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := fetcher.FetchTokenMetadata(ctx, "unknown"); !errors.Is(err, ErrTokenMetadataNotFound) {
+			t.Fatalf("FetchTokenMetadata error = %v, want ErrTokenMetadataNotFound", err)
+		}
+	}
+	if calls := server.calls.Load(); calls != 1 {
+		t.Fatalf("server saw %d requests within the negative TTL, want 1", calls)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, err := fetcher.FetchTokenMetadata(ctx, "unknown"); !errors.Is(err, ErrTokenMetadataNotFound) {
+		t.Fatalf("FetchTokenMetadata error = %v, want ErrTokenMetadataNotFound", err)
+	}
+	if calls := server.calls.Load(); calls != 2 {
+		t.Errorf("server saw %d requests, want the token looked up again after the negative TTL", calls)
+	}
+}
+
+func TestFetchTokenMetadataDoesNotCacheTransientErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		serve http.HandlerFunc
+	}{
+		{
+			name:  "server error",
+			serve: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) },
+		},
+		{
+			name: "rpc error",
+			serve: func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]interface{}{"code": -32603, "message": "Internal error"}})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				tt.serve(w, r)
+			}))
+			t.Cleanup(server.Close)
+			fetcher := NewTokenMetadataFetcher(server.URL, "key", time.Minute, nil)
+
+			for i := 0; i < 2; i++ {
+				_, err := fetcher.FetchTokenMetadata(context.Background(), "mint")
+				if err == nil || errors.Is(err, ErrTokenMetadataNotFound) {
+					t.Fatalf("FetchTokenMetadata error = %v, want a transient error", err)
+				}
+			}
+			if calls.Load() != 2 {
+				t.Errorf("server saw %d requests, want every lookup after a transient error to go out", calls.Load())
+			}
+		})
+	}
+}
+
+func TestFetchTokenMetadataBatchUsesOneCall(t *testing.T) {
+	assets := make(map[string]dasTokenAsset)
+	var tokens []string
+	for i := 0; i < 25; i++ {
+		id := fmt.Sprintf("mint-%d", i)
+		assets[id] = dasAsset(id, fmt.Sprintf("T%d", i), 6)
+		tokens = append(tokens, id)
+	}
+	server := newDASServer(t, assets)
+	fetcher := NewTokenMetadataFetcher(server.URL, "key", time.Minute, nil)
+
+	results := fetcher.FetchTokenMetadataBatch(context.Background(), tokens)
+	if len(results) != len(tokens) {
+		t.Fatalf("got metadata for %d tokens, want %d", len(results), len(tokens))
+	}
+	if results["mint-7"].Symbol != "T7" || results["mint-7"].Decimals != 6 {
+		t.Errorf("mint-7 = %+v, want T7 with 6 decimals", results["mint-7"])
+	}
+	if calls := server.calls.Load(); calls != 1 {
+		t.Errorf("server saw %d requests for %d tokens, want 1", calls, len(tokens))
+	}
+
+	// Everything fetched is cached, so fetching again makes no call
+	fetcher.FetchTokenMetadataBatch(context.Background(), tokens)
+	if calls := server.calls.Load(); calls != 1 {
+		t.Errorf("server saw %d requests after fetching cached tokens, want 1", calls)
+	}
+}
+
+func TestFetchTokenMetadataBatchSplitsAtBatchLimit(t *testing.T) {
+	assets := make(map[string]dasTokenAsset)
+	var tokens []string
+	for i := 0; i < maxAssetBatchSize+1; i++ {
+		id := fmt.Sprintf("mint-%d", i)
+		assets[id] = dasAsset(id, "T", 6)
+		tokens = append(tokens, id)
+	}
+	server := newDASServer(t, assets)
+	fetcher := NewTokenMetadataFetcher(server.URL, "key", time.Minute, NewTokenMetadataCacheWithSize(2*maxAssetBatchSize, time.Hour, time.Hour))
+
+	results := fetcher.FetchTokenMetadataBatch(context.Background(), tokens)
+	if len(results) != len(tokens) {
+		t.Errorf("got metadata for %d tokens, want %d", len(results), len(tokens))
+	}
+	if calls := server.batchCalls.Load(); calls != 2 || server.calls.Load() != 2 {
+		t.Errorf("server saw %d batch calls and %d requests, want 2 of each", calls, server.calls.Load())
+	}
+}
+
+func TestFetchTokenMetadataBatchFallsBackPerToken(t *testing.T) {
+	tests := []struct {
+		name        string
+		setup       func(*dasServer)
+		wantSingles int32
+	}{
+		{
+			name:        "token left out of the batch",
+			setup:       func(s *dasServer) { s.unbatched["b"] = true },
+			wantSingles: 1,
+		},
+		{
+			name:        "batch call fails",
+			setup:       func(s *dasServer) { s.failBatch = true },
+			wantSingles: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newDASServer(t, map[string]dasTokenAsset{
+				"a": dasAsset("a", "A", 6),
+				"b": dasAsset("b", "B", 9),
+				"c": dasAsset("c", "C", 2),
+			})
+			tt.setup(server)
+			fetcher := NewTokenMetadataFetcher(server.URL, "key", time.Minute, nil)
+
+			results := fetcher.FetchTokenMetadataBatch(context.Background(), []string{"a", "b", "c"})
+			for id, symbol := range map[string]string{"a": "A", "b": "B", "c": "C"} {
+				if results[id].Symbol != symbol {
+					t.Errorf("%s = %+v, want symbol %s", id, results[id], symbol)
+				}
+			}
+			if singles := server.calls.Load() - server.batchCalls.Load(); singles != tt.wantSingles {
+				t.Errorf("server saw %d getAsset calls, want %d", singles, tt.wantSingles)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// WrappedSOLMint is the mint of wrapped SOL. Native SOL has no mint, so
+// token price indexers track it under this one.
+const WrappedSOLMint = "So11111111111111111111111111111111111111112"
+
+// nativeMovement is what processNativeBalanceChanges reads off the native
+// balance changes of a transaction
+type nativeMovement struct {
+	// Amount is the SOL received across all accounts
+	Amount float64
+	// USDValue is the dollar value Helius put on the changes, zero when it
+	// gave none
+	USDValue float64
+}
+
+// tracksWrappedSOL reports whether wrapped SOL is one of the tracked tokens
+func (i *TokenPriceIndexer) tracksWrappedSOL() bool {
+	for _, t := range i.Tokens {
+		if strings.EqualFold(t, WrappedSOLMint) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNativeBalanceChanges sums the native balance changes of a transaction.
+// Each change carries its lamports as amount or nativeBalanceChange; only the
+// credited side is counted, so a transfer isn't counted twice and fees paid
+// are left out.
+func parseNativeBalanceChanges(changes []interface{}) nativeMovement {
+	var movement nativeMovement
+	for _, changeRaw := range changes {
+		change, ok := changeRaw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		lamports, ok := change["amount"].(float64)
+		if !ok {
+			lamports, ok = change["nativeBalanceChange"].(float64)
+		}
+		if !ok || lamports <= 0 {
+			continue
+		}
+
+		movement.Amount += lamports / lamportsPerSOL
+		if usdValue, ok := change["usdValue"].(float64); ok {
+			movement.USDValue += math.Abs(usdValue)
+		}
+	}
+	return movement
+}
+
+// processNativeBalanceChanges records the SOL a transaction moved as a wrapped
+// SOL price: one SOL per SOL, and a dollar price when Helius valued the
+// changes. It does nothing unless wrapped SOL is tracked.
+func (i *TokenPriceIndexer) processNativeBalanceChanges(ctx context.Context, pool *pgxpool.Pool, targetTable string, changes []interface{}, platform string, slot int64, transactionID string) error {
+	if !i.tracksWrappedSOL() {
+		return nil
+	}
+
+	movement := parseNativeBalanceChanges(changes)
+	if movement.Amount == 0 {
+		return nil
+	}
+
+	var priceUSD float64
+	if movement.USDValue > 0 {
+		priceUSD = movement.USDValue / movement.Amount
+	}
+	const priceSOL = 1.0
+
+	tx, err := beginTx(ctx, pool, i.acquireTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	written, err := tx.Exec(ctx, fmt.Sprintf(`
+        INSERT INTO %s (
+            token_address, token_name, token_symbol, platform,
+            price_usd, price_sol, transaction_id, updated_at, slot
+        ) VALUES (
+            $1, 'Wrapped SOL', 'SOL', $2, $3, $4, $5, NOW(), $6
+        ) ON CONFLICT (token_address, platform)
+        DO UPDATE SET
+            price_usd = CASE WHEN EXCLUDED.price_usd > 0 AND EXCLUDED.slot >= %s.slot THEN EXCLUDED.price_usd ELSE %s.price_usd END,
+            price_sol = EXCLUDED.price_sol,
+            transaction_id = CASE WHEN EXCLUDED.slot > %s.slot THEN EXCLUDED.transaction_id ELSE %s.transaction_id END,
+            updated_at = CASE WHEN EXCLUDED.slot > %s.slot THEN NOW() ELSE %s.updated_at END,
+            slot = GREATEST(EXCLUDED.slot, %s.slot)
+    `, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable, targetTable),
+		WrappedSOLMint, platform, priceUSD, priceSOL, transactionID, slot,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update wrapped SOL from native balance changes: %w", err)
+	}
+
+	if err := i.appendPriceHistory(ctx, tx, targetTable, WrappedSOLMint, platform, priceUSD, priceSOL, slot, transactionID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	recordRows(ctx, written.RowsAffected())
+
+	log.Info().
+		Str("platform", platform).
+		Float64("amountSOL", movement.Amount).
+		Float64("priceUSD", priceUSD).
+		Int64("slot", slot).
+		Msg("Processed native balance changes for wrapped SOL")
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// nativePayload is a transfer of 2 SOL worth $300, with the fee the sender
+// paid
+func nativePayload(signature string, slot int64) models.HeliusWebhookPayload {
+	details, _ := json.Marshal(map[string]interface{}{
+		"type":   "TRANSFER",
+		"source": "SYSTEM_PROGRAM",
+		"nativeBalanceChanges": []interface{}{
+			map[string]interface{}{"account": "sender", "amount": float64(-2_000_005_000), "usdValue": float64(-300)},
+			map[string]interface{}{"account": "receiver", "amount": float64(2_000_000_000), "usdValue": float64(300)},
+		},
+	})
+	return models.HeliusWebhookPayload{
+		Slot: slot,
+		Transaction: models.HeliusTransaction{
+			Signatures:      []string{signature},
+			EnhancedDetails: details,
+		},
+	}
+}
+
+func TestParseNativeBalanceChanges(t *testing.T) {
+	tests := []struct {
+		name       string
+		changes    string
+		wantAmount float64
+		wantUSD    float64
+	}{
+		{
+			name:       "transfer counts the credited side",
+			changes:    `[{"amount": -1500000000, "usdValue": -225}, {"amount": 1500000000, "usdValue": 225}]`,
+			wantAmount: 1.5,
+			wantUSD:    225,
+		},
+		{
+			name:       "nativeBalanceChange field",
+			changes:    `[{"nativeBalanceChange": 500000000}]`,
+			wantAmount: 0.5,
+		},
+		{
+			name:    "only fees paid",
+			changes: `[{"amount": -5000}]`,
+		},
+		{
+			name:       "unexpected entries skipped",
+			changes:    `["not an object", {"amount": "1"}, {"amount": 1000000000}]`,
+			wantAmount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var changes []interface{}
+			if err := json.Unmarshal([]byte(tt.changes), &changes); err != nil {
+				t.Fatalf("unmarshal changes: %v", err)
+			}
+
+			movement := parseNativeBalanceChanges(changes)
+			if math.Abs(movement.Amount-tt.wantAmount) > 1e-9 || math.Abs(movement.USDValue-tt.wantUSD) > 1e-9 {
+				t.Errorf("got %+v, want amount %v and USD value %v", movement, tt.wantAmount, tt.wantUSD)
+			}
+		})
+	}
+}
+
+func TestTracksWrappedSOL(t *testing.T) {
+	if !newTestTokenIndexer(t, `{"tokens": ["`+usdcMint+`", "`+WrappedSOLMint+`"]}`).tracksWrappedSOL() {
+		t.Error("indexer tracking wrapped SOL reported as not tracking it")
+	}
+	if newTestTokenIndexer(t, `{"tokens": ["`+usdcMint+`"]}`).tracksWrappedSOL() {
+		t.Error("indexer tracking only USDC reported as tracking wrapped SOL")
+	}
+}
+
+func TestTokenPriceRecordsNativeBalanceChanges(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		params  string
+		wantRow bool
+	}{
+		{name: "wrapped SOL tracked", params: `{"tokens": ["` + WrappedSOLMint + `"]}`, wantRow: true},
+		{name: "wrapped SOL not tracked", params: `{"tokens": ["` + usdcMint + `"]}`},
+		{name: "transfer source disabled", params: `{"tokens": ["` + WrappedSOLMint + `"], "sources": ["swap"]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := testTable(t, pool)
+			idx := newTestTokenIndexer(t, tt.params)
+			initializeTable(t, pool, idx, table)
+
+			if _, err := idx.ProcessPayload(ctx, pool, table, nativePayload("native-sig", 7)); err != nil {
+				t.Fatalf("ProcessPayload: %v", err)
+			}
+
+			var count int
+			if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+QuoteTableName(table)+" WHERE token_address = $1", WrappedSOLMint).Scan(&count); err != nil {
+				t.Fatalf("count rows: %v", err)
+			}
+			if (count == 1) != tt.wantRow {
+				t.Fatalf("got %d wrapped SOL rows, want row %v", count, tt.wantRow)
+			}
+			if !tt.wantRow {
+				return
+			}
+
+			var priceUSD, priceSOL float64
+			var transactionID string
+			var slot int64
+			if err := pool.QueryRow(ctx, "SELECT price_usd, price_sol, transaction_id, slot FROM "+QuoteTableName(table)+" WHERE token_address = $1", WrappedSOLMint).Scan(&priceUSD, &priceSOL, &transactionID, &slot); err != nil {
+				t.Fatalf("read wrapped SOL row: %v", err)
+			}
+			if priceSOL != 1 || priceUSD != 150 || transactionID != "native-sig" || slot != 7 {
+				t.Errorf("got price_sol %v, price_usd %v, transaction %s, slot %d; want 1, 150, native-sig, 7", priceSOL, priceUSD, transactionID, slot)
+			}
+		})
+	}
+}
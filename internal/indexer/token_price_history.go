@@ -0,0 +1,74 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// PriceHistoryTable is the companion table a token price indexer with
+// keepHistory appends every observed price to
+func PriceHistoryTable(targetTable string) string {
+	return QuoteTableName(tableName(targetTable) + "_price_history")
+}
+
+// initializePriceHistoryTable creates the price history table when missing
+func (i *TokenPriceIndexer) initializePriceHistoryTable(ctx context.Context, conn *pgx.Conn, targetTable string) error {
+	historyTable := PriceHistoryTable(targetTable)
+
+	_, err := conn.Exec(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id BIGSERIAL PRIMARY KEY,
+			token_address TEXT NOT NULL,
+			platform TEXT NOT NULL,
+			price_usd NUMERIC NOT NULL,
+			price_sol NUMERIC,
+			slot BIGINT NOT NULL,
+			transaction_id TEXT,
+			observed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)
+	`, historyTable))
+	if err != nil {
+		return fmt.Errorf("failed to create price history table: %w", err)
+	}
+
+	_, err = conn.Exec(ctx, fmt.Sprintf(`
+		CREATE INDEX IF NOT EXISTS %s ON %s(token_address, observed_at);
+	`, indexName(historyTable, "token_observed"), historyTable))
+	if err != nil {
+		return fmt.Errorf("failed to create price history indices: %w", err)
+	}
+
+	log.Info().
+		Str("historyTable", historyTable).
+		Msg("Token price history table ready")
+
+	return nil
+}
+
+// appendPriceHistory records an observed price in the history table, inside
+// the transaction that updates the latest price. Nothing is written unless
+// the indexer keeps history, or for rows without a USD price.
+func (i *TokenPriceIndexer) appendPriceHistory(ctx context.Context, tx pgx.Tx, targetTable, token, platform string, priceUSD, priceSOL float64, slot int64, transactionID string) error {
+	if !i.KeepHistory || priceUSD <= 0 {
+		return nil
+	}
+
+	written, err := tx.Exec(ctx, fmt.Sprintf(`
+		INSERT INTO %s (
+			token_address, platform, price_usd, price_sol, slot, transaction_id
+		) VALUES (
+			$1, $2, $3, $4, $5, $6
+		)
+	`, PriceHistoryTable(targetTable)),
+		token, platform, priceUSD, priceSOL, slot, nullableText(transactionID),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to append token price history: %w", err)
+	}
+	recordRows(ctx, written.RowsAffected())
+
+	return nil
+}
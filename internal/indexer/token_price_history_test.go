@@ -0,0 +1,64 @@
+package indexer
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTokenPriceKeepHistory(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		keepHistory string
+		wantHistory bool
+	}{
+		{name: "latest only", keepHistory: `false`, wantHistory: false},
+		{name: "with history", keepHistory: `true`, wantHistory: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := testTable(t, pool)
+			idx := newTestTokenIndexer(t, `{"tokens": ["`+usdcMint+`"], "keepHistory": `+tt.keepHistory+`}`)
+			initializeTable(t, pool, idx, table)
+
+			for slot, signature := range []string{"transfer-1", "transfer-2"} {
+				if _, err := idx.ProcessPayload(ctx, pool, table, transferPayload(signature, int64(slot+1))); err != nil {
+					t.Fatalf("ProcessPayload %s: %v", signature, err)
+				}
+			}
+
+			// The latest price table keeps one row per token and platform
+			var latest int
+			if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+QuoteTableName(table)).Scan(&latest); err != nil {
+				t.Fatalf("count latest rows: %v", err)
+			}
+			if latest != 1 {
+				t.Errorf("got %d latest price rows, want 1", latest)
+			}
+
+			historyTable := PriceHistoryTable(table)
+			var exists bool
+			if err := pool.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", historyTable).Scan(&exists); err != nil {
+				t.Fatalf("look up history table: %v", err)
+			}
+			if exists != tt.wantHistory {
+				t.Fatalf("history table exists = %v, want %v", exists, tt.wantHistory)
+			}
+			if !tt.wantHistory {
+				return
+			}
+
+			// The history table gets a row for every observed price
+			var history, maxSlot int64
+			if err := pool.QueryRow(ctx, "SELECT COUNT(*), MAX(slot) FROM "+historyTable+" WHERE token_address = $1", usdcMint).Scan(&history, &maxSlot); err != nil {
+				t.Fatalf("count history rows: %v", err)
+			}
+			if history != 2 || maxSlot != 2 {
+				t.Errorf("got %d history rows up to slot %d, want 2 up to slot 2", history, maxSlot)
+			}
+		})
+	}
+}
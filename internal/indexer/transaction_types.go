@@ -0,0 +1,84 @@
+package indexer
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// anyTransactionType asks Helius for every transaction touching an address
+const anyTransactionType = "ANY"
+
+// Helius transaction types each indexer asks for by default, so transactions
+// it would throw away are not delivered at all
+var (
+	nftBidTransactionTypes = []string{
+		"NFT_BID", "NFT_BID_CANCELLED",
+	}
+	// collectionOfferTransactionTypes are added when an NFT bid indexer also
+	// tracks collection offers and their fills
+	collectionOfferTransactionTypes = []string{
+		"NFT_GLOBAL_BID", "NFT_GLOBAL_BID_CANCELLED", "NFT_SALE",
+	}
+	nftPriceTransactionTypes = []string{
+		"NFT_LISTING", "NFT_CANCEL_LISTING", "NFT_SALE",
+	}
+	tokenBorrowTransactionTypes = []string{
+		"LOAN", "TAKE_LOAN", "REPAY_LOAN", "FORECLOSE_LOAN", "DEPOSIT", "WITHDRAW",
+	}
+)
+
+// parseTransactionTypes reads the transactionTypes override shared by every
+// indexer type's params. Types are upper-cased as Helius expects them.
+func parseTransactionTypes(params json.RawMessage) []string {
+	var override struct {
+		TransactionTypes []string `json:"transactionTypes"`
+	}
+	if err := json.Unmarshal(params, &override); err != nil {
+		return nil
+	}
+
+	var types []string
+	for _, t := range override.TransactionTypes {
+		if t = strings.ToUpper(strings.TrimSpace(t)); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// parseWebhookType reads the webhookType param shared by every indexer type
+func parseWebhookType(params json.RawMessage) string {
+	var cfg struct {
+		WebhookType string `json:"webhookType"`
+	}
+	if err := json.Unmarshal(params, &cfg); err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(cfg.WebhookType))
+}
+
+// webhookType is the Helius webhook type the indexer asks for
+func (b *BaseIndexer) webhookType() string {
+	if b.WebhookType == models.WebhookTypeRaw {
+		return models.WebhookTypeRaw
+	}
+	return models.WebhookTypeEnhanced
+}
+
+// webhookTransactionTypes returns the transaction types configured in params,
+// or defaults when the params don't override them. Raw webhooks carry
+// unparsed transactions, so Helius can only filter them as ANY.
+func (b *BaseIndexer) webhookTransactionTypes(defaults []string) []string {
+	if b.webhookType() == models.WebhookTypeRaw {
+		return []string{anyTransactionType}
+	}
+	if len(b.TransactionTypes) > 0 {
+		return b.TransactionTypes
+	}
+	if len(defaults) == 0 {
+		return []string{anyTransactionType}
+	}
+	return defaults
+}
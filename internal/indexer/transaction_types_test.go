@@ -0,0 +1,163 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestGetWebhookConfigTransactionTypes(t *testing.T) {
+	const collection = "J1S9H3QjnRtBbbuD4HjPV6RpRhwuk4zKbxsnCHuTgh9w"
+
+	tests := []struct {
+		name        string
+		newIndexer  func(id string, params json.RawMessage) (Indexer, error)
+		params      string
+		webhookType string
+		want        []string
+	}{
+		{
+			name:       "nft bids",
+			newIndexer: NewNFTBidIndexer,
+			params:     `{"collection": "` + collection + `"}`,
+			want:       []string{"NFT_BID", "NFT_BID_CANCELLED"},
+		},
+		{
+			name:       "nft bids with collection offers",
+			newIndexer: NewNFTBidIndexer,
+			params:     `{"collection": "` + collection + `", "collectionOffers": true}`,
+			want:       []string{"NFT_BID", "NFT_BID_CANCELLED", "NFT_GLOBAL_BID", "NFT_GLOBAL_BID_CANCELLED", "NFT_SALE"},
+		},
+		{
+			name:       "nft prices",
+			newIndexer: NewNFTPriceIndexer,
+			params:     `{"collection": "` + collection + `"}`,
+			want:       []string{"NFT_LISTING", "NFT_CANCEL_LISTING", "NFT_SALE"},
+		},
+		{
+			name:       "token prices",
+			newIndexer: NewTokenPriceIndexer,
+			params:     `{"tokens": ["` + usdcMint + `"]}`,
+			want:       []string{"SWAP", "TRANSFER"},
+		},
+		{
+			name:       "token prices from swaps",
+			newIndexer: NewTokenPriceIndexer,
+			params:     `{"tokens": ["` + usdcMint + `"], "sources": ["swap"]}`,
+			want:       []string{"SWAP"},
+		},
+		{
+			name:       "token prices from balances",
+			newIndexer: NewTokenPriceIndexer,
+			params:     `{"tokens": ["` + usdcMint + `"], "sources": ["balance"]}`,
+			want:       []string{"ANY"},
+		},
+		{
+			name:       "token borrow",
+			newIndexer: NewTokenBorrowIndexer,
+			params:     `{"tokens": ["` + usdcMint + `"]}`,
+			want:       []string{"LOAN", "TAKE_LOAN", "REPAY_LOAN", "FORECLOSE_LOAN", "DEPOSIT", "WITHDRAW"},
+		},
+		{
+			name:       "token holders",
+			newIndexer: NewTokenHolderIndexer,
+			params:     `{"tokens": ["` + usdcMint + `"]}`,
+			want:       []string{"ANY"},
+		},
+		{
+			name:       "override",
+			newIndexer: NewNFTPriceIndexer,
+			params:     `{"collection": "` + collection + `", "transactionTypes": ["nft_sale", " nft_listing "]}`,
+			want:       []string{"NFT_SALE", "NFT_LISTING"},
+		},
+		{
+			name:        "raw webhook",
+			newIndexer:  NewNFTPriceIndexer,
+			params:      `{"collection": "` + collection + `", "webhookType": "raw"}`,
+			webhookType: "raw",
+			want:        []string{"ANY"},
+		},
+		{
+			name:        "raw token holders",
+			newIndexer:  NewTokenHolderIndexer,
+			params:      `{"tokens": ["` + usdcMint + `"], "webhookType": " RAW ", "transactionTypes": ["TRANSFER"]}`,
+			webhookType: "raw",
+			want:        []string{"ANY"},
+		},
+		{
+			name:       "explicit enhanced",
+			newIndexer: NewTokenHolderIndexer,
+			params:     `{"tokens": ["` + usdcMint + `"], "webhookType": "enhanced", "transactionTypes": ["TRANSFER"]}`,
+			want:       []string{"TRANSFER"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx, err := tt.newIndexer("test", json.RawMessage(tt.params))
+			if err != nil {
+				t.Fatalf("new indexer: %v", err)
+			}
+			config, err := idx.GetWebhookConfig("test")
+			if err != nil {
+				t.Fatalf("GetWebhookConfig: %v", err)
+			}
+
+			if !reflect.DeepEqual(config.TransactionTypes, tt.want) {
+				t.Errorf("transaction types = %v, want %v", config.TransactionTypes, tt.want)
+			}
+			wantType := tt.webhookType
+			if wantType == "" {
+				wantType = "enhanced"
+			}
+			if config.WebhookType != wantType {
+				t.Errorf("webhook type = %q, want %q", config.WebhookType, wantType)
+			}
+		})
+	}
+}
+
+func TestAllocateAddressesSendsIndexerTransactionTypes(t *testing.T) {
+	fake, server := newHeliusFake(t)
+	client := newPoolClient(server.URL, "")
+	ctx := context.Background()
+
+	idx, err := NewNFTPriceIndexer("test", json.RawMessage(`{"collection": "J1S9H3QjnRtBbbuD4HjPV6RpRhwuk4zKbxsnCHuTgh9w"}`))
+	if err != nil {
+		t.Fatalf("NewNFTPriceIndexer: %v", err)
+	}
+	config, err := idx.GetWebhookConfig("test")
+	if err != nil {
+		t.Fatalf("GetWebhookConfig: %v", err)
+	}
+
+	settings := WebhookSettings{WebhookType: config.WebhookType, TransactionTypes: config.TransactionTypes}
+	webhookIDs, err := client.AllocateAddresses(ctx, "http://app.local/webhooks?key=secret", config.AccountAddresses, settings, "indexer-1")
+	if err != nil {
+		t.Fatalf("AllocateAddresses: %v", err)
+	}
+	if len(webhookIDs) != 1 {
+		t.Fatalf("got webhooks %v, want one", webhookIDs)
+	}
+
+	fake.mu.Lock()
+	sent := fake.webhooks[webhookIDs[0]]
+	fake.mu.Unlock()
+
+	if !reflect.DeepEqual(sent.TransactionTypes, []string{"NFT_LISTING", "NFT_CANCEL_LISTING", "NFT_SALE"}) || sent.WebhookType != "enhanced" {
+		t.Errorf("Helius got a %s webhook for %v, want an enhanced one for the NFT price types", sent.WebhookType, sent.TransactionTypes)
+	}
+
+	// Changing the indexer's types brings the existing webhook in line
+	settings.TransactionTypes = []string{"NFT_SALE"}
+	if _, err := client.AllocateAddresses(ctx, "http://app.local/webhooks?key=secret", config.AccountAddresses, settings, "indexer-1"); err != nil {
+		t.Fatalf("AllocateAddresses: %v", err)
+	}
+	fake.mu.Lock()
+	sent = fake.webhooks[webhookIDs[0]]
+	fake.mu.Unlock()
+	if !reflect.DeepEqual(sent.TransactionTypes, []string{"NFT_SALE"}) {
+		t.Errorf("Helius webhook types = %v, want [NFT_SALE]", sent.TransactionTypes)
+	}
+}
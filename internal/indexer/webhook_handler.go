@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/rs/zerolog/log"
 
@@ -20,20 +21,54 @@ type WebhookHandler struct {
 
 type WebhookProcessor interface {
 	ProcessWebhookPayload(ctx context.Context, webhookID string, payload models.HeliusWebhookPayload) error
+	// WebhookSecret returns the key deliveries for webhookID must carry
+	WebhookSecret(ctx context.Context, webhookID string) (string, error)
 }
 
-var heliusWebhookMapping = make(map[string]string)
+// webhookMappings maps Helius webhook IDs to the indexers they deliver for.
+// Indexers register their webhooks while payloads are being handled, so it
+// is only touched under webhookMappingsMu.
+var (
+	webhookMappingsMu sync.RWMutex
+	webhookMappings   = make(map[string]string)
+)
 
 func RegisterWebhookMapping(heliusWebhookID string, indexerID string) {
-	heliusWebhookMapping[heliusWebhookID] = indexerID
+	webhookMappingsMu.Lock()
+	webhookMappings[heliusWebhookID] = indexerID
+	webhookMappingsMu.Unlock()
+
 	log.Info().
 		Str("heliusWebhookID", heliusWebhookID).
 		Str("indexerID", indexerID).
 		Msg("Registered webhook ID mapping")
 }
 
+// ReplaceWebhookMapping moves the mapping of a recreated webhook to its new ID
+func ReplaceWebhookMapping(oldWebhookID, newWebhookID string) {
+	webhookMappingsMu.Lock()
+	indexerID, found := webhookMappings[oldWebhookID]
+	if found {
+		delete(webhookMappings, oldWebhookID)
+		webhookMappings[newWebhookID] = indexerID
+	}
+	webhookMappingsMu.Unlock()
+
+	if !found {
+		return
+	}
+	log.Info().
+		Str("oldWebhookID", oldWebhookID).
+		Str("newWebhookID", newWebhookID).
+		Str("indexerID", indexerID).
+		Msg("Moved webhook ID mapping to recreated webhook")
+}
+
 func GetIndexerIDFromHeliusWebhookID(heliusWebhookID string) (string, bool) {
-	indexerID, found := heliusWebhookMapping[heliusWebhookID]
+	webhookMappingsMu.RLock()
+	defer webhookMappingsMu.RUnlock()
+
+	indexerID, found := webhookMappings[heliusWebhookID]
 	return indexerID, found
 }
 
@@ -95,8 +130,14 @@ func (h *WebhookHandler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	log.Debug().Str("rawPayload", string(body)).Msg("Received webhook payload")
 
-	if h.heliusClient != nil && webhookSecret != "" {
-		if !h.heliusClient.VerifyWebhookSignature(webhookSecret, string(body)) {
+	if h.heliusClient != nil {
+		expectedSecret, err := h.processor.WebhookSecret(r.Context(), webhookID)
+		if err != nil {
+			log.Error().Err(err).Str("webhookID", webhookID).Msg("Failed to look up webhook secret")
+			http.Error(w, "Invalid signature", http.StatusUnauthorized)
+			return
+		}
+		if !h.heliusClient.VerifyWebhookSignature(expectedSecret, webhookSecret, string(body)) {
 			log.Error().Msg("Invalid webhook signature")
 			http.Error(w, "Invalid signature", http.StatusUnauthorized)
 			return
@@ -131,9 +172,14 @@ func (h *WebhookHandler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"status":"success"}`))
 }
 
+// GetAllWebhookMappings returns a copy of the webhook mappings taken at one
+// point in time, which callers are free to keep or change
 func GetAllWebhookMappings() map[string]string {
-	result := make(map[string]string)
-	for k, v := range heliusWebhookMapping {
+	webhookMappingsMu.RLock()
+	defer webhookMappingsMu.RUnlock()
+
+	result := make(map[string]string, len(webhookMappings))
+	for k, v := range webhookMappings {
 		result[k] = v
 	}
 	return result
@@ -0,0 +1,70 @@
+package indexer
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Run with -race: indexers register webhooks while handlers look them up
+func TestWebhookMappingsConcurrentRegisterAndRead(t *testing.T) {
+	prefix := t.Name() + "-"
+	const writers, perWriter = 4, 50
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				webhookID := fmt.Sprintf("%s%d-%d", prefix, w, i)
+				RegisterWebhookMapping(webhookID, "indexer-"+webhookID)
+				if i%10 == 0 {
+					ReplaceWebhookMapping(webhookID, webhookID+"-recreated")
+				}
+			}
+		}()
+	}
+	for r := 0; r < writers; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				GetIndexerIDFromHeliusWebhookID(fmt.Sprintf("%s%d-%d", prefix, r, i))
+				for webhookID, indexerID := range GetAllWebhookMappings() {
+					if strings.HasPrefix(webhookID, prefix) && !strings.HasPrefix(indexerID, "indexer-"+prefix) {
+						t.Errorf("snapshot maps %s to %s", webhookID, indexerID)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	registered := 0
+	for webhookID := range GetAllWebhookMappings() {
+		if strings.HasPrefix(webhookID, prefix) {
+			registered++
+		}
+	}
+	if registered != writers*perWriter {
+		t.Errorf("got %d mappings, want %d", registered, writers*perWriter)
+	}
+	if indexerID, ok := GetIndexerIDFromHeliusWebhookID(prefix + "0-0-recreated"); !ok || indexerID != "indexer-"+prefix+"0-0" {
+		t.Errorf("recreated webhook maps to %q, %v; want the original indexer", indexerID, ok)
+	}
+}
+
+func TestGetAllWebhookMappingsReturnsCopy(t *testing.T) {
+	webhookID := t.Name()
+	RegisterWebhookMapping(webhookID, "indexer-1")
+
+	snapshot := GetAllWebhookMappings()
+	snapshot[webhookID] = "changed"
+	delete(snapshot, webhookID)
+
+	if indexerID, ok := GetIndexerIDFromHeliusWebhookID(webhookID); !ok || indexerID != "indexer-1" {
+		t.Errorf("mapping = %q, %v after changing a snapshot, want indexer-1", indexerID, ok)
+	}
+}
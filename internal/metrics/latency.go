@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultLatencyWindowSize is the number of most recent samples kept per indexer
+const DefaultLatencyWindowSize = 1000
+
+// LatencySnapshot summarises the samples currently held in a window
+type LatencySnapshot struct {
+	Count int
+	P50   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// LatencyWindow is a fixed-size ring buffer of processing durations
+type LatencyWindow struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+	full    bool
+}
+
+// NewLatencyWindow creates a window that keeps the last size samples
+func NewLatencyWindow(size int) *LatencyWindow {
+	if size <= 0 {
+		size = DefaultLatencyWindowSize
+	}
+	return &LatencyWindow{
+		samples: make([]time.Duration, size),
+	}
+}
+
+// Record adds a sample, evicting the oldest one once the window is full
+func (w *LatencyWindow) Record(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % len(w.samples)
+	if w.next == 0 {
+		w.full = true
+	}
+}
+
+// Snapshot computes percentiles over the samples currently in the window
+func (w *LatencyWindow) Snapshot() LatencySnapshot {
+	w.mu.Lock()
+	n := w.next
+	if w.full {
+		n = len(w.samples)
+	}
+	sorted := make([]time.Duration, n)
+	copy(sorted, w.samples[:n])
+	w.mu.Unlock()
+
+	if n == 0 {
+		return LatencySnapshot{}
+	}
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return LatencySnapshot{
+		Count: n,
+		P50:   Percentile(sorted, 50),
+		P95:   Percentile(sorted, 95),
+		P99:   Percentile(sorted, 99),
+		Max:   sorted[n-1],
+	}
+}
+
+// Percentile returns the nearest-rank percentile p (0-100] of an ascending
+// slice of durations
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	if p <= 0 {
+		return sorted[0]
+	}
+	if p >= 100 {
+		return sorted[len(sorted)-1]
+	}
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[rank-1]
+}
+
+// LatencyTracker keeps a sliding latency window per indexer
+type LatencyTracker struct {
+	mu         sync.RWMutex
+	windows    map[uuid.UUID]*LatencyWindow
+	windowSize int
+}
+
+// NewLatencyTracker creates a tracker whose windows hold windowSize samples
+func NewLatencyTracker(windowSize int) *LatencyTracker {
+	if windowSize <= 0 {
+		windowSize = DefaultLatencyWindowSize
+	}
+	return &LatencyTracker{
+		windows:    make(map[uuid.UUID]*LatencyWindow),
+		windowSize: windowSize,
+	}
+}
+
+// Record adds a processing duration for an indexer
+func (t *LatencyTracker) Record(indexerID uuid.UUID, d time.Duration) {
+	t.mu.RLock()
+	w, ok := t.windows[indexerID]
+	t.mu.RUnlock()
+
+	if !ok {
+		t.mu.Lock()
+		if w, ok = t.windows[indexerID]; !ok {
+			w = NewLatencyWindow(t.windowSize)
+			t.windows[indexerID] = w
+		}
+		t.mu.Unlock()
+	}
+
+	w.Record(d)
+}
+
+// Snapshot returns the current percentiles for an indexer
+func (t *LatencyTracker) Snapshot(indexerID uuid.UUID) LatencySnapshot {
+	t.mu.RLock()
+	w, ok := t.windows[indexerID]
+	t.mu.RUnlock()
+
+	if !ok {
+		return LatencySnapshot{}
+	}
+	return w.Snapshot()
+}
+
+// Remove drops the window for an indexer
+func (t *LatencyTracker) Remove(indexerID uuid.UUID) {
+	t.mu.Lock()
+	delete(t.windows, indexerID)
+	t.mu.Unlock()
+}
+
+// WindowSize returns the number of samples kept per indexer
+func (t *LatencyTracker) WindowSize() int {
+	return t.windowSize
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{p: 0, want: time.Millisecond},
+		{p: 1, want: time.Millisecond},
+		{p: 50, want: 50 * time.Millisecond},
+		{p: 95, want: 95 * time.Millisecond},
+		{p: 99, want: 99 * time.Millisecond},
+		{p: 99.5, want: 100 * time.Millisecond},
+		{p: 100, want: 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := Percentile(sorted, tt.p); got != tt.want {
+			t.Errorf("Percentile(%v) = %s, want %s", tt.p, got, tt.want)
+		}
+	}
+
+	if got := Percentile(nil, 50); got != 0 {
+		t.Errorf("Percentile of no samples = %s, want 0", got)
+	}
+}
+
+func TestPercentileUsesNearestRank(t *testing.T) {
+	sorted := []time.Duration{10, 20, 30, 40}
+
+	// Nearest rank rounds up: p50 of four samples is the second, p51 the third
+	if got := Percentile(sorted, 50); got != 20 {
+		t.Errorf("p50 = %d, want 20", got)
+	}
+	if got := Percentile(sorted, 51); got != 30 {
+		t.Errorf("p51 = %d, want 30", got)
+	}
+}
+
+func TestLatencyWindowSnapshot(t *testing.T) {
+	w := NewLatencyWindow(10)
+	// Recorded out of order; the snapshot sorts them
+	for _, ms := range []int{7, 3, 10, 1, 5, 9, 2, 8, 4, 6} {
+		w.Record(time.Duration(ms) * time.Millisecond)
+	}
+
+	got := w.Snapshot()
+	want := LatencySnapshot{
+		Count: 10,
+		P50:   5 * time.Millisecond,
+		P95:   10 * time.Millisecond,
+		P99:   10 * time.Millisecond,
+		Max:   10 * time.Millisecond,
+	}
+	if got != want {
+		t.Errorf("Snapshot() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLatencyWindowEvictsOldestSamples(t *testing.T) {
+	w := NewLatencyWindow(3)
+	for _, ms := range []int{100, 1, 2, 3} {
+		w.Record(time.Duration(ms) * time.Millisecond)
+	}
+
+	got := w.Snapshot()
+	if got.Count != 3 || got.Max != 3*time.Millisecond {
+		t.Errorf("Snapshot() = %+v, want 3 samples with the 100ms one evicted", got)
+	}
+}
+
+func TestLatencyTrackerKeepsIndexersApart(t *testing.T) {
+	tracker := NewLatencyTracker(10)
+	a, b := uuid.New(), uuid.New()
+
+	tracker.Record(a, time.Second)
+	tracker.Record(b, time.Millisecond)
+
+	if got := tracker.Snapshot(a).Max; got != time.Second {
+		t.Errorf("indexer a max = %s, want 1s", got)
+	}
+	if got := tracker.Snapshot(b).Max; got != time.Millisecond {
+		t.Errorf("indexer b max = %s, want 1ms", got)
+	}
+
+	tracker.Remove(a)
+	if got := tracker.Snapshot(a); got.Count != 0 {
+		t.Errorf("snapshot after Remove = %+v, want empty", got)
+	}
+}
@@ -0,0 +1,179 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// EnhancedTransaction is a Helius enhanced transaction, the JSON kept in
+// HeliusTransaction.EnhancedDetails. The typed fields cover what the indexers
+// read; Raw holds the whole transaction for anything else.
+type EnhancedTransaction struct {
+	Signature       string           `json:"signature"`
+	Slot            int64            `json:"slot"`
+	Timestamp       int64            `json:"timestamp"`
+	Type            string           `json:"type"`
+	Source          string           `json:"source"`
+	Description     string           `json:"description"`
+	FeePayer        string           `json:"feePayer"`
+	Fee             int64            `json:"fee"`
+	TokenTransfers  []TokenTransfer  `json:"tokenTransfers"`
+	NativeTransfers []NativeTransfer `json:"nativeTransfers"`
+	AccountData     []AccountData    `json:"accountData"`
+	Events          EnhancedEvents   `json:"events"`
+
+	Raw map[string]interface{} `json:"-"`
+}
+
+// TokenTransfer is one SPL token movement of a transaction. TokenAmount is
+// already scaled by the mint's decimals.
+type TokenTransfer struct {
+	FromUserAccount  string  `json:"fromUserAccount"`
+	ToUserAccount    string  `json:"toUserAccount"`
+	FromTokenAccount string  `json:"fromTokenAccount"`
+	ToTokenAccount   string  `json:"toTokenAccount"`
+	TokenAmount      float64 `json:"tokenAmount"`
+	Mint             string  `json:"mint"`
+	TokenStandard    string  `json:"tokenStandard"`
+}
+
+// NativeTransfer is one SOL movement of a transaction, in lamports
+type NativeTransfer struct {
+	FromUserAccount string `json:"fromUserAccount"`
+	ToUserAccount   string `json:"toUserAccount"`
+	Amount          int64  `json:"amount"`
+}
+
+// AccountData is the balance change of one account touched by a transaction;
+// NativeBalanceChange is in lamports
+type AccountData struct {
+	Account             string               `json:"account"`
+	NativeBalanceChange int64                `json:"nativeBalanceChange"`
+	TokenBalanceChanges []TokenBalanceChange `json:"tokenBalanceChanges"`
+}
+
+// TokenBalanceChange is the change of one token account's balance
+type TokenBalanceChange struct {
+	UserAccount    string         `json:"userAccount"`
+	TokenAccount   string         `json:"tokenAccount"`
+	Mint           string         `json:"mint"`
+	RawTokenAmount RawTokenAmount `json:"rawTokenAmount"`
+}
+
+// RawTokenAmount is an unscaled token amount and the decimals to scale it by
+type RawTokenAmount struct {
+	TokenAmount string `json:"tokenAmount"`
+	Decimals    int    `json:"decimals"`
+}
+
+// EnhancedEvents holds the parsed events of a transaction. Helius sends them
+// as an object keyed by event kind; older payloads send an array of typed
+// events instead, which end up in List.
+type EnhancedEvents struct {
+	NFT        *NFTEvent         `json:"nft,omitempty"`
+	Swap       *SwapEvent        `json:"swap,omitempty"`
+	Compressed []CompressedEvent `json:"compressed,omitempty"`
+
+	List []EnhancedEvent `json:"-"`
+}
+
+func (e *EnhancedEvents) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '[' {
+		return lenient(json.Unmarshal(data, &e.List))
+	}
+	type events EnhancedEvents
+	return lenient(json.Unmarshal(data, (*events)(e)))
+}
+
+// EnhancedEvent is one entry of an events array. Its fields are either nested
+// under data or sit next to type, so Raw keeps the whole entry.
+type EnhancedEvent struct {
+	Type string                 `json:"type"`
+	Data map[string]interface{} `json:"data"`
+
+	Raw map[string]interface{} `json:"-"`
+}
+
+func (e *EnhancedEvent) UnmarshalJSON(data []byte) error {
+	type event EnhancedEvent
+	if err := lenient(json.Unmarshal(data, (*event)(e))); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &e.Raw)
+}
+
+// NFTEvent is an NFT listing, sale, bid or cancellation. Amount and Fee are
+// in lamports.
+type NFTEvent struct {
+	Type        string     `json:"type"`
+	Source      string     `json:"source"`
+	Description string     `json:"description"`
+	Amount      int64      `json:"amount"`
+	Fee         int64      `json:"fee"`
+	FeePayer    string     `json:"feePayer"`
+	Signature   string     `json:"signature"`
+	Slot        int64      `json:"slot"`
+	Timestamp   int64      `json:"timestamp"`
+	SaleType    string     `json:"saleType"`
+	Buyer       string     `json:"buyer"`
+	Seller      string     `json:"seller"`
+	Staker      string     `json:"staker"`
+	NFTs        []NFTToken `json:"nfts"`
+}
+
+// NFTToken is an NFT named by an NFT event
+type NFTToken struct {
+	Mint          string `json:"mint"`
+	TokenStandard string `json:"tokenStandard"`
+}
+
+// SwapEvent is a token swap. Native amounts are lamports as strings, token
+// amounts are raw amounts with their decimals.
+type SwapEvent struct {
+	NativeInput  *NativeAmount        `json:"nativeInput"`
+	NativeOutput *NativeAmount        `json:"nativeOutput"`
+	TokenInputs  []TokenBalanceChange `json:"tokenInputs"`
+	TokenOutputs []TokenBalanceChange `json:"tokenOutputs"`
+}
+
+// NativeAmount is a SOL amount of a swap
+type NativeAmount struct {
+	Account string `json:"account"`
+	Amount  string `json:"amount"`
+}
+
+// CompressedEvent is a change to a compressed NFT leaf
+type CompressedEvent struct {
+	Type         string `json:"type"`
+	TreeID       string `json:"treeId"`
+	AssetID      string `json:"assetId"`
+	LeafIndex    int64  `json:"leafIndex"`
+	NewLeafOwner string `json:"newLeafOwner"`
+	OldLeafOwner string `json:"oldLeafOwner"`
+}
+
+// ParseEnhancedTransaction decodes enhanced transaction details. A field of an
+// unexpected type is left zero rather than failing the whole transaction; its
+// value is still in Raw.
+func ParseEnhancedTransaction(data json.RawMessage) (*EnhancedTransaction, error) {
+	tx := &EnhancedTransaction{}
+	if err := json.Unmarshal(data, &tx.Raw); err != nil {
+		return nil, err
+	}
+
+	type transaction EnhancedTransaction
+	if err := lenient(json.Unmarshal(data, (*transaction)(tx))); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// lenient drops type mismatches, which encoding/json reports only after
+// decoding everything else
+func lenient(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return nil
+	}
+	return err
+}
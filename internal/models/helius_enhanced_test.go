@@ -0,0 +1,152 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// enhancedFixture parses a transaction captured from a Helius enhanced webhook
+func enhancedFixture(t *testing.T, path string) *EnhancedTransaction {
+	t.Helper()
+
+	body, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	tx, err := ParseEnhancedTransaction(body)
+	if err != nil {
+		t.Fatalf("ParseEnhancedTransaction(%s): %v", path, err)
+	}
+	return tx
+}
+
+func TestParseEnhancedTransactionNFTSale(t *testing.T) {
+	tx := enhancedFixture(t, filepath.Join("..", "indexer", "testdata", "tensor_sale.json"))
+
+	if tx.Type != "NFT_SALE" || tx.Source != "TENSOR" || tx.Slot != 251380107 || tx.Timestamp != 1708006412 {
+		t.Errorf("got %s from %s at slot %d, time %d", tx.Type, tx.Source, tx.Slot, tx.Timestamp)
+	}
+
+	nft := tx.Events.NFT
+	if nft == nil {
+		t.Fatal("no NFT event")
+	}
+	if nft.Amount != 139000000000 || nft.SaleType != "INSTANT_SALE" {
+		t.Errorf("sale of %d lamports (%s), want 139000000000 (INSTANT_SALE)", nft.Amount, nft.SaleType)
+	}
+	if nft.Buyer != "6Rg8Bm5yjGMRpYNLvwS8qkHX5BvCnZz4kbDDsNPDGr3u" || nft.Seller != "3jfKqzFm1yGFEDu7sQwUaWNt7cF6ZCnkHWmh4dpRZbVA" {
+		t.Errorf("buyer %s, seller %s", nft.Buyer, nft.Seller)
+	}
+	if len(nft.NFTs) != 1 || nft.NFTs[0].Mint != "7MsmqQ4Ns5BqQ2Yb6p8ZkYhZ5mQdUXkJ1nMDkUGJhHdR" {
+		t.Errorf("nfts = %+v, want the Mad Lad", nft.NFTs)
+	}
+	if tx.Events.Swap != nil || len(tx.Events.List) != 0 {
+		t.Error("sale parsed with a swap or an events array")
+	}
+}
+
+func TestParseEnhancedTransactionCompressedSale(t *testing.T) {
+	tx := enhancedFixture(t, filepath.Join("..", "indexer", "testdata", "tensor_cnft_sale.json"))
+
+	if len(tx.Events.Compressed) != 1 {
+		t.Fatalf("got %d compressed events, want 1", len(tx.Events.Compressed))
+	}
+	leaf := tx.Events.Compressed[0]
+	if leaf.AssetID != "6pKfzXcGNqDHyV3bdD7X6RuNHTuMBSUQ6rUbt3hsXXdD" || leaf.LeafIndex != 88213 {
+		t.Errorf("compressed event = %+v", leaf)
+	}
+	if leaf.NewLeafOwner != "BuYeR5qLJvTc2WfJkR8xNhD3pMa9sEoZgU7yVbKtQwXn" || leaf.OldLeafOwner != "SeLLeR8pHwN4rZbT6yJkQ2vMf9cLd3aXs7uEg5oWi1Kn" {
+		t.Errorf("leaf moved from %s to %s", leaf.OldLeafOwner, leaf.NewLeafOwner)
+	}
+}
+
+func TestParseEnhancedTransactionSwap(t *testing.T) {
+	tx := enhancedFixture(t, filepath.Join("testdata", "jupiter_swap.json"))
+
+	if len(tx.TokenTransfers) != 2 {
+		t.Fatalf("got %d token transfers, want 2", len(tx.TokenTransfers))
+	}
+	usdc := tx.TokenTransfers[1]
+	if usdc.Mint != "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v" || usdc.TokenAmount != 287.416532 {
+		t.Errorf("second transfer = %+v, want the USDC out", usdc)
+	}
+	if len(tx.NativeTransfers) != 1 || tx.NativeTransfers[0].Amount != 2000000000 {
+		t.Errorf("native transfers = %+v, want 2 SOL in", tx.NativeTransfers)
+	}
+
+	if len(tx.AccountData) != 2 || tx.AccountData[0].NativeBalanceChange != -2000005000 {
+		t.Fatalf("account data = %+v", tx.AccountData)
+	}
+	changes := tx.AccountData[1].TokenBalanceChanges
+	if len(changes) != 1 || changes[0].RawTokenAmount != (RawTokenAmount{TokenAmount: "287416532", Decimals: 6}) {
+		t.Errorf("token balance changes = %+v", changes)
+	}
+
+	swap := tx.Events.Swap
+	if swap == nil {
+		t.Fatal("no swap event")
+	}
+	if swap.NativeInput == nil || swap.NativeInput.Amount != "2000000000" || swap.NativeOutput != nil {
+		t.Errorf("native input %+v, output %+v; want 2 SOL in and nothing out", swap.NativeInput, swap.NativeOutput)
+	}
+	if len(swap.TokenOutputs) != 1 || swap.TokenOutputs[0].Mint != "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v" {
+		t.Errorf("token outputs = %+v, want USDC", swap.TokenOutputs)
+	}
+
+	// Fields the typed model leaves out are still in Raw
+	if _, ok := tx.Raw["instructions"]; !ok {
+		t.Error("Raw lost the instructions")
+	}
+	if _, ok := tx.Raw["transactionError"]; !ok {
+		t.Error("Raw lost the transaction error")
+	}
+}
+
+func TestParseEnhancedTransactionEventsArray(t *testing.T) {
+	tx, err := ParseEnhancedTransaction([]byte(`{
+		"signature": "sig",
+		"events": [
+			{"type": "NFT_LISTING", "data": {"mint": "mint-1", "amount": 2.5}},
+			{"type": "NFT_SALE", "mint": "mint-2", "amount": 3}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("ParseEnhancedTransaction: %v", err)
+	}
+
+	list := tx.Events.List
+	if len(list) != 2 {
+		t.Fatalf("got %d events, want 2", len(list))
+	}
+	if list[0].Type != "NFT_LISTING" || list[0].Data["mint"] != "mint-1" {
+		t.Errorf("first event = %+v, want the listing with its data", list[0])
+	}
+	// An event without data keeps its fields next to type, in Raw
+	if list[1].Type != "NFT_SALE" || list[1].Data != nil || list[1].Raw["mint"] != "mint-2" {
+		t.Errorf("second event = %+v, want the sale with its fields in Raw", list[1])
+	}
+	if tx.Events.NFT != nil {
+		t.Error("events array parsed as a keyed NFT event")
+	}
+}
+
+func TestParseEnhancedTransactionToleratesUnexpectedTypes(t *testing.T) {
+	tx, err := ParseEnhancedTransaction([]byte(`{"signature": "sig", "slot": "251380107", "type": "NFT_SALE", "fee": 5000}`))
+	if err != nil {
+		t.Fatalf("ParseEnhancedTransaction: %v", err)
+	}
+	if tx.Slot != 0 {
+		t.Errorf("slot = %d, want a string slot left zero", tx.Slot)
+	}
+	if tx.Type != "NFT_SALE" || tx.Fee != 5000 {
+		t.Errorf("got type %q and fee %d, want the well-typed fields kept", tx.Type, tx.Fee)
+	}
+	if tx.Raw["slot"] != "251380107" {
+		t.Errorf("Raw slot = %v, want the original value", tx.Raw["slot"])
+	}
+
+	if _, err := ParseEnhancedTransaction([]byte(`{"signature": `)); err == nil {
+		t.Error("ParseEnhancedTransaction accepted truncated JSON")
+	}
+}
@@ -5,15 +5,19 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/rishavmehra/indexer/pkg/validator"
 )
 
 type IndexerType string
 
 const (
-	NFTBids     IndexerType = "nft_bids"
-	NFTPrices   IndexerType = "nft_prices"
-	TokenBorrow IndexerType = "token_borrow"
-	TokenPrices IndexerType = "token_prices"
+	NFTBids      IndexerType = "nft_bids"
+	NFTPrices    IndexerType = "nft_prices"
+	TokenBorrow  IndexerType = "token_borrow"
+	TokenPrices  IndexerType = "token_prices"
+	TokenHolders IndexerType = "token_holders"
+	ProgramLogs  IndexerType = "program_logs"
 )
 
 type IndexerStatus string
@@ -26,24 +30,105 @@ const (
 	Completed IndexerStatus = "completed"
 )
 
+// Storage types for an NFT indexer's time column
+const (
+	TimeColumnTimestamptz = "timestamptz"
+	TimeColumnEpoch       = "epoch"
+)
+
+// What an NFT price indexer does with a listing whose mint cannot be resolved
+const (
+	UnresolvedMintSkip  = "skip"
+	UnresolvedMintStore = "store"
+)
+
+// Helius webhook types an indexer can ask for
+const (
+	WebhookTypeEnhanced = "enhanced"
+	WebhookTypeRaw      = "raw"
+)
+
+// TimeColumn renames the block_time column or stores it as epoch seconds so
+// an indexer can write into an existing schema
+type TimeColumn struct {
+	Name string `json:"name,omitempty"`
+	Type string `json:"type,omitempty"`
+}
+
+// IndexerOptions are the params every indexer type accepts on top of its own:
+// how its Helius webhook is set up and how its history is fetched
+type IndexerOptions struct {
+	// TransactionTypes overrides the Helius transaction types the webhook
+	// asks for; each indexer type has its own defaults
+	TransactionTypes []string `json:"transactionTypes,omitempty"`
+	// WebhookType is "enhanced" (the default) or, where supported, "raw"
+	WebhookType string `json:"webhookType,omitempty"`
+	// BackfillLimit is how many past transactions per address are indexed
+	// when the indexer is created, 0 for none
+	BackfillLimit int `json:"backfillLimit,omitempty"`
+	// PollInterval, such as "5m", also polls the Helius transactions API for
+	// payloads the webhook missed; empty for no polling
+	PollInterval string `json:"pollInterval,omitempty"`
+}
+
 type NFTBidParams struct {
 	Collection   string   `json:"collection"`
 	Marketplaces []string `json:"marketplaces,omitempty"`
+	// CollectionOffers also tracks collection-wide offers and their fills
+	CollectionOffers bool        `json:"collectionOffers,omitempty"`
+	TimeColumn       *TimeColumn `json:"timeColumn,omitempty"`
+	IndexerOptions
 }
 
 type NFTPriceParams struct {
-	Collection   string   `json:"collection"`
-	Marketplaces []string `json:"marketplaces,omitempty"`
+	Collection   string      `json:"collection"`
+	Marketplaces []string    `json:"marketplaces,omitempty"`
+	TimeColumn   *TimeColumn `json:"timeColumn,omitempty"`
+	// UnresolvedMint is "skip" (the default) or "store"; stored rows have a
+	// NULL nft_mint and mint_resolved set to false
+	UnresolvedMint string `json:"unresolvedMint,omitempty"`
+	IndexerOptions
 }
 
 type TokenBorrowParams struct {
 	Tokens    []string `json:"tokens"`
 	Platforms []string `json:"platforms,omitempty"`
+	IndexerOptions
 }
 
+// Token price sources that can be enabled per indexer
+const (
+	TokenSourceSwap     = "swap"
+	TokenSourceTransfer = "transfer"
+	TokenSourceBalance  = "balance"
+)
+
 type TokenPriceParams struct {
 	Tokens    []string `json:"tokens"`
 	Platforms []string `json:"platforms,omitempty"`
+	// Sources limits which extractors run; all of them when empty
+	Sources []string `json:"sources,omitempty"`
+	// KeepHistory also appends every observed price to a companion
+	// <targetTable>_price_history table
+	KeepHistory bool `json:"keepHistory,omitempty"`
+	IndexerOptions
+}
+
+type TokenHolderParams struct {
+	Tokens []string `json:"tokens"`
+	// SnapshotInterval, such as "5m", is the least time between two
+	// snapshots of the same mint; one minute when empty, "0s" for a snapshot
+	// per transaction
+	SnapshotInterval string `json:"snapshotInterval,omitempty"`
+	IndexerOptions
+}
+
+type ProgramLogParams struct {
+	ProgramID string `json:"programId"`
+	// Accounts are watched alongside the program; when set, only
+	// instructions touching one of them are stored
+	Accounts []string `json:"accounts,omitempty"`
+	IndexerOptions
 }
 
 type CreateIndexerRequest struct {
@@ -54,6 +139,37 @@ type CreateIndexerRequest struct {
 	WebhookID      string          `json:"webhookId,omitempty"`
 }
 
+// ValidateIndexerRequest checks params for an indexer type without creating
+// the indexer
+type ValidateIndexerRequest struct {
+	IndexerType IndexerType     `json:"indexerType" binding:"required"`
+	Params      json.RawMessage `json:"params" binding:"required"`
+}
+
+// ValidateIndexerResponse lists every problem found in the params, if any
+type ValidateIndexerResponse struct {
+	Valid    bool                     `json:"valid"`
+	Problems []validator.ParamProblem `json:"problems,omitempty"`
+}
+
+// UpdateIndexerRequest replaces the params of an existing indexer
+type UpdateIndexerRequest struct {
+	Params json.RawMessage `json:"params" binding:"required"`
+}
+
+// RetargetIndexerRequest moves an indexer to a new target table
+type RetargetIndexerRequest struct {
+	TargetTable string `json:"targetTable" binding:"required"`
+	// CopyExisting copies the rows of the current table into the new one
+	CopyExisting bool `json:"copyExisting"`
+}
+
+// ResetIndexerRequest empties an indexer's target table. Confirm has to be set
+// since the rows cannot be recovered.
+type ResetIndexerRequest struct {
+	Confirm bool `json:"confirm"`
+}
+
 type IndexerResponse struct {
 	ID             uuid.UUID     `json:"id"`
 	UserID         uuid.UUID     `json:"userId"`
@@ -62,11 +178,15 @@ type IndexerResponse struct {
 	Params         interface{}   `json:"params"`
 	TargetTable    string        `json:"targetTable"`
 	WebhookID      string        `json:"webhookId"`
+	Cluster        string        `json:"cluster"`
 	Status         IndexerStatus `json:"status"`
 	LastIndexedAt  *time.Time    `json:"lastIndexedAt"`
 	ErrorMessage   string        `json:"errorMessage"`
-	CreatedAt      time.Time     `json:"createdAt"`
-	UpdatedAt      time.Time     `json:"updatedAt"`
+	// LastError is the most recent processing failure, kept until cleared
+	LastError   string     `json:"lastError"`
+	LastErrorAt *time.Time `json:"lastErrorAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
 }
 
 type IndexingLogResponse struct {
@@ -78,15 +198,256 @@ type IndexingLogResponse struct {
 	CreatedAt time.Time   `json:"createdAt"`
 }
 
+type LogTailResponse struct {
+	Logs   []IndexingLogResponse `json:"logs"`
+	Cursor time.Time             `json:"cursor"`
+	// CursorID is the ID of the last log returned; passed back with the
+	// cursor it keeps logs sharing the cursor's timestamp from being skipped
+	CursorID *uuid.UUID `json:"cursorId,omitempty"`
+}
+
+// FailedPayloadResponse is a payload that ran out of processing attempts,
+// kept with its last error so it can be replayed
+type FailedPayloadResponse struct {
+	ID        int64           `json:"id"`
+	IndexerID uuid.UUID       `json:"indexerId"`
+	WebhookID string          `json:"webhookId"`
+	RequestID string          `json:"requestId,omitempty"`
+	Slot      int64           `json:"slot"`
+	Signature string          `json:"signature,omitempty"`
+	Error     string          `json:"error"`
+	Attempts  int32           `json:"attempts"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"createdAt"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+}
+
+// BackfillProgress is how far the backfill of one address of an indexer got
+type BackfillProgress struct {
+	Address string `json:"address"`
+	// Fetched counts the past transactions taken from the address's history
+	Fetched   int32     `json:"fetched"`
+	Completed bool      `json:"completed"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// BackfillResponse reports a backfill run of an indexer
+type BackfillResponse struct {
+	IndexerID uuid.UUID `json:"indexerId"`
+	// Processed counts the transactions indexed by this run
+	Processed int                `json:"processed"`
+	Addresses []BackfillProgress `json:"addresses"`
+}
+
+// IndexedEvent is pushed to stream subscribers as payloads are processed. A
+// success event carries the rows the payload wrote under details.
+type IndexedEvent struct {
+	IndexerID uuid.UUID   `json:"indexerId"`
+	EventType string      `json:"eventType"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details"`
+	CreatedAt time.Time   `json:"createdAt"`
+}
+
+type IndexerStatsResponse struct {
+	IndexerID  uuid.UUID    `json:"indexerId"`
+	WindowSize int          `json:"windowSize"`
+	Latency    LatencyStats `json:"latency"`
+	// Collection is only set for NFT price indexers
+	Collection *CollectionStats `json:"collection,omitempty"`
+}
+
+// CollectionStats are rollups over the table of an NFT price indexer, in the
+// currency the prices were indexed in
+type CollectionStats struct {
+	// FloorPrice is the lowest active listing, nil when nothing is listed
+	FloorPrice   *float64 `json:"floorPrice"`
+	Volume24h    float64  `json:"volume24h"`
+	SaleCount24h int64    `json:"saleCount24h"`
+}
+
+// IndexerHealthResponse reports whether an indexer is still receiving data.
+// Checks that could not be made leave their field nil and add a problem.
+type IndexerHealthResponse struct {
+	IndexerID uuid.UUID `json:"indexerId"`
+	Status    string    `json:"status"`
+	Healthy   bool      `json:"healthy"`
+	// Problems lists why the indexer is unhealthy or a check couldn't be made
+	Problems      []string   `json:"problems,omitempty"`
+	LastIndexedAt *time.Time `json:"lastIndexedAt"`
+	// LastEventAt is the time of the newest indexing log entry
+	LastEventAt           *time.Time `json:"lastEventAt"`
+	SecondsSinceLastEvent *float64   `json:"secondsSinceLastEvent"`
+	StaleAfterSeconds     float64    `json:"staleAfterSeconds"`
+	WebhookExists         *bool      `json:"webhookExists"`
+	RowCount              *int64     `json:"rowCount"`
+}
+
+type ReprocessRequest struct {
+	FromSlot *int64 `json:"fromSlot,omitempty"`
+	ToSlot   *int64 `json:"toSlot,omitempty"`
+	// DryRun parses the stored payloads without writing to the target table
+	DryRun bool `json:"dryRun"`
+}
+
+type ReprocessResponse struct {
+	IndexerID uuid.UUID          `json:"indexerId"`
+	DryRun    bool               `json:"dryRun"`
+	Total     int                `json:"total"`
+	Processed int                `json:"processed"`
+	Failed    int                `json:"failed"`
+	Failures  []ReprocessFailure `json:"failures,omitempty"`
+}
+
+type ReprocessFailure struct {
+	Slot      int64  `json:"slot"`
+	Signature string `json:"signature"`
+	Error     string `json:"error"`
+}
+
+type LatencyStats struct {
+	SampleCount int     `json:"sampleCount"`
+	P50Ms       float64 `json:"p50Ms"`
+	P95Ms       float64 `json:"p95Ms"`
+	P99Ms       float64 `json:"p99Ms"`
+	MaxMs       float64 `json:"maxMs"`
+}
+
+// PlatformPrice is one platform's row in a token price breakdown
+type PlatformPrice struct {
+	Platform  string    `json:"platform"`
+	PriceUSD  float64   `json:"priceUsd"`
+	Volume24h *float64  `json:"volume24h"`
+	Slot      int64     `json:"slot"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// TokenPriceResponse is a token's price combined across platforms
+type TokenPriceResponse struct {
+	IndexerID uuid.UUID `json:"indexerId"`
+	Token     string    `json:"token"`
+	PriceUSD  float64   `json:"priceUsd"`
+	// Method is vwap, or average when no platform reports a volume
+	Method    string          `json:"method"`
+	Platforms []PlatformPrice `json:"platforms"`
+}
+
+// PriceBucket is one interval of a token's price history
+type PriceBucket struct {
+	Start   time.Time `json:"start"`
+	Open    float64   `json:"open"`
+	High    float64   `json:"high"`
+	Low     float64   `json:"low"`
+	Close   float64   `json:"close"`
+	Samples int64     `json:"samples"`
+}
+
+// TokenPriceHistoryResponse is a token's price history in OHLC buckets;
+// intervals without any observed price are left out
+type TokenPriceHistoryResponse struct {
+	IndexerID uuid.UUID     `json:"indexerId"`
+	Token     string        `json:"token"`
+	Interval  string        `json:"interval"`
+	From      time.Time     `json:"from"`
+	To        time.Time     `json:"to"`
+	Buckets   []PriceBucket `json:"buckets"`
+}
+
+// TokenPriceRow is a row of a token price indexer's table. Columns the row
+// has no value for are null.
+type TokenPriceRow struct {
+	TokenAddress   string    `json:"tokenAddress"`
+	TokenName      *string   `json:"tokenName"`
+	TokenSymbol    *string   `json:"tokenSymbol"`
+	Platform       string    `json:"platform"`
+	PriceUSD       float64   `json:"priceUsd"`
+	PriceSOL       *float64  `json:"priceSol"`
+	Volume24h      *float64  `json:"volume24h"`
+	MarketCap      *float64  `json:"marketCap"`
+	Liquidity      *float64  `json:"liquidity"`
+	PriceChange24h *float64  `json:"priceChange24h"`
+	TotalSupply    *float64  `json:"totalSupply"`
+	TransactionID  *string   `json:"transactionId"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+	Slot           int64     `json:"slot"`
+}
+
+// ActivityEvent is a target table row normalized for the cross-indexer activity feed
+type ActivityEvent struct {
+	IndexerID   uuid.UUID   `json:"indexerId"`
+	IndexerType IndexerType `json:"indexerType"`
+	EventType   string      `json:"eventType"`
+	Signature   string      `json:"signature,omitempty"`
+	Slot        int64       `json:"slot"`
+	BlockTime   time.Time   `json:"blockTime"`
+	Asset       string      `json:"asset"`
+	Source      string      `json:"source,omitempty"`
+	Amount      float64     `json:"amount"`
+	Unit        string      `json:"unit,omitempty"`
+	Account     string      `json:"account,omitempty"`
+}
+
 type HeliusWebhookResponse struct {
 	WebhookID string `json:"webhookID"`
 	Endpoint  string `json:"webhookURL"`
 }
 
+// HeliusWebhook is a webhook as Helius lists it
+type HeliusWebhook struct {
+	WebhookID        string   `json:"webhookID"`
+	Wallet           string   `json:"wallet"`
+	WebhookURL       string   `json:"webhookURL"`
+	WebhookType      string   `json:"webhookType"`
+	TransactionTypes []string `json:"transactionTypes"`
+	AccountAddresses []string `json:"accountAddresses"`
+}
+
+// WebhookAudit compares the webhooks registered at Helius with the indexers
+// in the database
+type WebhookAudit struct {
+	Webhooks []AuditedWebhook `json:"webhooks"`
+	// MissingWebhooks are indexers whose webhook no longer exists at Helius
+	MissingWebhooks []MissingWebhook `json:"missingWebhooks"`
+	OrphanCount     int              `json:"orphanCount"`
+}
+
+// AuditedWebhook is a Helius webhook and the indexer it was matched to
+type AuditedWebhook struct {
+	WebhookID        string   `json:"webhookId"`
+	WebhookURL       string   `json:"webhookUrl"`
+	TransactionTypes []string `json:"transactionTypes"`
+	AddressCount     int      `json:"addressCount"`
+	IndexerID        string   `json:"indexerId,omitempty"`
+	// Managed is false for webhooks calling a URL other than this server's
+	Managed bool `json:"managed"`
+	// Shared is set for the configured webhook used by every indexer
+	Shared bool `json:"shared"`
+	// Orphaned is set for managed webhooks with no matching indexer
+	Orphaned bool `json:"orphaned"`
+}
+
+// WebhookReconcileResult reports what a reconcile run deleted and marked
+// failed, or would have in a dry run
+type WebhookReconcileResult struct {
+	DryRun          bool        `json:"dryRun"`
+	DeletedWebhooks []string    `json:"deletedWebhooks"`
+	FailedIndexers  []uuid.UUID `json:"failedIndexers"`
+	Errors          []string    `json:"errors,omitempty"`
+}
+
+// MissingWebhook is an indexer whose webhook was not found at Helius
+type MissingWebhook struct {
+	IndexerID uuid.UUID     `json:"indexerId"`
+	WebhookID string        `json:"webhookId"`
+	Status    IndexerStatus `json:"status"`
+}
+
 type HeliusWebhookPayload struct {
 	AccountData []HeliusAccountData `json:"accountData"`
 	Slot        int64               `json:"slot"`
 	Transaction HeliusTransaction   `json:"transaction,omitempty"`
+	// SchemaVersion fingerprints the shape of the delivery this payload came from
+	SchemaVersion string `json:"schemaVersion,omitempty"`
 }
 
 type HeliusAccountData struct {
@@ -108,4 +469,6 @@ type HeliusTransaction struct {
 	Type            string          `json:"type"`
 	StatusMessage   string          `json:"statusMessage"`
 	EnhancedDetails json.RawMessage `json:"enhancedDetails,omitempty"`
+	// Timestamp is the Unix block time of the transaction, zero when unknown
+	Timestamp int64 `json:"timestamp,omitempty"`
 }
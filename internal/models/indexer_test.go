@@ -0,0 +1,28 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestIndexerOptionsSitAtTheTopOfParams(t *testing.T) {
+	params := `{"tokens":["mint"],"transactionTypes":["SWAP"],"webhookType":"enhanced","backfillLimit":50,"pollInterval":"5m"}`
+
+	var priceParams TokenPriceParams
+	if err := json.Unmarshal([]byte(params), &priceParams); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	want := IndexerOptions{TransactionTypes: []string{"SWAP"}, WebhookType: "enhanced", BackfillLimit: 50, PollInterval: "5m"}
+	if !reflect.DeepEqual(priceParams.IndexerOptions, want) {
+		t.Errorf("options = %+v, want %+v", priceParams.IndexerOptions, want)
+	}
+
+	body, err := json.Marshal(priceParams)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if string(body) != params {
+		t.Errorf("marshalled params = %s, want %s", body, params)
+	}
+}
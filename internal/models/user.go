@@ -25,25 +25,50 @@ type UserResponse struct {
 type TokenResponse struct {
 	Token     string    `json:"token"`
 	ExpiresAt time.Time `json:"expiresAt"`
+	// The refresh token is only issued at login
+	RefreshToken          string     `json:"refreshToken,omitempty"`
+	RefreshTokenExpiresAt *time.Time `json:"refreshTokenExpiresAt,omitempty"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
 }
 
 type DBCredentialRequest struct {
-	Host     string `json:"host" binding:"required"`
-	Port     int    `json:"port" binding:"required"`
-	Name     string `json:"name" binding:"required"`
-	User     string `json:"user" binding:"required"`
-	Password string `json:"password" binding:"required"`
-	SSLMode  string `json:"sslMode"`
+	Host       string `json:"host" binding:"required"`
+	Port       int    `json:"port" binding:"required"`
+	Name       string `json:"name" binding:"required"`
+	User       string `json:"user" binding:"required"`
+	Password   string `json:"password" binding:"required"`
+	SSLMode    string `json:"sslMode"`
+	TableOwner string `json:"tableOwner"`
 }
 
 type DBCredentialResponse struct {
-	ID        uuid.UUID `json:"id"`
-	UserID    uuid.UUID `json:"userId"`
-	Host      string    `json:"host"`
-	Port      int       `json:"port"`
-	Name      string    `json:"name"`
-	User      string    `json:"user"`
-	SSLMode   string    `json:"sslMode"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID         uuid.UUID `json:"id"`
+	UserID     uuid.UUID `json:"userId"`
+	Host       string    `json:"host"`
+	Port       int       `json:"port"`
+	Name       string    `json:"name"`
+	User       string    `json:"user"`
+	SSLMode    string    `json:"sslMode"`
+	TableOwner string    `json:"tableOwner,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// AuditEventResponse records one change a user made to an indexer or
+// credential
+type AuditEventResponse struct {
+	ID         int64       `json:"id"`
+	Action     string      `json:"action"`
+	EntityType string      `json:"entityType"`
+	EntityID   uuid.UUID   `json:"entityId"`
+	Metadata   interface{} `json:"metadata"`
+	CreatedAt  time.Time   `json:"createdAt"`
 }
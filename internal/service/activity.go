@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/pkg/logger"
+)
+
+const (
+	DefaultActivityLimit = 50
+	MaxActivityLimit     = 200
+	// activityPerIndexerLimit bounds the rows read from each target table
+	activityPerIndexerLimit = 50
+	// activityQueryTimeout bounds the whole feed, slow target databases are skipped
+	activityQueryTimeout = 10 * time.Second
+)
+
+// GetActivity merges the most recent rows of every active indexer the user
+// owns into one feed, newest first. Indexers whose target database cannot be
+// read are skipped so one bad credential does not break the feed.
+func (s *IndexerService) GetActivity(ctx context.Context, userID uuid.UUID, limit int) ([]models.ActivityEvent, error) {
+	if limit <= 0 {
+		limit = DefaultActivityLimit
+	}
+	if limit > MaxActivityLimit {
+		limit = MaxActivityLimit
+	}
+
+	var pgUserID pgtype.UUID
+	if err := pgUserID.Scan(userID.String()); err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	indexers, err := s.store.GetIndexersByUserID(ctx, pgUserID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get indexers")
+		return nil, errors.New("failed to retrieve indexers")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, activityQueryTimeout)
+	defer cancel()
+
+	perIndexer := limit
+	if perIndexer > activityPerIndexerLimit {
+		perIndexer = activityPerIndexerLimit
+	}
+
+	// Indexers sharing a credential share one pool
+	pools := make(map[string]*pgxpool.Pool)
+	defer func() {
+		for _, pool := range pools {
+			pool.Close()
+		}
+	}()
+
+	events := []models.ActivityEvent{}
+	for _, idx := range indexers {
+		if idx.Status != db.IndexerStatusActive {
+			continue
+		}
+
+		credKey := idx.DbCredentialID.String()
+		pool, ok := pools[credKey]
+		if !ok {
+			pool, err = s.connectActivityPool(ctx, idx.DbCredentialID)
+			if err != nil {
+				log.Warn().
+					Err(logger.RedactError(err)).
+					Str("indexerID", idx.ID.String()).
+					Msg("Skipping indexer in activity feed, target database unavailable")
+				continue
+			}
+			pools[credKey] = pool
+		}
+
+		indexerEvents, err := fetchActivity(ctx, pool, idx, perIndexer)
+		if err != nil {
+			log.Warn().
+				Err(err).
+				Str("indexerID", idx.ID.String()).
+				Str("targetTable", idx.TargetTable).
+				Msg("Skipping indexer in activity feed, target table unreadable")
+			continue
+		}
+		events = append(events, indexerEvents...)
+	}
+
+	return mergeActivity(events, limit), nil
+}
+
+// mergeActivity sorts events newest first, by block time and then slot, and
+// keeps at most limit of them
+func mergeActivity(events []models.ActivityEvent, limit int) []models.ActivityEvent {
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].BlockTime.Equal(events[j].BlockTime) {
+			return events[i].BlockTime.After(events[j].BlockTime)
+		}
+		return events[i].Slot > events[j].Slot
+	})
+
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events
+}
+
+// connectActivityPool opens a small pool on the credential's database
+func (s *IndexerService) connectActivityPool(ctx context.Context, credID pgtype.UUID) (*pgxpool.Pool, error) {
+	cred, err := s.store.GetDBCredentialByID(ctx, credID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DB credential: %w", err)
+	}
+
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cred.DbHost, cred.DbPort, cred.DbUser, cred.DbPassword, cred.DbName, cred.DbSslMode)
+
+	poolConfig, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database config: %w", err)
+	}
+	poolConfig.MaxConns = 2
+	poolConfig.MinConns = 0
+	poolConfig.ConnConfig.ConnectTimeout = 5 * time.Second
+
+	return pgxpool.NewWithConfig(ctx, poolConfig)
+}
+
+// fetchActivity reads the newest rows of an indexer's target table and
+// normalizes them into activity events
+func fetchActivity(ctx context.Context, pool *pgxpool.Pool, idx db.Indexer, limit int) ([]models.ActivityEvent, error) {
+	indexerID, err := uuid.Parse(idx.ID.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid indexer ID: %w", err)
+	}
+
+	targetTable := indexer.QuoteTableName(idx.TargetTable)
+
+	var query string
+	switch idx.IndexerType {
+	case db.IndexerTypeNftBids:
+		query = fmt.Sprintf(`
+			SELECT 'bid', signature, slot, %[2]s, nft_mint, marketplace,
+				bid_amount::float8, bid_currency, bidder
+			FROM %[1]s
+			ORDER BY %[2]s DESC, slot DESC
+			LIMIT $1
+		`, targetTable, indexer.TimeColumnExpr(idx.Params))
+	case db.IndexerTypeNftPrices:
+		query = fmt.Sprintf(`
+			SELECT LOWER(status), signature, slot, %[2]s, COALESCE(nft_mint, ''), marketplace,
+				price::float8, currency, COALESCE(buyer, seller)
+			FROM %[1]s
+			ORDER BY %[2]s DESC, slot DESC
+			LIMIT $1
+		`, targetTable, indexer.TimeColumnExpr(idx.Params))
+	case db.IndexerTypeTokenPrices:
+		query = fmt.Sprintf(`
+			SELECT 'price', COALESCE(transaction_id, ''), slot, updated_at, token_address, platform,
+				price_usd::float8, 'USD', COALESCE(token_symbol, '')
+			FROM %s
+			ORDER BY updated_at DESC, slot DESC
+			LIMIT $1
+		`, targetTable)
+	case db.IndexerTypeTokenBorrow:
+		query = fmt.Sprintf(`
+			SELECT 'borrow_rate', '', slot, updated_at, token_address, platform,
+				COALESCE(borrow_rate, 0)::float8, '%%', ''
+			FROM %s
+			ORDER BY updated_at DESC, slot DESC
+			LIMIT $1
+		`, targetTable)
+	case db.IndexerTypeTokenHolders:
+		query = fmt.Sprintf(`
+			SELECT 'holders', '', slot, updated_at, mint, '',
+				COALESCE(holder_count, 0)::float8, 'holders', ''
+			FROM %s
+			ORDER BY updated_at DESC, slot DESC
+			LIMIT $1
+		`, targetTable)
+	case db.IndexerTypeProgramLogs:
+		query = fmt.Sprintf(`
+			SELECT 'instruction', signature, slot, block_time, program_id, '',
+				instruction_index::float8, 'index', ''
+			FROM %s
+			ORDER BY block_time DESC, slot DESC
+			LIMIT $1
+		`, targetTable)
+	default:
+		return nil, fmt.Errorf("unsupported indexer type: %s", idx.IndexerType)
+	}
+
+	rows, err := pool.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", targetTable, err)
+	}
+
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.ActivityEvent, error) {
+		event := models.ActivityEvent{
+			IndexerID:   indexerID,
+			IndexerType: models.IndexerType(idx.IndexerType),
+		}
+		err := row.Scan(
+			&event.EventType, &event.Signature, &event.Slot, &event.BlockTime,
+			&event.Asset, &event.Source, &event.Amount, &event.Unit, &event.Account,
+		)
+		return event, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", targetTable, err)
+	}
+
+	return events, nil
+}
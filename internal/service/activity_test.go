@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+func TestMergeActivitySortsNewestFirstAndTruncates(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := []models.ActivityEvent{
+		{Signature: "old-sale", BlockTime: base, Slot: 10, IndexerType: models.NFTPrices},
+		{Signature: "new-price", BlockTime: base.Add(2 * time.Minute), Slot: 30, IndexerType: models.TokenPrices},
+		{Signature: "same-time-low-slot", BlockTime: base.Add(time.Minute), Slot: 19, IndexerType: models.NFTBids},
+		{Signature: "same-time-high-slot", BlockTime: base.Add(time.Minute), Slot: 20, IndexerType: models.TokenPrices},
+	}
+
+	merged := mergeActivity(events, 3)
+
+	want := []string{"new-price", "same-time-high-slot", "same-time-low-slot"}
+	if len(merged) != len(want) {
+		t.Fatalf("got %d events, want %d", len(merged), len(want))
+	}
+	for i, signature := range want {
+		if merged[i].Signature != signature {
+			t.Errorf("event %d = %s, want %s", i, merged[i].Signature, signature)
+		}
+	}
+}
+
+func TestFetchActivityMergesNFTAndTokenIndexers(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("connect to test database: %v", err)
+	}
+	defer pool.Close()
+
+	suffix := time.Now().UnixNano()
+	nftTable := fmt.Sprintf("test_activity_nft_%d", suffix)
+	tokenTable := fmt.Sprintf("test_activity_token_%d", suffix)
+	t.Cleanup(func() {
+		pool.Exec(context.Background(), "DROP TABLE IF EXISTS "+nftTable+", "+tokenTable)
+	})
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE %s (
+			signature TEXT, slot BIGINT, block_time TIMESTAMPTZ, nft_mint TEXT, marketplace TEXT,
+			price NUMERIC, currency TEXT, buyer TEXT, seller TEXT, status TEXT)`, nftTable),
+		fmt.Sprintf(`CREATE TABLE %s (
+			transaction_id TEXT, slot BIGINT, updated_at TIMESTAMPTZ, token_address TEXT, platform TEXT,
+			price_usd NUMERIC, token_symbol TEXT)`, tokenTable),
+	}
+	for _, statement := range statements {
+		if _, err := pool.Exec(ctx, statement); err != nil {
+			t.Fatalf("create table: %v", err)
+		}
+	}
+
+	for i, minutes := range []int{0, 2, 4} {
+		if _, err := pool.Exec(ctx,
+			fmt.Sprintf("INSERT INTO %s VALUES ($1, $2, $3, 'mint', 'magic_eden', 1.5, 'SOL', 'buyer', 'seller', 'SOLD')", nftTable),
+			fmt.Sprintf("sale-%d", i), int64(100+minutes), base.Add(time.Duration(minutes)*time.Minute),
+		); err != nil {
+			t.Fatalf("insert sale: %v", err)
+		}
+	}
+	for i, minutes := range []int{1, 3} {
+		if _, err := pool.Exec(ctx,
+			fmt.Sprintf("INSERT INTO %s VALUES ($1, $2, $3, 'token', 'jupiter', 0.99, 'USDC')", tokenTable),
+			fmt.Sprintf("swap-%d", i), int64(100+minutes), base.Add(time.Duration(minutes)*time.Minute),
+		); err != nil {
+			t.Fatalf("insert price: %v", err)
+		}
+	}
+
+	var events []models.ActivityEvent
+	for _, idx := range []db.Indexer{
+		{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, IndexerType: db.IndexerTypeNftPrices, TargetTable: nftTable},
+		{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, IndexerType: db.IndexerTypeTokenPrices, TargetTable: tokenTable},
+	} {
+		indexerEvents, err := fetchActivity(ctx, pool, idx, 10)
+		if err != nil {
+			t.Fatalf("fetchActivity(%s): %v", idx.IndexerType, err)
+		}
+		events = append(events, indexerEvents...)
+	}
+
+	feed := mergeActivity(events, 10)
+
+	want := []struct {
+		signature string
+		eventType string
+	}{
+		{"sale-2", "sold"},
+		{"swap-1", "price"},
+		{"sale-1", "sold"},
+		{"swap-0", "price"},
+		{"sale-0", "sold"},
+	}
+	if len(feed) != len(want) {
+		t.Fatalf("got %d events, want %d", len(feed), len(want))
+	}
+	for i, w := range want {
+		if feed[i].Signature != w.signature || feed[i].EventType != w.eventType {
+			t.Errorf("event %d = %s (%s), want %s (%s)", i, feed[i].Signature, feed[i].EventType, w.signature, w.eventType)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// auditMaxLimit caps a page of audit events
+const auditMaxLimit = 500
+
+// Audited entity types
+const (
+	auditEntityIndexer    = "indexer"
+	auditEntityCredential = "db_credential"
+)
+
+// Audited actions
+const (
+	auditIndexerCreate       = "indexer.create"
+	auditIndexerUpdateParams = "indexer.update_params"
+	auditIndexerRetarget     = "indexer.retarget"
+	auditIndexerReset        = "indexer.reset"
+	auditIndexerPause        = "indexer.pause"
+	auditIndexerResume       = "indexer.resume"
+	auditIndexerDelete       = "indexer.delete"
+	auditIndexerRestore      = "indexer.restore"
+	auditIndexerRotateSecret = "indexer.rotate_secret"
+	auditCredentialCreate    = "db_credential.create"
+	auditCredentialUpdate    = "db_credential.update"
+	auditCredentialDelete    = "db_credential.delete"
+)
+
+// recordAudit writes an audit event for a change the actor made. The change
+// has already happened, so a failed write is logged rather than returned.
+func recordAudit(ctx context.Context, store db.Querier, actor uuid.UUID, action, entityType string, entityID pgtype.UUID, metadata map[string]interface{}) {
+	var pgActor pgtype.UUID
+	if err := pgActor.Scan(actor.String()); err != nil {
+		log.Error().Err(err).Str("action", action).Msg("Failed to convert audit actor ID")
+		return
+	}
+
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		log.Error().Err(err).Str("action", action).Msg("Failed to marshal audit metadata")
+		encoded = []byte("{}")
+	}
+
+	if err := store.CreateAuditEvent(ctx, db.CreateAuditEventParams{
+		ActorUserID: pgActor,
+		Action:      action,
+		EntityType:  entityType,
+		EntityID:    entityID,
+		Metadata:    encoded,
+	}); err != nil {
+		log.Error().Ctx(ctx).
+			Err(err).
+			Str("action", action).
+			Str("entityID", entityID.String()).
+			Msg("Failed to record audit event")
+	}
+}
+
+// GetAuditEvents returns the user's own audit trail, newest first
+func (s *UserService) GetAuditEvents(ctx context.Context, userID uuid.UUID, limit int32, offset int32) ([]models.AuditEventResponse, error) {
+	var pgUserID pgtype.UUID
+	if err := pgUserID.Scan(userID.String()); err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	if limit > auditMaxLimit {
+		limit = auditMaxLimit
+	}
+
+	events, err := s.store.GetAuditEventsByUserID(ctx, db.GetAuditEventsByUserIDParams{
+		ActorUserID: pgUserID,
+		Limit:       limit,
+		Offset:      offset,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get audit events")
+		return nil, errors.New("failed to retrieve audit events")
+	}
+
+	response := make([]models.AuditEventResponse, len(events))
+	for i, event := range events {
+		entityID, _ := uuid.Parse(event.EntityID.String())
+
+		var metadata interface{}
+		if err := json.Unmarshal(event.Metadata, &metadata); err != nil {
+			metadata = map[string]interface{}{}
+		}
+
+		response[i] = models.AuditEventResponse{
+			ID:         event.ID,
+			Action:     event.Action,
+			EntityType: event.EntityType,
+			EntityID:   entityID,
+			Metadata:   metadata,
+			CreatedAt:  event.CreatedAt.Time,
+		}
+	}
+	return response, nil
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// auditTrailStore creates indexers in memory and keeps the audit events
+// written along the way
+type auditTrailStore struct {
+	*createStore
+	events []db.CreateAuditEventParams
+}
+
+func (s *auditTrailStore) CreateIndexer(ctx context.Context, arg db.CreateIndexerParams) (db.Indexer, error) {
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	s.indexer = db.Indexer{
+		ID:             pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		UserID:         arg.UserID,
+		DbCredentialID: arg.DbCredentialID,
+		IndexerType:    arg.IndexerType,
+		Params:         arg.Params,
+		TargetTable:    arg.TargetTable,
+		Status:         arg.Status,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	return s.indexer, nil
+}
+
+func (s *auditTrailStore) UpdateIndexerStatus(ctx context.Context, arg db.UpdateIndexerStatusParams) (db.Indexer, error) {
+	s.indexer.Status = arg.Status
+	s.indexer.ErrorMessage = arg.ErrorMessage
+	return s.indexer, nil
+}
+
+func (s *auditTrailStore) UpsertIndexerWebhookSecret(ctx context.Context, arg db.UpsertIndexerWebhookSecretParams) (db.IndexerWebhookSecret, error) {
+	return db.IndexerWebhookSecret{IndexerID: arg.IndexerID, Secret: arg.Secret}, nil
+}
+
+func (s *auditTrailStore) CreateIndexingLog(ctx context.Context, arg db.CreateIndexingLogParams) (db.IndexingLog, error) {
+	return db.IndexingLog{}, nil
+}
+
+func (s *auditTrailStore) CreateAuditEvent(ctx context.Context, arg db.CreateAuditEventParams) error {
+	s.events = append(s.events, arg)
+	return nil
+}
+
+func TestCreateIndexerWritesAuditEvent(t *testing.T) {
+	cred, pool := testTarget(t)
+	table := testTable(t, pool)
+
+	userID := uuid.New()
+	store := &auditTrailStore{createStore: newCreateStore(userID)}
+	cred.ID, cred.UserID = store.cred.ID, store.cred.UserID
+	store.cred = cred
+	s := NewIndexerService(store, nil)
+
+	created, err := s.CreateIndexer(context.Background(), userID, models.CreateIndexerRequest{
+		DBCredentialID: uuid.UUID(cred.ID.Bytes),
+		IndexerType:    models.NFTPrices,
+		TargetTable:    table,
+		Params:         json.RawMessage(`{"collection": "J1S9H3QjnRtBbbuD4HjPV6RpRhwuk4zKbxsnCHuTgh9w"}`),
+	})
+	if err != nil {
+		t.Fatalf("CreateIndexer: %v", err)
+	}
+
+	if len(store.events) != 1 {
+		t.Fatalf("got %d audit events, want 1", len(store.events))
+	}
+	event := store.events[0]
+	if event.Action != auditIndexerCreate || event.EntityType != auditEntityIndexer {
+		t.Errorf("audit event is %s on %s, want %s on %s", event.Action, event.EntityType, auditIndexerCreate, auditEntityIndexer)
+	}
+	if uuid.UUID(event.ActorUserID.Bytes) != userID || uuid.UUID(event.EntityID.Bytes) != created.ID {
+		t.Errorf("audit event by %s on %s, want by %s on %s", event.ActorUserID, event.EntityID, userID, created.ID)
+	}
+
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(event.Metadata, &metadata); err != nil {
+		t.Fatalf("audit metadata %s: %v", event.Metadata, err)
+	}
+	if metadata["indexerType"] != "nft_prices" || metadata["targetTable"] != table {
+		t.Errorf("audit metadata = %v, want the indexer type and target table", metadata)
+	}
+}
+
+func TestCreateIndexerWithoutAuditEventWhenRejected(t *testing.T) {
+	userID := uuid.New()
+	store := &auditTrailStore{createStore: newCreateStore(userID)}
+	cred := unreachableCredential(t)
+	cred.ID, cred.UserID = store.cred.ID, store.cred.UserID
+	store.cred = cred
+	s := NewIndexerService(store, nil)
+
+	_, err := s.CreateIndexer(context.Background(), userID, models.CreateIndexerRequest{
+		DBCredentialID: uuid.UUID(cred.ID.Bytes),
+		IndexerType:    models.NFTPrices,
+		TargetTable:    "sales",
+		Params:         json.RawMessage(`{"collection": "J1S9H3QjnRtBbbuD4HjPV6RpRhwuk4zKbxsnCHuTgh9w"}`),
+	})
+	if !errors.Is(err, ErrPreflightFailed) {
+		t.Fatalf("CreateIndexer error = %v, want ErrPreflightFailed", err)
+	}
+	if len(store.events) != 0 {
+		t.Errorf("audit events = %+v, want none for an indexer that was never created", store.events)
+	}
+}
+
+func TestRecordAuditDefaultsMetadata(t *testing.T) {
+	store := &auditTrailStore{createStore: newCreateStore(uuid.New())}
+	actor := uuid.New()
+	entityID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+
+	recordAudit(context.Background(), store, actor, auditIndexerPause, auditEntityIndexer, entityID, nil)
+
+	if len(store.events) != 1 {
+		t.Fatalf("got %d audit events, want 1", len(store.events))
+	}
+	if event := store.events[0]; uuid.UUID(event.ActorUserID.Bytes) != actor || event.EntityID != entityID || string(event.Metadata) != "{}" {
+		t.Errorf("audit event = %+v, want the actor, the entity and empty metadata", event)
+	}
+}
@@ -17,6 +17,10 @@ import (
 	"github.com/rishavmehra/indexer/pkg/validator"
 )
 
+// ErrInvalidCurrentPassword is returned when a password change does not
+// present the user's current password
+var ErrInvalidCurrentPassword = errors.New("current password is incorrect")
+
 type AuthService struct {
 	cfg   config.JWTConfig
 	store db.Querier
@@ -101,12 +105,119 @@ func (s *AuthService) Login(ctx context.Context, req models.LoginRequest) (*mode
 		return nil, errors.New("authentication failed")
 	}
 
+	refreshToken, refreshExpiresAt, err := s.issueRefreshToken(ctx, user.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to issue refresh token")
+		return nil, errors.New("authentication failed")
+	}
+
+	return &models.TokenResponse{
+		Token:                 token,
+		ExpiresAt:             expiresAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: &refreshExpiresAt,
+	}, nil
+}
+
+// Refresh issues a new access token for a refresh token that is neither
+// expired nor revoked. The refresh token itself stays valid until it expires
+// or the user logs out.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*models.TokenResponse, error) {
+
+	stored, err := s.store.GetRefreshTokenByHash(ctx, crypto.HashToken(refreshToken))
+	if err != nil {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	if stored.RevokedAt.Valid {
+		return nil, errors.New("refresh token has been revoked")
+	}
+
+	if !stored.ExpiresAt.Valid || !time.Now().Before(stored.ExpiresAt.Time) {
+		return nil, errors.New("refresh token has expired")
+	}
+
+	id, err := uuid.Parse(stored.UserID.String())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to parse UUID")
+		return nil, errors.New("authentication failed")
+	}
+
+	expiresAt := time.Now().Add(s.cfg.ExpiresIn)
+	token, err := s.generateToken(id, expiresAt)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate token")
+		return nil, errors.New("authentication failed")
+	}
+
 	return &models.TokenResponse{
 		Token:     token,
 		ExpiresAt: expiresAt,
 	}, nil
 }
 
+// Logout revokes a refresh token. Revoking an unknown or already revoked
+// token is not an error so logout can be retried safely.
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	if err := s.store.RevokeRefreshToken(ctx, crypto.HashToken(refreshToken)); err != nil {
+		log.Error().Err(err).Msg("Failed to revoke refresh token")
+		return errors.New("failed to log out")
+	}
+	return nil
+}
+
+// ChangePassword replaces a user's password after checking the current one.
+// Every refresh token of the user is revoked, so other sessions have to log
+// in again once their access token expires.
+func (s *AuthService) ChangePassword(ctx context.Context, userID uuid.UUID, req models.ChangePasswordRequest) error {
+
+	var pgID pgtype.UUID
+	if err := pgID.Scan(userID.String()); err != nil {
+		log.Error().Err(err).Msg("Failed to convert UUID")
+		return errors.New("invalid user ID")
+	}
+
+	user, err := s.store.GetUserByID(ctx, pgID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get user")
+		return errors.New("user not found")
+	}
+
+	match, err := crypto.VerifyPassword(req.CurrentPassword, user.PasswordHash)
+	if err != nil || !match {
+		return ErrInvalidCurrentPassword
+	}
+
+	if !validator.IsValidPassword(req.NewPassword) {
+		return errors.New("password must be at least 8 characters and contain letters and numbers")
+	}
+
+	if req.NewPassword == req.CurrentPassword {
+		return errors.New("new password must differ from the current password")
+	}
+
+	hashedPassword, err := crypto.HashPassword(req.NewPassword)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to hash password")
+		return errors.New("failed to process password")
+	}
+
+	err = s.store.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+		ID:           pgID,
+		PasswordHash: hashedPassword,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to update password")
+		return errors.New("failed to update password")
+	}
+
+	if err := s.store.RevokeUserRefreshTokens(ctx, pgID); err != nil {
+		log.Error().Err(err).Str("userID", userID.String()).Msg("Failed to revoke refresh tokens after password change")
+	}
+
+	return nil
+}
+
 func (s *AuthService) GetUserByID(ctx context.Context, userID uuid.UUID) (*models.UserResponse, error) {
 
 	var pgID pgtype.UUID
@@ -134,6 +245,25 @@ func (s *AuthService) GetUserByID(ctx context.Context, userID uuid.UUID) (*model
 	}, nil
 }
 
+func (s *AuthService) issueRefreshToken(ctx context.Context, userID pgtype.UUID) (string, time.Time, error) {
+	token, err := crypto.GenerateToken(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt := time.Now().Add(s.cfg.RefreshExpiresIn)
+	_, err = s.store.CreateRefreshToken(ctx, db.CreateRefreshTokenParams{
+		UserID:    userID,
+		TokenHash: crypto.HashToken(token),
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
 func (s *AuthService) generateToken(userID uuid.UUID, expiresAt time.Time) (string, error) {
 	claims := jwt.RegisteredClaims{
 		Subject:   userID.String(),
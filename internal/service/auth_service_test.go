@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/rishavmehra/indexer/internal/config"
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/pkg/crypto"
+)
+
+const testPassword = "password123"
+
+// authStore keeps one user and their refresh tokens in memory
+type authStore struct {
+	db.Querier
+	user   db.User
+	tokens map[string]*db.RefreshToken
+}
+
+func newAuthStore(t *testing.T) *authStore {
+	t.Helper()
+
+	hash, err := crypto.HashPassword(testPassword)
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	return &authStore{
+		user: db.User{
+			ID:           pgtype.UUID{Bytes: uuid.New(), Valid: true},
+			Email:        "user@example.com",
+			PasswordHash: hash,
+		},
+		tokens: make(map[string]*db.RefreshToken),
+	}
+}
+
+func (s *authStore) GetUserByEmail(ctx context.Context, email string) (db.User, error) {
+	if email != s.user.Email {
+		return db.User{}, pgx.ErrNoRows
+	}
+	return s.user, nil
+}
+
+func (s *authStore) GetUserByID(ctx context.Context, id pgtype.UUID) (db.User, error) {
+	if id != s.user.ID {
+		return db.User{}, pgx.ErrNoRows
+	}
+	return s.user, nil
+}
+
+func (s *authStore) CreateRefreshToken(ctx context.Context, arg db.CreateRefreshTokenParams) (db.RefreshToken, error) {
+	token := &db.RefreshToken{UserID: arg.UserID, TokenHash: arg.TokenHash, ExpiresAt: arg.ExpiresAt}
+	s.tokens[arg.TokenHash] = token
+	return *token, nil
+}
+
+func (s *authStore) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (db.RefreshToken, error) {
+	token, ok := s.tokens[tokenHash]
+	if !ok {
+		return db.RefreshToken{}, pgx.ErrNoRows
+	}
+	return *token, nil
+}
+
+func (s *authStore) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	if token, ok := s.tokens[tokenHash]; ok {
+		token.RevokedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	}
+	return nil
+}
+
+func (s *authStore) UpdateUserPassword(ctx context.Context, arg db.UpdateUserPasswordParams) error {
+	s.user.PasswordHash = arg.PasswordHash
+	return nil
+}
+
+func (s *authStore) RevokeUserRefreshTokens(ctx context.Context, userID pgtype.UUID) error {
+	for _, token := range s.tokens {
+		if token.UserID == userID {
+			token.RevokedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+		}
+	}
+	return nil
+}
+
+func testJWTConfig() config.JWTConfig {
+	return config.JWTConfig{
+		Secret:           "test-secret",
+		ExpiresIn:        time.Hour,
+		RefreshExpiresIn: 24 * time.Hour,
+	}
+}
+
+func login(t *testing.T, s *AuthService) *models.TokenResponse {
+	t.Helper()
+
+	tokens, err := s.Login(context.Background(), models.LoginRequest{Email: "user@example.com", Password: testPassword})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	return tokens
+}
+
+func TestRefreshIssuesAccessTokenForUser(t *testing.T) {
+	store := newAuthStore(t)
+	s := NewAuthService(testJWTConfig(), store)
+
+	tokens := login(t, s)
+	if tokens.RefreshToken == "" || tokens.RefreshTokenExpiresAt == nil {
+		t.Fatal("Login returned no refresh token")
+	}
+	if _, stored := store.tokens[tokens.RefreshToken]; stored {
+		t.Error("refresh token stored in plain text, want only its hash")
+	}
+
+	refreshed, err := s.Refresh(context.Background(), tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	var claims jwt.RegisteredClaims
+	if _, err := jwt.ParseWithClaims(refreshed.Token, &claims, func(*jwt.Token) (interface{}, error) {
+		return []byte("test-secret"), nil
+	}); err != nil {
+		t.Fatalf("parse refreshed token: %v", err)
+	}
+	if claims.Subject != store.user.ID.String() {
+		t.Errorf("refreshed token subject = %s, want %s", claims.Subject, store.user.ID.String())
+	}
+}
+
+func TestRefreshRejectsExpiredToken(t *testing.T) {
+	store := newAuthStore(t)
+	s := NewAuthService(testJWTConfig(), store)
+
+	tokens := login(t, s)
+	stored := store.tokens[crypto.HashToken(tokens.RefreshToken)]
+	stored.ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true}
+
+	if _, err := s.Refresh(context.Background(), tokens.RefreshToken); err == nil || err.Error() != "refresh token has expired" {
+		t.Errorf("Refresh error = %v, want the token reported as expired", err)
+	}
+}
+
+func TestRefreshRejectsRevokedToken(t *testing.T) {
+	store := newAuthStore(t)
+	s := NewAuthService(testJWTConfig(), store)
+	ctx := context.Background()
+
+	tokens := login(t, s)
+	if err := s.Logout(ctx, tokens.RefreshToken); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	// Logging out twice is not an error
+	if err := s.Logout(ctx, tokens.RefreshToken); err != nil {
+		t.Fatalf("second Logout: %v", err)
+	}
+
+	if _, err := s.Refresh(ctx, tokens.RefreshToken); err == nil || err.Error() != "refresh token has been revoked" {
+		t.Errorf("Refresh error = %v, want the token reported as revoked", err)
+	}
+}
+
+func TestRefreshRejectsUnknownToken(t *testing.T) {
+	s := NewAuthService(testJWTConfig(), newAuthStore(t))
+
+	if _, err := s.Refresh(context.Background(), "not-a-token"); err == nil {
+		t.Error("Refresh accepted a token that was never issued")
+	}
+}
+
+func TestChangePasswordRejectsWrongCurrentPassword(t *testing.T) {
+	store := newAuthStore(t)
+	s := NewAuthService(testJWTConfig(), store)
+	oldHash := store.user.PasswordHash
+
+	err := s.ChangePassword(context.Background(), uuid.UUID(store.user.ID.Bytes), models.ChangePasswordRequest{
+		CurrentPassword: "wrong-password1",
+		NewPassword:     "newpassword456",
+	})
+	if !errors.Is(err, ErrInvalidCurrentPassword) {
+		t.Errorf("ChangePassword error = %v, want ErrInvalidCurrentPassword", err)
+	}
+	if store.user.PasswordHash != oldHash {
+		t.Error("password changed despite the wrong current password")
+	}
+}
+
+func TestChangePasswordRejectsWeakNewPassword(t *testing.T) {
+	store := newAuthStore(t)
+	s := NewAuthService(testJWTConfig(), store)
+	oldHash := store.user.PasswordHash
+
+	for _, weak := range []string{"short1", "onlyletters", "12345678", testPassword} {
+		err := s.ChangePassword(context.Background(), uuid.UUID(store.user.ID.Bytes), models.ChangePasswordRequest{
+			CurrentPassword: testPassword,
+			NewPassword:     weak,
+		})
+		if err == nil {
+			t.Errorf("ChangePassword accepted %q as the new password", weak)
+		}
+	}
+	if store.user.PasswordHash != oldHash {
+		t.Error("password changed to a rejected value")
+	}
+}
+
+func TestChangePasswordRevokesRefreshTokens(t *testing.T) {
+	store := newAuthStore(t)
+	s := NewAuthService(testJWTConfig(), store)
+	ctx := context.Background()
+
+	tokens := login(t, s)
+	if err := s.ChangePassword(ctx, uuid.UUID(store.user.ID.Bytes), models.ChangePasswordRequest{
+		CurrentPassword: testPassword,
+		NewPassword:     "newpassword456",
+	}); err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+
+	if match, err := crypto.VerifyPassword("newpassword456", store.user.PasswordHash); err != nil || !match {
+		t.Error("stored hash does not match the new password")
+	}
+	if _, err := s.Refresh(ctx, tokens.RefreshToken); err == nil {
+		t.Error("refresh token issued before the password change still works")
+	}
+}
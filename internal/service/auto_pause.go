@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/pkg/logger"
+)
+
+// failureStreak is the run of consecutive processing failures of one indexer
+type failureStreak struct {
+	count int
+	since time.Time
+}
+
+// failureTracker counts consecutive processing failures per indexer in
+// memory, so the counts start over when the server restarts
+type failureTracker struct {
+	mu      sync.Mutex
+	streaks map[uuid.UUID]*failureStreak
+}
+
+func newFailureTracker() *failureTracker {
+	return &failureTracker{streaks: make(map[uuid.UUID]*failureStreak)}
+}
+
+// fail records a failure and reports whether the indexer has now failed
+// threshold times in a row within window. A streak that started longer ago
+// than window starts over, and one that reaches the threshold is cleared.
+func (t *failureTracker) fail(indexerID uuid.UUID, threshold int, window time.Duration) (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	streak, found := t.streaks[indexerID]
+	if !found || now.Sub(streak.since) > window {
+		streak = &failureStreak{since: now}
+		t.streaks[indexerID] = streak
+	}
+	streak.count++
+
+	if streak.count < threshold {
+		return streak.count, false
+	}
+	delete(t.streaks, indexerID)
+	return streak.count, true
+}
+
+// succeed ends the failure streak of an indexer
+func (t *failureTracker) succeed(indexerID uuid.UUID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.streaks, indexerID)
+}
+
+// SetAutoPause pauses indexers that fail to process failures payloads in a
+// row within window. Zero failures turns auto-pausing off.
+func (s *IndexerService) SetAutoPause(failures int, window time.Duration) {
+	s.autoPauseFailures = failures
+	s.autoPauseWindow = window
+}
+
+// trackProcessingOutcome counts the outcome of processing a payload towards
+// the indexer's failure streak and pauses the indexer once the streak reaches
+// the auto-pause threshold. Failures caused by the request being cancelled,
+// such as on shutdown, don't count.
+func (s *IndexerService) trackProcessingOutcome(ctx context.Context, foundIndexer db.Indexer, processErr error) {
+	if s.autoPauseFailures <= 0 {
+		return
+	}
+	idUUID, err := uuid.Parse(foundIndexer.ID.String())
+	if err != nil {
+		return
+	}
+
+	if processErr == nil {
+		s.failures.succeed(idUUID)
+		return
+	}
+	if ctx.Err() != nil || foundIndexer.Status != db.IndexerStatusActive {
+		return
+	}
+
+	failures, trip := s.failures.fail(idUUID, s.autoPauseFailures, s.autoPauseWindow)
+	if !trip {
+		return
+	}
+	s.autoPauseIndexer(context.WithoutCancel(ctx), foundIndexer, failures, processErr)
+}
+
+// autoPauseIndexer pauses an indexer that kept failing, keeping the last
+// failure as its last_error. It stays paused until its owner resumes it.
+func (s *IndexerService) autoPauseIndexer(ctx context.Context, foundIndexer db.Indexer, failures int, cause error) {
+	reason := fmt.Sprintf("Auto-paused after %d consecutive failures within %s", failures, s.autoPauseWindow)
+
+	_, err := s.store.UpdateIndexerStatus(ctx, db.UpdateIndexerStatusParams{
+		ID:           foundIndexer.ID,
+		Status:       db.IndexerStatusPaused,
+		ErrorMessage: pgtype.Text{String: reason, Valid: true},
+	})
+	if err != nil {
+		log.Error().Err(err).Str("indexerID", foundIndexer.ID.String()).Msg("Failed to auto-pause indexer")
+		return
+	}
+
+	s.recordLastError(ctx, foundIndexer.ID, cause)
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"failures": failures,
+		"window":   s.autoPauseWindow.String(),
+		"error":    logger.Redact(cause.Error()),
+	})
+	message := reason + ": " + logger.Redact(cause.Error())
+
+	_, logErr := s.store.CreateIndexingLog(ctx, db.CreateIndexingLogParams{
+		IndexerID: foundIndexer.ID,
+		EventType: "auto_paused",
+		Message:   message,
+		Details:   details,
+	})
+	if logErr != nil {
+		log.Error().Err(logErr).Msg("Failed to create auto-pause log entry")
+	}
+
+	s.publishEvent(foundIndexer.ID, "auto_paused", message, details)
+
+	log.Warn().
+		Err(logger.RedactError(cause)).
+		Str("indexerID", foundIndexer.ID.String()).
+		Int("failures", failures).
+		Msg("Indexer auto-paused after repeated failures")
+}
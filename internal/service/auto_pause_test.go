@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+)
+
+// autoPauseStore records status and last error changes of its indexer. Its
+// credential points at a database that refuses connections, so every payload
+// that gets past the dedup fails.
+type autoPauseStore struct {
+	*rawStore
+	statuses   []db.UpdateIndexerStatusParams
+	lastErrors []db.UpdateIndexerLastErrorParams
+}
+
+func (s *autoPauseStore) UpdateIndexerStatus(ctx context.Context, arg db.UpdateIndexerStatusParams) (db.Indexer, error) {
+	s.statuses = append(s.statuses, arg)
+	s.indexer.Status = arg.Status
+	return s.indexer, nil
+}
+
+func (s *autoPauseStore) UpdateIndexerLastError(ctx context.Context, arg db.UpdateIndexerLastErrorParams) (db.Indexer, error) {
+	s.lastErrors = append(s.lastErrors, arg)
+	return s.indexer, nil
+}
+
+func newAutoPauseService(t *testing.T, failures int) (*IndexerService, *autoPauseStore) {
+	t.Helper()
+
+	store := &autoPauseStore{rawStore: newRawStore(uuid.New())}
+	store.cred = unreachableCredential(t)
+	s := NewIndexerService(store, nil)
+	s.SetDedupWindow(time.Hour)
+	s.SetAutoPause(failures, time.Minute)
+	return s, store
+}
+
+// processFailing processes a payload that fails at the target database
+func processFailing(t *testing.T, s *IndexerService, store *autoPauseStore, signature string) {
+	t.Helper()
+
+	if err := s.processIndexerPayload(context.Background(), store.indexer, "webhook", signedPayload(signature, 1)); err == nil {
+		t.Fatalf("processing %s succeeded, want it to fail at the target database", signature)
+	}
+}
+
+// processSucceeding processes a payload that succeeds without reaching the
+// target database, as one already processed does
+func processSucceeding(t *testing.T, s *IndexerService, store *autoPauseStore, signature string) {
+	t.Helper()
+
+	s.dedup.claim(uuid.UUID(store.indexer.ID.Bytes), signature)
+	if err := s.processIndexerPayload(context.Background(), store.indexer, "webhook", signedPayload(signature, 1)); err != nil {
+		t.Fatalf("processing %s: %v", signature, err)
+	}
+}
+
+func autoPauseLogs(store *autoPauseStore) int {
+	count := 0
+	for _, entry := range store.logs {
+		if entry.EventType == "auto_paused" {
+			count++
+		}
+	}
+	return count
+}
+
+func TestAutoPauseAfterConsecutiveFailures(t *testing.T) {
+	s, store := newAutoPauseService(t, 3)
+
+	for i := 1; i <= 2; i++ {
+		processFailing(t, s, store, fmt.Sprintf("fail-%d", i))
+	}
+	if len(store.statuses) != 0 || store.indexer.Status != db.IndexerStatusActive {
+		t.Fatalf("indexer paused after 2 failures, want it paused at 3 (statuses %+v)", store.statuses)
+	}
+
+	processFailing(t, s, store, "fail-3")
+
+	if len(store.statuses) != 1 || store.statuses[0].Status != db.IndexerStatusPaused {
+		t.Fatalf("statuses = %+v, want the indexer paused once", store.statuses)
+	}
+	if reason := store.statuses[0].ErrorMessage.String; !strings.HasPrefix(reason, "Auto-paused after 3 consecutive failures") {
+		t.Errorf("pause reason = %q, want the failure count", reason)
+	}
+	if len(store.lastErrors) != 1 || !store.lastErrors[0].LastError.Valid {
+		t.Errorf("last errors = %+v, want the last failure recorded", store.lastErrors)
+	}
+	if autoPauseLogs(store) != 1 {
+		t.Errorf("logs = %+v, want one auto_paused entry", store.logs)
+	}
+
+	// The indexer is paused now; further failures don't pause it again
+	processFailing(t, s, store, "fail-4")
+	if len(store.statuses) != 1 {
+		t.Errorf("paused indexer updated %d times, want once", len(store.statuses))
+	}
+}
+
+func TestAutoPauseSuccessResetsFailures(t *testing.T) {
+	s, store := newAutoPauseService(t, 3)
+
+	processFailing(t, s, store, "fail-1")
+	processFailing(t, s, store, "fail-2")
+	processSucceeding(t, s, store, "ok-1")
+	processFailing(t, s, store, "fail-3")
+	processFailing(t, s, store, "fail-4")
+
+	if len(store.statuses) != 0 {
+		t.Fatalf("indexer paused after a success broke the streak (statuses %+v)", store.statuses)
+	}
+
+	processFailing(t, s, store, "fail-5")
+	if len(store.statuses) != 1 || store.indexer.Status != db.IndexerStatusPaused {
+		t.Errorf("statuses = %+v, want the indexer paused at the third failure in a row", store.statuses)
+	}
+}
+
+func TestAutoPauseOff(t *testing.T) {
+	s, store := newAutoPauseService(t, 0)
+
+	for i := 1; i <= 5; i++ {
+		processFailing(t, s, store, fmt.Sprintf("fail-%d", i))
+	}
+	if len(store.statuses) != 0 || autoPauseLogs(store) != 0 {
+		t.Errorf("indexer auto-paused with auto-pausing off (statuses %+v)", store.statuses)
+	}
+}
+
+func TestAutoPauseIgnoresCancelledRequests(t *testing.T) {
+	s, store := newAutoPauseService(t, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for i := 1; i <= 3; i++ {
+		s.processIndexerPayload(ctx, store.indexer, "webhook", signedPayload(fmt.Sprintf("cancelled-%d", i), 1))
+	}
+	if len(store.statuses) != 0 {
+		t.Errorf("indexer paused by failures of cancelled requests (statuses %+v)", store.statuses)
+	}
+}
+
+func TestFailureTrackerStartsOverAfterWindow(t *testing.T) {
+	tracker := newFailureTracker()
+	indexerID := uuid.New()
+
+	if _, trip := tracker.fail(indexerID, 2, 20*time.Millisecond); trip {
+		t.Fatal("first failure tripped the threshold of 2")
+	}
+	time.Sleep(40 * time.Millisecond)
+	if failures, trip := tracker.fail(indexerID, 2, 20*time.Millisecond); trip || failures != 1 {
+		t.Errorf("failure after the window = %d, %v; want the streak started over", failures, trip)
+	}
+	if failures, trip := tracker.fail(indexerID, 2, 20*time.Millisecond); !trip || failures != 2 {
+		t.Errorf("second failure in the window = %d, %v; want the threshold tripped", failures, trip)
+	}
+
+	// A tripped streak is cleared
+	if failures, _ := tracker.fail(indexerID, 2, 20*time.Millisecond); failures != 1 {
+		t.Errorf("failure after tripping = %d, want a new streak", failures)
+	}
+}
@@ -0,0 +1,354 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/pkg/logger"
+)
+
+// ErrNoBackfill is returned when resuming the backfill of an indexer created
+// without a backfillLimit
+var ErrNoBackfill = errors.New("indexer has no backfill configured")
+
+// backfillTimeout bounds one backfill run; a run cut short is resumed with
+// POST /indexers/:id/backfill
+const backfillTimeout = 2 * time.Minute
+
+// indexerBackfillLimit returns the backfillLimit param of an indexer
+func indexerBackfillLimit(params json.RawMessage) int {
+	var options models.IndexerOptions
+	if err := json.Unmarshal(params, &options); err != nil {
+		return 0
+	}
+	return options.BackfillLimit
+}
+
+// runInitialBackfill backfills a new indexer before its webhook goes live. A
+// failed or unfinished backfill doesn't fail the indexer; its progress is
+// kept so it can be resumed.
+func (s *IndexerService) runInitialBackfill(ctx context.Context, createdIndexer db.Indexer, addresses []string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), backfillTimeout)
+	defer cancel()
+
+	processed, err := s.backfillIndexer(ctx, createdIndexer, addresses)
+	if err != nil {
+		log.Warn().Err(err).
+			Str("indexerID", createdIndexer.ID.String()).
+			Int("processed", processed).
+			Msg("Initial backfill did not finish, it can be resumed")
+	}
+	s.logBackfill(ctx, createdIndexer.ID, processed, err)
+}
+
+// backfillIndexer feeds past transactions of each address through the same
+// processing as webhook payloads, up to the indexer's backfillLimit per
+// address. Progress is stored after every page, so a run that stops part way
+// resumes from the last transaction it processed. It returns how many
+// transactions this run processed.
+func (s *IndexerService) backfillIndexer(ctx context.Context, foundIndexer db.Indexer, addresses []string) (int, error) {
+	if s.heliusClient == nil {
+		return 0, ErrHeliusNotConfigured
+	}
+
+	limit := indexerBackfillLimit(foundIndexer.Params)
+	if limit <= 0 {
+		return 0, ErrNoBackfill
+	}
+
+	existing, err := s.store.GetIndexerBackfills(ctx, foundIndexer.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load backfill progress: %w", err)
+	}
+	progress := make(map[string]db.IndexerBackfill, len(existing))
+	for _, backfill := range existing {
+		progress[backfill.Address] = backfill
+	}
+
+	processed := 0
+	for _, address := range addresses {
+		backfill, found := progress[address]
+		if !found {
+			backfill = db.IndexerBackfill{IndexerID: foundIndexer.ID, Address: address}
+		}
+		if backfill.Completed {
+			continue
+		}
+
+		n, err := s.backfillAddress(ctx, foundIndexer, &backfill, limit)
+		processed += n
+		if saveErr := s.saveBackfill(ctx, backfill); saveErr != nil {
+			log.Error().Err(saveErr).Str("indexerID", foundIndexer.ID.String()).Str("address", address).Msg("Failed to save backfill progress")
+		}
+		if err != nil {
+			return processed, err
+		}
+	}
+
+	return processed, nil
+}
+
+// backfillAddress pages back through the history of one address from the
+// backfill's cursor until limit transactions were fetched or the history runs
+// out, updating backfill as it goes
+func (s *IndexerService) backfillAddress(ctx context.Context, foundIndexer db.Indexer, backfill *db.IndexerBackfill, limit int) (int, error) {
+	processed := 0
+	for int(backfill.Fetched) < limit {
+		pageSize := limit - int(backfill.Fetched)
+		if pageSize > indexer.MaxAddressTransactionsLimit {
+			pageSize = indexer.MaxAddressTransactionsLimit
+		}
+
+		transactions, err := s.heliusClient.GetAddressTransactions(ctx, backfill.Address, backfill.BeforeSignature.String, pageSize)
+		if err != nil {
+			return processed, fmt.Errorf("failed to get transactions of %s: %w", backfill.Address, logger.RedactError(err))
+		}
+
+		n, err := s.backfillPage(ctx, foundIndexer, backfill, transactions)
+		processed += n
+		if err != nil {
+			return processed, err
+		}
+
+		if len(transactions) < pageSize {
+			break
+		}
+		if err := s.saveBackfill(ctx, *backfill); err != nil {
+			log.Error().Err(err).Str("indexerID", foundIndexer.ID.String()).Str("address", backfill.Address).Msg("Failed to save backfill progress")
+		}
+	}
+
+	backfill.Completed = true
+	return processed, nil
+}
+
+// backfillPage processes one page of an address's history, moving the
+// backfill's cursor past each transaction it processed. Indexers that batch
+// their writes get the whole page at once.
+func (s *IndexerService) backfillPage(ctx context.Context, foundIndexer db.Indexer, backfill *db.IndexerBackfill, transactions []json.RawMessage) (int, error) {
+	var payloads []models.HeliusWebhookPayload
+	for _, txData := range transactions {
+		payload, ok := TransactionPayload(txData)
+		if !ok {
+			backfill.Fetched++
+			continue
+		}
+		payloads = append(payloads, payload)
+	}
+	if len(payloads) == 0 {
+		return 0, nil
+	}
+
+	idxImpl, err := s.getOrCreateIndexerImpl(ctx, foundIndexer)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create indexer implementation: %w", err)
+	}
+
+	if batcher, ok := idxImpl.(indexer.BatchIndexer); ok && implTypeMatches(idxImpl, foundIndexer.IndexerType) {
+		if err := s.processIndexerBatch(ctx, foundIndexer, batcher, payloads); err != nil {
+			return 0, fmt.Errorf("failed to process backfilled page of %s: %w", backfill.Address, err)
+		}
+		backfill.BeforeSignature = pgtype.Text{String: payloads[len(payloads)-1].Transaction.ID, Valid: true}
+		backfill.Fetched += int32(len(payloads))
+		return len(payloads), nil
+	}
+
+	processed := 0
+	for _, payload := range payloads {
+		if err := s.processIndexerPayload(ctx, foundIndexer, "", payload); err != nil {
+			return processed, fmt.Errorf("failed to process backfilled transaction %s: %w", payload.Transaction.ID, err)
+		}
+
+		backfill.BeforeSignature = pgtype.Text{String: payload.Transaction.ID, Valid: true}
+		backfill.Fetched++
+		processed++
+	}
+	return processed, nil
+}
+
+// processIndexerBatch writes a page of payloads through an indexer that
+// batches its writes. Signatures the indexer already processed are skipped,
+// and the page gets one success log entry rather than one per payload.
+func (s *IndexerService) processIndexerBatch(ctx context.Context, foundIndexer db.Indexer, batcher indexer.BatchIndexer, payloads []models.HeliusWebhookPayload) (err error) {
+	defer func() { s.trackProcessingOutcome(ctx, foundIndexer, err) }()
+
+	indexerID, _ := uuid.Parse(foundIndexer.ID.String())
+	var claimed []string
+	var fresh []models.HeliusWebhookPayload
+	for _, payload := range payloads {
+		var signature string
+		if len(payload.Transaction.Signatures) > 0 {
+			signature = payload.Transaction.Signatures[0]
+		}
+		if !s.dedup.claim(indexerID, signature) {
+			continue
+		}
+		claimed = append(claimed, signature)
+		fresh = append(fresh, payload)
+	}
+	if len(fresh) == 0 {
+		return nil
+	}
+
+	processed := false
+	defer func() {
+		if !processed {
+			for _, signature := range claimed {
+				s.dedup.release(indexerID, signature)
+			}
+		}
+	}()
+
+	cred, err := s.store.GetDBCredentialByID(ctx, foundIndexer.DbCredentialID)
+	if err != nil {
+		return fmt.Errorf("database credential not found: %w", err)
+	}
+
+	pool, err := s.openTargetPool(ctx, cred)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	result, err := batcher.ProcessPayloads(ctx, pool, foundIndexer.TargetTable, fresh)
+	if err != nil {
+		s.recordLastError(ctx, foundIndexer.ID, err)
+		return err
+	}
+	processed = true
+
+	if _, err := s.store.UpdateLastIndexedTime(ctx, foundIndexer.ID); err != nil {
+		log.Error().Ctx(ctx).Err(err).Msg("Failed to update last indexed time")
+	}
+
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"payloads":     len(fresh),
+		"rows_written": result.RowsAffected,
+		"event_types":  result.EventTypes,
+	})
+
+	_, logErr := s.store.CreateIndexingLog(ctx, db.CreateIndexingLogParams{
+		IndexerID: foundIndexer.ID,
+		EventType: "success",
+		Message:   fmt.Sprintf("Successfully processed %d backfilled payloads", len(fresh)),
+		Details:   details,
+	})
+	if logErr != nil {
+		log.Error().Ctx(ctx).Err(logErr).Msg("Failed to create success log entry")
+	}
+	return nil
+}
+
+func (s *IndexerService) saveBackfill(ctx context.Context, backfill db.IndexerBackfill) error {
+	_, err := s.store.UpsertIndexerBackfill(ctx, db.UpsertIndexerBackfillParams{
+		IndexerID:       backfill.IndexerID,
+		Address:         backfill.Address,
+		BeforeSignature: backfill.BeforeSignature,
+		Fetched:         backfill.Fetched,
+		Completed:       backfill.Completed,
+	})
+	return err
+}
+
+// logBackfill records the outcome of a backfill run in the indexing logs
+func (s *IndexerService) logBackfill(ctx context.Context, indexerID pgtype.UUID, processed int, backfillErr error) {
+	detailsMap := map[string]interface{}{
+		"processed": processed,
+	}
+	message := fmt.Sprintf("Backfilled %d past transactions", processed)
+	if backfillErr != nil {
+		detailsMap["error"] = logger.Redact(backfillErr.Error())
+		message = fmt.Sprintf("Backfill stopped after %d past transactions: %s", processed, logger.Redact(backfillErr.Error()))
+	}
+	details, _ := json.Marshal(detailsMap)
+
+	_, logErr := s.store.CreateIndexingLog(ctx, db.CreateIndexingLogParams{
+		IndexerID: indexerID,
+		EventType: "backfill",
+		Message:   message,
+		Details:   details,
+	})
+	if logErr != nil {
+		log.Error().Err(logErr).Msg("Failed to create backfill log entry")
+	}
+}
+
+// ResumeBackfill continues the backfill of an active indexer from where its
+// last run stopped and reports the progress of each address
+func (s *IndexerService) ResumeBackfill(ctx context.Context, userID uuid.UUID, indexerID uuid.UUID) (*models.BackfillResponse, error) {
+
+	if s.maintenance.Enabled() {
+		return nil, ErrMaintenance
+	}
+
+	var pgIndexerID pgtype.UUID
+	if err := pgIndexerID.Scan(indexerID.String()); err != nil {
+		return nil, fmt.Errorf("invalid indexer ID: %w", err)
+	}
+
+	foundIndexer, err := s.store.GetIndexerByID(ctx, pgIndexerID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get indexer")
+		return nil, errors.New("indexer not found")
+	}
+
+	userIDFromDB, err := uuid.Parse(foundIndexer.UserID.String())
+	if err != nil || userIDFromDB != userID {
+		return nil, errors.New("indexer not found")
+	}
+
+	if foundIndexer.Status != db.IndexerStatusActive {
+		return nil, fmt.Errorf("%w (status: %s)", ErrIndexerNotActive, foundIndexer.Status)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, backfillTimeout)
+	defer cancel()
+
+	addresses := indexerAddresses(models.IndexerType(foundIndexer.IndexerType), foundIndexer.Params)
+	processed, backfillErr := s.backfillIndexer(runCtx, foundIndexer, addresses)
+	if errors.Is(backfillErr, ErrNoBackfill) || errors.Is(backfillErr, ErrHeliusNotConfigured) {
+		return nil, backfillErr
+	}
+	s.logBackfill(ctx, foundIndexer.ID, processed, backfillErr)
+
+	log.Info().
+		Str("indexerID", indexerID.String()).
+		Int("processed", processed).
+		AnErr("error", backfillErr).
+		Msg("Indexer backfill resumed")
+
+	backfills, err := s.store.GetIndexerBackfills(ctx, pgIndexerID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get backfill progress")
+		return nil, errors.New("failed to retrieve backfill progress")
+	}
+
+	response := &models.BackfillResponse{
+		IndexerID: indexerID,
+		Processed: processed,
+		Addresses: make([]models.BackfillProgress, len(backfills)),
+	}
+	for i, backfill := range backfills {
+		response.Addresses[i] = models.BackfillProgress{
+			Address:   backfill.Address,
+			Fetched:   backfill.Fetched,
+			Completed: backfill.Completed,
+			UpdatedAt: backfill.UpdatedAt.Time,
+		}
+	}
+	return response, nil
+}
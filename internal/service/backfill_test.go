@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+)
+
+// historyServer serves the transaction history of one address the way the
+// Helius enhanced transactions API pages it, newest first. Once failAfter
+// pages were served every further request fails.
+type historyServer struct {
+	mu         sync.Mutex
+	signatures []string
+	failAfter  int
+	befores    []string
+}
+
+// newHistoryServer returns a history of n transactions, sig-0 the newest
+func newHistoryServer(n int) *historyServer {
+	h := &historyServer{failAfter: -1}
+	for i := 0; i < n; i++ {
+		h.signatures = append(h.signatures, fmt.Sprintf("sig-%d", i))
+	}
+	return h
+}
+
+func (h *historyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !strings.HasSuffix(r.URL.Path, "/transactions") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if h.failAfter >= 0 && len(h.befores) >= h.failAfter {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	before := r.URL.Query().Get("before")
+	h.befores = append(h.befores, before)
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	start := 0
+	if before != "" {
+		for i, signature := range h.signatures {
+			if signature == before {
+				start = i + 1
+			}
+		}
+	}
+	end := min(start+limit, len(h.signatures))
+
+	page := []map[string]interface{}{}
+	for i, signature := range h.signatures[start:end] {
+		page = append(page, map[string]interface{}{
+			"signature": signature,
+			"slot":      len(h.signatures) - start - i,
+			"type":      "NFT_SALE",
+		})
+	}
+	json.NewEncoder(w).Encode(page)
+}
+
+// requestedBefores returns the before cursor of every page requested
+func (h *historyServer) requestedBefores() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.befores...)
+}
+
+// backfillStore keeps the backfill progress of its indexer in memory
+type backfillStore struct {
+	*rawStore
+	backfills map[string]db.IndexerBackfill
+}
+
+func newBackfillStore(userID uuid.UUID, backfillLimit int) *backfillStore {
+	store := &backfillStore{rawStore: newRawStore(userID), backfills: make(map[string]db.IndexerBackfill)}
+	store.indexer.Params = json.RawMessage(fmt.Sprintf(`{"collection": "collection", "backfillLimit": %d}`, backfillLimit))
+	return store
+}
+
+func (s *backfillStore) GetIndexerBackfills(ctx context.Context, indexerID pgtype.UUID) ([]db.IndexerBackfill, error) {
+	var backfills []db.IndexerBackfill
+	for _, backfill := range s.backfills {
+		backfills = append(backfills, backfill)
+	}
+	return backfills, nil
+}
+
+func (s *backfillStore) UpsertIndexerBackfill(ctx context.Context, arg db.UpsertIndexerBackfillParams) (db.IndexerBackfill, error) {
+	backfill := db.IndexerBackfill{
+		IndexerID:       arg.IndexerID,
+		Address:         arg.Address,
+		BeforeSignature: arg.BeforeSignature,
+		Fetched:         arg.Fetched,
+		Completed:       arg.Completed,
+		UpdatedAt:       pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	s.backfills[arg.Address] = backfill
+	return backfill, nil
+}
+
+// newBackfillService returns a service backfilling from history. Every
+// transaction of the history counts as already indexed, so processing one
+// succeeds without a target database.
+func newBackfillService(t *testing.T, store *backfillStore, history *historyServer) *IndexerService {
+	t.Helper()
+
+	s := NewIndexerService(store, newAuditClient(t, history))
+	s.SetDedupWindow(time.Hour)
+	for _, signature := range history.signatures {
+		s.dedup.claim(uuid.UUID(store.indexer.ID.Bytes), signature)
+	}
+	return s
+}
+
+func TestBackfillIndexerPagesUpToLimit(t *testing.T) {
+	store := newBackfillStore(uuid.New(), 150)
+	history := newHistoryServer(250)
+	s := newBackfillService(t, store, history)
+
+	processed, err := s.backfillIndexer(context.Background(), store.indexer, []string{"collection"})
+	if err != nil {
+		t.Fatalf("backfillIndexer: %v", err)
+	}
+	if processed != 150 {
+		t.Errorf("processed %d transactions, want the backfill limit of 150", processed)
+	}
+
+	befores := history.requestedBefores()
+	if len(befores) != 2 || befores[0] != "" || befores[1] != "sig-99" {
+		t.Errorf("requested pages before %q, want the newest page then the one before sig-99", befores)
+	}
+
+	backfill := store.backfills["collection"]
+	if backfill.Fetched != 150 || !backfill.Completed || backfill.BeforeSignature.String != "sig-149" {
+		t.Errorf("backfill = %+v, want 150 fetched up to sig-149 and completed", backfill)
+	}
+}
+
+func TestBackfillIndexerStopsAtEndOfHistory(t *testing.T) {
+	store := newBackfillStore(uuid.New(), 100)
+	history := newHistoryServer(30)
+	s := newBackfillService(t, store, history)
+
+	processed, err := s.backfillIndexer(context.Background(), store.indexer, []string{"collection"})
+	if err != nil {
+		t.Fatalf("backfillIndexer: %v", err)
+	}
+	if processed != 30 || len(history.requestedBefores()) != 1 {
+		t.Errorf("processed %d transactions in %d pages, want the whole history of 30 in one", processed, len(history.requestedBefores()))
+	}
+	if backfill := store.backfills["collection"]; !backfill.Completed {
+		t.Errorf("backfill = %+v, want it completed", backfill)
+	}
+}
+
+func TestResumeBackfillContinuesWhereItStopped(t *testing.T) {
+	userID := uuid.New()
+	store := newBackfillStore(userID, 250)
+	history := newHistoryServer(300)
+	history.failAfter = 1
+	s := newBackfillService(t, store, history)
+	ctx := context.Background()
+
+	processed, err := s.backfillIndexer(ctx, store.indexer, []string{"collection"})
+	if err == nil {
+		t.Fatal("backfillIndexer succeeded although the second page failed")
+	}
+	if processed != 100 {
+		t.Errorf("processed %d transactions before the failure, want the first page of 100", processed)
+	}
+	if backfill := store.backfills["collection"]; backfill.Completed || backfill.Fetched != 100 || backfill.BeforeSignature.String != "sig-99" {
+		t.Fatalf("saved progress = %+v, want 100 fetched up to sig-99", backfill)
+	}
+
+	history.mu.Lock()
+	history.failAfter = -1
+	history.befores = nil
+	history.mu.Unlock()
+
+	response, err := s.ResumeBackfill(ctx, userID, uuid.UUID(store.indexer.ID.Bytes))
+	if err != nil {
+		t.Fatalf("ResumeBackfill: %v", err)
+	}
+	if response.Processed != 150 {
+		t.Errorf("resumed run processed %d transactions, want the remaining 150", response.Processed)
+	}
+	if befores := history.requestedBefores(); len(befores) == 0 || befores[0] != "sig-99" {
+		t.Errorf("resumed run requested pages before %q, want it to start after sig-99", befores)
+	}
+	if len(response.Addresses) != 1 || response.Addresses[0].Fetched != 250 || !response.Addresses[0].Completed {
+		t.Errorf("progress = %+v, want 250 fetched and completed", response.Addresses)
+	}
+
+	// A completed backfill has nothing left to fetch
+	history.mu.Lock()
+	history.befores = nil
+	history.mu.Unlock()
+	if response, err = s.ResumeBackfill(ctx, userID, uuid.UUID(store.indexer.ID.Bytes)); err != nil || response.Processed != 0 {
+		t.Errorf("ResumeBackfill of a completed backfill = %+v, %v; want nothing processed", response, err)
+	}
+	if befores := history.requestedBefores(); len(befores) != 0 {
+		t.Errorf("completed backfill requested %d more pages", len(befores))
+	}
+}
+
+func TestResumeBackfillRequiresBackfillAndHelius(t *testing.T) {
+	userID := uuid.New()
+
+	store := newBackfillStore(userID, 0)
+	s := NewIndexerService(store, newAuditClient(t, newHistoryServer(1)))
+	if _, err := s.ResumeBackfill(context.Background(), userID, uuid.UUID(store.indexer.ID.Bytes)); !errors.Is(err, ErrNoBackfill) {
+		t.Errorf("ResumeBackfill without backfillLimit error = %v, want ErrNoBackfill", err)
+	}
+
+	store = newBackfillStore(userID, 10)
+	s = NewIndexerService(store, nil)
+	if _, err := s.ResumeBackfill(context.Background(), userID, uuid.UUID(store.indexer.ID.Bytes)); !errors.Is(err, ErrHeliusNotConfigured) {
+		t.Errorf("ResumeBackfill without Helius error = %v, want ErrHeliusNotConfigured", err)
+	}
+}
+
+func TestRunInitialBackfillLogsOutcome(t *testing.T) {
+	store := newBackfillStore(uuid.New(), 10)
+	history := newHistoryServer(5)
+	s := newBackfillService(t, store, history)
+
+	s.runInitialBackfill(context.Background(), store.indexer, []string{"collection"})
+
+	if len(store.logs) != 1 || store.logs[0].EventType != "backfill" || store.logs[0].Message != "Backfilled 5 past transactions" {
+		t.Errorf("logs = %+v, want one backfill entry for 5 transactions", store.logs)
+	}
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// collectionStatsWindow is the period volume and sale count are summed over
+const collectionStatsWindow = 24 * time.Hour
+
+// collectionStats computes the floor price, volume and sale count of an NFT
+// price indexer from its target table. Rows are one per listing that moves
+// to sold or cancelled, so the floor is taken over rows still listed.
+func (s *IndexerService) collectionStats(ctx context.Context, idx db.Indexer) (*models.CollectionStats, error) {
+	pool, err := s.connectActivityPool(ctx, idx.DbCredentialID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to target database: %w", err)
+	}
+	defer pool.Close()
+
+	targetTable := indexer.QuoteTableName(idx.TargetTable)
+
+	var stats models.CollectionStats
+	err = pool.QueryRow(ctx, fmt.Sprintf(`
+		SELECT
+			(SELECT MIN(price)::float8 FROM %[1]s WHERE LOWER(status) = 'listed' AND price > 0),
+			COALESCE(SUM(price), 0)::float8,
+			COUNT(*)
+		FROM %[1]s
+		WHERE LOWER(status) = 'sold' AND %[2]s >= $1
+	`, targetTable, indexer.TimeColumnExpr(idx.Params)), time.Now().Add(-collectionStatsWindow)).
+		Scan(&stats.FloorPrice, &stats.Volume24h, &stats.SaleCount24h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", targetTable, err)
+	}
+
+	return &stats, nil
+}
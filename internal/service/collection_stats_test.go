@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+)
+
+// seedPriceRow inserts one row into an NFT price table
+func seedPriceRow(t *testing.T, pool *pgxpool.Pool, table, signature, status string, price float64, blockTime time.Time) {
+	t.Helper()
+
+	_, err := pool.Exec(context.Background(), fmt.Sprintf(`
+		INSERT INTO %s (signature, slot, block_time, nft_mint, marketplace, price, seller, status)
+		VALUES ($1, 1, $2, 'mint', 'MAGIC_EDEN', $3, 'seller', $4)
+	`, indexer.QuoteTableName(table)), signature, blockTime, price, status)
+	if err != nil {
+		t.Fatalf("insert %s: %v", signature, err)
+	}
+}
+
+// statsFixture is an NFT price indexer whose table is empty
+func statsFixture(t *testing.T) (*IndexerService, *rawStore, *pgxpool.Pool) {
+	t.Helper()
+
+	cred, pool := testTarget(t)
+	table := testTable(t, pool)
+
+	store := newRawStore(uuid.New())
+	store.cred = cred
+	store.indexer.TargetTable = table
+
+	idx, err := indexer.NewNFTPriceIndexer("test", store.indexer.Params)
+	if err != nil {
+		t.Fatalf("NewNFTPriceIndexer: %v", err)
+	}
+	initializeTable(t, pool, idx, table)
+
+	return NewIndexerService(store, nil), store, pool
+}
+
+func TestGetIndexerStatsCollectionRollups(t *testing.T) {
+	s, store, pool := statsFixture(t)
+	table := store.indexer.TargetTable
+	now := time.Now()
+
+	seedPriceRow(t, pool, table, "listed-high", "listed", 3, now)
+	seedPriceRow(t, pool, table, "listed-floor", "listed", 2.5, now)
+	seedPriceRow(t, pool, table, "listed-free", "listed", 0, now)
+	seedPriceRow(t, pool, table, "cancelled", "cancelled", 1, now)
+	seedPriceRow(t, pool, table, "sold-1", "sold", 2, now.Add(-time.Hour))
+	seedPriceRow(t, pool, table, "sold-2", "SOLD", 4, now.Add(-23*time.Hour))
+	seedPriceRow(t, pool, table, "sold-old", "sold", 10, now.Add(-48*time.Hour))
+
+	stats, err := s.GetIndexerStats(context.Background(), uuid.UUID(store.indexer.UserID.Bytes), uuid.UUID(store.indexer.ID.Bytes))
+	if err != nil {
+		t.Fatalf("GetIndexerStats: %v", err)
+	}
+	collection := stats.Collection
+	if collection == nil {
+		t.Fatal("no collection stats for an NFT price indexer")
+	}
+
+	// Cancelled and zero priced listings don't set the floor
+	if collection.FloorPrice == nil || *collection.FloorPrice != 2.5 {
+		t.Errorf("floor = %v, want 2.5", collection.FloorPrice)
+	}
+	// The sale from two days ago is outside the window
+	if collection.Volume24h != 6 || collection.SaleCount24h != 2 {
+		t.Errorf("got volume %v over %d sales, want 6 over 2", collection.Volume24h, collection.SaleCount24h)
+	}
+}
+
+func TestGetIndexerStatsWithNothingListed(t *testing.T) {
+	s, store, pool := statsFixture(t)
+	seedPriceRow(t, pool, store.indexer.TargetTable, "sold", "sold", 2, time.Now())
+
+	stats, err := s.GetIndexerStats(context.Background(), uuid.UUID(store.indexer.UserID.Bytes), uuid.UUID(store.indexer.ID.Bytes))
+	if err != nil {
+		t.Fatalf("GetIndexerStats: %v", err)
+	}
+	if stats.Collection == nil || stats.Collection.FloorPrice != nil {
+		t.Errorf("collection = %+v, want stats without a floor", stats.Collection)
+	}
+}
+
+func TestGetIndexerStatsOnlyRollsUpNFTPrices(t *testing.T) {
+	store := newRawStore(uuid.New())
+	store.indexer.IndexerType = db.IndexerTypeTokenPrices
+	store.indexer.Params = json.RawMessage(`{"tokens": ["token"]}`)
+	s := NewIndexerService(store, nil)
+
+	stats, err := s.GetIndexerStats(context.Background(), uuid.UUID(store.indexer.UserID.Bytes), uuid.UUID(store.indexer.ID.Bytes))
+	if err != nil {
+		t.Fatalf("GetIndexerStats: %v", err)
+	}
+	if stats.Collection != nil {
+		t.Errorf("collection = %+v for a token price indexer, want none", stats.Collection)
+	}
+}
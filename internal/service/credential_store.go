@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/pkg/crypto"
+)
+
+// encryptingStore seals database credential passwords with AES-GCM on the way
+// into the control database and opens them on the way out, so every DSN built
+// from a credential sees the plaintext while the table only holds ciphertext.
+// Indexer webhook secrets are sealed the same way.
+type encryptingStore struct {
+	db.Querier
+	key []byte
+}
+
+// NewEncryptingStore returns a Querier that encrypts DB credential passwords
+// with key
+func NewEncryptingStore(store db.Querier, key []byte) db.Querier {
+	return &encryptingStore{Querier: store, key: key}
+}
+
+func (s *encryptingStore) CreateDBCredential(ctx context.Context, arg db.CreateDBCredentialParams) (db.DbCredential, error) {
+	sealed, err := crypto.Encrypt(s.key, arg.DbPassword)
+	if err != nil {
+		return db.DbCredential{}, fmt.Errorf("failed to encrypt password: %w", err)
+	}
+	arg.DbPassword = sealed
+
+	cred, err := s.Querier.CreateDBCredential(ctx, arg)
+	if err != nil {
+		return cred, err
+	}
+	return s.open(cred)
+}
+
+func (s *encryptingStore) UpdateDBCredential(ctx context.Context, arg db.UpdateDBCredentialParams) (db.DbCredential, error) {
+	sealed, err := crypto.Encrypt(s.key, arg.DbPassword)
+	if err != nil {
+		return db.DbCredential{}, fmt.Errorf("failed to encrypt password: %w", err)
+	}
+	arg.DbPassword = sealed
+
+	cred, err := s.Querier.UpdateDBCredential(ctx, arg)
+	if err != nil {
+		return cred, err
+	}
+	return s.open(cred)
+}
+
+func (s *encryptingStore) UpdateDBCredentialPassword(ctx context.Context, arg db.UpdateDBCredentialPasswordParams) error {
+	sealed, err := crypto.Encrypt(s.key, arg.DbPassword)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt password: %w", err)
+	}
+	arg.DbPassword = sealed
+
+	return s.Querier.UpdateDBCredentialPassword(ctx, arg)
+}
+
+func (s *encryptingStore) GetDBCredentialByID(ctx context.Context, id pgtype.UUID) (db.DbCredential, error) {
+	cred, err := s.Querier.GetDBCredentialByID(ctx, id)
+	if err != nil {
+		return cred, err
+	}
+	return s.open(cred)
+}
+
+func (s *encryptingStore) GetDBCredentialsByUserID(ctx context.Context, userID pgtype.UUID) ([]db.DbCredential, error) {
+	creds, err := s.Querier.GetDBCredentialsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.openAll(creds)
+}
+
+func (s *encryptingStore) ListDBCredentials(ctx context.Context) ([]db.DbCredential, error) {
+	creds, err := s.Querier.ListDBCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.openAll(creds)
+}
+
+func (s *encryptingStore) UpsertIndexerWebhookSecret(ctx context.Context, arg db.UpsertIndexerWebhookSecretParams) (db.IndexerWebhookSecret, error) {
+	sealed, err := crypto.Encrypt(s.key, arg.Secret)
+	if err != nil {
+		return db.IndexerWebhookSecret{}, fmt.Errorf("failed to encrypt webhook secret: %w", err)
+	}
+	arg.Secret = sealed
+
+	secret, err := s.Querier.UpsertIndexerWebhookSecret(ctx, arg)
+	if err != nil {
+		return secret, err
+	}
+	return s.openSecret(secret)
+}
+
+func (s *encryptingStore) GetIndexerWebhookSecret(ctx context.Context, indexerID pgtype.UUID) (db.IndexerWebhookSecret, error) {
+	secret, err := s.Querier.GetIndexerWebhookSecret(ctx, indexerID)
+	if err != nil {
+		return secret, err
+	}
+	return s.openSecret(secret)
+}
+
+// openSecret decrypts an indexer's webhook secret
+func (s *encryptingStore) openSecret(secret db.IndexerWebhookSecret) (db.IndexerWebhookSecret, error) {
+	if !crypto.IsEncrypted(secret.Secret) {
+		return secret, nil
+	}
+
+	opened, err := crypto.Decrypt(s.key, secret.Secret)
+	if err != nil {
+		return secret, fmt.Errorf("failed to decrypt webhook secret of indexer %s: %w", secret.IndexerID.String(), err)
+	}
+	secret.Secret = opened
+	return secret, nil
+}
+
+func (s *encryptingStore) openAll(creds []db.DbCredential) ([]db.DbCredential, error) {
+	for i := range creds {
+		opened, err := s.open(creds[i])
+		if err != nil {
+			return nil, err
+		}
+		creds[i] = opened
+	}
+	return creds, nil
+}
+
+// open decrypts the password of cred. Rows written before encryption was
+// introduced are passed through until EncryptStoredPasswords rewrites them.
+func (s *encryptingStore) open(cred db.DbCredential) (db.DbCredential, error) {
+	if !crypto.IsEncrypted(cred.DbPassword) {
+		return cred, nil
+	}
+
+	password, err := crypto.Decrypt(s.key, cred.DbPassword)
+	if err != nil {
+		return cred, fmt.Errorf("failed to decrypt password of credential %s: %w", cred.ID.String(), err)
+	}
+	cred.DbPassword = password
+	return cred, nil
+}
+
+// EncryptStoredPasswords encrypts every credential password still stored in
+// plaintext. store must be the unwrapped Querier so the stored values are
+// seen as they are. It is safe to run on every start; already encrypted rows
+// are left alone.
+func EncryptStoredPasswords(ctx context.Context, store db.Querier, key []byte) (int, error) {
+	creds, err := store.ListDBCredentials(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list DB credentials: %w", err)
+	}
+
+	encrypted := 0
+	var errs []error
+	for _, cred := range creds {
+		if crypto.IsEncrypted(cred.DbPassword) {
+			continue
+		}
+
+		sealed, err := crypto.Encrypt(key, cred.DbPassword)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("credential %s: %w", cred.ID.String(), err))
+			continue
+		}
+
+		err = store.UpdateDBCredentialPassword(ctx, db.UpdateDBCredentialPasswordParams{
+			ID:         cred.ID,
+			DbPassword: sealed,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("credential %s: %w", cred.ID.String(), err))
+			continue
+		}
+		encrypted++
+	}
+
+	if encrypted > 0 {
+		log.Info().Int("count", encrypted).Msg("Encrypted plaintext DB credential passwords")
+	}
+
+	return encrypted, errors.Join(errs...)
+}
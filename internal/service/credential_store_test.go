@@ -0,0 +1,172 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/pkg/crypto"
+)
+
+// credStore keeps DB credentials as they would sit in the control database
+type credStore struct {
+	db.Querier
+	creds map[pgtype.UUID]db.DbCredential
+}
+
+func newCredStore() *credStore {
+	return &credStore{creds: make(map[pgtype.UUID]db.DbCredential)}
+}
+
+func (s *credStore) CreateDBCredential(ctx context.Context, arg db.CreateDBCredentialParams) (db.DbCredential, error) {
+	cred := db.DbCredential{
+		ID:         pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		UserID:     arg.UserID,
+		DbHost:     arg.DbHost,
+		DbPort:     arg.DbPort,
+		DbName:     arg.DbName,
+		DbUser:     arg.DbUser,
+		DbPassword: arg.DbPassword,
+		DbSslMode:  arg.DbSslMode,
+	}
+	s.creds[cred.ID] = cred
+	return cred, nil
+}
+
+func (s *credStore) GetDBCredentialByID(ctx context.Context, id pgtype.UUID) (db.DbCredential, error) {
+	return s.creds[id], nil
+}
+
+func (s *credStore) ListDBCredentials(ctx context.Context) ([]db.DbCredential, error) {
+	var creds []db.DbCredential
+	for _, cred := range s.creds {
+		creds = append(creds, cred)
+	}
+	return creds, nil
+}
+
+func (s *credStore) UpdateDBCredentialPassword(ctx context.Context, arg db.UpdateDBCredentialPasswordParams) error {
+	cred := s.creds[arg.ID]
+	cred.DbPassword = arg.DbPassword
+	s.creds[arg.ID] = cred
+	return nil
+}
+
+func (s *credStore) CreateAuditEvent(ctx context.Context, arg db.CreateAuditEventParams) error {
+	return nil
+}
+
+func testCredentialKey() []byte {
+	return bytes.Repeat([]byte{1}, crypto.SecretKeySize)
+}
+
+func TestEncryptingStoreSealsPasswords(t *testing.T) {
+	inner := newCredStore()
+	store := NewEncryptingStore(inner, testCredentialKey())
+	ctx := context.Background()
+
+	created, err := store.CreateDBCredential(ctx, db.CreateDBCredentialParams{DbPassword: "s3cret"})
+	if err != nil {
+		t.Fatalf("CreateDBCredential: %v", err)
+	}
+	if created.DbPassword != "s3cret" {
+		t.Errorf("created password = %q, want the plaintext handed back", created.DbPassword)
+	}
+	if stored := inner.creds[created.ID].DbPassword; !crypto.IsEncrypted(stored) {
+		t.Errorf("stored password = %q, want it encrypted", stored)
+	}
+
+	cred, err := store.GetDBCredentialByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetDBCredentialByID: %v", err)
+	}
+	if cred.DbPassword != "s3cret" {
+		t.Errorf("read password = %q, want the plaintext", cred.DbPassword)
+	}
+
+	// Rows from before encryption was turned on still read back
+	legacy, _ := inner.CreateDBCredential(ctx, db.CreateDBCredentialParams{DbPassword: "plain"})
+	if cred, err := store.GetDBCredentialByID(ctx, legacy.ID); err != nil || cred.DbPassword != "plain" {
+		t.Errorf("legacy credential = %q, %v; want the plaintext passed through", cred.DbPassword, err)
+	}
+}
+
+func TestEncryptingStoreRejectsWrongKey(t *testing.T) {
+	inner := newCredStore()
+	ctx := context.Background()
+
+	created, err := NewEncryptingStore(inner, testCredentialKey()).CreateDBCredential(ctx, db.CreateDBCredentialParams{DbPassword: "s3cret"})
+	if err != nil {
+		t.Fatalf("CreateDBCredential: %v", err)
+	}
+
+	otherKey := bytes.Repeat([]byte{2}, crypto.SecretKeySize)
+	if _, err := NewEncryptingStore(inner, otherKey).GetDBCredentialByID(ctx, created.ID); err == nil {
+		t.Error("GetDBCredentialByID opened a password sealed under another key")
+	}
+}
+
+func TestEncryptStoredPasswords(t *testing.T) {
+	inner := newCredStore()
+	key := testCredentialKey()
+	ctx := context.Background()
+
+	plain, _ := inner.CreateDBCredential(ctx, db.CreateDBCredentialParams{DbPassword: "plain"})
+	sealed, _ := NewEncryptingStore(inner, key).CreateDBCredential(ctx, db.CreateDBCredentialParams{DbPassword: "sealed"})
+	sealedBefore := inner.creds[sealed.ID].DbPassword
+
+	for run, want := range []int{1, 0} {
+		encrypted, err := EncryptStoredPasswords(ctx, inner, key)
+		if err != nil {
+			t.Fatalf("EncryptStoredPasswords run %d: %v", run+1, err)
+		}
+		if encrypted != want {
+			t.Errorf("run %d encrypted %d passwords, want %d", run+1, encrypted, want)
+		}
+	}
+
+	if inner.creds[sealed.ID].DbPassword != sealedBefore {
+		t.Error("an already encrypted password was rewritten")
+	}
+	opened, err := crypto.Decrypt(key, inner.creds[plain.ID].DbPassword)
+	if err != nil || opened != "plain" {
+		t.Errorf("migrated password = %q, %v; want it encrypted from the plaintext", opened, err)
+	}
+}
+
+func TestInitializeIndexerConnectsWithEncryptedPassword(t *testing.T) {
+	cred, pool := testTarget(t)
+	table := testTable(t, pool)
+	key := testCredentialKey()
+
+	sealed, err := crypto.Encrypt(key, cred.DbPassword)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	cred.DbPassword = sealed
+
+	store := newRawStore(uuid.New())
+	store.cred = cred
+	store.indexer.TargetTable = table
+	s := NewIndexerService(NewEncryptingStore(store, key), nil)
+
+	// The DSN is built from the credential as the store returns it, so the
+	// connection only succeeds if the password was decrypted first
+	ctx := context.Background()
+	if err := s.initializeIndexer(ctx, store.indexer); err != nil {
+		t.Fatalf("initializeIndexer: %v", err)
+	}
+
+	var exists bool
+	if err := pool.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", indexer.QuoteTableName(table)).Scan(&exists); err != nil {
+		t.Fatalf("check table: %v", err)
+	}
+	if !exists {
+		t.Errorf("table %s was not created", table)
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+)
+
+// testTarget connects to the database in TEST_DATABASE_URL and returns a
+// credential pointing at it, skipping the test when it isn't set
+func testTarget(tb testing.TB) (db.DbCredential, *pgxpool.Pool) {
+	tb.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		tb.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		tb.Fatalf("parse TEST_DATABASE_URL: %v", err)
+	}
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		tb.Fatalf("connect to test database: %v", err)
+	}
+	tb.Cleanup(pool.Close)
+
+	sslMode := "disable"
+	if config.ConnConfig.TLSConfig != nil {
+		sslMode = "require"
+	}
+	return db.DbCredential{
+		DbHost:     config.ConnConfig.Host,
+		DbPort:     int32(config.ConnConfig.Port),
+		DbName:     config.ConnConfig.Database,
+		DbUser:     config.ConnConfig.User,
+		DbPassword: config.ConnConfig.Password,
+		DbSslMode:  sslMode,
+	}, pool
+}
+
+// testTable returns a target table name unique to the test, dropped along
+// with its companion tables when the test ends
+func testTable(tb testing.TB, pool *pgxpool.Pool) string {
+	tb.Helper()
+
+	name := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	tb.Cleanup(func() {
+		ctx := context.Background()
+		rows, err := pool.Query(ctx, "SELECT tablename FROM pg_tables WHERE schemaname = 'public' AND tablename LIKE $1", name+"%")
+		if err != nil {
+			return
+		}
+		var tables []string
+		for rows.Next() {
+			var table string
+			if rows.Scan(&table) == nil {
+				tables = append(tables, table)
+			}
+		}
+		rows.Close()
+		for _, table := range tables {
+			pool.Exec(ctx, "DROP TABLE IF EXISTS "+indexer.QuoteTableName(table)+" CASCADE")
+		}
+	})
+	return name
+}
+
+// initializeTable creates the target table of idx
+func initializeTable(tb testing.TB, pool *pgxpool.Pool, idx indexer.Indexer, table string) {
+	tb.Helper()
+
+	conn, err := pool.Acquire(context.Background())
+	if err != nil {
+		tb.Fatalf("acquire connection: %v", err)
+	}
+	defer conn.Release()
+
+	if err := idx.Initialize(context.Background(), conn.Conn(), table); err != nil {
+		tb.Fatalf("initialize %s: %v", table, err)
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// signatureDeduper remembers the transaction signatures each indexer has
+// processed within a time window, so a webhook Helius redelivers is skipped
+// before any parsing, metadata lookups or logging happen again
+type signatureDeduper struct {
+	mu        sync.Mutex
+	window    time.Duration
+	seen      map[string]time.Time
+	lastSweep time.Time
+}
+
+func newSignatureDeduper(window time.Duration) *signatureDeduper {
+	return &signatureDeduper{
+		window: window,
+		seen:   make(map[string]time.Time),
+	}
+}
+
+// setWindow changes how long a signature is remembered; zero disables
+// deduplication and forgets everything seen so far
+func (d *signatureDeduper) setWindow(window time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.window = window
+	if window <= 0 {
+		d.seen = make(map[string]time.Time)
+	}
+}
+
+// length returns how long a signature is remembered, zero when
+// deduplication is off
+func (d *signatureDeduper) length() time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.window
+}
+
+// claim reports whether signature is new for the indexer and, if so, records
+// it. An empty signature is always new.
+func (d *signatureDeduper) claim(indexerID uuid.UUID, signature string) bool {
+	if signature == "" {
+		return true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.window <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	d.sweep(now)
+
+	key := dedupKey(indexerID, signature)
+	if seenAt, ok := d.seen[key]; ok && now.Sub(seenAt) < d.window {
+		return false
+	}
+	d.seen[key] = now
+	return true
+}
+
+// release forgets a claimed signature, so a delivery that failed to process
+// is not skipped when Helius retries it
+func (d *signatureDeduper) release(indexerID uuid.UUID, signature string) {
+	if signature == "" {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.seen, dedupKey(indexerID, signature))
+}
+
+// forget drops every signature remembered for the indexer, so transactions
+// replayed after its data was reset are processed again
+func (d *signatureDeduper) forget(indexerID uuid.UUID) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	prefix := indexerID.String() + ":"
+	for key := range d.seen {
+		if strings.HasPrefix(key, prefix) {
+			delete(d.seen, key)
+		}
+	}
+}
+
+// sweep drops expired signatures at most once per window. Callers hold d.mu.
+func (d *signatureDeduper) sweep(now time.Time) {
+	if now.Sub(d.lastSweep) < d.window {
+		return
+	}
+	for key, seenAt := range d.seen {
+		if now.Sub(seenAt) >= d.window {
+			delete(d.seen, key)
+		}
+	}
+	d.lastSweep = now
+}
+
+func dedupKey(indexerID uuid.UUID, signature string) string {
+	return indexerID.String() + ":" + signature
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+func TestSignatureDeduperSkipsRepeats(t *testing.T) {
+	d := newSignatureDeduper(time.Minute)
+	indexerID, otherID := uuid.New(), uuid.New()
+
+	if !d.claim(indexerID, "sig") {
+		t.Fatal("first delivery claimed as a repeat")
+	}
+	if d.claim(indexerID, "sig") {
+		t.Error("redelivered signature not skipped")
+	}
+	if !d.claim(otherID, "sig") {
+		t.Error("signature skipped for an indexer that never processed it")
+	}
+	if !d.claim(indexerID, "") || !d.claim(indexerID, "") {
+		t.Error("payload without a signature skipped")
+	}
+
+	d.release(indexerID, "sig")
+	if !d.claim(indexerID, "sig") {
+		t.Error("released signature still skipped")
+	}
+
+	d.forget(indexerID)
+	if !d.claim(indexerID, "sig") {
+		t.Error("signature still skipped after the indexer was forgotten")
+	}
+	if d.claim(otherID, "sig") {
+		t.Error("forgetting one indexer dropped another's signatures")
+	}
+}
+
+func TestSignatureDeduperWindow(t *testing.T) {
+	d := newSignatureDeduper(20 * time.Millisecond)
+	indexerID := uuid.New()
+
+	d.claim(indexerID, "sig")
+	time.Sleep(30 * time.Millisecond)
+	if !d.claim(indexerID, "sig") {
+		t.Error("signature skipped after its window passed")
+	}
+
+	d.setWindow(0)
+	if !d.claim(indexerID, "sig") || !d.claim(indexerID, "sig") {
+		t.Error("signature skipped with deduplication disabled")
+	}
+}
+
+func TestProcessWebhookPayloadSkipsRedelivery(t *testing.T) {
+	cred, pool := testTarget(t)
+	table := testTable(t, pool)
+
+	store := newRawStore(uuid.New())
+	store.cred = cred
+	store.indexer.TargetTable = table
+	s := NewIndexerService(store, nil)
+	s.SetDedupWindow(time.Minute)
+	ctx := context.Background()
+
+	if err := s.initializeIndexer(ctx, store.indexer); err != nil {
+		t.Fatalf("initializeIndexer: %v", err)
+	}
+
+	var payload models.HeliusWebhookPayload
+	if err := json.Unmarshal(salePayload(t, "redelivered-sig", 10).Body, &payload); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+
+	store.logs = nil
+	if err := s.ProcessWebhookPayload(ctx, "webhook", payload); err != nil {
+		t.Fatalf("ProcessWebhookPayload: %v", err)
+	}
+
+	// With the row gone, a second insert would bring it back
+	if _, err := pool.Exec(ctx, "DELETE FROM "+indexer.QuoteTableName(table)); err != nil {
+		t.Fatalf("clear table: %v", err)
+	}
+	if err := s.ProcessWebhookPayload(ctx, "webhook", payload); err != nil {
+		t.Fatalf("ProcessWebhookPayload of the redelivery: %v", err)
+	}
+
+	var count int
+	if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+indexer.QuoteTableName(table)).Scan(&count); err != nil {
+		t.Fatalf("count rows: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("redelivery wrote %d rows, want the insert skipped", count)
+	}
+
+	var successes int
+	for _, entry := range store.logs {
+		if entry.EventType == "success" {
+			successes++
+		}
+	}
+	if successes != 1 {
+		t.Errorf("got %d success logs, want 1", successes)
+	}
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// DefaultEventBufferSize is how many events a subscriber may fall behind
+// before the oldest are dropped
+const DefaultEventBufferSize = 64
+
+// EventBroker fans processed events out to the subscribers of each indexer.
+// Publishing never blocks: a subscriber that does not keep up loses its
+// oldest buffered events instead of holding up webhook processing.
+type EventBroker struct {
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[*EventSubscription]struct{}
+}
+
+// EventSubscription receives the events of one indexer
+type EventSubscription struct {
+	broker    *EventBroker
+	indexerID uuid.UUID
+	events    chan models.IndexedEvent
+	dropped   int
+}
+
+// NewEventBroker creates a broker without subscribers
+func NewEventBroker() *EventBroker {
+	return &EventBroker{
+		subscribers: make(map[uuid.UUID]map[*EventSubscription]struct{}),
+	}
+}
+
+// Subscribe registers for the events of an indexer, buffering up to
+// bufferSize of them. Callers must Close the subscription when done.
+func (b *EventBroker) Subscribe(indexerID uuid.UUID, bufferSize int) *EventSubscription {
+	if bufferSize <= 0 {
+		bufferSize = DefaultEventBufferSize
+	}
+
+	sub := &EventSubscription{
+		broker:    b,
+		indexerID: indexerID,
+		events:    make(chan models.IndexedEvent, bufferSize),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subscribers[indexerID] == nil {
+		b.subscribers[indexerID] = make(map[*EventSubscription]struct{})
+	}
+	b.subscribers[indexerID][sub] = struct{}{}
+
+	return sub
+}
+
+// HasSubscribers reports whether anyone listens to the indexer, so callers
+// can skip building events nobody receives
+func (b *EventBroker) HasSubscribers(indexerID uuid.UUID) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers[indexerID]) > 0
+}
+
+// Publish delivers an event to every subscriber of its indexer
+func (b *EventBroker) Publish(event models.IndexedEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers[event.IndexerID] {
+		sub.push(event)
+	}
+}
+
+// push enqueues an event, dropping the oldest buffered one when full. It runs
+// under the broker lock, so pushes never race each other.
+func (s *EventSubscription) push(event models.IndexedEvent) {
+	for {
+		select {
+		case s.events <- event:
+			return
+		default:
+		}
+
+		select {
+		case <-s.events:
+			s.dropped++
+		default:
+		}
+	}
+}
+
+// Events returns the channel events arrive on. It is closed by Close.
+func (s *EventSubscription) Events() <-chan models.IndexedEvent {
+	return s.events
+}
+
+// Dropped returns how many events were discarded because the subscriber fell behind
+func (s *EventSubscription) Dropped() int {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	return s.dropped
+}
+
+// Close unregisters the subscription and closes its channel
+func (s *EventSubscription) Close() {
+	b := s.broker
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subs, ok := b.subscribers[s.indexerID]
+	if !ok {
+		return
+	}
+	if _, ok := subs[s]; !ok {
+		return
+	}
+
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(b.subscribers, s.indexerID)
+	}
+	close(s.events)
+}
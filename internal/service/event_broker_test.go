@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+func TestEventBrokerDeliversToIndexerSubscribers(t *testing.T) {
+	broker := NewEventBroker()
+	indexerID, otherID := uuid.New(), uuid.New()
+
+	sub := broker.Subscribe(indexerID, 4)
+	other := broker.Subscribe(otherID, 4)
+	defer other.Close()
+
+	broker.Publish(models.IndexedEvent{IndexerID: indexerID, EventType: "success"})
+
+	select {
+	case event := <-sub.Events():
+		if event.EventType != "success" {
+			t.Errorf("event type = %s, want success", event.EventType)
+		}
+	default:
+		t.Fatal("subscriber received no event")
+	}
+	select {
+	case event := <-other.Events():
+		t.Errorf("subscriber of another indexer received %+v", event)
+	default:
+	}
+
+	sub.Close()
+	sub.Close()
+	if broker.HasSubscribers(indexerID) {
+		t.Error("indexer still has subscribers after Close")
+	}
+	if _, open := <-sub.Events(); open {
+		t.Error("events channel still open after Close")
+	}
+}
+
+func TestEventBrokerDropsOldestForSlowSubscriber(t *testing.T) {
+	broker := NewEventBroker()
+	indexerID := uuid.New()
+	sub := broker.Subscribe(indexerID, 2)
+	defer sub.Close()
+
+	for _, message := range []string{"first", "second", "third"} {
+		broker.Publish(models.IndexedEvent{IndexerID: indexerID, Message: message})
+	}
+
+	if dropped := sub.Dropped(); dropped != 1 {
+		t.Errorf("dropped %d events, want 1", dropped)
+	}
+	for _, want := range []string{"second", "third"} {
+		if event := <-sub.Events(); event.Message != want {
+			t.Errorf("got event %q, want %q", event.Message, want)
+		}
+	}
+}
+
+func TestProcessWebhookPayloadPublishesEvent(t *testing.T) {
+	cred, pool := testTarget(t)
+	table := testTable(t, pool)
+
+	userID := uuid.New()
+	store := newRawStore(userID)
+	store.cred = cred
+	store.indexer.TargetTable = table
+	s := NewIndexerService(store, nil)
+	ctx := context.Background()
+
+	if err := s.initializeIndexer(ctx, store.indexer); err != nil {
+		t.Fatalf("initializeIndexer: %v", err)
+	}
+
+	sub, err := s.SubscribeEvents(ctx, userID, uuid.UUID(store.indexer.ID.Bytes))
+	if err != nil {
+		t.Fatalf("SubscribeEvents: %v", err)
+	}
+	defer sub.Close()
+
+	var payload models.HeliusWebhookPayload
+	if err := json.Unmarshal(salePayload(t, "streamed-sig", 10).Body, &payload); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	if err := s.ProcessWebhookPayload(ctx, "webhook", payload); err != nil {
+		t.Fatalf("ProcessWebhookPayload: %v", err)
+	}
+
+	select {
+	case event := <-sub.Events():
+		if event.EventType != "success" || event.IndexerID != uuid.UUID(store.indexer.ID.Bytes) {
+			t.Errorf("event = %+v, want a success event for the indexer", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscriber received no event for the processed payload")
+	}
+}
+
+func TestSubscribeEventsChecksOwnership(t *testing.T) {
+	store := newRawStore(uuid.New())
+	s := NewIndexerService(store, nil)
+
+	if _, err := s.SubscribeEvents(context.Background(), uuid.New(), uuid.UUID(store.indexer.ID.Bytes)); err == nil {
+		t.Error("SubscribeEvents to another user's indexer succeeded")
+	}
+}
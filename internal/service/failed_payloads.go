@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/pkg/logger"
+)
+
+var (
+	// ErrFailedPayloadNotFound is returned for a failure ID the indexer does not have
+	ErrFailedPayloadNotFound = errors.New("failed payload not found")
+	// ErrFailedPayloadRetry is returned when a replayed payload fails again;
+	// it stays in failed_payloads with the new error
+	ErrFailedPayloadRetry = errors.New("failed payload could not be processed")
+)
+
+// recordFailedPayload keeps a payload that ran out of attempts in
+// failed_payloads, and tells the indexer's owner through its logs and event
+// stream
+func (s *IndexerService) recordFailedPayload(ctx context.Context, webhookID string, payload models.HeliusWebhookPayload, attempts int, cause error) {
+	foundIndexer, err := s.store.GetIndexerByWebhookID(ctx, pgtype.Text{String: webhookID, Valid: true})
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("webhookID", webhookID).Msg("Failed to find indexer for failed payload, dropping it")
+		return
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("webhookID", webhookID).Msg("Failed to marshal failed payload")
+		return
+	}
+
+	var signature string
+	if len(payload.Transaction.Signatures) > 0 {
+		signature = payload.Transaction.Signatures[0]
+	}
+
+	failure, err := s.store.CreateFailedPayload(ctx, db.CreateFailedPayloadParams{
+		IndexerID: foundIndexer.ID,
+		WebhookID: webhookID,
+		RequestID: logger.RequestID(ctx),
+		Slot:      payload.Slot,
+		Signature: signature,
+		Body:      body,
+		Error:     logger.Redact(cause.Error()),
+		Attempts:  int32(attempts),
+	})
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).
+			Str("indexerID", foundIndexer.ID.String()).
+			Int64("slot", payload.Slot).
+			Msg("Failed to store failed payload")
+		return
+	}
+
+	log.Error().Ctx(ctx).
+		Err(cause).
+		Str("indexerID", foundIndexer.ID.String()).
+		Int64("failureID", failure.ID).
+		Int64("slot", payload.Slot).
+		Int("attempts", attempts).
+		Msg("Payload failed on every attempt, stored for replay")
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"failureId": failure.ID,
+		"slot":      payload.Slot,
+		"signature": signature,
+		"attempts":  attempts,
+		"error":     failure.Error,
+	})
+	message := fmt.Sprintf("Payload failed %d times and was stored for replay", attempts)
+
+	_, logErr := s.store.CreateIndexingLog(ctx, db.CreateIndexingLogParams{
+		IndexerID: foundIndexer.ID,
+		EventType: "payload_failed",
+		Message:   message,
+		Details:   details,
+	})
+	if logErr != nil {
+		log.Error().Ctx(ctx).Err(logErr).Msg("Failed to create failed payload log entry")
+	}
+
+	s.publishEvent(foundIndexer.ID, "payload_failed", message, details)
+}
+
+// GetFailedPayloads lists the payloads an indexer gave up on, newest first
+func (s *IndexerService) GetFailedPayloads(ctx context.Context, userID uuid.UUID, indexerID uuid.UUID, limit int32, offset int32) ([]models.FailedPayloadResponse, error) {
+	var pgIndexerID pgtype.UUID
+	if err := pgIndexerID.Scan(indexerID.String()); err != nil {
+		return nil, fmt.Errorf("invalid indexer ID: %w", err)
+	}
+
+	foundIndexer, err := s.store.GetIndexerByID(ctx, pgIndexerID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get indexer")
+		return nil, errors.New("indexer not found")
+	}
+
+	userIDFromDB, err := uuid.Parse(foundIndexer.UserID.String())
+	if err != nil || userIDFromDB != userID {
+		return nil, errors.New("indexer not found")
+	}
+
+	if limit > s.logsMaxLimit {
+		limit = s.logsMaxLimit
+	}
+
+	failures, err := s.store.GetFailedPayloadsByIndexerID(ctx, db.GetFailedPayloadsByIndexerIDParams{
+		IndexerID: pgIndexerID,
+		Limit:     limit,
+		Offset:    offset,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get failed payloads")
+		return nil, errors.New("failed to retrieve failed payloads")
+	}
+
+	response := make([]models.FailedPayloadResponse, len(failures))
+	for i, failure := range failures {
+		response[i] = failedPayloadResponse(indexerID, failure)
+	}
+	return response, nil
+}
+
+// RetryFailedPayload processes a failed payload again. It is removed from
+// failed_payloads once it succeeds; otherwise it stays with the new error and
+// ErrFailedPayloadRetry is returned.
+func (s *IndexerService) RetryFailedPayload(ctx context.Context, userID uuid.UUID, indexerID uuid.UUID, failureID int64) error {
+
+	if s.maintenance.Enabled() {
+		return ErrMaintenance
+	}
+
+	var pgIndexerID pgtype.UUID
+	if err := pgIndexerID.Scan(indexerID.String()); err != nil {
+		return fmt.Errorf("invalid indexer ID: %w", err)
+	}
+
+	foundIndexer, err := s.store.GetIndexerByID(ctx, pgIndexerID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get indexer")
+		return errors.New("indexer not found")
+	}
+
+	userIDFromDB, err := uuid.Parse(foundIndexer.UserID.String())
+	if err != nil || userIDFromDB != userID {
+		return errors.New("indexer not found")
+	}
+
+	failure, err := s.store.GetFailedPayloadByID(ctx, db.GetFailedPayloadByIDParams{
+		ID:        failureID,
+		IndexerID: pgIndexerID,
+	})
+	if err != nil {
+		return ErrFailedPayloadNotFound
+	}
+
+	var payload models.HeliusWebhookPayload
+	if err := json.Unmarshal(failure.Body, &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal failed payload: %w", err)
+	}
+
+	// The indexer's current webhook ID routes the payload back to it even if
+	// the webhook was recreated since the payload failed
+	webhookID := failure.WebhookID
+	if foundIndexer.WebhookID.Valid {
+		webhookID = foundIndexer.WebhookID.String
+	}
+
+	if processErr := s.ProcessWebhookPayload(ctx, webhookID, payload); processErr != nil {
+		if err := s.store.UpdateFailedPayloadError(ctx, db.UpdateFailedPayloadErrorParams{
+			ID:    failure.ID,
+			Error: logger.Redact(processErr.Error()),
+		}); err != nil {
+			log.Error().Err(err).Int64("failureID", failure.ID).Msg("Failed to update failed payload")
+		}
+		return fmt.Errorf("%w: %v", ErrFailedPayloadRetry, logger.RedactError(processErr))
+	}
+
+	if err := s.store.DeleteFailedPayload(ctx, failure.ID); err != nil {
+		log.Error().Err(err).Int64("failureID", failure.ID).Msg("Failed to delete retried payload")
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"failureId": failure.ID,
+		"slot":      failure.Slot,
+		"signature": failure.Signature,
+	})
+
+	_, logErr := s.store.CreateIndexingLog(ctx, db.CreateIndexingLogParams{
+		IndexerID: foundIndexer.ID,
+		EventType: "payload_retried",
+		Message:   "Failed payload retried successfully",
+		Details:   details,
+	})
+	if logErr != nil {
+		log.Error().Err(logErr).Msg("Failed to create payload retry log entry")
+	}
+
+	log.Info().
+		Str("indexerID", indexerID.String()).
+		Int64("failureID", failure.ID).
+		Msg("Failed payload retried")
+
+	return nil
+}
+
+func failedPayloadResponse(indexerID uuid.UUID, failure db.FailedPayload) models.FailedPayloadResponse {
+	return models.FailedPayloadResponse{
+		ID:        failure.ID,
+		IndexerID: indexerID,
+		WebhookID: failure.WebhookID,
+		RequestID: failure.RequestID,
+		Slot:      failure.Slot,
+		Signature: failure.Signature,
+		Error:     failure.Error,
+		Attempts:  failure.Attempts,
+		Payload:   failure.Body,
+		CreatedAt: failure.CreatedAt.Time,
+		UpdatedAt: failure.UpdatedAt.Time,
+	}
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/rishavmehra/indexer/internal/config"
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// failureStore keeps failed_payloads in memory
+type failureStore struct {
+	*flakyStore
+
+	mu       sync.Mutex
+	failures map[int64]db.FailedPayload
+	nextID   int64
+	deleted  []int64
+}
+
+func newFailureStore(failLookups int32) *failureStore {
+	return &failureStore{
+		flakyStore: &flakyStore{rawStore: newRawStore(uuid.New()), failLookups: failLookups},
+		failures:   make(map[int64]db.FailedPayload),
+	}
+}
+
+func (s *failureStore) CreateFailedPayload(ctx context.Context, arg db.CreateFailedPayloadParams) (db.FailedPayload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	failure := db.FailedPayload{
+		ID:        s.nextID,
+		IndexerID: arg.IndexerID,
+		WebhookID: arg.WebhookID,
+		RequestID: arg.RequestID,
+		Slot:      arg.Slot,
+		Signature: arg.Signature,
+		Body:      arg.Body,
+		Error:     arg.Error,
+		Attempts:  arg.Attempts,
+	}
+	s.failures[failure.ID] = failure
+	return failure, nil
+}
+
+func (s *failureStore) GetFailedPayloadByID(ctx context.Context, arg db.GetFailedPayloadByIDParams) (db.FailedPayload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failure, ok := s.failures[arg.ID]
+	if !ok || failure.IndexerID != arg.IndexerID {
+		return db.FailedPayload{}, pgx.ErrNoRows
+	}
+	return failure, nil
+}
+
+func (s *failureStore) UpdateFailedPayloadError(ctx context.Context, arg db.UpdateFailedPayloadErrorParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failure := s.failures[arg.ID]
+	failure.Error = arg.Error
+	s.failures[arg.ID] = failure
+	return nil
+}
+
+func (s *failureStore) DeleteFailedPayload(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.failures, id)
+	s.deleted = append(s.deleted, id)
+	return nil
+}
+
+func (s *failureStore) failureCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.failures)
+}
+
+func TestPayloadPastMaxAttemptsIsStoredAndCanBeRetried(t *testing.T) {
+	for _, queue := range []string{"", "memory"} {
+		name := queue
+		if name == "" {
+			name = "direct"
+		}
+		t.Run(name, func(t *testing.T) {
+			store := newFailureStore(2)
+			s := NewIndexerService(store, nil)
+			d := NewWebhookDispatcher(s, config.WebhookConfig{
+				MaxConcurrency:    1,
+				MaxAttempts:       2,
+				Queue:             queue,
+				QueuePollInterval: 10 * time.Millisecond,
+			})
+
+			if _, err := d.Dispatch(context.Background(), "webhook", []models.HeliusWebhookPayload{signedPayload("sig-1", 42)}); err != nil {
+				t.Fatalf("Dispatch: %v", err)
+			}
+			waitFor(t, "the failed payload", func() bool { return store.failureCount() == 1 })
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := d.Drain(ctx); err != nil {
+				t.Fatalf("Drain: %v", err)
+			}
+
+			failure := store.failures[1]
+			if failure.IndexerID != store.indexer.ID || failure.Signature != "sig-1" || failure.Slot != 42 || failure.Attempts != 2 {
+				t.Errorf("failed payload = %+v, want sig-1 after 2 attempts", failure)
+			}
+			if !strings.Contains(failure.Error, "collection address is required") {
+				t.Errorf("error = %q, want the processing error", failure.Error)
+			}
+			var body models.HeliusWebhookPayload
+			if err := json.Unmarshal(failure.Body, &body); err != nil || body.Slot != 42 {
+				t.Errorf("body = %s, want the raw payload", failure.Body)
+			}
+			if len(store.logs) == 0 || store.logs[len(store.logs)-1].EventType != "payload_failed" {
+				t.Errorf("logs = %+v, want the owner told about the failure", store.logs)
+			}
+			if stats := d.Stats(); stats.Retried != 1 || stats.GivenUp != 1 {
+				t.Errorf("retried %d and gave up on %d payloads, want 1 and 1", stats.Retried, stats.GivenUp)
+			}
+
+			// Once the indexer is healthy again the payload replays and
+			// leaves the table
+			s.SetDedupWindow(time.Hour)
+			s.dedup.claim(uuid.UUID(store.indexer.ID.Bytes), "sig-1")
+			if err := s.RetryFailedPayload(ctx, uuid.UUID(store.indexer.UserID.Bytes), uuid.UUID(store.indexer.ID.Bytes), failure.ID); err != nil {
+				t.Fatalf("RetryFailedPayload: %v", err)
+			}
+			if store.failureCount() != 0 || len(store.deleted) != 1 {
+				t.Errorf("failed payload not removed after a successful retry")
+			}
+			if store.logs[len(store.logs)-1].EventType != "payload_retried" {
+				t.Errorf("last log = %+v, want the retry recorded", store.logs[len(store.logs)-1])
+			}
+		})
+	}
+}
+
+func TestRetryFailedPayloadKeepsPayloadThatFailsAgain(t *testing.T) {
+	store := newFailureStore(1)
+	s := NewIndexerService(store, nil)
+	ctx := context.Background()
+
+	body, _ := json.Marshal(signedPayload("sig-1", 42))
+	failure, _ := store.CreateFailedPayload(ctx, db.CreateFailedPayloadParams{
+		IndexerID: store.indexer.ID,
+		WebhookID: "old-webhook",
+		Signature: "sig-1",
+		Body:      body,
+		Error:     "target down",
+		Attempts:  3,
+	})
+	userID, indexerID := uuid.UUID(store.indexer.UserID.Bytes), uuid.UUID(store.indexer.ID.Bytes)
+
+	err := s.RetryFailedPayload(ctx, userID, indexerID, failure.ID)
+	if !errors.Is(err, ErrFailedPayloadRetry) {
+		t.Fatalf("RetryFailedPayload error = %v, want ErrFailedPayloadRetry", err)
+	}
+	if kept := store.failures[failure.ID]; !strings.Contains(kept.Error, "collection address is required") {
+		t.Errorf("kept error = %q, want the new failure", kept.Error)
+	}
+	if len(store.deleted) != 0 {
+		t.Error("payload removed although its retry failed")
+	}
+
+	if err := s.RetryFailedPayload(ctx, userID, indexerID, failure.ID+1); !errors.Is(err, ErrFailedPayloadNotFound) {
+		t.Errorf("RetryFailedPayload of an unknown failure = %v, want ErrFailedPayloadNotFound", err)
+	}
+	if err := s.RetryFailedPayload(ctx, uuid.New(), indexerID, failure.ID); err == nil {
+		t.Error("RetryFailedPayload of another user's indexer succeeded")
+	}
+}
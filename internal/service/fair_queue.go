@@ -0,0 +1,155 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+type queuedPayload struct {
+	requestID string
+	webhookID string
+	payload   models.HeliusWebhookPayload
+}
+
+// fairQueue holds pending payloads in one FIFO per key and hands them out
+// round-robin across keys, so a key with a deep backlog gets one turn like
+// every other key instead of the whole worker pool
+type fairQueue struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	capacity int
+	queues   map[string][]queuedPayload
+	// ring lists the keys with pending payloads in the order they are served
+	ring   []string
+	closed bool
+}
+
+func newFairQueue(capacity int) *fairQueue {
+	q := &fairQueue{
+		capacity: capacity,
+		queues:   make(map[string][]queuedPayload),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push appends items to the queue of key if they all fit and returns how
+// many were accepted: all of them or none. A batch larger than the capacity
+// is accepted into an empty queue so it is not rejected forever. Nothing is
+// accepted once the queue is closed.
+func (q *fairQueue) push(key string, items []queuedPayload) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed || len(items) == 0 {
+		return 0
+	}
+
+	pending := q.queues[key]
+	if len(pending) > 0 && len(pending)+len(items) > q.capacity {
+		return 0
+	}
+	accepted := len(items)
+
+	if len(pending) == 0 {
+		q.ring = append(q.ring, key)
+	}
+	q.queues[key] = append(pending, items...)
+
+	for i := 0; i < accepted; i++ {
+		q.cond.Signal()
+	}
+	return accepted
+}
+
+// pop blocks until a payload is pending and returns the head of the next
+// key's queue. After close it keeps returning what is left, then false.
+func (q *fairQueue) pop() (queuedPayload, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.ring) == 0 {
+		if q.closed {
+			return queuedPayload{}, false
+		}
+		q.cond.Wait()
+	}
+
+	key := q.ring[0]
+	q.ring = q.ring[1:]
+
+	pending := q.queues[key]
+	item := pending[0]
+	if len(pending) == 1 {
+		delete(q.queues, key)
+	} else {
+		q.queues[key] = pending[1:]
+		q.ring = append(q.ring, key)
+	}
+
+	return item, true
+}
+
+// close stops accepting payloads and wakes idle workers so they can exit
+func (q *fairQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// depths returns the number of pending payloads per key
+func (q *fairQueue) depths() map[string]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	depths := make(map[string]int, len(q.queues))
+	for key, pending := range q.queues {
+		depths[key] = len(pending)
+	}
+	return depths
+}
+
+// work processes queued payloads until the queue is closed and empty
+func (d *WebhookDispatcher) work() {
+	for {
+		item, ok := d.queue.pop()
+		if !ok {
+			return
+		}
+
+		d.inFlight.Add(1)
+		d.processWithRetries(item.requestID, item.webhookID, item.payload)
+		d.inFlight.Add(-1)
+		d.wg.Done()
+	}
+}
+
+// dispatchFair queues payloads on the webhook's own queue. A batch that does
+// not fit is rejected as a whole; other webhooks' queues are unaffected.
+func (d *WebhookDispatcher) dispatchFair(requestID, webhookID string, payloads []models.HeliusWebhookPayload) (int, error) {
+	items := make([]queuedPayload, len(payloads))
+	for i, payload := range payloads {
+		items[i] = queuedPayload{requestID: requestID, webhookID: webhookID, payload: payload}
+	}
+
+	d.wg.Add(len(items))
+	accepted := d.queue.push(webhookID, items)
+	if rejected := len(items) - accepted; rejected > 0 {
+		d.wg.Add(-rejected)
+		d.rejected.Add(int64(rejected))
+		log.Warn().
+			Str("request_id", requestID).
+			Str("webhookID", webhookID).
+			Int("dispatched", accepted).
+			Int("rejected", rejected).
+			Msg("Webhook queue is full, rejecting payloads")
+		return accepted, ErrWebhookBackpressure
+	}
+
+	return accepted, nil
+}
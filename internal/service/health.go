@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/pkg/logger"
+)
+
+// healthCheckTimeout bounds the Helius and target database checks of a
+// health report
+const healthCheckTimeout = 10 * time.Second
+
+// GetIndexerHealth reports whether an indexer is still receiving data. It is
+// healthy when it is active, indexed something within the stale threshold
+// (counted from its creation if it never has), its Helius webhook still
+// exists and its target table can be read.
+func (s *IndexerService) GetIndexerHealth(ctx context.Context, userID uuid.UUID, indexerID uuid.UUID) (*models.IndexerHealthResponse, error) {
+
+	var pgIndexerID pgtype.UUID
+	if err := pgIndexerID.Scan(indexerID.String()); err != nil {
+		return nil, fmt.Errorf("invalid indexer ID: %w", err)
+	}
+
+	foundIndexer, err := s.store.GetIndexerByID(ctx, pgIndexerID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get indexer")
+		return nil, errors.New("indexer not found")
+	}
+
+	userIDFromDB, err := uuid.Parse(foundIndexer.UserID.String())
+	if err != nil || userIDFromDB != userID {
+		return nil, errors.New("indexer not found")
+	}
+
+	health := &models.IndexerHealthResponse{
+		IndexerID:         indexerID,
+		Status:            string(foundIndexer.Status),
+		LastIndexedAt:     optionalTime(foundIndexer.LastIndexedAt),
+		StaleAfterSeconds: s.staleAfter.Seconds(),
+	}
+
+	if foundIndexer.Status != db.IndexerStatusActive {
+		health.Problems = append(health.Problems, fmt.Sprintf("indexer is %s", foundIndexer.Status))
+	}
+
+	lastIndexed := foundIndexer.CreatedAt.Time
+	if foundIndexer.LastIndexedAt.Valid {
+		lastIndexed = foundIndexer.LastIndexedAt.Time
+	}
+	if since := time.Since(lastIndexed); since > s.staleAfter {
+		health.Problems = append(health.Problems, fmt.Sprintf("nothing indexed for %s", since.Round(time.Second)))
+	}
+
+	logs, err := s.store.GetIndexingLogsByIndexerID(ctx, db.GetIndexingLogsByIndexerIDParams{
+		IndexerID: pgIndexerID,
+		Limit:     1,
+		Offset:    0,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get latest indexing log")
+		return nil, errors.New("failed to retrieve indexing logs")
+	}
+	if len(logs) > 0 {
+		lastEvent := logs[0].CreatedAt.Time
+		since := time.Since(lastEvent).Seconds()
+		health.LastEventAt = &lastEvent
+		health.SecondsSinceLastEvent = &since
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	if problem := s.checkIndexerWebhook(checkCtx, foundIndexer, health); problem != "" {
+		health.Problems = append(health.Problems, problem)
+	}
+
+	rowCount, err := s.countTargetRows(checkCtx, foundIndexer)
+	if err != nil {
+		log.Warn().Err(logger.RedactError(err)).Str("indexerID", indexerID.String()).Msg("Failed to count target table rows for health check")
+		health.Problems = append(health.Problems, "target table could not be read")
+	} else {
+		health.RowCount = &rowCount
+	}
+
+	health.Healthy = len(health.Problems) == 0
+	return health, nil
+}
+
+// checkIndexerWebhook sets whether the indexer's Helius webhook still exists,
+// returning a problem when it doesn't or couldn't be checked
+func (s *IndexerService) checkIndexerWebhook(ctx context.Context, foundIndexer db.Indexer, health *models.IndexerHealthResponse) string {
+	if !foundIndexer.WebhookID.Valid || foundIndexer.WebhookID.String == "" {
+		exists := false
+		health.WebhookExists = &exists
+		return "indexer has no webhook"
+	}
+	if s.heliusClient == nil {
+		return "webhook not checked, Helius is not configured"
+	}
+
+	_, err := s.heliusClient.GetWebhookConfigByID(ctx, foundIndexer.WebhookID.String)
+	if errors.Is(err, indexer.ErrWebhookNotFound) {
+		exists := false
+		health.WebhookExists = &exists
+		return "webhook no longer exists on Helius"
+	}
+	if err != nil {
+		log.Warn().Err(logger.RedactError(err)).Str("indexerID", foundIndexer.ID.String()).Msg("Failed to check webhook for health check")
+		return "webhook could not be checked"
+	}
+
+	exists := true
+	health.WebhookExists = &exists
+	return ""
+}
+
+// countTargetRows counts the rows of the indexer's target table
+func (s *IndexerService) countTargetRows(ctx context.Context, foundIndexer db.Indexer) (int64, error) {
+	pool, err := s.connectActivityPool(ctx, foundIndexer.DbCredentialID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer pool.Close()
+
+	var count int64
+	err = pool.QueryRow(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", indexer.QuoteTableName(foundIndexer.TargetTable))).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count target table rows: %w", err)
+	}
+	return count, nil
+}
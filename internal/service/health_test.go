@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// healthStore serves its indexer with a newest indexing log entry from
+// lastEvent ago
+type healthStore struct {
+	*rawStore
+	lastEvent time.Duration
+}
+
+func (s *healthStore) GetIndexingLogsByIndexerID(ctx context.Context, arg db.GetIndexingLogsByIndexerIDParams) ([]db.IndexingLog, error) {
+	return []db.IndexingLog{{
+		IndexerID: arg.IndexerID,
+		EventType: "success",
+		CreatedAt: pgtype.Timestamptz{Time: time.Now().Add(-s.lastEvent), Valid: true},
+	}}, nil
+}
+
+// heliusWebhookConfigs answers webhook lookups for the webhook IDs it holds
+// and 404 for any other
+type heliusWebhookConfigs map[string]bool
+
+func (h heliusWebhookConfigs) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h[strings.TrimPrefix(r.URL.Path, "/webhooks/")] {
+		w.Write([]byte(`{"webhookURL": "http://app.local/webhooks", "accountAddresses": ["collection"]}`))
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func newHealthStore(userID uuid.UUID, lastIndexed time.Duration) *healthStore {
+	store := &healthStore{rawStore: newRawStore(userID), lastEvent: lastIndexed}
+	store.indexer.CreatedAt = pgtype.Timestamptz{Time: time.Now().Add(-24 * time.Hour), Valid: true}
+	store.indexer.LastIndexedAt = pgtype.Timestamptz{Time: time.Now().Add(-lastIndexed), Valid: true}
+	return store
+}
+
+func hasProblem(problems []string, prefix string) bool {
+	for _, problem := range problems {
+		if strings.HasPrefix(problem, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGetIndexerHealthFreshVersusStale(t *testing.T) {
+	tests := []struct {
+		name        string
+		lastIndexed time.Duration
+		neverRan    bool
+		wantStale   bool
+	}{
+		{name: "fresh", lastIndexed: time.Minute},
+		{name: "stale", lastIndexed: 2 * time.Hour, wantStale: true},
+		{name: "never indexed since creation", neverRan: true, wantStale: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New()
+			store := newHealthStore(userID, tt.lastIndexed)
+			if tt.neverRan {
+				store.indexer.LastIndexedAt = pgtype.Timestamptz{}
+			}
+			store.cred = unreachableCredential(t)
+			s := NewIndexerService(store, newAuditClient(t, heliusWebhookConfigs{"webhook": true}))
+			s.SetStaleAfter(time.Hour)
+
+			health, err := s.GetIndexerHealth(context.Background(), userID, uuid.UUID(store.indexer.ID.Bytes))
+			if err != nil {
+				t.Fatalf("GetIndexerHealth: %v", err)
+			}
+
+			if stale := hasProblem(health.Problems, "nothing indexed for"); stale != tt.wantStale {
+				t.Errorf("problems = %q, want stale reported %v", health.Problems, tt.wantStale)
+			}
+			if health.StaleAfterSeconds != 3600 {
+				t.Errorf("staleAfterSeconds = %v, want 3600", health.StaleAfterSeconds)
+			}
+			if health.WebhookExists == nil || !*health.WebhookExists {
+				t.Errorf("webhookExists = %v, want the webhook found", health.WebhookExists)
+			}
+			if health.LastEventAt == nil || health.SecondsSinceLastEvent == nil {
+				t.Error("no last event reported")
+			}
+			// The target database refuses connections, so the row count is
+			// missing and the indexer unhealthy whatever its freshness
+			if health.RowCount != nil || !hasProblem(health.Problems, "target table could not be read") || health.Healthy {
+				t.Errorf("health = %+v, want the unreadable target table reported", health)
+			}
+		})
+	}
+}
+
+func TestGetIndexerHealthReportsMissingWebhook(t *testing.T) {
+	userID := uuid.New()
+	store := newHealthStore(userID, time.Minute)
+	store.cred = unreachableCredential(t)
+	s := NewIndexerService(store, newAuditClient(t, heliusWebhookConfigs{}))
+
+	health, err := s.GetIndexerHealth(context.Background(), userID, uuid.UUID(store.indexer.ID.Bytes))
+	if err != nil {
+		t.Fatalf("GetIndexerHealth: %v", err)
+	}
+	if health.WebhookExists == nil || *health.WebhookExists || !hasProblem(health.Problems, "webhook no longer exists") {
+		t.Errorf("health = %+v, want the deleted webhook reported", health)
+	}
+}
+
+func TestGetIndexerHealthReportsPausedIndexer(t *testing.T) {
+	userID := uuid.New()
+	store := newHealthStore(userID, time.Minute)
+	store.indexer.Status = db.IndexerStatusPaused
+	store.cred = unreachableCredential(t)
+	s := NewIndexerService(store, nil)
+
+	health, err := s.GetIndexerHealth(context.Background(), userID, uuid.UUID(store.indexer.ID.Bytes))
+	if err != nil {
+		t.Fatalf("GetIndexerHealth: %v", err)
+	}
+	if health.Status != "paused" || !hasProblem(health.Problems, "indexer is paused") {
+		t.Errorf("health = %+v, want the paused status reported", health)
+	}
+}
+
+func TestGetIndexerHealthChecksOwnership(t *testing.T) {
+	store := newHealthStore(uuid.New(), time.Minute)
+	s := NewIndexerService(store, nil)
+
+	if _, err := s.GetIndexerHealth(context.Background(), uuid.New(), uuid.UUID(store.indexer.ID.Bytes)); err == nil {
+		t.Error("GetIndexerHealth of another user's indexer succeeded")
+	}
+}
+
+func TestGetIndexerHealthHealthyWithRows(t *testing.T) {
+	cred, pool := testTarget(t)
+	table := testTable(t, pool)
+
+	userID := uuid.New()
+	store := newHealthStore(userID, time.Minute)
+	store.cred = cred
+	store.indexer.TargetTable = table
+
+	idx, err := indexer.NewNFTPriceIndexer("test", store.indexer.Params)
+	if err != nil {
+		t.Fatalf("NewNFTPriceIndexer: %v", err)
+	}
+	initializeTable(t, pool, idx, table)
+	var sale models.HeliusWebhookPayload
+	if err := json.Unmarshal(salePayload(t, "sale-sig", 10).Body, &sale); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	if _, err := idx.ProcessPayload(context.Background(), pool, table, sale); err != nil {
+		t.Fatalf("ProcessPayload: %v", err)
+	}
+
+	s := NewIndexerService(store, newAuditClient(t, heliusWebhookConfigs{"webhook": true}))
+	s.SetStaleAfter(time.Hour)
+
+	health, err := s.GetIndexerHealth(context.Background(), userID, uuid.UUID(store.indexer.ID.Bytes))
+	if err != nil {
+		t.Fatalf("GetIndexerHealth: %v", err)
+	}
+	if !health.Healthy || len(health.Problems) != 0 {
+		t.Errorf("health = %+v, want a fresh indexer with its webhook and rows healthy", health)
+	}
+	if health.RowCount == nil || *health.RowCount != 1 {
+		t.Errorf("rowCount = %v, want 1", health.RowCount)
+	}
+
+	// The same indexer gone quiet for longer than the threshold is stale
+	store.indexer.LastIndexedAt = pgtype.Timestamptz{Time: time.Now().Add(-2 * time.Hour), Valid: true}
+	if health, err = s.GetIndexerHealth(context.Background(), userID, uuid.UUID(store.indexer.ID.Bytes)); err != nil {
+		t.Fatalf("GetIndexerHealth: %v", err)
+	}
+	if health.Healthy || !hasProblem(health.Problems, "nothing indexed for") {
+		t.Errorf("health = %+v, want the stale indexer unhealthy", health)
+	}
+}
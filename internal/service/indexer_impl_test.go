@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+func TestGetOrCreateIndexerImplRefreshesChangedParams(t *testing.T) {
+	s := NewIndexerService(nil, nil)
+	ctx := context.Background()
+	row := db.Indexer{
+		ID:          pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		IndexerType: db.IndexerTypeNftPrices,
+		Params:      json.RawMessage(`{"collection": "first"}`),
+	}
+
+	first, err := s.getOrCreateIndexerImpl(ctx, row)
+	if err != nil {
+		t.Fatalf("getOrCreateIndexerImpl: %v", err)
+	}
+	cached, err := s.getOrCreateIndexerImpl(ctx, row)
+	if err != nil {
+		t.Fatalf("getOrCreateIndexerImpl: %v", err)
+	}
+	if cached != first {
+		t.Error("unchanged row built a new impl instead of using the cached one")
+	}
+
+	// The row is edited in the database behind the cache's back
+	row.Params = json.RawMessage(`{"collection": "second"}`)
+	refreshed, err := s.getOrCreateIndexerImpl(ctx, row)
+	if err != nil {
+		t.Fatalf("getOrCreateIndexerImpl: %v", err)
+	}
+	if refreshed == first {
+		t.Fatal("changed params kept the stale cached impl")
+	}
+	if got := refreshed.(*indexer.NFTPriceIndexer).Collection; got != "second" {
+		t.Errorf("refreshed impl collection = %q, want second", got)
+	}
+}
+
+func TestGetOrCreateIndexerImplRefreshesChangedType(t *testing.T) {
+	s := NewIndexerService(nil, nil)
+	ctx := context.Background()
+	row := db.Indexer{
+		ID:          pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		IndexerType: db.IndexerTypeNftPrices,
+		Params:      json.RawMessage(`{"collection": "collection"}`),
+	}
+
+	if _, err := s.getOrCreateIndexerImpl(ctx, row); err != nil {
+		t.Fatalf("getOrCreateIndexerImpl: %v", err)
+	}
+
+	row.IndexerType = db.IndexerTypeNftBids
+	impl, err := s.getOrCreateIndexerImpl(ctx, row)
+	if err != nil {
+		t.Fatalf("getOrCreateIndexerImpl: %v", err)
+	}
+	if _, ok := impl.(*indexer.NFTBidIndexer); !ok {
+		t.Errorf("impl after a type change is %T, want *indexer.NFTBidIndexer", impl)
+	}
+}
+
+// sharedIndexerStore serves one indexer to any number of goroutines and
+// counts the deletes
+type sharedIndexerStore struct {
+	db.Querier
+	indexer db.Indexer
+	deletes atomic.Int32
+}
+
+func (s *sharedIndexerStore) GetIndexerByWebhookID(ctx context.Context, webhookID pgtype.Text) (db.Indexer, error) {
+	return s.indexer, nil
+}
+
+func (s *sharedIndexerStore) GetIndexerByID(ctx context.Context, id pgtype.UUID) (db.Indexer, error) {
+	return s.indexer, nil
+}
+
+func (s *sharedIndexerStore) GetDBCredentialByID(ctx context.Context, id pgtype.UUID) (db.DbCredential, error) {
+	return db.DbCredential{}, nil
+}
+
+func (s *sharedIndexerStore) DeleteIndexer(ctx context.Context, arg db.DeleteIndexerParams) error {
+	s.deletes.Add(1)
+	return nil
+}
+
+func (s *sharedIndexerStore) CreateAuditEvent(ctx context.Context, arg db.CreateAuditEventParams) error {
+	return nil
+}
+
+// Run with -race: processing caches the indexer's implementation while
+// deleting drops it, from different goroutines
+func TestProcessAndDeleteConcurrently(t *testing.T) {
+	userID := uuid.New()
+	store := &sharedIndexerStore{indexer: newRawStore(userID).indexer}
+	s := NewIndexerService(store, nil)
+	s.SetDeleteRetention(0)
+	indexerID := uuid.UUID(store.indexer.ID.Bytes)
+
+	// The signature counts as processed, so each payload builds or reuses
+	// the implementation and stops before the target database
+	s.SetDedupWindow(time.Hour)
+	s.dedup.claim(indexerID, "sig")
+	payload := models.HeliusWebhookPayload{Transaction: models.HeliusTransaction{Signatures: []string{"sig"}}}
+
+	const rounds = 200
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*rounds)
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			errs <- s.ProcessWebhookPayload(ctx, "webhook", payload)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			errs <- s.DeleteIndexer(ctx, userID, indexerID)
+		}
+	}()
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent process or delete failed: %v", err)
+		}
+	}
+	if store.deletes.Load() != rounds {
+		t.Errorf("deleted %d times, want %d", store.deletes.Load(), rounds)
+	}
+}
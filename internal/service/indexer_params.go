@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/pkg/logger"
+	"github.com/rishavmehra/indexer/pkg/validator"
+)
+
+// ErrInvalidIndexerParams is returned when new indexer params fail validation
+var ErrInvalidIndexerParams = errors.New("invalid indexer params")
+
+// indexerAddresses returns the accounts an indexer's webhook has to watch:
+// the token list of token indexers, the collection of NFT indexers or the
+// program and its accounts for program logs
+func indexerAddresses(indexerType models.IndexerType, params json.RawMessage) []string {
+	var addresses []string
+	switch indexerType {
+	case models.TokenBorrow, models.TokenPrices, models.TokenHolders:
+		var tokenParams struct {
+			Tokens []string `json:"tokens"`
+		}
+		if err := json.Unmarshal(params, &tokenParams); err != nil {
+			log.Error().Err(err).Msg("Failed to unmarshal token parameters")
+		} else {
+			addresses = tokenParams.Tokens
+		}
+	case models.NFTBids, models.NFTPrices:
+		var nftParams struct {
+			Collection string `json:"collection"`
+		}
+		if err := json.Unmarshal(params, &nftParams); err != nil {
+			log.Error().Err(err).Msg("Failed to unmarshal NFT parameters")
+		} else {
+			if nftParams.Collection != "" {
+				addresses = append(addresses, nftParams.Collection)
+			}
+		}
+	case models.ProgramLogs:
+		var programParams models.ProgramLogParams
+		if err := json.Unmarshal(params, &programParams); err != nil {
+			log.Error().Err(err).Msg("Failed to unmarshal program log parameters")
+		} else {
+			if programParams.ProgramID != "" {
+				addresses = append(addresses, programParams.ProgramID)
+			}
+			addresses = append(addresses, programParams.Accounts...)
+		}
+	}
+	return addresses
+}
+
+// diffAddresses returns the addresses in next but not in prev, and those in
+// prev but not in next
+func diffAddresses(prev, next []string) (added, removed []string) {
+	inPrev := make(map[string]bool, len(prev))
+	for _, addr := range prev {
+		inPrev[addr] = true
+	}
+	inNext := make(map[string]bool, len(next))
+	for _, addr := range next {
+		inNext[addr] = true
+	}
+
+	for _, addr := range next {
+		if !inPrev[addr] {
+			added = append(added, addr)
+			inPrev[addr] = true
+		}
+	}
+	for _, addr := range prev {
+		if !inNext[addr] {
+			removed = append(removed, addr)
+			inNext[addr] = true
+		}
+	}
+	return added, removed
+}
+
+// UpdateIndexerParams replaces the params of an indexer in place, keeping its
+// history and webhook. The indexer is initialized with the new params first,
+// then addresses that were added or dropped are moved on the Helius webhooks
+// before the params are stored.
+func (s *IndexerService) UpdateIndexerParams(ctx context.Context, userID uuid.UUID, indexerID uuid.UUID, params json.RawMessage) (*models.IndexerResponse, error) {
+
+	var pgIndexerID pgtype.UUID
+	if err := pgIndexerID.Scan(indexerID.String()); err != nil {
+		return nil, fmt.Errorf("invalid indexer ID: %w", err)
+	}
+
+	foundIndexer, err := s.store.GetIndexerByID(ctx, pgIndexerID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get indexer")
+		return nil, errors.New("indexer not found")
+	}
+
+	userIDFromDB, err := uuid.Parse(foundIndexer.UserID.String())
+	if err != nil || userIDFromDB != userID {
+		return nil, errors.New("indexer not found")
+	}
+
+	indexerType := models.IndexerType(foundIndexer.IndexerType)
+
+	if err := validator.ValidateIndexerParams(string(indexerType), params); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIndexerParams, err)
+	}
+	if err := s.ValidatePollInterval(params); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIndexerParams, err)
+	}
+	if indexerType == models.NFTBids || indexerType == models.NFTPrices {
+		params, err = validator.NormalizeMarketplaces(params)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidIndexerParams, err)
+		}
+	}
+
+	added, removed := diffAddresses(
+		indexerAddresses(indexerType, foundIndexer.Params),
+		indexerAddresses(indexerType, params),
+	)
+
+	updated := foundIndexer
+	updated.Params = params
+
+	// Initializing rebuilds the cached impl from the new params and creates
+	// whatever they need in the target table
+	if err := s.initializeIndexer(ctx, updated); err != nil {
+		s.forgetIndexerImpl(indexerID)
+		return nil, fmt.Errorf("failed to initialize indexer: %w", logger.RedactError(err))
+	}
+
+	settings := s.indexerWebhookSettings(ctx, updated)
+
+	if s.heliusClient != nil && len(added) > 0 {
+		webhookURL, err := s.indexerWebhookURL(ctx, foundIndexer)
+		if err != nil {
+			s.forgetIndexerImpl(indexerID)
+			return nil, err
+		}
+
+		webhookIDs, err := s.heliusClient.AllocateAddresses(ctx, webhookURL, added, settings, foundIndexer.ID.String())
+		for _, webhookID := range webhookIDs {
+			indexer.RegisterWebhookMapping(webhookID, foundIndexer.ID.String())
+		}
+		if err != nil {
+			s.forgetIndexerImpl(indexerID)
+			return nil, fmt.Errorf("failed to add addresses to Helius webhook: %w", logger.RedactError(err))
+		}
+
+		if !foundIndexer.WebhookID.Valid && len(webhookIDs) > 0 {
+			_, err := s.store.UpdateIndexerWebhookID(ctx, db.UpdateIndexerWebhookIDParams{
+				ID:        foundIndexer.ID,
+				WebhookID: pgtype.Text{String: foundIndexer.ID.String(), Valid: true},
+			})
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to update indexer webhook ID")
+			}
+		}
+	}
+
+	// Without new addresses the webhooks are only touched if the params
+	// changed the webhook type or transaction types they ask for
+	if s.heliusClient != nil && len(added) == 0 && foundIndexer.WebhookID.Valid {
+		if webhookURL, err := s.indexerWebhookURL(ctx, foundIndexer); err == nil {
+			if err := s.heliusClient.SetWebhookSettings(ctx, webhookURL, settings); err != nil {
+				s.forgetIndexerImpl(indexerID)
+				return nil, fmt.Errorf("failed to update Helius webhook settings: %w", logger.RedactError(err))
+			}
+		}
+	}
+
+	if s.heliusClient != nil && len(removed) > 0 {
+		if err := s.heliusClient.RemoveAddresses(ctx, removed, foundIndexer.ID.String()); err != nil {
+			log.Error().Err(err).
+				Str("indexerID", indexerID.String()).
+				Strs("addresses", removed).
+				Msg("Failed to remove addresses from Helius webhook")
+		}
+	}
+
+	if _, err := s.store.UpdateIndexerParams(ctx, db.UpdateIndexerParamsParams{
+		ID:     foundIndexer.ID,
+		Params: params,
+	}); err != nil {
+		log.Error().Err(err).Msg("Failed to update indexer params")
+		return nil, errors.New("failed to update indexer params")
+	}
+
+	recordAudit(ctx, s.store, userID, auditIndexerUpdateParams, auditEntityIndexer, foundIndexer.ID, map[string]interface{}{
+		"addedAddresses":   added,
+		"removedAddresses": removed,
+	})
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"addedAddresses":   added,
+		"removedAddresses": removed,
+		"webhookType":      settings.WebhookType,
+		"transactionTypes": settings.TransactionTypes,
+	})
+
+	_, logErr := s.store.CreateIndexingLog(ctx, db.CreateIndexingLogParams{
+		IndexerID: foundIndexer.ID,
+		EventType: "params_update",
+		Message:   "Indexer params updated",
+		Details:   details,
+	})
+	if logErr != nil {
+		log.Error().Err(logErr).Msg("Failed to create params update log entry")
+	}
+
+	return s.GetIndexerByID(ctx, userID, indexerID)
+}
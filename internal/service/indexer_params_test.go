@@ -0,0 +1,44 @@
+package service
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+func TestDiffAddresses(t *testing.T) {
+	tests := []struct {
+		name        string
+		prev, next  []string
+		wantAdded   []string
+		wantRemoved []string
+	}{
+		{name: "unchanged", prev: []string{"a", "b"}, next: []string{"b", "a"}},
+		{name: "token added", prev: []string{"a"}, next: []string{"a", "b"}, wantAdded: []string{"b"}},
+		{name: "token removed", prev: []string{"a", "b"}, next: []string{"a"}, wantRemoved: []string{"b"}},
+		{name: "tokens swapped", prev: []string{"a", "b"}, next: []string{"b", "c"}, wantAdded: []string{"c"}, wantRemoved: []string{"a"}},
+		{name: "duplicates reported once", prev: []string{"a", "a"}, next: []string{"b", "b"}, wantAdded: []string{"b"}, wantRemoved: []string{"a"}},
+		{name: "from nothing", next: []string{"a"}, wantAdded: []string{"a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, removed := diffAddresses(tt.prev, tt.next)
+			if !reflect.DeepEqual(added, tt.wantAdded) || !reflect.DeepEqual(removed, tt.wantRemoved) {
+				t.Errorf("diffAddresses(%v, %v) = %v, %v; want %v, %v", tt.prev, tt.next, added, removed, tt.wantAdded, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+func TestDiffAddressesMovesNFTCollection(t *testing.T) {
+	prev := indexerAddresses(models.NFTPrices, json.RawMessage(`{"collection": "old-collection"}`))
+	next := indexerAddresses(models.NFTPrices, json.RawMessage(`{"collection": "new-collection", "marketplaces": ["TENSOR"]}`))
+
+	added, removed := diffAddresses(prev, next)
+	if !reflect.DeepEqual(added, []string{"new-collection"}) || !reflect.DeepEqual(removed, []string{"old-collection"}) {
+		t.Errorf("got %v added and %v removed, want the collection swapped", added, removed)
+	}
+}
@@ -1,11 +1,12 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,15 +17,72 @@ import (
 
 	db "github.com/rishavmehra/indexer/internal/db/generated"
 	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/metrics"
 	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/pkg/logger"
 	"github.com/rishavmehra/indexer/pkg/validator"
 )
 
+// ErrAddressLimitExceeded is returned when an indexer's addresses do not fit on
+// the webhooks the Helius plan allows
+var ErrAddressLimitExceeded = indexer.ErrAddressLimitExceeded
+
+// ErrIndexerNotActive is returned for payloads that reach a paused or failed
+// indexer; they are dropped rather than retried
+var ErrIndexerNotActive = errors.New("indexer is not active")
+
 type IndexerService struct {
 	store        db.Querier
 	heliusClient *indexer.HeliusClient
+	// indexers caches the implementation of each indexer; webhook payloads
+	// are processed concurrently, so it is only touched under indexersMu
+	indexersMu   sync.RWMutex
 	indexers     map[uuid.UUID]indexer.Indexer
 	heliusAPIKey string
+	latency      *metrics.LatencyTracker
+	logNotifier  *LogNotifier
+	events       *EventBroker
+	inFlight     *inFlightTracker
+	maintenance  *Maintenance
+	dedup        *signatureDeduper
+	// metadataCache is shared by the token price indexers so a token's
+	// metadata is fetched once for all of them
+	metadataCache *indexer.TokenMetadataCache
+	// targetMaxConns and targetMinConns size the pools opened to target
+	// databases for processing payloads and reading logs
+	targetMaxConns int32
+	targetMinConns int32
+	// acquireTimeout bounds how long processors wait for a connection from
+	// a target pool
+	acquireTimeout time.Duration
+	// logsMaxLimit caps a page of indexing logs; logsEnhanceLimit is the
+	// largest page that gets target table rows attached
+	logsMaxLimit     int32
+	logsEnhanceLimit int32
+	// deleteRetention is how long a deleted indexer can be restored before
+	// it is purged; zero deletes indexers right away
+	deleteRetention time.Duration
+	// payloadJobRetention is how long done and failed payload jobs are kept
+	payloadJobRetention time.Duration
+	// rawPayloadRetention is how long received payloads are kept for replay
+	rawPayloadRetention time.Duration
+	// reservedTablePrefixes are prefixes target table names may not start
+	// with, on top of pg_
+	reservedTablePrefixes []string
+	// maxIndexersPerUser caps the indexers of users without an override of
+	// their own; zero means no cap
+	maxIndexersPerUser int
+	// staleAfter is how long an indexer may go without indexing anything
+	// before it is reported unhealthy
+	staleAfter time.Duration
+	// autoPauseFailures consecutive failures within autoPauseWindow pause
+	// an indexer; zero turns auto-pausing off
+	autoPauseFailures int
+	autoPauseWindow   time.Duration
+	failures          *failureTracker
+	// pollCheckInterval is how often the poller looks for indexers due a
+	// poll, zero until it is started
+	pollCheckInterval time.Duration
 }
 
 func NewIndexerService(store db.Querier, heliusClient *indexer.HeliusClient) *IndexerService {
@@ -34,14 +92,117 @@ func NewIndexerService(store db.Querier, heliusClient *indexer.HeliusClient) *In
 		apiKey = heliusClient.GetAPIKey()
 	}
 
+	logNotifier := NewLogNotifier()
+
 	return &IndexerService{
-		store:        store,
-		heliusClient: heliusClient,
-		indexers:     make(map[uuid.UUID]indexer.Indexer),
-		heliusAPIKey: apiKey,
+		store:               &notifyingStore{Querier: store, notifier: logNotifier},
+		heliusClient:        heliusClient,
+		indexers:            make(map[uuid.UUID]indexer.Indexer),
+		heliusAPIKey:        apiKey,
+		latency:             metrics.NewLatencyTracker(metrics.DefaultLatencyWindowSize),
+		logNotifier:         logNotifier,
+		events:              NewEventBroker(),
+		inFlight:            newInFlightTracker(),
+		maintenance:         NewMaintenance(),
+		dedup:               newSignatureDeduper(0),
+		failures:            newFailureTracker(),
+		metadataCache:       indexer.NewTokenMetadataCache(),
+		targetMaxConns:      10,
+		targetMinConns:      1,
+		acquireTimeout:      indexer.DefaultAcquireTimeout,
+		logsMaxLimit:        500,
+		logsEnhanceLimit:    100,
+		staleAfter:          time.Hour,
+		payloadJobRetention: 24 * time.Hour,
+		rawPayloadRetention: 7 * 24 * time.Hour,
 	}
 }
 
+// SetTargetPoolSize sets the connection limits of the pools opened to target
+// databases
+func (s *IndexerService) SetTargetPoolSize(maxConns, minConns int32) {
+	s.targetMaxConns = maxConns
+	s.targetMinConns = minConns
+}
+
+// SetDBAcquireTimeout sets how long processors wait for a target database
+// connection before failing with indexer.ErrPoolExhausted. Indexers already
+// cached keep the timeout they were built with.
+func (s *IndexerService) SetDBAcquireTimeout(timeout time.Duration) {
+	s.acquireTimeout = timeout
+}
+
+// SetMetadataCache sets the token metadata cache shared by the token price
+// indexers. Indexers already cached keep the cache they were built with.
+func (s *IndexerService) SetMetadataCache(cache *indexer.TokenMetadataCache) {
+	s.metadataCache = cache
+}
+
+// MetadataCacheStats returns usage stats for the token metadata cache
+func (s *IndexerService) MetadataCacheStats() indexer.MetadataCacheStats {
+	return s.metadataCache.Stats()
+}
+
+// SetLogLimits sets the largest page of indexing logs returned and the
+// largest page whose logs are enriched with target table rows
+func (s *IndexerService) SetLogLimits(maxLimit, enhanceLimit int32) {
+	s.logsMaxLimit = maxLimit
+	s.logsEnhanceLimit = enhanceLimit
+}
+
+// SetDedupWindow sets how long a processed transaction signature is
+// remembered per indexer; redelivered webhooks within the window are skipped.
+// Zero disables deduplication.
+func (s *IndexerService) SetDedupWindow(window time.Duration) {
+	s.dedup.setWindow(window)
+}
+
+// SetDeleteRetention sets how long deleted indexers are kept for restoring.
+// Zero deletes them, and their webhooks, right away.
+func (s *IndexerService) SetDeleteRetention(retention time.Duration) {
+	s.deleteRetention = retention
+}
+
+// SetPayloadJobRetention sets how long done and failed payload jobs are kept
+// before the purger deletes them
+func (s *IndexerService) SetPayloadJobRetention(retention time.Duration) {
+	s.payloadJobRetention = retention
+}
+
+// SetRawPayloadRetention sets how long received payloads are kept for
+// ReprocessPayloads before the purger deletes them
+func (s *IndexerService) SetRawPayloadRetention(retention time.Duration) {
+	s.rawPayloadRetention = retention
+}
+
+// SetStaleAfter sets how long an indexer may go without indexing anything
+// before its health check reports it unhealthy
+func (s *IndexerService) SetStaleAfter(staleAfter time.Duration) {
+	s.staleAfter = staleAfter
+}
+
+// SetReservedTablePrefixes sets extra prefixes target table names are not
+// allowed to start with
+func (s *IndexerService) SetReservedTablePrefixes(prefixes []string) {
+	s.reservedTablePrefixes = prefixes
+}
+
+// SetMaxIndexersPerUser sets how many indexers a user may have unless their
+// account carries its own limit. Zero means no cap.
+func (s *IndexerService) SetMaxIndexersPerUser(max int) {
+	s.maxIndexersPerUser = max
+}
+
+// Maintenance returns the switch that pauses all indexing
+func (s *IndexerService) Maintenance() *Maintenance {
+	return s.maintenance
+}
+
+// InFlightIndexers returns how many payloads each indexer is processing right now
+func (s *IndexerService) InFlightIndexers() map[uuid.UUID]int {
+	return s.inFlight.snapshot()
+}
+
 func (s *IndexerService) CreateIndexer(ctx context.Context, userID uuid.UUID, req models.CreateIndexerRequest) (*models.IndexerResponse, error) {
 
 	var pgUserID pgtype.UUID
@@ -64,13 +225,32 @@ func (s *IndexerService) CreateIndexer(ctx context.Context, userID uuid.UUID, re
 		return nil, errors.New("database credential not found")
 	}
 
-	if !validator.IsValidTableName(req.TargetTable) {
-		return nil, errors.New("invalid target table name")
+	if err := validator.ValidateTableName(req.TargetTable, s.reservedTablePrefixes); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTargetTable, err)
+	}
+
+	if err := s.checkIndexerQuota(ctx, pgUserID); err != nil {
+		return nil, err
 	}
 
 	if err := validator.ValidateIndexerParams(string(req.IndexerType), req.Params); err != nil {
 		return nil, err
 	}
+	if err := s.ValidatePollInterval(req.Params); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIndexerParams, err)
+	}
+
+	if req.IndexerType == models.NFTBids || req.IndexerType == models.NFTPrices {
+		req.Params, err = validator.NormalizeMarketplaces(req.Params)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := preflightTarget(ctx, cred, req.TargetTable); err != nil {
+		log.Warn().Err(err).Str("targetTable", req.TargetTable).Msg("Indexer target database failed preflight")
+		return nil, err
+	}
 
 	createdIndexer, err := s.store.CreateIndexer(ctx, db.CreateIndexerParams{
 		UserID:         pgUserID,
@@ -86,34 +266,24 @@ func (s *IndexerService) CreateIndexer(ctx context.Context, userID uuid.UUID, re
 		return nil, errors.New("failed to create indexer")
 	}
 
-	var addresses []string
-	switch req.IndexerType {
-	case models.TokenBorrow, models.TokenPrices:
-		var tokenParams struct {
-			Tokens []string `json:"tokens"`
-		}
-		if err := json.Unmarshal(req.Params, &tokenParams); err != nil {
-			log.Error().Err(err).Msg("Failed to unmarshal token parameters")
-		} else {
-			addresses = tokenParams.Tokens
-		}
-	case models.NFTBids, models.NFTPrices:
-		var nftParams struct {
-			Collection string `json:"collection"`
-		}
-		if err := json.Unmarshal(req.Params, &nftParams); err != nil {
-			log.Error().Err(err).Msg("Failed to unmarshal NFT parameters")
-		} else {
-			if nftParams.Collection != "" {
-				addresses = append(addresses, nftParams.Collection)
-			}
-		}
+	recordAudit(ctx, s.store, userID, auditIndexerCreate, auditEntityIndexer, createdIndexer.ID, map[string]interface{}{
+		"indexerType":    req.IndexerType,
+		"targetTable":    req.TargetTable,
+		"dbCredentialId": req.DBCredentialID,
+	})
+
+	addresses := indexerAddresses(req.IndexerType, req.Params)
+
+	// Without a secret of its own the indexer's webhook falls back to
+	// HELIUS_WEBHOOK_SECRET, so this isn't fatal
+	if _, err := s.createWebhookSecret(ctx, createdIndexer.ID); err != nil {
+		log.Error().Err(err).Str("indexerID", createdIndexer.ID.String()).Msg("Failed to create indexer webhook secret")
 	}
 
 	if err := s.initializeIndexer(ctx, createdIndexer); err != nil {
 
 		var errText pgtype.Text
-		errText.String = err.Error()
+		errText.String = logger.Redact(err.Error())
 		errText.Valid = true
 
 		_, updateErr := s.store.UpdateIndexerStatus(ctx, db.UpdateIndexerStatusParams{
@@ -125,7 +295,12 @@ func (s *IndexerService) CreateIndexer(ctx context.Context, userID uuid.UUID, re
 			log.Error().Err(updateErr).Msg("Failed to update indexer status")
 		}
 
-		return nil, fmt.Errorf("failed to initialize indexer: %w", err)
+		return nil, fmt.Errorf("failed to initialize indexer: %w", logger.RedactError(err))
+	}
+
+	// History goes in before the webhook so live payloads don't race it
+	if s.heliusClient != nil && len(addresses) > 0 && indexerBackfillLimit(req.Params) > 0 {
+		s.runInitialBackfill(ctx, createdIndexer, addresses)
 	}
 
 	if s.heliusClient != nil && len(addresses) > 0 {
@@ -134,7 +309,7 @@ func (s *IndexerService) CreateIndexer(ctx context.Context, userID uuid.UUID, re
 			log.Error().Err(err).Msg("Failed to create Helius webhook")
 
 			var errText pgtype.Text
-			errText.String = fmt.Sprintf("Failed to create webhook: %s", err.Error())
+			errText.String = fmt.Sprintf("Failed to create webhook: %s", logger.Redact(err.Error()))
 			errText.Valid = true
 
 			_, updateErr := s.store.UpdateIndexerStatus(ctx, db.UpdateIndexerStatusParams{
@@ -146,7 +321,7 @@ func (s *IndexerService) CreateIndexer(ctx context.Context, userID uuid.UUID, re
 				log.Error().Err(updateErr).Msg("Failed to update indexer status")
 			}
 
-			return nil, fmt.Errorf("failed to create Helius webhook: %w", err)
+			return nil, fmt.Errorf("failed to create Helius webhook: %w", logger.RedactError(err))
 		}
 
 		var webhookText pgtype.Text
@@ -236,14 +411,25 @@ func (s *IndexerService) CreateIndexer(ctx context.Context, userID uuid.UUID, re
 		Params:         params,
 		TargetTable:    createdIndexer.TargetTable,
 		WebhookID:      createdIndexer.WebhookID.String,
+		Cluster:        s.cluster(),
 		Status:         models.IndexerStatus(createdIndexer.Status),
 		LastIndexedAt:  lastIndexedAt,
 		ErrorMessage:   createdIndexer.ErrorMessage.String,
+		LastError:      createdIndexer.LastError.String,
+		LastErrorAt:    optionalTime(createdIndexer.LastErrorAt),
 		CreatedAt:      createdIndexer.CreatedAt.Time,
 		UpdatedAt:      createdIndexer.UpdatedAt.Time,
 	}, nil
 }
 
+// cluster returns the Solana cluster indexers run against
+func (s *IndexerService) cluster() string {
+	if s.heliusClient == nil {
+		return ""
+	}
+	return s.heliusClient.GetCluster()
+}
+
 func (s *IndexerService) GetDefaultWebhookID() string {
 	if s.heliusClient != nil {
 		return s.heliusClient.GetDefaultWebhookID()
@@ -304,9 +490,12 @@ func (s *IndexerService) GetIndexersByUserID(ctx context.Context, userID uuid.UU
 			Params:         params,
 			TargetTable:    idx.TargetTable,
 			WebhookID:      idx.WebhookID.String,
+			Cluster:        s.cluster(),
 			Status:         models.IndexerStatus(idx.Status),
 			LastIndexedAt:  lastIndexedAt,
 			ErrorMessage:   idx.ErrorMessage.String,
+			LastError:      idx.LastError.String,
+			LastErrorAt:    optionalTime(idx.LastErrorAt),
 			CreatedAt:      idx.CreatedAt.Time,
 			UpdatedAt:      idx.UpdatedAt.Time,
 		}
@@ -371,14 +560,77 @@ func (s *IndexerService) GetIndexerByID(ctx context.Context, userID uuid.UUID, i
 		Params:         params,
 		TargetTable:    foundIndexer.TargetTable,
 		WebhookID:      foundIndexer.WebhookID.String,
+		Cluster:        s.cluster(),
 		Status:         models.IndexerStatus(foundIndexer.Status),
 		LastIndexedAt:  lastIndexedAt,
 		ErrorMessage:   foundIndexer.ErrorMessage.String,
+		LastError:      foundIndexer.LastError.String,
+		LastErrorAt:    optionalTime(foundIndexer.LastErrorAt),
 		CreatedAt:      foundIndexer.CreatedAt.Time,
 		UpdatedAt:      foundIndexer.UpdatedAt.Time,
 	}, nil
 }
 
+// GetIndexerStats returns processing latency percentiles for an indexer
+func (s *IndexerService) GetIndexerStats(ctx context.Context, userID uuid.UUID, indexerID uuid.UUID) (*models.IndexerStatsResponse, error) {
+
+	var pgIndexerID pgtype.UUID
+	if err := pgIndexerID.Scan(indexerID.String()); err != nil {
+		return nil, fmt.Errorf("invalid indexer ID: %w", err)
+	}
+
+	foundIndexer, err := s.store.GetIndexerByID(ctx, pgIndexerID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get indexer")
+		return nil, errors.New("indexer not found")
+	}
+
+	userIDFromDB, err := uuid.Parse(foundIndexer.UserID.String())
+	if err != nil || userIDFromDB != userID {
+		return nil, errors.New("indexer not found")
+	}
+
+	snapshot := s.latency.Snapshot(indexerID)
+
+	stats := &models.IndexerStatsResponse{
+		IndexerID:  indexerID,
+		WindowSize: s.latency.WindowSize(),
+		Latency: models.LatencyStats{
+			SampleCount: snapshot.Count,
+			P50Ms:       durationMillis(snapshot.P50),
+			P95Ms:       durationMillis(snapshot.P95),
+			P99Ms:       durationMillis(snapshot.P99),
+			MaxMs:       durationMillis(snapshot.Max),
+		},
+	}
+
+	// The latency stats are still returned if the target database can't be read
+	if foundIndexer.IndexerType == db.IndexerTypeNftPrices {
+		collection, err := s.collectionStats(ctx, foundIndexer)
+		if err != nil {
+			log.Warn().Err(logger.RedactError(err)).Str("indexerID", indexerID.String()).Msg("Failed to compute collection stats")
+		} else {
+			stats.Collection = collection
+		}
+	}
+
+	return stats, nil
+}
+
+// optionalTime converts a nullable timestamp to a pointer, nil when NULL
+func optionalTime(ts pgtype.Timestamptz) *time.Time {
+	if !ts.Valid {
+		return nil
+	}
+	t := ts.Time
+	return &t
+}
+
+// durationMillis converts a duration to fractional milliseconds
+func durationMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
 func (s *IndexerService) PauseIndexer(ctx context.Context, userID uuid.UUID, indexerID uuid.UUID) (*models.IndexerResponse, error) {
 
 	var pgIndexerID pgtype.UUID
@@ -414,6 +666,8 @@ func (s *IndexerService) PauseIndexer(ctx context.Context, userID uuid.UUID, ind
 		return nil, errors.New("failed to pause indexer")
 	}
 
+	recordAudit(ctx, s.store, userID, auditIndexerPause, auditEntityIndexer, foundIndexer.ID, nil)
+
 	var params interface{}
 	if err := json.Unmarshal(foundIndexer.Params, &params); err != nil {
 		log.Error().Err(err).Msg("Failed to unmarshal indexer params")
@@ -452,9 +706,12 @@ func (s *IndexerService) PauseIndexer(ctx context.Context, userID uuid.UUID, ind
 		Params:         params,
 		TargetTable:    foundIndexer.TargetTable,
 		WebhookID:      foundIndexer.WebhookID.String,
+		Cluster:        s.cluster(),
 		Status:         models.IndexerStatus(foundIndexer.Status),
 		LastIndexedAt:  lastIndexedAt,
 		ErrorMessage:   foundIndexer.ErrorMessage.String,
+		LastError:      foundIndexer.LastError.String,
+		LastErrorAt:    optionalTime(foundIndexer.LastErrorAt),
 		CreatedAt:      foundIndexer.CreatedAt.Time,
 		UpdatedAt:      foundIndexer.UpdatedAt.Time,
 	}, nil
@@ -482,6 +739,10 @@ func (s *IndexerService) ResumeIndexer(ctx context.Context, userID uuid.UUID, in
 		return nil, errors.New("indexer is already active")
 	}
 
+	if err := s.checkIndexerQuota(ctx, foundIndexer.UserID); err != nil {
+		return nil, err
+	}
+
 	var emptyText pgtype.Text
 	emptyText.Valid = false
 
@@ -495,6 +756,8 @@ func (s *IndexerService) ResumeIndexer(ctx context.Context, userID uuid.UUID, in
 		return nil, errors.New("failed to resume indexer")
 	}
 
+	recordAudit(ctx, s.store, userID, auditIndexerResume, auditEntityIndexer, foundIndexer.ID, nil)
+
 	var params interface{}
 	if err := json.Unmarshal(foundIndexer.Params, &params); err != nil {
 		log.Error().Err(err).Msg("Failed to unmarshal indexer params")
@@ -533,14 +796,19 @@ func (s *IndexerService) ResumeIndexer(ctx context.Context, userID uuid.UUID, in
 		Params:         params,
 		TargetTable:    foundIndexer.TargetTable,
 		WebhookID:      foundIndexer.WebhookID.String,
+		Cluster:        s.cluster(),
 		Status:         models.IndexerStatus(foundIndexer.Status),
 		LastIndexedAt:  lastIndexedAt,
 		ErrorMessage:   foundIndexer.ErrorMessage.String,
+		LastError:      foundIndexer.LastError.String,
+		LastErrorAt:    optionalTime(foundIndexer.LastErrorAt),
 		CreatedAt:      foundIndexer.CreatedAt.Time,
 		UpdatedAt:      foundIndexer.UpdatedAt.Time,
 	}, nil
 }
 
+// DeleteIndexer deletes an indexer. Within the delete retention it is only
+// marked deleted and can be restored; without one it is removed right away.
 func (s *IndexerService) DeleteIndexer(ctx context.Context, userID uuid.UUID, indexerID uuid.UUID) error {
 
 	var pgIndexerID pgtype.UUID
@@ -548,11 +816,6 @@ func (s *IndexerService) DeleteIndexer(ctx context.Context, userID uuid.UUID, in
 		return fmt.Errorf("invalid indexer ID: %w", err)
 	}
 
-	var pgUserID pgtype.UUID
-	if err := pgUserID.Scan(userID.String()); err != nil {
-		return fmt.Errorf("invalid user ID: %w", err)
-	}
-
 	foundIndexer, err := s.store.GetIndexerByID(ctx, pgIndexerID)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get indexer")
@@ -564,19 +827,38 @@ func (s *IndexerService) DeleteIndexer(ctx context.Context, userID uuid.UUID, in
 		return errors.New("indexer not found")
 	}
 
+	if s.deleteRetention <= 0 {
+		err = s.purgeIndexer(ctx, foundIndexer)
+	} else {
+		err = s.softDeleteIndexer(ctx, foundIndexer)
+	}
+	if err != nil {
+		return err
+	}
+
+	recordAudit(ctx, s.store, userID, auditIndexerDelete, auditEntityIndexer, foundIndexer.ID, map[string]interface{}{
+		"restorable": s.deleteRetention > 0,
+	})
+	return nil
+}
+
+// purgeIndexer removes an indexer for good, deleting its Helius webhooks first
+func (s *IndexerService) purgeIndexer(ctx context.Context, foundIndexer db.Indexer) error {
+
 	if s.heliusClient != nil && foundIndexer.WebhookID.Valid && foundIndexer.WebhookID.String != "" {
 
 		indexerID := foundIndexer.ID.String()
 
-		var heliusWebhookID string
+		var heliusWebhookIDs []string
 		for helID, idxID := range indexer.GetAllWebhookMappings() {
 			if idxID == indexerID {
-				heliusWebhookID = helID
-				break
+				heliusWebhookIDs = append(heliusWebhookIDs, helID)
 			}
 		}
 
-		if heliusWebhookID != "" {
+		// An indexer with more addresses than one webhook holds is spread
+		// over several webhooks
+		for _, heliusWebhookID := range heliusWebhookIDs {
 			log.Info().
 				Str("indexerID", indexerID).
 				Str("heliusWebhookID", heliusWebhookID).
@@ -595,16 +877,18 @@ func (s *IndexerService) DeleteIndexer(ctx context.Context, userID uuid.UUID, in
 					Str("heliusWebhookID", heliusWebhookID).
 					Msg("Successfully deleted Helius webhook")
 			}
-		} else {
+		}
+
+		if len(heliusWebhookIDs) == 0 {
 			log.Warn().
 				Str("indexerID", indexerID).
 				Msg("Could not find Helius webhook ID for indexer")
 		}
 	}
 
-	err = s.store.DeleteIndexer(ctx, db.DeleteIndexerParams{
-		ID:     pgIndexerID,
-		UserID: pgUserID,
+	err := s.store.DeleteIndexer(ctx, db.DeleteIndexerParams{
+		ID:     foundIndexer.ID,
+		UserID: foundIndexer.UserID,
 	})
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to delete indexer")
@@ -617,7 +901,8 @@ func (s *IndexerService) DeleteIndexer(ctx context.Context, userID uuid.UUID, in
 
 	} else {
 
-		delete(s.indexers, idUUID)
+		s.forgetIndexerImpl(idUUID)
+		s.latency.Remove(idUUID)
 	}
 
 	return nil
@@ -640,6 +925,10 @@ func (s *IndexerService) GetIndexingLogs(ctx context.Context, userID uuid.UUID,
 		return nil, errors.New("indexer not found")
 	}
 
+	if limit > s.logsMaxLimit {
+		limit = s.logsMaxLimit
+	}
+
 	logs, err := s.store.GetIndexingLogsByIndexerID(ctx, db.GetIndexingLogsByIndexerIDParams{
 		IndexerID: pgIndexerID,
 		Limit:     limit,
@@ -652,35 +941,29 @@ func (s *IndexerService) GetIndexingLogs(ctx context.Context, userID uuid.UUID,
 
 	response := make([]models.IndexingLogResponse, len(logs))
 
+	// Enriching costs a pool and a query per log, so large pages skip it.
+	// The pool is opened on the first log that needs it, so pages of only
+	// error or initialization logs never connect to the target database.
+	enhance := limit <= s.logsEnhanceLimit
 	var targetPool *pgxpool.Pool
-	if len(logs) > 0 {
-		var pgCredID pgtype.UUID
-		if err := pgCredID.Scan(foundIndexer.DbCredentialID.String()); err != nil {
-			log.Error().Err(err).Msg("Failed to parse DB credential ID")
-		} else {
-			cred, err := s.store.GetDBCredentialByID(ctx, pgCredID)
+	targetPoolTried := false
+	openTargetPool := func() *pgxpool.Pool {
+		if !targetPoolTried {
+			targetPoolTried = true
+			pool, err := s.connectLogsPool(ctx, foundIndexer.DbCredentialID)
 			if err != nil {
-				log.Error().Err(err).Msg("Failed to get DB credential")
+				log.Error().Err(logger.RedactError(err)).Msg("Failed to connect to target database")
 			} else {
-				dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-					cred.DbHost, cred.DbPort, cred.DbUser, cred.DbPassword, cred.DbName, cred.DbSslMode)
-
-				poolConfig, connErr := pgxpool.ParseConfig(dsn)
-				if connErr != nil {
-					log.Error().Err(connErr).Msg("Failed to parse database config")
-				} else {
-					poolConfig.MaxConns = 5
-					poolConfig.MinConns = 1
-					targetPool, connErr = pgxpool.NewWithConfig(ctx, poolConfig)
-					if connErr != nil {
-						log.Error().Err(connErr).Msg("Failed to connect to target database")
-					} else {
-						defer targetPool.Close()
-					}
-				}
+				targetPool = pool
 			}
 		}
+		return targetPool
 	}
+	defer func() {
+		if targetPool != nil {
+			targetPool.Close()
+		}
+	}()
 
 	for i, l := range logs {
 		var details interface{}
@@ -706,25 +989,12 @@ func (s *IndexerService) GetIndexingLogs(ctx context.Context, userID uuid.UUID,
 		}
 
 		// Enhance details with target DB data for success and token_data events
-		if (l.EventType == "success" || l.EventType == "token_data") && targetPool != nil {
-			// If the log doesn't already have detailed token data and this is a token price indexer
-			if foundIndexer.IndexerType == db.IndexerTypeTokenPrices {
-				detailsMap, ok := details.(map[string]interface{})
-				if ok && detailsMap["token_data"] == nil && detailsMap["tokens"] == nil {
-					// For token price indexers, if we don't already have token data, try to fetch it
-					enhancedDetails, err := enhanceTokenPriceDetails(ctx, targetPool, foundIndexer.TargetTable, details)
-					if err != nil {
-						log.Warn().Err(err).Msg("Failed to enhance token price details")
-					} else if enhancedDetails != nil {
-						details = enhancedDetails
-					}
-				}
-			} else {
-				// For other indexer types, use the original enhancement method
-				enhancedDetails, err := enhanceGenericLogDetails(ctx, targetPool, foundIndexer.TargetTable, details, foundIndexer.IndexerType)
+		if enhance && (l.EventType == "success" || l.EventType == "token_data") {
+			if pool := openTargetPool(); pool != nil {
+				enhancedDetails, err := enhanceLogDetails(ctx, pool, foundIndexer, details)
 				if err != nil {
 					log.Warn().Err(err).Msg("Failed to enhance log details with target data")
-				} else if enhancedDetails != nil {
+				} else {
 					details = enhancedDetails
 				}
 			}
@@ -743,552 +1013,164 @@ func (s *IndexerService) GetIndexingLogs(ctx context.Context, userID uuid.UUID,
 	return response, nil
 }
 
-func enhanceTokenPriceDetails(ctx context.Context, pool *pgxpool.Pool, targetTable string, details interface{}) (interface{}, error) {
-	detailsMap, ok := details.(map[string]interface{})
-	if !ok {
-		return details, fmt.Errorf("details is not a map")
-	}
-
-	var slot int64
-	if slotVal, ok := detailsMap["slot"].(float64); ok {
-		slot = int64(slotVal)
-	}
-
-	targetTable = formatTableName(targetTable)
-
-	queryStr := fmt.Sprintf(`
-		SELECT 
-			token_address, 
-			COALESCE(token_name, '') as token_name, 
-			COALESCE(token_symbol, '') as token_symbol, 
-			platform, 
-			price_usd, 
-			price_sol, 
-			volume_24h,
-			market_cap,
-			liquidity,
-			price_change_24h,
-			total_supply,
-			transaction_id,
-			updated_at, 
-			slot
-		FROM %s
-	`, targetTable)
-
-	var rows pgx.Rows
-	var err error
-	if slot > 0 {
-		queryStr += " WHERE slot <= $1 ORDER BY updated_at DESC LIMIT 5"
-		rows, err = pool.Query(ctx, queryStr, slot)
-	} else {
-		queryStr += " ORDER BY updated_at DESC LIMIT 5"
-		rows, err = pool.Query(ctx, queryStr)
-	}
-
+// connectLogsPool opens a pool to the target database behind credID for
+// enriching indexing logs
+func (s *IndexerService) connectLogsPool(ctx context.Context, credID pgtype.UUID) (*pgxpool.Pool, error) {
+	cred, err := s.store.GetDBCredentialByID(ctx, credID)
 	if err != nil {
-		return details, fmt.Errorf("failed to query token price data: %w", err)
-	}
-	defer rows.Close()
-
-	var tokenData []map[string]interface{}
-	for rows.Next() {
-		var (
-			tokenAddress   string
-			tokenName      string
-			tokenSymbol    string
-			platform       string
-			priceUSD       float64
-			priceSOL       pgtype.Float8
-			volume24h      pgtype.Float8
-			marketCap      pgtype.Float8
-			liquidity      pgtype.Float8
-			priceChange24h pgtype.Float8
-			totalSupply    pgtype.Float8
-			transactionID  pgtype.Text
-			updatedAt      time.Time
-			rowSlot        int64
-		)
-
-		if err := rows.Scan(
-			&tokenAddress,
-			&tokenName,
-			&tokenSymbol,
-			&platform,
-			&priceUSD,
-			&priceSOL,
-			&volume24h,
-			&marketCap,
-			&liquidity,
-			&priceChange24h,
-			&totalSupply,
-			&transactionID,
-			&updatedAt,
-			&rowSlot,
-		); err != nil {
-			log.Error().Err(err).Msg("Failed to scan token row")
-			continue
-		}
-
-		token := map[string]interface{}{
-			"token_address": tokenAddress,
-			"token_name":    tokenName,
-			"token_symbol":  tokenSymbol,
-			"platform":      platform,
-			"price_usd":     priceUSD,
-			"updated_at":    updatedAt.Format(time.RFC3339),
-			"slot":          rowSlot,
-		}
-
-		if priceSOL.Valid {
-			token["price_sol"] = priceSOL.Float64
-		}
-		if volume24h.Valid {
-			token["volume_24h"] = volume24h.Float64
-		}
-		if marketCap.Valid {
-			token["market_cap"] = marketCap.Float64
-		}
-		if liquidity.Valid {
-			token["liquidity"] = liquidity.Float64
-		}
-		if priceChange24h.Valid {
-			token["price_change_24h"] = priceChange24h.Float64
-		}
-		if totalSupply.Valid {
-			token["total_supply"] = totalSupply.Float64
-		}
-		if transactionID.Valid {
-			token["transaction_id"] = transactionID.String
-		}
-
-		tokenData = append(tokenData, token)
+		return nil, fmt.Errorf("failed to get DB credential: %w", err)
 	}
 
-	if len(tokenData) > 0 {
-		detailsMap["tokens"] = tokenData
+	poolConfig, err := s.targetPoolConfig(cred)
+	if err != nil {
+		return nil, err
 	}
 
-	return detailsMap, nil
+	return pgxpool.NewWithConfig(ctx, poolConfig)
 }
 
-func formatTableName(name string) string {
-	formattedName := ""
-	for _, c := range name {
-		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' {
-			formattedName += string(c)
-		} else {
-			formattedName += "_"
-		}
-	}
+// targetPoolConfig builds the configuration of a pool to the target database
+// behind cred, sized by SetTargetPoolSize
+func (s *IndexerService) targetPoolConfig(cred db.DbCredential) (*pgxpool.Config, error) {
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cred.DbHost, cred.DbPort, cred.DbUser, cred.DbPassword, cred.DbName, cred.DbSslMode)
 
-	if len(formattedName) == 0 || !((formattedName[0] >= 'a' && formattedName[0] <= 'z') || (formattedName[0] >= 'A' && formattedName[0] <= 'Z')) {
-		formattedName = "idx_" + formattedName
+	poolConfig, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database config: %w", err)
 	}
+	poolConfig.MaxConns = s.targetMaxConns
+	poolConfig.MinConns = s.targetMinConns
 
-	return formattedName
+	return poolConfig, nil
 }
 
-func (s *IndexerService) enhanceLogDetailsWithTargetData(ctx context.Context, pool *pgxpool.Pool, targetTable string, details interface{}, indexerType db.IndexerType) (interface{}, error) {
-	detailsMap, ok := details.(map[string]interface{})
-	if !ok {
-		return details, fmt.Errorf("details is not a map")
+// TailIndexingLogs returns logs written after the (since, sinceID) cursor,
+// waiting up to wait for new ones to arrive when there are none yet. Logs are
+// ordered by creation time and then ID, so a page ending in the middle of
+// logs that share a timestamp picks up where it stopped. Without a sinceID
+// every log at since counts as already seen.
+func (s *IndexerService) TailIndexingLogs(ctx context.Context, userID uuid.UUID, indexerID uuid.UUID, since time.Time, sinceID uuid.UUID, wait time.Duration, limit int32) (*models.LogTailResponse, error) {
+	var pgIndexerID pgtype.UUID
+	if err := pgIndexerID.Scan(indexerID.String()); err != nil {
+		return nil, fmt.Errorf("invalid indexer ID: %w", err)
 	}
 
-	slotFloat, slotOk := detailsMap["slot"].(float64)
-
-	// Format the target table name to ensure it's valid
-	targetTable = formatTableName(targetTable)
-
-	// Query the target table for data based on indexer type
-	var rows pgx.Rows
-	var err error
-
-	// Different query based on indexer type
-	if indexerType == db.IndexerTypeTokenPrices {
-		// Token Price specific query
-		if slotOk {
-			slot := int64(slotFloat)
-			rows, err = pool.Query(ctx, fmt.Sprintf(`
-				SELECT 
-					token_address, token_name, token_symbol, platform,
-					price_usd, price_sol, volume_24h, market_cap, liquidity,
-					price_change_24h, total_supply, transaction_id, updated_at, slot
-				FROM %s
-				WHERE slot <= $1
-				ORDER BY updated_at DESC
-				LIMIT 5
-			`, targetTable), slot)
-		} else {
-			// If no slot, just get recent entries
-			rows, err = pool.Query(ctx, fmt.Sprintf(`
-				SELECT 
-					token_address, token_name, token_symbol, platform,
-					price_usd, price_sol, volume_24h, market_cap, liquidity,
-					price_change_24h, total_supply, transaction_id, updated_at, slot
-				FROM %s
-				ORDER BY updated_at DESC
-				LIMIT 5
-			`, targetTable))
-		}
-
-		if err != nil {
-			return details, fmt.Errorf("failed to query token price table: %w", err)
-		}
-		defer rows.Close()
-
-		var tokenData []map[string]interface{}
-		for rows.Next() {
-			var (
-				tokenAddress   string
-				tokenName      pgtype.Text
-				tokenSymbol    pgtype.Text
-				platform       string
-				priceUSD       float64
-				priceSOL       pgtype.Float8
-				volume24h      pgtype.Float8
-				marketCap      pgtype.Float8
-				liquidity      pgtype.Float8
-				priceChange24h pgtype.Float8
-				totalSupply    pgtype.Float8
-				transactionID  pgtype.Text
-				updatedAt      time.Time
-				slot           int64
-			)
-
-			if err := rows.Scan(
-				&tokenAddress, &tokenName, &tokenSymbol, &platform,
-				&priceUSD, &priceSOL, &volume24h, &marketCap, &liquidity,
-				&priceChange24h, &totalSupply, &transactionID, &updatedAt, &slot,
-			); err != nil {
-				log.Error().Err(err).Msg("Failed to scan row from token price table")
-				continue
-			}
-
-			rowData := map[string]interface{}{
-				"token_address": tokenAddress,
-				"platform":      platform,
-				"price_usd":     priceUSD,
-				"updated_at":    updatedAt.Format(time.RFC3339),
-				"slot":          slot,
-			}
+	foundIndexer, err := s.store.GetIndexerByID(ctx, pgIndexerID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get indexer")
+		return nil, errors.New("indexer not found")
+	}
 
-			// Add optional fields if they have values
-			if tokenName.Valid {
-				rowData["token_name"] = tokenName.String
-			}
-			if tokenSymbol.Valid {
-				rowData["token_symbol"] = tokenSymbol.String
-			}
-			if priceSOL.Valid {
-				rowData["price_sol"] = priceSOL.Float64
-			}
-			if volume24h.Valid {
-				rowData["volume_24h"] = volume24h.Float64
-			}
-			if marketCap.Valid {
-				rowData["market_cap"] = marketCap.Float64
-			}
-			if liquidity.Valid {
-				rowData["liquidity"] = liquidity.Float64
-			}
-			if priceChange24h.Valid {
-				rowData["price_change_24h"] = priceChange24h.Float64
-			}
-			if totalSupply.Valid {
-				rowData["total_supply"] = totalSupply.Float64
-			}
-			if transactionID.Valid {
-				rowData["transaction_id"] = transactionID.String
-			}
+	userIDFromDB, err := uuid.Parse(foundIndexer.UserID.String())
+	if err != nil || userIDFromDB != userID {
+		return nil, errors.New("indexer not found")
+	}
 
-			tokenData = append(tokenData, rowData)
-		}
+	if sinceID == uuid.Nil {
+		sinceID = uuid.Max
+	}
 
-		if len(tokenData) > 0 {
-			detailsMap["tokens"] = tokenData
-		}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
 
-	} else if indexerType == db.IndexerTypeNftPrices {
-		// Original NFT Price query logic
-		if slotOk {
-			slot := int64(slotFloat)
-			rows, err = pool.Query(ctx, fmt.Sprintf(`
-				SELECT 
-					id, signature, slot, block_time, 
-					nft_mint, COALESCE(nft_name, '') as nft_name, marketplace, 
-					price, currency, usd_value, 
-					seller, buyer, status, 
-					created_at, updated_at
-				FROM %s
-				WHERE slot = $1
-				ORDER BY created_at DESC
-				LIMIT 5
-			`, targetTable), slot)
-		} else {
-			// If no slot, just get recent entries
-			rows, err = pool.Query(ctx, fmt.Sprintf(`
-				SELECT 
-					id, signature, slot, block_time, 
-					nft_mint, COALESCE(nft_name, '') as nft_name, marketplace, 
-					price, currency, usd_value, 
-					seller, buyer, status, 
-					created_at, updated_at
-				FROM %s
-				ORDER BY created_at DESC
-				LIMIT 5
-			`, targetTable))
-		}
+	for {
+		// Subscribe before querying so a log written in between still wakes us
+		notified := s.logNotifier.Wait(indexerID)
 
+		logs, err := s.store.GetIndexingLogsSince(ctx, db.GetIndexingLogsSinceParams{
+			IndexerID:      pgIndexerID,
+			AfterCreatedAt: pgtype.Timestamptz{Time: since, Valid: true},
+			AfterID:        pgtype.UUID{Bytes: sinceID, Valid: true},
+			Limit:          limit,
+		})
 		if err != nil {
-			return details, fmt.Errorf("failed to query target table: %w", err)
+			log.Error().Err(err).Msg("Failed to get indexing logs")
+			return nil, errors.New("failed to retrieve indexing logs")
 		}
-		defer rows.Close()
-
-		var targetData []map[string]interface{}
-		for rows.Next() {
-			var (
-				id          string
-				signature   string
-				slot        int64
-				blockTime   time.Time
-				nftMint     string
-				nftName     string
-				marketplace string
-				price       float64
-				currency    string
-				usdValue    pgtype.Float8
-				seller      string
-				buyer       pgtype.Text
-				status      string
-				createdAt   time.Time
-				updatedAt   time.Time
-			)
-
-			if err := rows.Scan(
-				&id, &signature, &slot, &blockTime,
-				&nftMint, &nftName, &marketplace,
-				&price, &currency, &usdValue,
-				&seller, &buyer, &status,
-				&createdAt, &updatedAt,
-			); err != nil {
-				log.Error().Err(err).Msg("Failed to scan row from target table")
-				continue
-			}
 
-			rowData := map[string]interface{}{
-				"id":          id,
-				"signature":   signature,
-				"slot":        slot,
-				"block_time":  blockTime.Format(time.RFC3339),
-				"nft_mint":    nftMint,
-				"nft_name":    nftName,
-				"marketplace": marketplace,
-				"price":       price,
-				"currency":    currency,
-				"seller":      seller,
-				"status":      status,
-				"created_at":  createdAt.Format(time.RFC3339),
-				"updated_at":  updatedAt.Format(time.RFC3339),
-			}
-
-			if usdValue.Valid {
-				rowData["usd_value"] = usdValue.Float64
-			} else {
-				rowData["usd_value"] = nil
+		if len(logs) > 0 {
+			last := logs[len(logs)-1]
+			cursorID := uuid.UUID(last.ID.Bytes)
+			response := &models.LogTailResponse{
+				Logs:     make([]models.IndexingLogResponse, 0, len(logs)),
+				Cursor:   last.CreatedAt.Time,
+				CursorID: &cursorID,
 			}
-
-			if buyer.Valid {
-				rowData["buyer"] = buyer.String
-			} else {
-				rowData["buyer"] = nil
+			for _, l := range logs {
+				entry, err := toIndexingLogResponse(l)
+				if err != nil {
+					log.Error().Err(err).Msg("Failed to convert indexing log")
+					continue
+				}
+				response.Logs = append(response.Logs, entry)
 			}
-
-			targetData = append(targetData, rowData)
-		}
-
-		if len(targetData) > 0 {
-			detailsMap["transactions"] = targetData
-		}
-	} else if indexerType == db.IndexerTypeTokenBorrow {
-		// For token borrow indexers
-		if slotOk {
-			slot := int64(slotFloat)
-			rows, err = pool.Query(ctx, fmt.Sprintf(`
-				SELECT 
-					token_address, platform, available_amount, borrow_rate, 
-					supply_rate, utilization_rate, total_borrowed, total_supplied, 
-					updated_at, slot
-				FROM %s
-				WHERE slot <= $1
-				ORDER BY updated_at DESC
-				LIMIT 5
-			`, targetTable), slot)
-		} else {
-			rows, err = pool.Query(ctx, fmt.Sprintf(`
-				SELECT 
-					token_address, platform, available_amount, borrow_rate, 
-					supply_rate, utilization_rate, total_borrowed, total_supplied, 
-					updated_at, slot
-				FROM %s
-				ORDER BY updated_at DESC
-				LIMIT 5
-			`, targetTable))
+			return response, nil
 		}
 
-		if err != nil {
-			return details, fmt.Errorf("failed to query token borrow table: %w", err)
+		select {
+		case <-notified:
+		case <-timer.C:
+			return &models.LogTailResponse{
+				Logs:   []models.IndexingLogResponse{},
+				Cursor: since,
+			}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		}
-		defer rows.Close()
-
-		var borrowData []map[string]interface{}
-		for rows.Next() {
-			var (
-				tokenAddress    string
-				platform        string
-				availableAmount pgtype.Float8
-				borrowRate      pgtype.Float8
-				supplyRate      pgtype.Float8
-				utilizationRate pgtype.Float8
-				totalBorrowed   pgtype.Float8
-				totalSupplied   pgtype.Float8
-				updatedAt       time.Time
-				slot            int64
-			)
-
-			if err := rows.Scan(
-				&tokenAddress, &platform, &availableAmount, &borrowRate,
-				&supplyRate, &utilizationRate, &totalBorrowed, &totalSupplied,
-				&updatedAt, &slot,
-			); err != nil {
-				log.Error().Err(err).Msg("Failed to scan row from token borrow table")
-				continue
-			}
+	}
+}
 
-			rowData := map[string]interface{}{
-				"token_address": tokenAddress,
-				"platform":      platform,
-				"updated_at":    updatedAt.Format(time.RFC3339),
-				"slot":          slot,
-			}
+// SubscribeEvents streams the processing events of an indexer to its owner.
+// The caller must Close the subscription.
+func (s *IndexerService) SubscribeEvents(ctx context.Context, userID uuid.UUID, indexerID uuid.UUID) (*EventSubscription, error) {
+	var pgIndexerID pgtype.UUID
+	if err := pgIndexerID.Scan(indexerID.String()); err != nil {
+		return nil, fmt.Errorf("invalid indexer ID: %w", err)
+	}
 
-			// Add optional fields
-			if availableAmount.Valid {
-				rowData["available_amount"] = availableAmount.Float64
-			}
-			if borrowRate.Valid {
-				rowData["borrow_rate"] = borrowRate.Float64
-			}
-			if supplyRate.Valid {
-				rowData["supply_rate"] = supplyRate.Float64
-			}
-			if utilizationRate.Valid {
-				rowData["utilization_rate"] = utilizationRate.Float64
-			}
-			if totalBorrowed.Valid {
-				rowData["total_borrowed"] = totalBorrowed.Float64
-			}
-			if totalSupplied.Valid {
-				rowData["total_supplied"] = totalSupplied.Float64
-			}
+	foundIndexer, err := s.store.GetIndexerByID(ctx, pgIndexerID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get indexer")
+		return nil, errors.New("indexer not found")
+	}
 
-			borrowData = append(borrowData, rowData)
-		}
+	userIDFromDB, err := uuid.Parse(foundIndexer.UserID.String())
+	if err != nil || userIDFromDB != userID {
+		return nil, errors.New("indexer not found")
+	}
 
-		if len(borrowData) > 0 {
-			detailsMap["borrow_data"] = borrowData
-		}
-	} else if indexerType == db.IndexerTypeNftBids {
-		// For NFT bids indexers
-		if slotOk {
-			slot := int64(slotFloat)
-			rows, err = pool.Query(ctx, fmt.Sprintf(`
-				SELECT 
-					id, signature, slot, block_time, 
-					nft_mint, auction_house, marketplace, 
-					bidder, bid_amount, bid_currency, bid_usd_value, expiry,
-					created_at
-				FROM %s
-				WHERE slot = $1
-				ORDER BY created_at DESC
-				LIMIT 5
-			`, targetTable), slot)
-		} else {
-			rows, err = pool.Query(ctx, fmt.Sprintf(`
-				SELECT 
-					id, signature, slot, block_time, 
-					nft_mint, auction_house, marketplace, 
-					bidder, bid_amount, bid_currency, bid_usd_value, expiry,
-					created_at
-				FROM %s
-				ORDER BY created_at DESC
-				LIMIT 5
-			`, targetTable))
-		}
+	return s.events.Subscribe(indexerID, DefaultEventBufferSize), nil
+}
 
-		if err != nil {
-			return details, fmt.Errorf("failed to query NFT bids table: %w", err)
+// toIndexingLogResponse converts a stored log without enhancing its details
+func toIndexingLogResponse(l db.IndexingLog) (models.IndexingLogResponse, error) {
+	var details interface{} = map[string]interface{}{}
+	if l.Details != nil {
+		if err := json.Unmarshal(l.Details, &details); err != nil {
+			log.Error().Err(err).Msg("Failed to unmarshal log details")
+			details = map[string]interface{}{}
 		}
-		defer rows.Close()
-
-		var bidsData []map[string]interface{}
-		for rows.Next() {
-			var (
-				id           int
-				signature    string
-				slot         int64
-				blockTime    time.Time
-				nftMint      string
-				auctionHouse pgtype.Text
-				marketplace  string
-				bidder       string
-				bidAmount    float64
-				bidCurrency  string
-				bidUsdValue  pgtype.Float8
-				expiry       pgtype.Timestamptz
-				createdAt    time.Time
-			)
-
-			if err := rows.Scan(
-				&id, &signature, &slot, &blockTime,
-				&nftMint, &auctionHouse, &marketplace,
-				&bidder, &bidAmount, &bidCurrency, &bidUsdValue, &expiry,
-				&createdAt,
-			); err != nil {
-				log.Error().Err(err).Msg("Failed to scan row from NFT bids table")
-				continue
-			}
-
-			rowData := map[string]interface{}{
-				"signature":    signature,
-				"slot":         slot,
-				"block_time":   blockTime.Format(time.RFC3339),
-				"nft_mint":     nftMint,
-				"marketplace":  marketplace,
-				"bidder":       bidder,
-				"bid_amount":   bidAmount,
-				"bid_currency": bidCurrency,
-				"created_at":   createdAt.Format(time.RFC3339),
-			}
-
-			if auctionHouse.Valid {
-				rowData["auction_house"] = auctionHouse.String
-			}
-			if bidUsdValue.Valid {
-				rowData["bid_usd_value"] = bidUsdValue.Float64
-			}
-			if expiry.Valid {
-				rowData["expiry"] = expiry.Time.Format(time.RFC3339)
-			}
+	}
 
-			bidsData = append(bidsData, rowData)
-		}
+	idUUID, err := uuid.Parse(l.ID.String())
+	if err != nil {
+		return models.IndexingLogResponse{}, fmt.Errorf("failed to parse log ID: %w", err)
+	}
 
-		if len(bidsData) > 0 {
-			detailsMap["bids"] = bidsData
-		}
+	indexerIDUUID, err := uuid.Parse(l.IndexerID.String())
+	if err != nil {
+		return models.IndexingLogResponse{}, fmt.Errorf("failed to parse indexer ID in log: %w", err)
 	}
 
-	return detailsMap, nil
+	return models.IndexingLogResponse{
+		ID:        idUUID,
+		IndexerID: indexerIDUUID,
+		EventType: l.EventType,
+		Message:   l.Message,
+		Details:   details,
+		CreatedAt: l.CreatedAt.Time,
+	}, nil
 }
 
 func (s *IndexerService) ProcessWebhookPayload(ctx context.Context, webhookID string, payload models.HeliusWebhookPayload) error {
@@ -1297,7 +1179,7 @@ func (s *IndexerService) ProcessWebhookPayload(ctx context.Context, webhookID st
 	pgWebhookID.String = webhookID
 	pgWebhookID.Valid = true
 
-	log.Info().
+	log.Info().Ctx(ctx).
 		Str("webhookID", webhookID).
 		Int64("slot", payload.Slot).
 		Msg("Processing webhook payload")
@@ -1311,7 +1193,7 @@ func (s *IndexerService) ProcessWebhookPayload(ctx context.Context, webhookID st
 			break
 		}
 
-		log.Warn().
+		log.Warn().Ctx(ctx).
 			Err(err).
 			Str("webhookID", webhookID).
 			Int("attempt", attempt).
@@ -1324,10 +1206,23 @@ func (s *IndexerService) ProcessWebhookPayload(ctx context.Context, webhookID st
 		return fmt.Errorf("indexer not found for webhook ID %s: %w", webhookID, err)
 	}
 
+	if foundIndexer.DeletedAt.Valid {
+		return fmt.Errorf("%w (deleted)", ErrIndexerNotActive)
+	}
+
 	if foundIndexer.Status != db.IndexerStatusActive {
-		return fmt.Errorf("indexer is not active (status: %s)", foundIndexer.Status)
+		return fmt.Errorf("%w (status: %s)", ErrIndexerNotActive, foundIndexer.Status)
 	}
 
+	return s.processIndexerPayload(ctx, foundIndexer, webhookID, payload)
+}
+
+// processIndexerPayload writes a payload to the indexer's target table and
+// records the outcome, whatever the indexer's status. webhookID is only used
+// for logging and is empty for payloads that didn't come through a webhook.
+func (s *IndexerService) processIndexerPayload(ctx context.Context, foundIndexer db.Indexer, webhookID string, payload models.HeliusWebhookPayload) (err error) {
+	defer func() { s.trackProcessingOutcome(ctx, foundIndexer, err) }()
+
 	var cred db.DbCredential
 
 	for attempt := 1; attempt <= 3; attempt++ {
@@ -1336,7 +1231,7 @@ func (s *IndexerService) ProcessWebhookPayload(ctx context.Context, webhookID st
 			break
 		}
 
-		log.Warn().
+		log.Warn().Ctx(ctx).
 			Err(err).
 			Str("credentialID", foundIndexer.DbCredentialID.String()).
 			Int("attempt", attempt).
@@ -1354,105 +1249,150 @@ func (s *IndexerService) ProcessWebhookPayload(ctx context.Context, webhookID st
 		return fmt.Errorf("failed to create indexer implementation: %w", err)
 	}
 
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		cred.DbHost, cred.DbPort, cred.DbUser, cred.DbPassword, cred.DbName, cred.DbSslMode)
-
-	log.Debug().
-		Str("host", cred.DbHost).
-		Int32("port", cred.DbPort).
-		Str("database", cred.DbName).
-		Str("user", cred.DbUser).
-		Msg("Connecting to user database")
-
-	poolConfig, err := pgxpool.ParseConfig(dsn)
-	if err != nil {
-		return fmt.Errorf("failed to parse database config: %w", err)
+	if !implTypeMatches(idxImpl, foundIndexer.IndexerType) {
+		return fmt.Errorf("indexer implementation %T does not handle indexer type %s", idxImpl, foundIndexer.IndexerType)
 	}
 
-	poolConfig.MaxConns = 10
-	poolConfig.MinConns = 1
-	poolConfig.MaxConnLifetime = time.Hour
-	poolConfig.MaxConnIdleTime = 30 * time.Minute
-
-	poolConfig.ConnConfig.ConnectTimeout = 5 * time.Second
-
-	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	// Skip redeliveries of a signature this indexer has already processed.
+	// The claim is released if processing fails so Helius retries still run.
+	var signature string
+	if len(payload.Transaction.Signatures) > 0 {
+		signature = payload.Transaction.Signatures[0]
+	}
+	dedupIndexerID, _ := uuid.Parse(foundIndexer.ID.String())
+	if !s.dedup.claim(dedupIndexerID, signature) {
+		log.Debug().Ctx(ctx).
+			Str("indexerID", foundIndexer.ID.String()).
+			Str("signature", signature).
+			Msg("Skipping already processed webhook payload")
+		return nil
+	}
+	processed := false
+	defer func() {
+		if !processed {
+			s.dedup.release(dedupIndexerID, signature)
+		}
+	}()
+
+	// Track how long the target database work takes, whether or not it succeeds
+	if indexerUUID, err := uuid.Parse(foundIndexer.ID.String()); err == nil {
+		processingStart := time.Now()
+		done := s.inFlight.begin(indexerUUID)
+		defer func() {
+			done()
+			s.latency.Record(indexerUUID, time.Since(processingStart))
+		}()
+	}
+
+	pool, err := s.openTargetPool(ctx, cred)
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
+		return err
 	}
 	defer pool.Close()
 
-	if err := pool.Ping(ctx); err != nil {
-		return fmt.Errorf("failed to ping database: %w", err)
-	}
-
+	var result indexer.ProcessResult
 	if tokenIndexer, ok := idxImpl.(indexer.TokenIndexer); ok && s.heliusAPIKey != "" {
 
 		if err := tokenIndexer.EnrichTokenMetadata(ctx, pool, foundIndexer.TargetTable, s.heliusAPIKey); err != nil {
-			log.Warn().Err(err).Msg("Failed to enrich token metadata")
+			log.Warn().Ctx(ctx).Err(err).Msg("Failed to enrich token metadata")
 
 		}
 
-		if err := tokenIndexer.ProcessPayloadWithMetadata(ctx, pool, foundIndexer.TargetTable, payload, s.heliusAPIKey); err != nil {
+		result, err = tokenIndexer.ProcessPayloadWithMetadata(ctx, pool, foundIndexer.TargetTable, payload, s.heliusAPIKey)
+		if err != nil {
 
-			log.Error().Err(err).
+			log.Error().Ctx(ctx).Err(err).
 				Str("indexerID", foundIndexer.ID.String()).
 				Str("webhookID", webhookID).
 				Msg("Failed to process webhook payload")
 
 			details, _ := json.Marshal(map[string]interface{}{
-				"error": err.Error(),
-				"slot":  payload.Slot,
+				"error":          logger.Redact(err.Error()),
+				"slot":           payload.Slot,
+				"schema_version": payload.SchemaVersion,
 			})
 
 			_, logErr := s.store.CreateIndexingLog(ctx, db.CreateIndexingLogParams{
 				IndexerID: foundIndexer.ID,
 				EventType: "error",
-				Message:   fmt.Sprintf("Failed to process payload: %s", err.Error()),
+				Message:   fmt.Sprintf("Failed to process payload: %s", logger.Redact(err.Error())),
 				Details:   details,
 			})
 			if logErr != nil {
-				log.Error().Err(logErr).Msg("Failed to create error log entry")
+				log.Error().Ctx(ctx).Err(logErr).Msg("Failed to create error log entry")
 			}
 
+			s.recordLastError(ctx, foundIndexer.ID, err)
+			s.publishEvent(foundIndexer.ID, "error", "Failed to process payload: "+logger.Redact(err.Error()), details)
+
 			return err
 		}
 	} else {
 
-		if err := idxImpl.ProcessPayload(ctx, pool, foundIndexer.TargetTable, payload); err != nil {
+		result, err = idxImpl.ProcessPayload(ctx, pool, foundIndexer.TargetTable, payload)
+		if err != nil {
 
-			log.Error().Err(err).
+			log.Error().Ctx(ctx).Err(err).
 				Str("indexerID", foundIndexer.ID.String()).
 				Str("webhookID", webhookID).
 				Msg("Failed to process webhook payload")
 
 			details, _ := json.Marshal(map[string]interface{}{
-				"error": err.Error(),
-				"slot":  payload.Slot,
+				"error":          logger.Redact(err.Error()),
+				"slot":           payload.Slot,
+				"schema_version": payload.SchemaVersion,
 			})
 
 			_, logErr := s.store.CreateIndexingLog(ctx, db.CreateIndexingLogParams{
 				IndexerID: foundIndexer.ID,
 				EventType: "error",
-				Message:   fmt.Sprintf("Failed to process payload: %s", err.Error()),
+				Message:   fmt.Sprintf("Failed to process payload: %s", logger.Redact(err.Error())),
 				Details:   details,
 			})
 			if logErr != nil {
-				log.Error().Err(logErr).Msg("Failed to create error log entry")
+				log.Error().Ctx(ctx).Err(logErr).Msg("Failed to create error log entry")
 			}
 
+			s.recordLastError(ctx, foundIndexer.ID, err)
+			s.publishEvent(foundIndexer.ID, "error", "Failed to process payload: "+logger.Redact(err.Error()), details)
+
 			return err
 		}
 	}
 
+	processed = true
+
+	stampSchemaVersion(ctx, pool, foundIndexer, payload)
+
 	_, err = s.store.UpdateLastIndexedTime(ctx, foundIndexer.ID)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to update last indexed time")
+		log.Error().Ctx(ctx).Err(err).Msg("Failed to update last indexed time")
+	}
+
+	// A payload that matched none of the indexer's events, such as an
+	// unrelated transaction on a tracked account, leaves no log entry
+	if result.RowsAffected == 0 {
+		log.Debug().Ctx(ctx).
+			Str("webhookID", webhookID).
+			Int64("slot", payload.Slot).
+			Strs("eventTypes", result.EventTypes).
+			Bool("matched", result.Matched).
+			Msg("Webhook payload matched no events, nothing indexed")
+		return nil
 	}
 
 	// Create enhanced log details
 	logData := map[string]interface{}{
-		"slot": payload.Slot,
+		"slot":         payload.Slot,
+		"rows_written": result.RowsAffected,
+	}
+
+	if len(result.EventTypes) > 0 {
+		logData["event_types"] = result.EventTypes
+	}
+
+	if payload.SchemaVersion != "" {
+		logData["schema_version"] = payload.SchemaVersion
 	}
 
 	// Add transaction details if available
@@ -1475,13 +1415,41 @@ func (s *IndexerService) ProcessWebhookPayload(ctx context.Context, webhookID st
 		Details:   details,
 	})
 	if logErr != nil {
-		log.Error().Err(logErr).Msg("Failed to create success log entry")
+		log.Error().Ctx(ctx).Err(logErr).Msg("Failed to create success log entry")
+	}
+
+	if indexerID, parseErr := uuid.Parse(foundIndexer.ID.String()); parseErr == nil && s.events.HasSubscribers(indexerID) {
+		// Attach the rows the payload wrote; the round trip through JSON gives
+		// the slot the float64 type the enhancer expects
+		var eventDetails interface{}
+		if err := json.Unmarshal(details, &eventDetails); err == nil {
+			enhanced, err := enhanceLogDetails(ctx, pool, foundIndexer, eventDetails)
+			if err != nil {
+				log.Warn().Ctx(ctx).Err(err).Msg("Failed to attach target data to stream event")
+			} else {
+				eventDetails = enhanced
+			}
+		}
+		s.events.Publish(models.IndexedEvent{
+			IndexerID: indexerID,
+			EventType: "success",
+			Message:   "Successfully processed webhook payload",
+			Details:   eventDetails,
+			CreatedAt: time.Now(),
+		})
 	}
 
 	// For token price indexers, create an additional detailed token data log
 	if foundIndexer.IndexerType == db.IndexerTypeTokenPrices {
 		// Get token data from the database
-		tokenData := fetchCurrentTokenData(ctx, pool, foundIndexer.TargetTable)
+		var tokenData []map[string]interface{}
+		if scanner, ok := logDetailScannerFor(foundIndexer.IndexerType, foundIndexer.Params); ok {
+			var err error
+			tokenData, err = scanner.query(ctx, pool, foundIndexer.TargetTable, 0)
+			if err != nil {
+				log.Error().Ctx(ctx).Err(err).Str("table", foundIndexer.TargetTable).Msg("Failed to query token data")
+			}
+		}
 
 		if len(tokenData) > 0 {
 			tokenDetails, _ := json.Marshal(map[string]interface{}{
@@ -1497,19 +1465,113 @@ func (s *IndexerService) ProcessWebhookPayload(ctx context.Context, webhookID st
 			})
 
 			if tokenLogErr != nil {
-				log.Error().Err(tokenLogErr).Msg("Failed to create token data log entry")
+				log.Error().Ctx(ctx).Err(tokenLogErr).Msg("Failed to create token data log entry")
 			}
 		}
 	}
 
-	log.Info().
+	log.Info().Ctx(ctx).
 		Str("webhookID", webhookID).
 		Int64("slot", payload.Slot).
+		Int64("rowsWritten", result.RowsAffected).
+		Strs("eventTypes", result.EventTypes).
 		Msg("Successfully processed webhook payload")
 
 	return nil
 }
 
+// openTargetPool connects to the target database of a credential for
+// processing payloads
+func (s *IndexerService) openTargetPool(ctx context.Context, cred db.DbCredential) (*pgxpool.Pool, error) {
+	log.Debug().Ctx(ctx).
+		Str("host", cred.DbHost).
+		Int32("port", cred.DbPort).
+		Str("database", cred.DbName).
+		Str("user", cred.DbUser).
+		Msg("Connecting to user database")
+
+	poolConfig, err := s.targetPoolConfig(cred)
+	if err != nil {
+		return nil, err
+	}
+
+	poolConfig.MaxConnLifetime = time.Hour
+	poolConfig.MaxConnIdleTime = 30 * time.Minute
+
+	poolConfig.ConnConfig.ConnectTimeout = 5 * time.Second
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+	return pool, nil
+}
+
+// publishEvent pushes a processing event to the stream subscribers of an indexer
+func (s *IndexerService) publishEvent(indexerID pgtype.UUID, eventType, message string, details []byte) {
+	id, err := uuid.Parse(indexerID.String())
+	if err != nil || !s.events.HasSubscribers(id) {
+		return
+	}
+
+	var parsed interface{}
+	if err := json.Unmarshal(details, &parsed); err != nil {
+		parsed = map[string]interface{}{}
+	}
+
+	s.events.Publish(models.IndexedEvent{
+		IndexerID: id,
+		EventType: eventType,
+		Message:   message,
+		Details:   parsed,
+		CreatedAt: time.Now(),
+	})
+}
+
+// recordLastError stores a processing failure on the indexer without changing
+// its status, so one bad payload does not stop the indexer
+func (s *IndexerService) recordLastError(ctx context.Context, indexerID pgtype.UUID, err error) {
+	_, updateErr := s.store.UpdateIndexerLastError(ctx, db.UpdateIndexerLastErrorParams{
+		ID:        indexerID,
+		LastError: pgtype.Text{String: logger.Redact(err.Error()), Valid: true},
+	})
+	if updateErr != nil {
+		log.Error().Err(updateErr).Msg("Failed to record indexer last error")
+	}
+}
+
+// ClearLastError removes the recorded processing failure of an indexer
+func (s *IndexerService) ClearLastError(ctx context.Context, userID uuid.UUID, indexerID uuid.UUID) (*models.IndexerResponse, error) {
+
+	var pgIndexerID pgtype.UUID
+	if err := pgIndexerID.Scan(indexerID.String()); err != nil {
+		return nil, fmt.Errorf("invalid indexer ID: %w", err)
+	}
+
+	foundIndexer, err := s.store.GetIndexerByID(ctx, pgIndexerID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get indexer")
+		return nil, errors.New("indexer not found")
+	}
+
+	userIDFromDB, err := uuid.Parse(foundIndexer.UserID.String())
+	if err != nil || userIDFromDB != userID {
+		return nil, errors.New("indexer not found")
+	}
+
+	if _, err := s.store.ClearIndexerLastError(ctx, pgIndexerID); err != nil {
+		log.Error().Err(err).Msg("Failed to clear indexer last error")
+		return nil, errors.New("failed to clear last error")
+	}
+
+	return s.GetIndexerByID(ctx, userID, indexerID)
+}
+
 func (s *IndexerService) initializeIndexer(ctx context.Context, dbIndexer db.Indexer) error {
 
 	cred, err := s.store.GetDBCredentialByID(ctx, dbIndexer.DbCredentialID)
@@ -1543,9 +1605,22 @@ func (s *IndexerService) initializeIndexer(ctx context.Context, dbIndexer db.Ind
 		}
 	}
 
-	details, _ := json.Marshal(map[string]interface{}{
+	if err := addSchemaVersionColumn(ctx, conn, dbIndexer); err != nil {
+		return err
+	}
+
+	logDetails := map[string]interface{}{
 		"targetTable": dbIndexer.TargetTable,
-	})
+	}
+
+	if cred.TableOwner.Valid && cred.TableOwner.String != "" {
+		if err := indexer.SetTableOwner(ctx, conn, dbIndexer.TargetTable, cred.TableOwner.String); err != nil {
+			return err
+		}
+		logDetails["tableOwner"] = cred.TableOwner.String
+	}
+
+	details, _ := json.Marshal(logDetails)
 
 	_, logErr := s.store.CreateIndexingLog(ctx, db.CreateIndexingLogParams{
 		IndexerID: dbIndexer.ID,
@@ -1567,39 +1642,33 @@ func (s *IndexerService) createHeliusWebhook(ctx context.Context, dbIndexer db.I
 		Strs("addresses", addresses).
 		Msg("Creating dedicated Helius webhook for indexer")
 
-	webhookURL := ""
-	if s.heliusClient != nil && s.heliusClient.GetWebhookBaseURL() != "" {
-		baseURL := s.heliusClient.GetWebhookBaseURL()
-
-		baseURL = strings.TrimSuffix(baseURL, "/")
-
-		webhookURL = fmt.Sprintf("%s/webhooks?id=%s&key=%s",
-			baseURL,
-			dbIndexer.ID.String(),
-			s.heliusClient.GetWebhookSecret())
-	} else {
-		return "", fmt.Errorf("webhook base URL is not configured")
+	webhookURL, err := s.indexerWebhookURL(ctx, dbIndexer)
+	if err != nil {
+		return "", err
 	}
 
-	config := indexer.WebhookConfig{
-		WebhookURL:       webhookURL,
-		WebhookType:      "enhanced",
-		AccountAddresses: addresses,
-		TransactionTypes: []string{"ANY"},
-	}
+	settings := s.indexerWebhookSettings(ctx, dbIndexer)
 
-	webhook, err := s.heliusClient.CreateWebhook(ctx, config)
+	webhookIDs, err := s.heliusClient.AllocateAddresses(ctx, webhookURL, addresses, settings, dbIndexer.ID.String())
+	for _, webhookID := range webhookIDs {
+		indexer.RegisterWebhookMapping(webhookID, dbIndexer.ID.String())
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to create Helius webhook: %w", err)
 	}
-
-	indexer.RegisterWebhookMapping(webhook.WebhookID, dbIndexer.ID.String())
+	if len(webhookIDs) == 0 {
+		return "", fmt.Errorf("no Helius webhook was created for the indexer")
+	}
 
 	details, _ := json.Marshal(map[string]interface{}{
-		"heliusWebhookID": webhook.WebhookID,
-		"indexerID":       dbIndexer.ID.String(),
-		"endpoint":        webhook.Endpoint,
-		"addresses":       addresses,
+		"heliusWebhookID":  webhookIDs[0],
+		"heliusWebhookIDs": webhookIDs,
+		"indexerID":        dbIndexer.ID.String(),
+		"endpoint":         webhookURL,
+		"addresses":        addresses,
+		"webhookType":      settings.WebhookType,
+		"transactionTypes": settings.TransactionTypes,
+		"cluster":          s.heliusClient.GetCluster(),
 	})
 
 	_, logErr := s.store.CreateIndexingLog(ctx, db.CreateIndexingLogParams{
@@ -1615,11 +1684,42 @@ func (s *IndexerService) createHeliusWebhook(ctx context.Context, dbIndexer db.I
 
 	log.Info().
 		Str("indexerID", dbIndexer.ID.String()).
-		Str("webhookID", webhook.WebhookID).
-		Str("endpoint", webhook.Endpoint).
+		Strs("webhookIDs", webhookIDs).
+		Str("endpoint", webhookURL).
 		Msg("Successfully created dedicated webhook")
 
-	return webhook.WebhookID, nil
+	return webhookIDs[0], nil
+}
+
+// indexerWebhookSettings returns the Helius webhook type and transaction types
+// an indexer's webhook asks for, from its implementation's webhook config. It
+// returns empty settings, leaving the webhooks as they are, if the
+// implementation can't be built.
+func (s *IndexerService) indexerWebhookSettings(ctx context.Context, dbIndexer db.Indexer) indexer.WebhookSettings {
+	idxImpl, err := s.getOrCreateIndexerImpl(ctx, dbIndexer)
+	if err != nil {
+		log.Warn().Err(err).Str("indexerID", dbIndexer.ID.String()).Msg("Failed to build indexer for webhook config")
+		return indexer.WebhookSettings{}
+	}
+
+	webhookConfig, err := idxImpl.GetWebhookConfig(dbIndexer.ID.String())
+	if err != nil {
+		log.Warn().Err(err).Str("indexerID", dbIndexer.ID.String()).Msg("Failed to get indexer webhook config")
+		return indexer.WebhookSettings{}
+	}
+	return indexer.WebhookSettings{
+		WebhookType:      webhookConfig.WebhookType,
+		TransactionTypes: webhookConfig.TransactionTypes,
+	}
+}
+
+// indexerWebhookURL is the callback URL of the webhooks serving an indexer
+func (s *IndexerService) indexerWebhookURL(ctx context.Context, dbIndexer db.Indexer) (string, error) {
+	secret, err := s.indexerWebhookSecret(ctx, dbIndexer)
+	if err != nil {
+		return "", err
+	}
+	return s.webhookURLWithSecret(dbIndexer, secret)
 }
 
 func (s *IndexerService) GetIndexerByWebhookIDForDebug(ctx context.Context, webhookID string) (interface{}, error) {
@@ -1648,8 +1748,20 @@ func (s *IndexerService) getOrCreateIndexerImpl(ctx context.Context, dbIndexer d
 		return nil, fmt.Errorf("failed to parse indexer ID: %w", err)
 	}
 
-	if idx, ok := s.indexers[idUUID]; ok {
-		return idx, nil
+	s.indexersMu.RLock()
+	idx, ok := s.indexers[idUUID]
+	s.indexersMu.RUnlock()
+
+	if ok {
+		if implMatchesIndexer(idx, dbIndexer) {
+			return idx, nil
+		}
+
+		// The row was edited since the impl was cached, rebuild it from the current row
+		log.Warn().
+			Str("indexerID", dbIndexer.ID.String()).
+			Str("indexerType", string(dbIndexer.IndexerType)).
+			Msg("Cached indexer implementation is stale, rebuilding")
 	}
 
 	var idxImpl indexer.Indexer
@@ -1663,6 +1775,21 @@ func (s *IndexerService) getOrCreateIndexerImpl(ctx context.Context, dbIndexer d
 		idxImpl, err = indexer.NewTokenBorrowIndexer(dbIndexer.ID.String(), dbIndexer.Params)
 	case db.IndexerTypeTokenPrices:
 		idxImpl, err = indexer.NewTokenPriceIndexer(dbIndexer.ID.String(), dbIndexer.Params)
+		if tokenPriceIndexer, ok := idxImpl.(*indexer.TokenPriceIndexer); ok && s.heliusClient != nil {
+			tokenPriceIndexer.RPCURL = s.heliusClient.GetRPCURL()
+			tokenPriceIndexer.RPCTimeout = s.heliusClient.GetRPCTimeout()
+		}
+		if tokenPriceIndexer, ok := idxImpl.(*indexer.TokenPriceIndexer); ok {
+			tokenPriceIndexer.MetadataCache = s.metadataCache
+		}
+	case db.IndexerTypeTokenHolders:
+		idxImpl, err = indexer.NewTokenHolderIndexer(dbIndexer.ID.String(), dbIndexer.Params)
+		if tokenHolderIndexer, ok := idxImpl.(*indexer.TokenHolderIndexer); ok && s.heliusClient != nil {
+			tokenHolderIndexer.RPCURL = s.heliusClient.GetRPCURL()
+			tokenHolderIndexer.RPCTimeout = s.heliusClient.GetRPCTimeout()
+		}
+	case db.IndexerTypeProgramLogs:
+		idxImpl, err = indexer.NewProgramLogIndexer(dbIndexer.ID.String(), dbIndexer.Params)
 	default:
 		return nil, fmt.Errorf("unsupported indexer type: %s", dbIndexer.IndexerType)
 	}
@@ -1671,244 +1798,61 @@ func (s *IndexerService) getOrCreateIndexerImpl(ctx context.Context, dbIndexer d
 		return nil, fmt.Errorf("failed to create indexer implementation: %w", err)
 	}
 
-	s.indexers[idUUID] = idxImpl
-
-	return idxImpl, nil
-}
-
-func fetchCurrentTokenData(ctx context.Context, pool *pgxpool.Pool, targetTable string) []map[string]interface{} {
-	var tokenData []map[string]interface{}
-
-	conn, err := pool.Acquire(ctx)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to acquire connection for token data")
-		return tokenData
-	}
-	defer conn.Release()
-
-	// Format table name to ensure it's valid
-	targetTable = formatTableName(targetTable)
-
-	// Query the latest token data
-	rows, err := conn.Query(ctx, fmt.Sprintf(`
-		SELECT 
-			token_address, 
-			COALESCE(token_name, '') as token_name, 
-			COALESCE(token_symbol, '') as token_symbol, 
-			platform, 
-			price_usd, 
-			price_sol, 
-			volume_24h,
-			market_cap,
-			liquidity,
-			price_change_24h,
-			total_supply,
-			transaction_id,
-			updated_at, 
-			slot
-		FROM %s
-		ORDER BY updated_at DESC
-		LIMIT 5
-	`, targetTable))
-
-	if err != nil {
-		log.Error().Err(err).Str("table", targetTable).Msg("Failed to query token data")
-		return tokenData
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var (
-			tokenAddress   string
-			tokenName      string
-			tokenSymbol    string
-			platform       string
-			priceUSD       float64
-			priceSOL       pgtype.Float8
-			volume24h      pgtype.Float8
-			marketCap      pgtype.Float8
-			liquidity      pgtype.Float8
-			priceChange24h pgtype.Float8
-			totalSupply    pgtype.Float8
-			transactionID  pgtype.Text
-			updatedAt      time.Time
-			slot           int64
-		)
-
-		if err := rows.Scan(
-			&tokenAddress,
-			&tokenName,
-			&tokenSymbol,
-			&platform,
-			&priceUSD,
-			&priceSOL,
-			&volume24h,
-			&marketCap,
-			&liquidity,
-			&priceChange24h,
-			&totalSupply,
-			&transactionID,
-			&updatedAt,
-			&slot,
-		); err != nil {
-			log.Error().Err(err).Msg("Failed to scan token row")
-			continue
-		}
-
-		// Build token data map with all available fields
-		token := map[string]interface{}{
-			"token_address": tokenAddress,
-			"token_name":    tokenName,
-			"token_symbol":  tokenSymbol,
-			"platform":      platform,
-			"price_usd":     priceUSD,
-			"updated_at":    updatedAt.Format(time.RFC3339),
-			"slot":          slot,
-		}
-
-		// Add optional fields if they have values
-		if priceSOL.Valid {
-			token["price_sol"] = priceSOL.Float64
-		}
-		if volume24h.Valid {
-			token["volume_24h"] = volume24h.Float64
-		}
-		if marketCap.Valid {
-			token["market_cap"] = marketCap.Float64
-		}
-		if liquidity.Valid {
-			token["liquidity"] = liquidity.Float64
-		}
-		if priceChange24h.Valid {
-			token["price_change_24h"] = priceChange24h.Float64
-		}
-		if totalSupply.Valid {
-			token["total_supply"] = totalSupply.Float64
-		}
-		if transactionID.Valid {
-			token["transaction_id"] = transactionID.String
-		}
-
-		tokenData = append(tokenData, token)
+	if timed, ok := idxImpl.(interface{ SetAcquireTimeout(time.Duration) }); ok {
+		timed.SetAcquireTimeout(s.acquireTimeout)
 	}
 
-	return tokenData
-}
+	s.indexersMu.Lock()
+	defer s.indexersMu.Unlock()
 
-func enhanceGenericLogDetails(ctx context.Context, pool *pgxpool.Pool, targetTable string, details interface{}, indexerType db.IndexerType) (interface{}, error) {
-	detailsMap, ok := details.(map[string]interface{})
-	if !ok {
-		return details, fmt.Errorf("details is not a map")
+	// Another payload for the same indexer may have built one meanwhile; keep
+	// that one so every caller shares a single impl
+	if idx, ok := s.indexers[idUUID]; ok && implMatchesIndexer(idx, dbIndexer) {
+		return idx, nil
 	}
+	s.indexers[idUUID] = idxImpl
 
-	slotFloat, slotOk := detailsMap["slot"].(float64)
-
-	// Format the target table name to ensure it's valid
-	targetTable = formatTableName(targetTable)
+	return idxImpl, nil
+}
 
-	// Query the target table for data
-	var rows pgx.Rows
-	var err error
+// forgetIndexerImpl drops the cached implementation of an indexer, so the
+// next payload builds it again from the current row
+func (s *IndexerService) forgetIndexerImpl(indexerID uuid.UUID) {
+	s.indexersMu.Lock()
+	delete(s.indexers, indexerID)
+	s.indexersMu.Unlock()
+}
 
-	if slotOk {
-		slot := int64(slotFloat)
-		rows, err = pool.Query(ctx, fmt.Sprintf(`
-			SELECT 
-				id, signature, slot, block_time, 
-				nft_mint, COALESCE(nft_name, '') as nft_name, marketplace, 
-				price, currency, usd_value, 
-				seller, buyer, status, 
-				created_at, updated_at
-			FROM %s
-			WHERE slot = $1
-			ORDER BY created_at DESC
-			LIMIT 5
-		`, targetTable), slot)
-	} else {
-		// If no slot, just get recent entries
-		rows, err = pool.Query(ctx, fmt.Sprintf(`
-			SELECT 
-				id, signature, slot, block_time, 
-				nft_mint, COALESCE(nft_name, '') as nft_name, marketplace, 
-				price, currency, usd_value, 
-				seller, buyer, status, 
-				created_at, updated_at
-			FROM %s
-			ORDER BY created_at DESC
-			LIMIT 5
-		`, targetTable))
+// implTypeMatches reports whether an indexer implementation handles the given indexer type
+func implTypeMatches(idxImpl indexer.Indexer, indexerType db.IndexerType) bool {
+	switch idxImpl.(type) {
+	case *indexer.NFTBidIndexer:
+		return indexerType == db.IndexerTypeNftBids
+	case *indexer.NFTPriceIndexer:
+		return indexerType == db.IndexerTypeNftPrices
+	case *indexer.TokenBorrowIndexer:
+		return indexerType == db.IndexerTypeTokenBorrow
+	case *indexer.TokenPriceIndexer:
+		return indexerType == db.IndexerTypeTokenPrices
+	case *indexer.TokenHolderIndexer:
+		return indexerType == db.IndexerTypeTokenHolders
+	case *indexer.ProgramLogIndexer:
+		return indexerType == db.IndexerTypeProgramLogs
+	default:
+		return false
 	}
+}
 
-	if err != nil {
-		return details, fmt.Errorf("failed to query target table: %w", err)
-	}
-	defer rows.Close()
-
-	var targetData []map[string]interface{}
-	for rows.Next() {
-		var (
-			id          string
-			signature   string
-			slot        int64
-			blockTime   time.Time
-			nftMint     string
-			nftName     string
-			marketplace string
-			price       float64
-			currency    string
-			usdValue    pgtype.Float8
-			seller      string
-			buyer       pgtype.Text
-			status      string
-			createdAt   time.Time
-			updatedAt   time.Time
-		)
-
-		if err := rows.Scan(
-			&id, &signature, &slot, &blockTime,
-			&nftMint, &nftName, &marketplace,
-			&price, &currency, &usdValue,
-			&seller, &buyer, &status,
-			&createdAt, &updatedAt,
-		); err != nil {
-			log.Error().Err(err).Msg("Failed to scan row from target table")
-			continue
-		}
-
-		rowData := map[string]interface{}{
-			"id":          id,
-			"signature":   signature,
-			"slot":        slot,
-			"block_time":  blockTime.Format(time.RFC3339),
-			"nft_mint":    nftMint,
-			"nft_name":    nftName,
-			"marketplace": marketplace,
-			"price":       price,
-			"currency":    currency,
-			"seller":      seller,
-			"status":      status,
-			"created_at":  createdAt.Format(time.RFC3339),
-			"updated_at":  updatedAt.Format(time.RFC3339),
-		}
-
-		if usdValue.Valid {
-			rowData["usd_value"] = usdValue.Float64
-		} else {
-			rowData["usd_value"] = nil
-		}
-
-		if buyer.Valid {
-			rowData["buyer"] = buyer.String
-		} else {
-			rowData["buyer"] = nil
-		}
-
-		targetData = append(targetData, rowData)
+// implMatchesIndexer reports whether a cached implementation was built from the current indexer row
+func implMatchesIndexer(idxImpl indexer.Indexer, dbIndexer db.Indexer) bool {
+	if !implTypeMatches(idxImpl, dbIndexer.IndexerType) {
+		return false
 	}
 
-	if len(targetData) > 0 {
-		detailsMap["transactions"] = targetData
+	withParams, ok := idxImpl.(interface{ RawParams() json.RawMessage })
+	if !ok {
+		return false
 	}
 
-	return detailsMap, nil
+	return bytes.Equal(withParams.RawParams(), dbIndexer.Params)
 }
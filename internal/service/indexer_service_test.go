@@ -0,0 +1,361 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/rishavmehra/indexer/internal/config"
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// createStore serves the DB credential a CreateIndexer request points at,
+// and the user and active indexer count the quota is checked against
+type createStore struct {
+	db.Querier
+	cred    db.DbCredential
+	user    db.User
+	active  int64
+	indexer db.Indexer
+}
+
+func (s *createStore) GetDBCredentialByID(ctx context.Context, id pgtype.UUID) (db.DbCredential, error) {
+	return s.cred, nil
+}
+
+func (s *createStore) GetUserByID(ctx context.Context, id pgtype.UUID) (db.User, error) {
+	return s.user, nil
+}
+
+func (s *createStore) CountIndexersByUserID(ctx context.Context, userID pgtype.UUID) (int64, error) {
+	return s.active, nil
+}
+
+func (s *createStore) GetIndexerByID(ctx context.Context, id pgtype.UUID) (db.Indexer, error) {
+	return s.indexer, nil
+}
+
+func newCreateStore(userID uuid.UUID) *createStore {
+	return &createStore{cred: db.DbCredential{
+		ID:     pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		UserID: pgtype.UUID{Bytes: userID, Valid: true},
+	}, user: db.User{ID: pgtype.UUID{Bytes: userID, Valid: true}}}
+}
+
+func TestCreateIndexerRejectsReservedTablePrefix(t *testing.T) {
+	userID := uuid.New()
+	store := newCreateStore(userID)
+	s := NewIndexerService(store, nil)
+	s.SetReservedTablePrefixes([]string{"app_"})
+
+	for _, table := range []string{"pg_foo", "app_sales"} {
+		_, err := s.CreateIndexer(context.Background(), userID, models.CreateIndexerRequest{
+			DBCredentialID: uuid.UUID(store.cred.ID.Bytes),
+			IndexerType:    models.NFTBids,
+			TargetTable:    table,
+			Params:         json.RawMessage(`{}`),
+		})
+		if !errors.Is(err, ErrInvalidTargetTable) {
+			t.Errorf("CreateIndexer(%s) error = %v, want ErrInvalidTargetTable", table, err)
+		}
+	}
+}
+
+func TestCreateIndexerQuota(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     int
+		override  pgtype.Int4
+		active    int64
+		wantQuota bool
+	}{
+		{name: "under the limit", limit: 3, active: 2},
+		{name: "at the limit", limit: 3, active: 3, wantQuota: true},
+		{name: "no cap", limit: 0, active: 100},
+		{name: "user override raises the limit", limit: 3, override: pgtype.Int4{Int32: 5, Valid: true}, active: 3},
+		{name: "user override lowers the limit", limit: 3, override: pgtype.Int4{Int32: 1, Valid: true}, active: 1, wantQuota: true},
+		{name: "user override lifts the cap", limit: 3, override: pgtype.Int4{Int32: 0, Valid: true}, active: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New()
+			store := newCreateStore(userID)
+			store.user.MaxIndexers = tt.override
+			store.active = tt.active
+			s := NewIndexerService(store, nil)
+			s.SetMaxIndexersPerUser(tt.limit)
+
+			// Params without a collection fail validation right after the
+			// quota check, so a request that gets past it stops there
+			_, err := s.CreateIndexer(context.Background(), userID, models.CreateIndexerRequest{
+				DBCredentialID: uuid.UUID(store.cred.ID.Bytes),
+				IndexerType:    models.NFTBids,
+				TargetTable:    "nft_bids",
+				Params:         json.RawMessage(`{}`),
+			})
+			if err == nil {
+				t.Fatal("CreateIndexer succeeded with invalid params")
+			}
+			if got := errors.Is(err, ErrIndexerQuotaExceeded); got != tt.wantQuota {
+				t.Fatalf("CreateIndexer error = %v, quota exceeded = %v, want %v", err, got, tt.wantQuota)
+			}
+		})
+	}
+}
+
+func TestResumeIndexerChecksQuota(t *testing.T) {
+	userID := uuid.New()
+	store := newCreateStore(userID)
+	store.active = 2
+	store.indexer = db.Indexer{
+		ID:     pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		UserID: pgtype.UUID{Bytes: userID, Valid: true},
+		Status: db.IndexerStatusPaused,
+	}
+	s := NewIndexerService(store, nil)
+	s.SetMaxIndexersPerUser(2)
+
+	_, err := s.ResumeIndexer(context.Background(), userID, uuid.UUID(store.indexer.ID.Bytes))
+	if !errors.Is(err, ErrIndexerQuotaExceeded) {
+		t.Fatalf("ResumeIndexer error = %v, want ErrIndexerQuotaExceeded", err)
+	}
+}
+
+// webhookCreateStore has no per-indexer webhook secrets, so webhooks use the
+// shared one
+type webhookCreateStore struct {
+	db.Querier
+}
+
+func (s *webhookCreateStore) GetIndexerWebhookSecret(ctx context.Context, indexerID pgtype.UUID) (db.IndexerWebhookSecret, error) {
+	return db.IndexerWebhookSecret{}, pgx.ErrNoRows
+}
+
+func (s *webhookCreateStore) CreateIndexingLog(ctx context.Context, arg db.CreateIndexingLogParams) (db.IndexingLog, error) {
+	return db.IndexingLog{}, nil
+}
+
+// newWebhookServer accepts every Helius webhook request, giving created
+// webhooks IDs in turn
+func newWebhookServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	var created atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			json.NewEncoder(w).Encode(map[string]string{"webhookID": fmt.Sprintf("%s-%d", t.Name(), created.Add(1))})
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCreateHeliusWebhookFailsWhenWebhooksAreFull(t *testing.T) {
+	server := newWebhookServer(t)
+	client := indexer.NewHeliusClient(config.HeliusConfig{
+		APIKey:         "test-key",
+		APIBaseURL:     server.URL,
+		WebhookBaseURL: "http://app.local",
+		WebhookSecret:  "secret",
+		MaxWebhooks:    1,
+	})
+	s := NewIndexerService(&webhookCreateStore{}, client)
+	ctx := context.Background()
+
+	first := db.Indexer{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, IndexerType: db.IndexerTypeNftBids}
+	if _, err := s.createHeliusWebhook(ctx, first, []string{"collection-1"}); err != nil {
+		t.Fatalf("createHeliusWebhook: %v", err)
+	}
+
+	second := db.Indexer{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, IndexerType: db.IndexerTypeNftBids}
+	_, err := s.createHeliusWebhook(ctx, second, []string{"collection-2"})
+	if !errors.Is(err, ErrAddressLimitExceeded) {
+		t.Fatalf("createHeliusWebhook error = %v, want ErrAddressLimitExceeded", err)
+	}
+	if !strings.Contains(err.Error(), "1 new addresses requested but only 0 webhook address slots are free") {
+		t.Errorf("error = %q, want it to say how many addresses did not fit", err)
+	}
+}
+
+// lastErrorStore updates its indexer the way the status and last error
+// queries update the row
+type lastErrorStore struct {
+	*createStore
+	statusUpdates int
+}
+
+func (s *lastErrorStore) UpdateIndexerStatus(ctx context.Context, arg db.UpdateIndexerStatusParams) (db.Indexer, error) {
+	s.statusUpdates++
+	s.indexer.Status = arg.Status
+	s.indexer.ErrorMessage = arg.ErrorMessage
+	return s.indexer, nil
+}
+
+func (s *lastErrorStore) UpdateIndexerLastError(ctx context.Context, arg db.UpdateIndexerLastErrorParams) (db.Indexer, error) {
+	s.indexer.LastError = arg.LastError
+	s.indexer.LastErrorAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return s.indexer, nil
+}
+
+func (s *lastErrorStore) ClearIndexerLastError(ctx context.Context, id pgtype.UUID) (db.Indexer, error) {
+	s.indexer.LastError = pgtype.Text{}
+	s.indexer.LastErrorAt = pgtype.Timestamptz{}
+	return s.indexer, nil
+}
+
+func (s *lastErrorStore) CreateAuditEvent(ctx context.Context, arg db.CreateAuditEventParams) error {
+	return nil
+}
+
+func newLastErrorStore(userID uuid.UUID) *lastErrorStore {
+	store := &lastErrorStore{createStore: newCreateStore(userID)}
+	store.indexer = db.Indexer{
+		ID:             pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		UserID:         pgtype.UUID{Bytes: userID, Valid: true},
+		DbCredentialID: store.cred.ID,
+		Status:         db.IndexerStatusPaused,
+		Params:         json.RawMessage(`{}`),
+	}
+	return store
+}
+
+func TestResumeIndexerKeepsLastError(t *testing.T) {
+	userID := uuid.New()
+	store := newLastErrorStore(userID)
+	s := NewIndexerService(store, nil)
+	ctx := context.Background()
+
+	s.recordLastError(ctx, store.indexer.ID, errors.New("target table is missing"))
+
+	resumed, err := s.ResumeIndexer(ctx, userID, uuid.UUID(store.indexer.ID.Bytes))
+	if err != nil {
+		t.Fatalf("ResumeIndexer: %v", err)
+	}
+	if resumed.LastError != "target table is missing" || resumed.LastErrorAt == nil {
+		t.Errorf("lastError = %q at %v after resume, want the recorded failure kept", resumed.LastError, resumed.LastErrorAt)
+	}
+}
+
+func TestRecordLastErrorKeepsStatus(t *testing.T) {
+	userID := uuid.New()
+	store := newLastErrorStore(userID)
+	store.indexer.Status = db.IndexerStatusActive
+	s := NewIndexerService(store, nil)
+
+	s.recordLastError(context.Background(), store.indexer.ID, errors.New("insert failed"))
+
+	if store.statusUpdates != 0 || store.indexer.Status != db.IndexerStatusActive {
+		t.Errorf("status %s after %d updates, want the indexer left active", store.indexer.Status, store.statusUpdates)
+	}
+	if store.indexer.LastError.String != "insert failed" {
+		t.Errorf("last error = %q, want the failure recorded", store.indexer.LastError.String)
+	}
+}
+
+func TestClearLastError(t *testing.T) {
+	userID := uuid.New()
+	store := newLastErrorStore(userID)
+	s := NewIndexerService(store, nil)
+	ctx := context.Background()
+
+	s.recordLastError(ctx, store.indexer.ID, errors.New("insert failed"))
+
+	cleared, err := s.ClearLastError(ctx, userID, uuid.UUID(store.indexer.ID.Bytes))
+	if err != nil {
+		t.Fatalf("ClearLastError: %v", err)
+	}
+	if cleared.LastError != "" || cleared.LastErrorAt != nil {
+		t.Errorf("lastError = %q at %v, want it cleared", cleared.LastError, cleared.LastErrorAt)
+	}
+}
+
+func TestProcessWebhookPayloadLogsSuccessOnlyWhenIndexed(t *testing.T) {
+	cred, pool := testTarget(t)
+	table := testTable(t, pool)
+
+	store := newRawStore(uuid.New())
+	store.cred = cred
+	store.indexer.TargetTable = table
+	s := NewIndexerService(store, nil)
+	ctx := context.Background()
+
+	if err := s.initializeIndexer(ctx, store.indexer); err != nil {
+		t.Fatalf("initializeIndexer: %v", err)
+	}
+
+	// A transfer on a tracked account carries no NFT events
+	store.logs = nil
+	unrelated := models.HeliusWebhookPayload{
+		Slot: 9,
+		Transaction: models.HeliusTransaction{
+			Signatures:      []string{"transfer-sig"},
+			EnhancedDetails: json.RawMessage(`{"type": "TRANSFER", "timestamp": 1700000000}`),
+		},
+	}
+	if err := s.ProcessWebhookPayload(ctx, "webhook", unrelated); err != nil {
+		t.Fatalf("ProcessWebhookPayload of the transfer: %v", err)
+	}
+	if len(store.logs) != 0 {
+		t.Errorf("got %d logs for a payload that indexed nothing, want none", len(store.logs))
+	}
+
+	var sale models.HeliusWebhookPayload
+	if err := json.Unmarshal(salePayload(t, "sale-sig", 10).Body, &sale); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	if err := s.ProcessWebhookPayload(ctx, "webhook", sale); err != nil {
+		t.Fatalf("ProcessWebhookPayload of the sale: %v", err)
+	}
+	if len(store.logs) != 1 || store.logs[0].EventType != "success" {
+		t.Fatalf("logs = %+v, want one success log", store.logs)
+	}
+
+	var details map[string]interface{}
+	if err := json.Unmarshal(store.logs[0].Details, &details); err != nil {
+		t.Fatalf("unmarshal log details: %v", err)
+	}
+	if details["rows_written"] != float64(1) {
+		t.Errorf("rows_written = %v, want 1", details["rows_written"])
+	}
+}
+
+func TestTargetPoolConfigUsesConfiguredSize(t *testing.T) {
+	s := NewIndexerService(&recordingStore{}, nil)
+	cred := db.DbCredential{DbHost: "localhost", DbPort: 5432, DbUser: "user", DbPassword: "password", DbName: "indexer", DbSslMode: "disable"}
+
+	poolConfig, err := s.targetPoolConfig(cred)
+	if err != nil {
+		t.Fatalf("targetPoolConfig: %v", err)
+	}
+	if poolConfig.MaxConns != 10 || poolConfig.MinConns != 1 {
+		t.Errorf("default pool size = %d..%d, want 1..10", poolConfig.MinConns, poolConfig.MaxConns)
+	}
+
+	s.SetTargetPoolSize(4, 2)
+	poolConfig, err = s.targetPoolConfig(cred)
+	if err != nil {
+		t.Fatalf("targetPoolConfig: %v", err)
+	}
+	if poolConfig.MaxConns != 4 || poolConfig.MinConns != 2 {
+		t.Errorf("pool size = %d..%d, want 2..4", poolConfig.MinConns, poolConfig.MaxConns)
+	}
+	if poolConfig.ConnConfig.Host != "localhost" || poolConfig.ConnConfig.Database != "indexer" {
+		t.Errorf("pool connects to %s/%s, want localhost/indexer", poolConfig.ConnConfig.Host, poolConfig.ConnConfig.Database)
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+)
+
+// logsStore serves a page of indexing logs and counts how often the target
+// database credential is looked up to enrich them. The lookup fails, so
+// enriching is attempted without ever connecting.
+type logsStore struct {
+	*rawStore
+	page        []db.IndexingLog
+	limits      []int32
+	credLookups int
+}
+
+func (s *logsStore) GetIndexingLogsByIndexerID(ctx context.Context, arg db.GetIndexingLogsByIndexerIDParams) ([]db.IndexingLog, error) {
+	s.limits = append(s.limits, arg.Limit)
+	return s.page, nil
+}
+
+func (s *logsStore) GetDBCredentialByID(ctx context.Context, id pgtype.UUID) (db.DbCredential, error) {
+	s.credLookups++
+	return db.DbCredential{}, errors.New("no credential")
+}
+
+// newLogsStore returns a store whose page holds one log of each event type
+func newLogsStore(userID uuid.UUID, eventTypes ...string) *logsStore {
+	store := &logsStore{rawStore: newRawStore(userID)}
+	for i, eventType := range eventTypes {
+		details, _ := json.Marshal(map[string]interface{}{"slot": i + 1})
+		store.page = append(store.page, db.IndexingLog{
+			ID:        pgtype.UUID{Bytes: uuid.New(), Valid: true},
+			IndexerID: store.indexer.ID,
+			EventType: eventType,
+			Details:   details,
+		})
+	}
+	return store
+}
+
+func TestGetIndexingLogsClampsLimit(t *testing.T) {
+	userID := uuid.New()
+	store := newLogsStore(userID)
+	s := NewIndexerService(store, nil)
+	indexerID := uuid.UUID(store.indexer.ID.Bytes)
+	ctx := context.Background()
+
+	if _, err := s.GetIndexingLogs(ctx, userID, indexerID, 100000000, 0); err != nil {
+		t.Fatalf("GetIndexingLogs: %v", err)
+	}
+	s.SetLogLimits(50, 10)
+	for _, limit := range []int32{60, 20} {
+		if _, err := s.GetIndexingLogs(ctx, userID, indexerID, limit, 0); err != nil {
+			t.Fatalf("GetIndexingLogs: %v", err)
+		}
+	}
+
+	want := []int32{500, 50, 20}
+	if len(store.limits) != len(want) {
+		t.Fatalf("store got limits %v, want %v", store.limits, want)
+	}
+	for i := range want {
+		if store.limits[i] != want[i] {
+			t.Errorf("store got limits %v, want %v", store.limits, want)
+			break
+		}
+	}
+}
+
+func TestGetIndexingLogsSkipsEnhancementAboveThreshold(t *testing.T) {
+	userID := uuid.New()
+	store := newLogsStore(userID, "success", "success")
+	s := NewIndexerService(store, nil)
+	s.SetLogLimits(500, 10)
+	indexerID := uuid.UUID(store.indexer.ID.Bytes)
+	ctx := context.Background()
+
+	logs, err := s.GetIndexingLogs(ctx, userID, indexerID, 11, 0)
+	if err != nil {
+		t.Fatalf("GetIndexingLogs: %v", err)
+	}
+	if len(logs) != 2 {
+		t.Fatalf("got %d logs, want 2", len(logs))
+	}
+	if store.credLookups != 0 {
+		t.Errorf("page above the threshold looked up the target credential %d times, want 0", store.credLookups)
+	}
+
+	// At the threshold the page is enriched, opening the target pool once
+	if _, err := s.GetIndexingLogs(ctx, userID, indexerID, 10, 0); err != nil {
+		t.Fatalf("GetIndexingLogs: %v", err)
+	}
+	if store.credLookups != 1 {
+		t.Errorf("page at the threshold looked up the target credential %d times, want 1", store.credLookups)
+	}
+}
+
+func TestGetIndexingLogsConnectsOnlyForEnhancedLogs(t *testing.T) {
+	userID := uuid.New()
+	ctx := context.Background()
+
+	// A page of error and initialization logs has nothing to enrich
+	store := newLogsStore(userID, "error", "initialization", "error")
+	s := NewIndexerService(store, nil)
+	logs, err := s.GetIndexingLogs(ctx, userID, uuid.UUID(store.indexer.ID.Bytes), 10, 0)
+	if err != nil {
+		t.Fatalf("GetIndexingLogs: %v", err)
+	}
+	if len(logs) != 3 {
+		t.Fatalf("got %d logs, want 3", len(logs))
+	}
+	if store.credLookups != 0 {
+		t.Errorf("error-only page looked up the target credential %d times, want no connection", store.credLookups)
+	}
+
+	// The pool is opened on the first success log and not retried per log
+	store = newLogsStore(userID, "error", "success", "token_data", "success")
+	s = NewIndexerService(store, nil)
+	if _, err := s.GetIndexingLogs(ctx, userID, uuid.UUID(store.indexer.ID.Bytes), 10, 0); err != nil {
+		t.Fatalf("GetIndexingLogs: %v", err)
+	}
+	if store.credLookups != 1 {
+		t.Errorf("mixed page looked up the target credential %d times, want 1", store.credLookups)
+	}
+}
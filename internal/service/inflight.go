@@ -0,0 +1,49 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// inFlightTracker counts the payloads currently being processed per indexer
+type inFlightTracker struct {
+	mu     sync.Mutex
+	counts map[uuid.UUID]int
+}
+
+func newInFlightTracker() *inFlightTracker {
+	return &inFlightTracker{
+		counts: make(map[uuid.UUID]int),
+	}
+}
+
+// begin marks a payload for the indexer as in flight and returns the func
+// that marks it done
+func (t *inFlightTracker) begin(indexerID uuid.UUID) func() {
+	t.mu.Lock()
+	t.counts[indexerID]++
+	t.mu.Unlock()
+
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		t.counts[indexerID]--
+		if t.counts[indexerID] <= 0 {
+			delete(t.counts, indexerID)
+		}
+	}
+}
+
+// snapshot returns the in-flight payload count of every busy indexer
+func (t *inFlightTracker) snapshot() map[uuid.UUID]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := make(map[uuid.UUID]int, len(t.counts))
+	for id, count := range t.counts {
+		result[id] = count
+	}
+	return result
+}
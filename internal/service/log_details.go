@@ -0,0 +1,433 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// logDetailRows is how many target rows are attached to a log entry
+const logDetailRows = 5
+
+// logDetailScanner reads the target table rows of one indexer type that a log
+// entry refers to. Rows land in the details under key; slotFilter narrows them
+// to the log's slot when it has one.
+type logDetailScanner struct {
+	key        string
+	columns    string
+	slotFilter string
+	orderBy    string
+	scan       func(rows pgx.Rows) (map[string]interface{}, error)
+}
+
+// logDetailScannerFor returns the scanner for an indexer type. Each type
+// selects only the columns its own indexer creates, so a token borrow or NFT
+// bids table is never read with the NFT price columns. NFT tables read their
+// time column through the expression their params configure.
+func logDetailScannerFor(indexerType db.IndexerType, params json.RawMessage) (logDetailScanner, bool) {
+	switch indexerType {
+	case db.IndexerTypeTokenPrices:
+		return logDetailScanner{
+			key:        "tokens",
+			columns:    tokenPriceColumns,
+			slotFilter: "slot <= $1",
+			orderBy:    "updated_at DESC",
+			scan:       scanTokenPriceRow,
+		}, true
+	case db.IndexerTypeTokenBorrow:
+		return logDetailScanner{
+			key: "borrow_data",
+			columns: `token_address, platform, available_amount, borrow_rate,
+				supply_rate, utilization_rate, total_borrowed, total_supplied,
+				updated_at, slot`,
+			slotFilter: "slot <= $1",
+			orderBy:    "updated_at DESC",
+			scan:       scanTokenBorrowRow,
+		}, true
+	case db.IndexerTypeTokenHolders:
+		return logDetailScanner{
+			key:        "holders",
+			columns:    "mint, total_supply, decimals, holder_count, holder_count_exact, updated_at, slot",
+			slotFilter: "slot <= $1",
+			orderBy:    "updated_at DESC",
+			scan:       scanTokenHoldersRow,
+		}, true
+	case db.IndexerTypeProgramLogs:
+		return logDetailScanner{
+			key:        "instructions",
+			columns:    "signature, slot, block_time, program_id, instruction_index, account_keys, data",
+			slotFilter: "slot = $1",
+			orderBy:    "block_time DESC, instruction_index",
+			scan:       scanProgramLogRow,
+		}, true
+	case db.IndexerTypeNftPrices:
+		return logDetailScanner{
+			key: "transactions",
+			columns: fmt.Sprintf(`id, signature, slot, %s,
+				nft_mint, COALESCE(nft_name, ''), marketplace,
+				price, currency, usd_value,
+				seller, buyer, status,
+				created_at, updated_at`, indexer.TimeColumnExpr(params)),
+			slotFilter: "slot = $1",
+			orderBy:    "created_at DESC",
+			scan:       scanNFTPriceRow,
+		}, true
+	case db.IndexerTypeNftBids:
+		return logDetailScanner{
+			key: "bids",
+			columns: fmt.Sprintf(`id, signature, slot, %s,
+				nft_mint, auction_house, marketplace,
+				bidder, bid_amount, bid_currency, bid_usd_value, expiry,
+				created_at`, indexer.TimeColumnExpr(params)),
+			slotFilter: "slot = $1",
+			orderBy:    "created_at DESC",
+			scan:       scanNFTBidRow,
+		}, true
+	}
+	return logDetailScanner{}, false
+}
+
+// enhanceLogDetails attaches the target rows an indexing log refers to, read
+// with the scanner of the indexer's type. Details that already carry rows,
+// such as token_data logs, are returned unchanged.
+func enhanceLogDetails(ctx context.Context, pool *pgxpool.Pool, dbIndexer db.Indexer, details interface{}) (interface{}, error) {
+	detailsMap, ok := details.(map[string]interface{})
+	if !ok {
+		return details, fmt.Errorf("details is not a map")
+	}
+
+	scanner, ok := logDetailScannerFor(dbIndexer.IndexerType, dbIndexer.Params)
+	if !ok {
+		return detailsMap, nil
+	}
+	if detailsMap[scanner.key] != nil || detailsMap["token_data"] != nil {
+		return detailsMap, nil
+	}
+
+	var slot int64
+	if slotVal, ok := detailsMap["slot"].(float64); ok {
+		slot = int64(slotVal)
+	}
+
+	rows, err := scanner.query(ctx, pool, dbIndexer.TargetTable, slot)
+	if err != nil {
+		return details, fmt.Errorf("%s table %s: %w", dbIndexer.IndexerType, dbIndexer.TargetTable, err)
+	}
+
+	if len(rows) > 0 {
+		detailsMap[scanner.key] = rows
+	}
+	return detailsMap, nil
+}
+
+// query reads the latest rows of targetTable, limited to slot when it is set
+func (l logDetailScanner) query(ctx context.Context, pool *pgxpool.Pool, targetTable string, slot int64) ([]map[string]interface{}, error) {
+	queryStr := fmt.Sprintf("SELECT %s FROM %s", l.columns, indexer.QuoteTableName(targetTable))
+
+	var args []interface{}
+	if slot > 0 {
+		queryStr += " WHERE " + l.slotFilter
+		args = append(args, slot)
+	}
+	queryStr += fmt.Sprintf(" ORDER BY %s LIMIT %d", l.orderBy, logDetailRows)
+
+	rows, err := pool.Query(ctx, queryStr, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query target table: %w", err)
+	}
+	defer rows.Close()
+
+	var data []map[string]interface{}
+	for rows.Next() {
+		rowData, err := l.scan(rows)
+		if err != nil {
+			log.Error().Err(err).Str("key", l.key).Msg("Failed to scan row from target table")
+			continue
+		}
+		data = append(data, rowData)
+	}
+	return data, rows.Err()
+}
+
+// tokenPriceColumns are the columns scanTokenPrice reads, in its order
+const tokenPriceColumns = `token_address, token_name, token_symbol, platform,
+	price_usd, price_sol, volume_24h, market_cap, liquidity,
+	price_change_24h, total_supply, transaction_id, updated_at, slot`
+
+// scanTokenPrice reads a token price row selected with tokenPriceColumns,
+// leaving the columns without a value nil
+func scanTokenPrice(row pgx.CollectableRow) (models.TokenPriceRow, error) {
+	var token models.TokenPriceRow
+	err := row.Scan(
+		&token.TokenAddress, &token.TokenName, &token.TokenSymbol, &token.Platform,
+		&token.PriceUSD, &token.PriceSOL, &token.Volume24h, &token.MarketCap, &token.Liquidity,
+		&token.PriceChange24h, &token.TotalSupply, &token.TransactionID, &token.UpdatedAt, &token.Slot,
+	)
+	return token, err
+}
+
+func scanTokenPriceRow(rows pgx.Rows) (map[string]interface{}, error) {
+	row, err := scanTokenPrice(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	token := map[string]interface{}{
+		"token_address": row.TokenAddress,
+		"token_name":    stringValue(row.TokenName),
+		"token_symbol":  stringValue(row.TokenSymbol),
+		"platform":      row.Platform,
+		"price_usd":     row.PriceUSD,
+		"updated_at":    row.UpdatedAt.Format(time.RFC3339),
+		"slot":          row.Slot,
+	}
+
+	setFloatPtr(token, "price_sol", row.PriceSOL)
+	setFloatPtr(token, "volume_24h", row.Volume24h)
+	setFloatPtr(token, "market_cap", row.MarketCap)
+	setFloatPtr(token, "liquidity", row.Liquidity)
+	setFloatPtr(token, "price_change_24h", row.PriceChange24h)
+	setFloatPtr(token, "total_supply", row.TotalSupply)
+	if row.TransactionID != nil {
+		token["transaction_id"] = *row.TransactionID
+	}
+
+	return token, nil
+}
+
+func scanTokenBorrowRow(rows pgx.Rows) (map[string]interface{}, error) {
+	var (
+		tokenAddress    string
+		platform        string
+		availableAmount pgtype.Float8
+		borrowRate      pgtype.Float8
+		supplyRate      pgtype.Float8
+		utilizationRate pgtype.Float8
+		totalBorrowed   pgtype.Float8
+		totalSupplied   pgtype.Float8
+		updatedAt       time.Time
+		slot            int64
+	)
+
+	if err := rows.Scan(
+		&tokenAddress, &platform, &availableAmount, &borrowRate,
+		&supplyRate, &utilizationRate, &totalBorrowed, &totalSupplied,
+		&updatedAt, &slot,
+	); err != nil {
+		return nil, err
+	}
+
+	rowData := map[string]interface{}{
+		"token_address": tokenAddress,
+		"platform":      platform,
+		"updated_at":    updatedAt.Format(time.RFC3339),
+		"slot":          slot,
+	}
+
+	setFloat(rowData, "available_amount", availableAmount)
+	setFloat(rowData, "borrow_rate", borrowRate)
+	setFloat(rowData, "supply_rate", supplyRate)
+	setFloat(rowData, "utilization_rate", utilizationRate)
+	setFloat(rowData, "total_borrowed", totalBorrowed)
+	setFloat(rowData, "total_supplied", totalSupplied)
+
+	return rowData, nil
+}
+
+func scanTokenHoldersRow(rows pgx.Rows) (map[string]interface{}, error) {
+	var (
+		mint             string
+		totalSupply      pgtype.Float8
+		decimals         pgtype.Int4
+		holderCount      pgtype.Int8
+		holderCountExact bool
+		updatedAt        time.Time
+		slot             int64
+	)
+
+	if err := rows.Scan(
+		&mint, &totalSupply, &decimals, &holderCount, &holderCountExact,
+		&updatedAt, &slot,
+	); err != nil {
+		return nil, err
+	}
+
+	rowData := map[string]interface{}{
+		"mint":               mint,
+		"holder_count_exact": holderCountExact,
+		"updated_at":         updatedAt.Format(time.RFC3339),
+		"slot":               slot,
+	}
+
+	setFloat(rowData, "total_supply", totalSupply)
+	if decimals.Valid {
+		rowData["decimals"] = decimals.Int32
+	}
+	if holderCount.Valid {
+		rowData["holder_count"] = holderCount.Int64
+	}
+
+	return rowData, nil
+}
+
+func scanNFTPriceRow(rows pgx.Rows) (map[string]interface{}, error) {
+	var (
+		id          int64
+		signature   string
+		slot        int64
+		blockTime   time.Time
+		nftMint     string
+		nftName     string
+		marketplace string
+		price       float64
+		currency    string
+		usdValue    pgtype.Float8
+		seller      string
+		buyer       pgtype.Text
+		status      string
+		createdAt   time.Time
+		updatedAt   time.Time
+	)
+
+	if err := rows.Scan(
+		&id, &signature, &slot, &blockTime,
+		&nftMint, &nftName, &marketplace,
+		&price, &currency, &usdValue,
+		&seller, &buyer, &status,
+		&createdAt, &updatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	rowData := map[string]interface{}{
+		"id":          id,
+		"signature":   signature,
+		"slot":        slot,
+		"block_time":  blockTime.Format(time.RFC3339),
+		"nft_mint":    nftMint,
+		"nft_name":    nftName,
+		"marketplace": marketplace,
+		"price":       price,
+		"currency":    currency,
+		"usd_value":   nil,
+		"seller":      seller,
+		"buyer":       nil,
+		"status":      status,
+		"created_at":  createdAt.Format(time.RFC3339),
+		"updated_at":  updatedAt.Format(time.RFC3339),
+	}
+
+	setFloat(rowData, "usd_value", usdValue)
+	if buyer.Valid {
+		rowData["buyer"] = buyer.String
+	}
+
+	return rowData, nil
+}
+
+func scanNFTBidRow(rows pgx.Rows) (map[string]interface{}, error) {
+	var (
+		id           int64
+		signature    string
+		slot         int64
+		blockTime    time.Time
+		nftMint      string
+		auctionHouse pgtype.Text
+		marketplace  string
+		bidder       string
+		bidAmount    float64
+		bidCurrency  string
+		bidUsdValue  pgtype.Float8
+		expiry       pgtype.Timestamptz
+		createdAt    time.Time
+	)
+
+	if err := rows.Scan(
+		&id, &signature, &slot, &blockTime,
+		&nftMint, &auctionHouse, &marketplace,
+		&bidder, &bidAmount, &bidCurrency, &bidUsdValue, &expiry,
+		&createdAt,
+	); err != nil {
+		return nil, err
+	}
+
+	rowData := map[string]interface{}{
+		"signature":    signature,
+		"slot":         slot,
+		"block_time":   blockTime.Format(time.RFC3339),
+		"nft_mint":     nftMint,
+		"marketplace":  marketplace,
+		"bidder":       bidder,
+		"bid_amount":   bidAmount,
+		"bid_currency": bidCurrency,
+		"created_at":   createdAt.Format(time.RFC3339),
+	}
+
+	if auctionHouse.Valid {
+		rowData["auction_house"] = auctionHouse.String
+	}
+	setFloat(rowData, "bid_usd_value", bidUsdValue)
+	if expiry.Valid {
+		rowData["expiry"] = expiry.Time.Format(time.RFC3339)
+	}
+
+	return rowData, nil
+}
+
+func scanProgramLogRow(rows pgx.Rows) (map[string]interface{}, error) {
+	var (
+		signature        string
+		slot             int64
+		blockTime        time.Time
+		programID        string
+		instructionIndex int32
+		accountKeys      json.RawMessage
+		data             json.RawMessage
+	)
+
+	if err := rows.Scan(
+		&signature, &slot, &blockTime, &programID, &instructionIndex, &accountKeys, &data,
+	); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"signature":         signature,
+		"slot":              slot,
+		"block_time":        blockTime.Format(time.RFC3339),
+		"program_id":        programID,
+		"instruction_index": instructionIndex,
+		"account_keys":      accountKeys,
+		"data":              data,
+	}, nil
+}
+
+// setFloat sets key when value is not NULL
+func setFloat(rowData map[string]interface{}, key string, value pgtype.Float8) {
+	if value.Valid {
+		rowData[key] = value.Float64
+	}
+}
+
+func setFloatPtr(rowData map[string]interface{}, key string, value *float64) {
+	if value != nil {
+		rowData[key] = *value
+	}
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+)
+
+var allIndexerTypes = []db.IndexerType{
+	db.IndexerTypeNftBids,
+	db.IndexerTypeNftPrices,
+	db.IndexerTypeTokenBorrow,
+	db.IndexerTypeTokenPrices,
+	db.IndexerTypeTokenHolders,
+	db.IndexerTypeProgramLogs,
+}
+
+func TestLogDetailScannerForEveryType(t *testing.T) {
+	keys := make(map[string]db.IndexerType)
+	for _, indexerType := range allIndexerTypes {
+		scanner, ok := logDetailScannerFor(indexerType, json.RawMessage(`{}`))
+		if !ok {
+			t.Errorf("no log detail scanner for %s", indexerType)
+			continue
+		}
+		if other, taken := keys[scanner.key]; taken {
+			t.Errorf("%s and %s both attach rows under %q", indexerType, other, scanner.key)
+		}
+		keys[scanner.key] = indexerType
+	}
+
+	if _, ok := logDetailScannerFor("unknown", nil); ok {
+		t.Error("got a log detail scanner for an unknown indexer type")
+	}
+}
+
+func TestEnhanceLogDetailsKeepsAttachedRows(t *testing.T) {
+	dbIndexer := db.Indexer{IndexerType: db.IndexerTypeNftPrices, TargetTable: "sales"}
+
+	// Neither needs the target table, so no pool is given
+	for _, key := range []string{"transactions", "token_data"} {
+		details := map[string]interface{}{"slot": float64(7), key: []interface{}{"row"}}
+		enhanced, err := enhanceLogDetails(context.Background(), nil, dbIndexer, details)
+		if err != nil {
+			t.Fatalf("enhanceLogDetails with %s: %v", key, err)
+		}
+		if rows := enhanced.(map[string]interface{})[key].([]interface{}); len(rows) != 1 {
+			t.Errorf("%s = %v, want the attached rows kept", key, rows)
+		}
+	}
+
+	if _, err := enhanceLogDetails(context.Background(), nil, dbIndexer, "not a map"); err == nil {
+		t.Error("enhanceLogDetails accepted details that are not a map")
+	}
+}
+
+// logDetailCase is an indexer type with one row seeded at slot 7
+type logDetailCase struct {
+	indexerType db.IndexerType
+	params      string
+	insert      string
+	key         string
+	field       string
+	want        interface{}
+}
+
+var logDetailCases = []logDetailCase{
+	{
+		indexerType: db.IndexerTypeNftPrices,
+		params:      `{"collection": "collection"}`,
+		insert: `INSERT INTO %s (signature, slot, block_time, nft_mint, marketplace, price, seller, status)
+			VALUES ('sale-sig', 7, NOW(), 'mint', 'MAGIC_EDEN', 2.5, 'seller', 'sold')`,
+		key:   "transactions",
+		field: "signature",
+		want:  "sale-sig",
+	},
+	{
+		indexerType: db.IndexerTypeNftBids,
+		params:      `{"collection": "collection"}`,
+		insert: `INSERT INTO %s (signature, slot, block_time, nft_mint, marketplace, bidder, bid_amount)
+			VALUES ('bid-sig', 7, NOW(), 'mint', 'TENSOR', 'bidder', 1.5)`,
+		key:   "bids",
+		field: "bidder",
+		want:  "bidder",
+	},
+	{
+		indexerType: db.IndexerTypeTokenPrices,
+		params:      `{"tokens": ["token"]}`,
+		insert: `INSERT INTO %s (token_address, platform, price_usd, slot)
+			VALUES ('token', 'JUPITER', 0.99, 7)`,
+		key:   "tokens",
+		field: "token_address",
+		want:  "token",
+	},
+	{
+		indexerType: db.IndexerTypeTokenHolders,
+		params:      `{"tokens": ["token"]}`,
+		insert: `INSERT INTO %s (mint, holder_count, slot)
+			VALUES ('token', 42, 7)`,
+		key:   "holders",
+		field: "holder_count",
+		want:  int64(42),
+	},
+	{
+		indexerType: db.IndexerTypeProgramLogs,
+		params:      `{"programId": "program"}`,
+		insert: `INSERT INTO %s (signature, slot, block_time, program_id, instruction_index, account_keys, data)
+			VALUES ('program-sig', 7, NOW(), 'program', 0, '[]', '{}')`,
+		key:   "instructions",
+		field: "program_id",
+		want:  "program",
+	},
+}
+
+// seedLogDetailTable creates the target table of tt's indexer type and seeds
+// its row
+func seedLogDetailTable(t *testing.T, pool *pgxpool.Pool, tt logDetailCase) db.Indexer {
+	t.Helper()
+
+	dbIndexer := db.Indexer{
+		ID:          pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		IndexerType: tt.indexerType,
+		Params:      json.RawMessage(tt.params),
+		TargetTable: testTable(t, pool),
+	}
+
+	idx, err := NewIndexerService(nil, nil).getOrCreateIndexerImpl(context.Background(), dbIndexer)
+	if err != nil {
+		t.Fatalf("create %s indexer: %v", tt.indexerType, err)
+	}
+	initializeTable(t, pool, idx, dbIndexer.TargetTable)
+
+	if _, err := pool.Exec(context.Background(), fmt.Sprintf(tt.insert, indexer.QuoteTableName(dbIndexer.TargetTable))); err != nil {
+		t.Fatalf("seed %s: %v", dbIndexer.TargetTable, err)
+	}
+	return dbIndexer
+}
+
+func TestEnhanceLogDetailsPerType(t *testing.T) {
+	_, pool := testTarget(t)
+
+	for _, tt := range logDetailCases {
+		t.Run(string(tt.indexerType), func(t *testing.T) {
+			dbIndexer := seedLogDetailTable(t, pool, tt)
+
+			enhanced, err := enhanceLogDetails(context.Background(), pool, dbIndexer, map[string]interface{}{"slot": float64(7)})
+			if err != nil {
+				t.Fatalf("enhanceLogDetails: %v", err)
+			}
+
+			rows, ok := enhanced.(map[string]interface{})[tt.key].([]map[string]interface{})
+			if !ok || len(rows) != 1 {
+				t.Fatalf("%s = %v, want the seeded row", tt.key, enhanced)
+			}
+			if got := rows[0][tt.field]; got != tt.want {
+				t.Errorf("%s = %v (%T), want %v", tt.field, got, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnhanceLogDetailsSkipsOtherSlots(t *testing.T) {
+	_, pool := testTarget(t)
+	tt := logDetailCases[0]
+	dbIndexer := seedLogDetailTable(t, pool, tt)
+
+	enhanced, err := enhanceLogDetails(context.Background(), pool, dbIndexer, map[string]interface{}{"slot": float64(8)})
+	if err != nil {
+		t.Fatalf("enhanceLogDetails: %v", err)
+	}
+	if rows, ok := enhanced.(map[string]interface{})[tt.key]; ok {
+		t.Errorf("%s = %v for a log at another slot, want none", tt.key, rows)
+	}
+}
+
+func TestTokenBorrowScannerReadsBorrowColumns(t *testing.T) {
+	scanner, _ := logDetailScannerFor(db.IndexerTypeTokenBorrow, nil)
+	for _, column := range []string{"nft_mint", "seller", "buyer", "status"} {
+		if strings.Contains(scanner.columns, column) {
+			t.Errorf("token borrow scanner selects NFT price column %s", column)
+		}
+	}
+}
+
+func TestEnhanceLogDetailsTokenBorrow(t *testing.T) {
+	_, pool := testTarget(t)
+	ctx := context.Background()
+	dbIndexer := db.Indexer{IndexerType: db.IndexerTypeTokenBorrow, TargetTable: testTable(t, pool)}
+	table := indexer.QuoteTableName(dbIndexer.TargetTable)
+
+	// The columns the token borrow indexer upserts
+	if _, err := pool.Exec(ctx, fmt.Sprintf(`
+		CREATE TABLE %s (
+			token_address TEXT NOT NULL,
+			platform TEXT NOT NULL,
+			available_amount NUMERIC,
+			borrow_rate NUMERIC,
+			supply_rate NUMERIC,
+			utilization_rate NUMERIC,
+			total_borrowed NUMERIC,
+			total_supplied NUMERIC,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			slot BIGINT NOT NULL,
+			UNIQUE(token_address, platform)
+		)
+	`, table)); err != nil {
+		t.Fatalf("create borrow table: %v", err)
+	}
+	if _, err := pool.Exec(ctx, fmt.Sprintf(`
+		INSERT INTO %s (token_address, platform, borrow_rate, slot) VALUES ('token', 'SOLEND', 0.05, 7)
+	`, table)); err != nil {
+		t.Fatalf("seed borrow table: %v", err)
+	}
+
+	enhanced, err := enhanceLogDetails(ctx, pool, dbIndexer, map[string]interface{}{"slot": float64(7)})
+	if err != nil {
+		t.Fatalf("enhanceLogDetails: %v", err)
+	}
+	rows, ok := enhanced.(map[string]interface{})["borrow_data"].([]map[string]interface{})
+	if !ok || len(rows) != 1 || rows[0]["borrow_rate"] != 0.05 {
+		t.Errorf("borrow_data = %v, want the seeded borrow rate", enhanced)
+	}
+}
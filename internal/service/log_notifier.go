@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+)
+
+// LogNotifier signals waiters when a new indexing log is written for an indexer
+type LogNotifier struct {
+	mu      sync.Mutex
+	waiters map[uuid.UUID]chan struct{}
+}
+
+// NewLogNotifier creates an empty notifier
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{
+		waiters: make(map[uuid.UUID]chan struct{}),
+	}
+}
+
+// Wait returns a channel that is closed the next time a log is written for
+// the indexer. Callers should obtain the channel before checking for logs so
+// that a write between the check and the wait is not missed.
+func (n *LogNotifier) Wait(indexerID uuid.UUID) <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	ch, ok := n.waiters[indexerID]
+	if !ok {
+		ch = make(chan struct{})
+		n.waiters[indexerID] = ch
+	}
+	return ch
+}
+
+// Notify wakes everyone waiting on the indexer
+func (n *LogNotifier) Notify(indexerID uuid.UUID) {
+	n.mu.Lock()
+	ch, ok := n.waiters[indexerID]
+	delete(n.waiters, indexerID)
+	n.mu.Unlock()
+
+	if ok {
+		close(ch)
+	}
+}
+
+// notifyingStore wraps a Querier and fires the notifier after every log write
+type notifyingStore struct {
+	db.Querier
+	notifier *LogNotifier
+}
+
+func (s *notifyingStore) CreateIndexingLog(ctx context.Context, arg db.CreateIndexingLogParams) (db.IndexingLog, error) {
+	entry, err := s.Querier.CreateIndexingLog(ctx, arg)
+	if err == nil {
+		if indexerID, parseErr := uuid.Parse(arg.IndexerID.String()); parseErr == nil {
+			s.notifier.Notify(indexerID)
+		}
+	}
+	return entry, err
+}
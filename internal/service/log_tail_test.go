@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+)
+
+// logStore serves the logs of one indexer the way GetIndexingLogsSince does,
+// ordered by creation time and ID
+type logStore struct {
+	db.Querier
+	indexer db.Indexer
+	mu      sync.Mutex
+	logs    []db.IndexingLog
+}
+
+func (s *logStore) GetIndexerByID(ctx context.Context, id pgtype.UUID) (db.Indexer, error) {
+	return s.indexer, nil
+}
+
+func (s *logStore) GetIndexingLogsSince(ctx context.Context, arg db.GetIndexingLogsSinceParams) ([]db.IndexingLog, error) {
+	after := func(l db.IndexingLog) bool {
+		if !l.CreatedAt.Time.Equal(arg.AfterCreatedAt.Time) {
+			return l.CreatedAt.Time.After(arg.AfterCreatedAt.Time)
+		}
+		return uuid.UUID(l.ID.Bytes).String() > uuid.UUID(arg.AfterID.Bytes).String()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var logs []db.IndexingLog
+	for _, l := range s.logs {
+		if after(l) && int32(len(logs)) < arg.Limit {
+			logs = append(logs, l)
+		}
+	}
+	return logs, nil
+}
+
+func (s *logStore) CreateIndexingLog(ctx context.Context, arg db.CreateIndexingLogParams) (db.IndexingLog, error) {
+	entry := db.IndexingLog{
+		ID:        pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		IndexerID: arg.IndexerID,
+		EventType: arg.EventType,
+		Message:   arg.Message,
+		CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+
+	s.mu.Lock()
+	s.logs = append(s.logs, entry)
+	s.mu.Unlock()
+	return entry, nil
+}
+
+func newLogStore(userID, indexerID uuid.UUID) *logStore {
+	return &logStore{indexer: db.Indexer{
+		ID:     pgtype.UUID{Bytes: indexerID, Valid: true},
+		UserID: pgtype.UUID{Bytes: userID, Valid: true},
+	}}
+}
+
+func TestTailIndexingLogsWakesOnNewLog(t *testing.T) {
+	userID := uuid.New()
+	indexerID := uuid.New()
+	s := NewIndexerService(newLogStore(userID, indexerID), nil)
+	ctx := context.Background()
+	since := time.Now().Add(-time.Second)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		s.store.CreateIndexingLog(ctx, db.CreateIndexingLogParams{
+			IndexerID: pgtype.UUID{Bytes: indexerID, Valid: true},
+			EventType: "success",
+			Message:   "indexed",
+		})
+	}()
+
+	start := time.Now()
+	tail, err := s.TailIndexingLogs(ctx, userID, indexerID, since, uuid.Nil, 10*time.Second, 10)
+	if err != nil {
+		t.Fatalf("TailIndexingLogs: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("TailIndexingLogs returned after %s, want it woken by the new log", elapsed)
+	}
+	if len(tail.Logs) != 1 || tail.Logs[0].Message != "indexed" {
+		t.Errorf("logs = %+v, want the new log", tail.Logs)
+	}
+	if tail.CursorID == nil || !tail.Cursor.After(since) {
+		t.Errorf("cursor = %s / %v, want it moved to the new log", tail.Cursor, tail.CursorID)
+	}
+}
+
+func TestTailIndexingLogsTimesOutWithoutLogs(t *testing.T) {
+	userID := uuid.New()
+	indexerID := uuid.New()
+	s := NewIndexerService(newLogStore(userID, indexerID), nil)
+	since := time.Now()
+
+	start := time.Now()
+	tail, err := s.TailIndexingLogs(context.Background(), userID, indexerID, since, uuid.Nil, 30*time.Millisecond, 10)
+	if err != nil {
+		t.Fatalf("TailIndexingLogs: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("TailIndexingLogs returned after %s, before the wait ran out", elapsed)
+	}
+	if len(tail.Logs) != 0 || !tail.Cursor.Equal(since) || tail.CursorID != nil {
+		t.Errorf("tail = %+v, want no logs and the cursor left where it was", tail)
+	}
+}
+
+func TestTailIndexingLogsPagesThroughSharedTimestamp(t *testing.T) {
+	userID := uuid.New()
+	indexerID := uuid.New()
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	store := newLogStore(userID, indexerID)
+	// Three logs written in the same transaction share created_at
+	for _, id := range []string{
+		"00000000-0000-0000-0000-000000000001",
+		"00000000-0000-0000-0000-000000000002",
+		"00000000-0000-0000-0000-000000000003",
+	} {
+		store.logs = append(store.logs, db.IndexingLog{
+			ID:        pgtype.UUID{Bytes: uuid.MustParse(id), Valid: true},
+			IndexerID: pgtype.UUID{Bytes: indexerID, Valid: true},
+			EventType: "success",
+			CreatedAt: pgtype.Timestamptz{Time: at, Valid: true},
+		})
+	}
+	s := NewIndexerService(store, nil)
+	ctx := context.Background()
+
+	first, err := s.TailIndexingLogs(ctx, userID, indexerID, at.Add(-time.Second), uuid.Nil, 0, 2)
+	if err != nil {
+		t.Fatalf("TailIndexingLogs: %v", err)
+	}
+	if len(first.Logs) != 2 || first.CursorID == nil {
+		t.Fatalf("first page has %d logs and cursor ID %v, want 2 and a cursor ID", len(first.Logs), first.CursorID)
+	}
+
+	second, err := s.TailIndexingLogs(ctx, userID, indexerID, first.Cursor, *first.CursorID, 0, 2)
+	if err != nil {
+		t.Fatalf("TailIndexingLogs: %v", err)
+	}
+	if len(second.Logs) != 1 || second.Logs[0].ID != uuid.MustParse("00000000-0000-0000-0000-000000000003") {
+		t.Errorf("second page = %+v, want the third log sharing the timestamp", second.Logs)
+	}
+
+	// A cursor without an ID treats every log at its timestamp as seen
+	third, err := s.TailIndexingLogs(ctx, userID, indexerID, first.Cursor, uuid.Nil, 0, 2)
+	if err != nil {
+		t.Fatalf("TailIndexingLogs: %v", err)
+	}
+	if len(third.Logs) != 0 {
+		t.Errorf("got %d logs after a cursor without ID, want none", len(third.Logs))
+	}
+}
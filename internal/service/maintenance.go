@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrMaintenance is returned for work refused while maintenance mode is on
+var ErrMaintenance = errors.New("indexing is paused for maintenance")
+
+// MaintenanceStatus describes whether indexing is paused and why
+type MaintenanceStatus struct {
+	Enabled bool       `json:"enabled"`
+	Reason  string     `json:"reason,omitempty"`
+	Since   *time.Time `json:"since,omitempty"`
+}
+
+// Maintenance is a process-wide switch that pauses all indexing without
+// touching indexer state
+type Maintenance struct {
+	mu      sync.Mutex
+	enabled bool
+	reason  string
+	since   time.Time
+	// resumed is closed when maintenance is switched off
+	resumed chan struct{}
+}
+
+// NewMaintenance creates the switch with maintenance mode off
+func NewMaintenance() *Maintenance {
+	return &Maintenance{}
+}
+
+// Enabled reports whether indexing is paused
+func (m *Maintenance) Enabled() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.enabled
+}
+
+// Status returns the current state of the switch
+func (m *Maintenance) Status() MaintenanceStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := MaintenanceStatus{Enabled: m.enabled, Reason: m.reason}
+	if m.enabled {
+		since := m.since
+		status.Since = &since
+	}
+	return status
+}
+
+// Set turns maintenance mode on or off. Turning it off releases everything
+// blocked in Wait.
+func (m *Maintenance) Set(enabled bool, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if enabled == m.enabled {
+		if enabled {
+			m.reason = reason
+		}
+		return
+	}
+
+	m.enabled = enabled
+	if enabled {
+		m.reason = reason
+		m.since = time.Now()
+		m.resumed = make(chan struct{})
+
+		log.Warn().
+			Str("reason", reason).
+			Msg("Maintenance mode enabled, webhooks are rejected with 503 and indexing is paused")
+		return
+	}
+
+	log.Warn().
+		Dur("pausedFor", time.Since(m.since)).
+		Msg("Maintenance mode disabled, indexing resumes as Helius redelivers webhooks")
+
+	m.reason = ""
+	close(m.resumed)
+}
+
+// Wait blocks while maintenance mode is on, returning early if ctx is done
+func (m *Maintenance) Wait(ctx context.Context) error {
+	m.mu.Lock()
+	if !m.enabled {
+		m.mu.Unlock()
+		return nil
+	}
+	resumed := m.resumed
+	m.mu.Unlock()
+
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceWaitBlocksUntilDisabled(t *testing.T) {
+	m := NewMaintenance()
+	m.Set(true, "migration")
+
+	done := make(chan error, 1)
+	go func() { done <- m.Wait(context.Background()) }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Wait returned %v while maintenance was on", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	m.Set(false, "")
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Wait: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wait still blocked after maintenance was switched off")
+	}
+}
+
+func TestMaintenanceWaitReturnsOnCancel(t *testing.T) {
+	m := NewMaintenance()
+	m.Set(true, "incident")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := m.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait error = %v, want context.DeadlineExceeded", err)
+	}
+}
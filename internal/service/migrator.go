@@ -0,0 +1,115 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+
+	"github.com/rishavmehra/indexer/pkg/logger"
+)
+
+// MigrationStatus is the schema version of the control database
+type MigrationStatus struct {
+	Version uint `json:"version"`
+	// Dirty means a migration failed part way and has to be fixed by hand
+	// before migrations can run again
+	Dirty bool `json:"dirty"`
+	// Applied is false when no migration has run yet
+	Applied bool `json:"applied"`
+}
+
+// Migrator runs the control database migrations from sourceURL
+type Migrator struct {
+	sourceURL   string
+	databaseURL string
+}
+
+// NewMigrator returns a Migrator applying the migrations at sourceURL to the
+// database at databaseURL
+func NewMigrator(sourceURL, databaseURL string) *Migrator {
+	return &Migrator{
+		sourceURL:   sourceURL,
+		databaseURL: databaseURL,
+	}
+}
+
+// Up applies every pending migration
+func (m *Migrator) Up() error {
+	instance, err := m.open()
+	if err != nil {
+		return err
+	}
+	defer instance.Close()
+
+	if err := instance.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	return nil
+}
+
+// Down rolls back the last steps migrations
+func (m *Migrator) Down(steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("steps must be positive, got %d", steps)
+	}
+
+	instance, err := m.open()
+	if err != nil {
+		return err
+	}
+	defer instance.Close()
+
+	if err := instance.Steps(-steps); err != nil {
+		return fmt.Errorf("failed to roll back migrations: %w", err)
+	}
+	return nil
+}
+
+// Force sets the recorded version without running any migration and clears
+// the dirty flag, after a failed migration was fixed by hand
+func (m *Migrator) Force(version int) error {
+	instance, err := m.open()
+	if err != nil {
+		return err
+	}
+	defer instance.Close()
+
+	if err := instance.Force(version); err != nil {
+		return fmt.Errorf("failed to force migration version: %w", err)
+	}
+	return nil
+}
+
+// Version reports the migration the database is at
+func (m *Migrator) Version() (MigrationStatus, error) {
+	instance, err := m.open()
+	if err != nil {
+		return MigrationStatus{}, err
+	}
+	defer instance.Close()
+
+	version, dirty, err := instance.Version()
+	if err != nil {
+		if errors.Is(err, migrate.ErrNilVersion) {
+			return MigrationStatus{}, nil
+		}
+		return MigrationStatus{}, fmt.Errorf("failed to read migration version: %w", err)
+	}
+
+	return MigrationStatus{
+		Version: version,
+		Dirty:   dirty,
+		Applied: true,
+	}, nil
+}
+
+func (m *Migrator) open() (*migrate.Migrate, error) {
+	instance, err := migrate.New(m.sourceURL, m.databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration instance: %w", logger.RedactError(err))
+	}
+	return instance, nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+)
+
+type purgeStore struct {
+	db.Querier
+	finishedBefore time.Time
+	receivedBefore time.Time
+}
+
+func (s *purgeStore) DeleteFinishedPayloadJobs(ctx context.Context, finishedBefore pgtype.Timestamptz) (int64, error) {
+	s.finishedBefore = finishedBefore.Time
+	return 3, nil
+}
+
+func (s *purgeStore) DeleteRawPayloadsBefore(ctx context.Context, receivedBefore pgtype.Timestamptz) (int64, error) {
+	s.receivedBefore = receivedBefore.Time
+	return 5, nil
+}
+
+func TestPurgeFinishedPayloadJobsUsesRetention(t *testing.T) {
+	store := &purgeStore{}
+	s := NewIndexerService(store, nil)
+	s.SetPayloadJobRetention(2 * time.Hour)
+
+	purged, err := s.PurgeFinishedPayloadJobs(context.Background())
+	if err != nil {
+		t.Fatalf("PurgeFinishedPayloadJobs: %v", err)
+	}
+	if purged != 3 {
+		t.Errorf("purged %d jobs, want 3", purged)
+	}
+	if age := time.Since(store.finishedBefore); age < 2*time.Hour || age > 2*time.Hour+time.Minute {
+		t.Errorf("deleted jobs finished before %v ago, want 2h", age)
+	}
+}
+
+func TestPurgeRawPayloadsUsesRetention(t *testing.T) {
+	store := &purgeStore{}
+	s := NewIndexerService(store, nil)
+	s.SetRawPayloadRetention(48 * time.Hour)
+
+	purged, err := s.PurgeRawPayloads(context.Background())
+	if err != nil {
+		t.Fatalf("PurgeRawPayloads: %v", err)
+	}
+	if purged != 5 {
+		t.Errorf("purged %d raw payloads, want 5", purged)
+	}
+	if age := time.Since(store.receivedBefore); age < 48*time.Hour || age > 48*time.Hour+time.Minute {
+		t.Errorf("deleted raw payloads received before %v ago, want 48h", age)
+	}
+}
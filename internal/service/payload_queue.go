@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// stalePayloadJobAge is how long a payload may stay claimed before it is
+// assumed lost with a crashed worker and handed out again
+const stalePayloadJobAge = 5 * time.Minute
+
+// PayloadJob is a webhook payload claimed from a PayloadQueue
+type PayloadJob struct {
+	ID        int64
+	RequestID string
+	WebhookID string
+	Payload   models.HeliusWebhookPayload
+	// Attempts counts the tries so far, including the current one
+	Attempts int
+}
+
+// PayloadQueue holds webhook payloads until a worker processes them. A claimed
+// payload is settled with exactly one of Complete, Retry or Fail.
+type PayloadQueue interface {
+	Enqueue(ctx context.Context, requestID, webhookID string, payloads []models.HeliusWebhookPayload) error
+	// Dequeue claims the next payload that is due, reporting false when there
+	// is none
+	Dequeue(ctx context.Context) (PayloadJob, bool, error)
+	Complete(ctx context.Context, job PayloadJob) error
+	// Retry puts a payload back to be claimed again from retryAt
+	Retry(ctx context.Context, job PayloadJob, cause error, retryAt time.Time) error
+	// Fail gives up on a payload
+	Fail(ctx context.Context, job PayloadJob, cause error) error
+}
+
+type memoryPayloadJob struct {
+	job     PayloadJob
+	claimed bool
+	dueAt   time.Time
+}
+
+// memoryPayloadQueue keeps payloads in process. It retries like the Postgres
+// queue but loses whatever is pending when the server stops.
+type memoryPayloadQueue struct {
+	mu     sync.Mutex
+	jobs   []*memoryPayloadJob
+	nextID int64
+}
+
+// NewMemoryPayloadQueue returns a PayloadQueue held in memory
+func NewMemoryPayloadQueue() PayloadQueue {
+	return &memoryPayloadQueue{}
+}
+
+func (q *memoryPayloadQueue) Enqueue(ctx context.Context, requestID, webhookID string, payloads []models.HeliusWebhookPayload) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	for _, payload := range payloads {
+		q.nextID++
+		q.jobs = append(q.jobs, &memoryPayloadJob{
+			job: PayloadJob{
+				ID:        q.nextID,
+				RequestID: requestID,
+				WebhookID: webhookID,
+				Payload:   payload,
+			},
+			dueAt: now,
+		})
+	}
+	return nil
+}
+
+func (q *memoryPayloadQueue) Dequeue(ctx context.Context) (PayloadJob, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	var next *memoryPayloadJob
+	for _, j := range q.jobs {
+		if j.claimed || j.dueAt.After(now) {
+			continue
+		}
+		if next == nil || j.dueAt.Before(next.dueAt) {
+			next = j
+		}
+	}
+	if next == nil {
+		return PayloadJob{}, false, nil
+	}
+
+	next.claimed = true
+	next.job.Attempts++
+	return next.job, true, nil
+}
+
+func (q *memoryPayloadQueue) Complete(ctx context.Context, job PayloadJob) error {
+	q.remove(job.ID)
+	return nil
+}
+
+func (q *memoryPayloadQueue) Retry(ctx context.Context, job PayloadJob, cause error, retryAt time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, j := range q.jobs {
+		if j.job.ID == job.ID {
+			j.claimed = false
+			j.dueAt = retryAt
+			return nil
+		}
+	}
+	return fmt.Errorf("payload job %d not found", job.ID)
+}
+
+func (q *memoryPayloadQueue) Fail(ctx context.Context, job PayloadJob, cause error) error {
+	q.remove(job.ID)
+	return nil
+}
+
+func (q *memoryPayloadQueue) remove(id int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, j := range q.jobs {
+		if j.job.ID == id {
+			q.jobs = append(q.jobs[:i], q.jobs[i+1:]...)
+			return
+		}
+	}
+}
+
+// postgresPayloadQueue keeps payloads in the payload_jobs table, so they
+// survive a crash or restart. Several servers can share it: a payload is
+// claimed by one worker at a time.
+type postgresPayloadQueue struct {
+	store db.Querier
+
+	mu          sync.Mutex
+	lastRequeue time.Time
+}
+
+// NewPostgresPayloadQueue returns a PayloadQueue stored through store
+func NewPostgresPayloadQueue(store db.Querier) PayloadQueue {
+	return &postgresPayloadQueue{store: store}
+}
+
+func (q *postgresPayloadQueue) Enqueue(ctx context.Context, requestID, webhookID string, payloads []models.HeliusWebhookPayload) error {
+	for _, payload := range payloads {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal payload: %w", err)
+		}
+
+		if err := q.store.CreatePayloadJob(ctx, db.CreatePayloadJobParams{
+			WebhookID: webhookID,
+			RequestID: requestID,
+			Body:      body,
+		}); err != nil {
+			return fmt.Errorf("failed to enqueue payload: %w", err)
+		}
+	}
+	return nil
+}
+
+func (q *postgresPayloadQueue) Dequeue(ctx context.Context) (PayloadJob, bool, error) {
+	q.requeueStale(ctx)
+
+	row, err := q.store.ClaimPayloadJob(ctx)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return PayloadJob{}, false, nil
+		}
+		return PayloadJob{}, false, fmt.Errorf("failed to claim payload job: %w", err)
+	}
+
+	job := PayloadJob{
+		ID:        row.ID,
+		RequestID: row.RequestID,
+		WebhookID: row.WebhookID,
+		Attempts:  int(row.Attempts),
+	}
+	if err := json.Unmarshal(row.Body, &job.Payload); err != nil {
+		// A body that doesn't decode never will, so it isn't retried
+		if failErr := q.Fail(ctx, job, err); failErr != nil {
+			log.Error().Err(failErr).Int64("jobID", job.ID).Msg("Failed to mark undecodable payload job failed")
+		}
+		return PayloadJob{}, false, fmt.Errorf("failed to unmarshal payload job %d: %w", job.ID, err)
+	}
+
+	return job, true, nil
+}
+
+func (q *postgresPayloadQueue) Complete(ctx context.Context, job PayloadJob) error {
+	return q.store.CompletePayloadJob(ctx, job.ID)
+}
+
+func (q *postgresPayloadQueue) Retry(ctx context.Context, job PayloadJob, cause error, retryAt time.Time) error {
+	return q.store.RetryPayloadJob(ctx, db.RetryPayloadJobParams{
+		ID:          job.ID,
+		LastError:   pgtype.Text{String: cause.Error(), Valid: true},
+		NextRetryAt: pgtype.Timestamptz{Time: retryAt, Valid: true},
+	})
+}
+
+func (q *postgresPayloadQueue) Fail(ctx context.Context, job PayloadJob, cause error) error {
+	return q.store.FailPayloadJob(ctx, db.FailPayloadJobParams{
+		ID:        job.ID,
+		LastError: pgtype.Text{String: cause.Error(), Valid: true},
+	})
+}
+
+// requeueStale hands out again, at most once a minute, payloads whose worker
+// went away without settling them
+func (q *postgresPayloadQueue) requeueStale(ctx context.Context) {
+	q.mu.Lock()
+	if time.Since(q.lastRequeue) < time.Minute {
+		q.mu.Unlock()
+		return
+	}
+	q.lastRequeue = time.Now()
+	q.mu.Unlock()
+
+	requeued, err := q.store.RequeueStalePayloadJobs(ctx, pgtype.Timestamptz{
+		Time:  time.Now().Add(-stalePayloadJobAge),
+		Valid: true,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to requeue stale payload jobs")
+		return
+	}
+	if requeued > 0 {
+		log.Warn().Int64("requeued", requeued).Msg("Requeued payload jobs abandoned by a worker")
+	}
+}
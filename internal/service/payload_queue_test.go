@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/rishavmehra/indexer/internal/config"
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+func signedPayload(signature string, slot int64) models.HeliusWebhookPayload {
+	return models.HeliusWebhookPayload{
+		Slot:        slot,
+		Transaction: models.HeliusTransaction{Signatures: []string{signature}},
+	}
+}
+
+func TestMemoryPayloadQueueEnqueueDequeue(t *testing.T) {
+	q := NewMemoryPayloadQueue()
+	ctx := context.Background()
+
+	if err := q.Enqueue(ctx, "req-1", "webhook", []models.HeliusWebhookPayload{signedPayload("sig-1", 1), signedPayload("sig-2", 2)}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	var jobs []PayloadJob
+	for {
+		job, ok, err := q.Dequeue(ctx)
+		if err != nil {
+			t.Fatalf("Dequeue: %v", err)
+		}
+		if !ok {
+			break
+		}
+		jobs = append(jobs, job)
+	}
+
+	if len(jobs) != 2 {
+		t.Fatalf("dequeued %d jobs, want 2", len(jobs))
+	}
+	for i, job := range jobs {
+		if job.Payload.Slot != int64(i+1) || job.RequestID != "req-1" || job.WebhookID != "webhook" || job.Attempts != 1 {
+			t.Errorf("job %d = %+v, want slot %d claimed for the first time", i, job, i+1)
+		}
+	}
+
+	// A completed or failed job is gone for good
+	if err := q.Complete(ctx, jobs[0]); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if err := q.Fail(ctx, jobs[1], errors.New("broken")); err != nil {
+		t.Fatalf("Fail: %v", err)
+	}
+	if err := q.Retry(ctx, jobs[1], errors.New("broken"), time.Now()); err == nil {
+		t.Error("Retry of a failed job succeeded")
+	}
+	if _, ok, _ := q.Dequeue(ctx); ok {
+		t.Error("settled job dequeued again")
+	}
+}
+
+func TestMemoryPayloadQueueRetry(t *testing.T) {
+	q := NewMemoryPayloadQueue()
+	ctx := context.Background()
+
+	if err := q.Enqueue(ctx, "req-1", "webhook", []models.HeliusWebhookPayload{signedPayload("sig-1", 1)}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	job, ok, _ := q.Dequeue(ctx)
+	if !ok {
+		t.Fatal("enqueued job not dequeued")
+	}
+	if _, ok, _ := q.Dequeue(ctx); ok {
+		t.Fatal("claimed job dequeued twice")
+	}
+
+	if err := q.Retry(ctx, job, errors.New("target down"), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Retry: %v", err)
+	}
+	if _, ok, _ := q.Dequeue(ctx); ok {
+		t.Fatal("job dequeued before its retry was due")
+	}
+
+	if err := q.Retry(ctx, job, errors.New("target down"), time.Now()); err != nil {
+		t.Fatalf("Retry: %v", err)
+	}
+	retried, ok, _ := q.Dequeue(ctx)
+	if !ok || retried.ID != job.ID || retried.Attempts != 2 {
+		t.Errorf("got %+v, %v; want job %d back on its second attempt", retried, ok, job.ID)
+	}
+}
+
+// jobStore hands out one payload job from ClaimPayloadJob and records how it
+// is settled
+type jobStore struct {
+	db.Querier
+	job    db.PayloadJob
+	failed []db.FailPayloadJobParams
+}
+
+func (s *jobStore) RequeueStalePayloadJobs(ctx context.Context, staleBefore pgtype.Timestamptz) (int64, error) {
+	return 0, nil
+}
+
+func (s *jobStore) ClaimPayloadJob(ctx context.Context) (db.PayloadJob, error) {
+	return s.job, nil
+}
+
+func (s *jobStore) FailPayloadJob(ctx context.Context, arg db.FailPayloadJobParams) error {
+	s.failed = append(s.failed, arg)
+	return nil
+}
+
+func TestPostgresPayloadQueueDequeue(t *testing.T) {
+	body, _ := json.Marshal(signedPayload("sig-1", 7))
+	store := &jobStore{job: db.PayloadJob{ID: 3, WebhookID: "webhook", RequestID: "req-1", Body: body, Attempts: 2}}
+	q := NewPostgresPayloadQueue(store)
+
+	job, ok, err := q.Dequeue(context.Background())
+	if err != nil || !ok {
+		t.Fatalf("Dequeue = %v, %v", ok, err)
+	}
+	if job.ID != 3 || job.Attempts != 2 || job.Payload.Slot != 7 || job.Payload.Transaction.Signatures[0] != "sig-1" {
+		t.Errorf("job = %+v, want the claimed row decoded", job)
+	}
+}
+
+func TestPostgresPayloadQueueFailsUndecodableJob(t *testing.T) {
+	store := &jobStore{job: db.PayloadJob{ID: 3, Body: json.RawMessage(`{"slot": "seven"}`), Attempts: 1}}
+	q := NewPostgresPayloadQueue(store)
+
+	if _, ok, err := q.Dequeue(context.Background()); ok || err == nil {
+		t.Errorf("Dequeue = %v, %v; want the undecodable job reported", ok, err)
+	}
+	if len(store.failed) != 1 || store.failed[0].ID != 3 {
+		t.Errorf("failed jobs = %+v, want job 3 failed without a retry", store.failed)
+	}
+}
+
+// flakyStore serves its indexer with params no implementation can be built
+// from for the first failLookups lookups, so those payloads fail fast
+type flakyStore struct {
+	*rawStore
+	failLookups int32
+	lookups     atomic.Int32
+}
+
+func (s *flakyStore) GetIndexerByWebhookID(ctx context.Context, webhookID pgtype.Text) (db.Indexer, error) {
+	found := s.indexer
+	if s.lookups.Add(1) <= s.failLookups {
+		found.Params = json.RawMessage(`{}`)
+	}
+	return found, nil
+}
+
+// newQueueService returns a service whose indexer has already processed
+// signature, so processing it succeeds without a target database
+func newQueueService(store *flakyStore, signature string) *IndexerService {
+	s := NewIndexerService(store, nil)
+	s.SetDedupWindow(time.Hour)
+	s.dedup.claim(uuid.UUID(store.indexer.ID.Bytes), signature)
+	return s
+}
+
+// waitFor polls cond until it holds or the test times out
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestQueuedPayloadRetriedAfterFailure(t *testing.T) {
+	store := &flakyStore{rawStore: newRawStore(uuid.New()), failLookups: 1}
+	s := newQueueService(store, "sig-1")
+	d := NewWebhookDispatcher(s, config.WebhookConfig{
+		MaxConcurrency:    1,
+		MaxAttempts:       3,
+		Queue:             "memory",
+		QueuePollInterval: 10 * time.Millisecond,
+	})
+
+	accepted, err := d.Dispatch(context.Background(), "webhook", []models.HeliusWebhookPayload{signedPayload("sig-1", 1)})
+	if err != nil || accepted != 1 {
+		t.Fatalf("Dispatch = %d, %v; want the payload enqueued", accepted, err)
+	}
+
+	waitFor(t, "the retried payload", func() bool { return d.Stats().Processed == 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+
+	stats := d.Stats()
+	if stats.Failed != 1 || stats.Retried != 1 || stats.GivenUp != 0 {
+		t.Errorf("stats = %+v, want one failure retried to success", stats)
+	}
+	if store.lookups.Load() != 2 {
+		t.Errorf("payload processed %d times, want 2", store.lookups.Load())
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/rishavmehra/indexer/internal/config"
+	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/pkg/logger"
+)
+
+const (
+	// queueRetryBaseDelay is the wait before the first retry of a queued
+	// payload, doubled for each retry after it
+	queueRetryBaseDelay = time.Second
+	// queueRetryMaxDelay caps the wait between retries of a queued payload
+	queueRetryMaxDelay = 5 * time.Minute
+	// queueSettleTimeout bounds recording the outcome of a queued payload,
+	// which happens even while the dispatcher is shutting down
+	queueSettleTimeout = 5 * time.Second
+)
+
+// startJobWorkers switches the dispatcher to jobs and starts its workers
+func (d *WebhookDispatcher) startJobWorkers(jobs PayloadQueue, workers int, cfg config.WebhookConfig) {
+	d.jobs = jobs
+	d.pollInterval = cfg.QueuePollInterval
+	if d.pollInterval <= 0 {
+		d.pollInterval = time.Second
+	}
+	d.wakeup = make(chan struct{}, 1)
+	d.quit = make(chan struct{})
+
+	d.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go d.workJobs()
+	}
+}
+
+// dispatchJobs enqueues payloads for the workers. Once Dispatch returns the
+// payloads are the queue's to keep, so the webhook can be acknowledged.
+func (d *WebhookDispatcher) dispatchJobs(ctx context.Context, requestID, webhookID string, payloads []models.HeliusWebhookPayload) (int, error) {
+	if err := d.jobs.Enqueue(ctx, requestID, webhookID, payloads); err != nil {
+		log.Error().Ctx(ctx).Err(err).Str("webhookID", webhookID).Msg("Failed to enqueue webhook payloads")
+		return 0, fmt.Errorf("failed to enqueue webhook payloads: %w", err)
+	}
+
+	select {
+	case d.wakeup <- struct{}{}:
+	default:
+	}
+	return len(payloads), nil
+}
+
+// workJobs processes queued payloads until the dispatcher drains, sleeping
+// for the poll interval whenever nothing is due
+func (d *WebhookDispatcher) workJobs() {
+	defer d.wg.Done()
+
+	for {
+		select {
+		case <-d.quit:
+			return
+		case <-d.ctx.Done():
+			return
+		default:
+		}
+
+		job, ok, err := d.jobs.Dequeue(d.ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to take payload from the queue")
+		}
+		if !ok {
+			timer := time.NewTimer(d.pollInterval)
+			select {
+			case <-d.quit:
+				timer.Stop()
+				return
+			case <-d.ctx.Done():
+				timer.Stop()
+				return
+			case <-d.wakeup:
+				timer.Stop()
+			case <-timer.C:
+			}
+			continue
+		}
+
+		d.inFlight.Add(1)
+		err = d.process(job.RequestID, job.WebhookID, job.Payload)
+		d.inFlight.Add(-1)
+		d.settle(job, err)
+	}
+}
+
+// settle records the outcome of a queued payload: done, back in the queue
+// after a backoff, or failed and moved to failed_payloads once it has used up
+// its attempts. A payload cut short by shutdown goes straight back without
+// counting against it, and one for an inactive indexer is failed at once.
+func (d *WebhookDispatcher) settle(job PayloadJob, processErr error) {
+	ctx, cancel := context.WithTimeout(logger.WithRequestID(context.Background(), job.RequestID), queueSettleTimeout)
+	defer cancel()
+
+	logError := func(err error, msg string) {
+		log.Error().Ctx(ctx).
+			Err(err).
+			Int64("jobID", job.ID).
+			Str("webhookID", job.WebhookID).
+			Int("attempts", job.Attempts).
+			Msg(msg)
+	}
+
+	if processErr == nil {
+		if err := d.jobs.Complete(ctx, job); err != nil {
+			logError(err, "Failed to mark payload job done")
+		}
+		return
+	}
+
+	if d.ctx.Err() != nil {
+		if err := d.jobs.Retry(ctx, job, processErr, time.Now()); err != nil {
+			logError(err, "Failed to requeue interrupted payload job")
+		}
+		return
+	}
+
+	if !retryablePayloadError(processErr) {
+		if err := d.jobs.Fail(ctx, job, processErr); err != nil {
+			logError(err, "Failed to mark payload job failed")
+		}
+		return
+	}
+
+	if job.Attempts >= d.maxAttempts {
+		d.givenUp.Add(1)
+		if err := d.jobs.Fail(ctx, job, processErr); err != nil {
+			logError(err, "Failed to mark payload job failed")
+			return
+		}
+		logError(processErr, "Payload job failed on its last attempt, giving up")
+		d.indexerService.recordFailedPayload(ctx, job.WebhookID, job.Payload, job.Attempts, processErr)
+		return
+	}
+
+	delay := queueRetryDelay(job.Attempts)
+	d.retried.Add(1)
+	if err := d.jobs.Retry(ctx, job, processErr, time.Now().Add(delay)); err != nil {
+		logError(err, "Failed to requeue payload job")
+		return
+	}
+	log.Warn().Ctx(ctx).
+		Err(processErr).
+		Int64("jobID", job.ID).
+		Int("attempts", job.Attempts).
+		Dur("retryIn", delay).
+		Msg("Payload job failed, retrying")
+}
+
+// queueRetryDelay returns the wait before retrying a payload that has failed
+// the given number of attempts
+func queueRetryDelay(attempts int) time.Duration {
+	delay := queueRetryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= queueRetryMaxDelay {
+			return queueRetryMaxDelay
+		}
+	}
+	return delay
+}
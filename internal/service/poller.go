@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/pkg/logger"
+	"github.com/rishavmehra/indexer/pkg/validator"
+)
+
+// pollTimeout bounds one pass over the indexers that are due a poll
+const pollTimeout = 2 * time.Minute
+
+// indexerPollInterval returns the pollInterval param of an indexer, zero when
+// it doesn't poll
+func indexerPollInterval(params json.RawMessage) time.Duration {
+	var options models.IndexerOptions
+	if err := json.Unmarshal(params, &options); err != nil || options.PollInterval == "" {
+		return 0
+	}
+	interval, err := time.ParseDuration(options.PollInterval)
+	if err != nil {
+		return 0
+	}
+	return interval
+}
+
+// maxPollInterval returns how long a pollInterval may be for the dedup to
+// still skip what the webhook delivered: a delivered transaction is polled at
+// most a poll interval plus a poll check after it was processed. Zero or less
+// means no interval is short enough.
+func (s *IndexerService) maxPollInterval() time.Duration {
+	return s.dedup.length() - s.pollCheckInterval
+}
+
+// ValidatePollInterval checks the pollInterval param of params against the
+// dedup window, on top of what the validator checks
+func (s *IndexerService) ValidatePollInterval(params json.RawMessage) error {
+	var options models.IndexerOptions
+	if err := json.Unmarshal(params, &options); err != nil {
+		return nil
+	}
+	return validator.ValidatePollIntervalLimit(options.PollInterval, s.maxPollInterval())
+}
+
+// indexerPoll is the poll state of one indexer
+type indexerPoll struct {
+	lastPolled time.Time
+	// uncovered is set once the indexer was skipped for a pollInterval the
+	// dedup window doesn't cover, so that is logged only once
+	uncovered bool
+	// newest is the newest signature seen for each address; transactions
+	// after it are the ones the next poll processes
+	newest map[string]string
+}
+
+// indexerPoller polls the Helius transactions API for indexers with a
+// pollInterval, so payloads are still indexed while webhooks are down. Its
+// state lives in memory: after a restart the first poll of each indexer only
+// records where the history stands.
+type indexerPoller struct {
+	service *IndexerService
+	polls   map[uuid.UUID]*indexerPoll
+}
+
+// poll processes the new transactions of every active indexer whose
+// pollInterval has passed since its last poll
+func (p *indexerPoller) poll(ctx context.Context) {
+	if p.service.maintenance.Enabled() || p.service.heliusClient == nil {
+		return
+	}
+
+	indexers, err := p.service.store.GetActiveIndexers(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list indexers to poll")
+		return
+	}
+
+	active := make(map[uuid.UUID]bool, len(indexers))
+	for _, foundIndexer := range indexers {
+		interval := indexerPollInterval(foundIndexer.Params)
+		if interval <= 0 {
+			continue
+		}
+		idUUID, err := uuid.Parse(foundIndexer.ID.String())
+		if err != nil {
+			continue
+		}
+		active[idUUID] = true
+
+		state, found := p.polls[idUUID]
+		if !found {
+			state = &indexerPoll{newest: make(map[string]string)}
+			p.polls[idUUID] = state
+		}
+
+		// The dedup window may have been shortened since the indexer was
+		// created; polling it now would process delivered transactions again
+		if maxInterval := p.service.maxPollInterval(); interval >= maxInterval {
+			if !state.uncovered {
+				log.Warn().
+					Str("indexerID", foundIndexer.ID.String()).
+					Dur("pollInterval", interval).
+					Dur("maxPollInterval", max(maxInterval, 0)).
+					Msg("Not polling indexer, its pollInterval is longer than the webhook dedup window covers")
+				state.uncovered = true
+			}
+			continue
+		}
+		state.uncovered = false
+
+		if time.Since(state.lastPolled) < interval {
+			continue
+		}
+		state.lastPolled = time.Now()
+
+		addresses := indexerAddresses(models.IndexerType(foundIndexer.IndexerType), foundIndexer.Params)
+		for _, address := range addresses {
+			if err := p.pollAddress(ctx, foundIndexer, state, address); err != nil {
+				log.Warn().Err(err).
+					Str("indexerID", foundIndexer.ID.String()).
+					Str("address", address).
+					Msg("Failed to poll address transactions")
+			}
+		}
+	}
+
+	// Indexers that were paused, deleted or stopped polling start over
+	for idUUID := range p.polls {
+		if !active[idUUID] {
+			delete(p.polls, idUUID)
+		}
+	}
+}
+
+// pollAddress processes the transactions of an address newer than the last
+// one seen, oldest first. The first poll of an address only records its
+// newest transaction. Transactions the webhook already delivered are skipped
+// by the signature dedup.
+func (p *indexerPoller) pollAddress(ctx context.Context, foundIndexer db.Indexer, state *indexerPoll, address string) error {
+	transactions, err := p.service.heliusClient.GetAddressTransactions(ctx, address, "", indexer.MaxAddressTransactionsLimit)
+	if err != nil {
+		return logger.RedactError(err)
+	}
+
+	var payloads []models.HeliusWebhookPayload
+	for _, txData := range transactions {
+		payload, ok := TransactionPayload(txData)
+		if !ok {
+			continue
+		}
+		payloads = append(payloads, payload)
+	}
+	if len(payloads) == 0 {
+		return nil
+	}
+
+	newest, seen := state.newest[address]
+	if !seen {
+		state.newest[address] = payloads[0].Transaction.ID
+		return nil
+	}
+
+	unseen := len(payloads)
+	for i, payload := range payloads {
+		if payload.Transaction.ID == newest {
+			unseen = i
+			break
+		}
+	}
+	if unseen == len(payloads) {
+		log.Warn().
+			Str("indexerID", foundIndexer.ID.String()).
+			Str("address", address).
+			Int("transactions", len(payloads)).
+			Msg("Last polled transaction not in the latest page, older transactions may be missing")
+	}
+
+	for i := unseen - 1; i >= 0; i-- {
+		if err := p.service.processIndexerPayload(ctx, foundIndexer, "", payloads[i]); err != nil {
+			return err
+		}
+		state.newest[address] = payloads[i].Transaction.ID
+	}
+	return nil
+}
+
+// StartPoller checks every interval for indexers due a poll until the
+// returned stop function is called
+func (s *IndexerService) StartPoller(interval time.Duration) (stop func()) {
+	s.pollCheckInterval = interval
+	done := make(chan struct{})
+	var once sync.Once
+
+	poller := &indexerPoller{
+		service: s,
+		polls:   make(map[uuid.UUID]*indexerPoll),
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), pollTimeout)
+				poller.poll(ctx)
+				cancel()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
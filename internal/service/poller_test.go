@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+)
+
+// pollStore lists its indexer as the only active one, or none once it is
+// paused
+type pollStore struct {
+	*rawStore
+}
+
+func (s *pollStore) GetActiveIndexers(ctx context.Context) ([]db.Indexer, error) {
+	if s.indexer.Status != db.IndexerStatusActive {
+		return nil, nil
+	}
+	return []db.Indexer{s.indexer}, nil
+}
+
+// pollFixture is an indexer polling the history on every pass. Its
+// credential points at a database that refuses connections, so a payload
+// the dedup lets through fails at the target database and is counted by the
+// latency tracker.
+func pollFixture(t *testing.T, history *historyServer) (*indexerPoller, *pollStore, uuid.UUID) {
+	t.Helper()
+
+	store := &pollStore{rawStore: newRawStore(uuid.New())}
+	store.indexer.Params = json.RawMessage(`{"collection": "collection", "pollInterval": "1ns"}`)
+	store.cred = unreachableCredential(t)
+
+	s := NewIndexerService(store, newAuditClient(t, history))
+	s.SetDedupWindow(time.Hour)
+	poller := &indexerPoller{service: s, polls: make(map[uuid.UUID]*indexerPoll)}
+	return poller, store, uuid.UUID(store.indexer.ID.Bytes)
+}
+
+// arrive adds transactions to the history, oldest first
+func (h *historyServer) arrive(signatures ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, signature := range signatures {
+		h.signatures = append([]string{signature}, h.signatures...)
+	}
+}
+
+func TestPollFirstPassOnlyRecordsNewest(t *testing.T) {
+	history := newHistoryServer(5)
+	poller, _, indexerID := pollFixture(t, history)
+
+	poller.poll(context.Background())
+
+	if attempts := poller.service.latency.Snapshot(indexerID).Count; attempts != 0 {
+		t.Errorf("first poll processed %d transactions, want none", attempts)
+	}
+	if newest := poller.polls[indexerID].newest["collection"]; newest != "sig-0" {
+		t.Errorf("recorded newest %q, want sig-0", newest)
+	}
+}
+
+func TestPollSkipsTransactionsAlreadyDelivered(t *testing.T) {
+	history := newHistoryServer(5)
+	poller, _, indexerID := pollFixture(t, history)
+	ctx := context.Background()
+
+	poller.poll(ctx)
+
+	// The webhook delivered both new transactions before the poll
+	history.arrive("delivered-1", "delivered-2")
+	poller.service.dedup.claim(indexerID, "delivered-1")
+	poller.service.dedup.claim(indexerID, "delivered-2")
+
+	poller.poll(ctx)
+
+	if attempts := poller.service.latency.Snapshot(indexerID).Count; attempts != 0 {
+		t.Errorf("poll processed %d transactions the webhook already delivered, want none", attempts)
+	}
+	if newest := poller.polls[indexerID].newest["collection"]; newest != "delivered-2" {
+		t.Errorf("recorded newest %q, want delivered-2", newest)
+	}
+}
+
+func TestPollProcessesMissedTransactions(t *testing.T) {
+	history := newHistoryServer(5)
+	poller, _, indexerID := pollFixture(t, history)
+	ctx := context.Background()
+
+	poller.poll(ctx)
+
+	// The webhook delivered the older transaction but missed the newer one
+	history.arrive("delivered", "missed")
+	poller.service.dedup.claim(indexerID, "delivered")
+
+	poller.poll(ctx)
+
+	if attempts := poller.service.latency.Snapshot(indexerID).Count; attempts != 1 {
+		t.Errorf("poll processed %d transactions, want only the missed one", attempts)
+	}
+	// Processing the missed transaction failed, so the next poll retries it
+	if newest := poller.polls[indexerID].newest["collection"]; newest != "delivered" {
+		t.Errorf("recorded newest %q, want delivered so missed is polled again", newest)
+	}
+
+	poller.poll(ctx)
+	if attempts := poller.service.latency.Snapshot(indexerID).Count; attempts != 2 {
+		t.Errorf("after polling again %d transactions processed, want missed retried once", attempts)
+	}
+}
+
+func TestPollSkipsPausedIndexers(t *testing.T) {
+	history := newHistoryServer(5)
+	poller, store, indexerID := pollFixture(t, history)
+	ctx := context.Background()
+
+	poller.poll(ctx)
+	requests := len(history.requestedBefores())
+
+	store.indexer.Status = db.IndexerStatusPaused
+	history.arrive("while-paused")
+	poller.poll(ctx)
+
+	if got := len(history.requestedBefores()); got != requests {
+		t.Errorf("paused indexer polled %d more times", got-requests)
+	}
+	if _, found := poller.polls[indexerID]; found {
+		t.Error("paused indexer kept its poll state, want it to start over when resumed")
+	}
+	if attempts := poller.service.latency.Snapshot(indexerID).Count; attempts != 0 {
+		t.Errorf("paused indexer processed %d transactions", attempts)
+	}
+}
+
+func TestPollSkipsIndexersWithoutPollInterval(t *testing.T) {
+	history := newHistoryServer(5)
+	poller, store, _ := pollFixture(t, history)
+	store.indexer.Params = json.RawMessage(`{"collection": "collection"}`)
+
+	poller.poll(context.Background())
+
+	if got := len(history.requestedBefores()); got != 0 {
+		t.Errorf("indexer without a pollInterval polled %d times", got)
+	}
+}
+
+func TestPollSkipsIndexersTheDedupWindowDoesNotCover(t *testing.T) {
+	history := newHistoryServer(5)
+	poller, store, indexerID := pollFixture(t, history)
+	store.indexer.Params = json.RawMessage(`{"collection": "collection", "pollInterval": "2h"}`)
+	ctx := context.Background()
+
+	poller.poll(ctx)
+	history.arrive("delivered-1")
+	poller.poll(ctx)
+
+	if newest := poller.polls[indexerID].newest["collection"]; newest != "" {
+		t.Errorf("polled an indexer whose pollInterval outlasts the dedup window (newest %q)", newest)
+	}
+
+	// Turning the dedup off stops polling altogether
+	store.indexer.Params = json.RawMessage(`{"collection": "collection", "pollInterval": "1ns"}`)
+	poller.service.SetDedupWindow(0)
+	poller.poll(ctx)
+	if newest := poller.polls[indexerID].newest["collection"]; newest != "" {
+		t.Errorf("polled an indexer with the dedup off (newest %q)", newest)
+	}
+}
+
+func TestValidatePollIntervalAgainstDedupWindow(t *testing.T) {
+	s := NewIndexerService(&rawStore{}, nil)
+	s.SetDedupWindow(10 * time.Minute)
+	s.pollCheckInterval = time.Minute
+
+	tests := []struct {
+		params  string
+		wantErr bool
+	}{
+		{params: `{"collection": "collection"}`},
+		{params: `{"collection": "collection", "pollInterval": "5m"}`},
+		// A delivered transaction is polled up to a poll check later
+		{params: `{"collection": "collection", "pollInterval": "9m30s"}`, wantErr: true},
+		{params: `{"collection": "collection", "pollInterval": "1h"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := s.ValidatePollInterval(json.RawMessage(tt.params)); (err != nil) != tt.wantErr {
+			t.Errorf("ValidatePollInterval(%s) = %v, want error %v", tt.params, err, tt.wantErr)
+		}
+	}
+
+	s.SetDedupWindow(0)
+	if err := s.ValidatePollInterval(json.RawMessage(`{"pollInterval": "5m"}`)); err == nil {
+		t.Error("ValidatePollInterval accepted polling with the dedup off")
+	}
+}
+
+func TestUpdateIndexerParamsRejectsPollIntervalOutsideDedupWindow(t *testing.T) {
+	userID := uuid.New()
+	store := newRawStore(userID)
+	s := NewIndexerService(store, nil)
+	s.SetDedupWindow(10 * time.Minute)
+
+	_, err := s.UpdateIndexerParams(context.Background(), userID, uuid.UUID(store.indexer.ID.Bytes), json.RawMessage(`{"collection": "J1S9H3QjnRtBbbuD4HjPV6RpRhwuk4KKbxsnCHuTgh9w", "pollInterval": "1h"}`))
+	if !errors.Is(err, ErrInvalidIndexerParams) || !strings.Contains(err.Error(), "pollInterval must be shorter") {
+		t.Errorf("UpdateIndexerParams error = %v, want the pollInterval rejected", err)
+	}
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/pkg/logger"
+)
+
+// preflightTimeout bounds all preflight checks against the target database
+const preflightTimeout = 10 * time.Second
+
+// ErrPreflightFailed is returned when the target database of a new indexer
+// cannot be used with the given credential
+var ErrPreflightFailed = errors.New("target database preflight failed")
+
+// PreflightError names the preflight check that failed
+type PreflightError struct {
+	Check string
+	Err   error
+}
+
+func (e *PreflightError) Error() string {
+	return fmt.Sprintf("%s: %s check failed: %v", ErrPreflightFailed, e.Check, e.Err)
+}
+
+func (e *PreflightError) Unwrap() []error {
+	return []error{ErrPreflightFailed, e.Err}
+}
+
+// preflightTarget checks, without changing anything, that cred can reach its
+// database and write targetTable: the server accepts writes, and the user can
+// either create the table in the public schema or insert into and update the
+// existing one. It runs before an indexer has any side effects such as a
+// Helius webhook.
+func preflightTarget(ctx context.Context, cred db.DbCredential, targetTable string) error {
+	ctx, cancel := context.WithTimeout(ctx, preflightTimeout)
+	defer cancel()
+
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cred.DbHost, cred.DbPort, cred.DbUser, cred.DbPassword, cred.DbName, cred.DbSslMode)
+
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return &PreflightError{Check: "connect", Err: logger.RedactError(err)}
+	}
+	defer conn.Close(context.Background())
+
+	if err := conn.Ping(ctx); err != nil {
+		return &PreflightError{Check: "connect", Err: logger.RedactError(err)}
+	}
+
+	var readOnly string
+	if err := conn.QueryRow(ctx, "SHOW transaction_read_only").Scan(&readOnly); err != nil {
+		return &PreflightError{Check: "read_only", Err: err}
+	}
+	if readOnly == "on" {
+		return &PreflightError{Check: "read_only", Err: errors.New("database only accepts read-only transactions")}
+	}
+
+	targetTable = indexer.QuoteTableName(targetTable)
+
+	var exists bool
+	err = conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", "public."+targetTable).Scan(&exists)
+	if err != nil {
+		return &PreflightError{Check: "table", Err: err}
+	}
+
+	if !exists {
+		var canCreate bool
+		err := conn.QueryRow(ctx, "SELECT has_schema_privilege(current_user, 'public', 'CREATE')").Scan(&canCreate)
+		if err != nil {
+			return &PreflightError{Check: "create_table", Err: err}
+		}
+		if !canCreate {
+			return &PreflightError{
+				Check: "create_table",
+				Err:   fmt.Errorf("user %s cannot create tables in schema public", cred.DbUser),
+			}
+		}
+		return nil
+	}
+
+	var canInsert, canUpdate bool
+	err = conn.QueryRow(ctx, `
+		SELECT has_table_privilege(current_user, $1, 'INSERT'),
+			has_table_privilege(current_user, $1, 'UPDATE')
+	`, "public."+targetTable).Scan(&canInsert, &canUpdate)
+	if err != nil {
+		return &PreflightError{Check: "write_table", Err: err}
+	}
+	if !canInsert || !canUpdate {
+		return &PreflightError{
+			Check: "write_table",
+			Err:   fmt.Errorf("user %s cannot insert into and update existing table %s", cred.DbUser, targetTable),
+		}
+	}
+
+	return nil
+}
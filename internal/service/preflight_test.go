@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// unreachableCredential points at a local port nothing listens on
+func unreachableCredential(t *testing.T) db.DbCredential {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	return db.DbCredential{
+		DbHost:     "127.0.0.1",
+		DbPort:     int32(port),
+		DbName:     "indexer",
+		DbUser:     "indexer",
+		DbPassword: "hunter2",
+		DbSslMode:  "disable",
+	}
+}
+
+// readOnlyRole creates a login role whose transactions are read-only by
+// default and returns cred rewritten to connect as it
+func readOnlyRole(t *testing.T, pool *pgxpool.Pool, cred db.DbCredential) db.DbCredential {
+	t.Helper()
+	ctx := context.Background()
+
+	role := fmt.Sprintf("test_reader_%d", time.Now().UnixNano())
+	if _, err := pool.Exec(ctx, fmt.Sprintf("CREATE ROLE %s LOGIN PASSWORD 'reader'", role)); err != nil {
+		t.Skipf("cannot create a test role: %v", err)
+	}
+	t.Cleanup(func() {
+		pool.Exec(context.Background(), "DROP OWNED BY "+role)
+		pool.Exec(context.Background(), "DROP ROLE IF EXISTS "+role)
+	})
+
+	cred.DbUser = role
+	cred.DbPassword = "reader"
+	return cred
+}
+
+func TestPreflightRejectsUnreachableDatabase(t *testing.T) {
+	err := preflightTarget(context.Background(), unreachableCredential(t), "sales")
+
+	var preflightErr *PreflightError
+	if !errors.As(err, &preflightErr) || preflightErr.Check != "connect" {
+		t.Fatalf("preflightTarget error = %v, want the connect check to fail", err)
+	}
+	if !errors.Is(err, ErrPreflightFailed) {
+		t.Error("preflight error does not wrap ErrPreflightFailed")
+	}
+}
+
+func TestCreateIndexerStopsAtPreflight(t *testing.T) {
+	userID := uuid.New()
+	store := newCreateStore(userID)
+	cred := unreachableCredential(t)
+	cred.ID, cred.UserID = store.cred.ID, store.cred.UserID
+	store.cred = cred
+	s := NewIndexerService(store, nil)
+
+	// createStore has no CreateIndexer, so getting past the preflight would
+	// panic rather than leave a failed indexer behind
+	_, err := s.CreateIndexer(context.Background(), userID, models.CreateIndexerRequest{
+		DBCredentialID: uuid.UUID(store.cred.ID.Bytes),
+		IndexerType:    models.NFTPrices,
+		TargetTable:    "sales",
+		Params:         json.RawMessage(`{"collection": "J1S9H3QjnRtBbbuD4HjPV6RpRhwuk4zKbxsnCHuTgh9w"}`),
+	})
+	if !errors.Is(err, ErrPreflightFailed) {
+		t.Errorf("CreateIndexer error = %v, want ErrPreflightFailed", err)
+	}
+}
+
+func TestPreflightRejectsReadOnlyUser(t *testing.T) {
+	cred, pool := testTarget(t)
+	cred = readOnlyRole(t, pool, cred)
+
+	if _, err := pool.Exec(context.Background(), "ALTER ROLE "+cred.DbUser+" SET default_transaction_read_only = on"); err != nil {
+		t.Fatalf("make role read-only: %v", err)
+	}
+
+	var preflightErr *PreflightError
+	err := preflightTarget(context.Background(), cred, testTable(t, pool))
+	if !errors.As(err, &preflightErr) || preflightErr.Check != "read_only" {
+		t.Errorf("preflightTarget error = %v, want the read_only check to fail", err)
+	}
+}
+
+func TestPreflightRejectsUserWithoutWriteAccess(t *testing.T) {
+	cred, pool := testTarget(t)
+	table := testTable(t, pool)
+	if _, err := pool.Exec(context.Background(), "CREATE TABLE "+indexer.QuoteTableName(table)+" (id INT)"); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	reader := readOnlyRole(t, pool, cred)
+
+	var preflightErr *PreflightError
+	err := preflightTarget(context.Background(), reader, table)
+	if !errors.As(err, &preflightErr) || preflightErr.Check != "write_table" {
+		t.Errorf("preflightTarget error = %v, want the write_table check to fail", err)
+	}
+
+	if err := preflightTarget(context.Background(), cred, table); err != nil {
+		t.Errorf("preflightTarget as the table owner: %v", err)
+	}
+}
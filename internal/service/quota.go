@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrIndexerQuotaExceeded is returned when a user already has as many
+// indexers as they are allowed
+var ErrIndexerQuotaExceeded = errors.New("indexer quota reached")
+
+// checkIndexerQuota fails with ErrIndexerQuotaExceeded when the user cannot
+// run another indexer. Only active indexers count, as paused and failed ones
+// don't receive payloads; resuming one is checked against the quota too.
+func (s *IndexerService) checkIndexerQuota(ctx context.Context, userID pgtype.UUID) error {
+	limit := s.maxIndexersPerUser
+
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get user")
+		return errors.New("user not found")
+	}
+	if user.MaxIndexers.Valid {
+		limit = int(user.MaxIndexers.Int32)
+	}
+
+	if limit <= 0 {
+		return nil
+	}
+
+	count, err := s.store.CountIndexersByUserID(ctx, userID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to count indexers")
+		return errors.New("failed to check indexer quota")
+	}
+
+	if count >= int64(limit) {
+		return fmt.Errorf("%w: %d of %d active indexers in use, pause or delete one first", ErrIndexerQuotaExceeded, count, limit)
+	}
+	return nil
+}
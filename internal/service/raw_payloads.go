@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/pkg/logger"
+)
+
+// MaxReprocessPayloads caps how many stored payloads one reprocess call replays
+const MaxReprocessPayloads = 10000
+
+// ErrInvalidSlotRange is returned when a reprocess range ends before it starts
+var ErrInvalidSlotRange = errors.New("fromSlot must not be greater than toSlot")
+
+// ReceivedPayload is a payload together with the JSON Helius sent for it.
+// Batched is set when Body was one element of an array delivery rather than
+// the whole request body.
+type ReceivedPayload struct {
+	Payload models.HeliusWebhookPayload
+	Body    json.RawMessage
+	Batched bool
+}
+
+// StoreRawPayloads keeps the payloads received for a webhook, as Helius sent
+// them, so they can be replayed later with ReprocessPayloads
+func (s *IndexerService) StoreRawPayloads(ctx context.Context, webhookID string, received []ReceivedPayload) error {
+	foundIndexer, err := s.store.GetIndexerByWebhookID(ctx, pgtype.Text{String: webhookID, Valid: true})
+	if err != nil {
+		return fmt.Errorf("indexer not found for webhook ID %s: %w", webhookID, err)
+	}
+
+	for _, r := range received {
+		signature := r.Payload.Transaction.ID
+		if signature == "" && len(r.Payload.Transaction.Signatures) > 0 {
+			signature = r.Payload.Transaction.Signatures[0]
+		}
+
+		err = s.store.CreateRawPayload(ctx, db.CreateRawPayloadParams{
+			IndexerID: foundIndexer.ID,
+			WebhookID: webhookID,
+			Slot:      r.Payload.Slot,
+			Signature: signature,
+			Body:      r.Body,
+			Batched:   r.Batched,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to store raw payload: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// storedPayload parses a stored raw payload the way the webhook handler
+// parsed it when it arrived
+func storedPayload(raw db.RawPayload) (models.HeliusWebhookPayload, error) {
+	if !raw.Batched {
+		return WebhookPayload(raw.Body)
+	}
+
+	payload, ok := TransactionPayload(raw.Body)
+	if !ok {
+		return models.HeliusWebhookPayload{}, errors.New("stored transaction has no signature")
+	}
+	return payload, nil
+}
+
+// PurgeRawPayloads deletes the raw payloads received longer ago than the raw
+// payload retention and reports how many went
+func (s *IndexerService) PurgeRawPayloads(ctx context.Context) (int64, error) {
+	purged, err := s.store.DeleteRawPayloadsBefore(ctx, pgtype.Timestamptz{
+		Time:  time.Now().Add(-s.rawPayloadRetention),
+		Valid: true,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete raw payloads: %w", err)
+	}
+	return purged, nil
+}
+
+// ReprocessPayloads runs the stored payloads of an indexer through
+// ProcessWebhookPayload again, oldest slot first. Writes go through the same
+// ON CONFLICT upserts as live payloads, so replaying is idempotent. A dry run
+// only checks that every stored payload still parses.
+func (s *IndexerService) ReprocessPayloads(ctx context.Context, userID uuid.UUID, indexerID uuid.UUID, req models.ReprocessRequest) (*models.ReprocessResponse, error) {
+
+	if s.maintenance.Enabled() && !req.DryRun {
+		return nil, ErrMaintenance
+	}
+
+	var pgIndexerID pgtype.UUID
+	if err := pgIndexerID.Scan(indexerID.String()); err != nil {
+		return nil, fmt.Errorf("invalid indexer ID: %w", err)
+	}
+
+	foundIndexer, err := s.store.GetIndexerByID(ctx, pgIndexerID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get indexer")
+		return nil, errors.New("indexer not found")
+	}
+
+	userIDFromDB, err := uuid.Parse(foundIndexer.UserID.String())
+	if err != nil || userIDFromDB != userID {
+		return nil, errors.New("indexer not found")
+	}
+
+	if !foundIndexer.WebhookID.Valid || foundIndexer.WebhookID.String == "" {
+		return nil, errors.New("indexer has no webhook to reprocess")
+	}
+
+	fromSlot := int64(0)
+	if req.FromSlot != nil {
+		fromSlot = *req.FromSlot
+	}
+	toSlot := int64(math.MaxInt64)
+	if req.ToSlot != nil {
+		toSlot = *req.ToSlot
+	}
+	if fromSlot > toSlot {
+		return nil, ErrInvalidSlotRange
+	}
+
+	stored, err := s.store.GetRawPayloadsBySlotRange(ctx, db.GetRawPayloadsBySlotRangeParams{
+		IndexerID: pgIndexerID,
+		FromSlot:  fromSlot,
+		ToSlot:    toSlot,
+		RowLimit:  MaxReprocessPayloads,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get raw payloads")
+		return nil, errors.New("failed to get stored payloads")
+	}
+
+	result := &models.ReprocessResponse{
+		IndexerID: indexerID,
+		DryRun:    req.DryRun,
+		Total:     len(stored),
+	}
+
+	for _, raw := range stored {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		payload, err := storedPayload(raw)
+		if err == nil && !req.DryRun {
+			// A replay is deliberate, so it must not be skipped as a redelivery
+			if len(payload.Transaction.Signatures) > 0 {
+				s.dedup.release(indexerID, payload.Transaction.Signatures[0])
+			}
+			err = s.ProcessWebhookPayload(ctx, foundIndexer.WebhookID.String, payload)
+		}
+
+		if err != nil {
+			result.Failed++
+			result.Failures = append(result.Failures, models.ReprocessFailure{
+				Slot:      raw.Slot,
+				Signature: raw.Signature,
+				Error:     logger.Redact(err.Error()),
+			})
+			continue
+		}
+		result.Processed++
+	}
+
+	log.Info().
+		Str("indexerID", indexerID.String()).
+		Bool("dryRun", req.DryRun).
+		Int("total", result.Total).
+		Int("processed", result.Processed).
+		Int("failed", result.Failed).
+		Msg("Reprocessed stored payloads")
+
+	return result, nil
+}
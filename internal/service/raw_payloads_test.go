@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// rawStore holds one indexer and the payloads stored for it
+type rawStore struct {
+	db.Querier
+	indexer  db.Indexer
+	cred     db.DbCredential
+	payloads []db.RawPayload
+	logs     []db.CreateIndexingLogParams
+}
+
+func (s *rawStore) GetIndexerByID(ctx context.Context, id pgtype.UUID) (db.Indexer, error) {
+	return s.indexer, nil
+}
+
+func (s *rawStore) GetIndexerByWebhookID(ctx context.Context, webhookID pgtype.Text) (db.Indexer, error) {
+	return s.indexer, nil
+}
+
+func (s *rawStore) GetDBCredentialByID(ctx context.Context, id pgtype.UUID) (db.DbCredential, error) {
+	return s.cred, nil
+}
+
+func (s *rawStore) GetRawPayloadsBySlotRange(ctx context.Context, arg db.GetRawPayloadsBySlotRangeParams) ([]db.RawPayload, error) {
+	var payloads []db.RawPayload
+	for _, payload := range s.payloads {
+		if payload.Slot >= arg.FromSlot && payload.Slot <= arg.ToSlot {
+			payloads = append(payloads, payload)
+		}
+	}
+	return payloads, nil
+}
+
+func (s *rawStore) CreateRawPayload(ctx context.Context, arg db.CreateRawPayloadParams) error {
+	s.payloads = append(s.payloads, db.RawPayload{
+		IndexerID: arg.IndexerID,
+		WebhookID: arg.WebhookID,
+		Slot:      arg.Slot,
+		Signature: arg.Signature,
+		Body:      arg.Body,
+		Batched:   arg.Batched,
+	})
+	return nil
+}
+
+func (s *rawStore) UpdateLastIndexedTime(ctx context.Context, id pgtype.UUID) (db.Indexer, error) {
+	return s.indexer, nil
+}
+
+func (s *rawStore) CreateIndexingLog(ctx context.Context, arg db.CreateIndexingLogParams) (db.IndexingLog, error) {
+	s.logs = append(s.logs, arg)
+	return db.IndexingLog{}, nil
+}
+
+func newRawStore(userID uuid.UUID) *rawStore {
+	return &rawStore{indexer: db.Indexer{
+		ID:          pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		UserID:      pgtype.UUID{Bytes: userID, Valid: true},
+		Status:      db.IndexerStatusActive,
+		IndexerType: db.IndexerTypeNftPrices,
+		Params:      json.RawMessage(`{"collection": "collection"}`),
+		WebhookID:   pgtype.Text{String: "webhook", Valid: true},
+	}}
+}
+
+// salePayload is a stored NFT_SALE webhook payload
+func salePayload(t *testing.T, signature string, slot int64) db.RawPayload {
+	t.Helper()
+
+	details, err := json.Marshal(map[string]interface{}{
+		"type":      "NFT_SALE",
+		"timestamp": float64(1700000000),
+		"data": map[string]interface{}{
+			"mint":        "mint-1",
+			"marketplace": "MAGIC_EDEN",
+			"seller":      "seller",
+			"buyer":       "buyer",
+			"amount":      float64(2.5),
+		},
+	})
+	if err != nil {
+		t.Fatalf("marshal details: %v", err)
+	}
+	body, err := json.Marshal(models.HeliusWebhookPayload{
+		Slot: slot,
+		Transaction: models.HeliusTransaction{
+			Signatures:      []string{signature},
+			EnhancedDetails: details,
+		},
+	})
+	if err != nil {
+		t.Fatalf("marshal payload: %v", err)
+	}
+	return db.RawPayload{Slot: slot, Signature: signature, Body: body}
+}
+
+func TestReprocessPayloadsDryRunOnlyParses(t *testing.T) {
+	userID := uuid.New()
+	store := newRawStore(userID)
+	store.payloads = []db.RawPayload{
+		salePayload(t, "sale-sig", 10),
+		{Slot: 11, Signature: "broken-sig", Body: json.RawMessage(`{"slot": "not a number"}`)},
+	}
+	s := NewIndexerService(store, nil)
+
+	// The store's credential is empty, so the sale only counts as processed
+	// if nothing tries to write it
+	result, err := s.ReprocessPayloads(context.Background(), userID, uuid.UUID(store.indexer.ID.Bytes), models.ReprocessRequest{DryRun: true})
+	if err != nil {
+		t.Fatalf("ReprocessPayloads: %v", err)
+	}
+	if result.Total != 2 || result.Processed != 1 || result.Failed != 1 {
+		t.Errorf("got %d total, %d processed, %d failed; want 2, 1, 1", result.Total, result.Processed, result.Failed)
+	}
+	if len(result.Failures) != 1 || result.Failures[0].Signature != "broken-sig" {
+		t.Errorf("failures = %+v, want the unparseable payload", result.Failures)
+	}
+}
+
+func TestStoreRawPayloadsKeepsBodyAsSent(t *testing.T) {
+	store := newRawStore(uuid.New())
+	s := NewIndexerService(store, nil)
+
+	// Fields the payload model doesn't map must survive for a replay
+	body := json.RawMessage(`{"signature": "tx-sig", "slot": 12, "type": "NFT_SALE", "unmappedField": {"kept": true}}`)
+	payload, ok := TransactionPayload(body)
+	if !ok {
+		t.Fatal("TransactionPayload rejected the transaction")
+	}
+
+	if err := s.StoreRawPayloads(context.Background(), "webhook", []ReceivedPayload{{Payload: payload, Body: body, Batched: true}}); err != nil {
+		t.Fatalf("StoreRawPayloads: %v", err)
+	}
+
+	if len(store.payloads) != 1 {
+		t.Fatalf("stored %d payloads, want 1", len(store.payloads))
+	}
+	stored := store.payloads[0]
+	if string(stored.Body) != string(body) || !stored.Batched {
+		t.Errorf("stored body %s (batched %v), want %s as sent", stored.Body, stored.Batched, body)
+	}
+	if stored.Signature != "tx-sig" || stored.Slot != 12 || stored.IndexerID != store.indexer.ID {
+		t.Errorf("stored %+v, want tx-sig at slot 12 for the indexer", stored)
+	}
+}
+
+func TestReprocessPayloadsParsesStoredBodiesLikeTheHandler(t *testing.T) {
+	userID := uuid.New()
+	store := newRawStore(userID)
+	store.payloads = []db.RawPayload{
+		// A transaction from an array delivery, as Helius sent it
+		{Slot: 10, Signature: "batched-sig", Batched: true, Body: json.RawMessage(`{"signature": "batched-sig", "slot": 10, "type": "NFT_SALE", "timestamp": 1700000000}`)},
+		// A single payload naming its signature at the top level
+		{Slot: 11, Signature: "single-sig", Body: json.RawMessage(`{"signature": "single-sig", "slot": 11}`)},
+		{Slot: 12, Signature: "", Batched: true, Body: json.RawMessage(`{"slot": 12}`)},
+	}
+	s := NewIndexerService(store, nil)
+
+	result, err := s.ReprocessPayloads(context.Background(), userID, uuid.UUID(store.indexer.ID.Bytes), models.ReprocessRequest{DryRun: true})
+	if err != nil {
+		t.Fatalf("ReprocessPayloads: %v", err)
+	}
+	if result.Processed != 2 || result.Failed != 1 {
+		t.Errorf("got %d processed, %d failed (%+v); want 2, 1", result.Processed, result.Failed, result.Failures)
+	}
+	if len(result.Failures) != 1 || result.Failures[0].Slot != 12 {
+		t.Errorf("failures = %+v, want the transaction without a signature", result.Failures)
+	}
+}
+
+func TestReprocessPayloadsRejectsInvertedSlotRange(t *testing.T) {
+	userID := uuid.New()
+	store := newRawStore(userID)
+	s := NewIndexerService(store, nil)
+
+	from, to := int64(20), int64(10)
+	_, err := s.ReprocessPayloads(context.Background(), userID, uuid.UUID(store.indexer.ID.Bytes), models.ReprocessRequest{FromSlot: &from, ToSlot: &to})
+	if !errors.Is(err, ErrInvalidSlotRange) {
+		t.Errorf("ReprocessPayloads error = %v, want ErrInvalidSlotRange", err)
+	}
+}
+
+func TestReprocessPayloadsChecksOwnership(t *testing.T) {
+	store := newRawStore(uuid.New())
+	s := NewIndexerService(store, nil)
+
+	if _, err := s.ReprocessPayloads(context.Background(), uuid.New(), uuid.UUID(store.indexer.ID.Bytes), models.ReprocessRequest{DryRun: true}); err == nil {
+		t.Error("ReprocessPayloads of another user's indexer succeeded")
+	}
+}
+
+func TestReprocessPayloadsIsIdempotent(t *testing.T) {
+	cred, pool := testTarget(t)
+	table := testTable(t, pool)
+
+	userID := uuid.New()
+	store := newRawStore(userID)
+	store.cred = cred
+	store.indexer.TargetTable = table
+	store.payloads = []db.RawPayload{salePayload(t, "sale-sig", 10)}
+
+	idx, err := indexer.NewNFTPriceIndexer("test", store.indexer.Params)
+	if err != nil {
+		t.Fatalf("NewNFTPriceIndexer: %v", err)
+	}
+	initializeTable(t, pool, idx, table)
+
+	s := NewIndexerService(store, nil)
+	ctx := context.Background()
+	indexerID := uuid.UUID(store.indexer.ID.Bytes)
+
+	for run := 1; run <= 2; run++ {
+		result, err := s.ReprocessPayloads(ctx, userID, indexerID, models.ReprocessRequest{})
+		if err != nil {
+			t.Fatalf("ReprocessPayloads run %d: %v", run, err)
+		}
+		if result.Processed != 1 || result.Failed != 0 {
+			t.Fatalf("run %d: %d processed, %d failed (%+v), want 1 processed", run, result.Processed, result.Failed, result.Failures)
+		}
+	}
+
+	var count int
+	if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+indexer.QuoteTableName(table)+" WHERE signature = 'sale-sig' AND status = 'sold'").Scan(&count); err != nil {
+		t.Fatalf("count rows: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("got %d sale rows after reprocessing twice, want 1", count)
+	}
+}
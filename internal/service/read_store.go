@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+)
+
+// readRoutingStore sends read-heavy queries to a read replica and everything
+// else to the primary. Point lookups stay on the primary because they usually
+// guard a write that follows and must not see replica lag.
+type readRoutingStore struct {
+	db.Querier
+	reader db.Querier
+}
+
+// NewReadRoutingStore returns a Querier that writes through writer and serves
+// log and list queries from reader
+func NewReadRoutingStore(writer, reader db.Querier) db.Querier {
+	return &readRoutingStore{Querier: writer, reader: reader}
+}
+
+// primaryStore returns the primary behind store, for list queries that guard
+// a write and so must not see replica lag
+func primaryStore(store db.Querier) db.Querier {
+	if routing, ok := store.(*readRoutingStore); ok {
+		return routing.Querier
+	}
+	return store
+}
+
+func (s *readRoutingStore) GetActiveIndexers(ctx context.Context) ([]db.Indexer, error) {
+	return s.reader.GetActiveIndexers(ctx)
+}
+
+func (s *readRoutingStore) GetDBCredentialsByUserID(ctx context.Context, userID pgtype.UUID) ([]db.DbCredential, error) {
+	return s.reader.GetDBCredentialsByUserID(ctx, userID)
+}
+
+func (s *readRoutingStore) GetIndexersByUserID(ctx context.Context, userID pgtype.UUID) ([]db.Indexer, error) {
+	return s.reader.GetIndexersByUserID(ctx, userID)
+}
+
+func (s *readRoutingStore) GetIndexingLogsByIndexerID(ctx context.Context, arg db.GetIndexingLogsByIndexerIDParams) ([]db.IndexingLog, error) {
+	return s.reader.GetIndexingLogsByIndexerID(ctx, arg)
+}
+
+func (s *readRoutingStore) GetRawPayloadsBySlotRange(ctx context.Context, arg db.GetRawPayloadsBySlotRangeParams) ([]db.RawPayload, error) {
+	return s.reader.GetRawPayloadsBySlotRange(ctx, arg)
+}
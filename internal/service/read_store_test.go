@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+)
+
+// recordingStore notes which queries reach it
+type recordingStore struct {
+	db.Querier
+	calls []string
+}
+
+func (s *recordingStore) GetActiveIndexers(ctx context.Context) ([]db.Indexer, error) {
+	s.calls = append(s.calls, "GetActiveIndexers")
+	return nil, nil
+}
+
+func (s *recordingStore) GetIndexersByUserID(ctx context.Context, userID pgtype.UUID) ([]db.Indexer, error) {
+	s.calls = append(s.calls, "GetIndexersByUserID")
+	return nil, nil
+}
+
+func (s *recordingStore) GetIndexingLogsByIndexerID(ctx context.Context, arg db.GetIndexingLogsByIndexerIDParams) ([]db.IndexingLog, error) {
+	s.calls = append(s.calls, "GetIndexingLogsByIndexerID")
+	return nil, nil
+}
+
+func (s *recordingStore) GetIndexingLogsSince(ctx context.Context, arg db.GetIndexingLogsSinceParams) ([]db.IndexingLog, error) {
+	s.calls = append(s.calls, "GetIndexingLogsSince")
+	return nil, nil
+}
+
+func (s *recordingStore) GetIndexerByID(ctx context.Context, id pgtype.UUID) (db.Indexer, error) {
+	s.calls = append(s.calls, "GetIndexerByID")
+	return db.Indexer{}, nil
+}
+
+func (s *recordingStore) CreateIndexingLog(ctx context.Context, arg db.CreateIndexingLogParams) (db.IndexingLog, error) {
+	s.calls = append(s.calls, "CreateIndexingLog")
+	return db.IndexingLog{}, nil
+}
+
+func TestReadRoutingStoreSendsReadsToReader(t *testing.T) {
+	writer, reader := &recordingStore{}, &recordingStore{}
+	store := NewReadRoutingStore(writer, reader)
+	ctx := context.Background()
+
+	store.GetActiveIndexers(ctx)
+	store.GetIndexersByUserID(ctx, pgtype.UUID{})
+	store.GetIndexingLogsByIndexerID(ctx, db.GetIndexingLogsByIndexerIDParams{})
+	store.GetIndexerByID(ctx, pgtype.UUID{})
+	store.CreateIndexingLog(ctx, db.CreateIndexingLogParams{})
+	store.GetIndexingLogsSince(ctx, db.GetIndexingLogsSinceParams{})
+
+	// The log tail is woken by writes to the primary, so it reads the primary
+	wantReader := []string{"GetActiveIndexers", "GetIndexersByUserID", "GetIndexingLogsByIndexerID"}
+	wantWriter := []string{"GetIndexerByID", "CreateIndexingLog", "GetIndexingLogsSince"}
+
+	if len(reader.calls) != len(wantReader) {
+		t.Fatalf("reader got %v, want %v", reader.calls, wantReader)
+	}
+	for i := range wantReader {
+		if reader.calls[i] != wantReader[i] {
+			t.Errorf("reader got %v, want %v", reader.calls, wantReader)
+			break
+		}
+	}
+	if len(writer.calls) != len(wantWriter) {
+		t.Fatalf("writer got %v, want %v", writer.calls, wantWriter)
+	}
+	for i := range wantWriter {
+		if writer.calls[i] != wantWriter[i] {
+			t.Errorf("writer got %v, want %v", writer.calls, wantWriter)
+			break
+		}
+	}
+}
+
+func TestGetIndexersByUserIDReadsFromReader(t *testing.T) {
+	writer, reader := &recordingStore{}, &recordingStore{}
+	s := NewIndexerService(NewReadRoutingStore(writer, reader), nil)
+
+	if _, err := s.GetIndexersByUserID(context.Background(), uuid.New()); err != nil {
+		t.Fatalf("GetIndexersByUserID: %v", err)
+	}
+	if len(reader.calls) != 1 || reader.calls[0] != "GetIndexersByUserID" || len(writer.calls) != 0 {
+		t.Errorf("reader got %v and writer got %v, want the list served by the reader", reader.calls, writer.calls)
+	}
+}
+
+// credentialUsageStore holds one credential and the indexers using it
+type credentialUsageStore struct {
+	recordingStore
+	cred     db.DbCredential
+	indexers []db.Indexer
+	deleted  bool
+}
+
+func (s *credentialUsageStore) GetDBCredentialByID(ctx context.Context, id pgtype.UUID) (db.DbCredential, error) {
+	return s.cred, nil
+}
+
+func (s *credentialUsageStore) GetIndexersByUserID(ctx context.Context, userID pgtype.UUID) ([]db.Indexer, error) {
+	s.calls = append(s.calls, "GetIndexersByUserID")
+	return s.indexers, nil
+}
+
+func (s *credentialUsageStore) DeleteDBCredential(ctx context.Context, arg db.DeleteDBCredentialParams) error {
+	s.deleted = true
+	return nil
+}
+
+func TestDeleteDBCredentialChecksUsageOnPrimary(t *testing.T) {
+	userID, credID := uuid.New(), uuid.New()
+	cred := db.DbCredential{
+		ID:     pgtype.UUID{Bytes: credID, Valid: true},
+		UserID: pgtype.UUID{Bytes: userID, Valid: true},
+	}
+	// The indexer using the credential was just created and is not on the
+	// replica yet
+	writer := &credentialUsageStore{cred: cred, indexers: []db.Indexer{{DbCredentialID: cred.ID}}}
+	reader := &credentialUsageStore{cred: cred}
+	s := NewUserService(NewReadRoutingStore(writer, reader))
+
+	if err := s.DeleteDBCredential(context.Background(), userID, credID); err == nil {
+		t.Fatal("DeleteDBCredential deleted a credential in use")
+	}
+	if writer.deleted || reader.deleted {
+		t.Error("credential in use was deleted")
+	}
+	if len(reader.calls) != 0 {
+		t.Errorf("reader got %v, want the usage check served by the primary", reader.calls)
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/pkg/logger"
+)
+
+// ErrResetNotConfirmed is returned when a reset is requested without confirm
+var ErrResetNotConfirmed = errors.New("reset must be confirmed with \"confirm\": true")
+
+// ResetIndexer empties an indexer's target table so it can be backfilled
+// again. The table, the indexer and its webhook are kept; last_indexed_at is
+// cleared and the signatures remembered for deduplication are forgotten.
+func (s *IndexerService) ResetIndexer(ctx context.Context, userID uuid.UUID, indexerID uuid.UUID, confirm bool) (*models.IndexerResponse, error) {
+
+	if s.maintenance.Enabled() {
+		return nil, ErrMaintenance
+	}
+
+	var pgIndexerID pgtype.UUID
+	if err := pgIndexerID.Scan(indexerID.String()); err != nil {
+		return nil, fmt.Errorf("invalid indexer ID: %w", err)
+	}
+
+	foundIndexer, err := s.store.GetIndexerByID(ctx, pgIndexerID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get indexer")
+		return nil, errors.New("indexer not found")
+	}
+
+	userIDFromDB, err := uuid.Parse(foundIndexer.UserID.String())
+	if err != nil || userIDFromDB != userID {
+		return nil, errors.New("indexer not found")
+	}
+
+	if !confirm {
+		return nil, ErrResetNotConfirmed
+	}
+
+	pool, err := s.connectActivityPool(ctx, foundIndexer.DbCredentialID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", logger.RedactError(err))
+	}
+	defer pool.Close()
+
+	var deleted int64
+	err = pool.QueryRow(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", indexer.QuoteTableName(foundIndexer.TargetTable))).Scan(&deleted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count target table rows: %w", err)
+	}
+
+	if _, err := pool.Exec(ctx, fmt.Sprintf("TRUNCATE TABLE %s RESTART IDENTITY", indexer.QuoteTableName(foundIndexer.TargetTable))); err != nil {
+		return nil, fmt.Errorf("failed to truncate target table: %w", err)
+	}
+
+	if _, err := s.store.ClearLastIndexedTime(ctx, foundIndexer.ID); err != nil {
+		log.Error().Err(err).Msg("Failed to clear last indexed time")
+		return nil, errors.New("failed to reset indexer")
+	}
+
+	// Backfilled addresses count as done; forget that so a backfill fills
+	// the emptied table again
+	if err := s.store.DeleteIndexerBackfills(ctx, foundIndexer.ID); err != nil {
+		log.Error().Err(err).Msg("Failed to clear backfill progress")
+		return nil, errors.New("failed to reset indexer")
+	}
+
+	s.dedup.forget(indexerID)
+
+	recordAudit(ctx, s.store, userID, auditIndexerReset, auditEntityIndexer, foundIndexer.ID, map[string]interface{}{
+		"targetTable": foundIndexer.TargetTable,
+		"deletedRows": deleted,
+	})
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"targetTable": foundIndexer.TargetTable,
+		"deletedRows": deleted,
+	})
+
+	_, logErr := s.store.CreateIndexingLog(ctx, db.CreateIndexingLogParams{
+		IndexerID: foundIndexer.ID,
+		EventType: "reset",
+		Message:   fmt.Sprintf("Target table %s emptied", foundIndexer.TargetTable),
+		Details:   details,
+	})
+	if logErr != nil {
+		log.Error().Err(logErr).Msg("Failed to create reset log entry")
+	}
+
+	log.Info().
+		Str("indexerID", indexerID.String()).
+		Str("targetTable", foundIndexer.TargetTable).
+		Int64("deletedRows", deleted).
+		Msg("Reset indexer data")
+
+	return s.GetIndexerByID(ctx, userID, indexerID)
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+)
+
+// resetStore records that last_indexed_at and the backfill progress were
+// cleared
+type resetStore struct {
+	*rawStore
+	cleared          bool
+	backfillsCleared bool
+}
+
+func (s *resetStore) DeleteIndexerBackfills(ctx context.Context, indexerID pgtype.UUID) error {
+	s.backfillsCleared = true
+	return nil
+}
+
+func (s *resetStore) ClearLastIndexedTime(ctx context.Context, id pgtype.UUID) (db.Indexer, error) {
+	s.cleared = true
+	s.indexer.LastIndexedAt = pgtype.Timestamptz{}
+	return s.indexer, nil
+}
+
+func TestResetIndexerRequiresConfirmation(t *testing.T) {
+	userID := uuid.New()
+	store := &resetStore{rawStore: newRawStore(userID)}
+	s := NewIndexerService(store, nil)
+
+	_, err := s.ResetIndexer(context.Background(), userID, uuid.UUID(store.indexer.ID.Bytes), false)
+	if !errors.Is(err, ErrResetNotConfirmed) {
+		t.Errorf("ResetIndexer error = %v, want ErrResetNotConfirmed", err)
+	}
+	if store.cleared || store.backfillsCleared || len(store.logs) != 0 {
+		t.Error("unconfirmed reset changed the indexer")
+	}
+}
+
+func TestResetIndexerChecksOwnership(t *testing.T) {
+	store := &resetStore{rawStore: newRawStore(uuid.New())}
+	s := NewIndexerService(store, nil)
+
+	if _, err := s.ResetIndexer(context.Background(), uuid.New(), uuid.UUID(store.indexer.ID.Bytes), true); err == nil {
+		t.Error("ResetIndexer of another user's indexer succeeded")
+	}
+	if store.cleared {
+		t.Error("reset of another user's indexer cleared last_indexed_at")
+	}
+}
+
+func TestResetIndexerEmptiesTable(t *testing.T) {
+	_, raw, pool := statsFixture(t)
+	store := &resetStore{rawStore: raw}
+	s := NewIndexerService(store, nil)
+	table := raw.indexer.TargetTable
+	store.indexer.LastIndexedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+	seedPriceRow(t, pool, table, "listed", "listed", 2, time.Now())
+	seedPriceRow(t, pool, table, "sold", "sold", 3, time.Now())
+
+	ctx := context.Background()
+	response, err := s.ResetIndexer(ctx, uuid.UUID(store.indexer.UserID.Bytes), uuid.UUID(store.indexer.ID.Bytes), true)
+	if err != nil {
+		t.Fatalf("ResetIndexer: %v", err)
+	}
+
+	// The table is emptied, not dropped
+	var rows int
+	if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+indexer.QuoteTableName(table)).Scan(&rows); err != nil {
+		t.Fatalf("count rows after reset: %v", err)
+	}
+	if rows != 0 {
+		t.Errorf("got %d rows after reset, want 0", rows)
+	}
+
+	if !store.cleared || response.LastIndexedAt != nil {
+		t.Error("last_indexed_at not cleared")
+	}
+	if !store.backfillsCleared {
+		t.Error("backfill progress not cleared, so a backfill would skip the emptied table")
+	}
+	if len(store.logs) != 1 || store.logs[0].EventType != "reset" {
+		t.Errorf("logs = %+v, want one reset entry", store.logs)
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/pkg/logger"
+	"github.com/rishavmehra/indexer/pkg/validator"
+)
+
+// ErrInvalidTargetTable is returned when a target table name fails
+// validator.ValidateTableName; the reason follows it in the message
+var ErrInvalidTargetTable = errors.New("invalid target table name")
+
+// RetargetIndexer moves an indexer to a new target table. The table is
+// created through the indexer's Initialize and, with CopyExisting, filled
+// from the current table before the indexer is switched over. Running it
+// again with the same request is safe: an existing table is reused and rows
+// already copied are skipped.
+func (s *IndexerService) RetargetIndexer(ctx context.Context, userID uuid.UUID, indexerID uuid.UUID, req models.RetargetIndexerRequest) (*models.IndexerResponse, error) {
+
+	if s.maintenance.Enabled() {
+		return nil, ErrMaintenance
+	}
+
+	var pgIndexerID pgtype.UUID
+	if err := pgIndexerID.Scan(indexerID.String()); err != nil {
+		return nil, fmt.Errorf("invalid indexer ID: %w", err)
+	}
+
+	foundIndexer, err := s.store.GetIndexerByID(ctx, pgIndexerID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get indexer")
+		return nil, errors.New("indexer not found")
+	}
+
+	userIDFromDB, err := uuid.Parse(foundIndexer.UserID.String())
+	if err != nil || userIDFromDB != userID {
+		return nil, errors.New("indexer not found")
+	}
+
+	if err := validator.ValidateTableName(req.TargetTable, s.reservedTablePrefixes); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTargetTable, err)
+	}
+
+	oldTable := foundIndexer.TargetTable
+	if indexer.FormatTableName(oldTable) == indexer.FormatTableName(req.TargetTable) {
+		return s.GetIndexerByID(ctx, userID, indexerID)
+	}
+
+	cred, err := s.store.GetDBCredentialByID(ctx, foundIndexer.DbCredentialID)
+	if err != nil {
+		return nil, errors.New("database credential not found")
+	}
+
+	if err := preflightTarget(ctx, cred, req.TargetTable); err != nil {
+		log.Warn().Err(err).Str("targetTable", req.TargetTable).Msg("New target table failed preflight")
+		return nil, err
+	}
+
+	retargeted := foundIndexer
+	retargeted.TargetTable = req.TargetTable
+
+	if err := s.initializeIndexer(ctx, retargeted); err != nil {
+		return nil, fmt.Errorf("failed to initialize new target table: %w", logger.RedactError(err))
+	}
+
+	var copied int64
+	if req.CopyExisting {
+		dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+			cred.DbHost, cred.DbPort, cred.DbUser, cred.DbPassword, cred.DbName, cred.DbSslMode)
+
+		conn, err := pgx.Connect(ctx, dsn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to database: %w", logger.RedactError(err))
+		}
+		defer conn.Close(ctx)
+
+		copied, err = indexer.CopyTableRows(ctx, conn, oldTable, req.TargetTable)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := s.store.UpdateIndexerTargetTable(ctx, db.UpdateIndexerTargetTableParams{
+		ID:          foundIndexer.ID,
+		TargetTable: req.TargetTable,
+	}); err != nil {
+		log.Error().Err(err).Msg("Failed to update indexer target table")
+		return nil, errors.New("failed to update indexer target table")
+	}
+
+	recordAudit(ctx, s.store, userID, auditIndexerRetarget, auditEntityIndexer, foundIndexer.ID, map[string]interface{}{
+		"previousTable": oldTable,
+		"targetTable":   req.TargetTable,
+	})
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"previousTable": oldTable,
+		"targetTable":   req.TargetTable,
+		"copyExisting":  req.CopyExisting,
+		"copiedRows":    copied,
+	})
+
+	_, logErr := s.store.CreateIndexingLog(ctx, db.CreateIndexingLogParams{
+		IndexerID: foundIndexer.ID,
+		EventType: "retarget",
+		Message:   fmt.Sprintf("Target table changed from %s to %s", oldTable, req.TargetTable),
+		Details:   details,
+	})
+	if logErr != nil {
+		log.Error().Err(logErr).Msg("Failed to create retarget log entry")
+	}
+
+	log.Info().
+		Str("indexerID", indexerID.String()).
+		Str("previousTable", oldTable).
+		Str("targetTable", req.TargetTable).
+		Int64("copiedRows", copied).
+		Msg("Retargeted indexer")
+
+	return s.GetIndexerByID(ctx, userID, indexerID)
+}
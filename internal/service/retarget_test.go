@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// retargetStore is a rawStore that also records target table changes
+type retargetStore struct {
+	*rawStore
+}
+
+func (s *retargetStore) UpdateIndexerTargetTable(ctx context.Context, arg db.UpdateIndexerTargetTableParams) (db.Indexer, error) {
+	s.indexer.TargetTable = arg.TargetTable
+	return s.indexer, nil
+}
+
+func (s *retargetStore) CreateAuditEvent(ctx context.Context, arg db.CreateAuditEventParams) error {
+	return nil
+}
+
+func countRows(t *testing.T, pool *pgxpool.Pool, table string) int {
+	t.Helper()
+
+	var count int
+	if err := pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM "+indexer.QuoteTableName(table)).Scan(&count); err != nil {
+		t.Fatalf("count rows of %s: %v", table, err)
+	}
+	return count
+}
+
+func TestRetargetIndexerRejectsInvalidTable(t *testing.T) {
+	userID := uuid.New()
+	store := &retargetStore{newRawStore(userID)}
+	s := NewIndexerService(store, nil)
+
+	_, err := s.RetargetIndexer(context.Background(), userID, uuid.UUID(store.indexer.ID.Bytes), models.RetargetIndexerRequest{TargetTable: "pg_sales"})
+	if !errors.Is(err, ErrInvalidTargetTable) {
+		t.Errorf("RetargetIndexer error = %v, want ErrInvalidTargetTable", err)
+	}
+}
+
+func TestRetargetIndexerChecksOwnership(t *testing.T) {
+	store := &retargetStore{newRawStore(uuid.New())}
+	s := NewIndexerService(store, nil)
+
+	if _, err := s.RetargetIndexer(context.Background(), uuid.New(), uuid.UUID(store.indexer.ID.Bytes), models.RetargetIndexerRequest{TargetTable: "sales"}); err == nil {
+		t.Error("RetargetIndexer of another user's indexer succeeded")
+	}
+}
+
+// retargetFixture is an indexer whose current table holds one sale
+func retargetFixture(t *testing.T) (*IndexerService, *retargetStore, *pgxpool.Pool, string) {
+	t.Helper()
+
+	cred, pool := testTarget(t)
+	oldTable := testTable(t, pool)
+
+	store := &retargetStore{newRawStore(uuid.New())}
+	store.cred = cred
+	store.indexer.TargetTable = oldTable
+	s := NewIndexerService(store, nil)
+	ctx := context.Background()
+
+	if err := s.initializeIndexer(ctx, store.indexer); err != nil {
+		t.Fatalf("initializeIndexer: %v", err)
+	}
+
+	var payload models.HeliusWebhookPayload
+	if err := json.Unmarshal(salePayload(t, "retarget-sig", 10).Body, &payload); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	if err := s.ProcessWebhookPayload(ctx, "webhook", payload); err != nil {
+		t.Fatalf("ProcessWebhookPayload: %v", err)
+	}
+	return s, store, pool, oldTable
+}
+
+func TestRetargetIndexerCreateOnly(t *testing.T) {
+	s, store, pool, oldTable := retargetFixture(t)
+	ctx := context.Background()
+	userID := uuid.UUID(store.indexer.UserID.Bytes)
+	newTable := oldTable + "_moved"
+
+	resp, err := s.RetargetIndexer(ctx, userID, uuid.UUID(store.indexer.ID.Bytes), models.RetargetIndexerRequest{TargetTable: newTable})
+	if err != nil {
+		t.Fatalf("RetargetIndexer: %v", err)
+	}
+	if resp.TargetTable != newTable || store.indexer.TargetTable != newTable {
+		t.Errorf("target table = %q (stored %q), want %q", resp.TargetTable, store.indexer.TargetTable, newTable)
+	}
+
+	if n := countRows(t, pool, newTable); n != 0 {
+		t.Errorf("new table has %d rows, want it created empty", n)
+	}
+	if n := countRows(t, pool, oldTable); n != 1 {
+		t.Errorf("old table has %d rows, want it left untouched", n)
+	}
+}
+
+func TestRetargetIndexerCopiesExistingRows(t *testing.T) {
+	s, store, pool, oldTable := retargetFixture(t)
+	ctx := context.Background()
+	userID := uuid.UUID(store.indexer.UserID.Bytes)
+	indexerID := uuid.UUID(store.indexer.ID.Bytes)
+	newTable := oldTable + "_moved"
+	req := models.RetargetIndexerRequest{TargetTable: newTable, CopyExisting: true}
+
+	if _, err := s.RetargetIndexer(ctx, userID, indexerID, req); err != nil {
+		t.Fatalf("RetargetIndexer: %v", err)
+	}
+	if n := countRows(t, pool, newTable); n != 1 {
+		t.Fatalf("new table has %d rows, want the sale copied", n)
+	}
+
+	// Running the same move again, as after a failure before the switch,
+	// must not duplicate what was already copied
+	store.indexer.TargetTable = oldTable
+	if _, err := s.RetargetIndexer(ctx, userID, indexerID, req); err != nil {
+		t.Fatalf("second RetargetIndexer: %v", err)
+	}
+	if n := countRows(t, pool, newTable); n != 1 {
+		t.Errorf("new table has %d rows after copying twice, want 1", n)
+	}
+
+	var last db.CreateIndexingLogParams
+	for _, entry := range store.logs {
+		if entry.EventType == "retarget" {
+			last = entry
+		}
+	}
+	var details map[string]interface{}
+	if err := json.Unmarshal(last.Details, &details); err != nil {
+		t.Fatalf("unmarshal retarget log details: %v", err)
+	}
+	if details["copiedRows"] != float64(0) {
+		t.Errorf("second run copied %v rows, want 0", details["copiedRows"])
+	}
+}
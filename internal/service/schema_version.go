@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// PayloadSchemaVersion fingerprints the shape of a raw webhook payload
+var PayloadSchemaVersion = indexer.PayloadSchemaVersion
+
+// schemaVersionKeyColumns names, per indexer type, the target table column
+// holding the signature of the transaction that last wrote a row. Types
+// without one aggregate many transactions into a row and record no version.
+var schemaVersionKeyColumns = map[db.IndexerType]string{
+	db.IndexerTypeNftBids:     "signature",
+	db.IndexerTypeNftPrices:   "signature",
+	db.IndexerTypeTokenPrices: "transaction_id",
+}
+
+// addSchemaVersionColumn adds the schema_version column to the target table
+// of indexer types that can record it
+func addSchemaVersionColumn(ctx context.Context, conn *pgx.Conn, dbIndexer db.Indexer) error {
+	if _, ok := schemaVersionKeyColumns[dbIndexer.IndexerType]; !ok {
+		return nil
+	}
+	return indexer.AddSchemaVersionColumn(ctx, conn, dbIndexer.TargetTable)
+}
+
+// stampSchemaVersion records the payload's schema version on the rows it
+// wrote. Failures are only logged; the version is diagnostic.
+func stampSchemaVersion(ctx context.Context, pool *pgxpool.Pool, dbIndexer db.Indexer, payload models.HeliusWebhookPayload) {
+	keyColumn, ok := schemaVersionKeyColumns[dbIndexer.IndexerType]
+	if !ok || payload.SchemaVersion == "" {
+		return
+	}
+
+	signature := payload.Transaction.ID
+	if signature == "" && len(payload.Transaction.Signatures) > 0 {
+		signature = payload.Transaction.Signatures[0]
+	}
+
+	err := indexer.StampSchemaVersion(ctx, pool, dbIndexer.TargetTable, keyColumn, signature, payload.SchemaVersion)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("indexerID", dbIndexer.ID.String()).
+			Str("schemaVersion", payload.SchemaVersion).
+			Msg("Failed to record payload schema version")
+	}
+}
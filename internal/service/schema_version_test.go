@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+func TestProcessWebhookPayloadRecordsSchemaVersion(t *testing.T) {
+	cred, pool := testTarget(t)
+	table := testTable(t, pool)
+
+	store := newRawStore(uuid.New())
+	store.cred = cred
+	store.indexer.TargetTable = table
+	s := NewIndexerService(store, nil)
+	ctx := context.Background()
+
+	if err := s.initializeIndexer(ctx, store.indexer); err != nil {
+		t.Fatalf("initializeIndexer: %v", err)
+	}
+
+	raw := salePayload(t, "versioned-sig", 10)
+	var payload models.HeliusWebhookPayload
+	if err := json.Unmarshal(raw.Body, &payload); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	payload.SchemaVersion = PayloadSchemaVersion(raw.Body)
+	if payload.SchemaVersion == "" {
+		t.Fatal("no schema version detected for the sale payload")
+	}
+
+	store.logs = nil
+	if err := s.ProcessWebhookPayload(ctx, "webhook", payload); err != nil {
+		t.Fatalf("ProcessWebhookPayload: %v", err)
+	}
+
+	var version string
+	if err := pool.QueryRow(ctx, "SELECT schema_version FROM "+indexer.QuoteTableName(table)+" WHERE signature = 'versioned-sig'").Scan(&version); err != nil {
+		t.Fatalf("read schema_version: %v", err)
+	}
+	if version != payload.SchemaVersion {
+		t.Errorf("row schema_version = %q, want %q", version, payload.SchemaVersion)
+	}
+
+	if len(store.logs) != 1 {
+		t.Fatalf("got %d delivery logs, want 1", len(store.logs))
+	}
+	var details map[string]interface{}
+	if err := json.Unmarshal(store.logs[0].Details, &details); err != nil {
+		t.Fatalf("unmarshal log details: %v", err)
+	}
+	if details["schema_version"] != payload.SchemaVersion {
+		t.Errorf("log schema_version = %v, want %q", details["schema_version"], payload.SchemaVersion)
+	}
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// ErrRestoreWindowPassed is returned when a deleted indexer is older than the
+// delete retention and is about to be purged
+var ErrRestoreWindowPassed = errors.New("indexer was deleted too long ago to be restored")
+
+const (
+	// deletedIndexerPurgeInterval is how often deleted indexers past the
+	// retention are looked for
+	deletedIndexerPurgeInterval = time.Hour
+	// deletedIndexerPurgeTimeout bounds one purge run
+	deletedIndexerPurgeTimeout = 5 * time.Minute
+)
+
+// softDeleteIndexer marks an indexer deleted. Its table and webhook are kept
+// so it can be restored; payloads that still arrive are dropped.
+func (s *IndexerService) softDeleteIndexer(ctx context.Context, foundIndexer db.Indexer) error {
+	deleted, err := s.store.SoftDeleteIndexer(ctx, db.SoftDeleteIndexerParams{
+		ID:     foundIndexer.ID,
+		UserID: foundIndexer.UserID,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to delete indexer")
+		return errors.New("failed to delete indexer")
+	}
+
+	if idUUID, err := uuid.Parse(foundIndexer.ID.String()); err == nil {
+		s.forgetIndexerImpl(idUUID)
+		s.latency.Remove(idUUID)
+	}
+
+	purgeAt := deleted.DeletedAt.Time.Add(s.deleteRetention)
+	details, _ := json.Marshal(map[string]interface{}{
+		"purgeAt": purgeAt,
+	})
+
+	_, logErr := s.store.CreateIndexingLog(ctx, db.CreateIndexingLogParams{
+		IndexerID: foundIndexer.ID,
+		EventType: "deleted",
+		Message:   "Indexer deleted, restorable until " + purgeAt.UTC().Format(time.RFC3339),
+		Details:   details,
+	})
+	if logErr != nil {
+		log.Error().Err(logErr).Msg("Failed to create delete log entry")
+	}
+
+	log.Info().
+		Str("indexerID", foundIndexer.ID.String()).
+		Time("purgeAt", purgeAt).
+		Msg("Indexer deleted")
+
+	return nil
+}
+
+// RestoreIndexer brings back an indexer deleted within the delete retention,
+// in the status it had when it was deleted
+func (s *IndexerService) RestoreIndexer(ctx context.Context, userID uuid.UUID, indexerID uuid.UUID) (*models.IndexerResponse, error) {
+
+	var pgIndexerID pgtype.UUID
+	if err := pgIndexerID.Scan(indexerID.String()); err != nil {
+		return nil, fmt.Errorf("invalid indexer ID: %w", err)
+	}
+
+	foundIndexer, err := s.store.GetDeletedIndexerByID(ctx, pgIndexerID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get deleted indexer")
+		return nil, errors.New("indexer not found")
+	}
+
+	userIDFromDB, err := uuid.Parse(foundIndexer.UserID.String())
+	if err != nil || userIDFromDB != userID {
+		return nil, errors.New("indexer not found")
+	}
+
+	if time.Since(foundIndexer.DeletedAt.Time) > s.deleteRetention {
+		return nil, ErrRestoreWindowPassed
+	}
+
+	if _, err := s.store.RestoreIndexer(ctx, pgIndexerID); err != nil {
+		log.Error().Err(err).Msg("Failed to restore indexer")
+		return nil, errors.New("failed to restore indexer")
+	}
+
+	recordAudit(ctx, s.store, userID, auditIndexerRestore, auditEntityIndexer, foundIndexer.ID, nil)
+
+	_, logErr := s.store.CreateIndexingLog(ctx, db.CreateIndexingLogParams{
+		IndexerID: foundIndexer.ID,
+		EventType: "restored",
+		Message:   "Indexer restored",
+		Details:   []byte("{}"),
+	})
+	if logErr != nil {
+		log.Error().Err(logErr).Msg("Failed to create restore log entry")
+	}
+
+	log.Info().
+		Str("indexerID", indexerID.String()).
+		Str("status", string(foundIndexer.Status)).
+		Msg("Indexer restored")
+
+	return s.GetIndexerByID(ctx, userID, indexerID)
+}
+
+// PurgeDeletedIndexers removes the indexers deleted longer ago than the
+// delete retention, along with their webhooks, and reports how many went
+func (s *IndexerService) PurgeDeletedIndexers(ctx context.Context) (int, error) {
+	expired, err := s.store.GetExpiredDeletedIndexers(ctx, pgtype.Timestamptz{
+		Time:  time.Now().Add(-s.deleteRetention),
+		Valid: true,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired deleted indexers: %w", err)
+	}
+
+	purged := 0
+	for _, foundIndexer := range expired {
+		if err := s.purgeIndexer(ctx, foundIndexer); err != nil {
+			log.Error().Err(err).Str("indexerID", foundIndexer.ID.String()).Msg("Failed to purge deleted indexer")
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// PurgeFinishedPayloadJobs deletes the done and failed payload jobs older
+// than the payload job retention and reports how many went. A failed
+// payload is kept in failed_payloads, so nothing is lost with its job.
+func (s *IndexerService) PurgeFinishedPayloadJobs(ctx context.Context) (int64, error) {
+	purged, err := s.store.DeleteFinishedPayloadJobs(ctx, pgtype.Timestamptz{
+		Time:  time.Now().Add(-s.payloadJobRetention),
+		Valid: true,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete finished payload jobs: %w", err)
+	}
+	return purged, nil
+}
+
+// StartIndexerPurger runs PurgeDeletedIndexers, PurgeFinishedPayloadJobs and
+// PurgeRawPayloads every hour until the returned stop function is called
+func (s *IndexerService) StartIndexerPurger() (stop func()) {
+	done := make(chan struct{})
+	var once sync.Once
+
+	go func() {
+		ticker := time.NewTicker(deletedIndexerPurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), deletedIndexerPurgeTimeout)
+				purged, err := s.PurgeDeletedIndexers(ctx)
+				if err != nil {
+					log.Error().Err(err).Msg("Deleted indexer purge failed")
+				} else if purged > 0 {
+					log.Info().Int("purged", purged).Msg("Purged deleted indexers")
+				}
+
+				jobs, err := s.PurgeFinishedPayloadJobs(ctx)
+				if err != nil {
+					log.Error().Err(err).Msg("Payload job purge failed")
+				} else if jobs > 0 {
+					log.Info().Int64("purged", jobs).Msg("Purged finished payload jobs")
+				}
+
+				raw, err := s.PurgeRawPayloads(ctx)
+				if err != nil {
+					log.Error().Err(err).Msg("Raw payload purge failed")
+				} else if raw > 0 {
+					log.Info().Int64("purged", raw).Msg("Purged raw payloads")
+				}
+				cancel()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// softDeleteStore holds one indexer that can be soft deleted, restored and
+// purged the way the queries do it
+type softDeleteStore struct {
+	db.Querier
+	indexer db.Indexer
+	purged  bool
+	audits  []string
+}
+
+func newSoftDeleteStore(userID uuid.UUID) *softDeleteStore {
+	return &softDeleteStore{indexer: db.Indexer{
+		ID:             pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		UserID:         pgtype.UUID{Bytes: userID, Valid: true},
+		DbCredentialID: pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		Status:         db.IndexerStatusPaused,
+		IndexerType:    db.IndexerTypeNftPrices,
+		Params:         json.RawMessage(`{"collection": "collection"}`),
+	}}
+}
+
+func (s *softDeleteStore) GetIndexerByID(ctx context.Context, id pgtype.UUID) (db.Indexer, error) {
+	if s.purged || s.indexer.DeletedAt.Valid {
+		return db.Indexer{}, pgx.ErrNoRows
+	}
+	return s.indexer, nil
+}
+
+func (s *softDeleteStore) GetDeletedIndexerByID(ctx context.Context, id pgtype.UUID) (db.Indexer, error) {
+	if s.purged || !s.indexer.DeletedAt.Valid {
+		return db.Indexer{}, pgx.ErrNoRows
+	}
+	return s.indexer, nil
+}
+
+func (s *softDeleteStore) SoftDeleteIndexer(ctx context.Context, arg db.SoftDeleteIndexerParams) (db.Indexer, error) {
+	s.indexer.DeletedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return s.indexer, nil
+}
+
+func (s *softDeleteStore) RestoreIndexer(ctx context.Context, id pgtype.UUID) (db.Indexer, error) {
+	s.indexer.DeletedAt = pgtype.Timestamptz{}
+	return s.indexer, nil
+}
+
+func (s *softDeleteStore) GetExpiredDeletedIndexers(ctx context.Context, deletedAt pgtype.Timestamptz) ([]db.Indexer, error) {
+	if s.purged || !s.indexer.DeletedAt.Valid || !s.indexer.DeletedAt.Time.Before(deletedAt.Time) {
+		return nil, nil
+	}
+	return []db.Indexer{s.indexer}, nil
+}
+
+func (s *softDeleteStore) DeleteIndexer(ctx context.Context, arg db.DeleteIndexerParams) error {
+	s.purged = true
+	return nil
+}
+
+func (s *softDeleteStore) CreateIndexingLog(ctx context.Context, arg db.CreateIndexingLogParams) (db.IndexingLog, error) {
+	return db.IndexingLog{}, nil
+}
+
+func (s *softDeleteStore) CreateAuditEvent(ctx context.Context, arg db.CreateAuditEventParams) error {
+	s.audits = append(s.audits, arg.Action)
+	return nil
+}
+
+func TestDeleteThenRestoreIndexer(t *testing.T) {
+	userID := uuid.New()
+	store := newSoftDeleteStore(userID)
+	s := NewIndexerService(store, nil)
+	s.SetDeleteRetention(time.Hour)
+	ctx := context.Background()
+	indexerID := uuid.UUID(store.indexer.ID.Bytes)
+
+	if err := s.DeleteIndexer(ctx, userID, indexerID); err != nil {
+		t.Fatalf("DeleteIndexer: %v", err)
+	}
+	if store.purged {
+		t.Fatal("indexer purged at once despite the retention")
+	}
+	if _, err := s.GetIndexerByID(ctx, userID, indexerID); err == nil {
+		t.Error("deleted indexer still returned")
+	}
+
+	if _, err := s.RestoreIndexer(ctx, uuid.New(), indexerID); err == nil {
+		t.Error("another user restored the indexer")
+	}
+
+	restored, err := s.RestoreIndexer(ctx, userID, indexerID)
+	if err != nil {
+		t.Fatalf("RestoreIndexer: %v", err)
+	}
+	if restored.ID != indexerID || restored.Status != models.Paused {
+		t.Errorf("restored %s in status %s, want %s back in its old status", restored.ID, restored.Status, indexerID)
+	}
+	if _, err := s.GetIndexerByID(ctx, userID, indexerID); err != nil {
+		t.Errorf("restored indexer not returned: %v", err)
+	}
+	if len(store.audits) != 2 || store.audits[0] != auditIndexerDelete || store.audits[1] != auditIndexerRestore {
+		t.Errorf("audits = %v, want the delete and the restore", store.audits)
+	}
+}
+
+func TestDeleteThenExpireIndexer(t *testing.T) {
+	userID := uuid.New()
+	store := newSoftDeleteStore(userID)
+	s := NewIndexerService(store, nil)
+	s.SetDeleteRetention(time.Hour)
+	ctx := context.Background()
+	indexerID := uuid.UUID(store.indexer.ID.Bytes)
+
+	if err := s.DeleteIndexer(ctx, userID, indexerID); err != nil {
+		t.Fatalf("DeleteIndexer: %v", err)
+	}
+
+	// Nothing is purged inside the retention
+	if purged, err := s.PurgeDeletedIndexers(ctx); err != nil || purged != 0 {
+		t.Fatalf("PurgeDeletedIndexers = %d, %v; want nothing purged yet", purged, err)
+	}
+
+	store.indexer.DeletedAt.Time = time.Now().Add(-2 * time.Hour)
+	if _, err := s.RestoreIndexer(ctx, userID, indexerID); !errors.Is(err, ErrRestoreWindowPassed) {
+		t.Errorf("RestoreIndexer error = %v, want ErrRestoreWindowPassed", err)
+	}
+
+	purged, err := s.PurgeDeletedIndexers(ctx)
+	if err != nil {
+		t.Fatalf("PurgeDeletedIndexers: %v", err)
+	}
+	if purged != 1 || !store.purged {
+		t.Errorf("purged %d indexers, want the expired one removed", purged)
+	}
+	if _, err := s.RestoreIndexer(ctx, userID, indexerID); err == nil || errors.Is(err, ErrRestoreWindowPassed) {
+		t.Errorf("RestoreIndexer after the purge = %v, want the indexer not found", err)
+	}
+}
+
+func TestDeleteWithoutRetentionPurgesAtOnce(t *testing.T) {
+	userID := uuid.New()
+	store := newSoftDeleteStore(userID)
+	s := NewIndexerService(store, nil)
+	s.SetDeleteRetention(0)
+
+	if err := s.DeleteIndexer(context.Background(), userID, uuid.UUID(store.indexer.ID.Bytes)); err != nil {
+		t.Fatalf("DeleteIndexer: %v", err)
+	}
+	if !store.purged || store.indexer.DeletedAt.Valid {
+		t.Error("indexer soft deleted with no retention, want it purged")
+	}
+}
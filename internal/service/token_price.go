@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/pkg/logger"
+)
+
+// Methods used to combine platform prices into one
+const (
+	PriceMethodVWAP    = "vwap"
+	PriceMethodAverage = "average"
+)
+
+var (
+	// ErrNotTokenPriceIndexer is returned when a price is requested from an
+	// indexer of another type
+	ErrNotTokenPriceIndexer = errors.New("indexer is not a token_prices indexer")
+	// ErrTokenPriceNotFound is returned when no platform has a price for the token
+	ErrTokenPriceNotFound = errors.New("no price indexed for token")
+)
+
+// GetTokenPrice combines the per-platform rows of a token price indexer into
+// one price for token, weighting each platform by its 24h volume
+func (s *IndexerService) GetTokenPrice(ctx context.Context, userID uuid.UUID, indexerID uuid.UUID, token string) (*models.TokenPriceResponse, error) {
+
+	var pgIndexerID pgtype.UUID
+	if err := pgIndexerID.Scan(indexerID.String()); err != nil {
+		return nil, fmt.Errorf("invalid indexer ID: %w", err)
+	}
+
+	foundIndexer, err := s.store.GetIndexerByID(ctx, pgIndexerID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get indexer")
+		return nil, errors.New("indexer not found")
+	}
+
+	userIDFromDB, err := uuid.Parse(foundIndexer.UserID.String())
+	if err != nil || userIDFromDB != userID {
+		return nil, errors.New("indexer not found")
+	}
+
+	if foundIndexer.IndexerType != db.IndexerTypeTokenPrices {
+		return nil, ErrNotTokenPriceIndexer
+	}
+
+	pool, err := s.connectActivityPool(ctx, foundIndexer.DbCredentialID)
+	if err != nil {
+		log.Error().Err(logger.RedactError(err)).Msg("Failed to connect to target database")
+		return nil, errors.New("failed to connect to target database")
+	}
+	defer pool.Close()
+
+	targetTable := indexer.QuoteTableName(foundIndexer.TargetTable)
+
+	// Rows pre-seeded with metadata only carry a zero price and are skipped
+	rows, err := pool.Query(ctx, fmt.Sprintf(`
+		SELECT platform, price_usd::float8, volume_24h::float8, slot, updated_at
+		FROM %s
+		WHERE token_address = $1 AND price_usd > 0
+		ORDER BY platform
+	`, targetTable), token)
+	if err != nil {
+		log.Error().Err(err).Str("targetTable", targetTable).Msg("Failed to query token prices")
+		return nil, errors.New("failed to read token prices")
+	}
+
+	platforms, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.PlatformPrice, error) {
+		var p models.PlatformPrice
+		err := row.Scan(&p.Platform, &p.PriceUSD, &p.Volume24h, &p.Slot, &p.UpdatedAt)
+		return p, err
+	})
+	if err != nil {
+		log.Error().Err(err).Str("targetTable", targetTable).Msg("Failed to read token prices")
+		return nil, errors.New("failed to read token prices")
+	}
+
+	if len(platforms) == 0 {
+		return nil, ErrTokenPriceNotFound
+	}
+
+	price, method := volumeWeightedPrice(platforms)
+
+	return &models.TokenPriceResponse{
+		IndexerID: indexerID,
+		Token:     token,
+		PriceUSD:  price,
+		Method:    method,
+		Platforms: platforms,
+	}, nil
+}
+
+// volumeWeightedPrice returns the volume-weighted average of the platform
+// prices. Platforms without a positive volume carry no weight; when none has
+// one, it falls back to the plain average.
+func volumeWeightedPrice(platforms []models.PlatformPrice) (float64, string) {
+	var weighted, totalVolume, sum float64
+	for _, p := range platforms {
+		sum += p.PriceUSD
+		if p.Volume24h != nil && *p.Volume24h > 0 {
+			weighted += p.PriceUSD * *p.Volume24h
+			totalVolume += *p.Volume24h
+		}
+	}
+
+	if totalVolume > 0 {
+		return weighted / totalVolume, PriceMethodVWAP
+	}
+	if len(platforms) == 0 {
+		return 0, PriceMethodAverage
+	}
+	return sum / float64(len(platforms)), PriceMethodAverage
+}
+
+// Limits of a price history request
+const (
+	minPriceHistoryInterval = time.Minute
+	maxPriceHistoryBuckets  = 1000
+)
+
+var (
+	// ErrPriceHistoryDisabled is returned when price history is requested from
+	// an indexer created without keepHistory
+	ErrPriceHistoryDisabled = errors.New("indexer does not keep price history")
+	// ErrInvalidPriceHistoryRange is returned for a bad interval or time range
+	ErrInvalidPriceHistoryRange = errors.New("invalid price history range")
+)
+
+// GetTokenPriceHistory buckets the recorded prices of token between from and
+// to into OHLC intervals. Open and close are the first and last prices by slot.
+func (s *IndexerService) GetTokenPriceHistory(ctx context.Context, userID uuid.UUID, indexerID uuid.UUID, token string, interval time.Duration, from, to time.Time) (*models.TokenPriceHistoryResponse, error) {
+
+	if interval < minPriceHistoryInterval {
+		return nil, fmt.Errorf("%w: interval must be at least %s", ErrInvalidPriceHistoryRange, minPriceHistoryInterval)
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidPriceHistoryRange)
+	}
+	if to.Sub(from)/interval > maxPriceHistoryBuckets {
+		return nil, fmt.Errorf("%w: at most %d buckets per request", ErrInvalidPriceHistoryRange, maxPriceHistoryBuckets)
+	}
+
+	var pgIndexerID pgtype.UUID
+	if err := pgIndexerID.Scan(indexerID.String()); err != nil {
+		return nil, fmt.Errorf("invalid indexer ID: %w", err)
+	}
+
+	foundIndexer, err := s.store.GetIndexerByID(ctx, pgIndexerID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get indexer")
+		return nil, errors.New("indexer not found")
+	}
+
+	userIDFromDB, err := uuid.Parse(foundIndexer.UserID.String())
+	if err != nil || userIDFromDB != userID {
+		return nil, errors.New("indexer not found")
+	}
+
+	if foundIndexer.IndexerType != db.IndexerTypeTokenPrices {
+		return nil, ErrNotTokenPriceIndexer
+	}
+
+	var params models.TokenPriceParams
+	if err := json.Unmarshal(foundIndexer.Params, &params); err != nil || !params.KeepHistory {
+		return nil, ErrPriceHistoryDisabled
+	}
+
+	pool, err := s.connectActivityPool(ctx, foundIndexer.DbCredentialID)
+	if err != nil {
+		log.Error().Err(logger.RedactError(err)).Msg("Failed to connect to target database")
+		return nil, errors.New("failed to connect to target database")
+	}
+	defer pool.Close()
+
+	historyTable := indexer.PriceHistoryTable(foundIndexer.TargetTable)
+
+	rows, err := pool.Query(ctx, fmt.Sprintf(`
+		SELECT
+			to_timestamp(floor(extract(epoch FROM observed_at) / $2) * $2) AS bucket,
+			(array_agg(price_usd::float8 ORDER BY slot, id))[1],
+			MAX(price_usd)::float8,
+			MIN(price_usd)::float8,
+			(array_agg(price_usd::float8 ORDER BY slot DESC, id DESC))[1],
+			COUNT(*)
+		FROM %s
+		WHERE token_address = $1 AND observed_at >= $3 AND observed_at < $4
+		GROUP BY bucket
+		ORDER BY bucket
+	`, historyTable), token, interval.Seconds(), from, to)
+	if err != nil {
+		log.Error().Err(err).Str("historyTable", historyTable).Msg("Failed to query token price history")
+		return nil, errors.New("failed to read token price history")
+	}
+
+	buckets, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.PriceBucket, error) {
+		var b models.PriceBucket
+		err := row.Scan(&b.Start, &b.Open, &b.High, &b.Low, &b.Close, &b.Samples)
+		return b, err
+	})
+	if err != nil {
+		log.Error().Err(err).Str("historyTable", historyTable).Msg("Failed to read token price history")
+		return nil, errors.New("failed to read token price history")
+	}
+
+	return &models.TokenPriceHistoryResponse{
+		IndexerID: indexerID,
+		Token:     token,
+		Interval:  interval.String(),
+		From:      from,
+		To:        to,
+		Buckets:   buckets,
+	}, nil
+}
+
+// GetTokens returns the most recently updated row of each token a token price
+// indexer tracks, only looking at the given platform when it isn't empty
+func (s *IndexerService) GetTokens(ctx context.Context, userID uuid.UUID, indexerID uuid.UUID, platform string) ([]models.TokenPriceRow, error) {
+
+	var pgIndexerID pgtype.UUID
+	if err := pgIndexerID.Scan(indexerID.String()); err != nil {
+		return nil, fmt.Errorf("invalid indexer ID: %w", err)
+	}
+
+	foundIndexer, err := s.store.GetIndexerByID(ctx, pgIndexerID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get indexer")
+		return nil, errors.New("indexer not found")
+	}
+
+	userIDFromDB, err := uuid.Parse(foundIndexer.UserID.String())
+	if err != nil || userIDFromDB != userID {
+		return nil, errors.New("indexer not found")
+	}
+
+	if foundIndexer.IndexerType != db.IndexerTypeTokenPrices {
+		return nil, ErrNotTokenPriceIndexer
+	}
+
+	tokens := indexerAddresses(models.TokenPrices, foundIndexer.Params)
+	if len(tokens) == 0 {
+		return []models.TokenPriceRow{}, nil
+	}
+
+	pool, err := s.connectActivityPool(ctx, foundIndexer.DbCredentialID)
+	if err != nil {
+		log.Error().Err(logger.RedactError(err)).Msg("Failed to connect to target database")
+		return nil, errors.New("failed to connect to target database")
+	}
+	defer pool.Close()
+
+	targetTable := indexer.QuoteTableName(foundIndexer.TargetTable)
+
+	rows, err := pool.Query(ctx, fmt.Sprintf(`
+		SELECT DISTINCT ON (token_address) %s
+		FROM %s
+		WHERE token_address = ANY($1) AND ($2 = '' OR platform = $2)
+		ORDER BY token_address, updated_at DESC
+	`, tokenPriceColumns, targetTable), tokens, platform)
+	if err != nil {
+		log.Error().Err(err).Str("targetTable", targetTable).Msg("Failed to query tokens")
+		return nil, errors.New("failed to read tokens")
+	}
+
+	result, err := pgx.CollectRows(rows, scanTokenPrice)
+	if err != nil {
+		log.Error().Err(err).Str("targetTable", targetTable).Msg("Failed to read tokens")
+		return nil, errors.New("failed to read tokens")
+	}
+
+	return result, nil
+}
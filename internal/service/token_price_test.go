@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+func volume(v float64) *float64 {
+	return &v
+}
+
+func TestVolumeWeightedPrice(t *testing.T) {
+	tests := []struct {
+		name       string
+		platforms  []models.PlatformPrice
+		wantPrice  float64
+		wantMethod string
+	}{
+		{
+			name: "weighted by volume",
+			platforms: []models.PlatformPrice{
+				{Platform: "RAYDIUM", PriceUSD: 1.00, Volume24h: volume(300)},
+				{Platform: "ORCA", PriceUSD: 2.00, Volume24h: volume(100)},
+			},
+			wantPrice:  1.25,
+			wantMethod: PriceMethodVWAP,
+		},
+		{
+			name: "platforms without volume carry no weight",
+			platforms: []models.PlatformPrice{
+				{Platform: "RAYDIUM", PriceUSD: 1.00, Volume24h: volume(100)},
+				{Platform: "ORCA", PriceUSD: 5.00},
+				{Platform: "JUPITER", PriceUSD: 9.00, Volume24h: volume(0)},
+			},
+			wantPrice:  1.00,
+			wantMethod: PriceMethodVWAP,
+		},
+		{
+			name: "no volume falls back to the average",
+			platforms: []models.PlatformPrice{
+				{Platform: "RAYDIUM", PriceUSD: 1.00},
+				{Platform: "ORCA", PriceUSD: 2.00},
+			},
+			wantPrice:  1.50,
+			wantMethod: PriceMethodAverage,
+		},
+		{
+			name:       "no platforms",
+			wantPrice:  0,
+			wantMethod: PriceMethodAverage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, method := volumeWeightedPrice(tt.platforms)
+			if math.Abs(price-tt.wantPrice) > 1e-9 || method != tt.wantMethod {
+				t.Errorf("volumeWeightedPrice = %v (%s), want %v (%s)", price, method, tt.wantPrice, tt.wantMethod)
+			}
+		})
+	}
+}
+
+func TestGetTokenPriceRejectsOtherIndexerTypes(t *testing.T) {
+	userID := uuid.New()
+	store := newRawStore(userID)
+	s := NewIndexerService(store, nil)
+
+	_, err := s.GetTokenPrice(context.Background(), userID, uuid.UUID(store.indexer.ID.Bytes), "mint")
+	if !errors.Is(err, ErrNotTokenPriceIndexer) {
+		t.Errorf("GetTokenPrice error = %v, want ErrNotTokenPriceIndexer", err)
+	}
+
+	store.indexer.IndexerType = db.IndexerTypeTokenPrices
+	if _, err := s.GetTokenPrice(context.Background(), uuid.New(), uuid.UUID(store.indexer.ID.Bytes), "mint"); err == nil {
+		t.Error("GetTokenPrice of another user's indexer succeeded")
+	}
+}
+
+func TestGetTokenPriceHistoryValidatesRange(t *testing.T) {
+	userID := uuid.New()
+	store := newRawStore(userID)
+	store.indexer.IndexerType = db.IndexerTypeTokenPrices
+	store.indexer.Params = json.RawMessage(`{"tokens": ["mint"]}`)
+	s := NewIndexerService(store, nil)
+	indexerID := uuid.UUID(store.indexer.ID.Bytes)
+
+	now := time.Now()
+	tests := []struct {
+		name     string
+		interval time.Duration
+		from, to time.Time
+	}{
+		{name: "interval too short", interval: time.Second, from: now.Add(-time.Hour), to: now},
+		{name: "from after to", interval: time.Minute, from: now, to: now.Add(-time.Hour)},
+		{name: "too many buckets", interval: time.Minute, from: now.Add(-24 * time.Hour), to: now},
+	}
+	for _, tt := range tests {
+		_, err := s.GetTokenPriceHistory(context.Background(), userID, indexerID, "mint", tt.interval, tt.from, tt.to)
+		if !errors.Is(err, ErrInvalidPriceHistoryRange) {
+			t.Errorf("%s: error = %v, want ErrInvalidPriceHistoryRange", tt.name, err)
+		}
+	}
+
+	// A valid range still needs an indexer that keeps history
+	_, err := s.GetTokenPriceHistory(context.Background(), userID, indexerID, "mint", time.Hour, now.Add(-24*time.Hour), now)
+	if !errors.Is(err, ErrPriceHistoryDisabled) {
+		t.Errorf("GetTokenPriceHistory error = %v, want ErrPriceHistoryDisabled", err)
+	}
+}
+
+func TestTokenPriceIndexersShareTheServiceMetadataCache(t *testing.T) {
+	tokenIndexer := func(s *IndexerService) *indexer.TokenPriceIndexer {
+		t.Helper()
+
+		store := newRawStore(uuid.New())
+		store.indexer.IndexerType = db.IndexerTypeTokenPrices
+		store.indexer.Params = json.RawMessage(`{"tokens": ["mint"]}`)
+		impl, err := s.getOrCreateIndexerImpl(context.Background(), store.indexer)
+		if err != nil {
+			t.Fatalf("getOrCreateIndexerImpl: %v", err)
+		}
+		return impl.(*indexer.TokenPriceIndexer)
+	}
+
+	s := NewIndexerService(newRawStore(uuid.New()), nil)
+	cache := indexer.NewTokenMetadataCache()
+	s.SetMetadataCache(cache)
+
+	first, second := tokenIndexer(s), tokenIndexer(s)
+	if first.MetadataCache != cache || second.MetadataCache != cache {
+		t.Error("token price indexers of one service do not use the cache it was given")
+	}
+
+	other := tokenIndexer(NewIndexerService(newRawStore(uuid.New()), nil))
+	if other.MetadataCache == cache {
+		t.Error("token price indexer of another service uses the same cache")
+	}
+}
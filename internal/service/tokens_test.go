@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+)
+
+func TestGetTokensRejectsOtherIndexers(t *testing.T) {
+	userID := uuid.New()
+	store := newRawStore(userID)
+	s := NewIndexerService(store, nil)
+	indexerID := uuid.UUID(store.indexer.ID.Bytes)
+
+	if _, err := s.GetTokens(context.Background(), userID, indexerID, ""); !errors.Is(err, ErrNotTokenPriceIndexer) {
+		t.Errorf("GetTokens error = %v, want ErrNotTokenPriceIndexer", err)
+	}
+
+	store.indexer.IndexerType = db.IndexerTypeTokenPrices
+	store.indexer.Params = json.RawMessage(`{"tokens": []}`)
+	if _, err := s.GetTokens(context.Background(), uuid.New(), indexerID, ""); err == nil {
+		t.Error("GetTokens of another user's indexer succeeded")
+	}
+
+	// Without tokens there is nothing to read, so the target isn't touched
+	tokens, err := s.GetTokens(context.Background(), userID, indexerID, "")
+	if err != nil || tokens == nil || len(tokens) != 0 {
+		t.Errorf("GetTokens = %v, %v; want an empty list", tokens, err)
+	}
+}
+
+func TestGetTokensReadsLatestRowPerToken(t *testing.T) {
+	cred, pool := testTarget(t)
+	table := testTable(t, pool)
+
+	userID := uuid.New()
+	store := newRawStore(userID)
+	store.cred = cred
+	store.indexer.IndexerType = db.IndexerTypeTokenPrices
+	store.indexer.Params = json.RawMessage(`{"tokens": ["tok-a", "tok-b"]}`)
+	store.indexer.TargetTable = table
+
+	idx, err := indexer.NewTokenPriceIndexer("test", store.indexer.Params)
+	if err != nil {
+		t.Fatalf("NewTokenPriceIndexer: %v", err)
+	}
+	initializeTable(t, pool, idx, table)
+
+	if _, err := pool.Exec(context.Background(), fmt.Sprintf(`
+		INSERT INTO %s (token_address, token_name, token_symbol, platform, price_usd, price_sol,
+			volume_24h, market_cap, liquidity, price_change_24h, total_supply, transaction_id, updated_at, slot)
+		VALUES
+			('tok-a', NULL, NULL, 'JUPITER', 1.0, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NOW() - INTERVAL '1 hour', 1),
+			('tok-a', 'Token A', 'TKA', 'RAYDIUM', 1.1, 0.01, 500, 1000000, 200000, 5, 1000000000, 'sig-a', NOW(), 2),
+			('tok-b', NULL, NULL, 'JUPITER', 2.0, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NOW(), 3),
+			('untracked', NULL, NULL, 'JUPITER', 9.0, NULL, NULL, NULL, NULL, NULL, NULL, NULL, NOW(), 4)
+	`, indexer.QuoteTableName(table))); err != nil {
+		t.Fatalf("seed token rows: %v", err)
+	}
+
+	s := NewIndexerService(store, nil)
+	ctx := context.Background()
+	indexerID := uuid.UUID(store.indexer.ID.Bytes)
+
+	tokens, err := s.GetTokens(ctx, userID, indexerID, "")
+	if err != nil {
+		t.Fatalf("GetTokens: %v", err)
+	}
+	if len(tokens) != 2 || tokens[0].TokenAddress != "tok-a" || tokens[1].TokenAddress != "tok-b" {
+		t.Fatalf("tokens = %+v, want the latest row of tok-a and tok-b", tokens)
+	}
+
+	a := tokens[0]
+	if a.Platform != "RAYDIUM" || a.PriceUSD != 1.1 || a.Slot != 2 {
+		t.Errorf("tok-a = %s at %v in slot %d, want the newer RAYDIUM row", a.Platform, a.PriceUSD, a.Slot)
+	}
+	if a.TokenName == nil || *a.TokenName != "Token A" || a.TokenSymbol == nil || *a.TokenSymbol != "TKA" {
+		t.Errorf("tok-a name %v, symbol %v, want Token A (TKA)", a.TokenName, a.TokenSymbol)
+	}
+	for name, got := range map[string]*float64{
+		"price_sol": a.PriceSOL, "volume_24h": a.Volume24h, "market_cap": a.MarketCap,
+		"liquidity": a.Liquidity, "price_change_24h": a.PriceChange24h, "total_supply": a.TotalSupply,
+	} {
+		if got == nil {
+			t.Errorf("tok-a %s is nil, want the enriched value", name)
+		}
+	}
+	if a.TransactionID == nil || *a.TransactionID != "sig-a" {
+		t.Errorf("tok-a transaction = %v, want sig-a", a.TransactionID)
+	}
+
+	b := tokens[1]
+	if b.TokenName != nil || b.PriceSOL != nil || b.Volume24h != nil || b.MarketCap != nil || b.TransactionID != nil {
+		t.Errorf("tok-b = %+v, want its missing columns left nil", b)
+	}
+
+	jupiter, err := s.GetTokens(ctx, userID, indexerID, "JUPITER")
+	if err != nil {
+		t.Fatalf("GetTokens on JUPITER: %v", err)
+	}
+	if len(jupiter) != 2 || jupiter[0].Platform != "JUPITER" || jupiter[0].PriceUSD != 1.0 {
+		t.Errorf("JUPITER tokens = %+v, want tok-a's older JUPITER row and tok-b", jupiter)
+	}
+
+	orca, err := s.GetTokens(ctx, userID, indexerID, "ORCA")
+	if err != nil || len(orca) != 0 {
+		t.Errorf("ORCA tokens = %+v, %v; want none", orca, err)
+	}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// TransactionPayload turns one transaction of a Helius delivery or history
+// page, enhanced or raw, into a payload. It reports false for data that isn't
+// a transaction object or has no signature.
+func TransactionPayload(txData json.RawMessage) (models.HeliusWebhookPayload, bool) {
+	var tx map[string]interface{}
+	if err := json.Unmarshal(txData, &tx); err != nil {
+		return models.HeliusWebhookPayload{}, false
+	}
+
+	slot, signature := TransactionIdentity(tx)
+	if signature == "" {
+		return models.HeliusWebhookPayload{}, false
+	}
+
+	// Enhanced transactions carry timestamp, raw ones blockTime
+	timestamp, _ := tx["timestamp"].(float64)
+	if timestamp == 0 {
+		timestamp, _ = tx["blockTime"].(float64)
+	}
+
+	// Raw transactions are kept whole in EnhancedDetails as well; the
+	// indexers that accept raw webhooks search it for their accounts
+	enhancedDetails, _ := json.Marshal(tx)
+
+	return models.HeliusWebhookPayload{
+		Slot: slot,
+		Transaction: models.HeliusTransaction{
+			ID:              signature,
+			Signatures:      []string{signature},
+			EnhancedDetails: enhancedDetails,
+			Timestamp:       int64(timestamp),
+		},
+		SchemaVersion: PayloadSchemaVersion(txData),
+	}, true
+}
+
+// WebhookPayload parses a delivery of a single payload rather than an array of
+// transactions. A single transaction may also name its slot and signature at
+// the top level rather than in the payload shape. A payload without a
+// signature is returned as is for the caller to skip.
+func WebhookPayload(body json.RawMessage) (models.HeliusWebhookPayload, error) {
+	var payload models.HeliusWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return models.HeliusWebhookPayload{}, fmt.Errorf("failed to parse webhook payload: %w", err)
+	}
+
+	if payload.Slot == 0 || len(payload.Transaction.Signatures) == 0 {
+		var tx map[string]interface{}
+		if err := json.Unmarshal(body, &tx); err == nil {
+			slot, signature := TransactionIdentity(tx)
+			if payload.Slot == 0 {
+				payload.Slot = slot
+			}
+			if len(payload.Transaction.Signatures) == 0 && signature != "" {
+				payload.Transaction.Signatures = []string{signature}
+				if payload.Transaction.ID == "" {
+					payload.Transaction.ID = signature
+				}
+			}
+		}
+	}
+
+	if payload.SchemaVersion == "" {
+		payload.SchemaVersion = PayloadSchemaVersion(body)
+	}
+	return payload, nil
+}
+
+// TransactionIdentity returns the slot and signature of a transaction. An
+// enhanced transaction has the signature at the top level, a raw one nests
+// its signatures under transaction.signatures. Fields that are missing or of
+// the wrong type come back zero.
+func TransactionIdentity(tx map[string]interface{}) (slot int64, signature string) {
+	switch v := tx["slot"].(type) {
+	case float64:
+		slot = int64(v)
+	case string:
+		slot, _ = strconv.ParseInt(v, 10, 64)
+	}
+
+	if sig, ok := tx["signature"].(string); ok {
+		return slot, sig
+	}
+	if raw, ok := tx["transaction"].(map[string]interface{}); ok {
+		if signatures, ok := raw["signatures"].([]interface{}); ok && len(signatures) > 0 {
+			signature, _ = signatures[0].(string)
+		}
+	}
+	return slot, signature
+}
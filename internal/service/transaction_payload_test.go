@@ -0,0 +1,96 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTransactionIdentity(t *testing.T) {
+	tests := []struct {
+		name          string
+		tx            string
+		wantSlot      int64
+		wantSignature string
+	}{
+		{name: "enhanced", tx: `{"signature": "enhanced-sig", "slot": 10}`, wantSlot: 10, wantSignature: "enhanced-sig"},
+		{name: "raw", tx: `{"slot": 11, "transaction": {"signatures": ["raw-sig", "second-sig"]}}`, wantSlot: 11, wantSignature: "raw-sig"},
+		{name: "slot as string", tx: `{"signature": "sig", "slot": "12"}`, wantSlot: 12, wantSignature: "sig"},
+		{name: "raw without signatures", tx: `{"slot": 13, "transaction": {"signatures": []}}`, wantSlot: 13},
+		{name: "wrong types", tx: `{"signature": 42, "slot": true, "transaction": "raw"}`},
+		{name: "empty", tx: `{}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tx map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.tx), &tx); err != nil {
+				t.Fatalf("unmarshal transaction: %v", err)
+			}
+
+			slot, signature := TransactionIdentity(tx)
+			if slot != tt.wantSlot || signature != tt.wantSignature {
+				t.Errorf("got slot %d and signature %q, want %d and %q", slot, signature, tt.wantSlot, tt.wantSignature)
+			}
+		})
+	}
+}
+
+func TestTransactionPayload(t *testing.T) {
+	tests := []struct {
+		name          string
+		tx            string
+		wantSlot      int64
+		wantSignature string
+		wantTimestamp int64
+	}{
+		{
+			name:          "enhanced",
+			tx:            `{"signature": "enhanced-sig", "slot": 10, "timestamp": 1700000000, "type": "NFT_SALE"}`,
+			wantSlot:      10,
+			wantSignature: "enhanced-sig",
+			wantTimestamp: 1700000000,
+		},
+		{
+			name:          "raw",
+			tx:            `{"slot": 11, "blockTime": 1700000001, "transaction": {"signatures": ["raw-sig"]}}`,
+			wantSlot:      11,
+			wantSignature: "raw-sig",
+			wantTimestamp: 1700000001,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, ok := TransactionPayload(json.RawMessage(tt.tx))
+			if !ok {
+				t.Fatal("TransactionPayload rejected a transaction with a signature")
+			}
+			if payload.Slot != tt.wantSlot || payload.Transaction.ID != tt.wantSignature || payload.Transaction.Timestamp != tt.wantTimestamp {
+				t.Errorf("got slot %d, ID %q, timestamp %d; want %d, %q, %d",
+					payload.Slot, payload.Transaction.ID, payload.Transaction.Timestamp, tt.wantSlot, tt.wantSignature, tt.wantTimestamp)
+			}
+			if len(payload.Transaction.Signatures) != 1 || payload.Transaction.Signatures[0] != tt.wantSignature {
+				t.Errorf("signatures = %v, want [%s]", payload.Transaction.Signatures, tt.wantSignature)
+			}
+
+			// The whole transaction is kept for the indexers to read
+			var details map[string]interface{}
+			if err := json.Unmarshal(payload.Transaction.EnhancedDetails, &details); err != nil {
+				t.Fatalf("EnhancedDetails is not JSON: %v", err)
+			}
+			var original map[string]interface{}
+			json.Unmarshal([]byte(tt.tx), &original)
+			if len(details) != len(original) {
+				t.Errorf("EnhancedDetails = %s, want the whole transaction", payload.Transaction.EnhancedDetails)
+			}
+		})
+	}
+}
+
+func TestTransactionPayloadRejectsNonTransactions(t *testing.T) {
+	for _, tx := range []string{`"not an object"`, `[1, 2]`, `{"slot": 1}`, `{"signature": ""}`, `not json`} {
+		if _, ok := TransactionPayload(json.RawMessage(tx)); ok {
+			t.Errorf("TransactionPayload accepted %s", tx)
+		}
+	}
+}
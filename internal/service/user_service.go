@@ -12,6 +12,7 @@ import (
 
 	db "github.com/rishavmehra/indexer/internal/db/generated"
 	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/pkg/logger"
 	"github.com/rishavmehra/indexer/pkg/validator"
 )
 
@@ -58,6 +59,14 @@ func (s *UserService) CreateDBCredential(ctx context.Context, userID uuid.UUID,
 		return nil, err
 	}
 
+	if err := validator.ValidateSSLMode(req.SSLMode); err != nil {
+		return nil, err
+	}
+
+	if req.TableOwner != "" && !validator.IsValidRoleName(req.TableOwner) {
+		return nil, errors.New("invalid table owner role name")
+	}
+
 	var pgUserID pgtype.UUID
 	if err := pgUserID.Scan(userID.String()); err != nil {
 		log.Error().Err(err).Msg("Failed to convert UUID")
@@ -77,12 +86,18 @@ func (s *UserService) CreateDBCredential(ctx context.Context, userID uuid.UUID,
 		DbUser:     req.User,
 		DbPassword: req.Password,
 		DbSslMode:  sslMode,
+		TableOwner: pgtype.Text{String: req.TableOwner, Valid: req.TableOwner != ""},
 	})
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create DB credential")
 		return nil, errors.New("failed to create database credential")
 	}
 
+	recordAudit(ctx, s.store, userID, auditCredentialCreate, auditEntityCredential, cred.ID, map[string]interface{}{
+		"host": cred.DbHost,
+		"name": cred.DbName,
+	})
+
 	id, err := uuid.Parse(cred.ID.String())
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to parse credential ID")
@@ -96,15 +111,16 @@ func (s *UserService) CreateDBCredential(ctx context.Context, userID uuid.UUID,
 	}
 
 	return &models.DBCredentialResponse{
-		ID:        id,
-		UserID:    userIDParsed,
-		Host:      cred.DbHost,
-		Port:      int(cred.DbPort),
-		Name:      cred.DbName,
-		User:      cred.DbUser,
-		SSLMode:   cred.DbSslMode,
-		CreatedAt: cred.CreatedAt.Time,
-		UpdatedAt: cred.UpdatedAt.Time,
+		ID:         id,
+		UserID:     userIDParsed,
+		Host:       cred.DbHost,
+		Port:       int(cred.DbPort),
+		Name:       cred.DbName,
+		User:       cred.DbUser,
+		SSLMode:    cred.DbSslMode,
+		TableOwner: cred.TableOwner.String,
+		CreatedAt:  cred.CreatedAt.Time,
+		UpdatedAt:  cred.UpdatedAt.Time,
 	}, nil
 }
 
@@ -114,6 +130,14 @@ func (s *UserService) UpdateDBCredential(ctx context.Context, userID uuid.UUID,
 		return nil, err
 	}
 
+	if err := validator.ValidateSSLMode(req.SSLMode); err != nil {
+		return nil, err
+	}
+
+	if req.TableOwner != "" && !validator.IsValidRoleName(req.TableOwner) {
+		return nil, errors.New("invalid table owner role name")
+	}
+
 	var pgCredID pgtype.UUID
 	if err := pgCredID.Scan(credID.String()); err != nil {
 		log.Error().Err(err).Msg("Failed to convert credential ID")
@@ -143,12 +167,18 @@ func (s *UserService) UpdateDBCredential(ctx context.Context, userID uuid.UUID,
 		DbUser:     req.User,
 		DbPassword: req.Password,
 		DbSslMode:  sslMode,
+		TableOwner: pgtype.Text{String: req.TableOwner, Valid: req.TableOwner != ""},
 	})
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to update DB credential")
 		return nil, errors.New("failed to update database credential")
 	}
 
+	recordAudit(ctx, s.store, userID, auditCredentialUpdate, auditEntityCredential, updatedCred.ID, map[string]interface{}{
+		"host": updatedCred.DbHost,
+		"name": updatedCred.DbName,
+	})
+
 	id, err := uuid.Parse(updatedCred.ID.String())
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to parse credential ID")
@@ -162,15 +192,16 @@ func (s *UserService) UpdateDBCredential(ctx context.Context, userID uuid.UUID,
 	}
 
 	return &models.DBCredentialResponse{
-		ID:        id,
-		UserID:    userIDParsed,
-		Host:      updatedCred.DbHost,
-		Port:      int(updatedCred.DbPort),
-		Name:      updatedCred.DbName,
-		User:      updatedCred.DbUser,
-		SSLMode:   updatedCred.DbSslMode,
-		CreatedAt: updatedCred.CreatedAt.Time,
-		UpdatedAt: updatedCred.UpdatedAt.Time,
+		ID:         id,
+		UserID:     userIDParsed,
+		Host:       updatedCred.DbHost,
+		Port:       int(updatedCred.DbPort),
+		Name:       updatedCred.DbName,
+		User:       updatedCred.DbUser,
+		SSLMode:    updatedCred.DbSslMode,
+		TableOwner: updatedCred.TableOwner.String,
+		CreatedAt:  updatedCred.CreatedAt.Time,
+		UpdatedAt:  updatedCred.UpdatedAt.Time,
 	}, nil
 }
 
@@ -204,15 +235,16 @@ func (s *UserService) GetDBCredentials(ctx context.Context, userID uuid.UUID) ([
 		}
 
 		response[i] = models.DBCredentialResponse{
-			ID:        id,
-			UserID:    userIDParsed,
-			Host:      cred.DbHost,
-			Port:      int(cred.DbPort),
-			Name:      cred.DbName,
-			User:      cred.DbUser,
-			SSLMode:   cred.DbSslMode,
-			CreatedAt: cred.CreatedAt.Time,
-			UpdatedAt: cred.UpdatedAt.Time,
+			ID:         id,
+			UserID:     userIDParsed,
+			Host:       cred.DbHost,
+			Port:       int(cred.DbPort),
+			Name:       cred.DbName,
+			User:       cred.DbUser,
+			SSLMode:    cred.DbSslMode,
+			TableOwner: cred.TableOwner.String,
+			CreatedAt:  cred.CreatedAt.Time,
+			UpdatedAt:  cred.UpdatedAt.Time,
 		}
 	}
 
@@ -245,15 +277,16 @@ func (s *UserService) GetDBCredentialByID(ctx context.Context, userID uuid.UUID,
 	}
 
 	return &models.DBCredentialResponse{
-		ID:        id,
-		UserID:    credUserID,
-		Host:      cred.DbHost,
-		Port:      int(cred.DbPort),
-		Name:      cred.DbName,
-		User:      cred.DbUser,
-		SSLMode:   cred.DbSslMode,
-		CreatedAt: cred.CreatedAt.Time,
-		UpdatedAt: cred.UpdatedAt.Time,
+		ID:         id,
+		UserID:     credUserID,
+		Host:       cred.DbHost,
+		Port:       int(cred.DbPort),
+		Name:       cred.DbName,
+		User:       cred.DbUser,
+		SSLMode:    cred.DbSslMode,
+		TableOwner: cred.TableOwner.String,
+		CreatedAt:  cred.CreatedAt.Time,
+		UpdatedAt:  cred.UpdatedAt.Time,
 	}, nil
 }
 
@@ -281,7 +314,8 @@ func (s *UserService) DeleteDBCredential(ctx context.Context, userID uuid.UUID,
 		return errors.New("database credential not found")
 	}
 
-	indexers, err := s.store.GetIndexersByUserID(ctx, pgUserID)
+	// An indexer created a moment ago may not be on the replica yet
+	indexers, err := primaryStore(s.store).GetIndexersByUserID(ctx, pgUserID)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get indexers")
 		return errors.New("failed to verify credential usage")
@@ -303,6 +337,11 @@ func (s *UserService) DeleteDBCredential(ctx context.Context, userID uuid.UUID,
 		return errors.New("failed to delete database credential")
 	}
 
+	recordAudit(ctx, s.store, userID, auditCredentialDelete, auditEntityCredential, pgCredID, map[string]interface{}{
+		"host": cred.DbHost,
+		"name": cred.DbName,
+	})
+
 	return nil
 }
 
@@ -311,6 +350,10 @@ func (s *UserService) TestDBConnection(ctx context.Context, req models.DBCredent
 		return err
 	}
 
+	if err := validator.ValidateSSLMode(req.SSLMode); err != nil {
+		return err
+	}
+
 	sslMode := req.SSLMode
 	if sslMode == "" {
 		sslMode = "disable"
@@ -323,7 +366,7 @@ func (s *UserService) TestDBConnection(ctx context.Context, req models.DBCredent
 	conn, err := pgx.Connect(ctx, dsn)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to connect to database")
-		return fmt.Errorf("connection failed: %w", err)
+		return fmt.Errorf("connection failed: %w", logger.RedactError(err))
 	}
 	defer conn.Close(ctx)
 
@@ -332,7 +375,7 @@ func (s *UserService) TestDBConnection(ctx context.Context, req models.DBCredent
 	err = conn.QueryRow(ctx, "SELECT 1").Scan(&result)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to execute test query")
-		return fmt.Errorf("query test failed: %w", err)
+		return fmt.Errorf("query test failed: %w", logger.RedactError(err))
 	}
 
 	if result != 1 {
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/pkg/validator"
+)
+
+func credentialRequest(sslMode string) models.DBCredentialRequest {
+	return models.DBCredentialRequest{
+		Host:     "db.example.com",
+		Port:     5432,
+		Name:     "indexer",
+		User:     "indexer",
+		Password: "password",
+		SSLMode:  sslMode,
+	}
+}
+
+func TestCreateDBCredentialAcceptsPostgresSSLModes(t *testing.T) {
+	s := NewUserService(newCredStore())
+
+	for _, mode := range append([]string{""}, validator.SSLModes...) {
+		cred, err := s.CreateDBCredential(context.Background(), uuid.New(), credentialRequest(mode))
+		if err != nil {
+			t.Errorf("CreateDBCredential(sslMode %q): %v", mode, err)
+			continue
+		}
+
+		want := mode
+		if want == "" {
+			want = "disable"
+		}
+		if cred.SSLMode != want {
+			t.Errorf("sslMode %q stored as %q, want %q", mode, cred.SSLMode, want)
+		}
+	}
+}
+
+func TestCreateDBCredentialRejectsUnknownSSLMode(t *testing.T) {
+	store := newCredStore()
+	s := NewUserService(store)
+
+	_, err := s.CreateDBCredential(context.Background(), uuid.New(), credentialRequest("requre"))
+	if err == nil {
+		t.Fatal("CreateDBCredential accepted sslMode requre")
+	}
+	if len(store.creds) != 0 {
+		t.Errorf("stored %d credentials, want none for a rejected sslMode", len(store.creds))
+	}
+}
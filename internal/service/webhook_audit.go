@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/pkg/logger"
+)
+
+// ErrHeliusNotConfigured is returned by operations that need the Helius client
+var ErrHeliusNotConfigured = errors.New("helius client is not configured")
+
+// AuditWebhooks lists the webhooks registered at Helius and matches each to
+// an indexer: through the id parameter of its callback URL, the in-memory
+// webhook mapping or the indexer's stored webhook ID. Webhooks calling this
+// server that match no indexer are flagged as orphaned, and indexers whose
+// webhook matches none at Helius are listed as missing.
+func (s *IndexerService) AuditWebhooks(ctx context.Context) (*models.WebhookAudit, error) {
+	if s.heliusClient == nil {
+		return nil, ErrHeliusNotConfigured
+	}
+
+	webhooks, err := s.heliusClient.ListWebhooks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Helius webhooks: %w", logger.RedactError(err))
+	}
+
+	indexers, err := s.store.ListIndexers(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list indexers")
+		return nil, errors.New("failed to list indexers")
+	}
+
+	byID := make(map[string]db.Indexer, len(indexers))
+	byWebhookID := make(map[string]db.Indexer)
+	for _, idx := range indexers {
+		byID[idx.ID.String()] = idx
+		if idx.WebhookID.Valid && idx.WebhookID.String != "" {
+			byWebhookID[idx.WebhookID.String] = idx
+		}
+	}
+
+	audit := &models.WebhookAudit{
+		Webhooks:        []models.AuditedWebhook{},
+		MissingWebhooks: []models.MissingWebhook{},
+	}
+	covered := make(map[string]bool)
+
+	for _, webhook := range webhooks {
+		entry := models.AuditedWebhook{
+			WebhookID:        webhook.WebhookID,
+			WebhookURL:       logger.Redact(webhook.WebhookURL),
+			TransactionTypes: webhook.TransactionTypes,
+			AddressCount:     len(webhook.AccountAddresses),
+			Managed:          s.isManagedWebhookURL(webhook.WebhookURL),
+			Shared:           webhook.WebhookID == s.heliusClient.GetDefaultWebhookID(),
+		}
+
+		if idx, ok := s.webhookIndexer(webhook, byID, byWebhookID); ok {
+			entry.IndexerID = idx.ID.String()
+			covered[entry.IndexerID] = true
+		} else if entry.Managed && !entry.Shared {
+			entry.Orphaned = true
+			audit.OrphanCount++
+		}
+
+		audit.Webhooks = append(audit.Webhooks, entry)
+	}
+
+	for _, idx := range indexers {
+		if !idx.WebhookID.Valid || idx.WebhookID.String == "" || covered[idx.ID.String()] {
+			continue
+		}
+		// Indexers on the shared webhook have no webhook of their own
+		if idx.WebhookID.String == s.heliusClient.GetDefaultWebhookID() {
+			continue
+		}
+
+		indexerID, err := uuid.Parse(idx.ID.String())
+		if err != nil {
+			continue
+		}
+		audit.MissingWebhooks = append(audit.MissingWebhooks, models.MissingWebhook{
+			IndexerID: indexerID,
+			WebhookID: idx.WebhookID.String,
+			Status:    models.IndexerStatus(idx.Status),
+		})
+	}
+
+	return audit, nil
+}
+
+// webhookIndexer finds the indexer a Helius webhook delivers to
+func (s *IndexerService) webhookIndexer(webhook models.HeliusWebhook, byID, byWebhookID map[string]db.Indexer) (db.Indexer, bool) {
+	if u, err := url.Parse(webhook.WebhookURL); err == nil {
+		if id := u.Query().Get("id"); id != "" {
+			idx, ok := byID[id]
+			return idx, ok
+		}
+	}
+
+	if indexerID, ok := indexer.GetIndexerIDFromHeliusWebhookID(webhook.WebhookID); ok {
+		if idx, ok := byID[indexerID]; ok {
+			return idx, true
+		}
+	}
+
+	idx, ok := byWebhookID[webhook.WebhookID]
+	return idx, ok
+}
+
+// isManagedWebhookURL reports whether a webhook calls back to this server.
+// Webhooks for other deployments under the same API key are left alone.
+func (s *IndexerService) isManagedWebhookURL(webhookURL string) bool {
+	baseURL := strings.TrimSuffix(s.heliusClient.GetWebhookBaseURL(), "/")
+	if baseURL == "" {
+		return false
+	}
+	return strings.HasPrefix(webhookURL, baseURL+"/webhooks")
+}
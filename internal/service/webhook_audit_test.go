@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/rishavmehra/indexer/internal/config"
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// heliusWebhooks serves a list of webhooks the way Helius lists them, and
+// deletes them
+type heliusWebhooks struct {
+	mu       sync.Mutex
+	webhooks []models.HeliusWebhook
+	deleted  []string
+}
+
+func (h *heliusWebhooks) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if r.Method == http.MethodGet && strings.TrimSuffix(r.URL.Path, "/") == "/webhooks" {
+		json.NewEncoder(w).Encode(h.webhooks)
+		return
+	}
+	if r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/webhooks/") {
+		webhookID := strings.TrimPrefix(r.URL.Path, "/webhooks/")
+		h.deleted = append(h.deleted, webhookID)
+		for i, webhook := range h.webhooks {
+			if webhook.WebhookID == webhookID {
+				h.webhooks = append(h.webhooks[:i], h.webhooks[i+1:]...)
+				break
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+}
+
+// newAuditClient returns a Helius client for the webhooks, calling back to
+// http://app.local
+func newAuditClient(t *testing.T, webhooks http.Handler) *indexer.HeliusClient {
+	t.Helper()
+
+	server := httptest.NewServer(webhooks)
+	t.Cleanup(server.Close)
+	return indexer.NewHeliusClient(config.HeliusConfig{
+		APIKey:         "test-key",
+		APIBaseURL:     server.URL,
+		WebhookBaseURL: "http://app.local",
+		WebhookSecret:  "secret",
+		MaxAttempts:    1,
+	})
+}
+
+// auditStore lists a fixed set of indexers and records what reconciling
+// changes about them
+type auditStore struct {
+	db.Querier
+	indexers   []db.Indexer
+	statuses   []db.UpdateIndexerStatusParams
+	lastErrors []db.UpdateIndexerLastErrorParams
+	logs       []db.CreateIndexingLogParams
+}
+
+func (s *auditStore) ListIndexers(ctx context.Context) ([]db.Indexer, error) {
+	return s.indexers, nil
+}
+
+func (s *auditStore) UpdateIndexerStatus(ctx context.Context, arg db.UpdateIndexerStatusParams) (db.Indexer, error) {
+	s.statuses = append(s.statuses, arg)
+	return db.Indexer{}, nil
+}
+
+func (s *auditStore) UpdateIndexerLastError(ctx context.Context, arg db.UpdateIndexerLastErrorParams) (db.Indexer, error) {
+	s.lastErrors = append(s.lastErrors, arg)
+	return db.Indexer{}, nil
+}
+
+func (s *auditStore) CreateIndexingLog(ctx context.Context, arg db.CreateIndexingLogParams) (db.IndexingLog, error) {
+	s.logs = append(s.logs, arg)
+	return db.IndexingLog{}, nil
+}
+
+func auditIndexer(webhookID string, status db.IndexerStatus) db.Indexer {
+	return db.Indexer{
+		ID:        pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		Status:    status,
+		WebhookID: pgtype.Text{String: webhookID, Valid: true},
+	}
+}
+
+// webhookAuditFixture is one indexer with its webhook, one whose webhook is
+// gone, an orphaned webhook and a webhook of another deployment
+func webhookAuditFixture(t *testing.T) (*auditStore, *heliusWebhooks) {
+	t.Helper()
+
+	matched := auditIndexer("wh-matched", db.IndexerStatusActive)
+	missing := auditIndexer("wh-gone", db.IndexerStatusActive)
+
+	store := &auditStore{indexers: []db.Indexer{matched, missing}}
+	webhooks := &heliusWebhooks{webhooks: []models.HeliusWebhook{
+		{
+			WebhookID:        "wh-matched",
+			WebhookURL:       "http://app.local/webhooks?id=" + matched.ID.String() + "&key=secret",
+			TransactionTypes: []string{"NFT_SALE"},
+			AccountAddresses: []string{"collection-1", "collection-2"},
+		},
+		{
+			WebhookID:        "wh-orphan",
+			WebhookURL:       "http://app.local/webhooks?id=" + uuid.NewString() + "&key=secret",
+			TransactionTypes: []string{"ANY"},
+		},
+		{
+			WebhookID:  "wh-foreign",
+			WebhookURL: "http://other.local/webhooks?id=" + uuid.NewString(),
+		},
+	}}
+	return store, webhooks
+}
+
+func TestAuditWebhooks(t *testing.T) {
+	store, webhooks := webhookAuditFixture(t)
+	s := NewIndexerService(store, newAuditClient(t, webhooks))
+
+	audit, err := s.AuditWebhooks(context.Background())
+	if err != nil {
+		t.Fatalf("AuditWebhooks: %v", err)
+	}
+
+	if len(audit.Webhooks) != 3 {
+		t.Fatalf("got %d webhooks, want 3", len(audit.Webhooks))
+	}
+	byID := make(map[string]models.AuditedWebhook)
+	for _, webhook := range audit.Webhooks {
+		byID[webhook.WebhookID] = webhook
+	}
+
+	matched := byID["wh-matched"]
+	if matched.IndexerID != store.indexers[0].ID.String() || matched.Orphaned || !matched.Managed || matched.AddressCount != 2 {
+		t.Errorf("matched webhook = %+v, want it tied to its indexer", matched)
+	}
+	if strings.Contains(matched.WebhookURL, "secret") {
+		t.Errorf("webhook URL %q shows the webhook key", matched.WebhookURL)
+	}
+	if orphan := byID["wh-orphan"]; !orphan.Orphaned || orphan.IndexerID != "" {
+		t.Errorf("orphan webhook = %+v, want it flagged", orphan)
+	}
+	if foreign := byID["wh-foreign"]; foreign.Managed || foreign.Orphaned {
+		t.Errorf("foreign webhook = %+v, want it left unmanaged and unflagged", foreign)
+	}
+	if audit.OrphanCount != 1 {
+		t.Errorf("orphan count = %d, want 1", audit.OrphanCount)
+	}
+
+	if len(audit.MissingWebhooks) != 1 {
+		t.Fatalf("missing webhooks = %+v, want one", audit.MissingWebhooks)
+	}
+	if missing := audit.MissingWebhooks[0]; missing.IndexerID != uuid.UUID(store.indexers[1].ID.Bytes) || missing.WebhookID != "wh-gone" {
+		t.Errorf("missing webhook = %+v, want the indexer on wh-gone", missing)
+	}
+}
+
+func TestAuditWebhooksWithoutHelius(t *testing.T) {
+	s := NewIndexerService(&auditStore{}, nil)
+
+	if _, err := s.AuditWebhooks(context.Background()); err != ErrHeliusNotConfigured {
+		t.Errorf("AuditWebhooks error = %v, want ErrHeliusNotConfigured", err)
+	}
+}
@@ -0,0 +1,377 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/rishavmehra/indexer/internal/config"
+	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/pkg/logger"
+)
+
+// ErrWebhookBackpressure is returned when no processing slot frees up in time
+var ErrWebhookBackpressure = errors.New("webhook processing is at capacity")
+
+const (
+	// payloadProcessingTimeout bounds the processing of a single webhook payload
+	payloadProcessingTimeout = 30 * time.Second
+	// abandonGracePeriod is how long Drain waits for cancelled payloads to
+	// unwind once the drain timeout has passed
+	abandonGracePeriod = 5 * time.Second
+)
+
+// WebhookDispatcherStats is a point-in-time view of webhook processing
+type WebhookDispatcherStats struct {
+	InFlight       int64 `json:"inFlight"`
+	MaxConcurrency int   `json:"maxConcurrency"`
+	Processed      int64 `json:"processed"`
+	Failed         int64 `json:"failed"`
+	Rejected       int64 `json:"rejected"`
+	// Queued and Queues are only reported when fair queueing is enabled;
+	// Queues holds the pending payloads per webhook ID
+	Queued int            `json:"queued,omitempty"`
+	Queues map[string]int `json:"queues,omitempty"`
+	// Retried counts payloads tried again after a failure and GivenUp those
+	// that ran out of attempts and were moved to failed_payloads
+	Retried int64 `json:"retried,omitempty"`
+	GivenUp int64 `json:"givenUp,omitempty"`
+}
+
+// WebhookDispatcher runs webhook payloads in the background with a cap on how
+// many are processed at once. With fair queueing enabled a fixed set of
+// workers serves one queue per webhook round-robin instead, and with a
+// payload queue the workers take payloads from it. A failed payload is tried
+// up to maxAttempts times before it is moved to failed_payloads.
+type WebhookDispatcher struct {
+	indexerService *IndexerService
+	slots          chan struct{}
+	// reserving is held by the batch that is taking slots
+	reserving      chan struct{}
+	acquireTimeout time.Duration
+	wg             sync.WaitGroup
+	// queue is nil unless fair queueing is enabled
+	queue *fairQueue
+	// maxAttempts is how often a payload is tried before it is given up on
+	maxAttempts int
+
+	// jobs is nil unless a payload queue is configured; it takes precedence
+	// over fair queueing
+	jobs         PayloadQueue
+	pollInterval time.Duration
+	wakeup       chan struct{}
+	quit         chan struct{}
+	quitOnce     sync.Once
+
+	// ctx is the parent of every payload context, cancelled when a drain
+	// times out so stuck processors give up their connections
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	inFlight  atomic.Int64
+	processed atomic.Int64
+	failed    atomic.Int64
+	rejected  atomic.Int64
+	retried   atomic.Int64
+	givenUp   atomic.Int64
+}
+
+// NewWebhookDispatcher creates a dispatcher that runs at most cfg.MaxConcurrency payloads at once
+func NewWebhookDispatcher(indexerService *IndexerService, cfg config.WebhookConfig) *WebhookDispatcher {
+	maxConcurrency := cfg.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = 1
+	}
+
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	d := &WebhookDispatcher{
+		indexerService: indexerService,
+		slots:          make(chan struct{}, maxConcurrency),
+		reserving:      make(chan struct{}, 1),
+		acquireTimeout: cfg.AcquireTimeout,
+		maxAttempts:    maxAttempts,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+
+	switch cfg.Queue {
+	case "memory":
+		d.startJobWorkers(NewMemoryPayloadQueue(), maxConcurrency, cfg)
+	case "postgres":
+		d.startJobWorkers(NewPostgresPayloadQueue(indexerService.store), maxConcurrency, cfg)
+	}
+
+	if d.jobs == nil && cfg.FairQueueCapacity > 0 {
+		d.queue = newFairQueue(cfg.FairQueueCapacity)
+		for i := 0; i < maxConcurrency; i++ {
+			go d.work()
+		}
+	}
+
+	return d
+}
+
+// Dispatch starts background processing for the payloads. A batch is
+// accepted or rejected as a whole: processing slots for it are reserved
+// before any payload starts, and if they do not free up within the acquire
+// timeout nothing is dispatched and ErrWebhookBackpressure is returned, so a
+// redelivery by Helius does not process part of the batch twice. A batch
+// larger than the concurrency cap runs on every slot in turn. The request ID
+// carried by ctx follows the payloads into processing; ctx itself is not used
+// once Dispatch returns.
+func (d *WebhookDispatcher) Dispatch(ctx context.Context, webhookID string, payloads []models.HeliusWebhookPayload) (int, error) {
+	requestID := logger.RequestID(ctx)
+
+	if d.jobs != nil {
+		return d.dispatchJobs(ctx, requestID, webhookID, payloads)
+	}
+
+	if d.queue != nil {
+		return d.dispatchFair(requestID, webhookID, payloads)
+	}
+
+	if len(payloads) == 0 {
+		return 0, nil
+	}
+
+	workers := min(len(payloads), cap(d.slots))
+	if !d.reserve(workers) {
+		d.rejected.Add(int64(len(payloads)))
+		log.Warn().
+			Ctx(ctx).
+			Str("webhookID", webhookID).
+			Int("rejected", len(payloads)).
+			Msg("Webhook processing at capacity, rejecting payloads")
+		return 0, ErrWebhookBackpressure
+	}
+
+	batch := make(chan models.HeliusWebhookPayload, len(payloads))
+	for _, payload := range payloads {
+		batch <- payload
+	}
+	close(batch)
+
+	d.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer func() {
+				<-d.slots
+				d.wg.Done()
+			}()
+
+			for p := range batch {
+				// Whatever is left of the batch is dropped once a drain
+				// times out, like a payload cut short by shutdown
+				if d.ctx.Err() != nil {
+					return
+				}
+				d.inFlight.Add(1)
+				d.processWithRetries(requestID, webhookID, p)
+				d.inFlight.Add(-1)
+			}
+		}()
+	}
+
+	return len(payloads), nil
+}
+
+// process runs one payload and updates the counters
+func (d *WebhookDispatcher) process(requestID, webhookID string, p models.HeliusWebhookPayload) error {
+	ctx, cancel := context.WithTimeout(logger.WithRequestID(d.ctx, requestID), payloadProcessingTimeout)
+	defer cancel()
+
+	// Payloads accepted just before maintenance was switched on hold here
+	// until it is switched off again
+	if err := d.indexerService.maintenance.Wait(d.ctx); err != nil {
+		d.failed.Add(1)
+		log.Error().Ctx(ctx).Err(err).Str("webhookID", webhookID).Int64("slot", p.Slot).Msg("Abandoned payload held for maintenance")
+		return err
+	}
+
+	if err := d.indexerService.ProcessWebhookPayload(ctx, webhookID, p); err != nil {
+		d.failed.Add(1)
+		log.Error().Ctx(ctx).Err(err).Str("webhookID", webhookID).Int64("slot", p.Slot).Msg("Failed to process")
+		return err
+	}
+	d.processed.Add(1)
+	return nil
+}
+
+// processWithRetries runs a payload outside the payload queue, trying it again
+// in place after a backoff and storing it in failed_payloads once it has used
+// up its attempts. A payload cut short by shutdown is dropped as before.
+func (d *WebhookDispatcher) processWithRetries(requestID, webhookID string, p models.HeliusWebhookPayload) {
+	for attempt := 1; ; attempt++ {
+		err := d.process(requestID, webhookID, p)
+		if err == nil || !retryablePayloadError(err) || d.ctx.Err() != nil {
+			return
+		}
+
+		if attempt >= d.maxAttempts {
+			d.givenUp.Add(1)
+			d.deadLetter(requestID, webhookID, p, attempt, err)
+			return
+		}
+
+		d.retried.Add(1)
+		timer := time.NewTimer(queueRetryDelay(attempt))
+		select {
+		case <-d.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// deadLetter hands a payload that ran out of attempts to the indexer service
+// to keep for replay
+func (d *WebhookDispatcher) deadLetter(requestID, webhookID string, p models.HeliusWebhookPayload, attempts int, cause error) {
+	ctx, cancel := context.WithTimeout(logger.WithRequestID(context.Background(), requestID), queueSettleTimeout)
+	defer cancel()
+
+	d.indexerService.recordFailedPayload(ctx, webhookID, p, attempts, cause)
+}
+
+// retryablePayloadError reports whether another attempt could succeed; a
+// payload for an indexer that is not active is dropped straight away
+func retryablePayloadError(err error) bool {
+	return !errors.Is(err, ErrIndexerNotActive)
+}
+
+// stop cancels processing and lets fair queue workers exit once their
+// queues are empty
+func (d *WebhookDispatcher) stop() {
+	d.cancel()
+	if d.queue != nil {
+		d.queue.close()
+	}
+}
+
+// reserve takes n processing slots, waiting up to the acquire timeout for
+// them to free up. It takes all of them or none; batches reserve one at a
+// time so two batches cannot each hold part of the slots they need.
+func (d *WebhookDispatcher) reserve(n int) bool {
+	// Without an acquire timeout the slots must be free straight away
+	expired := make(chan time.Time)
+	close(expired)
+	var timeout <-chan time.Time = expired
+	if d.acquireTimeout > 0 {
+		timer := time.NewTimer(d.acquireTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	take := func(ch chan struct{}) bool {
+		select {
+		case ch <- struct{}{}:
+			return true
+		default:
+		}
+		select {
+		case ch <- struct{}{}:
+			return true
+		case <-timeout:
+			return false
+		}
+	}
+
+	if !take(d.reserving) {
+		return false
+	}
+	defer func() { <-d.reserving }()
+
+	for taken := 0; taken < n; taken++ {
+		if !take(d.slots) {
+			for ; taken > 0; taken-- {
+				<-d.slots
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// Drain waits for in-flight payloads to finish. If ctx expires first the
+// remaining payloads are abandoned: the indexers they belong to are logged,
+// their contexts are cancelled, and Drain waits at most abandonGracePeriod
+// more for them to unwind before returning ctx's error.
+func (d *WebhookDispatcher) Drain(ctx context.Context) error {
+	// Queue workers finish the payload they hold and stop; whatever is still
+	// queued stays there
+	if d.jobs != nil {
+		d.quitOnce.Do(func() { close(d.quit) })
+	}
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.stop()
+		return nil
+	case <-ctx.Done():
+	}
+
+	inFlight := d.indexerService.InFlightIndexers()
+	for indexerID, count := range inFlight {
+		log.Warn().
+			Str("indexerID", indexerID.String()).
+			Int("payloads", count).
+			Msg("Abandoning in-flight webhook processing")
+	}
+	log.Warn().
+		Int64("inFlight", d.inFlight.Load()).
+		Int("indexers", len(inFlight)).
+		Msg("Drain timed out, cancelling remaining webhook processing")
+
+	d.stop()
+
+	timer := time.NewTimer(abandonGracePeriod)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
+		log.Error().
+			Int64("inFlight", d.inFlight.Load()).
+			Msg("Webhook processing did not stop after cancellation, shutting down anyway")
+	}
+
+	return ctx.Err()
+}
+
+// Stats returns the current in-flight count and lifetime counters
+func (d *WebhookDispatcher) Stats() WebhookDispatcherStats {
+	stats := WebhookDispatcherStats{
+		InFlight:       d.inFlight.Load(),
+		MaxConcurrency: cap(d.slots),
+		Processed:      d.processed.Load(),
+		Failed:         d.failed.Load(),
+		Rejected:       d.rejected.Load(),
+		Retried:        d.retried.Load(),
+		GivenUp:        d.givenUp.Load(),
+	}
+
+	if d.queue != nil {
+		stats.Queues = d.queue.depths()
+		for _, depth := range stats.Queues {
+			stats.Queued += depth
+		}
+	}
+
+	return stats
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/rishavmehra/indexer/internal/config"
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// webhookStore resolves every webhook to a paused indexer, so a payload is
+// done as soon as it is looked up. Lookups wait for release when it is set,
+// or until they are cancelled unless ignoreCancel is set.
+type webhookStore struct {
+	db.Querier
+	release      chan struct{}
+	ignoreCancel bool
+
+	mu      sync.Mutex
+	running int
+	peak    int
+	seen    atomic.Int64
+}
+
+func (s *webhookStore) GetIndexerByWebhookID(ctx context.Context, webhookID pgtype.Text) (db.Indexer, error) {
+	s.mu.Lock()
+	s.running++
+	s.peak = max(s.peak, s.running)
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.running--
+		s.mu.Unlock()
+	}()
+
+	s.seen.Add(1)
+	if s.release != nil {
+		done := ctx.Done()
+		if s.ignoreCancel {
+			done = nil
+		}
+		select {
+		case <-s.release:
+		case <-done:
+		}
+	}
+	return db.Indexer{Status: db.IndexerStatusPaused}, nil
+}
+
+func testPayloads(n int) []models.HeliusWebhookPayload {
+	payloads := make([]models.HeliusWebhookPayload, n)
+	for i := range payloads {
+		payloads[i] = models.HeliusWebhookPayload{Slot: int64(i)}
+	}
+	return payloads
+}
+
+func TestDispatchRunsBatchWithinConcurrencyCap(t *testing.T) {
+	store := &webhookStore{}
+	dispatcher := NewWebhookDispatcher(NewIndexerService(store, nil), config.WebhookConfig{MaxConcurrency: 4})
+
+	dispatched, err := dispatcher.Dispatch(context.Background(), "webhook", testPayloads(200))
+	if err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if dispatched != 200 {
+		t.Fatalf("dispatched %d payloads, want 200", dispatched)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := dispatcher.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+
+	if seen := store.seen.Load(); seen != 200 {
+		t.Errorf("processed %d payloads, want 200", seen)
+	}
+	if store.peak > 4 {
+		t.Errorf("%d payloads ran at once, want at most 4", store.peak)
+	}
+	if stats := dispatcher.Stats(); stats.InFlight != 0 || stats.Failed != 200 {
+		t.Errorf("stats = %+v, want nothing in flight and every payload dropped for the paused indexer", stats)
+	}
+}
+
+func TestDispatchRejectsWholeBatchAtCapacity(t *testing.T) {
+	store := &webhookStore{release: make(chan struct{})}
+	dispatcher := NewWebhookDispatcher(NewIndexerService(store, nil), config.WebhookConfig{
+		MaxConcurrency: 2,
+		AcquireTimeout: 10 * time.Millisecond,
+	})
+
+	if _, err := dispatcher.Dispatch(context.Background(), "webhook", testPayloads(1)); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+
+	dispatched, err := dispatcher.Dispatch(context.Background(), "webhook", testPayloads(3))
+	if !errors.Is(err, ErrWebhookBackpressure) {
+		t.Fatalf("Dispatch error = %v, want ErrWebhookBackpressure", err)
+	}
+	if dispatched != 0 {
+		t.Errorf("dispatched %d payloads of a rejected batch, want 0", dispatched)
+	}
+
+	close(store.release)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := dispatcher.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+
+	if seen := store.seen.Load(); seen != 1 {
+		t.Errorf("processed %d payloads, want only the first batch", seen)
+	}
+	if rejected := dispatcher.Stats().Rejected; rejected != 3 {
+		t.Errorf("rejected %d payloads, want 3", rejected)
+	}
+}
+
+func TestFairQueueRejectsBatchThatDoesNotFit(t *testing.T) {
+	q := newFairQueue(3)
+	items := make([]queuedPayload, 2)
+
+	if accepted := q.push("a", items); accepted != 2 {
+		t.Fatalf("accepted %d payloads into an empty queue, want 2", accepted)
+	}
+	if accepted := q.push("a", items); accepted != 0 {
+		t.Errorf("accepted %d payloads of a batch that does not fit, want 0", accepted)
+	}
+	if accepted := q.push("b", make([]queuedPayload, 5)); accepted != 5 {
+		t.Errorf("accepted %d payloads of an oversized batch into an empty queue, want 5", accepted)
+	}
+	if depths := q.depths(); depths["a"] != 2 || depths["b"] != 5 {
+		t.Errorf("depths = %v", depths)
+	}
+}
+
+// slowWebhookStore takes delay to look up the noisy webhook and signals
+// quiet for every lookup of any other webhook
+type slowWebhookStore struct {
+	db.Querier
+	noisy string
+	delay time.Duration
+	quiet chan struct{}
+}
+
+func (s *slowWebhookStore) GetIndexerByWebhookID(ctx context.Context, webhookID pgtype.Text) (db.Indexer, error) {
+	if webhookID.String == s.noisy {
+		select {
+		case <-time.After(s.delay):
+		case <-ctx.Done():
+		}
+	} else {
+		s.quiet <- struct{}{}
+	}
+	return db.Indexer{Status: db.IndexerStatusPaused}, nil
+}
+
+func TestFairQueueKeepsBusyWebhookFromStarvingOthers(t *testing.T) {
+	store := &slowWebhookStore{noisy: "noisy", delay: 20 * time.Millisecond, quiet: make(chan struct{}, 1)}
+	dispatcher := NewWebhookDispatcher(NewIndexerService(store, nil), config.WebhookConfig{
+		MaxConcurrency:    2,
+		FairQueueCapacity: 100,
+	})
+
+	// 100 payloads at 20ms each on two workers keep the noisy webhook busy
+	// for about a second
+	if _, err := dispatcher.Dispatch(context.Background(), "noisy", testPayloads(100)); err != nil {
+		t.Fatalf("Dispatch noisy: %v", err)
+	}
+	if _, err := dispatcher.Dispatch(context.Background(), "quiet", testPayloads(1)); err != nil {
+		t.Fatalf("Dispatch quiet: %v", err)
+	}
+
+	select {
+	case <-store.quiet:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("quiet webhook's payload waited behind the noisy backlog")
+	}
+
+	if depth := dispatcher.Stats().Queues["noisy"]; depth == 0 {
+		t.Error("noisy queue already empty, want the quiet payload served ahead of its backlog")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := dispatcher.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+}
+
+func TestDrainCancelsProcessingAfterTimeout(t *testing.T) {
+	store := &webhookStore{release: make(chan struct{})}
+	defer close(store.release)
+	dispatcher := NewWebhookDispatcher(NewIndexerService(store, nil), config.WebhookConfig{MaxConcurrency: 2})
+
+	if _, err := dispatcher.Dispatch(context.Background(), "webhook", testPayloads(2)); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := dispatcher.Drain(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Drain error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Drain returned after %s, want soon after the 50ms timeout", elapsed)
+	}
+	if inFlight := dispatcher.Stats().InFlight; inFlight != 0 {
+		t.Errorf("%d payloads still in flight after the drain, want the cancelled ones unwound", inFlight)
+	}
+}
+
+func TestDrainDoesNotWaitForStuckProcessorBeyondBound(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits out the abandon grace period")
+	}
+
+	store := &webhookStore{release: make(chan struct{}), ignoreCancel: true}
+	defer close(store.release)
+	dispatcher := NewWebhookDispatcher(NewIndexerService(store, nil), config.WebhookConfig{MaxConcurrency: 1})
+
+	if _, err := dispatcher.Dispatch(context.Background(), "webhook", testPayloads(1)); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+
+	drainTimeout := 50 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := dispatcher.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Drain error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed, bound := time.Since(start), drainTimeout+abandonGracePeriod+time.Second; elapsed > bound {
+		t.Errorf("Drain returned after %s, want at most %s", elapsed, bound)
+	}
+	if inFlight := dispatcher.Stats().InFlight; inFlight != 1 {
+		t.Errorf("in flight = %d, want the stuck payload still running", inFlight)
+	}
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/pkg/logger"
+)
+
+// reconcileTimeout bounds one background reconcile run
+const reconcileTimeout = 2 * time.Minute
+
+// Reconcile cleans up after the webhook audit: orphaned webhooks are deleted
+// at Helius, and indexers whose webhook vanished are marked failed with the
+// reason as their last error. Indexers already failed are left as they are.
+// With dryRun nothing is changed and the result lists what would have been.
+// A failure on one webhook or indexer is reported and the rest still run.
+func (s *IndexerService) Reconcile(ctx context.Context, dryRun bool) (*models.WebhookReconcileResult, error) {
+	audit, err := s.AuditWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.WebhookReconcileResult{
+		DryRun:          dryRun,
+		DeletedWebhooks: []string{},
+		FailedIndexers:  []uuid.UUID{},
+	}
+
+	for _, webhook := range audit.Webhooks {
+		if !webhook.Orphaned {
+			continue
+		}
+
+		if !dryRun {
+			if err := s.heliusClient.DeleteWebhook(ctx, webhook.WebhookID); err != nil {
+				log.Error().Err(err).Str("webhookID", webhook.WebhookID).Msg("Failed to delete orphaned webhook")
+				result.Errors = append(result.Errors,
+					fmt.Sprintf("webhook %s: %s", webhook.WebhookID, logger.Redact(err.Error())))
+				continue
+			}
+		}
+
+		log.Info().
+			Str("webhookID", webhook.WebhookID).
+			Bool("dryRun", dryRun).
+			Msg("Reconciled orphaned webhook")
+		result.DeletedWebhooks = append(result.DeletedWebhooks, webhook.WebhookID)
+	}
+
+	for _, missing := range audit.MissingWebhooks {
+		if missing.Status == models.Failed {
+			continue
+		}
+
+		if !dryRun {
+			if err := s.markWebhookMissing(ctx, missing); err != nil {
+				log.Error().Err(err).Str("indexerID", missing.IndexerID.String()).Msg("Failed to mark indexer failed")
+				result.Errors = append(result.Errors,
+					fmt.Sprintf("indexer %s: %s", missing.IndexerID, err.Error()))
+				continue
+			}
+		}
+
+		log.Warn().
+			Str("indexerID", missing.IndexerID.String()).
+			Str("webhookID", missing.WebhookID).
+			Bool("dryRun", dryRun).
+			Msg("Reconciled indexer whose webhook no longer exists")
+		result.FailedIndexers = append(result.FailedIndexers, missing.IndexerID)
+	}
+
+	return result, nil
+}
+
+// markWebhookMissing fails an indexer whose webhook is gone at Helius
+func (s *IndexerService) markWebhookMissing(ctx context.Context, missing models.MissingWebhook) error {
+	var pgIndexerID pgtype.UUID
+	if err := pgIndexerID.Scan(missing.IndexerID.String()); err != nil {
+		return fmt.Errorf("invalid indexer ID: %w", err)
+	}
+
+	message := fmt.Sprintf("Helius webhook %s no longer exists", missing.WebhookID)
+
+	if _, err := s.store.UpdateIndexerStatus(ctx, db.UpdateIndexerStatusParams{
+		ID:           pgIndexerID,
+		Status:       db.IndexerStatusFailed,
+		ErrorMessage: pgtype.Text{String: message, Valid: true},
+	}); err != nil {
+		return fmt.Errorf("failed to update indexer status: %w", err)
+	}
+
+	if _, err := s.store.UpdateIndexerLastError(ctx, db.UpdateIndexerLastErrorParams{
+		ID:        pgIndexerID,
+		LastError: pgtype.Text{String: message, Valid: true},
+	}); err != nil {
+		return fmt.Errorf("failed to record last error: %w", err)
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"webhookID":      missing.WebhookID,
+		"previousStatus": missing.Status,
+	})
+
+	_, logErr := s.store.CreateIndexingLog(ctx, db.CreateIndexingLogParams{
+		IndexerID: pgIndexerID,
+		EventType: "webhook_missing",
+		Message:   message,
+		Details:   details,
+	})
+	if logErr != nil {
+		log.Error().Err(logErr).Msg("Failed to create webhook missing log entry")
+	}
+
+	return nil
+}
+
+// StartWebhookReconciler runs Reconcile every interval until the returned
+// stop function is called
+func (s *IndexerService) StartWebhookReconciler(interval time.Duration, dryRun bool) (stop func()) {
+	done := make(chan struct{})
+	var once sync.Once
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), reconcileTimeout)
+				result, err := s.Reconcile(ctx, dryRun)
+				cancel()
+				if err != nil {
+					log.Error().Err(err).Msg("Webhook reconcile failed")
+					continue
+				}
+				if len(result.DeletedWebhooks) > 0 || len(result.FailedIndexers) > 0 || len(result.Errors) > 0 {
+					log.Info().
+						Bool("dryRun", dryRun).
+						Strs("deletedWebhooks", result.DeletedWebhooks).
+						Int("failedIndexers", len(result.FailedIndexers)).
+						Int("errors", len(result.Errors)).
+						Msg("Reconciled Helius webhooks")
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+)
+
+func TestReconcileDeletesOrphansAndFailsMissingIndexers(t *testing.T) {
+	store, webhooks := webhookAuditFixture(t)
+	s := NewIndexerService(store, newAuditClient(t, webhooks))
+	missing := store.indexers[1]
+
+	result, err := s.Reconcile(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(result.Errors) != 0 {
+		t.Fatalf("reconcile errors: %v", result.Errors)
+	}
+
+	// Only the orphan is deleted, not the other deployment's webhook
+	if len(result.DeletedWebhooks) != 1 || result.DeletedWebhooks[0] != "wh-orphan" {
+		t.Errorf("deleted webhooks = %v, want [wh-orphan]", result.DeletedWebhooks)
+	}
+	if len(webhooks.deleted) != 1 || webhooks.deleted[0] != "wh-orphan" {
+		t.Errorf("Helius got deletes for %v, want [wh-orphan]", webhooks.deleted)
+	}
+
+	if len(result.FailedIndexers) != 1 || result.FailedIndexers[0].String() != missing.ID.String() {
+		t.Errorf("failed indexers = %v, want the indexer on wh-gone", result.FailedIndexers)
+	}
+	if len(store.statuses) != 1 || store.statuses[0].ID != missing.ID || store.statuses[0].Status != db.IndexerStatusFailed {
+		t.Errorf("status updates = %+v, want the indexer on wh-gone failed", store.statuses)
+	}
+	if len(store.lastErrors) != 1 || store.lastErrors[0].LastError.String != "Helius webhook wh-gone no longer exists" {
+		t.Errorf("last errors = %+v, want the missing webhook recorded", store.lastErrors)
+	}
+	if len(store.logs) != 1 || store.logs[0].EventType != "webhook_missing" {
+		t.Errorf("logs = %+v, want one webhook_missing entry", store.logs)
+	}
+
+	// Once reconciled, the audit has nothing left to clean up
+	audit, err := s.AuditWebhooks(context.Background())
+	if err != nil {
+		t.Fatalf("AuditWebhooks: %v", err)
+	}
+	if audit.OrphanCount != 0 {
+		t.Errorf("orphan count after reconcile = %d, want 0", audit.OrphanCount)
+	}
+}
+
+func TestReconcileDryRunChangesNothing(t *testing.T) {
+	store, webhooks := webhookAuditFixture(t)
+	s := NewIndexerService(store, newAuditClient(t, webhooks))
+
+	result, err := s.Reconcile(context.Background(), true)
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	if !result.DryRun || len(result.DeletedWebhooks) != 1 || len(result.FailedIndexers) != 1 {
+		t.Errorf("dry run result = %+v, want the orphan and the missing indexer listed", result)
+	}
+	if len(webhooks.deleted) != 0 || len(webhooks.webhooks) != 3 {
+		t.Errorf("dry run deleted %v at Helius, want nothing", webhooks.deleted)
+	}
+	if len(store.statuses) != 0 || len(store.lastErrors) != 0 || len(store.logs) != 0 {
+		t.Errorf("dry run wrote %d statuses, %d last errors and %d logs, want none",
+			len(store.statuses), len(store.lastErrors), len(store.logs))
+	}
+}
+
+func TestReconcileLeavesFailedIndexers(t *testing.T) {
+	store, webhooks := webhookAuditFixture(t)
+	store.indexers[1].Status = db.IndexerStatusFailed
+	s := NewIndexerService(store, newAuditClient(t, webhooks))
+
+	result, err := s.Reconcile(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(result.FailedIndexers) != 0 || len(store.statuses) != 0 {
+		t.Errorf("failed indexers = %v, want the already failed indexer left alone", result.FailedIndexers)
+	}
+}
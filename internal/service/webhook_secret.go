@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/models"
+	"github.com/rishavmehra/indexer/pkg/crypto"
+	"github.com/rishavmehra/indexer/pkg/logger"
+)
+
+// webhookSecretBytes is the entropy of a generated webhook secret
+const webhookSecretBytes = 32
+
+// ErrInvalidWebhookKey is returned for a webhook delivery whose key doesn't
+// match the secret of the indexer it is for, or that is for no indexer
+var ErrInvalidWebhookKey = errors.New("invalid webhook key")
+
+// WebhookSecret returns the key deliveries for webhookID must carry: the
+// secret of the indexer the webhook serves, or HELIUS_WEBHOOK_SECRET for
+// indexers created before they had one
+func (s *IndexerService) WebhookSecret(ctx context.Context, webhookID string) (string, error) {
+	foundIndexer, err := s.store.GetIndexerByWebhookID(ctx, pgtype.Text{String: webhookID, Valid: true})
+	if err != nil {
+		return "", fmt.Errorf("indexer not found for webhook ID %s: %w", webhookID, err)
+	}
+	return s.indexerWebhookSecret(ctx, foundIndexer)
+}
+
+// VerifyWebhookKey checks the key a delivery for webhookID came with against
+// the secret of the indexer it is for. Deliveries are accepted unchecked when
+// there is no secret at all.
+func (s *IndexerService) VerifyWebhookKey(ctx context.Context, webhookID, key string) error {
+	secret, err := s.WebhookSecret(ctx, webhookID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrInvalidWebhookKey
+	}
+	if err != nil {
+		return err
+	}
+
+	if secret == "" {
+		return nil
+	}
+	if subtle.ConstantTimeCompare([]byte(key), []byte(secret)) != 1 {
+		return ErrInvalidWebhookKey
+	}
+	return nil
+}
+
+// indexerWebhookSecret returns the secret an indexer's webhook URL carries
+func (s *IndexerService) indexerWebhookSecret(ctx context.Context, dbIndexer db.Indexer) (string, error) {
+	secret, err := s.store.GetIndexerWebhookSecret(ctx, dbIndexer.ID)
+	if err == nil {
+		return secret.Secret, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("failed to get webhook secret: %w", err)
+	}
+
+	if s.heliusClient == nil {
+		return "", nil
+	}
+	return s.heliusClient.GetWebhookSecret(), nil
+}
+
+// createWebhookSecret gives an indexer a secret of its own
+func (s *IndexerService) createWebhookSecret(ctx context.Context, indexerID pgtype.UUID) (string, error) {
+	secret, err := crypto.GenerateToken(webhookSecretBytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	if _, err := s.store.UpsertIndexerWebhookSecret(ctx, db.UpsertIndexerWebhookSecretParams{
+		IndexerID: indexerID,
+		Secret:    secret,
+	}); err != nil {
+		return "", fmt.Errorf("failed to store webhook secret: %w", err)
+	}
+	return secret, nil
+}
+
+// webhookURLWithSecret is the callback URL of an indexer's webhooks when they
+// carry secret
+func (s *IndexerService) webhookURLWithSecret(dbIndexer db.Indexer, secret string) (string, error) {
+	if s.heliusClient == nil || s.heliusClient.GetWebhookBaseURL() == "" {
+		return "", fmt.Errorf("webhook base URL is not configured")
+	}
+
+	baseURL := strings.TrimSuffix(s.heliusClient.GetWebhookBaseURL(), "/")
+
+	return fmt.Sprintf("%s/webhooks?id=%s&key=%s",
+		baseURL,
+		dbIndexer.ID.String(),
+		secret), nil
+}
+
+// RotateWebhookSecret gives an indexer a new webhook secret and points its
+// Helius webhooks at a callback URL carrying it. From then on deliveries
+// with the old secret are rejected.
+func (s *IndexerService) RotateWebhookSecret(ctx context.Context, userID uuid.UUID, indexerID uuid.UUID) (*models.IndexerResponse, error) {
+
+	if s.maintenance.Enabled() {
+		return nil, ErrMaintenance
+	}
+
+	var pgIndexerID pgtype.UUID
+	if err := pgIndexerID.Scan(indexerID.String()); err != nil {
+		return nil, fmt.Errorf("invalid indexer ID: %w", err)
+	}
+
+	foundIndexer, err := s.store.GetIndexerByID(ctx, pgIndexerID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get indexer")
+		return nil, errors.New("indexer not found")
+	}
+
+	userIDFromDB, err := uuid.Parse(foundIndexer.UserID.String())
+	if err != nil || userIDFromDB != userID {
+		return nil, errors.New("indexer not found")
+	}
+
+	oldSecret, err := s.indexerWebhookSecret(ctx, foundIndexer)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get webhook secret")
+		return nil, errors.New("failed to rotate webhook secret")
+	}
+
+	newSecret, err := crypto.GenerateToken(webhookSecretBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	// Helius is switched first: if that fails the stored secret still
+	// matches what the webhooks send
+	var webhookIDs []string
+	oldURL, newURL := "", ""
+	if s.heliusClient != nil && foundIndexer.WebhookID.Valid {
+		if oldURL, err = s.webhookURLWithSecret(foundIndexer, oldSecret); err != nil {
+			return nil, err
+		}
+		if newURL, err = s.webhookURLWithSecret(foundIndexer, newSecret); err != nil {
+			return nil, err
+		}
+
+		webhookIDs, err = s.heliusClient.ChangeWebhookURL(ctx, oldURL, newURL)
+		if err != nil {
+			s.revertWebhookURL(ctx, foundIndexer, webhookIDs, newURL, oldURL)
+			return nil, fmt.Errorf("failed to update Helius webhook URL: %w", logger.RedactError(err))
+		}
+	}
+
+	if _, err := s.store.UpsertIndexerWebhookSecret(ctx, db.UpsertIndexerWebhookSecretParams{
+		IndexerID: foundIndexer.ID,
+		Secret:    newSecret,
+	}); err != nil {
+		log.Error().Err(err).Msg("Failed to store webhook secret")
+		s.revertWebhookURL(ctx, foundIndexer, webhookIDs, newURL, oldURL)
+		return nil, errors.New("failed to rotate webhook secret")
+	}
+
+	recordAudit(ctx, s.store, userID, auditIndexerRotateSecret, auditEntityIndexer, foundIndexer.ID, map[string]interface{}{
+		"webhookCount": len(webhookIDs),
+	})
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"webhookCount": len(webhookIDs),
+	})
+
+	_, logErr := s.store.CreateIndexingLog(ctx, db.CreateIndexingLogParams{
+		IndexerID: foundIndexer.ID,
+		EventType: "secret_rotated",
+		Message:   "Webhook secret rotated",
+		Details:   details,
+	})
+	if logErr != nil {
+		log.Error().Err(logErr).Msg("Failed to create secret rotation log entry")
+	}
+
+	log.Info().
+		Str("indexerID", indexerID.String()).
+		Int("webhookCount", len(webhookIDs)).
+		Msg("Webhook secret rotated")
+
+	return s.GetIndexerByID(ctx, userID, indexerID)
+}
+
+// revertWebhookURL points webhooks already moved to a new callback URL back
+// at the old one after a rotation failed part way
+func (s *IndexerService) revertWebhookURL(ctx context.Context, dbIndexer db.Indexer, webhookIDs []string, newURL, oldURL string) {
+	if len(webhookIDs) == 0 {
+		return
+	}
+	if _, err := s.heliusClient.ChangeWebhookURL(ctx, newURL, oldURL); err != nil {
+		log.Error().
+			Err(logger.RedactError(err)).
+			Str("indexerID", dbIndexer.ID.String()).
+			Strs("webhookIDs", webhookIDs).
+			Msg("Failed to restore webhook URL after a failed secret rotation")
+	}
+}
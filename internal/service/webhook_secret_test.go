@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/rishavmehra/indexer/internal/db/generated"
+	"github.com/rishavmehra/indexer/internal/indexer"
+	"github.com/rishavmehra/indexer/internal/models"
+)
+
+// secretStore keeps the webhook secret of its indexer in memory
+type secretStore struct {
+	*rawStore
+	secret string
+	audits []db.CreateAuditEventParams
+}
+
+func newSecretStore(userID uuid.UUID) *secretStore {
+	store := &secretStore{rawStore: newRawStore(userID)}
+	store.indexer.DbCredentialID = pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	return store
+}
+
+func (s *secretStore) GetIndexerWebhookSecret(ctx context.Context, indexerID pgtype.UUID) (db.IndexerWebhookSecret, error) {
+	if s.secret == "" {
+		return db.IndexerWebhookSecret{}, pgx.ErrNoRows
+	}
+	return db.IndexerWebhookSecret{IndexerID: indexerID, Secret: s.secret}, nil
+}
+
+func (s *secretStore) UpsertIndexerWebhookSecret(ctx context.Context, arg db.UpsertIndexerWebhookSecretParams) (db.IndexerWebhookSecret, error) {
+	s.secret = arg.Secret
+	return db.IndexerWebhookSecret{IndexerID: arg.IndexerID, Secret: arg.Secret}, nil
+}
+
+func (s *secretStore) CreateAuditEvent(ctx context.Context, arg db.CreateAuditEventParams) error {
+	s.audits = append(s.audits, arg)
+	return nil
+}
+
+// editableWebhooks also lets webhooks be updated, failing every update when
+// failUpdates is set
+type editableWebhooks struct {
+	*heliusWebhooks
+	failUpdates bool
+}
+
+func (h *editableWebhooks) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		h.heliusWebhooks.ServeHTTP(w, r)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.failUpdates {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var config indexer.WebhookConfig
+	json.NewDecoder(r.Body).Decode(&config)
+	webhookID := strings.TrimPrefix(r.URL.Path, "/webhooks/")
+	for i := range h.webhooks {
+		if h.webhooks[i].WebhookID == webhookID {
+			h.webhooks[i].WebhookURL = config.WebhookURL
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]string{"webhookID": webhookID})
+}
+
+// rotationFixture is an indexer without a secret of its own whose webhook
+// calls back with HELIUS_WEBHOOK_SECRET
+func rotationFixture(t *testing.T) (*IndexerService, *secretStore, *editableWebhooks, uuid.UUID) {
+	t.Helper()
+
+	userID := uuid.New()
+	store := newSecretStore(userID)
+	webhooks := &editableWebhooks{heliusWebhooks: &heliusWebhooks{webhooks: []models.HeliusWebhook{{
+		WebhookID:        "webhook",
+		WebhookURL:       "http://app.local/webhooks?id=" + store.indexer.ID.String() + "&key=secret",
+		TransactionTypes: []string{"NFT_SALE"},
+		AccountAddresses: []string{"collection"},
+	}}}}
+	return NewIndexerService(store, newAuditClient(t, webhooks)), store, webhooks, userID
+}
+
+func TestRotateWebhookSecret(t *testing.T) {
+	s, store, webhooks, userID := rotationFixture(t)
+	ctx := context.Background()
+
+	if err := s.VerifyWebhookKey(ctx, "webhook", "secret"); err != nil {
+		t.Fatalf("VerifyWebhookKey before rotation: %v", err)
+	}
+
+	if _, err := s.RotateWebhookSecret(ctx, userID, uuid.UUID(store.indexer.ID.Bytes)); err != nil {
+		t.Fatalf("RotateWebhookSecret: %v", err)
+	}
+
+	if store.secret == "" || store.secret == "secret" {
+		t.Fatalf("stored secret = %q, want a new one", store.secret)
+	}
+	wantURL := "http://app.local/webhooks?id=" + store.indexer.ID.String() + "&key=" + store.secret
+	if got := webhooks.webhooks[0].WebhookURL; got != wantURL {
+		t.Errorf("webhook URL = %s, want %s", got, wantURL)
+	}
+
+	if err := s.VerifyWebhookKey(ctx, "webhook", "secret"); !errors.Is(err, ErrInvalidWebhookKey) {
+		t.Errorf("old secret verified with %v, want ErrInvalidWebhookKey", err)
+	}
+	if err := s.VerifyWebhookKey(ctx, "webhook", store.secret); err != nil {
+		t.Errorf("new secret: %v", err)
+	}
+
+	if len(store.audits) != 1 || store.audits[0].Action != auditIndexerRotateSecret {
+		t.Errorf("audits = %+v, want the rotation recorded", store.audits)
+	}
+	if len(store.logs) != 1 || store.logs[0].EventType != "secret_rotated" {
+		t.Errorf("logs = %+v, want a secret_rotated entry", store.logs)
+	}
+}
+
+func TestRotateWebhookSecretTwice(t *testing.T) {
+	s, store, webhooks, userID := rotationFixture(t)
+	ctx := context.Background()
+	indexerID := uuid.UUID(store.indexer.ID.Bytes)
+
+	if _, err := s.RotateWebhookSecret(ctx, userID, indexerID); err != nil {
+		t.Fatalf("first RotateWebhookSecret: %v", err)
+	}
+	first := store.secret
+	if _, err := s.RotateWebhookSecret(ctx, userID, indexerID); err != nil {
+		t.Fatalf("second RotateWebhookSecret: %v", err)
+	}
+
+	if store.secret == first {
+		t.Fatal("second rotation kept the secret")
+	}
+	if err := s.VerifyWebhookKey(ctx, "webhook", first); !errors.Is(err, ErrInvalidWebhookKey) {
+		t.Errorf("secret of the first rotation verified with %v, want ErrInvalidWebhookKey", err)
+	}
+	if !strings.HasSuffix(webhooks.webhooks[0].WebhookURL, "&key="+store.secret) {
+		t.Errorf("webhook URL = %s, want it to carry the latest secret", webhooks.webhooks[0].WebhookURL)
+	}
+}
+
+func TestRotateWebhookSecretKeepsOldSecretWhenHeliusFails(t *testing.T) {
+	s, store, webhooks, userID := rotationFixture(t)
+	webhooks.failUpdates = true
+	ctx := context.Background()
+
+	if _, err := s.RotateWebhookSecret(ctx, userID, uuid.UUID(store.indexer.ID.Bytes)); err == nil {
+		t.Fatal("RotateWebhookSecret succeeded although Helius rejected the update")
+	}
+
+	if store.secret != "" {
+		t.Errorf("stored secret %q, want none stored after a failed rotation", store.secret)
+	}
+	if err := s.VerifyWebhookKey(ctx, "webhook", "secret"); err != nil {
+		t.Errorf("old secret stopped verifying after a failed rotation: %v", err)
+	}
+}
+
+func TestRotateWebhookSecretWithoutHelius(t *testing.T) {
+	userID := uuid.New()
+	store := newSecretStore(userID)
+	store.secret = "old-secret"
+	s := NewIndexerService(store, nil)
+	ctx := context.Background()
+
+	if _, err := s.RotateWebhookSecret(ctx, userID, uuid.UUID(store.indexer.ID.Bytes)); err != nil {
+		t.Fatalf("RotateWebhookSecret: %v", err)
+	}
+
+	if store.secret == "old-secret" {
+		t.Fatal("secret not rotated")
+	}
+	if err := s.VerifyWebhookKey(ctx, "webhook", "old-secret"); !errors.Is(err, ErrInvalidWebhookKey) {
+		t.Errorf("old secret verified with %v, want ErrInvalidWebhookKey", err)
+	}
+	if err := s.VerifyWebhookKey(ctx, "webhook", store.secret); err != nil {
+		t.Errorf("new secret: %v", err)
+	}
+}
+
+func TestRotateWebhookSecretChecksOwnership(t *testing.T) {
+	store := newSecretStore(uuid.New())
+	store.secret = "old-secret"
+	s := NewIndexerService(store, nil)
+
+	if _, err := s.RotateWebhookSecret(context.Background(), uuid.New(), uuid.UUID(store.indexer.ID.Bytes)); err == nil {
+		t.Error("RotateWebhookSecret of another user's indexer succeeded")
+	}
+	if store.secret != "old-secret" {
+		t.Error("secret of another user's indexer rotated")
+	}
+}
@@ -0,0 +1,89 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, SecretKeySize)
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	key := testKey(1)
+
+	sealed, err := Encrypt(key, "s3cret password")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if !IsEncrypted(sealed) || strings.Contains(sealed, "s3cret") {
+		t.Fatalf("Encrypt = %q, want a prefixed value without the plaintext", sealed)
+	}
+
+	again, err := Encrypt(key, "s3cret password")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if again == sealed {
+		t.Error("encrypting the same value twice gave the same ciphertext, want a fresh nonce")
+	}
+
+	opened, err := Decrypt(key, sealed)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if opened != "s3cret password" {
+		t.Errorf("Decrypt = %q, want the original plaintext", opened)
+	}
+}
+
+func TestDecryptRejectsBadInput(t *testing.T) {
+	key := testKey(1)
+	sealed, err := Encrypt(key, "password")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	raw, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, encryptedPrefix))
+	raw[len(raw)-1] ^= 1
+	tampered := encryptedPrefix + base64.StdEncoding.EncodeToString(raw)
+
+	tests := []struct {
+		name  string
+		key   []byte
+		value string
+	}{
+		{name: "wrong key", key: testKey(2), value: sealed},
+		{name: "tampered", key: key, value: tampered},
+		{name: "plaintext", key: key, value: "password"},
+		{name: "truncated", key: key, value: encryptedPrefix + "AAAA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decrypt(tt.key, tt.value); !errors.Is(err, ErrInvalidCiphertext) {
+				t.Errorf("Decrypt error = %v, want ErrInvalidCiphertext", err)
+			}
+		})
+	}
+}
+
+func TestParseSecretKey(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(testKey(7))
+	key, err := ParseSecretKey(" " + encoded + "\n")
+	if err != nil {
+		t.Fatalf("ParseSecretKey: %v", err)
+	}
+	if !bytes.Equal(key, testKey(7)) {
+		t.Error("ParseSecretKey returned a different key")
+	}
+
+	for _, bad := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		if _, err := ParseSecretKey(bad); err == nil {
+			t.Errorf("ParseSecretKey(%q) succeeded, want an error", bad)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package logger
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"time"
@@ -10,10 +11,45 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-func SetupLogger(level string) {
+// Log output formats accepted by SetupLogger
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// SetupLogger configures the global logger. The json format writes one JSON
+// object per line for log shippers; console writes the human readable form.
+func SetupLogger(level, format string) {
+	log.Logger = newLogger(level, format, os.Stdout)
+}
+
+// newLogger builds a logger writing to out in the given level and format
+func newLogger(level, format string, out io.Writer) zerolog.Logger {
+
+	output := out
+	if format != FormatJSON {
+		output = consoleWriter(out)
+	}
+
+	logLevel, err := zerolog.ParseLevel(strings.ToLower(level))
+	if err != nil {
+		logLevel = zerolog.InfoLevel
+	}
+
+	// Secrets are masked on the JSON event before it is written or formatted
+	// for the console
+	return zerolog.New(NewRedactingWriter(output)).
+		Level(logLevel).
+		With().
+		Timestamp().
+		Caller().
+		Logger().
+		Hook(RequestIDHook{})
+}
 
+func consoleWriter(out io.Writer) zerolog.ConsoleWriter {
 	output := zerolog.ConsoleWriter{
-		Out:        os.Stdout,
+		Out:        out,
 		TimeFormat: time.RFC3339,
 		NoColor:    false,
 	}
@@ -34,17 +70,7 @@ func SetupLogger(level string) {
 		return fmt.Sprintf("%s", i)
 	}
 
-	logLevel, err := zerolog.ParseLevel(strings.ToLower(level))
-	if err != nil {
-		logLevel = zerolog.InfoLevel
-	}
-
-	log.Logger = zerolog.New(output).
-		Level(logLevel).
-		With().
-		Timestamp().
-		Caller().
-		Logger()
+	return output
 }
 
 func GetLogger(component string) zerolog.Logger {
@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewLoggerJSONFormat(t *testing.T) {
+	var out bytes.Buffer
+	l := newLogger("debug", FormatJSON, &out)
+
+	l.Info().Str("indexerID", "idx-1").Msg("first")
+	l.Warn().Ctx(WithRequestID(context.Background(), "req-1")).Int("attempt", 2).Msg("second")
+
+	scanner := bufio.NewScanner(&out)
+	var lines []map[string]interface{}
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("log line %q is not valid JSON: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want 2", len(lines))
+	}
+	if lines[0]["level"] != "info" || lines[0]["message"] != "first" || lines[0]["indexerID"] != "idx-1" {
+		t.Errorf("first line = %v, want the info message with its field", lines[0])
+	}
+	if lines[1]["request_id"] != "req-1" || lines[1]["attempt"] != float64(2) {
+		t.Errorf("second line = %v, want the request ID and attempt", lines[1])
+	}
+	if _, ok := lines[0]["time"]; !ok {
+		t.Error("log line has no timestamp")
+	}
+}
+
+func TestNewLoggerConsoleFormat(t *testing.T) {
+	var out bytes.Buffer
+	l := newLogger("info", FormatConsole, &out)
+
+	l.Info().Str("indexerID", "idx-1").Msg("hello")
+
+	line := out.String()
+	if json.Valid(bytes.TrimSpace(out.Bytes())) {
+		t.Errorf("console output %q is JSON, want the human readable form", line)
+	}
+	if !strings.Contains(line, "hello") || !strings.Contains(line, "indexerID:idx-1") {
+		t.Errorf("console output = %q, want the message and field", line)
+	}
+}
+
+func TestNewLoggerLevel(t *testing.T) {
+	var out bytes.Buffer
+	l := newLogger("warn", FormatJSON, &out)
+
+	l.Info().Msg("dropped")
+	if out.Len() != 0 {
+		t.Errorf("info logged at warn level: %q", out.String())
+	}
+
+	// An unknown level falls back to info
+	l = newLogger("loud", FormatJSON, &out)
+	l.Info().Msg("kept")
+	if !strings.Contains(out.String(), "kept") {
+		t.Error("info dropped with an unknown level, want the info default")
+	}
+}
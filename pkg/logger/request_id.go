@@ -0,0 +1,33 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the ID of the request it serves
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request ID carried by ctx, or "" if there is none
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// RequestIDHook adds the request ID of an event's context, attached with
+// Event.Ctx, to the log line as request_id
+type RequestIDHook struct{}
+
+func (RequestIDHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	if requestID := RequestID(e.GetCtx()); requestID != "" {
+		e.Str("request_id", requestID)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
@@ -53,6 +54,23 @@ func ValidateDBCredentials(host string, port int, dbName, username, password str
 	return nil
 }
 
+// SSLModes are the sslmode values PostgreSQL accepts
+var SSLModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// ValidateSSLMode checks a database credential's sslmode. Empty is allowed and
+// means disable.
+func ValidateSSLMode(mode string) error {
+	if mode == "" {
+		return nil
+	}
+	for _, valid := range SSLModes {
+		if mode == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid SSL mode %q, must be one of %s", mode, strings.Join(SSLModes, ", "))
+}
+
 func IsValidJSON(jsonStr string) bool {
 	var js json.RawMessage
 	return json.Unmarshal([]byte(jsonStr), &js) == nil
@@ -77,7 +95,15 @@ func IsValidSolanaAddress(address string) bool {
 	return matched
 }
 
-func IsValidTableName(tableName string) bool {
+// MetadataTableSuffixes are appended to a target table's name for the
+// companion tables some indexers create next to it, such as price history
+// and collection offers
+var MetadataTableSuffixes = []string{"_price_history", "_collection_offers", "_collection_offer_fills"}
+
+// IsPlainTableName reports whether tableName is an identifier of at most 63
+// letters, digits and underscores starting with a letter. Unlike
+// IsValidTableName it doesn't check reserved names.
+func IsPlainTableName(tableName string) bool {
 
 	matched, err := regexp.MatchString(`^[a-zA-Z][a-zA-Z0-9_]*$`, tableName)
 	if err != nil {
@@ -87,39 +113,380 @@ func IsValidTableName(tableName string) bool {
 	return matched && len(tableName) <= 63
 }
 
+// ValidateTableName checks a target table name: it must be a plain
+// identifier, not start with pg_ or one of reservedPrefixes, and not end like
+// the companion tables indexers create, which could clash with another
+// indexer's. Reserved prefixes are compared case-insensitively.
+func ValidateTableName(tableName string, reservedPrefixes []string) error {
+	if !IsPlainTableName(tableName) {
+		return fmt.Errorf("table name must start with a letter and contain only letters, digits and underscores, at most 63 characters")
+	}
+
+	name := strings.ToLower(tableName)
+	for _, prefix := range append([]string{"pg_"}, reservedPrefixes...) {
+		prefix = strings.ToLower(strings.TrimSpace(prefix))
+		if prefix != "" && strings.HasPrefix(name, prefix) {
+			return fmt.Errorf("table name must not start with the reserved prefix %q", prefix)
+		}
+	}
+	for _, suffix := range MetadataTableSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return fmt.Errorf("table name must not end with %q, which is used for indexer metadata tables", suffix)
+		}
+	}
+
+	return nil
+}
+
+// IsValidTableName reports whether tableName passes ValidateTableName with no
+// reserved prefixes beyond pg_
+func IsValidTableName(tableName string) bool {
+	return ValidateTableName(tableName, nil) == nil
+}
+
+// IsValidRoleName reports whether role is a plain, unquoted PostgreSQL role name
+func IsValidRoleName(role string) bool {
+
+	matched, err := regexp.MatchString(`^[a-zA-Z_][a-zA-Z0-9_$]*$`, role)
+	if err != nil {
+		log.Error().Err(err).Msg("Error matching role name regex")
+		return false
+	}
+	return matched && len(role) <= 63
+}
+
+// ValidateTimeColumn checks an indexer's time column override. Both fields may
+// be empty, which keeps the default block_time TIMESTAMPTZ column.
+func ValidateTimeColumn(name, columnType string) error {
+	if name != "" {
+		matched, err := regexp.MatchString(`^[a-z_][a-z0-9_]*$`, name)
+		if err != nil {
+			log.Error().Err(err).Msg("Error matching column name regex")
+			return fmt.Errorf("invalid time column name")
+		}
+		if !matched || len(name) > 63 {
+			return fmt.Errorf("invalid time column name %q, must be a lowercase identifier of at most 63 characters", name)
+		}
+	}
+
+	switch columnType {
+	case "", "timestamptz", "epoch":
+	default:
+		return fmt.Errorf("invalid time column type %q, must be one of timestamptz, epoch", columnType)
+	}
+
+	return nil
+}
+
+// Marketplaces are the NFT marketplace source names Helius reports on
+// enhanced transactions
+var Marketplaces = []string{
+	"CORAL_CUBE", "DIGITAL_EYES", "ELIXIR", "EXCHANGE_ART", "FORM_FUNCTION",
+	"HADESWAP", "HOLAPLEX", "HYPERSPACE", "MAGIC_EDEN", "METAPLEX", "OPENSEA",
+	"SNIPER_MARKET", "SOLANART", "SOLSEA", "TENSOR", "YAWWW",
+}
+
+// marketplaceAliases maps spellings seen in configs and in Helius sources
+// other than the canonical name, after upper-casing and turning spaces and
+// dashes into underscores
+var marketplaceAliases = map[string]string{
+	"MAGICEDEN":      "MAGIC_EDEN",
+	"MAGIC_EDEN_V2":  "MAGIC_EDEN",
+	"MAGICEDEN_V2":   "MAGIC_EDEN",
+	"ME":             "MAGIC_EDEN",
+	"TENSORSWAP":     "TENSOR",
+	"TENSOR_SWAP":    "TENSOR",
+	"TENSOR_CNFT":    "TENSOR",
+	"TCOMP":          "TENSOR",
+	"CORALCUBE":      "CORAL_CUBE",
+	"DIGITALEYES":    "DIGITAL_EYES",
+	"EXCHANGEART":    "EXCHANGE_ART",
+	"FORMFUNCTION":   "FORM_FUNCTION",
+	"SNIPER":         "SNIPER_MARKET",
+	"SNIPERMARKET":   "SNIPER_MARKET",
+	"HYPERSPACE_NFT": "HYPERSPACE",
+}
+
+// CanonicalMarketplace maps a marketplace name or Helius source to the single
+// form it is stored and compared in, such as MAGIC_EDEN for "magic-eden" or
+// "MAGICEDEN_V2". Names it does not know are only upper-cased.
+func CanonicalMarketplace(marketplace string) string {
+	name := strings.ToUpper(strings.TrimSpace(marketplace))
+	name = strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+	if canonical, ok := marketplaceAliases[name]; ok {
+		return canonical
+	}
+	return name
+}
+
+// NormalizeMarketplace returns the canonical name of a marketplace filter
+// value and checks it against Marketplaces. Unknown names are rejected rather
+// than silently filtering out every event.
+func NormalizeMarketplace(marketplace string) (string, error) {
+	normalized := CanonicalMarketplace(marketplace)
+	for _, valid := range Marketplaces {
+		if normalized == valid {
+			return normalized, nil
+		}
+	}
+	return "", fmt.Errorf("invalid marketplace %q, must be one of %s", marketplace, strings.Join(Marketplaces, ", "))
+}
+
+// NormalizeMarketplaces rewrites the marketplaces of NFT indexer params to
+// their canonical names, keeping every other field as it is
+func NormalizeMarketplaces(paramsJson []byte) ([]byte, error) {
+	var params map[string]json.RawMessage
+	if err := json.Unmarshal(paramsJson, &params); err != nil {
+		return nil, fmt.Errorf("invalid JSON format for params")
+	}
+
+	raw, ok := params["marketplaces"]
+	if !ok {
+		return paramsJson, nil
+	}
+
+	var marketplaces []string
+	if err := json.Unmarshal(raw, &marketplaces); err != nil {
+		return nil, fmt.Errorf("marketplaces must be a list of marketplace names")
+	}
+	for i, marketplace := range marketplaces {
+		normalized, err := NormalizeMarketplace(marketplace)
+		if err != nil {
+			return nil, err
+		}
+		marketplaces[i] = normalized
+	}
+
+	normalized, err := json.Marshal(marketplaces)
+	if err != nil {
+		return nil, err
+	}
+	params["marketplaces"] = normalized
+
+	return json.Marshal(params)
+}
+
+// ValidateTransactionTypes checks a transactionTypes override. Helius names
+// transaction types in upper snake case, such as NFT_SALE or SWAP; ANY asks
+// for everything and cannot be combined with other types.
+func ValidateTransactionTypes(types []string) error {
+	for _, t := range types {
+		t = strings.ToUpper(strings.TrimSpace(t))
+		if t == "" {
+			return fmt.Errorf("transaction types must not be empty")
+		}
+		for _, c := range t {
+			if (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '_' {
+				return fmt.Errorf("invalid transaction type %q", t)
+			}
+		}
+		if t == "ANY" && len(types) > 1 {
+			return fmt.Errorf("transaction type ANY cannot be combined with other types")
+		}
+	}
+	return nil
+}
+
+// MaxBackfillLimit caps how many past transactions per address an indexer
+// may backfill when it is created
+const MaxBackfillLimit = 1000
+
+// ValidateBackfillLimit checks the backfillLimit param, 0 for no backfill
+func ValidateBackfillLimit(limit int) error {
+	if limit < 0 || limit > MaxBackfillLimit {
+		return fmt.Errorf("backfillLimit must be between 0 and %d", MaxBackfillLimit)
+	}
+	return nil
+}
+
+// MinPollInterval is the shortest pollInterval an indexer may set
+const MinPollInterval = time.Minute
+
+// ValidatePollInterval checks the pollInterval param, a duration such as
+// "5m", or empty for no polling
+func ValidatePollInterval(value string) error {
+	if value == "" {
+		return nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid pollInterval %q: %v", value, err)
+	}
+	if interval < MinPollInterval {
+		return fmt.Errorf("pollInterval must be at least %s", MinPollInterval)
+	}
+	return nil
+}
+
+// ValidatePollIntervalLimit checks that the pollInterval param is shorter than
+// limit. A poll relies on the webhook dedup to skip the transactions the
+// webhook already delivered, so limit is how long the dedup remembers them
+// for; zero means it doesn't, and polling isn't available. A value that
+// ValidatePollInterval rejects is left for it to report.
+func ValidatePollIntervalLimit(value string, limit time.Duration) error {
+	if ValidatePollInterval(value) != nil || value == "" {
+		return nil
+	}
+	interval, _ := time.ParseDuration(value)
+	if limit <= 0 {
+		return fmt.Errorf("pollInterval is not available while the webhook dedup is off")
+	}
+	if interval >= limit {
+		return fmt.Errorf("pollInterval must be shorter than %s so the webhook dedup still skips delivered transactions", limit)
+	}
+	return nil
+}
+
+// ValidateWebhookType checks the webhookType param. Raw webhooks deliver
+// unparsed transactions, which only token holder indexers can process since
+// they look for their mints in the token balances; raw webhooks also can't
+// be filtered by transaction type.
+func ValidateWebhookType(indexerType string, webhookType string, transactionTypes []string) error {
+	switch strings.ToLower(strings.TrimSpace(webhookType)) {
+	case "", "enhanced":
+		return nil
+	case "raw":
+		if indexerType != "token_holders" {
+			return fmt.Errorf("webhook type raw is not supported for %s indexers", indexerType)
+		}
+		for _, t := range transactionTypes {
+			if strings.ToUpper(strings.TrimSpace(t)) != "ANY" {
+				return fmt.Errorf("raw webhooks cannot be filtered by transaction type")
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid webhook type %q: must be enhanced or raw", webhookType)
+	}
+}
+
+// ParamProblem is one thing wrong with indexer params. Field names the param
+// at fault, or "params" when the blob as a whole is unusable.
+type ParamProblem struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ParamsError collects every problem found in indexer params so they can be
+// fixed together rather than one per request
+type ParamsError struct {
+	Problems []ParamProblem
+}
+
+func (e *ParamsError) Error() string {
+	messages := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		messages[i] = p.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *ParamsError) add(field string, err error) {
+	if err != nil {
+		e.Problems = append(e.Problems, ParamProblem{Field: field, Message: err.Error()})
+	}
+}
+
+func (e *ParamsError) addf(field string, format string, args ...interface{}) {
+	e.add(field, fmt.Errorf(format, args...))
+}
+
+// validateAddress requires a Solana address
+func (e *ParamsError) validateAddress(field, address, requiredMsg, invalidMsg string) {
+	if address == "" {
+		e.addf(field, "%s", requiredMsg)
+	} else if !IsValidSolanaAddress(address) {
+		e.addf(field, "%s", invalidMsg)
+	}
+}
+
+// validateTokens requires at least one token, each a Solana address
+func (e *ParamsError) validateTokens(tokens []string, indexing string) {
+	if len(tokens) == 0 {
+		e.addf("tokens", "at least one token address is required for %s indexing", indexing)
+	}
+	for _, token := range tokens {
+		if !IsValidSolanaAddress(token) {
+			e.addf("tokens", "invalid token address format: %s", token)
+		}
+	}
+}
+
+// validateMarketplaces requires every marketplace to be a known one
+func (e *ParamsError) validateMarketplaces(marketplaces []string) {
+	for _, marketplace := range marketplaces {
+		_, err := NormalizeMarketplace(marketplace)
+		e.add("marketplaces", err)
+	}
+}
+
+// ValidateIndexerParams checks the params of an indexer type. It reports every
+// problem it finds as a *ParamsError, except for params that aren't a JSON
+// object of the expected shape, which leave nothing further to check.
 func ValidateIndexerParams(indexerType string, paramsJson []byte) error {
+	problems := &ParamsError{}
 
 	if !IsValidJSON(string(paramsJson)) {
-		return fmt.Errorf("invalid JSON format for params")
+		problems.addf("params", "invalid JSON format for params")
+		return problems
+	}
+
+	var common struct {
+		TransactionTypes []string `json:"transactionTypes"`
+		WebhookType      string   `json:"webhookType"`
+		BackfillLimit    int      `json:"backfillLimit"`
+		PollInterval     string   `json:"pollInterval"`
+	}
+	if err := json.Unmarshal(paramsJson, &common); err != nil {
+		problems.addf("params", "invalid transactionTypes, webhookType, backfillLimit or pollInterval: %v", err)
+		return problems
+	}
+	problems.add("transactionTypes", ValidateTransactionTypes(common.TransactionTypes))
+	problems.add("webhookType", ValidateWebhookType(indexerType, common.WebhookType, common.TransactionTypes))
+	problems.add("backfillLimit", ValidateBackfillLimit(common.BackfillLimit))
+	problems.add("pollInterval", ValidatePollInterval(common.PollInterval))
+
+	type timeColumn struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
 	}
 
 	switch indexerType {
 	case "nft_bids":
 		var params struct {
-			Collection string `json:"collection"`
+			Collection       string     `json:"collection"`
+			CollectionOffers bool       `json:"collectionOffers"`
+			Marketplaces     []string   `json:"marketplaces"`
+			TimeColumn       timeColumn `json:"timeColumn"`
 		}
 		if err := json.Unmarshal(paramsJson, &params); err != nil {
-			return fmt.Errorf("invalid NFT bid parameters: %w", err)
-		}
-		if params.Collection == "" {
-			return fmt.Errorf("collection address is required for NFT bid indexing")
-		}
-		if !IsValidSolanaAddress(params.Collection) {
-			return fmt.Errorf("invalid collection address format")
+			problems.addf("params", "invalid NFT bid parameters: %v", err)
+			return problems
 		}
+		problems.validateAddress("collection", params.Collection,
+			"collection address is required for NFT bid indexing", "invalid collection address format")
+		problems.validateMarketplaces(params.Marketplaces)
+		problems.add("timeColumn", ValidateTimeColumn(params.TimeColumn.Name, params.TimeColumn.Type))
 
 	case "nft_prices":
 		var params struct {
-			Collection string `json:"collection"`
+			Collection     string     `json:"collection"`
+			Marketplaces   []string   `json:"marketplaces"`
+			TimeColumn     timeColumn `json:"timeColumn"`
+			UnresolvedMint string     `json:"unresolvedMint"`
 		}
 		if err := json.Unmarshal(paramsJson, &params); err != nil {
-			return fmt.Errorf("invalid NFT price parameters: %w", err)
-		}
-		if params.Collection == "" {
-			return fmt.Errorf("collection address is required for NFT price indexing")
+			problems.addf("params", "invalid NFT price parameters: %v", err)
+			return problems
 		}
-		if !IsValidSolanaAddress(params.Collection) {
-			return fmt.Errorf("invalid collection address format")
+		problems.validateAddress("collection", params.Collection,
+			"collection address is required for NFT price indexing", "invalid collection address format")
+		problems.validateMarketplaces(params.Marketplaces)
+		problems.add("timeColumn", ValidateTimeColumn(params.TimeColumn.Name, params.TimeColumn.Type))
+		switch params.UnresolvedMint {
+		case "", "skip", "store":
+		default:
+			problems.addf("unresolvedMint", "invalid unresolvedMint %q, must be one of skip, store", params.UnresolvedMint)
 		}
 
 	case "token_borrow":
@@ -127,36 +494,70 @@ func ValidateIndexerParams(indexerType string, paramsJson []byte) error {
 			Tokens []string `json:"tokens"`
 		}
 		if err := json.Unmarshal(paramsJson, &params); err != nil {
-			return fmt.Errorf("invalid token borrow parameters: %w", err)
+			problems.addf("params", "invalid token borrow parameters: %v", err)
+			return problems
 		}
-		if len(params.Tokens) == 0 {
-			return fmt.Errorf("at least one token address is required for token borrow indexing")
+		problems.validateTokens(params.Tokens, "token borrow")
+
+	case "token_holders":
+		var params struct {
+			Tokens           []string `json:"tokens"`
+			SnapshotInterval string   `json:"snapshotInterval"`
+		}
+		if err := json.Unmarshal(paramsJson, &params); err != nil {
+			problems.addf("params", "invalid token holder parameters: %v", err)
+			return problems
 		}
-		for _, token := range params.Tokens {
-			if !IsValidSolanaAddress(token) {
-				return fmt.Errorf("invalid token address format: %s", token)
+		problems.validateTokens(params.Tokens, "token holder")
+		if params.SnapshotInterval != "" {
+			if interval, err := time.ParseDuration(params.SnapshotInterval); err != nil {
+				problems.addf("snapshotInterval", "invalid snapshotInterval %q: %v", params.SnapshotInterval, err)
+			} else if interval < 0 {
+				problems.addf("snapshotInterval", "snapshotInterval must not be negative")
 			}
 		}
 
 	case "token_prices":
 		var params struct {
-			Tokens []string `json:"tokens"`
+			Tokens  []string `json:"tokens"`
+			Sources []string `json:"sources"`
 		}
 		if err := json.Unmarshal(paramsJson, &params); err != nil {
-			return fmt.Errorf("invalid token price parameters: %w", err)
+			problems.addf("params", "invalid token price parameters: %v", err)
+			return problems
+		}
+		problems.validateTokens(params.Tokens, "token price")
+		for _, source := range params.Sources {
+			switch strings.ToLower(source) {
+			case "swap", "transfer", "balance":
+			default:
+				problems.addf("sources", "invalid token price source %q, must be one of swap, transfer, balance", source)
+			}
+		}
+
+	case "program_logs":
+		var params struct {
+			ProgramID string   `json:"programId"`
+			Accounts  []string `json:"accounts"`
 		}
-		if len(params.Tokens) == 0 {
-			return fmt.Errorf("at least one token address is required for token price indexing")
+		if err := json.Unmarshal(paramsJson, &params); err != nil {
+			problems.addf("params", "invalid program log parameters: %v", err)
+			return problems
 		}
-		for _, token := range params.Tokens {
-			if !IsValidSolanaAddress(token) {
-				return fmt.Errorf("invalid token address format: %s", token)
+		problems.validateAddress("programId", params.ProgramID,
+			"program ID is required for program log indexing", "invalid program ID format")
+		for _, account := range params.Accounts {
+			if !IsValidSolanaAddress(account) {
+				problems.addf("accounts", "invalid account address format: %s", account)
 			}
 		}
 
 	default:
-		return fmt.Errorf("unsupported indexer type: %s", indexerType)
+		problems.addf("indexerType", "unsupported indexer type: %s", indexerType)
 	}
 
+	if len(problems.Problems) > 0 {
+		return problems
+	}
 	return nil
 }
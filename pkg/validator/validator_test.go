@@ -0,0 +1,279 @@
+package validator
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateTableName(t *testing.T) {
+	reserved := []string{"app_", " Internal_ "}
+
+	tests := []struct {
+		name    string
+		table   string
+		wantErr string
+	}{
+		{name: "valid name", table: "nft_bids"},
+		{name: "pg prefix", table: "pg_foo", wantErr: `reserved prefix "pg_"`},
+		{name: "pg prefix in upper case", table: "PG_foo", wantErr: `reserved prefix "pg_"`},
+		{name: "configured prefix", table: "app_users", wantErr: `reserved prefix "app_"`},
+		{name: "configured prefix is trimmed and case-insensitive", table: "INTERNAL_jobs", wantErr: `reserved prefix "internal_"`},
+		{name: "metadata table suffix", table: "sales_price_history", wantErr: `must not end with "_price_history"`},
+		{name: "starts with a digit", table: "1sales", wantErr: "must start with a letter"},
+		{name: "too long", table: "t" + strings.Repeat("a", 63), wantErr: "at most 63 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTableName(tt.table, reserved)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateTableName(%q) = %v, want nil", tt.table, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ValidateTableName(%q) = %v, want an error containing %q", tt.table, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsValidTableNameOnlyReservesPg(t *testing.T) {
+	if !IsValidTableName("app_users") {
+		t.Error("IsValidTableName(app_users) = false, want true without configured prefixes")
+	}
+	if IsValidTableName("pg_foo") {
+		t.Error("IsValidTableName(pg_foo) = true, want false")
+	}
+}
+
+func TestValidateTimeColumn(t *testing.T) {
+	tests := []struct {
+		name       string
+		column     string
+		columnType string
+		wantErr    bool
+	}{
+		{name: "defaults", column: "", columnType: ""},
+		{name: "timestamptz", column: "event_time", columnType: "timestamptz"},
+		{name: "epoch", column: "ts", columnType: "epoch"},
+		{name: "unknown type", column: "ts", columnType: "unix", wantErr: true},
+		{name: "upper case name", column: "EventTime", wantErr: true},
+		{name: "too long", column: strings.Repeat("t", 64), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTimeColumn(tt.column, tt.columnType)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTimeColumn(%q, %q) = %v, want error %v", tt.column, tt.columnType, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateSSLMode(t *testing.T) {
+	for _, mode := range []string{"", "disable", "allow", "prefer", "require", "verify-ca", "verify-full"} {
+		if err := ValidateSSLMode(mode); err != nil {
+			t.Errorf("ValidateSSLMode(%q) = %v, want nil", mode, err)
+		}
+	}
+
+	for _, mode := range []string{"requre", "REQUIRE", "verify_full"} {
+		err := ValidateSSLMode(mode)
+		if err == nil || !strings.Contains(err.Error(), "invalid SSL mode") {
+			t.Errorf("ValidateSSLMode(%q) = %v, want an invalid SSL mode error", mode, err)
+		}
+	}
+}
+
+func TestNormalizeMarketplacesAcceptsLowerCase(t *testing.T) {
+	params := []byte(`{"collection": "J1S9H3QjnRtBbbuD4HjPV6RpRhwuk4zKbxsnCHuTgh9w", "marketplaces": ["magic_eden", " tensor "]}`)
+
+	if err := ValidateIndexerParams("nft_prices", params); err != nil {
+		t.Fatalf("ValidateIndexerParams: %v", err)
+	}
+
+	normalized, err := NormalizeMarketplaces(params)
+	if err != nil {
+		t.Fatalf("NormalizeMarketplaces: %v", err)
+	}
+
+	var got struct {
+		Collection   string   `json:"collection"`
+		Marketplaces []string `json:"marketplaces"`
+	}
+	if err := json.Unmarshal(normalized, &got); err != nil {
+		t.Fatalf("unmarshal normalized params: %v", err)
+	}
+	if len(got.Marketplaces) != 2 || got.Marketplaces[0] != "MAGIC_EDEN" || got.Marketplaces[1] != "TENSOR" {
+		t.Errorf("marketplaces = %v, want [MAGIC_EDEN TENSOR]", got.Marketplaces)
+	}
+	if got.Collection != "J1S9H3QjnRtBbbuD4HjPV6RpRhwuk4zKbxsnCHuTgh9w" {
+		t.Errorf("collection = %q, want it kept", got.Collection)
+	}
+}
+
+func TestValidateIndexerParamsRejectsUnknownMarketplace(t *testing.T) {
+	params := []byte(`{"collection": "J1S9H3QjnRtBbbuD4HjPV6RpRhwuk4zKbxsnCHuTgh9w", "marketplaces": ["MAGIC_EDEN", "NOT_A_MARKET"]}`)
+
+	err := ValidateIndexerParams("nft_prices", params)
+	if err == nil || !strings.Contains(err.Error(), `invalid marketplace "NOT_A_MARKET"`) || !strings.Contains(err.Error(), "MAGIC_EDEN, METAPLEX") {
+		t.Errorf("ValidateIndexerParams error = %v, want the unknown marketplace named with the valid options", err)
+	}
+	if _, err := NormalizeMarketplaces(params); err == nil {
+		t.Error("NormalizeMarketplaces accepted an unknown marketplace")
+	}
+}
+
+func TestValidateIndexerParamsReportsEveryProblem(t *testing.T) {
+	params := []byte(`{"collection": "not-an-address", "marketplaces": ["NOT_A_MARKET"]}`)
+
+	var paramsErr *ParamsError
+	if err := ValidateIndexerParams("nft_prices", params); !errors.As(err, &paramsErr) {
+		t.Fatalf("ValidateIndexerParams error = %v, want a *ParamsError", err)
+	}
+
+	fields := make(map[string]bool)
+	for _, problem := range paramsErr.Problems {
+		fields[problem.Field] = true
+	}
+	if len(paramsErr.Problems) != 2 || !fields["collection"] || !fields["marketplaces"] {
+		t.Errorf("problems = %+v, want the collection and the marketplace reported together", paramsErr.Problems)
+	}
+}
+
+func TestValidateIndexerParamsReportsUnparseableParams(t *testing.T) {
+	var paramsErr *ParamsError
+	if err := ValidateIndexerParams("nft_prices", []byte(`{"collection": `)); !errors.As(err, &paramsErr) {
+		t.Fatalf("ValidateIndexerParams error = %v, want a *ParamsError", err)
+	}
+	if len(paramsErr.Problems) != 1 || paramsErr.Problems[0].Field != "params" {
+		t.Errorf("problems = %+v, want one problem with the params as a whole", paramsErr.Problems)
+	}
+}
+
+func TestValidateIndexerParamsUnresolvedMint(t *testing.T) {
+	for _, mode := range []string{"", "skip", "store"} {
+		params := []byte(`{"collection": "J1S9H3QjnRtBbbuD4HjPV6RpRhwuk4zKbxsnCHuTgh9w", "unresolvedMint": "` + mode + `"}`)
+		if err := ValidateIndexerParams("nft_prices", params); err != nil {
+			t.Errorf("ValidateIndexerParams rejected unresolvedMint %q: %v", mode, err)
+		}
+	}
+
+	params := []byte(`{"collection": "J1S9H3QjnRtBbbuD4HjPV6RpRhwuk4zKbxsnCHuTgh9w", "unresolvedMint": "collection"}`)
+	if err := ValidateIndexerParams("nft_prices", params); err == nil || !strings.Contains(err.Error(), `invalid unresolvedMint "collection"`) {
+		t.Errorf("ValidateIndexerParams error = %v, want the unknown unresolvedMint named", err)
+	}
+}
+
+func TestValidateWebhookType(t *testing.T) {
+	tests := []struct {
+		name             string
+		indexerType      string
+		webhookType      string
+		transactionTypes []string
+		wantErr          string
+	}{
+		{name: "default", indexerType: "nft_prices"},
+		{name: "enhanced", indexerType: "nft_prices", webhookType: "enhanced"},
+		{name: "raw token holders", indexerType: "token_holders", webhookType: " Raw "},
+		{name: "raw with ANY", indexerType: "token_holders", webhookType: "raw", transactionTypes: []string{"any"}},
+		{name: "raw nft prices", indexerType: "nft_prices", webhookType: "raw", wantErr: "not supported for nft_prices"},
+		{name: "raw filtered", indexerType: "token_holders", webhookType: "raw", transactionTypes: []string{"TRANSFER"}, wantErr: "cannot be filtered"},
+		{name: "unknown", indexerType: "token_holders", webhookType: "decoded", wantErr: `invalid webhook type "decoded"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateWebhookType(tt.indexerType, tt.webhookType, tt.transactionTypes)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateWebhookType: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateWebhookType error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePollIntervalLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval string
+		limit    time.Duration
+		wantErr  string
+	}{
+		{name: "no polling", interval: "", limit: 0},
+		{name: "within the dedup window", interval: "5m", limit: 10 * time.Minute},
+		{name: "as long as the dedup window", interval: "10m", limit: 10 * time.Minute, wantErr: "must be shorter than 10m0s"},
+		{name: "longer than the dedup window", interval: "1h", limit: 10 * time.Minute, wantErr: "must be shorter than 10m0s"},
+		{name: "dedup off", interval: "5m", limit: 0, wantErr: "dedup is off"},
+		{name: "left to ValidatePollInterval", interval: "soon", limit: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePollIntervalLimit(tt.interval, tt.limit)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidatePollIntervalLimit(%q, %v) = %v, want nil", tt.interval, tt.limit, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ValidatePollIntervalLimit(%q, %v) = %v, want an error containing %q", tt.interval, tt.limit, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateIndexerParamsRejectsRawWebhook(t *testing.T) {
+	params := []byte(`{"collection": "J1S9H3QjnRtBbbuD4HjPV6RpRhwuk4zKbxsnCHuTgh9w", "webhookType": "raw"}`)
+	if err := ValidateIndexerParams("nft_prices", params); err == nil {
+		t.Error("ValidateIndexerParams accepted a raw webhook for an NFT price indexer")
+	}
+}
+
+func TestValidateIndexerParamsProgramLogs(t *testing.T) {
+	valid := []byte(`{"programId": "JUP6LkbZbjS1jKKwapdHNy74zcZ3tLUZoi5QNyVTaV4", "accounts": ["EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"]}`)
+	if err := ValidateIndexerParams("program_logs", valid); err != nil {
+		t.Errorf("ValidateIndexerParams: %v", err)
+	}
+
+	for _, params := range []string{
+		`{"accounts": []}`,
+		`{"programId": "not-a-program"}`,
+		`{"programId": "JUP6LkbZbjS1jKKwapdHNy74zcZ3tLUZoi5QNyVTaV4", "accounts": ["bad"]}`,
+	} {
+		if err := ValidateIndexerParams("program_logs", []byte(params)); err == nil {
+			t.Errorf("ValidateIndexerParams accepted %s", params)
+		}
+	}
+}
+
+func TestCanonicalMarketplace(t *testing.T) {
+	tests := map[string]string{
+		"MAGIC_EDEN":    "MAGIC_EDEN",
+		"MAGIC_EDEN_V2": "MAGIC_EDEN",
+		"magic-eden":    "MAGIC_EDEN",
+		"Magic Eden":    "MAGIC_EDEN",
+		"MAGICEDEN":     "MAGIC_EDEN",
+		"TENSOR":        "TENSOR",
+		"TENSOR_CNFT":   "TENSOR",
+		"TCOMP":         "TENSOR",
+		"tensorswap":    "TENSOR",
+		"unknown":       "UNKNOWN",
+	}
+
+	for source, want := range tests {
+		if got := CanonicalMarketplace(source); got != want {
+			t.Errorf("CanonicalMarketplace(%q) = %q, want %q", source, got, want)
+		}
+	}
+}